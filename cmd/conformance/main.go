@@ -0,0 +1,87 @@
+// conformance 单点登录协议一致性测试工具
+//
+// 对 OIDC 发现文档、JWKS、Token、UserInfo，CAS 票据校验及 SAML2、WS-Fed 元数据接口按规范进行检查，
+// 按能力输出检查结果，存在未通过的能力时以非0状态码退出，可在发布前或CI中执行以发现协议实现的退化。
+//
+// 使用示例：
+//
+//	go run ./cmd/conformance -url http://127.0.0.1:8000 -capabilities oidc_discovery,oidc_jwks -output report.json
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"ops-api/utils/conformance"
+	"os"
+	"strings"
+	"time"
+)
+
+func main() {
+	var (
+		target       string
+		capabilities string
+		output       string
+		timeout      time.Duration
+	)
+	flag.StringVar(&target, "url", "http://127.0.0.1:8000", "IDSphere 服务地址")
+	flag.StringVar(&capabilities, "capabilities", "", "测试的能力，多个使用逗号分隔，为空时测试所有能力（"+strings.Join(conformance.Capabilities(), ",")+"）")
+	flag.StringVar(&output, "output", "", "测试报告（JSON）保存路径")
+	flag.DurationVar(&timeout, "timeout", 10*time.Second, "单个请求超时时间")
+	flag.Parse()
+
+	var names []string
+	for _, name := range strings.Split(capabilities, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		// 不跟随跳转，跳转本身即为需要检查的响应
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	report, err := conformance.Run(target, client, names)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(2)
+	}
+
+	printReport(report)
+
+	if output != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "测试报告保存失败：%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("测试报告已保存至 %s\n", output)
+	}
+
+	if report.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// printReport 输出测试结果
+func printReport(report *conformance.Report) {
+	for _, capability := range report.Capabilities {
+		status := "PASS"
+		if !capability.Passed {
+			status = "FAIL"
+		}
+		fmt.Printf("%-4s %-24s %-8s %5dms\n", status, capability.Name, capability.Protocol, capability.Duration)
+		for _, check := range capability.Checks {
+			if !check.Passed {
+				fmt.Printf("       - %s（%s）：%s\n", check.Name, check.Spec, check.Message)
+			}
+		}
+	}
+	fmt.Printf("通过 %d 项，未通过 %d 项，耗时 %dms\n", report.Passed, report.Failed, report.Duration)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// client IDSphere 接口客户端
+type client struct {
+	opts *options
+	http *http.Client
+}
+
+// apiResponse 通用接口响应
+type apiResponse struct {
+	Code        int    `json:"code"`
+	Msg         string `json:"msg"`
+	Token       string `json:"token"`
+	RedirectURI string `json:"redirect_uri"`
+}
+
+func newClient(opts *options) *client {
+	return &client{
+		opts: opts,
+		http: &http.Client{
+			Timeout: opts.Timeout,
+			Transport: &http.Transport{
+				MaxIdleConns:        opts.Concurrency * 2,
+				MaxIdleConnsPerHost: opts.Concurrency * 2,
+			},
+			// 不跟随跳转，回调地址由各场景自行解析
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// do 发送请求并返回响应内容
+func (c *client) do(method, path, token, contentType string, body io.Reader) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimRight(c.opts.URL, "/")+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s 返回状态码 %d", method, path, resp.StatusCode)
+	}
+
+	return data, nil
+}
+
+// postJSON 发送JSON请求，接口返回code不为0时返回错误
+func (c *client) postJSON(path, token string, payload interface{}) (*apiResponse, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	data, err := c.do(http.MethodPost, path, token, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseResponse(path, data)
+}
+
+// postForm 发送表单请求
+func (c *client) postForm(path, token string, form url.Values) ([]byte, error) {
+	return c.do(http.MethodPost, path, token, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+}
+
+// login 账号密码登录，返回用户Token
+func (c *client) login() (string, error) {
+	resp, err := c.postJSON("/api/auth/login", "", map[string]string{
+		"username": c.opts.Username,
+		"password": c.opts.Password,
+	})
+	if err != nil {
+		return "", err
+	}
+	if resp.Token == "" {
+		return "", errors.New("登录未返回Token，请确认压测用户未开启MFA")
+	}
+
+	return resp.Token, nil
+}
+
+// allocStats 获取服务端累计内存分配数据（来自 pprof 的 runtime.MemStats）
+func (c *client) allocStats() (totalAlloc, mallocs uint64, err error) {
+	data, err := c.do(http.MethodGet, "/debug/pprof/allocs?debug=1", "", "", nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "# TotalAlloc = "); ok {
+			totalAlloc, _ = strconv.ParseUint(value, 10, 64)
+		}
+		if value, ok := strings.CutPrefix(line, "# Mallocs = "); ok {
+			mallocs, _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+	if totalAlloc == 0 {
+		return 0, 0, errors.New("未获取到内存分配数据")
+	}
+
+	return totalAlloc, mallocs, scanner.Err()
+}
+
+// parseResponse 解析通用接口响应
+func parseResponse(path string, data []byte) (*apiResponse, error) {
+	resp := &apiResponse{}
+	if err := json.Unmarshal(data, resp); err != nil {
+		return nil, err
+	}
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("%s 返回错误：%d %s", path, resp.Code, resp.Msg)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,116 @@
+// loadtest 单点登录关键路径压测工具
+//
+// 对账号密码登录、OAuth2.0 Token 换取、CAS3.0 票据校验及 SAML2 响应生成进行并发压测，
+// 统计接口延迟及服务端内存分配，并与基线数据进行对比，用于在发布前发现性能退化。
+//
+// 使用示例：
+//
+//	go run ./cmd/loadtest -url http://127.0.0.1:8000 -username admin -password xxx \
+//	    -client-id xxx -client-secret xxx -redirect-uri https://app.example.com/callback \
+//	    -service https://cas.example.com/login -sp-entity-id https://sp.example.com/metadata \
+//	    -n 500 -c 20 -baseline loadtest-baseline.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// options 压测参数
+type options struct {
+	URL            string
+	Username       string
+	Password       string
+	ClientId       string
+	ClientSecret   string
+	RedirectURI    string
+	Service        string
+	SPEntityId     string
+	SPAcsURL       string
+	Scenarios      string
+	Requests       int
+	Concurrency    int
+	Timeout        time.Duration
+	Baseline       string
+	UpdateBaseline bool
+	Threshold      float64
+}
+
+func main() {
+	opts := &options{}
+	flag.StringVar(&opts.URL, "url", "http://127.0.0.1:8000", "IDSphere 服务地址")
+	flag.StringVar(&opts.Username, "username", "", "压测使用的用户名（需关闭MFA）")
+	flag.StringVar(&opts.Password, "password", "", "压测使用的用户密码")
+	flag.StringVar(&opts.ClientId, "client-id", "", "OAuth2.0 站点 client_id（token 场景）")
+	flag.StringVar(&opts.ClientSecret, "client-secret", "", "OAuth2.0 站点 client_secret（token 场景）")
+	flag.StringVar(&opts.RedirectURI, "redirect-uri", "", "OAuth2.0 站点回调地址（token 场景）")
+	flag.StringVar(&opts.Service, "service", "", "CAS3.0 站点 service 地址（cas 场景）")
+	flag.StringVar(&opts.SPEntityId, "sp-entity-id", "", "SAML2 站点 EntityID（saml 场景）")
+	flag.StringVar(&opts.SPAcsURL, "sp-acs-url", "", "SAML2 站点 ACS 地址（saml 场景，可选）")
+	flag.StringVar(&opts.Scenarios, "scenarios", "login,token,cas,saml", "压测场景，多个使用逗号分隔")
+	flag.IntVar(&opts.Requests, "n", 200, "每个场景的请求总数")
+	flag.IntVar(&opts.Concurrency, "c", 10, "并发数")
+	flag.DurationVar(&opts.Timeout, "timeout", 10*time.Second, "单个请求超时时间")
+	flag.StringVar(&opts.Baseline, "baseline", "", "基线数据文件，指定后与基线进行对比")
+	flag.BoolVar(&opts.UpdateBaseline, "update-baseline", false, "使用本次压测结果更新基线数据文件")
+	flag.Float64Var(&opts.Threshold, "threshold", 0.2, "允许的性能退化比例，超过后以非0状态码退出")
+	flag.Parse()
+
+	if opts.Username == "" || opts.Password == "" {
+		fmt.Fprintln(os.Stderr, "username 和 password 不能为空")
+		os.Exit(2)
+	}
+
+	client := newClient(opts)
+
+	// 执行压测
+	var results []*result
+	for _, name := range strings.Split(opts.Scenarios, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		s, err := newScenario(name, client)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "场景 %s 初始化失败：%v\n", name, err)
+			os.Exit(1)
+		}
+		results = append(results, run(s, client, opts))
+	}
+
+	printResults(results)
+
+	// 更新基线数据
+	if opts.UpdateBaseline {
+		if opts.Baseline == "" {
+			fmt.Fprintln(os.Stderr, "更新基线数据时 baseline 不能为空")
+			os.Exit(2)
+		}
+		if err := saveBaseline(opts.Baseline, results); err != nil {
+			fmt.Fprintf(os.Stderr, "基线数据保存失败：%v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("基线数据已保存至 %s\n", opts.Baseline)
+		return
+	}
+
+	// 与基线数据对比
+	if opts.Baseline != "" {
+		baseline, err := loadBaseline(opts.Baseline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "基线数据加载失败：%v\n", err)
+			os.Exit(1)
+		}
+		if regressions := compareBaseline(baseline, results, opts.Threshold); len(regressions) > 0 {
+			fmt.Println("发现性能退化：")
+			for _, r := range regressions {
+				fmt.Println("  " + r)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("未发现性能退化")
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// result 单个场景的压测结果
+type result struct {
+	Scenario    string  `json:"scenario"`
+	Requests    int     `json:"requests"`
+	Errors      int     `json:"errors"`
+	RPS         float64 `json:"rps"`
+	AvgMs       float64 `json:"avg_ms"`
+	P50Ms       float64 `json:"p50_ms"`
+	P95Ms       float64 `json:"p95_ms"`
+	P99Ms       float64 `json:"p99_ms"`
+	BytesPerOp  uint64  `json:"bytes_per_op"`  // 服务端每次操作的内存分配字节数（包含准备数据的请求，仅用于趋势对比）
+	AllocsPerOp uint64  `json:"allocs_per_op"` // 服务端每次操作的内存分配次数
+	FirstError  string  `json:"-"`
+}
+
+// run 并发执行压测场景
+func run(s *scenario, c *client, opts *options) *result {
+	var (
+		wg        sync.WaitGroup
+		mutex     sync.Mutex
+		next      int64
+		errCount  int
+		durations = make([]time.Duration, 0, opts.Requests)
+		res       = &result{Scenario: s.name, Requests: opts.Requests}
+	)
+
+	// 压测前记录服务端内存分配数据，pprof 不可用时不统计内存分配
+	beforeAlloc, beforeMallocs, allocErr := c.allocStats()
+
+	start := time.Now()
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for atomic.AddInt64(&next, 1) <= int64(opts.Requests) {
+				elapsed, err := s.op()
+				mutex.Lock()
+				if err != nil {
+					errCount++
+					if res.FirstError == "" {
+						res.FirstError = err.Error()
+					}
+				} else {
+					durations = append(durations, elapsed)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	total := time.Since(start)
+
+	if allocErr == nil {
+		if afterAlloc, afterMallocs, err := c.allocStats(); err == nil {
+			res.BytesPerOp = (afterAlloc - beforeAlloc) / uint64(opts.Requests)
+			res.AllocsPerOp = (afterMallocs - beforeMallocs) / uint64(opts.Requests)
+		}
+	}
+
+	res.Errors = errCount
+	res.RPS = float64(len(durations)) / total.Seconds()
+	if len(durations) > 0 {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		var sum time.Duration
+		for _, d := range durations {
+			sum += d
+		}
+		res.AvgMs = milliseconds(sum / time.Duration(len(durations)))
+		res.P50Ms = milliseconds(percentile(durations, 0.50))
+		res.P95Ms = milliseconds(percentile(durations, 0.95))
+		res.P99Ms = milliseconds(percentile(durations, 0.99))
+	}
+
+	return res
+}
+
+// percentile 获取已排序耗时的百分位值
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	index := int(float64(len(sorted))*p+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+// milliseconds 转换为毫秒
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// printResults 输出压测结果
+func printResults(results []*result) {
+	fmt.Printf("%-8s %8s %6s %9s %9s %9s %9s %9s %12s %10s\n",
+		"场景", "请求数", "错误", "RPS", "avg(ms)", "p50(ms)", "p95(ms)", "p99(ms)", "B/op", "allocs/op")
+	for _, r := range results {
+		fmt.Printf("%-8s %8d %6d %9.1f %9.2f %9.2f %9.2f %9.2f %12d %10d\n",
+			r.Scenario, r.Requests, r.Errors, r.RPS, r.AvgMs, r.P50Ms, r.P95Ms, r.P99Ms, r.BytesPerOp, r.AllocsPerOp)
+	}
+	for _, r := range results {
+		if r.FirstError != "" {
+			fmt.Printf("场景 %s 首个错误：%s\n", r.Scenario, r.FirstError)
+		}
+	}
+}
+
+// loadBaseline 加载基线数据
+func loadBaseline(path string) (map[string]*result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []*result
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, err
+	}
+
+	baseline := make(map[string]*result, len(results))
+	for _, r := range results {
+		baseline[r.Scenario] = r
+	}
+
+	return baseline, nil
+}
+
+// saveBaseline 保存基线数据
+func saveBaseline(path string, results []*result) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// compareBaseline 与基线数据对比，返回超过允许退化比例的指标
+func compareBaseline(baseline map[string]*result, results []*result, threshold float64) []string {
+	var regressions []string
+
+	check := func(scenario, metric string, base, current float64) {
+		if base > 0 && current > base*(1+threshold) {
+			regressions = append(regressions, fmt.Sprintf("%s %s：基线 %.2f，当前 %.2f（+%.0f%%）",
+				scenario, metric, base, current, (current/base-1)*100))
+		}
+	}
+
+	for _, r := range results {
+		if r.Errors > 0 {
+			regressions = append(regressions, fmt.Sprintf("%s 存在 %d 个失败请求", r.Scenario, r.Errors))
+		}
+		base, ok := baseline[r.Scenario]
+		if !ok {
+			continue
+		}
+		check(r.Scenario, "p95(ms)", base.P95Ms, r.P95Ms)
+		check(r.Scenario, "p99(ms)", base.P99Ms, r.P99Ms)
+		check(r.Scenario, "B/op", float64(base.BytesPerOp), float64(r.BytesPerOp))
+		check(r.Scenario, "allocs/op", float64(base.AllocsPerOp), float64(r.AllocsPerOp))
+	}
+
+	return regressions
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// scenario 压测场景，op 执行一次操作并返回被测接口的耗时（不包含准备数据的请求）
+type scenario struct {
+	name string
+	op   func() (time.Duration, error)
+}
+
+// newScenario 创建压测场景
+func newScenario(name string, c *client) (*scenario, error) {
+	switch name {
+	case "login":
+		return &scenario{name: name, op: func() (time.Duration, error) {
+			start := time.Now()
+			_, err := c.login()
+			return time.Since(start), err
+		}}, nil
+	case "token":
+		if c.opts.ClientId == "" || c.opts.ClientSecret == "" || c.opts.RedirectURI == "" {
+			return nil, errors.New("client-id、client-secret、redirect-uri 不能为空")
+		}
+		token, err := c.login()
+		if err != nil {
+			return nil, err
+		}
+		return &scenario{name: name, op: func() (time.Duration, error) { return tokenExchange(c, token) }}, nil
+	case "cas":
+		if c.opts.Service == "" {
+			return nil, errors.New("service 不能为空")
+		}
+		token, err := c.login()
+		if err != nil {
+			return nil, err
+		}
+		return &scenario{name: name, op: func() (time.Duration, error) { return casValidate(c, token) }}, nil
+	case "saml":
+		if c.opts.SPEntityId == "" {
+			return nil, errors.New("sp-entity-id 不能为空")
+		}
+		token, err := c.login()
+		if err != nil {
+			return nil, err
+		}
+		return &scenario{name: name, op: func() (time.Duration, error) { return samlResponse(c, token) }}, nil
+	default:
+		return nil, fmt.Errorf("不支持的场景：%s", name)
+	}
+}
+
+// tokenExchange 获取授权码后使用授权码换取Token，仅统计换取Token的耗时
+func tokenExchange(c *client, token string) (time.Duration, error) {
+	resp, err := c.postJSON("/api/v1/sso/oauth/authorize", token, map[string]string{
+		"response_type": "code",
+		"client_id":     c.opts.ClientId,
+		"redirect_uri":  c.opts.RedirectURI,
+		"scope":         "openid",
+		"state":         "loadtest",
+	})
+	if err != nil {
+		return 0, err
+	}
+	code, err := queryValue(resp.RedirectURI, "code")
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	data, err := c.postForm("/api/v1/sso/oauth/token", "", url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"client_id":     {c.opts.ClientId},
+		"client_secret": {c.opts.ClientSecret},
+		"redirect_uri":  {c.opts.RedirectURI},
+	})
+	elapsed := time.Since(start)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Contains(data, []byte("access_token")) {
+		return 0, fmt.Errorf("Token换取失败：%s", data)
+	}
+
+	return elapsed, nil
+}
+
+// casValidate 获取CAS票据后进行票据校验，仅统计票据校验的耗时
+func casValidate(c *client, token string) (time.Duration, error) {
+	resp, err := c.postJSON("/api/v1/sso/cas/authorize", token, map[string]string{
+		"service": c.opts.Service,
+	})
+	if err != nil {
+		return 0, err
+	}
+	ticket, err := queryValue(resp.RedirectURI, "ticket")
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	path := "/p3/serviceValidate?" + url.Values{"service": {c.opts.Service}, "ticket": {ticket}}.Encode()
+	data, err := c.do(http.MethodGet, path, "", "", nil)
+	elapsed := time.Since(start)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Contains(data, []byte("authenticationSuccess")) {
+		return 0, fmt.Errorf("票据校验失败：%s", data)
+	}
+
+	return elapsed, nil
+}
+
+// samlResponse 提交AuthnRequest并生成签名后的SAMLResponse
+func samlResponse(c *client, token string) (time.Duration, error) {
+	samlRequest, err := authnRequest(c.opts.SPEntityId, c.opts.SPAcsURL)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	data, err := c.postForm("/api/v1/sso/saml/authorize", token, url.Values{
+		"SAMLRequest": {samlRequest},
+		"RelayState":  {"loadtest"},
+	})
+	elapsed := time.Since(start)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := parseResponse("/api/v1/sso/saml/authorize", data); err != nil {
+		return 0, err
+	}
+
+	return elapsed, nil
+}
+
+// authnRequest 生成DEFLATE压缩 + base64编码的AuthnRequest
+func authnRequest(entityId, acsURL string) (string, error) {
+	acs := ""
+	if acsURL != "" {
+		acs = fmt.Sprintf(` AssertionConsumerServiceURL="%s"`, acsURL)
+	}
+	request := fmt.Sprintf(`<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_loadtest%d" Version="2.0" IssueInstant="%s"%s><saml:Issuer>%s</saml:Issuer></samlp:AuthnRequest>`,
+		time.Now().UnixNano(), time.Now().UTC().Format(time.RFC3339), acs, entityId)
+
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err := writer.Write([]byte(request)); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// queryValue 从回调地址中获取指定参数
+func queryValue(rawURL, key string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	value := u.Query().Get(key)
+	if value == "" {
+		return "", fmt.Errorf("回调地址中未包含 %s：%s", key, strings.TrimSpace(rawURL))
+	}
+
+	return value, nil
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"github.com/spf13/viper"
 	"github.com/wonderivan/logger"
+	"os"
 )
 
 // Conf 全局变量
@@ -13,8 +14,12 @@ type Config struct {
 	Server   string `yaml:"server"`
 	MySQL    MySQL  `yaml:"mysql"`
 	Redis    Redis  `yaml:"redis"`
+	Cache    Cache  `yaml:"cache"`
 	OSS      OSS    `yaml:"oss"`
 	Settings map[string]interface{}
+
+	Bootstrap string `yaml:"bootstrap"` // 启动时导入的应用配置文件路径，为空时不导入
+	Admin     Admin  `yaml:"admin"`     // 初始管理员，系统未初始化时使用该配置创建管理员
 }
 
 type MySQL struct {
@@ -26,6 +31,7 @@ type MySQL struct {
 	MaxIdleConns int    `yaml:"maxIdleConns"`
 	MaxOpenConns int    `yaml:"maxOpenConns"`
 	MaxLifeTime  int    `yaml:"maxLifeTime"`
+	Loc          string `yaml:"loc"` // 数据库时间的存储时区，默认为Local（服务器本地时区），新部署可配置为UTC；已有数据的部署切换前需先转换已有时间数据
 }
 
 type Redis struct {
@@ -34,6 +40,19 @@ type Redis struct {
 	Password string `yaml:"password"`
 }
 
+// Cache 缓存配置，Type 为空或 redis 时使用Redis，memory 为内存缓存（仅适用于单实例的轻量部署），memcached 使用 Servers 中的Memcached服务器
+type Cache struct {
+	Type    string   `yaml:"type"`
+	Servers []string `yaml:"servers"`
+}
+
+// Admin 初始管理员配置，可使用环境变量 IDSPHERE_ADMIN_PASSWORD 及 IDSPHERE_ADMIN_EMAIL 覆盖，
+// 未配置密码时需调用初始化接口创建管理员
+type Admin struct {
+	Password string `yaml:"password"`
+	Email    string `yaml:"email"`
+}
+
 type OSS struct {
 	Endpoint   string `yaml:"endpoint"`
 	AccessKey  string `yaml:"accessKey"`
@@ -69,6 +88,14 @@ func InitConfig() {
 		return
 	}
 
+	// 环境变量中的初始管理员配置优先
+	if password := os.Getenv("IDSPHERE_ADMIN_PASSWORD"); password != "" {
+		cfg.Admin.Password = password
+	}
+	if email := os.Getenv("IDSPHERE_ADMIN_EMAIL"); email != "" {
+		cfg.Admin.Email = email
+	}
+
 	// 将解析出来的配置赋值给全局变量
 	Conf = &cfg
 }
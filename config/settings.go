@@ -0,0 +1,365 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 配置项类型，与settings表中的value_type一致
+const (
+	SettingString  = "string"
+	SettingInt     = "int"
+	SettingBoolean = "boolean"
+	SettingList    = "list"
+)
+
+// settingSpec 配置项定义
+type settingSpec struct {
+	Type     string
+	Default  interface{} // 未配置（值为空）时使用的默认值，为nil时不设置默认值，获取时返回类型的零值
+	Required bool        // 必须配置，缺少时启动失败
+}
+
+// settingSpecs 系统使用的所有配置项
+var settingSpecs = map[string]settingSpec{
+	// 站点基本配置
+	"externalUrl":     {Type: SettingString, Required: true},
+	"logo":            {Type: SettingString},
+	"swagger":         {Type: SettingBoolean, Default: true},
+	"defaultLanguage": {Type: SettingString, Default: "en-US"},
+	"ossPublicUrl":    {Type: SettingString},
+	"publicDirectory": {Type: SettingBoolean, Default: false},
+	"timezone":        {Type: SettingString}, // 系统时区，如：Asia/Shanghai，为空时使用服务器本地时区
+
+	// 安全设置
+	"mfa":                     {Type: SettingBoolean, Default: false},
+	"issuer":                  {Type: SettingString, Required: true},
+	"secret":                  {Type: SettingString, Required: true},
+	"tokenExpiresTime":        {Type: SettingInt, Default: 12},
+	"certificate":             {Type: SettingString, Required: true},
+	"publicKey":               {Type: SettingString, Required: true},
+	"privateKey":              {Type: SettingString, Required: true},
+	"securityNotifyDigest":    {Type: SettingBoolean, Default: true},
+	"tokenRevocationFailOpen": {Type: SettingBoolean, Default: false}, // Redis不可用时是否跳过令牌吊销检查，默认拒绝请求
+	"dormantAccountDays":      {Type: SettingInt, Default: 90},
+	"endpointAllowlist":       {Type: SettingList},
+	"corsPolicies":            {Type: SettingString},                 // 按接口路径配置的跨域策略（JSON），为空时允许所有来源
+	"legacyStatusCode":        {Type: SettingBoolean, Default: true}, // 兼容模式（默认开启），所有接口错误均返回HTTP 200，仅通过响应体中的code区分，关闭后返回与code对应的HTTP状态码
+	"publicRateLimit":         {Type: SettingInt, Default: 120},
+	"trustedNetworks":         {Type: SettingList},
+	"trustedProxies":          {Type: SettingList},              // 可信代理的IP或网段，仅信任可信代理转发的客户端IP，为空时使用连接的来源地址，修改后需重启服务生效
+	"trustedRateLimit":        {Type: SettingInt, Default: 600}, // 可信网络公开接口每分钟请求次数限制，为0时不限制，账号密码登录及MFA认证接口不放宽
+	"breakGlassNetworks":      {Type: SettingList},
+	"breakGlassWindow":        {Type: SettingInt, Default: 60},
+	"auditorMaxDays":          {Type: SettingInt, Default: 30}, // 审计员最长有效期（天）
+	"redirectAllowlist":       {Type: SettingList},
+	"firstLoginActions":       {Type: SettingList}, // 新用户首次登录时必须完成的操作
+	"firstLoginTerms":         {Type: SettingString},
+	"protocolSunset":          {Type: SettingList}, // 单点登录协议停用日期，格式为：协议=YYYY-MM-DD
+
+	// 密码策略
+	"passwordExpireDays":         {Type: SettingInt, Default: 90},
+	"passwordLength":             {Type: SettingInt, Default: 8},
+	"passwordComplexity":         {Type: SettingList, Default: []string{"numbers", "uppercase", "lowercase"}},
+	"passwordExpiryReminderDays": {Type: SettingInt, Default: 7},
+	"passwordMailResetOff":       {Type: SettingBoolean, Default: false},
+	"passwordBreachCheck":        {Type: SettingBoolean, Default: false},
+	"passwordBreachMode":         {Type: SettingString, Default: "online"},
+	"passwordBreachCorpus":       {Type: SettingString, Default: "config/breached_passwords.txt"},
+
+	// LDAP 设置
+	"ldapAddress":                {Type: SettingString},
+	"ldapBindDn":                 {Type: SettingString},
+	"ldapBindPassword":           {Type: SettingString},
+	"ldapSearchDn":               {Type: SettingString},
+	"ldapFilterAttribute":        {Type: SettingString, Default: "uid"},
+	"ldapUserPasswordExpireDays": {Type: SettingInt, Default: 90},
+
+	// LDAP 连接池，ldapRateLimit 为所有实例每秒对LDAP服务器的最大操作次数，为0时不限制
+	"ldapStartTLS":        {Type: SettingBoolean, Default: false},
+	"ldapSkipVerify":      {Type: SettingBoolean, Default: true},
+	"ldapFollowReferrals": {Type: SettingBoolean, Default: false},
+	"ldapTimeout":         {Type: SettingInt, Default: 10},
+	"ldapPoolSize":        {Type: SettingInt, Default: 5},
+	"ldapRateLimit":       {Type: SettingInt, Default: 0},
+
+	// 内置LDAP服务
+	"ldapServer":                 {Type: SettingBoolean, Default: false},
+	"ldapServerAddress":          {Type: SettingString, Default: ":1389"},
+	"ldapServerBaseDn":           {Type: SettingString},
+	"ldapServerBindDn":           {Type: SettingString},
+	"ldapServerBindPassword":     {Type: SettingString},
+	"ldapServerLockoutThreshold": {Type: SettingInt, Default: 5},
+	"ldapServerLockoutMinutes":   {Type: SettingInt, Default: 15},
+	"ldapServerTlsAddress":       {Type: SettingString, Default: ":1636"}, // LDAPS监听地址，配置证书后生效
+	"ldapServerCertificate":      {Type: SettingString},                   // TLS证书（PEM），配置后支持LDAPS及StartTLS，未配置时仅允许匿名绑定
+	"ldapServerPrivateKey":       {Type: SettingString},                   // TLS私钥（PEM）
+
+	// 邮件设置
+	"mailAddress":  {Type: SettingString},
+	"mailPort":     {Type: SettingInt},
+	"mailForm":     {Type: SettingString},
+	"mailPassword": {Type: SettingString},
+
+	// 短信设置
+	"smsProvider":     {Type: SettingString, Default: "huawei"},
+	"smsSignature":    {Type: SettingString},
+	"smsEndpoint":     {Type: SettingString},
+	"smsSender":       {Type: SettingString},
+	"smsAppKey":       {Type: SettingString},
+	"smsAppSecret":    {Type: SettingString},
+	"smsCallbackUrl":  {Type: SettingString},
+	"smsTemplateId":   {Type: SettingString},
+	"smsTemplateIdEn": {Type: SettingString},
+	"smsRegion":       {Type: SettingString, Default: "CN"}, // 手机号默认地区，未携带国际区号的手机号按该地区解析
+	"smsTemplates":    {Type: SettingString},                // 按服务商配置的短信模板映射（JSON），为空时使用 smsTemplateId、smsTemplateIdEn
+
+	// 第三方登录
+	"dingdingAppKey":    {Type: SettingString},
+	"dingdingAppSecret": {Type: SettingString},
+	"feishuAppId":       {Type: SettingString},
+	"feishuAppSecret":   {Type: SettingString},
+	"wechatCorpId":      {Type: SettingString},
+	"wechatAgentId":     {Type: SettingInt},
+	"wechatSecret":      {Type: SettingString},
+
+	// SCIM
+	"scimToken": {Type: SettingString},
+
+	// 外部审批（ITSM），itsmProvider 为空时不允许提交访问申请，可选值：jira、feishu
+	"itsmProvider":           {Type: SettingString},
+	"itsmJiraAddress":        {Type: SettingString},
+	"itsmJiraUser":           {Type: SettingString}, // 为空时使用个人访问令牌（Bearer）认证
+	"itsmJiraToken":          {Type: SettingString},
+	"itsmJiraServiceDesk":    {Type: SettingString},
+	"itsmJiraRequestType":    {Type: SettingString},
+	"itsmFeishuApprovalCode": {Type: SettingString},
+	"itsmFeishuWidget":       {Type: SettingString, Default: "widget1"}, // 审批定义中用于填写申请内容的多行文本控件ID
+	"itsmWebhookToken":       {Type: SettingString},
+
+	// 内部服务令牌有效期（秒），最长1小时
+	"serviceTokenTTL": {Type: SettingInt, Default: 300},
+
+	// OIDC签发者及端点
+	"oidcIssuer":                {Type: SettingString},
+	"oidcAuthorizationEndpoint": {Type: SettingString},
+	"oidcTokenEndpoint":         {Type: SettingString},
+	"oidcUserinfoEndpoint":      {Type: SettingString},
+	"oidcJwksUri":               {Type: SettingString},
+	"oidcDeviceEndpoint":        {Type: SettingString}, // 设备授权（RFC 8628）端点
+	"oidcVerificationUri":       {Type: SettingString}, // 设备授权时用户输入user_code的验证页面地址
+
+	// OIDC动态客户端注册（RFC 7591），初始访问令牌为空时允许匿名注册
+	"oidcRegistration":      {Type: SettingBoolean, Default: false},
+	"oidcRegistrationToken": {Type: SettingString},
+
+	// 文件上传
+	"uploadScanner":      {Type: SettingString},
+	"uploadScanAddress":  {Type: SettingString},
+	"uploadMaxSize":      {Type: SettingInt, Default: 2048},
+	"uploadMaxDimension": {Type: SettingInt, Default: 4096},
+
+	// 内置告警，阈值为0时不检查对应的告警规则
+	"alertWindow":                {Type: SettingInt, Default: 15},
+	"alertLoginFailureThreshold": {Type: SettingInt, Default: 50},
+	"alertSmsErrorRate":          {Type: SettingInt, Default: 20},
+	"alertCertificateDays":       {Type: SettingInt, Default: 30},
+}
+
+// SettingsError 配置校验失败，包含所有缺少或无效的配置项，Fatal 为true时必须配置的配置项缺少或无效
+type SettingsError struct {
+	Problems []string
+	Fatal    bool
+}
+
+func (e *SettingsError) Error() string {
+	return "配置校验失败：" + strings.Join(e.Problems, "；")
+}
+
+// LoadSettings 校验配置项并补充默认值，invalid 为解析失败的配置项及原因。
+// 存在缺少或无效的配置项时返回 *SettingsError，无效的配置项使用默认值，其余配置仍然可用
+func LoadSettings(values map[string]interface{}, invalid map[string]string) (map[string]interface{}, error) {
+
+	settings := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		settings[key] = value
+	}
+
+	var (
+		problems []string
+		fatal    bool
+	)
+	for _, key := range sortedSpecKeys() {
+		spec := settingSpecs[key]
+
+		if reason, ok := invalid[key]; ok {
+			problems = append(problems, fmt.Sprintf("配置项%s无效（%s）", key, reason))
+			fatal = fatal || spec.Required
+			delete(settings, key)
+		} else if value := settings[key]; value != nil && !matchSettingType(spec.Type, value) {
+			problems = append(problems, fmt.Sprintf("配置项%s类型错误，应为%s", key, spec.Type))
+			fatal = fatal || spec.Required
+			delete(settings, key)
+		}
+
+		if value := settings[key]; value == nil || value == "" {
+			if spec.Required {
+				if _, ok := invalid[key]; !ok {
+					problems = append(problems, fmt.Sprintf("缺少配置项%s", key))
+				}
+				fatal = true
+				continue
+			}
+			if spec.Default != nil {
+				settings[key] = spec.Default
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return settings, &SettingsError{Problems: problems, Fatal: fatal}
+	}
+	return settings, nil
+}
+
+// GetString 获取字符串类型的配置，未配置或类型错误时返回默认值
+func GetString(key string) string {
+	value, _ := getSetting(key).(string)
+	return value
+}
+
+// GetInt 获取整数类型的配置，未配置或类型错误时返回默认值
+func GetInt(key string) int {
+	value, _ := getSetting(key).(int)
+	return value
+}
+
+// GetBool 获取布尔类型的配置，未配置或类型错误时返回默认值
+func GetBool(key string) bool {
+	value, _ := getSetting(key).(bool)
+	return value
+}
+
+// GetList 获取列表类型的配置，未配置或类型错误时返回默认值
+func GetList(key string) []string {
+	value, _ := getSetting(key).([]string)
+	return value
+}
+
+// locations 已加载的时区
+var locations sync.Map
+
+// LoadLocation 加载时区，为空时使用服务器本地时区
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// Location 获取系统时区，用于定时任务调度、按日统计、报告及通知中的时间展示，未配置或配置错误时使用服务器本地时区
+func Location() *time.Location {
+	loc, err := LoadLocation(GetString("timezone"))
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// SMSSettings 短信配置
+type SMSSettings struct {
+	Provider     string
+	Signature    string
+	Endpoint     string
+	Sender       string
+	AppKey       string
+	AppSecret    string // 加密保存的AppSecret
+	CallbackUrl  string
+	TemplateId   string
+	TemplateIdEn string
+}
+
+// SMS 获取短信配置
+func SMS() SMSSettings {
+	return SMSSettings{
+		Provider:     GetString("smsProvider"),
+		Signature:    GetString("smsSignature"),
+		Endpoint:     GetString("smsEndpoint"),
+		Sender:       GetString("smsSender"),
+		AppKey:       GetString("smsAppKey"),
+		AppSecret:    GetString("smsAppSecret"),
+		CallbackUrl:  GetString("smsCallbackUrl"),
+		TemplateId:   GetString("smsTemplateId"),
+		TemplateIdEn: GetString("smsTemplateIdEn"),
+	}
+}
+
+// SSOSettings 单点登录配置
+type SSOSettings struct {
+	ExternalUrl      string
+	Issuer           string // 站点名称，用于SAML2、CAS3.0等协议中的签发者名称及MFA签发者
+	Secret           string
+	Certificate      string
+	PrivateKey       string
+	TokenExpiresTime int // Token过期时间（小时）
+}
+
+// SSO 获取单点登录配置
+func SSO() SSOSettings {
+	return SSOSettings{
+		ExternalUrl:      GetString("externalUrl"),
+		Issuer:           GetString("issuer"),
+		Secret:           GetString("secret"),
+		Certificate:      GetString("certificate"),
+		PrivateKey:       GetString("privateKey"),
+		TokenExpiresTime: GetInt("tokenExpiresTime"),
+	}
+}
+
+// getSetting 获取配置值，未加载配置或类型错误时返回配置项的默认值
+func getSetting(key string) interface{} {
+	spec := settingSpecs[key]
+	if Conf != nil {
+		if value, ok := Conf.Settings[key]; ok && value != nil && (spec.Type == "" || matchSettingType(spec.Type, value)) {
+			return value
+		}
+	}
+	return spec.Default
+}
+
+func matchSettingType(valueType string, value interface{}) bool {
+	switch valueType {
+	case SettingInt:
+		_, ok := value.(int)
+		return ok
+	case SettingBoolean:
+		_, ok := value.(bool)
+		return ok
+	case SettingList:
+		_, ok := value.([]string)
+		return ok
+	default:
+		_, ok := value.(string)
+		return ok
+	}
+}
+
+func sortedSpecKeys() []string {
+	keys := make([]string, 0, len(settingSpecs))
+	for key := range settingSpecs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/service"
+	"strconv"
+)
+
+var AccessRequest accessRequest
+
+type accessRequest struct{}
+
+// GetAccessRequestList 获取访问申请列表（表格）
+// @Summary 获取访问申请列表（表格）
+// @Description 访问申请相关接口
+// @Tags 访问申请管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param name query string false "申请人、申请内容或工单号"
+// @Param status query string false "状态：pending、approved、rejected、failed"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Success 200 {object} DataResult{data=dao.AccessRequestList}
+// @Router /api/v1/access_requests [get]
+func (a *accessRequest) GetAccessRequestList(c *gin.Context) {
+	params := new(struct {
+		Name   string `form:"name"`
+		Status string `form:"status"`
+		Page   int    `form:"page" binding:"required"`
+		Limit  int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.AccessRequest.GetAccessRequestList(params.Name, params.Status, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// ResolveAccessRequest 手动处理访问申请
+// @Summary 手动处理访问申请
+// @Description 访问申请相关接口，用于ITSM故障或提交审批失败时手动处理，同意后自动授权
+// @Tags 访问申请管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "访问申请ID"
+// @Param data body service.AccessRequestResolve true "处理结果"
+// @Success 200 {object} Result "处理成功"
+// @Router /api/v1/access_request/{id}/resolve [put]
+func (a *accessRequest) ResolveAccessRequest(c *gin.Context) {
+	var data = &service.AccessRequestResolve{}
+
+	// 对ID进行类型转换
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.AccessRequest.ResolveAccessRequest(uint(id), data, c.GetString("username")); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "处理成功", nil)
+}
+
+// GetUserAccessRequests 获取当前用户提交的访问申请
+// @Summary 获取当前用户提交的访问申请
+// @Description 个人信息管理相关接口
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]model.AccessRequest}
+// @Router /api/v1/user/access_requests [get]
+func (a *accessRequest) GetUserAccessRequests(c *gin.Context) {
+
+	data, err := service.AccessRequest.GetUserAccessRequests(c.GetUint("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddAccessRequest 提交访问申请
+// @Summary 提交访问申请
+// @Description 个人信息管理相关接口，申请访问应用（site）或加入用户组（group），申请提交到外部ITSM中审批，审批通过后自动授权
+// @Tags 个人信息管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param data body service.AccessRequestCreate true "申请内容"
+// @Success 200 {object} MsgDataResult{data=model.AccessRequest} "提交成功"
+// @Router /api/v1/user/access_request [post]
+func (a *accessRequest) AddAccessRequest(c *gin.Context) {
+	var data = &service.AccessRequestCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	request, err := service.AccessRequest.AddAccessRequest(data, c.GetUint("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "提交成功", request)
+}
+
+// ITSMWebhook ITSM审批结果通知
+// @Summary ITSM审批结果通知
+// @Description 访问申请相关接口，ITSM在审批完成后调用，使用请求头 X-ITSM-Token 认证；仅触发查询审批结果，审批结果以ITSM接口返回的为准
+// @Tags 访问申请管理
+// @Accept application/json
+// @Produce application/json
+// @Param X-ITSM-Token header string true "系统配置中的itsmWebhookToken"
+// @Param data body service.ITSMWebhook true "工单信息"
+// @Success 200 {object} Result "处理成功"
+// @Router /api/v1/itsm/webhook [post]
+func (a *accessRequest) ITSMWebhook(c *gin.Context) {
+	var data = &service.ITSMWebhook{}
+
+	if !service.AccessRequest.VerifyWebhookToken(c.GetHeader("X-ITSM-Token")) {
+		Response(c, 90401, "token无效")
+		return
+	}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.AccessRequest.Webhook(data); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "处理成功")
+}
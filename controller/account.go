@@ -19,7 +19,7 @@ type account struct{}
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.AccountCreate true "账号信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Account} "创建成功"
 // @Router /api/v1/account [post]
 func (a *account) AddAccount(c *gin.Context) {
 	var account = &service.AccountCreate{}
@@ -32,7 +32,7 @@ func (a *account) AddAccount(c *gin.Context) {
 	userID := c.GetUint("id")
 	data, err := service.Account.AddAccount(account, userID)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -47,7 +47,7 @@ func (a *account) AddAccount(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.BatchAccountCreate true "账号信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=[]model.Account} "创建成功"
 // @Router /api/v1/accounts [post]
 func (a *account) AddAccounts(c *gin.Context) {
 	var account = &service.BatchAccountCreate{}
@@ -60,7 +60,7 @@ func (a *account) AddAccounts(c *gin.Context) {
 	userID := c.GetUint("id")
 	accounts, err := service.Account.AddAccounts(account.Accounts, userID)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -73,21 +73,21 @@ func (a *account) AddAccounts(c *gin.Context) {
 // @Tags 账号管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "账号ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/account/{id} [delete]
 func (a *account) DeleteAccount(c *gin.Context) {
 
 	// 获取账号ID
 	accountId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 获取用户ID
 	userID := c.GetUint("id")
 	if err := service.Account.DeleteAccount(accountId, int(userID)); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -100,7 +100,7 @@ func (a *account) DeleteAccount(c *gin.Context) {
 // @Tags 账号管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body dao.AccountUpdate true "账号信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Account} "更新成功"
 // @Router /api/v1/account [put]
 func (a *account) UpdateAccount(c *gin.Context) {
 	var data = &dao.AccountUpdate{}
@@ -113,7 +113,7 @@ func (a *account) UpdateAccount(c *gin.Context) {
 	userID := c.GetUint("id")
 	account, err := service.Account.UpdateAccount(data, userID)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -127,7 +127,7 @@ func (a *account) UpdateAccount(c *gin.Context) {
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param account_ids query []int true "账号ID列表"
 // @Param new_owner_id query int true "新所有者ID"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=[]model.Account} "更新成功"
 // @Router /api/v1/account/owners [put]
 func (a *account) BatchUpdateAccountOwner(c *gin.Context) {
 	var data struct {
@@ -143,7 +143,7 @@ func (a *account) BatchUpdateAccountOwner(c *gin.Context) {
 	oldOwnerID := c.GetUint("id")
 	accounts, err := service.Account.BatchUpdateAccountOwner(data.Accounts, oldOwnerID, data.OwnerUserID)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -156,7 +156,7 @@ func (a *account) BatchUpdateAccountOwner(c *gin.Context) {
 // @Tags 账号管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param users body dao.AccountUpdateUser true "用户ID列表"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Account} "更新成功"
 // @Router /api/v1/account/users [put]
 func (a *account) UpdateAccountUser(c *gin.Context) {
 	var data = &dao.AccountUpdateUser{}
@@ -171,7 +171,7 @@ func (a *account) UpdateAccountUser(c *gin.Context) {
 	userId := c.GetUint("id")
 	account, err := service.Account.UpdateAccountUser(data, userId)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -184,7 +184,7 @@ func (a *account) UpdateAccountUser(c *gin.Context) {
 // @Tags 账号管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body dao.AccountUpdatePassword true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} Result "更新成功"
 // @Router /api/v1/account/password [put]
 func (a *account) UpdatePassword(c *gin.Context) {
 	var account = &dao.AccountUpdatePassword{}
@@ -198,7 +198,7 @@ func (a *account) UpdatePassword(c *gin.Context) {
 	// 更新用户信息
 	userId := c.GetUint("id")
 	if err := service.Account.UpdatePassword(account, userId); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -213,7 +213,7 @@ func (a *account) UpdatePassword(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "账号信息"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.AccountList}
 // @Router /api/v1/accounts [get]
 func (a *account) GetAccountList(c *gin.Context) {
 	params := new(struct {
@@ -229,7 +229,7 @@ func (a *account) GetAccountList(c *gin.Context) {
 	userID := c.GetUint("id")
 	data, err := service.Account.GetAccountList(params.Name, userID, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -245,7 +245,7 @@ func (a *account) GetAccountList(c *gin.Context) {
 // @Tags 账号管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "账号ID"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=string}
 // @Router /api/v1/account/password/{id} [delete]
 func (a *account) GetAccountPassword(c *gin.Context) {
 
@@ -260,7 +260,7 @@ func (a *account) GetAccountPassword(c *gin.Context) {
 	userId := c.GetUint("id")
 	password, err := service.Account.GetAccountPassword(uint(accountID), username.(string), userId)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -278,7 +278,7 @@ func (a *account) GetAccountPassword(c *gin.Context) {
 // @Accept application/json
 // @Produce application/json
 // @Param user body service.GetVerification true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "校验码已发送，5分钟之内有效"}"
+// @Success 200 {object} Result "校验码已发送，5分钟之内有效"
 // @Router /api/v1/account/code [post]
 func (a *account) GetSMSCode(c *gin.Context) {
 
@@ -293,7 +293,7 @@ func (a *account) GetSMSCode(c *gin.Context) {
 	// 获取短信验证码
 	userID := c.GetUint("id")
 	if err := service.Account.GetSMSCode(data, userID); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -308,7 +308,7 @@ func (a *account) GetSMSCode(c *gin.Context) {
 // @Description 账号相关接口
 // @Tags 账号管理
 // @Param user body service.CodeVerification true "验证码信息"
-// @Success 200 {string} json "{"code": 0, "msg": "验证成功，本次验证有效期为10分钟"}"
+// @Success 200 {object} Result "验证成功，本次验证有效期为10分钟"
 // @Router /api/v1/account/code_verification [post]
 func (a *account) CodeVerification(c *gin.Context) {
 	var data = &service.CodeVerification{}
@@ -322,7 +322,7 @@ func (a *account) CodeVerification(c *gin.Context) {
 	// 更新用户信息
 	userID := c.GetUint("id")
 	if err := service.Account.CodeVerification(userID, data); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
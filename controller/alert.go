@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/service"
+)
+
+var Alert alert
+
+type alert struct{}
+
+// GetAlertList 获取内置告警状态
+// @Summary 获取内置告警状态
+// @Description 告警相关接口，返回登录失败激增、短信发送失败率、证书过期、定时任务未执行等内置告警规则的当前值、阈值及是否正在触发，阈值为0的规则不返回
+// @Tags 告警相关接口
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]service.AlertStatus}
+// @Router /api/v1/alerts [get]
+func (a *alert) GetAlertList(c *gin.Context) {
+
+	data, err := service.Alert.GetAlertList()
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetAlertRules 导出Prometheus告警规则
+// @Summary 导出Prometheus告警规则
+// @Description 告警相关接口，导出内置告警规则对应的Prometheus告警规则文件（YAML），阈值使用当前配置，已部署Prometheus时可直接加载
+// @Tags 告警相关接口
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {string} string "Prometheus告警规则"
+// @Router /api/v1/alert/rules [get]
+func (a *alert) GetAlertRules(c *gin.Context) {
+
+	data, err := service.Alert.PrometheusRules()
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=idsphere-alerts.yaml")
+	c.Data(http.StatusOK, "application/x-yaml; charset=utf-8", data)
+}
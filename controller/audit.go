@@ -3,7 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
-	"net/http"
+	"ops-api/middleware"
 	"ops-api/service"
 )
 
@@ -19,7 +19,7 @@ type audit struct{}
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param receiver query string false "电话号码"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.SMSRecordList}
 // @Router /api/v1/audit/sms [get]
 func (l *audit) GetSMSRecord(c *gin.Context) {
 
@@ -30,7 +30,7 @@ func (l *audit) GetSMSRecord(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -41,7 +41,7 @@ func (l *audit) GetSMSRecord(c *gin.Context) {
 
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
@@ -60,7 +60,7 @@ func (l *audit) GetSMSRecord(c *gin.Context) {
 // @Tags 登录日志管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id query int true "短信记录ID"
-// @Success 200 {string} json "{"code": 0}"
+// @Success 200 {object} Result
 // @Router /api/v1/audit/sms/receipt [get]
 func (l *audit) GetSMSReceipt(c *gin.Context) {
 	params := new(struct {
@@ -68,7 +68,7 @@ func (l *audit) GetSMSReceipt(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -78,7 +78,7 @@ func (l *audit) GetSMSReceipt(c *gin.Context) {
 	err := service.Audit.GetSMSReceipt(params.Id)
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
@@ -98,7 +98,7 @@ func (l *audit) GetSMSReceipt(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "关键字"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.LoginRecordList}
 // @Router /api/v1/audit/login [get]
 func (l *audit) GetLoginRecord(c *gin.Context) {
 
@@ -109,7 +109,7 @@ func (l *audit) GetLoginRecord(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -120,7 +120,7 @@ func (l *audit) GetLoginRecord(c *gin.Context) {
 
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
@@ -141,7 +141,7 @@ func (l *audit) GetLoginRecord(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "关键字"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.OplogList}
 // @Router /api/v1/audit/oplog [get]
 func (l *audit) GetOplog(c *gin.Context) {
 
@@ -152,7 +152,7 @@ func (l *audit) GetOplog(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -163,7 +163,50 @@ func (l *audit) GetOplog(c *gin.Context) {
 
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
+			"code": 90500,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetSCIMRecord 获取SCIM同步记录
+// @Summary 获取SCIM同步记录
+// @Description 审计相关接口
+// @Tags SCIM同步日志管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "关键字"
+// @Success 200 {object} DataResult{data=dao.SCIMRecordList}
+// @Router /api/v1/audit/scim [get]
+func (l *audit) GetSCIMRecord(c *gin.Context) {
+
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		logger.Error("ERROR：" + err.Error())
+		c.JSON(middleware.StatusCode(90400), gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	data, err := service.Audit.GetSCIMRecordList(params.Name, params.Page, params.Limit)
+
+	if err != nil {
+		logger.Error("ERROR：" + err.Error())
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
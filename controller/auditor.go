@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/service"
+	"strconv"
+)
+
+var Auditor auditor
+
+type auditor struct{}
+
+// GetAuditorGrantList 获取审计员列表（表格）
+// @Summary 获取审计员列表（表格）
+// @Description 审计员相关接口
+// @Tags 审计员管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param name query string false "用户名或审计事由"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Success 200 {object} DataResult{data=dao.AuditorGrantList}
+// @Router /api/v1/auditors [get]
+func (a *auditor) GetAuditorGrantList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Auditor.GetAuditorGrantList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddAuditorGrant 授予审计员
+// @Summary 授予审计员
+// @Description 审计员相关接口，有效期内用户只能以只读方式查看用户、权限、登录记录及系统配置，返回数据中的个人信息脱敏，所有查看操作记录到操作日志中
+// @Tags 审计员管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param data body service.AuditorGrantCreate true "审计员信息"
+// @Success 200 {object} MsgDataResult{data=model.AuditorGrant} "授予成功"
+// @Router /api/v1/auditor [post]
+func (a *auditor) AddAuditorGrant(c *gin.Context) {
+	var data = &service.AuditorGrantCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	grant, err := service.Auditor.AddAuditorGrant(data, c.GetString("username"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "授予成功", grant)
+}
+
+// RevokeAuditorGrant 撤销审计员
+// @Summary 撤销审计员
+// @Description 审计员相关接口，提前撤销审计员授权
+// @Tags 审计员管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "审计员授权ID"
+// @Success 200 {object} Result "撤销成功"
+// @Router /api/v1/auditor/{id}/revoke [put]
+func (a *auditor) RevokeAuditorGrant(c *gin.Context) {
+
+	// 对ID进行类型转换
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Auditor.RevokeAuditorGrant(uint(id), c.GetString("username")); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "撤销成功", nil)
+}
@@ -20,7 +20,7 @@ type certificate struct{}
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.DomainCertificateRequest true "申请证书信息"
-// @Success 200 {string} json "{"code": 0, "msg": "请求成功", "data": nil}"
+// @Success 200 {object} Result "请求成功"
 // @Router /api/v1/certificate/request [post]
 func (cert *certificate) RequestDomainCertificate(c *gin.Context) {
 
@@ -35,7 +35,7 @@ func (cert *certificate) RequestDomainCertificate(c *gin.Context) {
 		// 获取当前登录用户信息
 		user, err := service.User.GetUser(c.GetUint("id"))
 		if err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
 			return
 		}
 		data.Email = &user.Email
@@ -46,7 +46,7 @@ func (cert *certificate) RequestDomainCertificate(c *gin.Context) {
 	}
 
 	if err := service.Certificate.RequestDomainCertificate(data); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -61,7 +61,7 @@ func (cert *certificate) RequestDomainCertificate(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.DomainCertificateCreate true "域名证书信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.DomainCertificate} "创建成功"
 // @Router /api/v1/certificate/upload [post]
 func (cert *certificate) UploadDomainCertificate(c *gin.Context) {
 	var provider = &service.DomainCertificateCreate{}
@@ -73,7 +73,7 @@ func (cert *certificate) UploadDomainCertificate(c *gin.Context) {
 
 	data, err := service.Certificate.UploadDomainCertificate(provider)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -86,18 +86,18 @@ func (cert *certificate) UploadDomainCertificate(c *gin.Context) {
 // @Tags 域名证书相关
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "域名证书ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/certificate/{id} [delete]
 func (cert *certificate) DeleteDomainCertificate(c *gin.Context) {
 
 	certId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	if err := service.Certificate.DeleteDomainCertificate(certId); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -112,7 +112,7 @@ func (cert *certificate) DeleteDomainCertificate(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "域名信息"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.DomainCertificateList}
 // @Router /api/v1/certificates [get]
 func (cert *certificate) GetDomainCertificateList(c *gin.Context) {
 
@@ -129,7 +129,7 @@ func (cert *certificate) GetDomainCertificateList(c *gin.Context) {
 
 	data, err := service.Certificate.GetDomainCertificateList(params.Name, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -150,13 +150,13 @@ func (cert *certificate) DownloadDomainCertificate(c *gin.Context) {
 
 	certId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	zip, name, err := service.Certificate.DownloadDomainCertificate(certId)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+)
+
+var Conformance conformance
+
+type conformance struct{}
+
+// RunConformance 执行协议一致性测试
+// @Summary 执行协议一致性测试
+// @Description 协议一致性测试相关接口，对OIDC发现、JWKS、Token、UserInfo，CAS票据校验及SAML2、WS-Fed元数据接口按规范进行检查，返回每项能力的检查结果
+// @Tags 协议一致性测试
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param data body service.ConformanceRun false "需要测试的能力"
+// @Success 200 {object} DataResult{data=conformance.Report}
+// @Router /api/v1/conformance/run [post]
+func (t *conformance) RunConformance(c *gin.Context) {
+	var data = &service.ConformanceRun{}
+
+	// 请求体可以为空
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(data); err != nil {
+			Response(c, 90400, err.Error())
+			return
+		}
+	}
+
+	report, err := service.Conformance.Run(data)
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": report,
+	})
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"ops-api/middleware"
+	"ops-api/service"
+	"strconv"
+)
+
+var Consent consent
+
+type consent struct{}
+
+// consentRequired 单点登录授权需要用户确认时返回需要确认的Scope，前端展示授权确认页面并携带用户的选择（consent）重新发起授权
+func consentRequired(c *gin.Context, err error) bool {
+	var consentErr *service.ConsentRequiredError
+	if !errors.As(err, &consentErr) {
+		return false
+	}
+	c.JSON(middleware.StatusCode(90428), gin.H{
+		"code": 90428,
+		"msg":  consentErr.Error(),
+		"data": consentErr,
+	})
+	return true
+}
+
+// GetUserConsents 获取当前用户的应用授权记录
+// @Summary 获取当前用户的应用授权记录
+// @Description 个人信息管理相关接口
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]dao.UserConsentItem}
+// @Router /api/v1/user/consents [get]
+func (cs *consent) GetUserConsents(c *gin.Context) {
+
+	data, err := service.Consent.GetUserConsents(c.GetUint("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// RevokeUserConsent 撤销当前用户对应用的授权
+// @Summary 撤销当前用户对应用的授权
+// @Description 个人信息管理相关接口，同时注销该应用的离线访问授权，再次访问应用时需要重新确认授权
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "授权记录ID"
+// @Success 200 {object} Result "撤销成功"
+// @Router /api/v1/user/consent/{id} [delete]
+func (cs *consent) RevokeUserConsent(c *gin.Context) {
+
+	// 对ID进行类型转换
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Consent.Revoke(c.GetUint("id"), uint(id)); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "撤销成功", nil)
+}
@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"ops-api/service"
+	"strings"
+	"time"
+)
+
+var ConsoleEvent consoleEvent
+
+type consoleEvent struct{}
+
+// GetConsoleEvents 订阅管理控制台实时事件
+// @Summary 订阅管理控制台实时事件
+// @Description 管理控制台相关接口，使用Server-Sent Events实时推送登录成功（login）、登录失败（login_failed）、告警触发及恢复（alert）、定时任务执行完成（task）、新的访问申请（access_request）等事件，事件名称为事件类型，无事件时每30秒发送一次心跳
+// @Tags 管理控制台相关接口
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param types query string false "订阅的事件类型，多个以逗号分隔，为空时订阅所有类型"
+// @Produce text/event-stream
+// @Success 200 {string} string "事件流"
+// @Router /api/v1/console/events [get]
+func (e *consoleEvent) GetConsoleEvents(c *gin.Context) {
+
+	var types []string
+	if value := c.Query("types"); value != "" {
+		types = strings.Split(value, ",")
+	}
+
+	client := service.ConsoleEvent.Subscribe(types)
+	defer service.ConsoleEvent.Unsubscribe(client)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // 禁止Nginx缓冲响应
+	c.Status(http.StatusOK)
+
+	heartbeat := time.NewTicker(service.ConsoleEventHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-client.Events:
+			c.SSEvent(event.Type, gin.H{
+				"id":   event.ID,
+				"type": event.Type,
+				"data": event.Data,
+				"time": event.Time,
+			})
+		case <-heartbeat.C:
+			c.SSEvent("heartbeat", time.Now().Unix())
+		}
+		return true
+	})
+}
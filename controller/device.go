@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"ops-api/service"
+	"strconv"
+)
+
+var Device device
+
+type device struct{}
+
+// recordDevice 登录成功后记录登录设备，记录失败不影响用户登录
+func recordDevice(c *gin.Context, username string) {
+	if err := service.Device.Record(username, service.NewDeviceInfo(c.Request.Header), c.ClientIP()); err != nil {
+		logger.Error("登录设备记录失败：" + err.Error())
+	}
+}
+
+// GetDeviceList 获取登录设备列表（表格）
+// @Summary 获取登录设备列表（表格）
+// @Description 登录设备相关接口
+// @Tags 用户管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param username query string false "用户名"
+// @Success 200 {object} DataResult{data=dao.DeviceList}
+// @Router /api/v1/devices [get]
+func (d *device) GetDeviceList(c *gin.Context) {
+	params := new(struct {
+		Username string `form:"username"`
+		Page     int    `form:"page" binding:"required"`
+		Limit    int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Device.GetDeviceList(params.Username, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// WipeDevice 擦除登录设备
+// @Summary 擦除登录设备
+// @Description 登录设备相关接口，注销设备上在线的登录会话及离线访问（offline_access）的刷新令牌，用于设备丢失等场景
+// @Tags 用户管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "设备ID"
+// @Success 200 {object} Result "擦除成功"
+// @Router /api/v1/device/{id}/wipe [post]
+func (d *device) WipeDevice(c *gin.Context) {
+
+	// 对ID进行类型转换
+	deviceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Device.Wipe(uint(deviceID)); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "擦除成功", nil)
+}
+
+// GetUserDevices 获取当前用户的登录设备
+// @Summary 获取当前用户的登录设备
+// @Description 个人信息管理相关接口
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]model.UserDevice}
+// @Router /api/v1/user/devices [get]
+func (d *device) GetUserDevices(c *gin.Context) {
+
+	data, err := service.Device.GetUserDevices(c.GetUint("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// DeleteUserDevice 删除当前用户的登录设备
+// @Summary 删除当前用户的登录设备
+// @Description 个人信息管理相关接口
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "设备ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/user/device/{id} [delete]
+func (d *device) DeleteUserDevice(c *gin.Context) {
+
+	// 对ID进行类型转换
+	deviceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Device.DeleteUserDevice(c.GetUint("id"), uint(deviceID)); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "删除成功", nil)
+}
+
+// SecureAccount 保护账号
+// @Summary 保护账号
+// @Description 个人信息管理相关接口，用户通过登录提醒邮件确认登录非本人操作后调用，注销所有登录会话并要求下次登录时修改密码
+// @Tags 个人信息管理
+// @Param token body object true "登录提醒邮件中的令牌"
+// @Success 200 {object} Result "账号已保护"
+// @Router /api/v1/secure_account [post]
+func (d *device) SecureAccount(c *gin.Context) {
+	params := new(struct {
+		Token string `json:"token" binding:"required"`
+	})
+	if err := c.ShouldBindJSON(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Device.SecureAccount(params.Token, c.ClientIP()); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"msg":  "已在所有设备上退出登录，请在下次登录时修改密码",
+	})
+}
@@ -19,7 +19,7 @@ type domain struct{}
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.DomainServiceProviderCreate true "域名服务商信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.DomainServiceProvider} "创建成功"
 // @Router /api/v1/domain/provider [post]
 func (d *domain) AddDomainServiceProvider(c *gin.Context) {
 	var provider = &service.DomainServiceProviderCreate{}
@@ -31,7 +31,7 @@ func (d *domain) AddDomainServiceProvider(c *gin.Context) {
 
 	data, err := service.Domain.AddDomainServiceProvider(provider)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -44,18 +44,18 @@ func (d *domain) AddDomainServiceProvider(c *gin.Context) {
 // @Tags 域名管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "域名服务商ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/domain/provider/{id} [delete]
 func (d *domain) DeleteDomainServiceProvider(c *gin.Context) {
 
 	providerId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	if err := service.Domain.DeleteDomainServiceProvider(providerId); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -68,7 +68,7 @@ func (d *domain) DeleteDomainServiceProvider(c *gin.Context) {
 // @Tags 域名管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body dao.ProviderUpdate true "服务商信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.DomainServiceProvider} "更新成功"
 // @Router /api/v1/domain/provider [put]
 func (d *domain) UpdateDomainServiceProvider(c *gin.Context) {
 	var data = &dao.ProviderUpdate{}
@@ -79,7 +79,7 @@ func (d *domain) UpdateDomainServiceProvider(c *gin.Context) {
 
 	provider, err := service.Domain.UpdateDomainServiceProviderList(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -91,13 +91,13 @@ func (d *domain) UpdateDomainServiceProvider(c *gin.Context) {
 // @Description 域名相关
 // @Tags 域名管理
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=[]model.DomainServiceProvider}
 // @Router /api/v1/domain/providers [get]
 func (d *domain) GetDomainServiceProviderList(c *gin.Context) {
 
 	data, err := service.Domain.GetDomainServiceProviderList()
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -115,7 +115,7 @@ func (d *domain) GetDomainServiceProviderList(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.DomainCreate true "域名服务商信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Domain} "创建成功"
 // @Router /api/v1/domain [post]
 func (d *domain) AddDomain(c *gin.Context) {
 	var domain = &service.DomainCreate{}
@@ -127,7 +127,7 @@ func (d *domain) AddDomain(c *gin.Context) {
 
 	data, err := service.Domain.AddDomain(domain)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -140,18 +140,18 @@ func (d *domain) AddDomain(c *gin.Context) {
 // @Tags 域名管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "域名ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/domain/{id} [delete]
 func (d *domain) DeleteDomain(c *gin.Context) {
 
 	providerId, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	if err := service.Domain.DeleteDomain(providerId); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -164,7 +164,7 @@ func (d *domain) DeleteDomain(c *gin.Context) {
 // @Tags 域名管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body dao.DomainUpdate true "域名信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Domain} "更新成功"
 // @Router /api/v1/domain [put]
 func (d *domain) UpdateDomain(c *gin.Context) {
 	var data = &dao.DomainUpdate{}
@@ -175,7 +175,7 @@ func (d *domain) UpdateDomain(c *gin.Context) {
 
 	provider, err := service.Domain.UpdateDomain(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -191,7 +191,7 @@ func (d *domain) UpdateDomain(c *gin.Context) {
 // @Param limit query int true "分页大小"
 // @Param provider_id query int false "域名服务提供商"
 // @Param name query string false "域名信息"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.DomainList}
 // @Router /api/v1/domains [get]
 func (d *domain) GetDomainList(c *gin.Context) {
 
@@ -209,7 +209,7 @@ func (d *domain) GetDomainList(c *gin.Context) {
 
 	data, err := service.Domain.GetDomainList(params.Name, params.ProviderId, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -227,7 +227,7 @@ func (d *domain) GetDomainList(c *gin.Context) {
 // @Produce application/json
 // @Param provider_id query int true "域名服务提供商ID"
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "msg": "同步完成"}"
+// @Success 200 {object} Result "同步完成"
 // @Router /api/v1/domain/sync [post]
 func (d *domain) SyncDomain(c *gin.Context) {
 
@@ -241,7 +241,7 @@ func (d *domain) SyncDomain(c *gin.Context) {
 	}
 
 	if err := service.Domain.SyncDomain(params.ProviderId); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -257,7 +257,7 @@ func (d *domain) SyncDomain(c *gin.Context) {
 // @Param limit query int true "分页大小"
 // @Param ID query uint true "域名 id"
 // @Param KeyWord query string false "关键字"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=public_cloud.DnsList}
 // @Router /api/v1/domain/dns [get]
 func (d *domain) GetDomainDnsList(c *gin.Context) {
 
@@ -275,7 +275,7 @@ func (d *domain) GetDomainDnsList(c *gin.Context) {
 
 	data, err := service.Domain.GetDnsList(params.KeyWord, params.ID, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -293,7 +293,7 @@ func (d *domain) GetDomainDnsList(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.DnsCreate true "DNS记录信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} Result "创建成功"
 // @Router /api/v1/domain/dns [post]
 func (d *domain) AddDomainDns(c *gin.Context) {
 
@@ -305,7 +305,7 @@ func (d *domain) AddDomainDns(c *gin.Context) {
 	}
 
 	if err := service.Domain.AddDns(dns); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -318,7 +318,7 @@ func (d *domain) AddDomainDns(c *gin.Context) {
 // @Tags 域名管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body service.DnsUpdate true "域名信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功"}"
+// @Success 200 {object} Result "更新成功"
 // @Router /api/v1/domain/dns [put]
 func (d *domain) UpdateDns(c *gin.Context) {
 	var data = &service.DnsUpdate{}
@@ -328,7 +328,7 @@ func (d *domain) UpdateDns(c *gin.Context) {
 	}
 
 	if err := service.Domain.UpdateDomainDns(data); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -341,7 +341,7 @@ func (d *domain) UpdateDns(c *gin.Context) {
 // @Tags 域名管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body service.DnsDelete true "域名信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功"}"
+// @Success 200 {object} Result "更新成功"
 // @Router /api/v1/domain/dns [delete]
 func (d *domain) DeleteDns(c *gin.Context) {
 	var data = &service.DnsDelete{}
@@ -351,7 +351,7 @@ func (d *domain) DeleteDns(c *gin.Context) {
 	}
 
 	if err := service.Domain.DeleteDns(data); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -366,7 +366,7 @@ func (d *domain) DeleteDns(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.SetDnsStatus true "DN状态信息"
-// @Success 200 {string} json "{"code": 0, "msg": "设置成功", "data": nil}"
+// @Success 200 {object} Result "设置成功"
 // @Router /api/v1/domain/dns_status [put]
 func (d *domain) SetDomainStatus(c *gin.Context) {
 
@@ -378,7 +378,7 @@ func (d *domain) SetDomainStatus(c *gin.Context) {
 	}
 
 	if err := service.Domain.SetDnsStatus(dns); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"ops-api/service"
+)
+
+var External external
+
+type external struct{}
+
+// externalErrorResponse 返回外部资源接口错误信息，使用对应的HTTP状态码，便于IaC工具识别冲突及前置条件失败
+func externalErrorResponse(c *gin.Context, err error) {
+	var externalErr *service.ExternalError
+	if !errors.As(err, &externalErr) {
+		externalErr = service.NewExternalError(http.StatusInternalServerError, err.Error())
+	}
+	logger.Error("ERROR：" + externalErr.Error())
+
+	response := map[string]interface{}{
+		"code": 90000 + externalErr.StatusCode(),
+		"msg":  externalErr.Error(),
+	}
+	c.Set("response", response)
+	c.JSON(externalErr.StatusCode(), response)
+}
+
+// externalResponse 返回外部资源及ETag，新建资源时返回201
+func externalResponse(c *gin.Context, data interface{}, etag string, created bool) {
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.Header("ETag", etag)
+	c.JSON(status, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// externalPreconditions 获取条件请求头
+func externalPreconditions(c *gin.Context) *service.Preconditions {
+	return &service.Preconditions{
+		IfMatch:     c.GetHeader("If-Match"),
+		IfNoneMatch: c.GetHeader("If-None-Match"),
+	}
+}
+
+// GetUser 根据外部标识获取用户
+// @Summary 根据外部标识获取用户
+// @Description 外部资源相关接口，响应头ETag用于并发控制
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Success 200 {object} DataResult{data=service.ExternalUser}
+// @Router /api/v1/external/user/{external_id} [get]
+func (e *external) GetUser(c *gin.Context) {
+	data, etag, err := service.External.GetUser(c.Param("external_id"))
+	if err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	externalResponse(c, data, etag, false)
+}
+
+// PutUser 根据外部标识创建或更新用户
+// @Summary 根据外部标识创建或更新用户
+// @Description 外部资源相关接口，外部标识不存在时纳管同用户名的用户，If-Match 与当前ETag不一致或 If-None-Match: * 且资源已存在时返回412
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Param If-Match header string false "资源ETag"
+// @Param If-None-Match header string false "仅在资源不存在时创建，值为*"
+// @Param user body service.ExternalUser true "用户信息"
+// @Success 200 {object} DataResult{data=service.ExternalUser}
+// @Router /api/v1/external/user/{external_id} [put]
+func (e *external) PutUser(c *gin.Context) {
+	var data = &service.ExternalUser{}
+	if err := c.ShouldBind(&data); err != nil {
+		externalErrorResponse(c, service.NewExternalError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	user, etag, created, err := service.External.PutUser(c.Param("external_id"), externalPreconditions(c), data)
+	if err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	externalResponse(c, user, etag, created)
+}
+
+// DeleteUser 根据外部标识删除用户
+// @Summary 根据外部标识删除用户
+// @Description 外部资源相关接口
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Param If-Match header string false "资源ETag"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/external/user/{external_id} [delete]
+func (e *external) DeleteUser(c *gin.Context) {
+	if err := service.External.DeleteUser(c.Param("external_id"), externalPreconditions(c)); err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	Response(c, 0, "删除成功")
+}
+
+// GetGroup 根据外部标识获取分组
+// @Summary 根据外部标识获取分组
+// @Description 外部资源相关接口，响应头ETag用于并发控制
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Success 200 {object} DataResult{data=service.ExternalGroup}
+// @Router /api/v1/external/group/{external_id} [get]
+func (e *external) GetGroup(c *gin.Context) {
+	data, etag, err := service.External.GetGroup(c.Param("external_id"))
+	if err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	externalResponse(c, data, etag, false)
+}
+
+// PutGroup 根据外部标识创建或更新分组
+// @Summary 根据外部标识创建或更新分组
+// @Description 外部资源相关接口，外部标识不存在时纳管同名分组，If-Match 与当前ETag不一致或 If-None-Match: * 且资源已存在时返回412
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Param If-Match header string false "资源ETag"
+// @Param If-None-Match header string false "仅在资源不存在时创建，值为*"
+// @Param group body service.ExternalGroup true "分组信息"
+// @Success 200 {object} DataResult{data=service.ExternalGroup}
+// @Router /api/v1/external/group/{external_id} [put]
+func (e *external) PutGroup(c *gin.Context) {
+	var data = &service.ExternalGroup{}
+	if err := c.ShouldBind(&data); err != nil {
+		externalErrorResponse(c, service.NewExternalError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	group, etag, created, err := service.External.PutGroup(c.Param("external_id"), externalPreconditions(c), data)
+	if err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	externalResponse(c, group, etag, created)
+}
+
+// DeleteGroup 根据外部标识删除分组
+// @Summary 根据外部标识删除分组
+// @Description 外部资源相关接口
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Param If-Match header string false "资源ETag"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/external/group/{external_id} [delete]
+func (e *external) DeleteGroup(c *gin.Context) {
+	if err := service.External.DeleteGroup(c.Param("external_id"), externalPreconditions(c)); err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	Response(c, 0, "删除成功")
+}
+
+// GetSite 根据外部标识获取站点
+// @Summary 根据外部标识获取站点
+// @Description 外部资源相关接口，响应头ETag用于并发控制
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Success 200 {object} DataResult{data=service.ExternalSite}
+// @Router /api/v1/external/site/{external_id} [get]
+func (e *external) GetSite(c *gin.Context) {
+	data, etag, err := service.External.GetSite(c.Param("external_id"))
+	if err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	externalResponse(c, data, etag, false)
+}
+
+// PutSite 根据外部标识创建或更新站点
+// @Summary 根据外部标识创建或更新站点
+// @Description 外部资源相关接口，外部标识不存在时纳管唯一同名站点，If-Match 与当前ETag不一致或 If-None-Match: * 且资源已存在时返回412
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Param If-Match header string false "资源ETag"
+// @Param If-None-Match header string false "仅在资源不存在时创建，值为*"
+// @Param site body service.ExternalSite true "站点信息"
+// @Success 200 {object} DataResult{data=service.ExternalSite}
+// @Router /api/v1/external/site/{external_id} [put]
+func (e *external) PutSite(c *gin.Context) {
+	var data = &service.ExternalSite{}
+	if err := c.ShouldBind(&data); err != nil {
+		externalErrorResponse(c, service.NewExternalError(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	site, etag, created, err := service.External.PutSite(c.Param("external_id"), externalPreconditions(c), data)
+	if err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	externalResponse(c, site, etag, created)
+}
+
+// DeleteSite 根据外部标识删除站点
+// @Summary 根据外部标识删除站点
+// @Description 外部资源相关接口
+// @Tags 外部资源管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param external_id path string true "外部标识"
+// @Param If-Match header string false "资源ETag"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/external/site/{external_id} [delete]
+func (e *external) DeleteSite(c *gin.Context) {
+	if err := service.External.DeleteSite(c.Param("external_id"), externalPreconditions(c)); err != nil {
+		externalErrorResponse(c, err)
+		return
+	}
+	Response(c, 0, "删除成功")
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+	"strconv"
+)
+
+var FeatureFlag featureFlag
+
+type featureFlag struct{}
+
+// FeatureEnabled 判断功能是否对当前登录用户开放，供其它接口根据功能开关调整处理逻辑
+func FeatureEnabled(c *gin.Context, key string) bool {
+	return service.FeatureFlag.IsEnabled(key, c.GetUint("id"), c.GetString("username"))
+}
+
+// GetFeatureFlagList 获取功能开关列表（表格）
+// @Summary 获取功能开关列表（表格）
+// @Description 功能开关相关接口
+// @Tags 功能开关管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "功能名称或标识"
+// @Success 200 {object} DataResult{data=dao.FeatureFlagList}
+// @Router /api/v1/feature_flags [get]
+func (f *featureFlag) GetFeatureFlagList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.FeatureFlag.GetFeatureFlagList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddFeatureFlag 创建功能开关
+// @Summary 创建功能开关
+// @Description 功能开关相关接口
+// @Tags 功能开关管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param flag body service.FeatureFlagCreate true "功能开关信息"
+// @Success 200 {object} MsgDataResult{data=model.FeatureFlag} "创建成功"
+// @Router /api/v1/feature_flag [post]
+func (f *featureFlag) AddFeatureFlag(c *gin.Context) {
+	var data = &service.FeatureFlagCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	flag, err := service.FeatureFlag.AddFeatureFlag(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", flag)
+}
+
+// UpdateFeatureFlag 更新功能开关
+// @Summary 更新功能开关
+// @Description 功能开关相关接口
+// @Tags 功能开关管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param flag body service.FeatureFlagUpdate true "功能开关信息"
+// @Success 200 {object} MsgDataResult{data=model.FeatureFlag} "更新成功"
+// @Router /api/v1/feature_flag [put]
+func (f *featureFlag) UpdateFeatureFlag(c *gin.Context) {
+	var data = &service.FeatureFlagUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	flag, err := service.FeatureFlag.UpdateFeatureFlag(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", flag)
+}
+
+// DeleteFeatureFlag 删除功能开关
+// @Summary 删除功能开关
+// @Description 功能开关相关接口
+// @Tags 功能开关管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "功能开关ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/feature_flag/{id} [delete]
+func (f *featureFlag) DeleteFeatureFlag(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.FeatureFlag.DeleteFeatureFlag(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
+
+// GetUserFeatures 获取对当前用户开放的功能
+// @Summary 获取对当前用户开放的功能
+// @Description 功能开关相关接口
+// @Tags 功能开关管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]string}
+// @Router /api/v1/user/features [get]
+func (f *featureFlag) GetUserFeatures(c *gin.Context) {
+
+	data, err := service.FeatureFlag.GetUserFeatures(c.GetUint("id"), c.GetString("username"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
@@ -18,7 +18,7 @@ type group struct{}
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "组名称"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.GroupList}
 // @Router /api/v1/groups [get]
 func (u *group) GetGroupList(c *gin.Context) {
 	params := new(struct {
@@ -33,7 +33,7 @@ func (u *group) GetGroupList(c *gin.Context) {
 
 	data, err := service.Group.GetGroupList(params.Name, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -51,7 +51,7 @@ func (u *group) GetGroupList(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param group body service.GroupCreate true "组信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.AuthGroup} "创建成功"
 // @Router /api/v1/group [post]
 func (u *group) AddGroup(c *gin.Context) {
 	var group = &service.GroupCreate{}
@@ -63,7 +63,7 @@ func (u *group) AddGroup(c *gin.Context) {
 
 	authGroup, err := service.Group.AddGroup(group)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -76,20 +76,20 @@ func (u *group) AddGroup(c *gin.Context) {
 // @Tags 组管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "组ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/group/{id} [delete]
 func (u *group) DeleteGroup(c *gin.Context) {
 
 	// 对ID进行类型转换
 	groupID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 执行删除
 	if err := service.Group.DeleteGroup(groupID); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -102,7 +102,7 @@ func (u *group) DeleteGroup(c *gin.Context) {
 // @Tags 组管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param group body service.GroupUpdate true "组信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.AuthGroup} "更新成功"
 // @Router /api/v1/group [put]
 func (u *group) UpdateGroup(c *gin.Context) {
 	var data = &service.GroupUpdate{}
@@ -116,7 +116,7 @@ func (u *group) UpdateGroup(c *gin.Context) {
 	// 更新用户信息
 	result, err := service.Group.UpdateGroup(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -129,7 +129,7 @@ func (u *group) UpdateGroup(c *gin.Context) {
 // @Tags 组管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param users body service.GroupUpdateUser true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.AuthGroup} "更新成功"
 // @Router /api/v1/group/users [put]
 func (u *group) UpdateGroupUser(c *gin.Context) {
 	var data = &service.GroupUpdateUser{}
@@ -141,9 +141,9 @@ func (u *group) UpdateGroupUser(c *gin.Context) {
 	}
 
 	// 更新用户信息
-	group, err := service.Group.UpdateGroupUser(data)
+	group, err := service.Group.UpdateGroupUser(data, c.GetString("username"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -156,7 +156,7 @@ func (u *group) UpdateGroupUser(c *gin.Context) {
 // @Tags 组管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param users body service.GroupUpdatePermission true "权限名称"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} Result "更新成功"
 // @Router /api/v1/group/permissions [put]
 func (u *group) UpdateGroupPermission(c *gin.Context) {
 	var data = &service.GroupUpdatePermission{}
@@ -169,7 +169,7 @@ func (u *group) UpdateGroupPermission(c *gin.Context) {
 
 	// 更新用户信息
 	if err := service.Group.UpdateGroupPermission(data); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
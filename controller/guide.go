@@ -0,0 +1,195 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/service"
+	"strconv"
+)
+
+var Guide guide
+
+type guide struct{}
+
+// GetGuideStepList 获取引导步骤列表（表格）
+// @Summary 获取引导步骤列表（表格）
+// @Description 站点引导相关接口
+// @Tags 站点引导管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param title query string false "步骤标题"
+// @Success 200 {object} DataResult{data=dao.GuideStepList}
+// @Router /api/v1/guides [get]
+func (g *guide) GetGuideStepList(c *gin.Context) {
+	params := new(struct {
+		Title string `form:"title"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Guide.GetGuideStepList(params.Title, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetUserGuideSteps 获取当前用户可见的引导步骤
+// @Summary 获取当前用户可见的引导步骤
+// @Description 站点引导相关接口
+// @Tags 站点引导管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]dao.GuideStepItem}
+// @Router /api/v1/guide/steps [get]
+func (g *guide) GetUserGuideSteps(c *gin.Context) {
+
+	data, err := service.Guide.GetUserGuideSteps(c.GetString("username"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddGuideStep 创建引导步骤
+// @Summary 创建引导步骤
+// @Description 站点引导相关接口
+// @Tags 站点引导管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param step body service.GuideStepCreate true "步骤信息"
+// @Success 200 {object} MsgDataResult{data=model.SiteGuideStep} "创建成功"
+// @Router /api/v1/guide [post]
+func (g *guide) AddGuideStep(c *gin.Context) {
+	var data = &service.GuideStepCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	step, err := service.Guide.AddGuideStep(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", step)
+}
+
+// UpdateGuideStep 更新引导步骤
+// @Summary 更新引导步骤
+// @Description 站点引导相关接口
+// @Tags 站点引导管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param step body service.GuideStepUpdate true "步骤信息"
+// @Success 200 {object} MsgDataResult{data=model.SiteGuideStep} "更新成功"
+// @Router /api/v1/guide [put]
+func (g *guide) UpdateGuideStep(c *gin.Context) {
+	var data = &service.GuideStepUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	step, err := service.Guide.UpdateGuideStep(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", step)
+}
+
+// UpdateGuideStepSort 更新引导步骤排序
+// @Summary 更新引导步骤排序
+// @Description 站点引导相关接口
+// @Tags 站点引导管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param sort body service.GuideStepSort true "排序信息"
+// @Success 200 {object} Result "更新成功"
+// @Router /api/v1/guide/sort [put]
+func (g *guide) UpdateGuideStepSort(c *gin.Context) {
+	var data = &service.GuideStepSort{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Guide.UpdateGuideStepSort(data); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "更新成功")
+}
+
+// DeleteGuideStep 删除引导步骤
+// @Summary 删除引导步骤
+// @Description 站点引导相关接口
+// @Tags 站点引导管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "步骤ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/guide/{id} [delete]
+func (g *guide) DeleteGuideStep(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.Guide.DeleteGuideStep(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
+
+// UploadImage 引导步骤图片上传
+// @Summary 引导步骤图片上传
+// @Description 站点引导相关接口
+// @Tags 站点引导管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param image formData file true "图片"
+// @Success 200 {object} PathResult
+// @Router /api/v1/guide/imageUpload [post]
+func (g *guide) UploadImage(c *gin.Context) {
+
+	image, err := c.FormFile("image")
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	path, err := service.Guide.UploadImage(image)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"path": path,
+		"msg":  "图片上传成功",
+	})
+}
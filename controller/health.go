@@ -0,0 +1,33 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/service"
+)
+
+var Health health
+
+type health struct{}
+
+// GetReadiness 服务就绪检查
+// @Summary 服务就绪检查
+// @Description 健康检查相关接口，无需登录，返回MySQL、Redis、MinIO的可用状态；Redis或MinIO不可用时状态为degraded，MySQL不可用时返回503
+// @Tags 健康检查
+// @Success 200 {object} DataResult{data=service.HealthStatus}
+// @Failure 503 {object} DataResult{data=service.HealthStatus}
+// @Router /health/ready [get]
+func (h *health) GetReadiness(c *gin.Context) {
+
+	status := service.Health.Ready()
+
+	code := http.StatusOK
+	if status.Status == service.HealthUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+
+	c.JSON(code, gin.H{
+		"code": 0,
+		"data": status,
+	})
+}
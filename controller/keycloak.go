@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"io"
+	"net/http"
+	"ops-api/service"
+)
+
+var Keycloak keycloak
+
+type keycloak struct{}
+
+// ImportRealm 导入Keycloak Realm导出文件
+// @Summary 导入Keycloak Realm导出文件
+// @Description 数据迁移相关接口，将Keycloak中的用户、分组、角色、客户端导入为IDSphere的用户、分组和站点
+// @Tags 数据迁移
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param file formData file true "Realm导出文件（JSON）"
+// @Param dry_run formData bool false "仅预览映射报告，不写入数据"
+// @Success 200 {object} DataResult{data=service.KeycloakImportReport}
+// @Router /api/v1/import/keycloak [post]
+func (k *keycloak) ImportRealm(c *gin.Context) {
+
+	params := new(struct {
+		DryRun bool `form:"dry_run"`
+	})
+	if err := c.ShouldBind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	// 获取上传的导出文件
+	file, err := c.FormFile("file")
+	if err != nil {
+		logger.Error("ERROR：" + err.Error())
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 90400,
+			"msg":  err.Error(),
+		})
+		return
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(src)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	data, err := service.Keycloak.ImportRealm(content, params.DryRun)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
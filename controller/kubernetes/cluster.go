@@ -3,9 +3,9 @@ package kubernetes
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
-	"net/http"
 	"ops-api/controller"
 	dao "ops-api/dao/kubernetes"
+	"ops-api/middleware"
 	service "ops-api/service/kubernetes"
 	"strconv"
 )
@@ -22,7 +22,7 @@ type cluster struct{}
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.CreateData true "集群信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} controller.MsgDataResult "创建成功"
 // @Router /api/v1/kubernetes/cluster [post]
 func (cl *cluster) AddCluster(c *gin.Context) {
 	var params = &service.CreateData{}
@@ -34,7 +34,7 @@ func (cl *cluster) AddCluster(c *gin.Context) {
 
 	data, err := service.Cluster.AddCluster(params)
 	if err != nil {
-		controller.Response(c, 90500, err.Error())
+		controller.ErrorResponse(c, err)
 		return
 	}
 
@@ -47,18 +47,18 @@ func (cl *cluster) AddCluster(c *gin.Context) {
 // @Tags Kubernetes相关接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} controller.Result "删除成功"
 // @Router /api/v1/kubernetes/cluster/{id} [delete]
 func (cl *cluster) DeleteCluster(c *gin.Context) {
 
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		controller.Response(c, 90500, err.Error())
+		controller.ErrorResponse(c, err)
 		return
 	}
 
 	if err := service.Cluster.DeleteCluster(id); err != nil {
-		controller.Response(c, 90500, err.Error())
+		controller.ErrorResponse(c, err)
 		return
 	}
 
@@ -71,7 +71,7 @@ func (cl *cluster) DeleteCluster(c *gin.Context) {
 // @Tags Kubernetes相关接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body dao.UpdateData true "集群信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} controller.MsgDataResult "更新成功"
 // @Router /api/v1/kubernetes/cluster [put]
 func (cl *cluster) UpdateCluster(c *gin.Context) {
 	var data = &dao.UpdateData{}
@@ -82,7 +82,7 @@ func (cl *cluster) UpdateCluster(c *gin.Context) {
 
 	provider, err := service.Cluster.UpdateCluster(data)
 	if err != nil {
-		controller.Response(c, 90500, err.Error())
+		controller.ErrorResponse(c, err)
 		return
 	}
 
@@ -97,7 +97,7 @@ func (cl *cluster) UpdateCluster(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "集群名称"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} controller.DataResult{data=dao.K8sList}
 // @Router /api/v1/kubernetes/clusters [get]
 func (cl *cluster) GetKubernetesList(c *gin.Context) {
 
@@ -108,7 +108,7 @@ func (cl *cluster) GetKubernetesList(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -119,7 +119,7 @@ func (cl *cluster) GetKubernetesList(c *gin.Context) {
 
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
@@ -138,7 +138,7 @@ func (cl *cluster) GetKubernetesList(c *gin.Context) {
 // @Tags Kubernetes相关接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param uuid query string false "集群 UUID"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} controller.DataResult{data=string}
 // @Router /api/v1/kubernetes/cluster/info [get]
 func (cl *cluster) GetKubernetesInfo(c *gin.Context) {
 
@@ -147,7 +147,7 @@ func (cl *cluster) GetKubernetesInfo(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -2,8 +2,8 @@ package kubernetes
 
 import (
 	"github.com/gin-gonic/gin"
-	"net/http"
 	"ops-api/kubernetes"
+	"ops-api/middleware"
 	service "ops-api/service/kubernetes"
 )
 
@@ -21,7 +21,7 @@ func (d *daemonSet) ListDaemonSets(c *gin.Context) {
 		Page      int    `form:"page" binding:"required"`
 	})
 	if err := c.Bind(params); err != nil {
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -31,7 +31,7 @@ func (d *daemonSet) ListDaemonSets(c *gin.Context) {
 	client := c.MustGet("kc").(*kubernetes.ClientList)
 	list, err := service.DaemonSet.List(params.Name, params.Namespace, params.Page, params.Limit, client)
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
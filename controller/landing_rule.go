@@ -0,0 +1,144 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+	"strconv"
+)
+
+var LandingRule landingRule
+
+type landingRule struct{}
+
+// GetLanding 获取当前用户登录后的跳转地址
+// @Summary 获取当前用户登录后的跳转地址
+// @Description 个人信息管理相关接口，控制台直接登录后调用，next仅允许站内路径、用户有权访问的应用地址或允许跳转的域名，否则返回用户所属分组的默认跳转地址，redirect_uri为空时跳转至首页
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param next query string false "登录前访问的地址"
+// @Success 200 {object} DataResult{data=RedirectData}
+// @Router /api/v1/user/landing [get]
+func (l *landingRule) GetLanding(c *gin.Context) {
+
+	redirectUri, err := service.LandingRule.Resolve(c.GetUint("id"), c.Query("next"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": map[string]string{
+			"redirect_uri": redirectUri,
+		},
+	})
+}
+
+// GetLandingRuleList 获取登录后跳转规则列表（表格）
+// @Summary 获取登录后跳转规则列表（表格）
+// @Description 登录后跳转规则相关接口
+// @Tags 登录后跳转规则管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "规则名称"
+// @Success 200 {object} DataResult{data=dao.LandingRuleList}
+// @Router /api/v1/landing_rules [get]
+func (l *landingRule) GetLandingRuleList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.LandingRule.GetLandingRuleList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddLandingRule 创建登录后跳转规则
+// @Summary 创建登录后跳转规则
+// @Description 登录后跳转规则相关接口，site_id为空时为分组默认跳转地址，不为空时在跳转目标为该应用时覆盖跳转地址，groups为空时适用于所有用户，多条规则匹配时ID最小的规则生效
+// @Tags 登录后跳转规则管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param rule body service.LandingRuleCreate true "规则信息"
+// @Success 200 {object} MsgDataResult{data=model.LandingRule} "创建成功"
+// @Router /api/v1/landing_rule [post]
+func (l *landingRule) AddLandingRule(c *gin.Context) {
+	var data = &service.LandingRuleCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	rule, err := service.LandingRule.AddLandingRule(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", rule)
+}
+
+// UpdateLandingRule 更新登录后跳转规则
+// @Summary 更新登录后跳转规则
+// @Description 登录后跳转规则相关接口
+// @Tags 登录后跳转规则管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param rule body service.LandingRuleUpdate true "规则信息"
+// @Success 200 {object} MsgDataResult{data=model.LandingRule} "更新成功"
+// @Router /api/v1/landing_rule [put]
+func (l *landingRule) UpdateLandingRule(c *gin.Context) {
+	var data = &service.LandingRuleUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	rule, err := service.LandingRule.UpdateLandingRule(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", rule)
+}
+
+// DeleteLandingRule 删除登录后跳转规则
+// @Summary 删除登录后跳转规则
+// @Description 登录后跳转规则相关接口
+// @Tags 登录后跳转规则管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "规则ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/landing_rule/{id} [delete]
+func (l *landingRule) DeleteLandingRule(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.LandingRule.DeleteLandingRule(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
@@ -0,0 +1,135 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/dao"
+	"ops-api/service"
+	"strconv"
+)
+
+var LoginHook loginHook
+
+type loginHook struct{}
+
+// GetLoginHookList 获取登录扩展列表（表格）
+// @Summary 获取登录扩展列表（表格）
+// @Description 登录扩展相关接口
+// @Tags 登录扩展管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "扩展名称"
+// @Success 200 {object} DataResult{data=dao.LoginHookList}
+// @Router /api/v1/login_hooks [get]
+func (l *loginHook) GetLoginHookList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.LoginHook.GetLoginHookList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetLoginHookPlugins 获取已注册的内置扩展
+// @Summary 获取已注册的内置扩展
+// @Description 登录扩展相关接口
+// @Tags 登录扩展管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]service.LoginHookPlugin}
+// @Router /api/v1/login_hooks/plugins [get]
+func (l *loginHook) GetLoginHookPlugins(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": service.LoginHook.GetLoginHookPlugins(),
+	})
+}
+
+// AddLoginHook 创建登录扩展
+// @Summary 创建登录扩展
+// @Description 登录扩展相关接口
+// @Tags 登录扩展管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param hook body service.LoginHookCreate true "扩展信息"
+// @Success 200 {object} MsgDataResult{data=model.LoginHook} "创建成功"
+// @Router /api/v1/login_hook [post]
+func (l *loginHook) AddLoginHook(c *gin.Context) {
+	var data = &service.LoginHookCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	hook, err := service.LoginHook.AddLoginHook(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", hook)
+}
+
+// UpdateLoginHook 更新登录扩展
+// @Summary 更新登录扩展
+// @Description 登录扩展相关接口
+// @Tags 登录扩展管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param hook body dao.LoginHookUpdate true "扩展信息"
+// @Success 200 {object} MsgDataResult{data=model.LoginHook} "更新成功"
+// @Router /api/v1/login_hook [put]
+func (l *loginHook) UpdateLoginHook(c *gin.Context) {
+	var data = &dao.LoginHookUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	hook, err := service.LoginHook.UpdateLoginHook(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", hook)
+}
+
+// DeleteLoginHook 删除登录扩展
+// @Summary 删除登录扩展
+// @Description 登录扩展相关接口
+// @Tags 登录扩展管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "扩展ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/login_hook/{id} [delete]
+func (l *loginHook) DeleteLoginHook(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.LoginHook.DeleteLoginHook(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
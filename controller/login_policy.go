@@ -0,0 +1,299 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+	"strconv"
+)
+
+var LoginPolicy loginPolicy
+
+type loginPolicy struct{}
+
+// GetLoginPolicyList 获取登录策略列表（表格）
+// @Summary 获取登录策略列表（表格）
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "策略名称"
+// @Success 200 {object} DataResult{data=dao.LoginPolicyList}
+// @Router /api/v1/login_policies [get]
+func (l *loginPolicy) GetLoginPolicyList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.LoginPolicy.GetLoginPolicyList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddLoginPolicy 创建登录策略
+// @Summary 创建登录策略
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param policy body service.LoginPolicyCreate true "策略信息"
+// @Success 200 {object} MsgDataResult{data=model.LoginPolicy} "创建成功"
+// @Router /api/v1/login_policy [post]
+func (l *loginPolicy) AddLoginPolicy(c *gin.Context) {
+	var data = &service.LoginPolicyCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	policy, err := service.LoginPolicy.AddLoginPolicy(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", policy)
+}
+
+// UpdateLoginPolicy 更新登录策略
+// @Summary 更新登录策略
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param policy body service.LoginPolicyUpdate true "策略信息"
+// @Success 200 {object} MsgDataResult{data=model.LoginPolicy} "更新成功"
+// @Router /api/v1/login_policy [put]
+func (l *loginPolicy) UpdateLoginPolicy(c *gin.Context) {
+	var data = &service.LoginPolicyUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	policy, err := service.LoginPolicy.UpdateLoginPolicy(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", policy)
+}
+
+// DeleteLoginPolicy 删除登录策略
+// @Summary 删除登录策略
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "策略ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/login_policy/{id} [delete]
+func (l *loginPolicy) DeleteLoginPolicy(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.LoginPolicy.DeleteLoginPolicy(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
+
+// GetLoginTimePolicyList 获取登录时间策略列表（表格）
+// @Summary 获取登录时间策略列表（表格）
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "策略名称"
+// @Success 200 {object} DataResult{data=dao.LoginTimePolicyList}
+// @Router /api/v1/login_time_policies [get]
+func (l *loginPolicy) GetLoginTimePolicyList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.LoginPolicy.GetLoginTimePolicyList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddLoginTimePolicy 创建登录时间策略
+// @Summary 创建登录时间策略
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param policy body service.LoginTimePolicyCreate true "策略信息"
+// @Success 200 {object} MsgDataResult{data=model.LoginTimePolicy} "创建成功"
+// @Router /api/v1/login_time_policy [post]
+func (l *loginPolicy) AddLoginTimePolicy(c *gin.Context) {
+	var data = &service.LoginTimePolicyCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	policy, err := service.LoginPolicy.AddLoginTimePolicy(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", policy)
+}
+
+// UpdateLoginTimePolicy 更新登录时间策略
+// @Summary 更新登录时间策略
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param policy body service.LoginTimePolicyUpdate true "策略信息"
+// @Success 200 {object} MsgDataResult{data=model.LoginTimePolicy} "更新成功"
+// @Router /api/v1/login_time_policy [put]
+func (l *loginPolicy) UpdateLoginTimePolicy(c *gin.Context) {
+	var data = &service.LoginTimePolicyUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	policy, err := service.LoginPolicy.UpdateLoginTimePolicy(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", policy)
+}
+
+// DeleteLoginTimePolicy 删除登录时间策略
+// @Summary 删除登录时间策略
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "策略ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/login_time_policy/{id} [delete]
+func (l *loginPolicy) DeleteLoginTimePolicy(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.LoginPolicy.DeleteLoginTimePolicy(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
+
+// GetHolidayList 获取节假日日历
+// @Summary 获取节假日日历
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param year query string false "年份，默认为当前年份"
+// @Success 200 {object} DataResult{data=[]model.Holiday}
+// @Router /api/v1/holidays [get]
+func (l *loginPolicy) GetHolidayList(c *gin.Context) {
+
+	data, err := service.LoginPolicy.GetHolidayList(c.Query("year"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// SaveHolidays 批量保存节假日
+// @Summary 批量保存节假日
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param holidays body service.HolidaySave true "节假日信息"
+// @Success 200 {object} Result "保存成功"
+// @Router /api/v1/holidays [post]
+func (l *loginPolicy) SaveHolidays(c *gin.Context) {
+	var data = &service.HolidaySave{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.LoginPolicy.SaveHolidays(data); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "保存成功")
+}
+
+// DeleteHoliday 删除节假日
+// @Summary 删除节假日
+// @Description 登录策略相关接口
+// @Tags 登录策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "节假日ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/holiday/{id} [delete]
+func (l *loginPolicy) DeleteHoliday(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.LoginPolicy.DeleteHoliday(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
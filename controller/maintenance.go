@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/service"
+)
+
+var Maintenance maintenance
+
+type maintenance struct{}
+
+// loginFailed 登录失败响应，维护期间拒绝非管理员登录时返回503及维护提示信息
+func loginFailed(c *gin.Context, err error) {
+	if service.IsMaintenanceError(err) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code": 90503,
+			"msg":  err.Error(),
+		})
+		return
+	}
+	ErrorResponse(c, err)
+}
+
+// GetMaintenanceStatus 获取维护模式状态
+// @Summary 获取维护模式状态
+// @Description 维护模式相关接口，无需登录，登录页面可据此展示维护提示信息
+// @Tags 配置相接口
+// @Success 200 {object} DataResult{data=MaintenanceNotice}
+// @Router /api/v1/maintenance/status [get]
+func (m *maintenance) GetMaintenanceStatus(c *gin.Context) {
+
+	status := service.Maintenance.Get()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": map[string]interface{}{
+			"enabled": status.Enabled,
+			"message": status.Message,
+		},
+	})
+}
+
+// UpdateMaintenance 开启或关闭维护模式
+// @Summary 开启或关闭维护模式
+// @Description 维护模式相关接口，开启后非管理员的登录及单点登录请求返回503及提示信息，有权限调用该接口的用户视为管理员，仍可登录及访问
+// @Tags 配置相接口
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param maintenance body service.MaintenanceUpdate true "维护模式"
+// @Success 200 {object} Result "更新成功"
+// @Router /api/v1/maintenance [put]
+func (m *maintenance) UpdateMaintenance(c *gin.Context) {
+	var data = &service.MaintenanceUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Maintenance.Update(data, c.GetString("username")); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "更新成功")
+}
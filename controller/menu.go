@@ -3,7 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
-	"net/http"
+	"ops-api/middleware"
 	"ops-api/service"
 )
 
@@ -16,7 +16,7 @@ type menu struct{}
 // @Description 组相关接口
 // @Tags 组管理
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.MenuList}
 // @Router /api/v1/menu/list [get]
 func (u *menu) GetMenuListAll(c *gin.Context) {
 
@@ -24,7 +24,7 @@ func (u *menu) GetMenuListAll(c *gin.Context) {
 
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
@@ -44,7 +44,7 @@ func (u *menu) GetMenuListAll(c *gin.Context) {
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.MenuList}
 // @Router /api/v1/menus [get]
 func (u *menu) GetMenuList(c *gin.Context) {
 	params := new(struct {
@@ -54,7 +54,7 @@ func (u *menu) GetMenuList(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -64,7 +64,7 @@ func (u *menu) GetMenuList(c *gin.Context) {
 	data, err := service.Menu.GetMenuList(params.Title, params.Page, params.Limit)
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/swaggo/swag"
+	"net/http"
+	"ops-api/docs"
+	"ops-api/utils/openapi"
+)
+
+var OpenAPI openAPI
+
+type openAPI struct{}
+
+// openAPISpecs 各版本接口的Swagger文档，新增接口版本时在此注册
+var openAPISpecs = map[string]*swag.Spec{
+	"v1": docs.SwaggerInfo,
+}
+
+func init() {
+	// 文档未设置版本时使用接口版本
+	for version, spec := range openAPISpecs {
+		if spec.Version == "" {
+			spec.Version = version
+		}
+	}
+}
+
+// GetSwaggerSpec 获取指定版本的Swagger 2.0文档
+// @Summary 获取Swagger 2.0文档
+// @Description 接口文档相关接口
+// @Tags 接口文档
+// @Param version path string true "接口版本，如：v1"
+// @Success 200 {object} object
+// @Router /openapi/{version}/swagger.json [get]
+func (o *openAPI) GetSwaggerSpec(c *gin.Context) {
+
+	spec, ok := openAPISpecs[c.Param("version")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 90404,
+			"msg":  "接口版本不存在",
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(spec.ReadDoc()))
+}
+
+// GetOpenAPISpec 获取指定版本的OpenAPI 3.0文档，可用于生成各语言的客户端
+// @Summary 获取OpenAPI 3.0文档
+// @Description 接口文档相关接口
+// @Tags 接口文档
+// @Param version path string true "接口版本，如：v1"
+// @Success 200 {object} object
+// @Router /openapi/{version}/openapi.json [get]
+func (o *openAPI) GetOpenAPISpec(c *gin.Context) {
+
+	spec, ok := openAPISpecs[c.Param("version")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code": 90404,
+			"msg":  "接口版本不存在",
+		})
+		return
+	}
+
+	data, err := openapi.Convert([]byte(spec.ReadDoc()))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
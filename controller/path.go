@@ -3,7 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
-	"net/http"
+	"ops-api/middleware"
 	"ops-api/service"
 )
 
@@ -16,7 +16,7 @@ type path struct{}
 // @Description 组相关接口
 // @Tags 组管理
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=[]dao.MenuPaths}
 // @Router /api/v1/path/list [get]
 func (p *path) GetPathListAll(c *gin.Context) {
 
@@ -24,7 +24,7 @@ func (p *path) GetPathListAll(c *gin.Context) {
 
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
@@ -45,7 +45,7 @@ func (p *path) GetPathListAll(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param menu_name query string true "菜单名称"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.PathList}
 // @Router /api/v1/paths [get]
 func (p *path) GetPathList(c *gin.Context) {
 	params := new(struct {
@@ -55,7 +55,7 @@ func (p *path) GetPathList(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -65,7 +65,7 @@ func (p *path) GetPathList(c *gin.Context) {
 	data, err := service.Path.GetPathList(params.MenuName, params.Page, params.Limit)
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/service"
+	"time"
+)
+
+var Privacy privacy
+
+type privacy struct{}
+
+// ExportPersonalData 导出当前用户的个人数据
+// @Summary 导出当前用户的个人数据
+// @Description 个人信息管理相关接口，导出平台中保存的当前用户的个人数据，包括基本信息、关联身份、登录设备、会话、登录记录及应用授权，format为zip时每类数据保存为一个JSON文件
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param format query string false "导出格式：json（默认）、zip"
+// @Success 200 {file} file "个人数据文件"
+// @Router /api/v1/user/personal_data [get]
+func (p *privacy) ExportPersonalData(c *gin.Context) {
+
+	filename := fmt.Sprintf("personal-data-%s-%s", c.GetString("username"), time.Now().Format("20060102150405"))
+
+	if c.Query("format") == "zip" {
+		buf, err := service.Privacy.ExportZip(c.GetUint("id"))
+		if err != nil {
+			ErrorResponse(c, err)
+			return
+		}
+		c.Header("Content-Disposition", "attachment; filename="+filename+".zip")
+		c.Data(http.StatusOK, "application/zip", buf.Bytes())
+		return
+	}
+
+	data, err := service.Privacy.Export(c.GetUint("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+	content, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+filename+".json")
+	c.Data(http.StatusOK, "application/json; charset=utf-8", content)
+}
+
+// EraseUser 擦除用户个人数据
+// @Summary 擦除用户个人数据
+// @Description 用户相关接口，匿名化用户信息及历史记录中的个人数据并禁用用户，历史记录使用化名关联以保留审计记录，擦除后无法恢复
+// @Tags 用户管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param user body service.UserErase true "用户ID及擦除原因"
+// @Success 200 {object} DataResult{data=model.UserErasure}
+// @Router /api/v1/user/erase [post]
+func (p *privacy) EraseUser(c *gin.Context) {
+
+	var data = &service.UserErase{}
+
+	// 解析请求参数
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	record, err := service.Privacy.Erase(data, c.GetString("username"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "擦除成功", record)
+}
+
+// GetUserErasureList 获取用户数据擦除记录（表格）
+// @Summary 获取用户数据擦除记录（表格）
+// @Description 用户相关接口
+// @Tags 用户管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param pseudonym query string false "化名"
+// @Success 200 {object} DataResult{data=dao.UserErasureList}
+// @Router /api/v1/user/erasures [get]
+func (p *privacy) GetUserErasureList(c *gin.Context) {
+	params := new(struct {
+		Pseudonym string `form:"pseudonym"`
+		Page      int    `form:"page" binding:"required"`
+		Limit     int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Privacy.GetErasureList(params.Pseudonym, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
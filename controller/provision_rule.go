@@ -0,0 +1,120 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+	"strconv"
+)
+
+var ProvisionRule provisionRule
+
+type provisionRule struct{}
+
+// GetProvisionRuleList 获取自动分配规则列表（表格）
+// @Summary 获取自动分配规则列表（表格）
+// @Description 自动分配规则相关接口
+// @Tags 自动分配规则管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "规则名称"
+// @Success 200 {object} DataResult{data=dao.ProvisionRuleList}
+// @Router /api/v1/provision_rules [get]
+func (p *provisionRule) GetProvisionRuleList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.ProvisionRule.GetProvisionRuleList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddProvisionRule 创建自动分配规则
+// @Summary 创建自动分配规则
+// @Description 自动分配规则相关接口，新用户创建时根据邮箱域名（match_type=1）或LDAP OU（match_type=2）自动加入分组并授权站点
+// @Tags 自动分配规则管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param rule body service.ProvisionRuleCreate true "规则信息"
+// @Success 200 {object} MsgDataResult{data=model.ProvisionRule} "创建成功"
+// @Router /api/v1/provision_rule [post]
+func (p *provisionRule) AddProvisionRule(c *gin.Context) {
+	var data = &service.ProvisionRuleCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	rule, err := service.ProvisionRule.AddProvisionRule(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", rule)
+}
+
+// UpdateProvisionRule 更新自动分配规则
+// @Summary 更新自动分配规则
+// @Description 自动分配规则相关接口，仅对之后创建的用户生效
+// @Tags 自动分配规则管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param rule body service.ProvisionRuleUpdate true "规则信息"
+// @Success 200 {object} MsgDataResult{data=model.ProvisionRule} "更新成功"
+// @Router /api/v1/provision_rule [put]
+func (p *provisionRule) UpdateProvisionRule(c *gin.Context) {
+	var data = &service.ProvisionRuleUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	rule, err := service.ProvisionRule.UpdateProvisionRule(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", rule)
+}
+
+// DeleteProvisionRule 删除自动分配规则
+// @Summary 删除自动分配规则
+// @Description 自动分配规则相关接口
+// @Tags 自动分配规则管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "规则ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/provision_rule/{id} [delete]
+func (p *provisionRule) DeleteProvisionRule(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.ProvisionRule.DeleteProvisionRule(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/dao"
+	"ops-api/service"
+	"strconv"
+)
+
+var ProvisionWebhook provisionWebhook
+
+type provisionWebhook struct{}
+
+// GetProvisionWebhookList 获取身份事件推送列表（表格）
+// @Summary 获取身份事件推送列表（表格）
+// @Description 身份事件推送相关接口
+// @Tags 身份事件推送管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "名称"
+// @Success 200 {object} DataResult{data=dao.ProvisionWebhookList}
+// @Router /api/v1/provision_webhooks [get]
+func (p *provisionWebhook) GetProvisionWebhookList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.ProvisionWebhook.GetProvisionWebhookList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddProvisionWebhook 创建身份事件推送
+// @Summary 创建身份事件推送
+// @Description 身份事件推送相关接口，用户或分组变更时推送至下游系统，events为订阅的事件（user.created、user.updated、user.deleted、group.members_changed），多个事件使用英文逗号分隔，为空时订阅所有事件；template为请求体模板（Go Template），为空时推送原始事件（JSON）
+// @Tags 身份事件推送管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param webhook body service.ProvisionWebhookCreate true "推送信息"
+// @Success 200 {object} MsgDataResult{data=model.ProvisionWebhook} "创建成功"
+// @Router /api/v1/provision_webhook [post]
+func (p *provisionWebhook) AddProvisionWebhook(c *gin.Context) {
+	var data = &service.ProvisionWebhookCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	webhook, err := service.ProvisionWebhook.AddProvisionWebhook(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", webhook)
+}
+
+// UpdateProvisionWebhook 更新身份事件推送
+// @Summary 更新身份事件推送
+// @Description 身份事件推送相关接口
+// @Tags 身份事件推送管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param webhook body dao.ProvisionWebhookUpdate true "推送信息"
+// @Success 200 {object} MsgDataResult{data=model.ProvisionWebhook} "更新成功"
+// @Router /api/v1/provision_webhook [put]
+func (p *provisionWebhook) UpdateProvisionWebhook(c *gin.Context) {
+	var data = &dao.ProvisionWebhookUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	webhook, err := service.ProvisionWebhook.UpdateProvisionWebhook(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", webhook)
+}
+
+// DeleteProvisionWebhook 删除身份事件推送
+// @Summary 删除身份事件推送
+// @Description 身份事件推送相关接口
+// @Tags 身份事件推送管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "推送ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/provision_webhook/{id} [delete]
+func (p *provisionWebhook) DeleteProvisionWebhook(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.ProvisionWebhook.DeleteProvisionWebhook(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
+
+// PreviewProvisionWebhook 预览推送内容
+// @Summary 预览推送内容
+// @Description 身份事件推送相关接口，使用示例事件渲染模板
+// @Tags 身份事件推送管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param preview body service.ProvisionWebhookPreview true "事件及模板"
+// @Success 200 {object} DataResult{data=string}
+// @Router /api/v1/provision_webhook/preview [post]
+func (p *provisionWebhook) PreviewProvisionWebhook(c *gin.Context) {
+	var data = &service.ProvisionWebhookPreview{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	body, err := service.ProvisionWebhook.Preview(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": body,
+	})
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+	"strconv"
+)
+
+var ComplianceReport complianceReport
+
+type complianceReport struct{}
+
+// GetReportList 获取合规报告列表（表格）
+// @Summary 获取合规报告列表（表格）
+// @Description 合规报告相关接口
+// @Tags 合规报告
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param period query string false "报告周期，格式：YYYY-MM"
+// @Success 200 {object} DataResult{data=dao.ComplianceReportList}
+// @Router /api/v1/reports [get]
+func (r *complianceReport) GetReportList(c *gin.Context) {
+	params := new(struct {
+		Period string `form:"period"`
+		Page   int    `form:"page" binding:"required"`
+		Limit  int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.ComplianceReport.GetReportList(params.Period, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// CreateReport 生成合规报告
+// @Summary 生成合规报告
+// @Description 合规报告相关接口，报告周期及报告中的时间按当前用户的时区计算
+// @Tags 合规报告
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param report body service.ComplianceReportCreate true "报告周期及格式"
+// @Success 200 {object} MsgDataResult{data=[]model.ComplianceReport} "生成成功"
+// @Router /api/v1/report [post]
+func (r *complianceReport) CreateReport(c *gin.Context) {
+	var data = &service.ComplianceReportCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	reports, err := service.ComplianceReport.CreateReport(data, c.GetUint("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "生成成功", reports)
+}
+
+// GetReportURL 获取合规报告下载地址
+// @Summary 获取合规报告下载地址
+// @Description 合规报告相关接口
+// @Tags 合规报告
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "报告ID"
+// @Success 200 {object} DataResult{data=string}
+// @Router /api/v1/report/{id}/download [get]
+func (r *complianceReport) GetReportURL(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	url, err := service.ComplianceReport.GetReportURL(uint(id))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": url,
+	})
+}
+
+// DeleteReport 删除合规报告
+// @Summary 删除合规报告
+// @Description 合规报告相关接口
+// @Tags 合规报告
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "报告ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/report/{id} [delete]
+func (r *complianceReport) DeleteReport(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.ComplianceReport.DeleteReport(uint(id)); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
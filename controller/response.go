@@ -2,8 +2,12 @@ package controller
 
 import (
 	"encoding/json"
+	"errors"
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"ops-api/middleware"
+	"ops-api/service"
 	"ops-api/utils"
 	"reflect"
 )
@@ -53,7 +57,25 @@ func Response(c *gin.Context, code int, msg string) {
 		"msg":  msg,
 	}
 	c.Set("response", response)
-	c.JSON(200, response)
+	c.JSON(middleware.StatusCode(code), response)
+}
+
+// ErrorResponse 服务端错误响应，按错误类型区分响应码：记录不存在为90404，唯一键冲突或存在关联数据为90409，
+// 操作过于频繁为90429（同时返回Retry-After），其它为90500
+func ErrorResponse(c *gin.Context, err error) {
+
+	var throttleErr *service.ThrottleError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		Response(c, 90404, err.Error())
+	case utils.IsDuplicateEntryError(err), utils.IsForeignKeyConstraintError(err):
+		Response(c, 90409, err.Error())
+	case errors.As(err, &throttleErr):
+		middleware.SetRetryAfter(c, throttleErr.RetryAfter())
+		Response(c, 90429, err.Error())
+	default:
+		Response(c, 90500, err.Error())
+	}
 }
 
 // CreateOrUpdateResponse 创建或更新请求的响应
@@ -83,5 +105,72 @@ func CreateOrUpdateResponse(c *gin.Context, code int, msg string, data interface
 		"data": responseData,
 	}
 	c.Set("response", response)
-	c.JSON(200, response)
+	c.JSON(middleware.StatusCode(code), response)
+}
+
+// 以下结构体仅用于接口文档，描述接口的响应格式，code为0时请求成功，90400为参数错误，90404为资源不存在，90409为资源冲突，
+// 90429为请求过于频繁，90500为服务端错误；默认开启兼容模式（legacyStatusCode），HTTP状态码均为200，关闭后错误响应的HTTP状态码与code一致（如90404返回404）
+
+// Result 普通请求响应
+type Result struct {
+	Code int    `json:"code" example:"0"`
+	Msg  string `json:"msg"`
+}
+
+// DataResult 数据请求响应，data 在接口注释中指定具体类型，如：DataResult{data=dao.SiteList}
+type DataResult struct {
+	Code int         `json:"code" example:"0"`
+	Data interface{} `json:"data"`
+}
+
+// MsgDataResult 创建、更新请求响应，data 为创建或更新后的数据（已过滤敏感信息）
+type MsgDataResult struct {
+	Code int         `json:"code" example:"0"`
+	Msg  string      `json:"msg"`
+	Data interface{} `json:"data"`
+}
+
+// TokenResult 登录请求响应
+type TokenResult struct {
+	Code        int    `json:"code" example:"0"`
+	Msg         string `json:"msg,omitempty"`
+	Token       string `json:"token"`                  // 用户令牌
+	RedirectURI string `json:"redirect_uri,omitempty"` // 单点登录时为客户端回调地址
+}
+
+// AuthorizeResult 单点登录授权响应
+type AuthorizeResult struct {
+	Code        int    `json:"code" example:"0"`
+	Msg         string `json:"msg" example:"授权成功"`
+	RedirectURI string `json:"redirect_uri"` // 客户端回调地址
+}
+
+// PathResult 上传文件响应
+type PathResult struct {
+	Code int    `json:"code" example:"0"`
+	Path string `json:"path"` // 文件地址
+}
+
+// QRCodeResult MFA二维码响应
+type QRCodeResult struct {
+	Code   int    `json:"code" example:"0"`
+	QRCode string `json:"qrcode"` // 二维码图片（Base64）
+}
+
+// ResetTokenResult 密码重置校验码响应
+type ResetTokenResult struct {
+	Code       int    `json:"code" example:"0"`
+	Msg        string `json:"msg"`
+	ResetToken string `json:"reset_token"` // 重置令牌
+}
+
+// RedirectData 跳转地址
+type RedirectData struct {
+	RedirectURI string `json:"redirect_uri"`
+}
+
+// MaintenanceNotice 维护模式状态（无需登录）
+type MaintenanceNotice struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
 }
@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化访问申请相关路由
+func initAccessRequestRouters(router *gin.Engine) {
+	// 获取访问申请列表（表格）
+	router.GET("/api/v1/access_requests", controller.AccessRequest.GetAccessRequestList)
+	// 手动处理访问申请
+	router.PUT("/api/v1/access_request/:id/resolve", controller.AccessRequest.ResolveAccessRequest)
+	// ITSM审批结果通知
+	router.POST("/api/v1/itsm/webhook", controller.AccessRequest.ITSMWebhook)
+
+	user := router.Group("/api/v1/user")
+	{
+		// 获取当前用户提交的访问申请
+		user.GET("/access_requests", controller.AccessRequest.GetUserAccessRequests)
+		// 提交访问申请
+		user.POST("/access_request", controller.AccessRequest.AddAccessRequest)
+	}
+}
@@ -0,0 +1,14 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化告警相关路由
+func initAlertRouters(router *gin.Engine) {
+	// 获取内置告警状态
+	router.GET("/api/v1/alerts", controller.Alert.GetAlertList)
+	// 导出Prometheus告警规则
+	router.GET("/api/v1/alert/rules", controller.Alert.GetAlertRules)
+}
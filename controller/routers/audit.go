@@ -17,5 +17,7 @@ func initAuditRouters(router *gin.Engine) {
 		audit.GET("/login", controller.Audit.GetLoginRecord)
 		// 获取系统操作记录
 		audit.GET("/oplog", controller.Audit.GetOplog)
+		// 获取SCIM同步记录
+		audit.GET("/scim", controller.Audit.GetSCIMRecord)
 	}
 }
@@ -0,0 +1,20 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化审计员相关路由
+func initAuditorRouters(router *gin.Engine) {
+	// 获取审计员列表（表格）
+	router.GET("/api/v1/auditors", controller.Auditor.GetAuditorGrantList)
+
+	auditor := router.Group("/api/v1/auditor")
+	{
+		// 授予审计员
+		auditor.POST("", controller.Auditor.AddAuditorGrant)
+		// 撤销审计员
+		auditor.PUT("/:id/revoke", controller.Auditor.RevokeAuditorGrant)
+	}
+}
@@ -14,6 +14,12 @@ func initAuthRouters(router *gin.Engine) {
 		user.GET("/mfa_qrcode", controller.User.GetGoogleQrcode)
 		// MFA认证
 		user.POST("/mfa_auth", controller.User.GoogleQrcodeValidate)
+		// 首次登录需要完成的操作
+		user.GET("/required_actions", controller.User.GetRequiredActions)
+		user.POST("/required_actions", controller.User.SubmitRequiredAction)
+		user.POST("/required_actions/code", controller.User.GetRequiredActionCode)
+		// 升级认证（MFA）
+		user.POST("/step_up", controller.User.MFAStepUp)
 		// 获取用户信息
 		user.GET("/info", controller.User.GetUser)
 	}
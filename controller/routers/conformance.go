@@ -0,0 +1,12 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化协议一致性测试相关路由
+func initConformanceRouters(router *gin.Engine) {
+	// 执行协议一致性测试
+	router.POST("/api/v1/conformance/run", controller.Conformance.RunConformance)
+}
@@ -0,0 +1,17 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化应用授权相关路由
+func initConsentRouters(router *gin.Engine) {
+	user := router.Group("/api/v1/user")
+	{
+		// 获取当前用户的应用授权记录
+		user.GET("/consents", controller.Consent.GetUserConsents)
+		// 撤销当前用户对应用的授权
+		user.DELETE("/consent/:id", controller.Consent.RevokeUserConsent)
+	}
+}
@@ -0,0 +1,12 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化管理控制台实时事件相关路由
+func initConsoleEventRouters(router *gin.Engine) {
+	// 订阅管理控制台实时事件
+	router.GET("/api/v1/console/events", controller.ConsoleEvent.GetConsoleEvents)
+}
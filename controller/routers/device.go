@@ -0,0 +1,25 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化登录设备相关路由
+func initDeviceRouters(router *gin.Engine) {
+	// 获取登录设备列表（表格）
+	router.GET("/api/v1/devices", controller.Device.GetDeviceList)
+	// 擦除登录设备
+	router.POST("/api/v1/device/:id/wipe", controller.Device.WipeDevice)
+
+	user := router.Group("/api/v1/user")
+	{
+		// 获取当前用户的登录设备
+		user.GET("/devices", controller.Device.GetUserDevices)
+		// 删除当前用户的登录设备
+		user.DELETE("/device/:id", controller.Device.DeleteUserDevice)
+	}
+
+	// 保护账号（登录提醒邮件）
+	router.POST("/api/v1/secure_account", controller.Device.SecureAccount)
+}
@@ -0,0 +1,31 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化外部资源相关路由，供Terraform等IaC工具按外部标识管理资源
+func initExternalRouters(router *gin.Engine) {
+	external := router.Group("/api/v1/external")
+	{
+		// 根据外部标识获取用户
+		external.GET("/user/:external_id", controller.External.GetUser)
+		// 根据外部标识创建或更新用户
+		external.PUT("/user/:external_id", controller.External.PutUser)
+		// 根据外部标识删除用户
+		external.DELETE("/user/:external_id", controller.External.DeleteUser)
+		// 根据外部标识获取分组
+		external.GET("/group/:external_id", controller.External.GetGroup)
+		// 根据外部标识创建或更新分组
+		external.PUT("/group/:external_id", controller.External.PutGroup)
+		// 根据外部标识删除分组
+		external.DELETE("/group/:external_id", controller.External.DeleteGroup)
+		// 根据外部标识获取站点
+		external.GET("/site/:external_id", controller.External.GetSite)
+		// 根据外部标识创建或更新站点
+		external.PUT("/site/:external_id", controller.External.PutSite)
+		// 根据外部标识删除站点
+		external.DELETE("/site/:external_id", controller.External.DeleteSite)
+	}
+}
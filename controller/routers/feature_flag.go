@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化功能开关相关路由
+func initFeatureFlagRouters(router *gin.Engine) {
+	// 获取功能开关列表（表格）
+	router.GET("/api/v1/feature_flags", controller.FeatureFlag.GetFeatureFlagList)
+	// 获取对当前用户开放的功能
+	router.GET("/api/v1/user/features", controller.FeatureFlag.GetUserFeatures)
+
+	flag := router.Group("/api/v1/feature_flag")
+	{
+		// 新增功能开关
+		flag.POST("", controller.FeatureFlag.AddFeatureFlag)
+		// 修改功能开关
+		flag.PUT("", controller.FeatureFlag.UpdateFeatureFlag)
+		// 删除功能开关
+		flag.DELETE("/:id", controller.FeatureFlag.DeleteFeatureFlag)
+	}
+}
@@ -0,0 +1,28 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化站点引导相关路由
+func initGuideRouters(router *gin.Engine) {
+	// 获取引导步骤列表（表格）
+	router.GET("/api/v1/guides", controller.Guide.GetGuideStepList)
+
+	guide := router.Group("/api/v1/guide")
+	{
+		// 获取当前用户可见的引导步骤
+		guide.GET("/steps", controller.Guide.GetUserGuideSteps)
+		// 新增引导步骤
+		guide.POST("", controller.Guide.AddGuideStep)
+		// 修改引导步骤
+		guide.PUT("", controller.Guide.UpdateGuideStep)
+		// 修改引导步骤排序
+		guide.PUT("/sort", controller.Guide.UpdateGuideStepSort)
+		// 删除引导步骤
+		guide.DELETE("/:id", controller.Guide.DeleteGuideStep)
+		// 上传引导步骤图片
+		guide.POST("/imageUpload", controller.Guide.UploadImage)
+	}
+}
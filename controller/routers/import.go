@@ -0,0 +1,15 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化数据迁移相关路由
+func initImportRouters(router *gin.Engine) {
+	migrate := router.Group("/api/v1/import")
+	{
+		// 导入Keycloak Realm导出文件
+		migrate.POST("/keycloak", controller.Keycloak.ImportRealm)
+	}
+}
@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化登录后跳转规则相关路由
+func initLandingRuleRouters(router *gin.Engine) {
+	// 获取当前用户登录后的跳转地址
+	router.GET("/api/v1/user/landing", controller.LandingRule.GetLanding)
+	// 获取登录后跳转规则列表（表格）
+	router.GET("/api/v1/landing_rules", controller.LandingRule.GetLandingRuleList)
+
+	rule := router.Group("/api/v1/landing_rule")
+	{
+		// 新增登录后跳转规则
+		rule.POST("", controller.LandingRule.AddLandingRule)
+		// 修改登录后跳转规则
+		rule.PUT("", controller.LandingRule.UpdateLandingRule)
+		// 删除登录后跳转规则
+		rule.DELETE("/:id", controller.LandingRule.DeleteLandingRule)
+	}
+}
@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化登录扩展相关路由
+func initLoginHookRouters(router *gin.Engine) {
+	// 获取登录扩展列表（表格）
+	router.GET("/api/v1/login_hooks", controller.LoginHook.GetLoginHookList)
+	// 获取已注册的内置扩展
+	router.GET("/api/v1/login_hooks/plugins", controller.LoginHook.GetLoginHookPlugins)
+
+	hook := router.Group("/api/v1/login_hook")
+	{
+		// 新增登录扩展
+		hook.POST("", controller.LoginHook.AddLoginHook)
+		// 修改登录扩展
+		hook.PUT("", controller.LoginHook.UpdateLoginHook)
+		// 删除登录扩展
+		hook.DELETE("/:id", controller.LoginHook.DeleteLoginHook)
+	}
+}
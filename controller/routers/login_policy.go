@@ -0,0 +1,42 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化登录策略相关路由
+func initLoginPolicyRouters(router *gin.Engine) {
+	// 获取登录策略列表（表格）
+	router.GET("/api/v1/login_policies", controller.LoginPolicy.GetLoginPolicyList)
+
+	policy := router.Group("/api/v1/login_policy")
+	{
+		// 新增登录策略
+		policy.POST("", controller.LoginPolicy.AddLoginPolicy)
+		// 修改登录策略
+		policy.PUT("", controller.LoginPolicy.UpdateLoginPolicy)
+		// 删除登录策略
+		policy.DELETE("/:id", controller.LoginPolicy.DeleteLoginPolicy)
+	}
+
+	// 获取登录时间策略列表（表格）
+	router.GET("/api/v1/login_time_policies", controller.LoginPolicy.GetLoginTimePolicyList)
+
+	timePolicy := router.Group("/api/v1/login_time_policy")
+	{
+		// 新增登录时间策略
+		timePolicy.POST("", controller.LoginPolicy.AddLoginTimePolicy)
+		// 修改登录时间策略
+		timePolicy.PUT("", controller.LoginPolicy.UpdateLoginTimePolicy)
+		// 删除登录时间策略
+		timePolicy.DELETE("/:id", controller.LoginPolicy.DeleteLoginTimePolicy)
+	}
+
+	// 获取节假日日历
+	router.GET("/api/v1/holidays", controller.LoginPolicy.GetHolidayList)
+	// 批量保存节假日
+	router.POST("/api/v1/holidays", controller.LoginPolicy.SaveHolidays)
+	// 删除节假日
+	router.DELETE("/api/v1/holiday/:id", controller.LoginPolicy.DeleteHoliday)
+}
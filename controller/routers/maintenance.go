@@ -0,0 +1,14 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化维护模式相关路由
+func initMaintenanceRouters(router *gin.Engine) {
+	// 获取维护模式状态
+	router.GET("/api/v1/maintenance/status", controller.Maintenance.GetMaintenanceStatus)
+	// 开启或关闭维护模式
+	router.PUT("/api/v1/maintenance", controller.Maintenance.UpdateMaintenance)
+}
@@ -0,0 +1,22 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化自动分配规则相关路由
+func initProvisionRuleRouters(router *gin.Engine) {
+	// 获取自动分配规则列表（表格）
+	router.GET("/api/v1/provision_rules", controller.ProvisionRule.GetProvisionRuleList)
+
+	rule := router.Group("/api/v1/provision_rule")
+	{
+		// 新增自动分配规则
+		rule.POST("", controller.ProvisionRule.AddProvisionRule)
+		// 修改自动分配规则
+		rule.PUT("", controller.ProvisionRule.UpdateProvisionRule)
+		// 删除自动分配规则
+		rule.DELETE("/:id", controller.ProvisionRule.DeleteProvisionRule)
+	}
+}
@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化身份事件推送相关路由
+func initProvisionWebhookRouters(router *gin.Engine) {
+	// 获取身份事件推送列表（表格）
+	router.GET("/api/v1/provision_webhooks", controller.ProvisionWebhook.GetProvisionWebhookList)
+
+	webhook := router.Group("/api/v1/provision_webhook")
+	{
+		// 新增身份事件推送
+		webhook.POST("", controller.ProvisionWebhook.AddProvisionWebhook)
+		// 修改身份事件推送
+		webhook.PUT("", controller.ProvisionWebhook.UpdateProvisionWebhook)
+		// 删除身份事件推送
+		webhook.DELETE("/:id", controller.ProvisionWebhook.DeleteProvisionWebhook)
+		// 预览推送内容
+		webhook.POST("/preview", controller.ProvisionWebhook.PreviewProvisionWebhook)
+	}
+}
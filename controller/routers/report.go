@@ -0,0 +1,22 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化合规报告相关路由
+func initReportRouters(router *gin.Engine) {
+	// 获取合规报告列表（表格）
+	router.GET("/api/v1/reports", controller.ComplianceReport.GetReportList)
+
+	report := router.Group("/api/v1/report")
+	{
+		// 生成合规报告
+		report.POST("", controller.ComplianceReport.CreateReport)
+		// 获取合规报告下载地址
+		report.GET("/:id/download", controller.ComplianceReport.GetReportURL)
+		// 删除合规报告
+		report.DELETE("/:id", controller.ComplianceReport.DeleteReport)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"ops-api/config"
+	"ops-api/controller"
 	"ops-api/docs"
 )
 
@@ -15,12 +16,15 @@ type router struct{}
 
 func (r *router) InitRouter(router *gin.Engine) {
 
-	swagger := config.Conf.Settings["swagger"].(bool)
+	swagger := config.GetBool("swagger")
 
 	// Swagger接口文档
 	if swagger {
 		docs.SwaggerInfo.BasePath = ""
 		router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
+		// 各版本接口的Swagger 2.0及OpenAPI 3.0文档，用于生成客户端
+		router.GET("/openapi/:version/swagger.json", controller.OpenAPI.GetSwaggerSpec)
+		router.GET("/openapi/:version/openapi.json", controller.OpenAPI.GetOpenAPISpec)
 	}
 
 	// 注册 pprof 路由
@@ -43,9 +47,35 @@ func (r *router) InitRouter(router *gin.Engine) {
 	initDomainCertificateRouters(router)
 	initKubernetesRouters(router)
 	initUrlRouters(router)
+	initGuideRouters(router)
+	initSCIMRouters(router)
+	initImportRouters(router)
+	initLoginPolicyRouters(router)
+	initStatsRouters(router)
+	initReportRouters(router)
+	initLoginHookRouters(router)
+	initFeatureFlagRouters(router)
+	initDeviceRouters(router)
+	initExternalRouters(router)
+	initProvisionRuleRouters(router)
+	initSessionRouters(router)
+	initLandingRuleRouters(router)
+	initProvisionWebhookRouters(router)
+	initMaintenanceRouters(router)
+	initAlertRouters(router)
+	initTermsRouters(router)
+	initServiceClientRouters(router)
+	initConsentRouters(router)
+	initSigningKeyRouters(router)
+	initAccessRequestRouters(router)
+	initAuditorRouters(router)
+	initSetupRouters(router)
+	initConsoleEventRouters(router)
+	initConformanceRouters(router)
 
 	// 健康检查
 	router.GET("/health", func(c *gin.Context) {
 		c.String(200, "ok")
 	})
+	router.GET("/health/ready", controller.Health.GetReadiness)
 }
@@ -0,0 +1,32 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+	"ops-api/middleware"
+)
+
+// 初始化SCIM相关路由，使用独立的Bearer令牌认证
+func initSCIMRouters(router *gin.Engine) {
+	scim := router.Group("/scim/v2", middleware.SCIMAuth())
+	{
+		// 服务能力说明
+		scim.GET("/ServiceProviderConfig", controller.SCIM.ServiceProviderConfig)
+
+		// 用户
+		scim.GET("/Users", controller.SCIM.ListUsers)
+		scim.GET("/Users/:id", controller.SCIM.GetUser)
+		scim.POST("/Users", controller.SCIM.CreateUser)
+		scim.PUT("/Users/:id", controller.SCIM.ReplaceUser)
+		scim.PATCH("/Users/:id", controller.SCIM.PatchUser)
+		scim.DELETE("/Users/:id", controller.SCIM.DeleteUser)
+
+		// 分组
+		scim.GET("/Groups", controller.SCIM.ListGroups)
+		scim.GET("/Groups/:id", controller.SCIM.GetGroup)
+		scim.POST("/Groups", controller.SCIM.CreateGroup)
+		scim.PUT("/Groups/:id", controller.SCIM.ReplaceGroup)
+		scim.PATCH("/Groups/:id", controller.SCIM.PatchGroup)
+		scim.DELETE("/Groups/:id", controller.SCIM.DeleteGroup)
+	}
+}
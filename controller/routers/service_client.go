@@ -0,0 +1,24 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化服务客户端相关路由
+func initServiceClientRouters(router *gin.Engine) {
+	// 获取服务客户端列表（表格）
+	router.GET("/api/v1/service_clients", controller.ServiceClient.GetServiceClientList)
+
+	client := router.Group("/api/v1/service_client")
+	{
+		// 新增服务客户端
+		client.POST("", controller.ServiceClient.AddServiceClient)
+		// 修改服务客户端
+		client.PUT("", controller.ServiceClient.UpdateServiceClient)
+		// 删除服务客户端
+		client.DELETE("/:id", controller.ServiceClient.DeleteServiceClient)
+		// 重置服务客户端密钥
+		client.PUT("/:id/secret", controller.ServiceClient.ResetServiceClientSecret)
+	}
+}
@@ -0,0 +1,26 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化会话相关路由
+func initSessionRouters(router *gin.Engine) {
+	// 获取会话列表（表格）
+	router.GET("/api/v1/sessions", controller.Session.GetSessionList)
+	// 获取当前用户的会话
+	router.GET("/api/v1/user/sessions", controller.Session.GetUserSessions)
+	// 获取会话并发策略列表（表格）
+	router.GET("/api/v1/session_policies", controller.Session.GetSessionPolicyList)
+
+	policy := router.Group("/api/v1/session_policy")
+	{
+		// 新增会话并发策略
+		policy.POST("", controller.Session.AddSessionPolicy)
+		// 修改会话并发策略
+		policy.PUT("", controller.Session.UpdateSessionPolicy)
+		// 删除会话并发策略
+		policy.DELETE("/:id", controller.Session.DeleteSessionPolicy)
+	}
+}
@@ -17,6 +17,12 @@ func initSettingsRouters(router *gin.Engine) {
 		settings.GET("/site/logo", controller.Settings.GetLogo)
 		// 修改配置
 		settings.PUT("", controller.Settings.UpdateSettings)
+		// 预览配置修改
+		settings.POST("/preview", controller.Settings.PreviewSettings)
+		// 获取配置修改记录
+		settings.GET("/revisions", controller.Settings.GetSettingsRevisionList)
+		// 回滚配置修改
+		settings.POST("/revision/:id/rollback", controller.Settings.RollbackSettings)
 		// 证书替换
 		settings.PUT("/cert", controller.Settings.CertUpdate)
 		// 发送邮箱测试
@@ -0,0 +1,14 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化系统初始化相关路由
+func initSetupRouters(router *gin.Engine) {
+	// 获取系统初始化状态
+	router.GET("/api/v1/setup/status", controller.Setup.GetSetupStatus)
+	// 系统初始化
+	router.POST("/api/v1/setup", controller.Setup.CreateSetup)
+}
@@ -0,0 +1,22 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化签名密钥相关路由
+func initSigningKeyRouters(router *gin.Engine) {
+	// 获取签名密钥列表
+	router.GET("/api/v1/signing_keys", controller.SigningKey.GetSigningKeyList)
+
+	key := router.Group("/api/v1/signing_key")
+	{
+		// 生成签名密钥
+		key.POST("", controller.SigningKey.AddSigningKey)
+		// 启用签名密钥
+		key.PUT("/:id/activate", controller.SigningKey.ActivateSigningKey)
+		// 停用签名密钥
+		key.PUT("/:id/retire", controller.SigningKey.RetireSigningKey)
+	}
+}
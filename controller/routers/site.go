@@ -16,12 +16,18 @@ func initSiteRouters(router *gin.Engine) {
 		site.POST("", controller.Site.AddSite)
 		// 修改站点
 		site.PUT("", controller.Site.UpdateSite)
+		// 站点配置预检
+		site.POST("/validate", controller.Site.ValidateSite)
 		// 删除站点
 		site.DELETE("/:id", controller.Site.DeleteSite)
+		// 获取站点集成模板列表
+		site.GET("/templates", controller.Site.GetSiteTemplateList)
 		// 上传站点Logo
 		site.POST("/logoUpload", controller.Site.UploadLogo)
 		// 获取站点列表（导航页）
 		site.GET("/guide", controller.Site.GetSiteGuideList)
+		// 获取公开应用目录
+		site.GET("/directory", controller.Site.GetPublicDirectory)
 		// 创建站点分组
 		site.POST("/group", controller.Site.AddGroup)
 		// 修改站点分组
@@ -30,7 +36,25 @@ func initSiteRouters(router *gin.Engine) {
 		site.DELETE("/group/:id", controller.Site.DeleteGroup)
 		// 修改站点用户
 		site.PUT("/users", controller.Site.UpdateSiteUser)
+		// 设置站点授权有效期
+		site.PUT("/grant/expiry", controller.Site.UpdateSiteGrantExpiry)
+		// 获取即将到期的站点授权
+		site.GET("/grants/expiring", controller.Site.GetExpiringSiteGrants)
 		// 修改站点标签
 		site.PUT("/tags", controller.Site.UpdateSiteTag)
+		// 获取站点单点登录错误报告
+		site.GET("/sso_errors", controller.Site.GetSiteErrorReport)
+		// 获取站点集成描述
+		site.GET("/integration", controller.Site.GetSiteIntegration)
+		// 下载站点集成配置文件
+		site.GET("/integration/file", controller.Site.DownloadSiteIntegrationFile)
+		// 获取站点SP证书
+		site.GET("/certificates", controller.Site.GetSiteCertificates)
+		// 新增站点SP证书
+		site.POST("/certificate", controller.Site.AddSiteCertificate)
+		// 修改站点SP证书
+		site.PUT("/certificate", controller.Site.UpdateSiteCertificate)
+		// 删除站点SP证书
+		site.DELETE("/certificate/:id", controller.Site.DeleteSiteCertificate)
 	}
 }
@@ -16,28 +16,47 @@ func initSSORouters(router *gin.Engine) {
 		sso.POST("/oauth/authorize", controller.SSO.OAuthAuthorize)
 		// 获取Token（OAuth2.0）
 		sso.POST("/oauth/token", controller.SSO.GetToken)
+		// 设备申请授权（OAuth2.0 设备授权）
+		sso.POST("/oauth/device_authorization", controller.SSO.DeviceAuthorization)
+		// 获取设备授权信息（OAuth2.0 设备授权）
+		sso.GET("/oauth/device", controller.SSO.GetDeviceVerification)
+		// 确认设备授权（OAuth2.0 设备授权）
+		sso.POST("/oauth/device", controller.SSO.VerifyDevice)
 		// 获取用户信息（OAuth2.0 GET请求）
 		sso.GET("/oauth/userinfo", controller.SSO.GetUserInfo)
 		// 获取用户信息（OAuth2.0 POST请求）
 		sso.POST("/oauth/userinfo", controller.SSO.GetUserInfo)
 		// 获取Jwks配置
 		sso.GET("/oidc/jwks", controller.SSO.GetJwksConfig)
+		// 动态注册客户端（OIDC）
+		sso.POST("/oidc/register", controller.SSO.RegisterClient)
 		// 获取授权（CAS3.0）
 		sso.POST("/cas/authorize", controller.SSO.CASAuthorize)
 		// 获取IDP元数据（SAML2）
 		sso.GET("/saml/metadata", controller.SSO.GetIdPMetadata)
 		// SP授权（SAML2）
 		sso.POST("/saml/authorize", controller.SSO.SPAuthorize)
+		// RP授权（WS-Fed）
+		sso.POST("/wsfed/authorize", controller.SSO.WsFedAuthorize)
 		// SP元数据解析
 		sso.POST("/saml/metadata", controller.Site.ParseSPMetadata)
 		// SP HTTP-POST
 		sso.POST("/saml/post", controller.SSO.SPHttpPost)
+		// SAML2单点注销（HTTP-Redirect）
+		sso.GET("/saml/slo", controller.SSO.SAMLSingleLogout)
+		// SAML2单点注销（HTTP-POST）
+		sso.POST("/saml/slo", controller.SSO.SAMLSingleLogout)
 		// Cookie认证（用于Nginx转发过来的认证请求）
 		sso.GET("/cookie/auth", controller.SSO.CookieAuth)
 	}
 
 	// CAS3.0客户端票据校验
 	router.GET("/p3/serviceValidate", controller.SSO.CASServiceValidate)
+	// CAS1.0及CAS2.0客户端票据校验
+	router.GET("/validate", controller.SSO.CASValidate)
+	router.GET("/serviceValidate", controller.SSO.CASServiceValidateV2)
 	// 获取OIDC配置
 	router.GET("/.well-known/openid-configuration", controller.SSO.GetOIDCConfig)
+	// 获取联合元数据（WS-Fed）
+	router.GET("/FederationMetadata/2007-06/FederationMetadata.xml", controller.SSO.GetWsFedMetadata)
 }
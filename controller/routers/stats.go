@@ -0,0 +1,28 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化统计看板相关路由
+func initStatsRouters(router *gin.Engine) {
+
+	stats := router.Group("/api/v1/stats")
+	{
+		// 获取登录趋势
+		stats.GET("/logins", controller.Stats.GetLoginTrend)
+		// 获取应用登录次数排行
+		stats.GET("/apps", controller.Stats.GetAppLaunches)
+		// 获取登录失败统计
+		stats.GET("/failures", controller.Stats.GetFailureStats)
+		// 获取用户登录次数排行
+		stats.GET("/users", controller.Stats.GetUserLoginStats)
+		// 获取操作日志统计
+		stats.GET("/operations", controller.Stats.GetOplogStats)
+		// 获取MFA使用率趋势
+		stats.GET("/mfa", controller.Stats.GetMFAAdoption)
+		// 回填统计数据
+		stats.POST("/rollup", controller.Stats.RollupStats)
+	}
+}
@@ -0,0 +1,28 @@
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/controller"
+)
+
+// 初始化使用条款相关路由
+func initTermsRouters(router *gin.Engine) {
+	terms := router.Group("/api/v1/terms")
+	{
+		// 获取使用条款列表（表格）
+		terms.GET("", controller.Terms.GetTermsList)
+		// 新增使用条款
+		terms.POST("", controller.Terms.AddTerms)
+		// 修改使用条款
+		terms.PUT("", controller.Terms.UpdateTerms)
+		// 删除使用条款
+		terms.DELETE("/:id", controller.Terms.DeleteTerms)
+		// 获取使用条款的历史版本
+		terms.GET("/:id/versions", controller.Terms.GetTermsVersions)
+		// 获取使用条款接受记录（表格）
+		terms.GET("/acceptances", controller.Terms.GetAcceptanceList)
+	}
+
+	// 用户接受使用条款
+	router.POST("/api/v1/user/terms/accept", controller.Terms.AcceptTerms)
+}
@@ -22,10 +22,28 @@ func initUserRouters(router *gin.Engine) {
 		user.PUT("/reset_password", controller.User.UpdateUserPassword)
 		// 重置用户MFA
 		user.PUT("/reset_mfa/:id", controller.User.ResetUserMFA)
+		// 设置应急账号
+		user.PUT("/break_glass", controller.User.UpdateBreakGlass)
+		// 强制下线
+		user.PUT("/logout/:id", controller.User.LogoutUser)
 		// 获取用户列表（下拉框：分组用户管理）
 		user.GET("/list", controller.User.GetUserListAll)
 		// 用户头像上传
 		user.POST("/avatarUpload", controller.User.UploadAvatar)
+		// 获取头像临时上传链接
+		user.GET("/avatarUploadUrl", controller.User.GetAvatarUploadURL)
+		// 头像更新（临时链接上传完成后调用）
+		user.PUT("/avatar", controller.User.UpdateAvatar)
+		// 设置首选语言
+		user.PUT("/language", controller.User.UpdateLanguage)
+		// 设置时区
+		user.PUT("/timezone", controller.User.UpdateTimezone)
+		// 导出当前用户的个人数据
+		user.GET("/personal_data", controller.Privacy.ExportPersonalData)
+		// 擦除用户个人数据
+		user.POST("/erase", controller.Privacy.EraseUser)
+		// 获取用户数据擦除记录
+		user.GET("/erasures", controller.Privacy.GetUserErasureList)
 		// 从LDAP从步用户
 		user.POST("/sync/ad", controller.User.UserSyncAd)
 	}
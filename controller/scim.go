@@ -0,0 +1,307 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"ops-api/service"
+	"strconv"
+)
+
+var SCIM scim
+
+type scim struct{}
+
+// scimResponse 按SCIM协议格式返回数据
+func scimResponse(c *gin.Context, code int, data interface{}) {
+	c.Header("Content-Type", "application/scim+json")
+	c.JSON(code, data)
+}
+
+// scimErrorResponse 按SCIM协议格式返回错误信息
+func scimErrorResponse(c *gin.Context, err error) {
+	var scimErr *service.ScimError
+	if !errors.As(err, &scimErr) {
+		logger.Error("ERROR：" + err.Error())
+		scimErr = service.NewScimError(http.StatusInternalServerError, "", err.Error())
+	}
+	scimResponse(c, scimErr.StatusCode(), scimErr)
+}
+
+// scimListParams 获取SCIM列表查询参数，count未传递时为-1
+func scimListParams(c *gin.Context) (filter string, startIndex, count int) {
+	startIndex, _ = strconv.Atoi(c.DefaultQuery("startIndex", "1"))
+	count, err := strconv.Atoi(c.DefaultQuery("count", "-1"))
+	if err != nil {
+		count = -1
+	}
+	return c.Query("filter"), startIndex, count
+}
+
+// scimClient 获取SCIM请求来源
+func scimClient(c *gin.Context) *service.ScimClient {
+	return &service.ScimClient{
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// ServiceProviderConfig 获取SCIM服务能力说明
+// @Summary 获取SCIM服务能力说明
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Success 200 {object} object
+// @Router /scim/v2/ServiceProviderConfig [get]
+func (s *scim) ServiceProviderConfig(c *gin.Context) {
+	scimResponse(c, http.StatusOK, service.SCIM.ServiceProviderConfig())
+}
+
+// ListUsers 获取用户列表
+// @Summary 获取用户列表
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param filter query string false "过滤条件，例如 userName eq \"zhangsan\""
+// @Param startIndex query int false "起始位置，从1开始"
+// @Param count query int false "分页大小"
+// @Success 200 {object} service.ScimListResponse{Resources=[]service.ScimUser}
+// @Router /scim/v2/Users [get]
+func (s *scim) ListUsers(c *gin.Context) {
+	filter, startIndex, count := scimListParams(c)
+	data, err := service.SCIM.ListUsers(filter, startIndex, count)
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusOK, data)
+}
+
+// GetUser 获取单个用户
+// @Summary 获取单个用户
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param id path string true "用户ID"
+// @Success 200 {object} service.ScimUser
+// @Router /scim/v2/Users/{id} [get]
+func (s *scim) GetUser(c *gin.Context) {
+	data, err := service.SCIM.GetUser(c.Param("id"))
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusOK, data)
+}
+
+// CreateUser 创建用户
+// @Summary 创建用户
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param user body service.ScimUser true "用户信息"
+// @Success 201 {object} service.ScimUser
+// @Router /scim/v2/Users [post]
+func (s *scim) CreateUser(c *gin.Context) {
+	var params = &service.ScimUser{}
+	if err := c.ShouldBindJSON(params); err != nil {
+		scimErrorResponse(c, service.NewScimError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return
+	}
+
+	data, err := service.SCIM.CreateUser(scimClient(c), params)
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusCreated, data)
+}
+
+// ReplaceUser 全量更新用户
+// @Summary 全量更新用户
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param id path string true "用户ID"
+// @Param user body service.ScimUser true "用户信息"
+// @Success 200 {object} service.ScimUser
+// @Router /scim/v2/Users/{id} [put]
+func (s *scim) ReplaceUser(c *gin.Context) {
+	var params = &service.ScimUser{}
+	if err := c.ShouldBindJSON(params); err != nil {
+		scimErrorResponse(c, service.NewScimError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return
+	}
+
+	data, err := service.SCIM.ReplaceUser(scimClient(c), c.Param("id"), params)
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusOK, data)
+}
+
+// PatchUser 部分更新用户
+// @Summary 部分更新用户
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param id path string true "用户ID"
+// @Param operations body service.ScimPatchOp true "更新操作"
+// @Success 200 {object} service.ScimUser
+// @Router /scim/v2/Users/{id} [patch]
+func (s *scim) PatchUser(c *gin.Context) {
+	var params = &service.ScimPatchOp{}
+	if err := c.ShouldBindJSON(params); err != nil {
+		scimErrorResponse(c, service.NewScimError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return
+	}
+
+	data, err := service.SCIM.PatchUser(scimClient(c), c.Param("id"), params)
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusOK, data)
+}
+
+// DeleteUser 删除用户
+// @Summary 删除用户
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param id path string true "用户ID"
+// @Success 204
+// @Router /scim/v2/Users/{id} [delete]
+func (s *scim) DeleteUser(c *gin.Context) {
+	if err := service.SCIM.DeleteUser(scimClient(c), c.Param("id")); err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListGroups 获取分组列表
+// @Summary 获取分组列表
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param filter query string false "过滤条件，例如 displayName eq \"运维组\""
+// @Param startIndex query int false "起始位置，从1开始"
+// @Param count query int false "分页大小"
+// @Success 200 {object} service.ScimListResponse{Resources=[]service.ScimGroup}
+// @Router /scim/v2/Groups [get]
+func (s *scim) ListGroups(c *gin.Context) {
+	filter, startIndex, count := scimListParams(c)
+	data, err := service.SCIM.ListGroups(filter, startIndex, count)
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusOK, data)
+}
+
+// GetGroup 获取单个分组
+// @Summary 获取单个分组
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param id path string true "分组ID"
+// @Success 200 {object} service.ScimGroup
+// @Router /scim/v2/Groups/{id} [get]
+func (s *scim) GetGroup(c *gin.Context) {
+	data, err := service.SCIM.GetGroup(c.Param("id"))
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusOK, data)
+}
+
+// CreateGroup 创建分组
+// @Summary 创建分组
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param group body service.ScimGroup true "分组信息"
+// @Success 201 {object} service.ScimGroup
+// @Router /scim/v2/Groups [post]
+func (s *scim) CreateGroup(c *gin.Context) {
+	var params = &service.ScimGroup{}
+	if err := c.ShouldBindJSON(params); err != nil {
+		scimErrorResponse(c, service.NewScimError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return
+	}
+
+	data, err := service.SCIM.CreateGroup(scimClient(c), params)
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusCreated, data)
+}
+
+// ReplaceGroup 全量更新分组
+// @Summary 全量更新分组
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param id path string true "分组ID"
+// @Param group body service.ScimGroup true "分组信息"
+// @Success 200 {object} service.ScimGroup
+// @Router /scim/v2/Groups/{id} [put]
+func (s *scim) ReplaceGroup(c *gin.Context) {
+	var params = &service.ScimGroup{}
+	if err := c.ShouldBindJSON(params); err != nil {
+		scimErrorResponse(c, service.NewScimError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return
+	}
+
+	data, err := service.SCIM.ReplaceGroup(scimClient(c), c.Param("id"), params)
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusOK, data)
+}
+
+// PatchGroup 部分更新分组
+// @Summary 部分更新分组
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param id path string true "分组ID"
+// @Param operations body service.ScimPatchOp true "更新操作"
+// @Success 200 {object} service.ScimGroup
+// @Router /scim/v2/Groups/{id} [patch]
+func (s *scim) PatchGroup(c *gin.Context) {
+	var params = &service.ScimPatchOp{}
+	if err := c.ShouldBindJSON(params); err != nil {
+		scimErrorResponse(c, service.NewScimError(http.StatusBadRequest, "invalidSyntax", err.Error()))
+		return
+	}
+
+	data, err := service.SCIM.PatchGroup(scimClient(c), c.Param("id"), params)
+	if err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	scimResponse(c, http.StatusOK, data)
+}
+
+// DeleteGroup 删除分组
+// @Summary 删除分组
+// @Description SCIM相关接口
+// @Tags SCIM
+// @Param Authorization header string true "Bearer SCIM令牌"
+// @Param id path string true "分组ID"
+// @Success 204
+// @Router /scim/v2/Groups/{id} [delete]
+func (s *scim) DeleteGroup(c *gin.Context) {
+	if err := service.SCIM.DeleteGroup(scimClient(c), c.Param("id")); err != nil {
+		scimErrorResponse(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/dao"
+	"ops-api/service"
+	"strconv"
+)
+
+var ServiceClient serviceClient
+
+type serviceClient struct{}
+
+// GetServiceClientList 获取服务客户端列表（表格）
+// @Summary 获取服务客户端列表（表格）
+// @Description 服务客户端相关接口
+// @Tags 服务客户端管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "名称"
+// @Success 200 {object} DataResult{data=dao.ServiceClientList}
+// @Router /api/v1/service_clients [get]
+func (s *serviceClient) GetServiceClientList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.ServiceClient.GetServiceClientList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddServiceClient 创建服务客户端
+// @Summary 创建服务客户端
+// @Description 服务客户端相关接口，其它内部平台使用服务客户端通过/api/v1/sso/oauth/token（grant_type=client_credentials）获取短期服务令牌后调用平台接口；scopes为允许调用的接口名称，多个以空格分隔；client_secret仅在创建时返回
+// @Tags 服务客户端管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param client body service.ServiceClientCreate true "服务客户端信息"
+// @Success 200 {object} MsgDataResult{data=service.ServiceClientSecret} "创建成功"
+// @Router /api/v1/service_client [post]
+func (s *serviceClient) AddServiceClient(c *gin.Context) {
+	var data = &service.ServiceClientCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	client, err := service.ServiceClient.AddServiceClient(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", client)
+}
+
+// UpdateServiceClient 更新服务客户端
+// @Summary 更新服务客户端
+// @Description 服务客户端相关接口，更新后此前签发的服务令牌立即失效
+// @Tags 服务客户端管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param client body dao.ServiceClientUpdate true "服务客户端信息"
+// @Success 200 {object} MsgDataResult{data=model.ServiceClient} "更新成功"
+// @Router /api/v1/service_client [put]
+func (s *serviceClient) UpdateServiceClient(c *gin.Context) {
+	var data = &dao.ServiceClientUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	client, err := service.ServiceClient.UpdateServiceClient(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", client)
+}
+
+// ResetServiceClientSecret 重置服务客户端密钥
+// @Summary 重置服务客户端密钥
+// @Description 服务客户端相关接口，重置后此前签发的服务令牌立即失效，新的client_secret仅返回一次
+// @Tags 服务客户端管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "服务客户端ID"
+// @Success 200 {object} MsgDataResult{data=service.ServiceClientSecret} "重置成功"
+// @Router /api/v1/service_client/{id}/secret [put]
+func (s *serviceClient) ResetServiceClientSecret(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	client, err := service.ServiceClient.ResetServiceClientSecret(id)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "重置成功", client)
+}
+
+// DeleteServiceClient 删除服务客户端
+// @Summary 删除服务客户端
+// @Description 服务客户端相关接口，删除后此前签发的服务令牌立即失效
+// @Tags 服务客户端管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "服务客户端ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/service_client/{id} [delete]
+func (s *serviceClient) DeleteServiceClient(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.ServiceClient.DeleteServiceClient(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
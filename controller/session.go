@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+	"strconv"
+)
+
+var Session session
+
+type session struct{}
+
+// admitSession 登录成功后检查维护模式、记录会话并执行会话并发策略，返回错误时拒绝本次登录
+func admitSession(c *gin.Context, token string) error {
+	if err := service.Maintenance.Admit(token); err != nil {
+		return err
+	}
+	return service.Session.Admit(token, service.NewDeviceInfo(c.Request.Header), c.ClientIP())
+}
+
+// GetSessionList 获取会话列表（表格）
+// @Summary 获取会话列表（表格）
+// @Description 会话相关接口，包括已注销的会话，revoke_reason为evicted时表示会话超出会话并发策略限制被注销
+// @Tags 用户管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param username query string false "用户名"
+// @Param active query bool false "仅返回在线的会话"
+// @Success 200 {object} DataResult{data=dao.UserSessionList}
+// @Router /api/v1/sessions [get]
+func (s *session) GetSessionList(c *gin.Context) {
+	params := new(struct {
+		Username string `form:"username"`
+		Active   bool   `form:"active"`
+		Page     int    `form:"page" binding:"required"`
+		Limit    int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Session.GetUserSessionList(params.Username, params.Active, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetUserSessions 获取当前用户的会话
+// @Summary 获取当前用户的会话
+// @Description 个人信息管理相关接口，返回最近的50个会话，包括已注销的会话
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]model.UserSession}
+// @Router /api/v1/user/sessions [get]
+func (s *session) GetUserSessions(c *gin.Context) {
+
+	data, err := service.Session.GetUserSessions(c.GetUint("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetSessionPolicyList 获取会话并发策略列表（表格）
+// @Summary 获取会话并发策略列表（表格）
+// @Description 会话并发策略相关接口
+// @Tags 会话并发策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "策略名称"
+// @Success 200 {object} DataResult{data=dao.SessionPolicyList}
+// @Router /api/v1/session_policies [get]
+func (s *session) GetSessionPolicyList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Session.GetSessionPolicyList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddSessionPolicy 创建会话并发策略
+// @Summary 创建会话并发策略
+// @Description 会话并发策略相关接口，限制分组内用户的最大会话数量（max_sessions）或每种设备类型仅允许一个会话（per_device_type），超出限制时拒绝新的登录（action=1）或注销最早的会话（action=2）
+// @Tags 会话并发策略管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param policy body service.SessionPolicyCreate true "策略信息"
+// @Success 200 {object} MsgDataResult{data=model.SessionPolicy} "创建成功"
+// @Router /api/v1/session_policy [post]
+func (s *session) AddSessionPolicy(c *gin.Context) {
+	var data = &service.SessionPolicyCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	policy, err := service.Session.AddSessionPolicy(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", policy)
+}
+
+// UpdateSessionPolicy 更新会话并发策略
+// @Summary 更新会话并发策略
+// @Description 会话并发策略相关接口，用户下次登录时生效
+// @Tags 会话并发策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param policy body service.SessionPolicyUpdate true "策略信息"
+// @Success 200 {object} MsgDataResult{data=model.SessionPolicy} "更新成功"
+// @Router /api/v1/session_policy [put]
+func (s *session) UpdateSessionPolicy(c *gin.Context) {
+	var data = &service.SessionPolicyUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	policy, err := service.Session.UpdateSessionPolicy(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", policy)
+}
+
+// DeleteSessionPolicy 删除会话并发策略
+// @Summary 删除会话并发策略
+// @Description 会话并发策略相关接口
+// @Tags 会话并发策略管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "策略ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/session_policy/{id} [delete]
+func (s *session) DeleteSessionPolicy(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.Session.DeleteSessionPolicy(id); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
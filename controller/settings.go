@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"ops-api/service"
 	"path/filepath"
+	"strconv"
 )
 
 var Settings settings
@@ -18,13 +19,13 @@ type settings struct{}
 // @Description 配置相接口
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=object}
 // @Router /api/v1/settings [get]
 func (s *settings) GetSettings(c *gin.Context) {
 
 	data, err := service.Settings.GetAllSettingsWithParsedValues()
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -39,13 +40,13 @@ func (s *settings) GetSettings(c *gin.Context) {
 // @Description 配置相接口
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "path": logoPreview}"
+// @Success 200 {object} PathResult
 // @Router /api/v1/settings/site/logo [get]
 func (s *settings) GetLogo(c *gin.Context) {
 
 	logoPreview, err := service.Settings.GetLogo()
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -61,7 +62,7 @@ func (s *settings) GetLogo(c *gin.Context) {
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body service.SettingsUpdate true "配置信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult "更新成功"
 // @Router /api/v1/settings [put]
 func (s *settings) UpdateSettings(c *gin.Context) {
 	var data = &service.SettingsUpdate{}
@@ -72,22 +73,106 @@ func (s *settings) UpdateSettings(c *gin.Context) {
 	}
 
 	// 更新
-	result, err := service.Settings.UpdateSettingValues(data)
+	result, err := service.Settings.UpdateSettingValues(data, c.GetString("username"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	CreateOrUpdateResponse(c, 0, "更新成功", result)
 }
 
+// PreviewSettings 预览配置修改
+// @Summary 预览配置修改
+// @Description 配置相接口，校验配置并返回与当前配置的差异，不修改配置，problems不为空时无法应用
+// @Tags 配置相接口
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param task body service.SettingsUpdate true "配置信息"
+// @Success 200 {object} DataResult{data=service.SettingsPreview}
+// @Router /api/v1/settings/preview [post]
+func (s *settings) PreviewSettings(c *gin.Context) {
+	var data = &service.SettingsUpdate{}
+
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	preview, err := service.Settings.Preview(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": preview,
+	})
+}
+
+// GetSettingsRevisionList 获取配置修改记录
+// @Summary 获取配置修改记录
+// @Description 配置相接口
+// @Tags 配置相接口
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Success 200 {object} DataResult{data=service.SettingsRevisionList}
+// @Router /api/v1/settings/revisions [get]
+func (s *settings) GetSettingsRevisionList(c *gin.Context) {
+	params := new(struct {
+		Page  int `form:"page" binding:"required"`
+		Limit int `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Settings.GetSettingsRevisionList(params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// RollbackSettings 回滚配置修改
+// @Summary 回滚配置修改
+// @Description 配置相接口，将配置恢复为指定修改之前的值，修改之后又被修改过的配置不允许回滚
+// @Tags 配置相接口
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "修改记录ID"
+// @Success 200 {object} MsgDataResult "回滚成功"
+// @Router /api/v1/settings/revision/{id}/rollback [post]
+func (s *settings) RollbackSettings(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	result, err := service.Settings.Rollback(uint(id), c.GetString("username"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "回滚成功", result)
+}
+
 // UploadLogo 上传 Logo
 // @Summary 上传 Logo
 // @Description 配置相接口
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param logo formData file true "Logo"
-// @Success 200 {string} json "{"code": 0, "path": logoPreview}"
+// @Success 200 {object} PathResult
 // @Router /api/v1/settings/logoUpload [post]
 func (s *settings) UploadLogo(c *gin.Context) {
 	// 获取上传的Logo
@@ -103,13 +188,13 @@ func (s *settings) UploadLogo(c *gin.Context) {
 	// 执行上传
 	logoPreview, err := service.Settings.UploadLogo(logoPath, logo)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 保存
 	if err := service.Settings.UpdateSettingValue("logo", logoPath); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -126,7 +211,7 @@ func (s *settings) UploadLogo(c *gin.Context) {
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body service.MailTest true "接收者邮箱"
-// @Success 200 {string} json "{"code": 0: "msg": "发送成功"}"
+// @Success 200 {object} Result "发送成功"
 // @Router /api/v1/settings/test/mailSend [post]
 func (s *settings) SendMail(c *gin.Context) {
 
@@ -140,7 +225,7 @@ func (s *settings) SendMail(c *gin.Context) {
 
 	// 测试
 	if err := service.Settings.MailTest(data.Receiver); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -155,7 +240,7 @@ func (s *settings) SendMail(c *gin.Context) {
 // @Description 配置相接口
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0: "msg": "接口调用成功"}"
+// @Success 200 {object} Result "接口调用成功"
 // @Router /api/v1/settings/test/smsSend [post]
 func (s *settings) SendSms(c *gin.Context) {
 
@@ -163,7 +248,7 @@ func (s *settings) SendSms(c *gin.Context) {
 	username, _ := c.Get("username")
 
 	if err := service.Settings.SmsTest(username.(string)); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -179,7 +264,7 @@ func (s *settings) SendSms(c *gin.Context) {
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body service.CertTest true "密钥信息"
-// @Success 200 {string} json "{"code": 0: "msg": "测试成功"}"
+// @Success 200 {object} Result "测试成功"
 // @Router /api/v1/settings/test/certTest [post]
 func (s *settings) CertTest(c *gin.Context) {
 
@@ -192,7 +277,7 @@ func (s *settings) CertTest(c *gin.Context) {
 	}
 
 	if err := service.Settings.CertTest(data.Certificate, data.PrivateKey, data.PublicKey); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -208,7 +293,7 @@ func (s *settings) CertTest(c *gin.Context) {
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body service.CertTest true "密钥信息"
-// @Success 200 {string} json "{"code": 0: "msg": "更新成功"}"
+// @Success 200 {object} Result "更新成功"
 // @Router /api/v1/settings/cert [put]
 func (s *settings) CertUpdate(c *gin.Context) {
 
@@ -222,14 +307,14 @@ func (s *settings) CertUpdate(c *gin.Context) {
 
 	// 证书及密钥测试
 	if err := service.Settings.CertTest(data.Certificate, data.PrivateKey, data.PublicKey); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 证书及密钥更新
 	result, err := service.Settings.CertUpdate(data.Certificate, data.PrivateKey, data.PublicKey)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -242,7 +327,7 @@ func (s *settings) CertUpdate(c *gin.Context) {
 // @Tags 配置相接口
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body service.LoginTest true "用户名密码"
-// @Success 200 {string} json "{"code": 0: "msg": "登录成功"}"
+// @Success 200 {object} Result "登录成功"
 // @Router /api/v1/settings/test/ldapLogin [post]
 func (s *settings) LdapLogin(c *gin.Context) {
 
@@ -256,7 +341,7 @@ func (s *settings) LdapLogin(c *gin.Context) {
 
 	// 测试
 	if err := service.Settings.LoginTest(data.Username, data.Password); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
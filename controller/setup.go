@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/service"
+)
+
+var Setup setup
+
+type setup struct{}
+
+// GetSetupStatus 获取系统初始化状态
+// @Summary 获取系统初始化状态
+// @Description 系统初始化相关接口，无需登录，前端可据此判断是否展示初始化页面
+// @Tags 配置相接口
+// @Success 200 {object} DataResult{data=service.SetupStatus}
+// @Router /api/v1/setup/status [get]
+func (s *setup) GetSetupStatus(c *gin.Context) {
+
+	status, err := service.Setup.GetStatus()
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": status,
+	})
+}
+
+// CreateSetup 系统初始化
+// @Summary 系统初始化
+// @Description 系统初始化相关接口，无需登录，仅能在系统未初始化时调用，使用启动日志中的初始化令牌创建管理员（admin）及默认角色
+// @Tags 配置相接口
+// @Param setup body service.SetupCreate true "初始化信息"
+// @Success 200 {object} Result "初始化成功"
+// @Router /api/v1/setup [post]
+func (s *setup) CreateSetup(c *gin.Context) {
+	var data = &service.SetupCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Setup.Create(data); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "初始化成功")
+}
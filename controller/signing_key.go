@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+	"strconv"
+)
+
+var SigningKey signingKey
+
+type signingKey struct{}
+
+// GetSigningKeyList 获取签名密钥列表
+// @Summary 获取签名密钥列表
+// @Description 签名密钥相关接口，系统配置的密钥不在列表中，没有启用的签名密钥时使用系统配置的密钥签名
+// @Tags 签名密钥管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]service.SigningKeyItem}
+// @Router /api/v1/signing_keys [get]
+func (s *signingKey) GetSigningKeyList(c *gin.Context) {
+
+	data, err := service.SigningKey.GetSigningKeyList()
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddSigningKey 生成签名密钥
+// @Summary 生成签名密钥
+// @Description 签名密钥相关接口，新密钥立即发布在JWKS中，待客户端刷新JWKS后再启用
+// @Tags 签名密钥管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param key body service.SigningKeyCreate true "密钥信息"
+// @Success 200 {object} DataResult{data=model.SigningKey} "生成成功"
+// @Router /api/v1/signing_key [post]
+func (s *signingKey) AddSigningKey(c *gin.Context) {
+
+	var data = &service.SigningKeyCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	key, err := service.SigningKey.AddSigningKey(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "生成成功", key)
+}
+
+// ActivateSigningKey 启用签名密钥
+// @Summary 启用签名密钥
+// @Description 签名密钥相关接口，此后签发的Token使用该密钥签名，原签名密钥停用并在其签发的Token过期前继续发布在JWKS中
+// @Tags 签名密钥管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "签名密钥ID"
+// @Success 200 {object} DataResult{data=model.SigningKey} "启用成功"
+// @Router /api/v1/signing_key/{id}/activate [put]
+func (s *signingKey) ActivateSigningKey(c *gin.Context) {
+
+	// 对ID进行类型转换
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	key, err := service.SigningKey.ActivateSigningKey(uint(id), c.GetString("username"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "启用成功", key)
+}
+
+// RetireSigningKey 停用签名密钥
+// @Summary 停用签名密钥
+// @Description 签名密钥相关接口，停用的密钥在其签发的Token过期前继续发布在JWKS中，停用当前启用的签名密钥后使用系统配置的密钥签名
+// @Tags 签名密钥管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "签名密钥ID"
+// @Success 200 {object} DataResult{data=model.SigningKey} "停用成功"
+// @Router /api/v1/signing_key/{id}/retire [put]
+func (s *signingKey) RetireSigningKey(c *gin.Context) {
+
+	// 对ID进行类型转换
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	key, err := service.SigningKey.RetireSigningKey(uint(id), c.GetString("username"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "停用成功", key)
+}
@@ -1,14 +1,15 @@
 package controller
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"net/http"
 	"ops-api/dao"
+	"ops-api/middleware"
 	"ops-api/service"
 	"ops-api/utils"
-	"path/filepath"
 	"strconv"
 )
 
@@ -24,7 +25,7 @@ type site struct{}
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "站点名称"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.SiteList}
 // @Router /api/v1/sites [get]
 func (s *site) GetSiteList(c *gin.Context) {
 	params := new(struct {
@@ -40,7 +41,7 @@ func (s *site) GetSiteList(c *gin.Context) {
 
 	data, err := service.Site.GetSiteList(params.GroupName, params.SiteName, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -55,7 +56,7 @@ func (s *site) GetSiteList(c *gin.Context) {
 // @Description 站点相关接口
 // @Tags 站点管理
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.SiteGuideList}
 // @Router /api/v1/site/guide [get]
 func (s *site) GetSiteGuideList(c *gin.Context) {
 	params := new(struct {
@@ -68,7 +69,26 @@ func (s *site) GetSiteGuideList(c *gin.Context) {
 
 	data, err := service.Site.GetSiteGuideList(params.Name)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetPublicDirectory 获取公开应用目录
+// @Summary 获取公开应用目录
+// @Description 站点相关接口，无需登录，仅返回标记为公开的站点，需在系统设置中开启公开应用目录
+// @Tags 站点管理
+// @Success 200 {object} DataResult{data=[]dao.PublicDirectoryItem}
+// @Router /api/v1/site/directory [get]
+func (s *site) GetPublicDirectory(c *gin.Context) {
+	data, err := service.Site.GetPublicDirectory()
+	if err != nil {
+		Response(c, 90403, err.Error())
 		return
 	}
 
@@ -86,7 +106,7 @@ func (s *site) GetSiteGuideList(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param group body service.SiteGroupCreate true "分组信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.SiteGroup} "创建成功"
 // @Router /api/v1/site/group [post]
 func (s *site) AddGroup(c *gin.Context) {
 	var group = &service.SiteGroupCreate{}
@@ -98,13 +118,27 @@ func (s *site) AddGroup(c *gin.Context) {
 
 	siteGroup, err := service.Site.AddGroup(group)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	CreateOrUpdateResponse(c, 0, "创建成功", siteGroup)
 }
 
+// GetSiteTemplateList 获取站点集成模板列表
+// @Summary 获取站点集成模板列表
+// @Description 站点相关接口
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Success 200 {object} DataResult{data=[]service.SiteTemplate}
+// @Router /api/v1/site/templates [get]
+func (s *site) GetSiteTemplateList(c *gin.Context) {
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": service.Site.GetSiteTemplateList(),
+	})
+}
+
 // AddSite 创建站点
 // @Summary 创建站点
 // @Description 站点相关接口
@@ -113,7 +147,7 @@ func (s *site) AddGroup(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param group body service.SiteCreate true "分组信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Site} "创建成功"
 // @Router /api/v1/site [post]
 func (s *site) AddSite(c *gin.Context) {
 	var data = &service.SiteCreate{}
@@ -125,33 +159,57 @@ func (s *site) AddSite(c *gin.Context) {
 
 	site, err := service.Site.AddSite(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	CreateOrUpdateResponse(c, 0, "创建成功", site)
 }
 
+// ValidateSite 站点配置预检
+// @Summary 站点配置预检
+// @Description 站点相关接口，创建或修改站点前校验配置：获取并解析SP Metadata、探测回调地址、校验证书，不保存站点
+// @Tags 站点管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param site body service.SiteValidate true "站点配置"
+// @Success 200 {object} DataResult{data=service.SiteValidateResult}
+// @Router /api/v1/site/validate [post]
+func (s *site) ValidateSite(c *gin.Context) {
+	var data = &service.SiteValidate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": service.Site.ValidateSite(data),
+	})
+}
+
 // DeleteGroup 删除站点分组
 // @Summary 删除站点分组
 // @Description 站点相关接口
 // @Tags 站点管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "分组ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/site/group/{id} [delete]
 func (s *site) DeleteGroup(c *gin.Context) {
 
 	// 对ID进行类型转换
 	groupID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 执行删除
 	if err := service.Site.DeleteGroup(groupID); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -164,20 +222,20 @@ func (s *site) DeleteGroup(c *gin.Context) {
 // @Tags 站点管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "分组ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/site/{id} [delete]
 func (s *site) DeleteSite(c *gin.Context) {
 
 	// 对ID进行类型转换
 	siteID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 执行删除
 	if err := service.Site.DeleteSite(siteID); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -190,7 +248,7 @@ func (s *site) DeleteSite(c *gin.Context) {
 // @Tags 站点管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param group body service.SiteGroupUpdate true "分组信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.SiteGroup} "更新成功"
 // @Router /api/v1/site/group [put]
 func (s *site) UpdateGroup(c *gin.Context) {
 	var data = &service.SiteGroupUpdate{}
@@ -204,7 +262,7 @@ func (s *site) UpdateGroup(c *gin.Context) {
 	// 更新站点分组信息
 	group, err := service.Site.UpdateGroup(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -217,7 +275,7 @@ func (s *site) UpdateGroup(c *gin.Context) {
 // @Tags 站点管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param group body dao.UpdateSite true "分组信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Site} "更新成功"
 // @Router /api/v1/site [put]
 func (s *site) UpdateSite(c *gin.Context) {
 	var data = &dao.UpdateSite{}
@@ -231,7 +289,7 @@ func (s *site) UpdateSite(c *gin.Context) {
 	// 更新站点信息
 	site, err := service.Site.UpdateSite(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -244,7 +302,7 @@ func (s *site) UpdateSite(c *gin.Context) {
 // @Tags 站点管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param logo formData file true "头像"
-// @Success 200 {string} json "{"code": 0, "path": logoPath}"
+// @Success 200 {object} PathResult
 // @Router /api/v1/site/logoUpload [post]
 func (s *site) UploadLogo(c *gin.Context) {
 	// 获取上传的Logo
@@ -257,26 +315,34 @@ func (s *site) UploadLogo(c *gin.Context) {
 	// 打开上传的图片
 	src, err := logo.Open()
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
+		return
+	}
+	defer src.Close()
+
+	// 校验图片并重新编码
+	image, err := service.Upload.Image(logo.Filename, src)
+	if err != nil {
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 上传图片到MinIO
 	// 拼接存储的路径（此路径为临时路径，在表单提交时会将图片移动到实际位置）
-	logoPath := fmt.Sprintf("site/logo/%s%s", uuid.New(), filepath.Ext(logo.Filename))
+	logoPath := fmt.Sprintf("site/logo/%s%s", uuid.New(), image.Ext)
 	// 检查对象是否存在，err不为空是则表示对象已存在
 	_, err = utils.StatObject(logoPath)
 	if err == nil {
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  "上传的对象已存在",
 		})
 		return
 	}
 
-	err = utils.FileUpload(logoPath, logo.Header.Get("Content-Type"), src, logo.Size)
+	err = utils.FileUpload(logoPath, image.ContentType, bytes.NewReader(image.Data), int64(len(image.Data)))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -293,7 +359,7 @@ func (s *site) UploadLogo(c *gin.Context) {
 // @Tags 站点管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param users body service.SiteUserUpdate true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Site} "更新成功"
 // @Router /api/v1/site/users [put]
 func (s *site) UpdateSiteUser(c *gin.Context) {
 	var data = &service.SiteUserUpdate{}
@@ -307,7 +373,7 @@ func (s *site) UpdateSiteUser(c *gin.Context) {
 	// 更新用户信息
 	site, err := service.Site.UpdateSiteUser(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -320,7 +386,7 @@ func (s *site) UpdateSiteUser(c *gin.Context) {
 // @Tags 站点管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param users body service.SiteTagUpdate true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.Site} "更新成功"
 // @Router /api/v1/site/tags [put]
 func (s *site) UpdateSiteTag(c *gin.Context) {
 	var data = &service.SiteTagUpdate{}
@@ -334,9 +400,254 @@ func (s *site) UpdateSiteTag(c *gin.Context) {
 	// 更新用户信息
 	site, err := service.Site.UpdateSiteTag(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	CreateOrUpdateResponse(c, 0, "更新成功", site)
 }
+
+// GetSiteErrorReport 获取站点单点登录错误报告
+// @Summary 获取站点单点登录错误报告
+// @Description 站点相关接口
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param site_id query int false "站点ID，为0时获取未注册应用的错误"
+// @Param days query int false "统计最近几天的错误，默认为7天"
+// @Success 200 {object} DataResult{data=service.SSOErrorReport}
+// @Router /api/v1/site/sso_errors [get]
+func (s *site) GetSiteErrorReport(c *gin.Context) {
+	params := new(struct {
+		SiteID uint `form:"site_id"`
+		Days   int  `form:"days" binding:"omitempty,min=1,max=90"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Site.GetSiteErrorReport(params.SiteID, params.Days)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetSiteCertificates 获取站点SP证书
+// @Summary 获取站点SP证书
+// @Description 站点相关接口
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param site_id query int true "站点ID"
+// @Success 200 {object} DataResult{data=[]model.SiteCertificate}
+// @Router /api/v1/site/certificates [get]
+func (s *site) GetSiteCertificates(c *gin.Context) {
+	params := new(struct {
+		SiteID uint `form:"site_id" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Site.GetSiteCertificates(params.SiteID)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddSiteCertificate 新增站点SP证书
+// @Summary 新增站点SP证书
+// @Description 站点相关接口
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param certificate body service.SiteCertificateCreate true "证书信息"
+// @Success 200 {object} MsgDataResult{data=model.SiteCertificate} "创建成功"
+// @Router /api/v1/site/certificate [post]
+func (s *site) AddSiteCertificate(c *gin.Context) {
+	var data = &service.SiteCertificateCreate{}
+
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	certificate, err := service.Site.AddSiteCertificate(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", certificate)
+}
+
+// UpdateSiteCertificate 修改站点SP证书
+// @Summary 修改站点SP证书
+// @Description 站点相关接口
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param certificate body service.SiteCertificateUpdate true "证书信息"
+// @Success 200 {object} Result "更新成功"
+// @Router /api/v1/site/certificate [put]
+func (s *site) UpdateSiteCertificate(c *gin.Context) {
+	var data = &service.SiteCertificateUpdate{}
+
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Site.UpdateSiteCertificate(data); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", nil)
+}
+
+// DeleteSiteCertificate 删除站点SP证书
+// @Summary 删除站点SP证书
+// @Description 站点相关接口
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "证书ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/site/certificate/{id} [delete]
+func (s *site) DeleteSiteCertificate(c *gin.Context) {
+
+	// 对ID进行类型转换
+	certificateID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.Site.DeleteSiteCertificate(uint(certificateID)); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
+
+// UpdateSiteGrantExpiry 设置站点授权有效期
+// @Summary 设置站点授权有效期
+// @Description 站点相关接口，为已授权访问站点的用户设置授权到期时间，到期后自动撤销授权，到期时间为空时永久有效
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param grant body service.SiteGrantExpiryUpdate true "授权有效期"
+// @Success 200 {object} Result "更新成功"
+// @Router /api/v1/site/grant/expiry [put]
+func (s *site) UpdateSiteGrantExpiry(c *gin.Context) {
+	var data = &service.SiteGrantExpiryUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.SiteGrant.UpdateExpiry(data, c.GetString("username")); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "更新成功")
+}
+
+// GetExpiringSiteGrants 获取即将到期的站点授权
+// @Summary 获取即将到期的站点授权
+// @Description 站点相关接口，列出指定天数内到期的授权（包含已到期尚未撤销的授权），用于授权复核
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param days query int false "到期天数，默认为30天"
+// @Success 200 {object} DataResult{data=[]dao.SiteGrantItem}
+// @Router /api/v1/site/grants/expiring [get]
+func (s *site) GetExpiringSiteGrants(c *gin.Context) {
+	params := new(struct {
+		Days int `form:"days" binding:"omitempty,min=1,max=365"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.SiteGrant.GetExpiringList(params.Days)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetSiteIntegration 获取站点集成描述
+// @Summary 获取站点集成描述
+// @Description 站点相关接口，根据站点配置及当前环境的地址生成应用侧的对接配置，包括环境变量、OIDC客户端JSON、SAML2 Metadata及Nginx/Traefik代理配置示例
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param site_id query int true "站点ID"
+// @Success 200 {object} DataResult{data=service.SiteIntegration}
+// @Router /api/v1/site/integration [get]
+func (s *site) GetSiteIntegration(c *gin.Context) {
+	params := new(struct {
+		SiteID uint `form:"site_id" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Site.GetSiteIntegration(params.SiteID)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// DownloadSiteIntegrationFile 下载站点集成配置文件
+// @Summary 下载站点集成配置文件
+// @Description 站点相关接口，下载站点集成描述中的单个配置文件，如：oidc-client.json、idp-metadata.xml、sp-metadata.xml、nginx.conf、traefik.yml、.env
+// @Tags 站点管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param site_id query int true "站点ID"
+// @Param name query string true "文件名"
+// @Success 200 {string} string "配置文件"
+// @Router /api/v1/site/integration/file [get]
+func (s *site) DownloadSiteIntegrationFile(c *gin.Context) {
+	params := new(struct {
+		SiteID uint   `form:"site_id" binding:"required"`
+		Name   string `form:"name" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	file, err := service.Site.GetSiteIntegrationFile(params.SiteID, params.Name)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+file.Name)
+	c.Data(http.StatusOK, file.ContentType+"; charset=utf-8", []byte(file.Content))
+}
@@ -3,7 +3,6 @@ package controller
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/wonderivan/logger"
 	"io"
 	"ops-api/service"
 )
@@ -19,8 +18,8 @@ func (s *sms) SMSCallback(c *gin.Context) {
 	body, _ := io.ReadAll(c.Request.Body)
 	bodyStr := fmt.Sprintf("%s", body)
 
-	if err := service.SMS.SMSCallback(bodyStr); err != nil {
-		logger.Error("ERROR：" + err.Error())
+	if err := service.SMS.SMSCallback(bodyStr, c.Query("ts"), c.Query("sign")); err != nil {
+		Response(c, 90403, err.Error())
 		return
 	}
 }
@@ -4,19 +4,36 @@ import (
 	"errors"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
-	"ops-api/dao"
 	"ops-api/middleware"
 	"ops-api/service"
-	"ops-api/utils"
+	"ops-api/utils/i18n"
 )
 
 var SSO sso
 
 type sso struct{}
 
-// CookieAuth Cookie认证
+// protocolRetired 应用使用的单点登录协议已停用时返回停用信息，前端展示停用页面，返回是否已处理
+func protocolRetired(c *gin.Context, err error) bool {
+	var retiredErr *service.ProtocolRetiredError
+	if !errors.As(err, &retiredErr) {
+		return false
+	}
+	c.JSON(middleware.StatusCode(90410), gin.H{
+		"code": 90410,
+		"msg":  retiredErr.Error(),
+		"data": retiredErr,
+	})
+	return true
+}
+
+// CookieAuth Cookie认证，票据续期时通过 X-Nginx-Token 响应头及 Set-Cookie 返回新Token，
+// Nginx 可使用 auth_request_set $sso_cookie $upstream_http_set_cookie 获取后通过 add_header Set-Cookie 返回给浏览器
 // @Summary Cookie认证
 // @Description Cookie认证相关接口
 // @Tags Cookie认证
@@ -27,10 +44,17 @@ func (s *sso) CookieAuth(c *gin.Context) {
 	// 获取 Cookie 并校验
 	cookies := c.Request.Cookies()
 	for _, cookie := range cookies {
-		// 获取Token（如果有数据则表明：1、Code存在，2、在有效期内）
-		token, _ := utils.Decrypt(cookie.Value)
-		ticket, _ := dao.SSO.GetAuthorizeToken(token)
-		if ticket.Token == token {
+		renewed, err := service.SSO.NginxCookieAuth(cookie.Value)
+		if err == nil {
+			if renewed != "" {
+				c.Header("X-Nginx-Token", renewed)
+				http.SetCookie(c.Writer, &http.Cookie{
+					Name:     cookie.Name,
+					Value:    renewed,
+					Path:     "/",
+					HttpOnly: true,
+				})
+			}
 			c.JSON(http.StatusOK, gin.H{
 				"code": 0,
 				"data": "认证成功",
@@ -51,7 +75,7 @@ func (s *sso) CookieAuth(c *gin.Context) {
 // @Tags Cookie认证
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param authorize body service.NginxAuthorize true "授权请求参数"
-// @Success 200 {string} json "{"code": 0, "msg": 授权成功, "redirect_uri": redirect_uri}"
+// @Success 200 {object} AuthorizeResult
 // @Router /api/v1/sso/nginx/authorize [get]
 func (s *sso) NginxAuthorize(c *gin.Context) {
 
@@ -72,7 +96,7 @@ func (s *sso) NginxAuthorize(c *gin.Context) {
 	token := c.Request.Header.Get("Authorization")
 	mc, err := middleware.ValidateJWT(token)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -80,20 +104,29 @@ func (s *sso) NginxAuthorize(c *gin.Context) {
 	callbackUrl, application, err := service.SSO.GetNginxAuthorize(
 		&service.NginxAuthorize{CallbackURL: params.NginxRedirectURI},
 		mc.ID,
+		mc.SessionID,
 	)
 	if err != nil {
+		// 需要先接受使用条款
+		if termsRequired(c, err) {
+			return
+		}
 		// 记录登录失败信息
 		if err := service.User.RecordLoginInfo("账号密码", mc.Username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
+			return
+		}
+		// 应用使用的单点登录协议已停用
+		if protocolRetired(c, err) {
 			return
 		}
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 记录登录授权信息
 	if err := service.User.RecordLoginInfo("SSO授权", mc.Username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -105,13 +138,60 @@ func (s *sso) NginxAuthorize(c *gin.Context) {
 	})
 }
 
+// asOAuthError 转换为OAuth2.0协议错误，非协议错误视为服务端内部错误
+func asOAuthError(err error) *service.OAuthError {
+	var oauthErr *service.OAuthError
+	if errors.As(err, &oauthErr) {
+		return oauthErr
+	}
+	logger.Error("ERROR：" + err.Error())
+	return service.NewOAuthServerError()
+}
+
+// oauthErrorResponse 按OAuth2.0协议格式返回错误信息（Token、用户信息接口）
+func oauthErrorResponse(c *gin.Context, err error) {
+	oauthErr := asOAuthError(err)
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(oauthErr.StatusCode(), oauthErr)
+}
+
+// oauthAuthorizeErrorResponse 授权接口错误响应
+// 授权接口由前端调用：客户端校验通过后的错误（如无权访问）按协议拼接到客户端回调地址，由前端跳转回客户端；
+// 其余错误（如客户端未注册）不能重定向到客户端，返回协议错误信息，同时保留code、msg字段供前端展示
+func oauthAuthorizeErrorResponse(c *gin.Context, err error) {
+	oauthErr := asOAuthError(err)
+
+	if redirectURI := oauthErr.RedirectURI(); redirectURI != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"code":         0,
+			"msg":          oauthErr.Description,
+			"redirect_uri": redirectURI,
+		})
+		return
+	}
+
+	code := 90400
+	if oauthErr.StatusCode() >= http.StatusInternalServerError {
+		code = 90500
+	}
+	c.JSON(oauthErr.StatusCode(), gin.H{
+		"code":              code,
+		"msg":               oauthErr.Description,
+		"error":             oauthErr.ErrorCode,
+		"error_description": oauthErr.Description,
+		"state":             oauthErr.State,
+	})
+}
+
 // OAuthAuthorize 客户端授权
 // @Summary 客户端授权
 // @Description OAuth2.0认证相关接口
 // @Tags OAuth2.0认证
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param authorize body service.OAuthAuthorize true "授权请求参数"
-// @Success 200 {string} json "{"code": 0, "msg": 授权成功, "redirect_uri": redirect_uri}"
+// @Success 200 {object} AuthorizeResult
+// @Failure 400 {object} service.OAuthError
 // @Router /api/v1/sso/oauth/authorize [post]
 func (s *sso) OAuthAuthorize(c *gin.Context) {
 
@@ -119,7 +199,7 @@ func (s *sso) OAuthAuthorize(c *gin.Context) {
 
 	// 请求参数绑定
 	if err := c.ShouldBind(&data); err != nil {
-		Response(c, 90400, err.Error())
+		oauthAuthorizeErrorResponse(c, service.NewOAuthError(http.StatusBadRequest, service.OAuthInvalidRequest, err.Error()).WithState(data.State))
 		return
 	}
 
@@ -132,25 +212,37 @@ func (s *sso) OAuthAuthorize(c *gin.Context) {
 	token := c.Request.Header.Get("Authorization")
 	mc, err := middleware.ValidateJWT(token)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		oauthAuthorizeErrorResponse(c, service.NewOAuthError(http.StatusUnauthorized, service.OAuthLoginRequired, "End-user authentication is required").WithState(data.State))
 		return
 	}
 
 	// 获取授权码
-	callbackUrl, application, err := service.SSO.GetOAuthAuthorize(data, mc.ID)
+	callbackUrl, application, err := service.SSO.GetOAuthAuthorize(data, mc.ID, mc.SessionID)
 	if err != nil {
+		// 需要先接受使用条款
+		if termsRequired(c, err) {
+			return
+		}
+		// 需要用户确认授权
+		if consentRequired(c, err) {
+			return
+		}
 		// 记录登录失败信息
 		if err := service.User.RecordLoginInfo("账号密码", mc.Username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
+			return
+		}
+		// 应用使用的单点登录协议已停用
+		if protocolRetired(c, err) {
 			return
 		}
-		Response(c, 90500, err.Error())
+		oauthAuthorizeErrorResponse(c, err)
 		return
 	}
 
 	// 记录登录授权信息
 	if err := service.User.RecordLoginInfo("SSO授权", mc.Username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -164,43 +256,206 @@ func (s *sso) OAuthAuthorize(c *gin.Context) {
 
 // GetToken 客户端认证
 // @Summary 客户端认证
-// @Description OAuth2.0认证相关接口
+// @Description OAuth2.0认证相关接口，客户端认证支持 client_secret_basic、client_secret_post 及 private_key_jwt
 // @Tags OAuth2.0认证
+// @Param Authorization header string false "Basic 客户端凭据（client_secret_basic）"
 // @Param authorize body service.Token true "授权请求参数"
 // @Success 200 {object} service.ResponseToken
+// @Failure 400 {object} service.OAuthError
+// @Failure 401 {object} service.OAuthError
 // @Router /api/v1/oauth/token [post]
 func (s *sso) GetToken(c *gin.Context) {
 
 	var data = &service.Token{}
 
 	// 请求参数绑定
-	if err := c.Bind(&data); err != nil {
-		Response(c, 90400, err.Error())
+	if err := c.ShouldBind(&data); err != nil {
+		oauthErrorResponse(c, service.NewOAuthError(http.StatusBadRequest, service.OAuthInvalidRequest, "Malformed token request"))
 		return
 	}
 
-	// 检查必需参数是否存在（要求客户端在获取Token时必须传入client_id和client_secret）
-	if data.ClientId == "" && data.ClientSecret == "" {
-		err := errors.New("missing required parameters")
-		Response(c, 90400, err.Error())
-		return
+	// 客户端使用HTTP Basic认证（client_secret_basic）
+	if username, password, ok := c.Request.BasicAuth(); ok {
+		if err := data.UseBasicAuth(username, password); err != nil {
+			oauthErrorResponse(c, err)
+			return
+		}
 	}
 
 	token, err := service.SSO.GetToken(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		oauthErrorResponse(c, err)
 		return
 	}
 
+	// Token响应不允许缓存（RFC 6749 5.1）
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
 	c.JSON(http.StatusOK, token)
 }
 
+// DeviceAuthorization 设备申请授权
+// @Summary 设备申请授权
+// @Description OAuth2.0认证相关接口，设备授权（RFC 8628），适用于无法打开浏览器回调的命令行工具及大屏等设备，设备展示user_code及验证地址后使用device_code轮询Token接口
+// @Tags OAuth2.0认证
+// @Param authorize body service.DeviceAuthorization true "设备授权请求参数"
+// @Success 200 {object} service.ResponseDeviceAuthorization
+// @Failure 400 {object} service.OAuthError
+// @Failure 401 {object} service.OAuthError
+// @Router /api/v1/sso/oauth/device_authorization [post]
+func (s *sso) DeviceAuthorization(c *gin.Context) {
+
+	var data = &service.DeviceAuthorization{}
+
+	// 请求参数绑定
+	if err := c.ShouldBind(&data); err != nil {
+		oauthErrorResponse(c, service.NewOAuthError(http.StatusBadRequest, service.OAuthInvalidRequest, "Malformed device authorization request"))
+		return
+	}
+
+	// 客户端使用HTTP Basic认证（client_secret_basic）
+	if username, password, ok := c.Request.BasicAuth(); ok {
+		if err := data.UseBasicAuth(username, password); err != nil {
+			oauthErrorResponse(c, err)
+			return
+		}
+	}
+
+	response, err := service.SSO.DeviceAuthorize(data)
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusOK, response)
+}
+
+// RegisterClient 动态注册客户端
+// @Summary 动态注册客户端
+// @Description OAuth2.0认证相关接口，OIDC动态客户端注册（RFC 7591），需要在系统配置中开启，配置了初始访问令牌时需要携带；注册的应用需要管理员授权用户后才能使用
+// @Tags OAuth2.0认证
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string false "Bearer 初始访问令牌"
+// @Param client body service.ClientRegistration true "客户端元数据"
+// @Success 201 {object} service.ResponseClientRegistration
+// @Failure 400 {object} service.OAuthError
+// @Failure 401 {object} service.OAuthError
+// @Router /api/v1/sso/oidc/register [post]
+func (s *sso) RegisterClient(c *gin.Context) {
+
+	var data = &service.ClientRegistration{}
+
+	// 请求参数绑定
+	if err := c.ShouldBindJSON(data); err != nil {
+		oauthErrorResponse(c, service.NewOAuthError(http.StatusBadRequest, service.OAuthInvalidClientMetadata, "Malformed client metadata"))
+		return
+	}
+
+	response, err := service.SSO.RegisterClient(data, c.Request.Header.Get("Authorization"), c.ClientIP())
+	if err != nil {
+		oauthErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetDeviceVerification 获取设备授权信息
+// @Summary 获取设备授权信息
+// @Description OAuth2.0认证相关接口，验证页面根据用户输入的user_code获取申请授权的应用及Scope，供用户确认
+// @Tags OAuth2.0认证
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param user_code query string true "用户码"
+// @Success 200 {object} DataResult{data=service.DeviceVerificationInfo}
+// @Router /api/v1/sso/oauth/device [get]
+func (s *sso) GetDeviceVerification(c *gin.Context) {
+
+	data, err := service.SSO.GetDeviceVerification(c.Query("user_code"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// VerifyDevice 确认设备授权
+// @Summary 确认设备授权
+// @Description OAuth2.0认证相关接口，用户在验证页面允许或拒绝设备授权，设备下一次轮询Token接口时获取结果
+// @Tags OAuth2.0认证
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param verification body service.DeviceVerification true "确认授权请求参数"
+// @Success 200 {object} Result "授权成功"
+// @Router /api/v1/sso/oauth/device [post]
+func (s *sso) VerifyDevice(c *gin.Context) {
+
+	var data = &service.DeviceVerification{}
+
+	// 请求参数绑定
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	// 获取客户端Agent
+	userAgent := c.Request.UserAgent()
+	// 获取客户端IP
+	clientIP := c.ClientIP()
+
+	// Token校验
+	token := c.Request.Header.Get("Authorization")
+	mc, err := middleware.ValidateJWT(token)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	application, err := service.SSO.VerifyDevice(data, mc.ID, mc.SessionID)
+	if err != nil {
+		// 需要先接受使用条款
+		if termsRequired(c, err) {
+			return
+		}
+		// 记录登录失败信息
+		if application != "" {
+			if err := service.User.RecordLoginInfo("账号密码", mc.Username, userAgent, clientIP, application, err); err != nil {
+				ErrorResponse(c, err)
+				return
+			}
+		}
+		ErrorResponse(c, err)
+		return
+	}
+
+	if !data.Approve {
+		Response(c, 0, "已拒绝授权")
+		return
+	}
+
+	// 记录登录授权信息
+	if err := service.User.RecordLoginInfo("SSO授权", mc.Username, userAgent, clientIP, application, nil); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "授权成功")
+}
+
 // GetUserInfo 获取用户信息
 // @Summary 获取用户信息
 // @Description OAuth2.0认证相关接口
 // @Tags OAuth2.0认证
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Success 200 {object} service.ResponseUserinfo
+// @Failure 401 {object} service.OAuthError
 // @Router /api/v1/oauth/userinfo [get]
 func (s *sso) GetUserInfo(c *gin.Context) {
 
@@ -210,7 +465,14 @@ func (s *sso) GetUserInfo(c *gin.Context) {
 	// 获取用户信息
 	user, err := service.SSO.GetUserinfo(token)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		// Bearer Token校验失败时需返回 WWW-Authenticate 响应头（RFC 6750 3）
+		oauthErr := asOAuthError(err)
+		if oauthErr.ErrorCode == service.OAuthInvalidToken {
+			c.Header("WWW-Authenticate", fmt.Sprintf(`Bearer error="%s", error_description="%s"`, oauthErr.ErrorCode, oauthErr.Description))
+		} else if oauthErr.StatusCode() == http.StatusUnauthorized {
+			c.Header("WWW-Authenticate", "Bearer")
+		}
+		oauthErrorResponse(c, oauthErr)
 		return
 	}
 
@@ -223,7 +485,7 @@ func (s *sso) GetUserInfo(c *gin.Context) {
 // @Tags CAS3.0认证
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param authorize body service.CASAuthorize true "授权请求参数"
-// @Success 200 {string} json "{"code": 0, "msg": 授权成功, "redirect_uri": redirect_uri}"
+// @Success 200 {object} AuthorizeResult
 // @Router /api/v1/sso/cas/authorize [post]
 func (s *sso) CASAuthorize(c *gin.Context) {
 
@@ -244,25 +506,33 @@ func (s *sso) CASAuthorize(c *gin.Context) {
 	token := c.Request.Header.Get("Authorization")
 	mc, err := middleware.ValidateJWT(token)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 获取票据
-	callbackUrl, application, err := service.SSO.GetCASAuthorize(data, mc.ID, mc.Username)
+	callbackUrl, application, err := service.SSO.GetCASAuthorize(data, mc.ID, mc.Username, mc.SessionID)
 	if err != nil {
+		// 需要先接受使用条款
+		if termsRequired(c, err) {
+			return
+		}
 		// 记录登录失败信息
 		if err := service.User.RecordLoginInfo("账号密码", mc.Username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
+			return
+		}
+		// 应用使用的单点登录协议已停用
+		if protocolRetired(c, err) {
 			return
 		}
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 记录登录授权信息
 	if err := service.User.RecordLoginInfo("SSO授权", mc.Username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -280,7 +550,7 @@ func (s *sso) CASAuthorize(c *gin.Context) {
 // @Tags CAS3.0认证
 // @Param authorize body service.CASServiceValidate true "授权请求参数"
 // @Produce xml
-// @Success 200
+// @Success 200 {object} service.CASServiceResponse
 // @Router /p3/serviceValidate [get]
 func (s *sso) CASServiceValidate(c *gin.Context) {
 
@@ -295,7 +565,7 @@ func (s *sso) CASServiceValidate(c *gin.Context) {
 	// 获取票据
 	response, err := service.SSO.ServiceValidate(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -306,19 +576,73 @@ func (s *sso) CASServiceValidate(c *gin.Context) {
 	c.XML(http.StatusOK, response)
 }
 
+// CASValidate 票据校验（CAS1.0）
+// @Summary 票据校验（CAS1.0）
+// @Description CAS3.0认证相关接口，兼容CAS1.0客户端，校验成功时返回“yes”及用户名，失败时返回“no”
+// @Tags CAS3.0认证
+// @Param service query string true "应用回调地址"
+// @Param ticket query string true "票据"
+// @Produce plain
+// @Success 200 {string} string "yes\n用户名\n"
+// @Router /validate [get]
+func (s *sso) CASValidate(c *gin.Context) {
+
+	var data = &service.CASServiceValidate{}
+
+	// 请求参数绑定
+	if err := c.ShouldBind(&data); err != nil {
+		c.String(http.StatusOK, "no\n\n")
+		return
+	}
+
+	// 校验票据
+	username, err := service.SSO.Validate(data)
+	if err != nil {
+		c.String(http.StatusOK, "no\n\n")
+		return
+	}
+
+	c.String(http.StatusOK, "yes\n%s\n", username)
+}
+
+// CASServiceValidateV2 票据校验（CAS2.0）
+// @Summary 票据校验（CAS2.0）
+// @Description CAS3.0认证相关接口，兼容CAS2.0客户端，响应中不包含用户属性，校验失败时返回 authenticationFailure
+// @Tags CAS3.0认证
+// @Param service query string true "应用回调地址"
+// @Param ticket query string true "票据"
+// @Produce xml
+// @Success 200 {object} service.CASServiceResponse
+// @Router /serviceValidate [get]
+func (s *sso) CASServiceValidateV2(c *gin.Context) {
+
+	var data = &service.CASServiceValidate{}
+
+	// 设置响应头为XML格式
+	c.Header("Content-Type", "application/xml")
+
+	// 请求参数绑定
+	if err := c.ShouldBind(&data); err != nil {
+		c.XML(http.StatusOK, service.CASFailureResponse(service.CASInvalidRequest, "service and ticket parameters are both required"))
+		return
+	}
+
+	c.XML(http.StatusOK, service.SSO.ServiceValidateV2(data))
+}
+
 // GetOIDCConfig 获取配置
 // @Summary 获取配置
 // @Description OIDC认证相关接口
 // @Tags OIDC认证
 // @Produce json
-// @Success 200
+// @Success 200 {object} service.OIDCConfig
 // @Router /.well-known/openid-configuration [get]
 func (s *sso) GetOIDCConfig(c *gin.Context) {
 
 	// 获取票据
 	config, err := service.SSO.GetOIDCConfig()
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -336,7 +660,7 @@ func (s *sso) GetJwksConfig(c *gin.Context) {
 
 	jwks, err := service.SSO.GetJwks()
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -357,7 +681,7 @@ func (s *sso) GetIdPMetadata(c *gin.Context) {
 
 	response, err := service.SSO.GetIdPMetadata()
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -372,9 +696,8 @@ func (s *sso) GetIdPMetadata(c *gin.Context) {
 // @Summary SP授权
 // @Description SAML2认证相关接口
 // @Tags SAML2认证
-// @Success 200
 // @Router /api/v1/sso/saml/authorize [post]
-// @Success 200 {string} json "{"code": 0, "data": nil}"
+// @Success 200 {object} DataResult{data=string}
 func (s *sso) SPAuthorize(c *gin.Context) {
 	var data = &service.SAMLRequest{}
 
@@ -393,25 +716,96 @@ func (s *sso) SPAuthorize(c *gin.Context) {
 	token := c.Request.Header.Get("Authorization")
 	mc, err := middleware.ValidateJWT(token)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// authnRequest校验
-	html, application, err := service.SSO.GetSPAuthorize(data, mc.ID)
+	html, application, err := service.SSO.GetSPAuthorize(data, mc.ID, mc.SessionID)
+	if err != nil {
+		// 需要先接受使用条款
+		if termsRequired(c, err) {
+			return
+		}
+		// 记录登录失败信息
+		if err := service.User.RecordLoginInfo("账号密码", mc.Username, userAgent, clientIP, application, err); err != nil {
+			ErrorResponse(c, err)
+			return
+		}
+		// 应用使用的单点登录协议已停用
+		if protocolRetired(c, err) {
+			return
+		}
+		ErrorResponse(c, err)
+		return
+	}
+
+	// 记录登录授权信息
+	if err := service.User.RecordLoginInfo("SSO授权", mc.Username, userAgent, clientIP, application, nil); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	// 返回客户端授权信息
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": html,
+	})
+}
+
+// WsFedAuthorize RP授权
+// @Summary RP授权
+// @Description WS-Fed认证相关接口
+// @Tags WS-Fed认证
+// @Param wtrealm query string true "RP标识"
+// @Param wreply query string false "RP回调地址"
+// @Param wctx query string false "RP状态信息"
+// @Router /api/v1/sso/wsfed/authorize [post]
+// @Success 200 {object} DataResult{data=string}
+func (s *sso) WsFedAuthorize(c *gin.Context) {
+	var data = &service.WsFedSignIn{}
+
+	// 请求参数绑定
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	// 获取客户端Agent
+	userAgent := c.Request.UserAgent()
+	// 获取客户端IP
+	clientIP := c.ClientIP()
+
+	// Token校验
+	token := c.Request.Header.Get("Authorization")
+	mc, err := middleware.ValidateJWT(token)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	html, application, err := service.SSO.GetWsFedAuthorize(data, mc.ID)
 	if err != nil {
+		// 需要先接受使用条款
+		if termsRequired(c, err) {
+			return
+		}
 		// 记录登录失败信息
 		if err := service.User.RecordLoginInfo("账号密码", mc.Username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
 			return
 		}
-		Response(c, 90500, err.Error())
+		// 应用使用的单点登录协议已停用
+		if protocolRetired(c, err) {
+			return
+		}
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 记录登录授权信息
 	if err := service.User.RecordLoginInfo("SSO授权", mc.Username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -422,13 +816,29 @@ func (s *sso) SPAuthorize(c *gin.Context) {
 	})
 }
 
+// GetWsFedMetadata 获取WS-Fed联合元数据
+// @Summary 获取WS-Fed联合元数据
+// @Description WS-Fed认证相关接口
+// @Tags WS-Fed认证
+// @Success 200
+// @Router /FederationMetadata/2007-06/FederationMetadata.xml [get]
+func (s *sso) GetWsFedMetadata(c *gin.Context) {
+	metadata, err := service.SSO.GetWsFedMetadata()
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/xml")
+	c.Data(http.StatusOK, "application/xml", []byte(metadata))
+}
+
 // SPHttpPost SP HTTP-POST
 // @Summary SP HTTP-POST
 // @Description SAML2认证相关接口
 // @Tags SAML2认证
 // @Success 200
 // @Router /api/v1/sso/saml/post [post]
-// @Success 200"
 func (s *sso) SPHttpPost(c *gin.Context) {
 	var data = &service.SAMLRequest{}
 	if err := c.ShouldBind(&data); err != nil {
@@ -439,34 +849,116 @@ func (s *sso) SPHttpPost(c *gin.Context) {
 	loginUrl := fmt.Sprintf("https://d-ops.50yc.cn/login?SAMLRequest=%s&RelayState=%s", url.QueryEscape(data.SAMLRequest), url.QueryEscape(data.RelayState))
 
 	c.Header("Content-Type", "text/html; charset=utf-8")
+	locale := service.RequestLocale(c.GetHeader("Accept-Language"))
 	c.HTML(http.StatusOK, "redirect.html", gin.H{
-		"URL": loginUrl,
+		"URL":     loginUrl,
+		"Lang":    locale,
+		"Title":   i18n.T(locale, "sso.title"),
+		"Message": i18n.T(locale, "sso.redirect"),
 	})
 }
 
+// SAMLSingleLogout SAML2单点注销
+// @Summary SAML2单点注销
+// @Description SAML2认证相关接口，SP发起的单点注销，支持HTTP-Redirect及HTTP-POST绑定，LogoutRequest必须使用SP证书签名；注销平台会话后返回自动提交到SP单点注销地址的LogoutResponse表单
+// @Tags SAML2认证
+// @Param SAMLRequest query string true "LogoutRequest"
+// @Param RelayState query string false "RelayState"
+// @Param SigAlg query string false "签名算法（HTTP-Redirect）"
+// @Param Signature query string false "签名（HTTP-Redirect）"
+// @Produce html
+// @Success 200
+// @Router /api/v1/sso/saml/slo [get]
+func (s *sso) SAMLSingleLogout(c *gin.Context) {
+	var data = &service.SAMLLogoutRequest{}
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	locale := service.RequestLocale(c.GetHeader("Accept-Language"))
+	html, err := service.SSO.SAMLSingleLogout(data, c.Request.Method, c.Request.URL.RawQuery, locale)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+}
+
 // ParseSPMetadata SP Metadata解析
 // @Summary SP Metadata解析
-// @Description SAML2认证相关接口
+// @Description SAML2认证相关接口，支持三种方式：JSON请求体传递Metadata地址（sp_metadata_url）或Metadata XML（sp_metadata_xml）；multipart/form-data上传Metadata文件（file）；请求体直接为Metadata XML（Content-Type为application/xml、text/xml或application/samlmetadata+xml）
 // @Tags SAML2认证
-// @Accept application/json
+// @Accept application/json,multipart/form-data,application/xml
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Param url body service.ParseSPMetadata true "授权请求参数"
-// @Success 200 {string} json "{"code": 0, "data": nil}"
+// @Param url body service.ParseSPMetadata false "Metadata地址或Metadata XML"
+// @Param file formData file false "Metadata文件"
+// @Success 200 {object} DataResult{data=service.SPMetadata}
 // @Router /api/v1/sso/saml/metadata [post]
 func (s *site) ParseSPMetadata(c *gin.Context) {
-	var data = &service.ParseSPMetadata{}
 
-	if err := c.ShouldBind(&data); err != nil {
-		Response(c, 90400, err.Error())
-		return
-	}
+	var (
+		metadataInfo *service.SPMetadata
+		err          error
+	)
 
-	// 获取SP Metadata信息
-	metadataInfo, err := service.SSO.ParseSPMetadata(data.SPMetadataURL)
-	if err != nil {
-		Response(c, 90500, err.Error())
-		return
+	switch c.ContentType() {
+	case "multipart/form-data":
+		// 上传的Metadata文件
+		file, err := c.FormFile("file")
+		if err != nil {
+			Response(c, 90400, err.Error())
+			return
+		}
+		content, err := readSPMetadata(file)
+		if err != nil {
+			ErrorResponse(c, err)
+			return
+		}
+		metadataInfo, err = service.SSO.ParseSPMetadataXML(content)
+		if err != nil {
+			Response(c, 90400, err.Error())
+			return
+		}
+	case "application/xml", "text/xml", "application/samlmetadata+xml":
+		// 请求体为Metadata XML，多读取1个字节用于判断是否超过大小限制
+		content, err := io.ReadAll(io.LimitReader(c.Request.Body, service.SPMetadataMaxSize+1))
+		if err != nil {
+			ErrorResponse(c, err)
+			return
+		}
+		metadataInfo, err = service.SSO.ParseSPMetadataXML(content)
+		if err != nil {
+			Response(c, 90400, err.Error())
+			return
+		}
+	default:
+		var data = &service.ParseSPMetadata{}
+		if err := c.ShouldBind(&data); err != nil {
+			Response(c, 90400, err.Error())
+			return
+		}
+
+		switch {
+		case data.SPMetadataXML != "":
+			metadataInfo, err = service.SSO.ParseSPMetadataXML([]byte(data.SPMetadataXML))
+			if err != nil {
+				Response(c, 90400, err.Error())
+				return
+			}
+		case data.SPMetadataURL != "":
+			// 获取SP Metadata信息
+			metadataInfo, err = service.SSO.ParseSPMetadata(data.SPMetadataURL)
+			if err != nil {
+				ErrorResponse(c, err)
+				return
+			}
+		default:
+			Response(c, 90400, "Metadata地址及Metadata XML不能同时为空")
+			return
+		}
 	}
 
 	c.JSON(200, gin.H{
@@ -475,3 +967,14 @@ func (s *site) ParseSPMetadata(c *gin.Context) {
 		"data": metadataInfo,
 	})
 }
+
+// readSPMetadata 读取上传的Metadata文件，多读取1个字节用于判断是否超过大小限制
+func readSPMetadata(file *multipart.FileHeader) ([]byte, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return io.ReadAll(io.LimitReader(src, service.SPMetadataMaxSize+1))
+}
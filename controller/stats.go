@@ -0,0 +1,208 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"ops-api/service"
+)
+
+var Stats stats
+
+type stats struct{}
+
+// GetLoginTrend 获取登录趋势
+// @Summary 获取登录趋势
+// @Description 统计看板相关接口
+// @Tags 统计看板
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param start query string false "开始日期，格式：YYYY-MM-DD"
+// @Param end query string false "结束日期，格式：YYYY-MM-DD"
+// @Param period query string false "统计周期：day、week"
+// @Success 200 {object} DataResult{data=[]service.LoginTrendItem}
+// @Router /api/v1/stats/logins [get]
+func (s *stats) GetLoginTrend(c *gin.Context) {
+	query := &service.StatsQuery{}
+	if err := c.Bind(query); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Stats.GetLoginTrend(query)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetAppLaunches 获取应用登录次数排行
+// @Summary 获取应用登录次数排行
+// @Description 统计看板相关接口
+// @Tags 统计看板
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param start query string false "开始日期，格式：YYYY-MM-DD"
+// @Param end query string false "结束日期，格式：YYYY-MM-DD"
+// @Param limit query int false "排行数量"
+// @Success 200 {object} DataResult{data=[]dao.LoginStatCount}
+// @Router /api/v1/stats/apps [get]
+func (s *stats) GetAppLaunches(c *gin.Context) {
+	query := &service.StatsQuery{}
+	if err := c.Bind(query); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Stats.GetAppLaunches(query)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetFailureStats 获取登录失败统计
+// @Summary 获取登录失败统计
+// @Description 统计看板相关接口
+// @Tags 统计看板
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param start query string false "开始日期，格式：YYYY-MM-DD"
+// @Param end query string false "结束日期，格式：YYYY-MM-DD"
+// @Param limit query int false "失败原因排行数量"
+// @Success 200 {object} DataResult{data=service.FailureStats}
+// @Router /api/v1/stats/failures [get]
+func (s *stats) GetFailureStats(c *gin.Context) {
+	query := &service.StatsQuery{}
+	if err := c.Bind(query); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Stats.GetFailureStats(query)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetUserLoginStats 获取用户登录次数排行
+// @Summary 获取用户登录次数排行
+// @Description 统计看板相关接口
+// @Tags 统计看板
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param start query string false "开始日期，格式：YYYY-MM-DD"
+// @Param end query string false "结束日期，格式：YYYY-MM-DD"
+// @Param limit query int false "排行数量"
+// @Success 200 {object} DataResult{data=service.UserLoginStats}
+// @Router /api/v1/stats/users [get]
+func (s *stats) GetUserLoginStats(c *gin.Context) {
+	query := &service.StatsQuery{}
+	if err := c.Bind(query); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Stats.GetUserLoginStats(query)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetOplogStats 获取操作日志统计
+// @Summary 获取操作日志统计
+// @Description 统计看板相关接口
+// @Tags 统计看板
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param start query string false "开始日期，格式：YYYY-MM-DD"
+// @Param end query string false "结束日期，格式：YYYY-MM-DD"
+// @Param period query string false "统计周期：day、week"
+// @Param limit query int false "排行数量"
+// @Success 200 {object} DataResult{data=service.OplogStats}
+// @Router /api/v1/stats/operations [get]
+func (s *stats) GetOplogStats(c *gin.Context) {
+	query := &service.StatsQuery{}
+	if err := c.Bind(query); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Stats.GetOplogStats(query)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetMFAAdoption 获取MFA使用率趋势
+// @Summary 获取MFA使用率趋势
+// @Description 统计看板相关接口
+// @Tags 统计看板
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param start query string false "开始日期，格式：YYYY-MM-DD"
+// @Param end query string false "结束日期，格式：YYYY-MM-DD"
+// @Success 200 {object} DataResult{data=[]service.MFAAdoptionItem}
+// @Router /api/v1/stats/mfa [get]
+func (s *stats) GetMFAAdoption(c *gin.Context) {
+	query := &service.StatsQuery{}
+	if err := c.Bind(query); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Stats.GetMFAAdoption(query)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// RollupStats 回填统计数据
+// @Summary 回填统计数据
+// @Description 统计看板相关接口
+// @Tags 统计看板
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param rollup body service.StatsRollup true "回填天数或日期范围"
+// @Success 200 {object} Result "统计数据汇总成功"
+// @Router /api/v1/stats/rollup [post]
+func (s *stats) RollupStats(c *gin.Context) {
+	var data = &service.StatsRollup{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Stats.Backfill(data); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "统计数据汇总成功")
+}
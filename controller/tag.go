@@ -3,7 +3,7 @@ package controller
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/wonderivan/logger"
-	"net/http"
+	"ops-api/middleware"
 	"ops-api/service"
 )
 
@@ -19,7 +19,7 @@ func (t *tag) GetTagList(c *gin.Context) {
 	})
 	if err := c.Bind(params); err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90400), gin.H{
 			"code": 90400,
 			"msg":  err.Error(),
 		})
@@ -29,7 +29,7 @@ func (t *tag) GetTagList(c *gin.Context) {
 	data, err := service.Tag.GetTagList(params.Name)
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusOK, gin.H{
+		c.JSON(middleware.StatusCode(90500), gin.H{
 			"code": 90500,
 			"msg":  err.Error(),
 		})
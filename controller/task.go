@@ -19,7 +19,7 @@ type task struct{}
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body service.TaskCreate true "定时任务信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.ScheduledTask} "创建成功"
 // @Router /api/v1/task [post]
 func (t *task) AddTask(c *gin.Context) {
 	var task = &service.TaskCreate{}
@@ -31,7 +31,7 @@ func (t *task) AddTask(c *gin.Context) {
 
 	job, err := service.Task.AddTask(task)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -44,20 +44,20 @@ func (t *task) AddTask(c *gin.Context) {
 // @Tags 定时任务管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "定时任务ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/task/{id} [delete]
 func (t *task) DeleteTask(c *gin.Context) {
 
 	// 对ID进行类型转换
 	taskID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 执行删除
 	if err := service.Task.DeleteTask(taskID); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -70,7 +70,7 @@ func (t *task) DeleteTask(c *gin.Context) {
 // @Tags 定时任务管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body dao.TaskUpdate true "定时任务信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.ScheduledTask} "更新成功"
 // @Router /api/v1/task [put]
 func (t *task) UpdateTask(c *gin.Context) {
 	var data = &dao.TaskUpdate{}
@@ -84,7 +84,7 @@ func (t *task) UpdateTask(c *gin.Context) {
 	// 更新用户信息
 	task, err := service.Task.UpdateTask(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -99,7 +99,7 @@ func (t *task) UpdateTask(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "定时任务名称"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.TaskList}
 // @Router /api/v1/tasks [get]
 func (t *task) GetTaskList(c *gin.Context) {
 	params := new(struct {
@@ -114,7 +114,7 @@ func (t *task) GetTaskList(c *gin.Context) {
 
 	data, err := service.Task.GetTaskList(params.Name, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -132,7 +132,7 @@ func (t *task) GetTaskList(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param id query int true "定时ID"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.TaskLogList}
 // @Router /api/v1/task/logs [get]
 func (t *task) GetTaskLogList(c *gin.Context) {
 	params := new(struct {
@@ -147,7 +147,7 @@ func (t *task) GetTaskLogList(c *gin.Context) {
 
 	data, err := service.Task.GetTaskLogList(params.Id, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
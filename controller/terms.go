@@ -0,0 +1,228 @@
+package controller
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"ops-api/middleware"
+	"ops-api/service"
+	"strconv"
+)
+
+var Terms terms
+
+type terms struct{}
+
+// sessionIssued 判断登录是否已签发用户Token，需要接受使用条款（TERMS_ACCEPT）或确认授权（OAUTH_CONSENT）时用户已完成登录
+func sessionIssued(nextPage *string) bool {
+	return nextPage == nil || *nextPage == service.TermsAcceptPage || *nextPage == service.ConsentPage
+}
+
+// termsRequired 单点登录授权需要先接受使用条款时返回待接受的条款，前端展示条款并在用户接受后重新发起授权
+func termsRequired(c *gin.Context, err error) bool {
+	var termsErr *service.TermsRequiredError
+	if !errors.As(err, &termsErr) {
+		return false
+	}
+	c.JSON(middleware.StatusCode(90412), gin.H{
+		"code": 90412,
+		"msg":  termsErr.Error(),
+		"data": termsErr,
+	})
+	return true
+}
+
+// GetTermsList 获取使用条款列表（表格）
+// @Summary 获取使用条款列表（表格）
+// @Description 使用条款相关接口
+// @Tags 使用条款管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param name query string false "条款名称"
+// @Success 200 {object} DataResult{data=dao.TermsList}
+// @Router /api/v1/terms [get]
+func (t *terms) GetTermsList(c *gin.Context) {
+	params := new(struct {
+		Name  string `form:"name"`
+		Page  int    `form:"page" binding:"required"`
+		Limit int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Terms.GetTermsList(params.Name, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// GetTermsVersions 获取使用条款的历史版本
+// @Summary 获取使用条款的历史版本
+// @Description 使用条款相关接口
+// @Tags 使用条款管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "条款ID"
+// @Success 200 {object} DataResult{data=[]model.TermsVersion}
+// @Router /api/v1/terms/{id}/versions [get]
+func (t *terms) GetTermsVersions(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	data, err := service.Terms.GetTermsVersions(uint(id))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AddTerms 创建使用条款
+// @Summary 创建使用条款
+// @Description 使用条款相关接口，all_sites为true时对所有应用生效，否则仅对指定的应用生效
+// @Tags 使用条款管理
+// @Accept application/json
+// @Produce application/json
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param terms body service.TermsCreate true "条款信息"
+// @Success 200 {object} MsgDataResult{data=model.Terms} "创建成功"
+// @Router /api/v1/terms [post]
+func (t *terms) AddTerms(c *gin.Context) {
+	var data = &service.TermsCreate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	item, err := service.Terms.AddTerms(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "创建成功", item)
+}
+
+// UpdateTerms 更新使用条款
+// @Summary 更新使用条款
+// @Description 使用条款相关接口，条款内容变更时版本号递增，所有用户需要重新接受
+// @Tags 使用条款管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param terms body service.TermsUpdate true "条款信息"
+// @Success 200 {object} MsgDataResult{data=model.Terms} "更新成功"
+// @Router /api/v1/terms [put]
+func (t *terms) UpdateTerms(c *gin.Context) {
+	var data = &service.TermsUpdate{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	item, err := service.Terms.UpdateTerms(data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", item)
+}
+
+// DeleteTerms 删除使用条款
+// @Summary 删除使用条款
+// @Description 使用条款相关接口，历史版本及接受记录会保留
+// @Tags 使用条款管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "条款ID"
+// @Success 200 {object} Result "删除成功"
+// @Router /api/v1/terms/{id} [delete]
+func (t *terms) DeleteTerms(c *gin.Context) {
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	if err := service.Terms.DeleteTerms(uint(id)); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "删除成功")
+}
+
+// GetAcceptanceList 获取使用条款接受记录（表格）
+// @Summary 获取使用条款接受记录（表格）
+// @Description 使用条款相关接口
+// @Tags 使用条款管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param page query int true "分页"
+// @Param limit query int true "分页大小"
+// @Param terms_id query int false "条款ID"
+// @Param username query string false "用户名"
+// @Success 200 {object} DataResult{data=dao.TermsAcceptanceList}
+// @Router /api/v1/terms/acceptances [get]
+func (t *terms) GetAcceptanceList(c *gin.Context) {
+	params := new(struct {
+		TermsID  uint   `form:"terms_id"`
+		Username string `form:"username"`
+		Page     int    `form:"page" binding:"required"`
+		Limit    int    `form:"limit" binding:"required"`
+	})
+	if err := c.Bind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	data, err := service.Terms.GetAcceptanceList(params.TermsID, params.Username, params.Page, params.Limit)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// AcceptTerms 接受使用条款
+// @Summary 接受使用条款
+// @Description 使用条款相关接口，单点登录授权返回90412时展示待接受的条款，用户接受后重新发起授权
+// @Tags 使用条款管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param terms body service.TermsAccept true "接受的条款及版本"
+// @Success 200 {object} Result "接受成功"
+// @Router /api/v1/user/terms/accept [post]
+func (t *terms) AcceptTerms(c *gin.Context) {
+	var data = &service.TermsAccept{}
+
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.Terms.Accept(c.GetUint("id"), c.GetString("username"), data, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	Response(c, 0, "接受成功")
+}
@@ -19,7 +19,7 @@ type urlAddress struct{}
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body service.UrlAddressCreate true "Url信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.DomainCertificateMonitor} "创建成功"
 // @Router /api/v1/url [post]
 func (u *urlAddress) AddUrl(c *gin.Context) {
 	var url = &service.UrlAddressCreate{}
@@ -31,7 +31,7 @@ func (u *urlAddress) AddUrl(c *gin.Context) {
 
 	data, err := service.UrlAddress.AddUrl(url)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -44,18 +44,18 @@ func (u *urlAddress) AddUrl(c *gin.Context) {
 // @Tags Url监控相关
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/url/{id} [delete]
 func (u *urlAddress) DeleteUrl(c *gin.Context) {
 
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	if err := service.UrlAddress.DeleteUrl(id); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -68,7 +68,7 @@ func (u *urlAddress) DeleteUrl(c *gin.Context) {
 // @Tags Url监控相关
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param task body dao.UrlAddressUpdate true "域名信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.DomainCertificateMonitor} "更新成功"
 // @Router /api/v1/url [put]
 func (u *urlAddress) UpdateUrl(c *gin.Context) {
 	var data = &dao.UrlAddressUpdate{}
@@ -79,7 +79,7 @@ func (u *urlAddress) UpdateUrl(c *gin.Context) {
 
 	provider, err := service.UrlAddress.UpdateUrl(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -94,7 +94,7 @@ func (u *urlAddress) UpdateUrl(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "Url信息"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.UrlAddressList}
 // @Router /api/v1/urls [get]
 func (u *urlAddress) GetUrlList(c *gin.Context) {
 
@@ -111,7 +111,7 @@ func (u *urlAddress) GetUrlList(c *gin.Context) {
 
 	data, err := service.UrlAddress.GetUrlList(params.Name, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -127,7 +127,7 @@ func (u *urlAddress) GetUrlList(c *gin.Context) {
 // @Tags Url监控相关
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id query int true "ID"
-// @Success 200 {string} json "{"code": 0, "msg": "检查完成"}"
+// @Success 200 {object} Result "检查完成"
 // @Router /api/v1/url/check [post]
 func (u *urlAddress) CertificateCheck(c *gin.Context) {
 
@@ -141,7 +141,7 @@ func (u *urlAddress) CertificateCheck(c *gin.Context) {
 	}
 
 	if err := service.UrlAddress.ManualCertificateCheck(params.ID); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -6,14 +6,8 @@ import (
 	"github.com/wonderivan/logger"
 	"net/http"
 	"ops-api/dao"
-	"ops-api/global"
-	"ops-api/model"
 	"ops-api/service"
-	"ops-api/utils"
-	"path/filepath"
 	"strconv"
-	"strings"
-	"time"
 )
 
 var User user
@@ -27,7 +21,7 @@ type user struct{}
 // @Accept application/json
 // @Produce application/json
 // @Param user body service.UserLogin true "用户名密码"
-// @Success 200 {string} json "{"code": 0, "token": "用户令牌", "redirect_uri": redirect_uri}"
+// @Success 200 {object} TokenResult
 // @Router /api/auth/login [post]
 func (u *user) Login(c *gin.Context) {
 	var params = &service.UserLogin{}
@@ -42,23 +36,33 @@ func (u *user) Login(c *gin.Context) {
 	// 获取客户端IP
 	clientIP := c.ClientIP()
 
-	token, redirectUri, application, nextPage, err := service.User.Login(params)
+	token, redirectUri, application, nextPage, err := service.User.Login(params, clientIP)
+	// 执行会话并发策略（开启MFA认证时在MFA认证通过后执行）
+	if err == nil && sessionIssued(nextPage) {
+		err = admitSession(c, token)
+	}
 	if err != nil {
 		// 记录登录失败信息
 		if err := service.User.RecordLoginInfo("账号密码", params.Username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
 			return
 		}
-		Response(c, 90500, err.Error())
+		loginFailed(c, err)
 		return
 	}
 	// 记录登录成功信息
 	if err := service.User.RecordLoginInfo("账号密码", params.Username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
-	// 如果开启MFA认证需要携带临时Token和MFA对应页面，前端会跳转至指定的页面进行MFA认证（MFA_AUTH）或开启MFA认证（MFA_ENABLE）
+	// 记录登录设备（开启MFA认证时在MFA认证通过后记录）
+	if sessionIssued(nextPage) {
+		recordDevice(c, params.Username)
+	}
+
+	// 如果开启MFA认证需要携带临时Token和MFA对应页面，前端会跳转至指定的页面进行MFA认证（MFA_AUTH）或开启MFA认证（MFA_ENABLE），
+	// 需要接受使用条款时携带用户Token，前端跳转至条款页面（TERMS_ACCEPT）
 	if nextPage != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"code":     0,
@@ -80,7 +84,7 @@ func (u *user) Login(c *gin.Context) {
 // @Description 用户认证相关接口
 // @Tags 用户认证
 // @Param authorize body service.FeishuLogin true "授权请求参数"
-// @Success 200 {string} json "{"code": 0, "token": "用户令牌", "redirect_uri": redirect_uri}"
+// @Success 200 {object} TokenResult
 // @Router /api/auth/feishu_login [post]
 func (u *user) FeishuLogin(c *gin.Context) {
 
@@ -98,21 +102,27 @@ func (u *user) FeishuLogin(c *gin.Context) {
 	clientIP := c.ClientIP()
 
 	// 获取JWT Token
-	token, redirectUri, username, application, err := service.User.FeishuLogin(params)
+	token, redirectUri, username, application, err := service.User.FeishuLogin(params, clientIP)
+	// 执行会话并发策略
+	if err == nil {
+		err = admitSession(c, token)
+	}
 	if err != nil {
 		// 记录登录失败信息
 		if err := service.User.RecordLoginInfo("飞书扫码", username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
 			return
 		}
-		Response(c, 90500, err.Error())
+		loginFailed(c, err)
 		return
 	}
 	// 记录登录成功信息
 	if err := service.User.RecordLoginInfo("飞书扫码", username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
+	// 记录登录设备
+	recordDevice(c, username)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":         0,
@@ -126,7 +136,7 @@ func (u *user) FeishuLogin(c *gin.Context) {
 // @Description 用户认证相关接口
 // @Tags 用户认证
 // @Param authorize body service.DingTalkLogin true "授权请求参数"
-// @Success 200 {string} json "{"code": 0, "token": "用户令牌", "redirect_uri": redirect_uri}"
+// @Success 200 {object} TokenResult
 // @Router /api/auth/dingtalk_login [post]
 func (u *user) DingTalkLogin(c *gin.Context) {
 
@@ -144,22 +154,28 @@ func (u *user) DingTalkLogin(c *gin.Context) {
 	clientIP := c.ClientIP()
 
 	// 获取JWT Token
-	token, redirectUri, username, application, err := service.User.DingTalkLogin(params)
+	token, redirectUri, username, application, err := service.User.DingTalkLogin(params, clientIP)
+	// 执行会话并发策略
+	if err == nil {
+		err = admitSession(c, token)
+	}
 	if err != nil {
 		// 记录登录失败信息
 		if err := service.User.RecordLoginInfo("钉钉扫码", username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
 			return
 		}
 
-		Response(c, 90500, err.Error())
+		loginFailed(c, err)
 		return
 	}
 	// 记录登录成功信息
 	if err := service.User.RecordLoginInfo("钉钉扫码", username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
+	// 记录登录设备
+	recordDevice(c, username)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":         0,
@@ -173,7 +189,7 @@ func (u *user) DingTalkLogin(c *gin.Context) {
 // @Description 用户认证相关接口
 // @Tags 用户认证
 // @Param authorize body service.WeChatLogin true "授权请求参数"
-// @Success 200 {string} json "{"code": 0, "token": "用户令牌", "redirect_uri": redirect_uri}"
+// @Success 200 {object} TokenResult
 // @Router /api/auth/ww_login [post]
 func (u *user) WeChatLogin(c *gin.Context) {
 
@@ -191,22 +207,28 @@ func (u *user) WeChatLogin(c *gin.Context) {
 	clientIP := c.ClientIP()
 
 	// 获取JWT Token
-	token, redirectUri, username, application, err := service.User.WeChatLogin(params)
+	token, redirectUri, username, application, err := service.User.WeChatLogin(params, clientIP)
+	// 执行会话并发策略
+	if err == nil {
+		err = admitSession(c, token)
+	}
 	if err != nil {
 		// 记录登录信息
 		if err := service.User.RecordLoginInfo("企业微信扫码", username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
 			return
 		}
 
-		Response(c, 90500, err.Error())
+		loginFailed(c, err)
 		return
 	}
 	// 记录登录成功信息
 	if err := service.User.RecordLoginInfo("企业微信扫码", username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
+	// 记录登录设备
+	recordDevice(c, username)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":         0,
@@ -220,17 +242,15 @@ func (u *user) WeChatLogin(c *gin.Context) {
 // @Description 用户认证相关接口
 // @Tags 用户认证
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "data": nil}"
+// @Success 200 {object} Result
 // @Router /api/auth/logout [post]
 func (u *user) Logout(c *gin.Context) {
 	// 获取Token
 	token := c.Request.Header.Get("Authorization")
-	parts := strings.SplitN(token, " ", 2)
 
-	// 将Token存入Redis缓存
-	err := global.RedisClient.Set(parts[1], true, 24*time.Hour).Err()
-	if err != nil {
-		Response(c, 90500, err.Error())
+	// 注销Token及其所属会话，会话签发的单点登录票据一并失效
+	if err := service.Session.Logout(token); err != nil {
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -246,7 +266,8 @@ func (u *user) Logout(c *gin.Context) {
 // @Tags 个人信息管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param avatar formData file true "头像"
-// @Success 200 {string} json "{"code": 0, "msg": "头像更新成功"}"
+// @Success 200 {object} Result "头像更新成功"
+// @Success 202 {object} Result "文件服务暂不可用，头像将在恢复后自动更新"
 // @Router /api/v1/user/avatarUpload [post]
 func (u *user) UploadAvatar(c *gin.Context) {
 	// 获取上传的头像
@@ -271,24 +292,82 @@ func (u *user) UploadAvatar(c *gin.Context) {
 		return
 	}
 
+	defer src.Close()
+
 	// 上传头像
 	// 获取当前登录用户的用户名
 	username, _ := c.Get("username")
-	// 拼接头像存储的路径和文件名：avatar/<用户名><文件后缀>
-	avatarName := fmt.Sprintf("avatar/%v%v", username, filepath.Ext(avatar.Filename))
-	err = utils.FileUpload(avatarName, avatar.Header.Get("Content-Type"), src, avatar.Size)
+	queued, err := service.User.UploadAvatar(username.(string), avatar.Filename, src)
 	if err != nil {
 		logger.Error("ERROR：" + err.Error())
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code": 90500,
-			"msg":  err.Error(),
+		ErrorResponse(c, err)
+		return
+	}
+	if queued {
+		c.JSON(http.StatusAccepted, gin.H{
+			"code": 0,
+			"msg":  "文件服务暂不可用，头像将在恢复后自动更新",
 		})
 		return
 	}
 
-	// 将头像地址存储到数据库
-	var user model.AuthUser
-	global.MySQLClient.Model(&user).Where("username = ?", username).Update("avatar", avatarName)
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "头像更新成功",
+	})
+}
+
+// GetAvatarUploadURL 获取头像临时上传链接
+// @Summary 获取头像临时上传链接
+// @Description 个人信息管理相关接口
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param filename query string true "头像文件名"
+// @Success 200 {object} DataResult{data=service.AvatarUploadURL}
+// @Router /api/v1/user/avatarUploadUrl [get]
+func (u *user) GetAvatarUploadURL(c *gin.Context) {
+	filename := c.Query("filename")
+	if filename == "" {
+		Response(c, 90400, "文件名不能为空")
+		return
+	}
+
+	username, _ := c.Get("username")
+	data, err := service.User.GetAvatarUploadURL(username.(string), filename)
+	if err != nil {
+		logger.Error("ERROR：" + err.Error())
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": data,
+	})
+}
+
+// UpdateAvatar 头像更新（通过临时链接上传完成后调用）
+// @Summary 头像更新
+// @Description 个人信息管理相关接口
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param avatar body service.AvatarUpdate true "头像对象路径"
+// @Success 200 {object} Result "头像更新成功"
+// @Router /api/v1/user/avatar [put]
+func (u *user) UpdateAvatar(c *gin.Context) {
+	var data = &service.AvatarUpdate{}
+
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	username, _ := c.Get("username")
+	if err := service.User.UpdateAvatar(username.(string), data); err != nil {
+		logger.Error("ERROR：" + err.Error())
+		ErrorResponse(c, err)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
@@ -296,19 +375,74 @@ func (u *user) UploadAvatar(c *gin.Context) {
 	})
 }
 
+// UpdateTimezone 设置时区
+// @Summary 设置时区
+// @Description 个人信息管理相关接口，接口返回的时间带有时区偏移（默认为服务器本地时区，配置文件mysql.loc设置为UTC时为UTC），前端按用户时区展示；通知邮件及个人生成的报告按用户时区展示时间
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param timezone body service.TimezoneUpdate true "时区，如：Asia/Shanghai，为空时使用系统时区"
+// @Success 200 {object} Result "时区设置成功"
+// @Router /api/v1/user/timezone [put]
+func (u *user) UpdateTimezone(c *gin.Context) {
+	var data = &service.TimezoneUpdate{}
+
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.User.UpdateTimezone(c.GetString("username"), data); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "时区设置成功",
+	})
+}
+
+// UpdateLanguage 设置首选语言
+// @Summary 设置首选语言
+// @Description 个人信息管理相关接口
+// @Tags 个人信息管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param language body service.LanguageUpdate true "语言，如：zh-CN、en-US"
+// @Success 200 {object} Result "语言设置成功"
+// @Router /api/v1/user/language [put]
+func (u *user) UpdateLanguage(c *gin.Context) {
+	var data = &service.LanguageUpdate{}
+
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.User.UpdateLanguage(c.GetString("username"), data); err != nil {
+		logger.Error("ERROR：" + err.Error())
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "语言设置成功",
+	})
+}
+
 // GetUser 获取用户信息
 // @Summary 获取用户信息
 // @Description 用户认证相关接口
 // @Tags 用户认证
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "data": {}}"
+// @Success 200 {object} DataResult{data=dao.UserInfoWithMenu}
 // @Router /api/v1/user/info [get]
 func (u *user) GetUser(c *gin.Context) {
 
 	// 获取用户信息
 	data, err := service.User.GetUser(c.GetUint("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -324,7 +458,7 @@ func (u *user) GetUser(c *gin.Context) {
 // @Description 用户相关接口
 // @Tags 用户管理
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.UserListAll}
 // @Router /api/v1/user/list [get]
 func (u *user) GetUserListAll(c *gin.Context) {
 
@@ -348,7 +482,7 @@ func (u *user) GetUserListAll(c *gin.Context) {
 // @Param page query int true "分页"
 // @Param limit query int true "分页大小"
 // @Param name query string false "用户姓名"
-// @Success 200 {string} json "{"code": 0, "data": []}"
+// @Success 200 {object} DataResult{data=dao.UserList}
 // @Router /api/v1/users [get]
 func (u *user) GetUserList(c *gin.Context) {
 	params := new(struct {
@@ -363,7 +497,7 @@ func (u *user) GetUserList(c *gin.Context) {
 
 	data, err := service.User.GetUserList(params.Name, params.Page, params.Limit)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -381,7 +515,7 @@ func (u *user) GetUserList(c *gin.Context) {
 // @Produce application/json
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body dao.UserCreate true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "创建成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.AuthUser} "创建成功"
 // @Router /api/v1/user [post]
 func (u *user) AddUser(c *gin.Context) {
 	var user = &dao.UserCreate{}
@@ -393,7 +527,7 @@ func (u *user) AddUser(c *gin.Context) {
 
 	authUser, err := service.User.AddUser(user)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -406,20 +540,20 @@ func (u *user) AddUser(c *gin.Context) {
 // @Tags 用户管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "用户ID"
-// @Success 200 {string} json "{"code": 0, "msg": "删除成功"}"
+// @Success 200 {object} Result "删除成功"
 // @Router /api/v1/user/{id} [delete]
 func (u *user) DeleteUser(c *gin.Context) {
 
 	// 对ID进行类型转换
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 执行删除
 	if err := service.User.DeleteUser(userID); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -432,7 +566,7 @@ func (u *user) DeleteUser(c *gin.Context) {
 // @Tags 用户管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body dao.UserUpdate true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功", "data": nil}"
+// @Success 200 {object} MsgDataResult{data=model.AuthUser} "更新成功"
 // @Router /api/v1/user [put]
 func (u *user) UpdateUser(c *gin.Context) {
 	var data = &dao.UserUpdate{}
@@ -446,7 +580,7 @@ func (u *user) UpdateUser(c *gin.Context) {
 	// 更新用户信息
 	user, err := service.User.UpdateUser(data)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -459,7 +593,7 @@ func (u *user) UpdateUser(c *gin.Context) {
 // @Tags 用户管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param user body dao.UserPasswordUpdate true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "重置成功", "data": nil}"
+// @Success 200 {object} Result "重置成功"
 // @Router /api/v1/user/reset_password [put]
 func (u *user) UpdateUserPassword(c *gin.Context) {
 	var data = &dao.UserPasswordUpdate{}
@@ -472,39 +606,91 @@ func (u *user) UpdateUserPassword(c *gin.Context) {
 
 	// 密码更新
 	if err := service.User.UpdateUserPassword(data); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	CreateOrUpdateResponse(c, 0, "重置成功", nil)
 }
 
+// UpdateBreakGlass 设置应急账号
+// @Summary 设置应急账号
+// @Description 用户相关接口，应急账号必须为本地账号并已绑定MFA，仅允许从配置的网络（breakGlassNetworks）登录，登录时发送告警，首次登录超过使用时间窗口（breakGlassWindow）后自动禁用
+// @Tags 用户管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param user body service.BreakGlassUpdate true "应急账号信息"
+// @Success 200 {object} Result "更新成功"
+// @Router /api/v1/user/break_glass [put]
+func (u *user) UpdateBreakGlass(c *gin.Context) {
+
+	var data = &service.BreakGlassUpdate{}
+
+	// 解析请求参数
+	if err := c.ShouldBind(data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.BreakGlass.Update(data, c.GetString("username")); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "更新成功", nil)
+}
+
 // ResetUserMFA MFA重置
 // @Summary MFA重置
 // @Description 用户相关接口
 // @Tags 用户管理
 // @Param Authorization header string true "Bearer 用户令牌"
 // @Param id path int true "用户ID"
-// @Success 200 {string} json "{"code": 0, "msg": "重置成功", "data": nil}"
+// @Success 200 {object} Result "重置成功"
 // @Router /api/v1/user/reset_mfa/{id} [put]
 func (u *user) ResetUserMFA(c *gin.Context) {
 
 	// 对ID进行类型转换
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
 	// 更新用户信息
-	if err := service.User.ResetUserMFA(userID); err != nil {
-		Response(c, 90500, err.Error())
+	if err := service.User.ResetUserMFA(userID, c.GetString("username")); err != nil {
+		ErrorResponse(c, err)
 		return
 	}
 
 	CreateOrUpdateResponse(c, 0, "重置成功", nil)
 }
 
+// LogoutUser 强制下线
+// @Summary 强制下线
+// @Description 用户相关接口
+// @Tags 用户管理
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param id path int true "用户ID"
+// @Success 200 {object} Result "强制下线成功"
+// @Router /api/v1/user/logout/{id} [put]
+func (u *user) LogoutUser(c *gin.Context) {
+
+	// 对ID进行类型转换
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	// 注销用户的所有会话
+	if err := service.Session.RevokeUser(uint(userID)); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	CreateOrUpdateResponse(c, 0, "强制下线成功", nil)
+}
+
 // GetVerificationCode 获取验证码
 // @Summary 获取验证码
 // @Description 个人信息管理相关接口
@@ -512,7 +698,7 @@ func (u *user) ResetUserMFA(c *gin.Context) {
 // @Accept application/json
 // @Produce application/json
 // @Param user body service.ValidateCode true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "校验码已发送..."}"
+// @Success 200 {object} ResetTokenResult
 // @Router /api/v1/sms/reset_password [post]
 func (u *user) GetVerificationCode(c *gin.Context) {
 
@@ -524,15 +710,17 @@ func (u *user) GetVerificationCode(c *gin.Context) {
 		return
 	}
 
-	// 获取短信验证码
-	if err := service.User.GetVerificationCode(data); err != nil {
-		Response(c, 90500, err.Error())
+	// 获取验证码，重置令牌与当前设备绑定
+	resetToken, err := service.User.GetVerificationCode(data, service.NewDeviceInfo(c.Request.Header).Fingerprint())
+	if err != nil {
+		ErrorResponse(c, err)
 		return
 	}
 
 	c.JSON(200, gin.H{
-		"code": 0,
-		"msg":  fmt.Sprintf("校验码已发送，有效期为5分钟"),
+		"code":        0,
+		"msg":         fmt.Sprintf("校验码已发送，有效期为5分钟"),
+		"reset_token": resetToken,
 	})
 }
 
@@ -540,8 +728,8 @@ func (u *user) GetVerificationCode(c *gin.Context) {
 // @Summary 密码更新
 // @Description 个人信息管理相关接口
 // @Tags 个人信息管理
-// @Param user body dao.UserPasswordUpdate true "用户信息"
-// @Success 200 {string} json "{"code": 0, "msg": "更新成功"}"
+// @Param user body service.RestPassword true "用户信息"
+// @Success 200 {object} Result "更新成功"
 // @Router /api/v1/reset_password [post]
 func (u *user) UpdateSelfPassword(c *gin.Context) {
 	var data = &service.RestPassword{}
@@ -553,8 +741,8 @@ func (u *user) UpdateSelfPassword(c *gin.Context) {
 	}
 
 	// 更新用户信息
-	if err := service.User.UpdateSelfPassword(data); err != nil {
-		Response(c, 90500, err.Error())
+	if err := service.User.UpdateSelfPassword(data, service.NewDeviceInfo(c.Request.Header).Fingerprint(), c.ClientIP()); err != nil {
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -569,13 +757,13 @@ func (u *user) UpdateSelfPassword(c *gin.Context) {
 // @Description 用户相关接口
 // @Tags 用户管理
 // @Param Authorization header string true "Bearer 用户令牌"
-// @Success 200 {string} json "{"code": 0, "msg": "同步成功"}"
+// @Success 200 {object} Result "同步成功"
 // @Router /api/v1/user/sync/ad [post]
 func (u *user) UserSyncAd(c *gin.Context) {
 
 	// 同步用户
 	if err := service.User.UserSync(); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -590,7 +778,7 @@ func (u *user) UserSyncAd(c *gin.Context) {
 // @Description 用户认证相关接口
 // @Tags 用户认证
 // @Param token query string true "用户认证通过后的Token"
-// @Success 200 {string} json "{"code": 0, "qrcode": ""}"
+// @Success 200 {object} QRCodeResult
 // @Router /api/v1/user/mfa_qrcode [get]
 func (u *user) GetGoogleQrcode(c *gin.Context) {
 	params := new(struct {
@@ -604,7 +792,7 @@ func (u *user) GetGoogleQrcode(c *gin.Context) {
 	// 获取二维码
 	qrcode, err := service.MFA.GetGoogleQrcode(params.Token)
 	if err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
 		return
 	}
 
@@ -616,12 +804,41 @@ func (u *user) GetGoogleQrcode(c *gin.Context) {
 	})
 }
 
+// MFAStepUp 升级认证
+// @Summary 升级认证
+// @Description 用户认证相关接口
+// @Tags 用户认证
+// @Param Authorization header string true "Bearer 用户令牌"
+// @Param step_up body service.MFAStepUp true "MFA校验码"
+// @Success 200 {object} TokenResult "认证成功"
+// @Router /api/v1/user/step_up [post]
+func (u *user) MFAStepUp(c *gin.Context) {
+	var data = &service.MFAStepUp{}
+
+	if err := c.ShouldBind(&data); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	token, err := service.MFA.StepUp(c.GetHeader("Authorization"), data)
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":  0,
+		"msg":   "认证成功",
+		"token": token,
+	})
+}
+
 // GoogleQrcodeValidate MFA认证
 // @Summary MFA认证
 // @Description 用户认证相关接口
 // @Tags 用户认证
 // @Param user body service.MFAValidate true "MFA认证信息"
-// @Success 200 {string} json "{"code": 0, "token": "用户令牌"}"
+// @Success 200 {object} TokenResult
 // @Router /api/v1/user/mfa_auth [post]
 func (u *user) GoogleQrcodeValidate(c *gin.Context) {
 
@@ -639,20 +856,39 @@ func (u *user) GoogleQrcodeValidate(c *gin.Context) {
 	clientIP := c.ClientIP()
 
 	// MFA校验
-	token, redirectUri, application, err := service.MFA.GoogleQrcodeValidate(params)
+	token, redirectUri, application, nextPage, err := service.MFA.GoogleQrcodeValidate(params, clientIP)
+	// 执行会话并发策略（需要完成首次登录操作时在操作完成后执行）
+	if err == nil && sessionIssued(nextPage) {
+		err = admitSession(c, token)
+	}
 	if err != nil {
 		// 记录登录信息
 		if err := service.User.RecordLoginInfo("双因子", params.Username, userAgent, clientIP, application, err); err != nil {
-			Response(c, 90500, err.Error())
+			ErrorResponse(c, err)
 			return
 		}
 
-		Response(c, 90500, err.Error())
+		loginFailed(c, err)
 		return
 	}
 	// 记录登录成功信息
 	if err := service.User.RecordLoginInfo("双因子", params.Username, userAgent, clientIP, application, nil); err != nil {
-		Response(c, 90500, err.Error())
+		ErrorResponse(c, err)
+		return
+	}
+
+	// 记录登录设备
+	if sessionIssued(nextPage) {
+		recordDevice(c, params.Username)
+	}
+
+	// 需要完成首次登录操作时携带临时Token和对应页面（REQUIRED_ACTIONS），需要接受使用条款时携带用户Token（TERMS_ACCEPT）
+	if nextPage != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":     0,
+			"token":    token,
+			"redirect": nextPage,
+		})
 		return
 	}
 
@@ -662,3 +898,98 @@ func (u *user) GoogleQrcodeValidate(c *gin.Context) {
 		"redirect_uri": redirectUri,
 	})
 }
+
+// GetRequiredActions 获取首次登录需要完成的操作
+// @Summary 获取首次登录需要完成的操作
+// @Description 用户认证相关接口
+// @Tags 用户认证
+// @Param token query string true "登录时返回的临时Token"
+// @Success 200 {object} DataResult{data=service.RequiredActionInfo}
+// @Router /api/v1/user/required_actions [get]
+func (u *user) GetRequiredActions(c *gin.Context) {
+
+	info, err := service.RequiredAction.GetRequiredActions(c.Query("token"))
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": info,
+	})
+}
+
+// GetRequiredActionCode 获取首次登录验证邮箱或手机号的验证码
+// @Summary 获取首次登录验证码
+// @Description 用户认证相关接口
+// @Tags 用户认证
+// @Param code body service.RequiredActionCode true "临时Token及操作名称"
+// @Success 200 {object} Result
+// @Router /api/v1/user/required_actions/code [post]
+func (u *user) GetRequiredActionCode(c *gin.Context) {
+
+	var params = &service.RequiredActionCode{}
+	if err := c.ShouldBind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	if err := service.RequiredAction.SendCode(params); err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "验证码已发送",
+	})
+}
+
+// SubmitRequiredAction 完成首次登录操作
+// @Summary 完成首次登录操作
+// @Description 用户认证相关接口，所有操作完成后返回用户Token，需要MFA认证时返回临时Token及MFA对应页面
+// @Tags 用户认证
+// @Param action body service.RequiredActionSubmit true "操作信息"
+// @Success 200 {object} TokenResult
+// @Router /api/v1/user/required_actions [post]
+func (u *user) SubmitRequiredAction(c *gin.Context) {
+
+	var params = &service.RequiredActionSubmit{}
+	if err := c.ShouldBind(params); err != nil {
+		Response(c, 90400, err.Error())
+		return
+	}
+
+	result, err := service.RequiredAction.Submit(params, c.ClientIP())
+	// 执行会话并发策略
+	if err == nil && sessionIssued(result.NextPage) {
+		err = admitSession(c, result.Token)
+	}
+	if err != nil {
+		ErrorResponse(c, err)
+		return
+	}
+
+	// 记录登录设备
+	if sessionIssued(result.NextPage) {
+		recordDevice(c, result.Username)
+	}
+
+	// 还有未完成的操作或需要进行MFA认证时携带临时Token和对应页面，需要接受使用条款时携带用户Token
+	if result.NextPage != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"code":     0,
+			"token":    result.Token,
+			"redirect": result.NextPage,
+			"actions":  result.Actions,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":         0,
+		"token":        result.Token,
+		"redirect_uri": result.RedirectURI,
+	})
+}
@@ -0,0 +1,116 @@
+package dao
+
+import (
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var AccessRequest accessRequest
+
+type accessRequest struct{}
+
+// AccessRequestList 返回给前端表格的数据结构体
+type AccessRequestList struct {
+	Items []*model.AccessRequest `json:"items"`
+	Total int64                  `json:"total"`
+}
+
+// GetAccessRequestList 获取访问申请列表（表格）
+func (a *accessRequest) GetAccessRequestList(name, status string, page, limit int) (data *AccessRequestList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		items []*model.AccessRequest
+		total int64
+	)
+
+	tx := global.MySQLClient.Model(&model.AccessRequest{}).
+		Where("(username like ? OR target_name like ? OR external_id like ?)", "%"+name+"%", "%"+name+"%", "%"+name+"%")
+	if status != "" {
+		tx = tx.Where("status = ?", status)
+	}
+	if err := tx.Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return &AccessRequestList{
+		Items: items,
+		Total: total,
+	}, nil
+}
+
+// GetUserAccessRequests 获取用户提交的访问申请
+func (a *accessRequest) GetUserAccessRequests(userId uint) (items []*model.AccessRequest, err error) {
+	if err := global.MySQLClient.Where("user_id = ?", userId).Order("id desc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetAccessRequest 获取单个访问申请
+func (a *accessRequest) GetAccessRequest(conditions interface{}) (*model.AccessRequest, error) {
+	var data model.AccessRequest
+	if err := global.MySQLClient.Where(conditions).First(&data).Error; err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// GetPendingAccessRequests 获取已提交到ITSM且审批中的访问申请
+func (a *accessRequest) GetPendingAccessRequests(status string) (items []*model.AccessRequest, err error) {
+	if err := global.MySQLClient.Where("status = ? AND external_id <> ''", status).Order("id").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// HasPendingAccessRequest 判断用户是否存在相同目标的审批中申请
+func (a *accessRequest) HasPendingAccessRequest(userId uint, requestType string, targetId uint, status string) (bool, error) {
+	var count int64
+	if err := global.MySQLClient.Model(&model.AccessRequest{}).
+		Where("user_id = ? AND type = ? AND target_id = ? AND status = ?", userId, requestType, targetId, status).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AddAccessRequest 新增访问申请
+func (a *accessRequest) AddAccessRequest(data *model.AccessRequest) (*model.AccessRequest, error) {
+	if err := global.MySQLClient.Create(data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateAccessRequest 更新访问申请
+func (a *accessRequest) UpdateAccessRequest(id uint, data map[string]interface{}) error {
+	return global.MySQLClient.Model(&model.AccessRequest{}).Where("id = ?", id).Updates(data).Error
+}
+
+// ResolveAccessRequest 将审批中的访问申请更新为审批结果，返回是否更新成功，多个实例同时处理时只有一个实例更新成功
+func (a *accessRequest) ResolveAccessRequest(id uint, pending, status, approver, message string) (bool, error) {
+	tx := global.MySQLClient.Model(&model.AccessRequest{}).
+		Where("id = ? AND status = ?", id, pending).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"approver":    approver,
+			"message":     message,
+			"resolved_at": time.Now(),
+		})
+	if tx.Error != nil {
+		return false, tx.Error
+	}
+	return tx.RowsAffected > 0, nil
+}
+
+// AddAuditLog 在操作日志中记录访问申请的授权结果，与ITSM工单交叉引用
+func (a *accessRequest) AddAuditLog(log *model.LogOplog) error {
+	return global.MySQLClient.Create(log).Error
+}
@@ -0,0 +1,55 @@
+package dao
+
+import (
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var Alert alert
+
+type alert struct{}
+
+// CountLoginFailures 统计指定时间之后的登录失败次数
+func (a *alert) CountLoginFailures(since time.Time) (count int64, err error) {
+	if err := global.MySQLClient.Model(&model.LogLogin{}).
+		Where("created_at >= ? AND status <> ?", since, 1).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// CountSMS 统计指定时间之后发送的短信数量及其中发送失败（服务商接口请求失败或回执为发送失败）的数量
+func (a *alert) CountSMS(since time.Time) (total, failed int64, err error) {
+	if err := global.MySQLClient.Model(&model.LogSMS{}).
+		Where("created_at >= ?", since).
+		Count(&total).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := global.MySQLClient.Model(&model.LogSMS{}).
+		Where("created_at >= ? AND status IN ?", since, []string{"API请求失败", "发送失败"}).
+		Count(&failed).Error; err != nil {
+		return 0, 0, err
+	}
+	return total, failed, nil
+}
+
+// GetExpiringDomainCertificates 获取指定时间之前过期的可用域名证书
+func (a *alert) GetExpiringDomainCertificates(before time.Time) (certificates []*model.DomainCertificate, err error) {
+	if err := global.MySQLClient.
+		Where("status = ? AND expiration_at <= ?", "active", before).
+		Order("expiration_at").
+		Find(&certificates).Error; err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// GetEnabledTasks 获取已启用的定时任务
+func (a *alert) GetEnabledTasks() (tasks []*model.ScheduledTask, err error) {
+	if err := global.MySQLClient.Where("enabled = ?", true).Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
@@ -5,6 +5,7 @@ import (
 	"gorm.io/gorm"
 	"ops-api/global"
 	"ops-api/model"
+	"time"
 )
 
 var Audit audit
@@ -32,6 +33,20 @@ type OplogList struct {
 //	AuthMethod string `json:"auth_method"`
 //}
 
+// SCIMRecordList 返回给前端SCIM同步日志列表
+type SCIMRecordList struct {
+	Items []*model.LogSCIM `json:"items"`
+	Total int64            `json:"total"`
+}
+
+// SSOErrorSummary 单点登录协议错误统计
+type SSOErrorSummary struct {
+	Protocol string    `json:"protocol"`
+	Reason   string    `json:"reason"`
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+}
+
 // SMSRecordList 返回给前端短信发送列表结构体
 type SMSRecordList struct {
 	Items []*model.LogSMS `json:"items"`
@@ -157,7 +172,81 @@ func (a *audit) SMSCallback(data *Callback) (err error) {
 	return global.MySQLClient.Model(&model.LogSMS{}).Where("sms_msg_id = ?", data.SmsMsgId).Updates(data).Error
 }
 
+// SMSRecordExists 判断短信发送记录是否存在
+func (a *audit) SMSRecordExists(smsMsgId string) (bool, error) {
+	var total int64
+	if err := global.MySQLClient.Model(&model.LogSMS{}).Where("sms_msg_id = ?", smsMsgId).Count(&total).Error; err != nil {
+		return false, err
+	}
+	return total > 0, nil
+}
+
 // AddLoginRecord 新增用户登录记录
 func (a *audit) AddLoginRecord(tx *gorm.DB, data *model.LogLogin) (err error) {
 	return tx.Create(&data).Error
 }
+
+// GetSCIMRecordList 获取SCIM同步记录
+func (a *audit) GetSCIMRecordList(name string, page, limit int) (data *SCIMRecordList, err error) {
+
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	// 定义返回的内容
+	var (
+		record []*model.LogSCIM
+		total  int64
+	)
+
+	// 获取同步记录列表
+	tx := global.MySQLClient.Model(&model.LogSCIM{}).
+		Where("resource_name like ? OR resource_id like ? OR client_ip like ?", "%"+name+"%", "%"+name+"%", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&record)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &SCIMRecordList{
+		Items: record,
+		Total: total,
+	}, nil
+}
+
+// AddSCIMRecord 新增SCIM同步记录
+func (a *audit) AddSCIMRecord(data *model.LogSCIM) (err error) {
+	return global.MySQLClient.Create(&data).Error
+}
+
+// AddSSOErrorRecord 新增单点登录协议错误记录
+func (a *audit) AddSSOErrorRecord(data *model.LogSSOError) (err error) {
+	return global.MySQLClient.Create(&data).Error
+}
+
+// GetSSOErrorSummary 按协议及错误类型统计站点指定时间后的单点登录协议错误
+func (a *audit) GetSSOErrorSummary(siteId uint, since time.Time) (data []*SSOErrorSummary, err error) {
+	if err := global.MySQLClient.Model(&model.LogSSOError{}).
+		Select("protocol, reason, COUNT(*) AS count, MAX(created_at) AS last_seen").
+		Where("site_id = ? AND created_at >= ?", siteId, since).
+		Group("protocol, reason").
+		Order("count desc").
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetSSOErrorRecords 获取站点指定时间后最近的单点登录协议错误记录
+func (a *audit) GetSSOErrorRecords(siteId uint, since time.Time, limit int) (data []*model.LogSSOError, err error) {
+	if err := global.MySQLClient.
+		Where("site_id = ? AND created_at >= ?", siteId, since).
+		Order("id desc").
+		Limit(limit).
+		Find(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,78 @@
+package dao
+
+import (
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var Auditor auditor
+
+type auditor struct{}
+
+// AuditorGrantList 返回给前端表格的数据结构体
+type AuditorGrantList struct {
+	Items []*model.AuditorGrant `json:"items"`
+	Total int64                 `json:"total"`
+}
+
+// GetAuditorGrantList 获取审计员授权列表（表格）
+func (a *auditor) GetAuditorGrantList(name string, page, limit int) (data *AuditorGrantList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		items []*model.AuditorGrant
+		total int64
+	)
+
+	if err := global.MySQLClient.Model(&model.AuditorGrant{}).
+		Where("(username like ? OR reason like ?)", "%"+name+"%", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return &AuditorGrantList{
+		Items: items,
+		Total: total,
+	}, nil
+}
+
+// GetAuditorGrant 获取单个审计员授权
+func (a *auditor) GetAuditorGrant(id uint) (*model.AuditorGrant, error) {
+	var data model.AuditorGrant
+	if err := global.MySQLClient.First(&data, id).Error; err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// HasActiveAuditorGrant 判断用户是否存在有效期内的审计员授权
+func (a *auditor) HasActiveAuditorGrant(userId uint) (bool, error) {
+	var count int64
+	if err := global.MySQLClient.Model(&model.AuditorGrant{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now()).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// AddAuditorGrant 新增审计员授权
+func (a *auditor) AddAuditorGrant(data *model.AuditorGrant) (*model.AuditorGrant, error) {
+	if err := global.MySQLClient.Create(data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// RevokeAuditorGrant 撤销审计员授权
+func (a *auditor) RevokeAuditorGrant(id uint, operator string) error {
+	return global.MySQLClient.Model(&model.AuditorGrant{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_by": operator}).Error
+}
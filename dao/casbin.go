@@ -1,7 +1,6 @@
 package dao
 
 import (
-	"errors"
 	"gorm.io/gorm"
 	"ops-api/global"
 	"ops-api/model"
@@ -83,6 +82,11 @@ func (c *casbin) UpdateRoleUser(tx *gorm.DB, groupName string, users []string) (
 	return nil
 }
 
+// UpdateUserName 修改用户名时同步更新角色中的用户名
+func (c *casbin) UpdateUserName(tx *gorm.DB, oldName, newName string) (err error) {
+	return tx.Model(&model.CasbinRule{}).Where("ptype = ? AND v0 = ?", "g", oldName).Update("v0", newName).Error
+}
+
 // UpdateRoleName 修改角色名称
 func (c *casbin) UpdateRoleName(tx *gorm.DB, oldName, newName string) (err error) {
 
@@ -113,6 +117,12 @@ func (c *casbin) GetUserPermissions(username string) (perm []string, err error)
 		return nil, err
 	}
 
+	// 获取所有接口，避免逐条权限查询接口名称
+	paths, err := Path.GetPathMap()
+	if err != nil {
+		return nil, err
+	}
+
 	// 获取角色对应的所有权限的中文名称
 	for _, role := range roles {
 
@@ -121,17 +131,10 @@ func (c *casbin) GetUserPermissions(username string) (perm []string, err error)
 
 		for _, policy := range policies {
 
-			var systemPath model.SystemPath
-
-			// 获取权限对应的中文名称
-			result := global.MySQLClient.Where("path = ? AND method = ?", policy[1], policy[2]).First(&systemPath)
-			if result.Error != nil {
-				// 有的权限是菜单权限，在SystemPath会找不到，需要跳过
-				if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-					continue
-				} else {
-					return nil, err
-				}
+			// 有的权限是菜单权限，在SystemPath会找不到，需要跳过
+			systemPath, ok := paths[policy[2]+" "+policy[1]]
+			if !ok {
+				continue
 			}
 
 			// 将权限对应的名称添加到列表
@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var Consent consent
+
+type consent struct{}
+
+// UserConsentItem 用户的应用授权记录（个人信息）
+type UserConsentItem struct {
+	ID          uint      `json:"id"`
+	Application string    `json:"application"`
+	ClientID    string    `json:"client_id"`
+	Scope       string    `json:"scope"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GetUserConsent 获取用户对应用的授权记录
+func (c *consent) GetUserConsent(userId uint, clientId string) (*model.UserConsent, error) {
+	var data model.UserConsent
+	if err := global.MySQLClient.Where("user_id = ? AND client_id = ?", userId, clientId).First(&data).Error; err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// SaveUserConsent 保存用户对应用的授权记录，已存在时更新授权的Scope
+func (c *consent) SaveUserConsent(data *model.UserConsent) error {
+	return global.MySQLClient.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+	}).Create(data).Error
+}
+
+// GetUserConsents 获取用户的所有应用授权记录
+func (c *consent) GetUserConsents(userId uint) (items []*UserConsentItem, err error) {
+	if err := global.MySQLClient.Table("user_consent AS t").
+		Select("t.id, site.name AS application, t.client_id, t.scope, t.created_at, t.updated_at").
+		Joins("LEFT JOIN site ON site.client_id = t.client_id").
+		Where("t.user_id = ?", userId).
+		Order("t.updated_at desc").
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// DeleteUserConsent 删除用户的应用授权记录，返回被删除的记录
+func (c *consent) DeleteUserConsent(userId, id uint) (*model.UserConsent, error) {
+	var data model.UserConsent
+	if err := global.MySQLClient.Where("id = ? AND user_id = ?", id, userId).First(&data).Error; err != nil {
+		return nil, err
+	}
+	if err := global.MySQLClient.Delete(&data).Error; err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// DeleteUserConsents 删除用户的所有应用授权记录
+func (c *consent) DeleteUserConsents(tx *gorm.DB, userId uint) (int64, error) {
+	result := tx.Where("user_id = ?", userId).Delete(&model.UserConsent{})
+	return result.RowsAffected, result.Error
+}
+
+// GetUserClientRefreshSessions 获取用户在应用中未注销的刷新令牌对应的会话ID
+func (c *consent) GetUserClientRefreshSessions(userId uint, clientId string) (sessionIds []string, err error) {
+	if err := global.MySQLClient.Model(&model.SsoOAuthRefreshToken{}).
+		Where("user_id = ? AND client_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, clientId, time.Now()).
+		Distinct().
+		Pluck("session_id", &sessionIds).Error; err != nil {
+		return nil, err
+	}
+	return sessionIds, nil
+}
@@ -0,0 +1,152 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var Device device
+
+type device struct{}
+
+// DeviceList 返回给前端表格的数据结构体
+type DeviceList struct {
+	Items []*model.UserDevice `json:"items"`
+	Total int64               `json:"total"`
+}
+
+// GetDeviceList 获取登录设备列表（表格）
+func (d *device) GetDeviceList(username string, page, limit int) (data *DeviceList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		devices []*model.UserDevice
+		total   int64
+	)
+
+	tx := global.MySQLClient.Model(&model.UserDevice{}).
+		Where("username like ?", "%"+username+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("last_seen_at desc").
+		Find(&devices)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &DeviceList{
+		Items: devices,
+		Total: total,
+	}, nil
+}
+
+// GetUserDevices 获取用户的所有登录设备
+func (d *device) GetUserDevices(userId uint) (devices []*model.UserDevice, err error) {
+	if err := global.MySQLClient.
+		Where("user_id = ?", userId).
+		Order("last_seen_at desc").
+		Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// GetDevice 获取单个登录设备
+func (d *device) GetDevice(id uint) (*model.UserDevice, error) {
+	var userDevice model.UserDevice
+	if err := global.MySQLClient.First(&userDevice, id).Error; err != nil {
+		return nil, err
+	}
+	return &userDevice, nil
+}
+
+// GetUserDevice 根据设备指纹获取用户的登录设备
+func (d *device) GetUserDevice(userId uint, fingerprint string) (*model.UserDevice, error) {
+	var userDevice model.UserDevice
+	if err := global.MySQLClient.
+		Where("user_id = ? AND fingerprint = ?", userId, fingerprint).
+		First(&userDevice).Error; err != nil {
+		return nil, err
+	}
+	return &userDevice, nil
+}
+
+// CountUserDevices 获取用户的登录设备数量
+func (d *device) CountUserDevices(userId uint) (total int64, err error) {
+	if err := global.MySQLClient.Model(&model.UserDevice{}).
+		Where("user_id = ?", userId).
+		Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// AddUserDevice 新增登录设备
+func (d *device) AddUserDevice(data *model.UserDevice) (*model.UserDevice, error) {
+	if err := global.MySQLClient.Create(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// TouchUserDevice 更新设备最后登录时间及IP
+func (d *device) TouchUserDevice(id uint, clientIP, userAgent string, seenAt time.Time) error {
+	return global.MySQLClient.Model(&model.UserDevice{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_ip":      clientIP,
+			"user_agent":   userAgent,
+			"last_seen_at": seenAt,
+		}).Error
+}
+
+// DeleteUserDevice 删除用户的登录设备，删除后该设备再次登录时视为新设备
+func (d *device) DeleteUserDevice(userId, id uint) error {
+	tx := global.MySQLClient.Unscoped().
+		Where("id = ? AND user_id = ?", id, userId).
+		Delete(&model.UserDevice{})
+	if tx.Error != nil {
+		return tx.Error
+	}
+	if tx.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetUserCountry 获取用户登录过的国家/地区
+func (d *device) GetUserCountry(userId uint, country string) (*model.UserLoginCountry, error) {
+	var loginCountry model.UserLoginCountry
+	if err := global.MySQLClient.
+		Where("user_id = ? AND country = ?", userId, country).
+		First(&loginCountry).Error; err != nil {
+		return nil, err
+	}
+	return &loginCountry, nil
+}
+
+// CountUserCountries 获取用户登录过的国家/地区数量
+func (d *device) CountUserCountries(userId uint) (total int64, err error) {
+	if err := global.MySQLClient.Model(&model.UserLoginCountry{}).
+		Where("user_id = ?", userId).
+		Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// AddUserCountry 新增用户登录过的国家/地区
+func (d *device) AddUserCountry(data *model.UserLoginCountry) error {
+	return global.MySQLClient.Create(data).Error
+}
+
+// TouchUserCountry 更新国家/地区最后登录时间
+func (d *device) TouchUserCountry(id uint, seenAt time.Time) error {
+	return global.MySQLClient.Model(&model.UserLoginCountry{}).
+		Where("id = ?", id).
+		Update("last_seen_at", seenAt).Error
+}
@@ -0,0 +1,81 @@
+package dao
+
+import (
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var External external
+
+type external struct{}
+
+// GetUser 根据外部标识获取用户
+func (e *external) GetUser(externalId string) (*model.AuthUser, error) {
+	var user model.AuthUser
+	if err := global.MySQLClient.Where("external_id = ?", externalId).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetGroup 根据外部标识获取分组
+func (e *external) GetGroup(externalId string) (*model.AuthGroup, error) {
+	var group model.AuthGroup
+	if err := global.MySQLClient.Where("external_id = ?", externalId).First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetSite 根据外部标识获取站点
+func (e *external) GetSite(externalId string) (*model.Site, error) {
+	var site model.Site
+	if err := global.MySQLClient.Where("external_id = ?", externalId).First(&site).Error; err != nil {
+		return nil, err
+	}
+	return &site, nil
+}
+
+// GetUnboundUser 根据用户名获取未绑定外部标识的用户，用于纳管已存在的用户
+func (e *external) GetUnboundUser(username string) (*model.AuthUser, error) {
+	var user model.AuthUser
+	if err := global.MySQLClient.Where("username = ? AND external_id IS NULL", username).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUnboundGroup 根据名称获取未绑定外部标识的分组，用于纳管已存在的分组
+func (e *external) GetUnboundGroup(name string) (*model.AuthGroup, error) {
+	var group model.AuthGroup
+	if err := global.MySQLClient.Where("name = ? AND external_id IS NULL", name).First(&group).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// GetUnboundSites 根据名称获取未绑定外部标识的站点，用于纳管已存在的站点
+func (e *external) GetUnboundSites(name string) ([]*model.Site, error) {
+	var sites []*model.Site
+	if err := global.MySQLClient.Where("name = ? AND external_id IS NULL", name).Find(&sites).Error; err != nil {
+		return nil, err
+	}
+	return sites, nil
+}
+
+// BindExternalId 绑定外部标识，value 为 *model.AuthUser、*model.AuthGroup 或 *model.Site
+func (e *external) BindExternalId(value interface{}, externalId string) error {
+	return global.MySQLClient.Model(value).Update("external_id", externalId).Error
+}
+
+// UpdateUser 更新用户基本信息
+func (e *external) UpdateUser(user *model.AuthUser, data map[string]interface{}) error {
+	if err := global.MySQLClient.Model(user).Updates(data).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	User.ClearUserInfoCache(user.ID)
+
+	return nil
+}
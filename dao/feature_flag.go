@@ -0,0 +1,192 @@
+package dao
+
+import (
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var FeatureFlag featureFlag
+
+type featureFlag struct{}
+
+// featureFlagCacheTTL 功能开关规则缓存时间
+const featureFlagCacheTTL = 5 * time.Minute
+
+// FeatureFlagList 返回给前端表格的数据结构体
+type FeatureFlagList struct {
+	Items []*model.FeatureFlag `json:"items"`
+	Total int64                `json:"total"`
+}
+
+// FeatureFlagUpdate 更新功能开关结构体
+type FeatureFlagUpdate struct {
+	ID          uint   `json:"id" binding:"required"`
+	Key         string `json:"key" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Enabled     *bool  `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+	Percentage  *uint  `json:"percentage" binding:"required,max=100"`
+}
+
+// FeatureFlagRule 功能开关判定规则（缓存于Redis）
+type FeatureFlagRule struct {
+	Key        string `json:"key"`
+	Enabled    bool   `json:"enabled"`
+	Percentage uint   `json:"percentage"`
+	UserIds    []uint `json:"user_ids"`
+	GroupIds   []uint `json:"group_ids"`
+}
+
+// GetFeatureFlagList 获取功能开关列表（表格）
+func (f *featureFlag) GetFeatureFlagList(name string, page, limit int) (data *FeatureFlagList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		flags []*model.FeatureFlag
+		total int64
+	)
+
+	tx := global.MySQLClient.Model(&model.FeatureFlag{}).
+		Preload("Users", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name", "username") }).
+		Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Where("name like ? OR `key` like ?", "%"+name+"%", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&flags)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &FeatureFlagList{
+		Items: flags,
+		Total: total,
+	}, nil
+}
+
+// GetFeatureFlag 获取单个功能开关
+func (f *featureFlag) GetFeatureFlag(id uint) (*model.FeatureFlag, error) {
+	var flag model.FeatureFlag
+	if err := global.MySQLClient.First(&flag, id).Error; err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+// GetEnabledFeatureFlagKeys 获取已开启的功能开关标识
+func (f *featureFlag) GetEnabledFeatureFlagKeys() (keys []string, err error) {
+	if err := global.MySQLClient.Model(&model.FeatureFlag{}).
+		Where("enabled = ?", true).
+		Order("id").
+		Pluck("key", &keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// AddFeatureFlag 新增功能开关
+func (f *featureFlag) AddFeatureFlag(tx *gorm.DB, data *model.FeatureFlag) (*model.FeatureFlag, error) {
+	if err := tx.Create(data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateFeatureFlag 修改功能开关
+func (f *featureFlag) UpdateFeatureFlag(tx *gorm.DB, flag *model.FeatureFlag, data *FeatureFlagUpdate) (*model.FeatureFlag, error) {
+	if err := tx.Model(flag).Select("key", "name", "description", "enabled", "percentage").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return flag, nil
+}
+
+// UpdateFeatureFlagTargets 更新功能开关指定开放的用户及分组
+func (f *featureFlag) UpdateFeatureFlagTargets(tx *gorm.DB, flag *model.FeatureFlag, users []model.AuthUser, groups []model.AuthGroup) error {
+	if len(users) == 0 {
+		if err := tx.Model(flag).Association("Users").Clear(); err != nil {
+			return err
+		}
+	} else if err := tx.Model(flag).Association("Users").Replace(users); err != nil {
+		return err
+	}
+
+	if len(groups) == 0 {
+		return tx.Model(flag).Association("Groups").Clear()
+	}
+	return tx.Model(flag).Association("Groups").Replace(groups)
+}
+
+// DeleteFeatureFlag 删除功能开关
+func (f *featureFlag) DeleteFeatureFlag(tx *gorm.DB, flag *model.FeatureFlag) error {
+
+	// 删除关联的用户及分组
+	if err := tx.Model(flag).Association("Users").Clear(); err != nil {
+		return err
+	}
+	if err := tx.Model(flag).Association("Groups").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(flag).Error
+}
+
+// GetFeatureFlagRule 获取功能开关判定规则（优先从Redis缓存中获取），功能开关不存在时返回关闭状态的规则
+func (f *featureFlag) GetFeatureFlagRule(key string) (*FeatureFlagRule, error) {
+
+	cacheKey := f.ruleCacheKey(key)
+
+	// 读取缓存，缓存不可用时直接查询数据库
+	if data, err := global.Cache.Get(cacheKey); err == nil {
+		rule := &FeatureFlagRule{}
+		if err := json.Unmarshal([]byte(data), rule); err == nil {
+			return rule, nil
+		}
+	}
+
+	rule := &FeatureFlagRule{Key: key}
+
+	var flag model.FeatureFlag
+	err := global.MySQLClient.
+		Preload("Users", func(db *gorm.DB) *gorm.DB { return db.Select("id") }).
+		Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Select("id") }).
+		Where("`key` = ?", key).
+		First(&flag).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil {
+		rule.Enabled = flag.Enabled
+		rule.Percentage = flag.Percentage
+		for _, user := range flag.Users {
+			rule.UserIds = append(rule.UserIds, user.ID)
+		}
+		for _, group := range flag.Groups {
+			rule.GroupIds = append(rule.GroupIds, group.ID)
+		}
+	}
+
+	// 写入缓存，不存在的功能开关同样缓存，避免频繁查询数据库
+	if data, err := json.Marshal(rule); err == nil {
+		global.Cache.Set(cacheKey, string(data), featureFlagCacheTTL)
+	}
+
+	return rule, nil
+}
+
+// ClearFeatureFlagCache 清除功能开关判定规则缓存，功能开关修改、删除后调用
+func (f *featureFlag) ClearFeatureFlagCache(keys ...string) {
+	for _, key := range keys {
+		global.Cache.Del(f.ruleCacheKey(key))
+	}
+}
+
+// ruleCacheKey 获取功能开关判定规则缓存Key
+func (f *featureFlag) ruleCacheKey(key string) string {
+	return "feature_flag:" + key
+}
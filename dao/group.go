@@ -21,6 +21,7 @@ type AuthGroup struct {
 	ID          uint             `json:"id"`
 	Name        string           `json:"name"`
 	IsRoleGroup bool             `json:"is_role_group"`
+	DynamicRule string           `json:"dynamic_rule"`
 	Users       []*UserBasicInfo `json:"users"`
 	Menus       []string         `json:"menus"`
 	Paths       []string         `json:"paths"`
@@ -54,6 +55,12 @@ func (u *group) GetGroupList(name string, page, limit int) (data *GroupList, err
 		Items: make([]*AuthGroup, len(authGroup)), // 初始化分组列表切片，并指定长度为authGroup长度
 	}
 
+	// 获取所有接口，避免逐条权限查询接口名称
+	systemPaths, err := Path.GetPathMap()
+	if err != nil {
+		return nil, err
+	}
+
 	for g, group := range authGroup {
 
 		// 获取分组的权限列表
@@ -65,11 +72,12 @@ func (u *group) GetGroupList(name string, page, limit int) (data *GroupList, err
 				v1 := permission[1]
 				menus = append(menus, v1)
 			} else {
-				name, err := Path.GetPathName(permission[1], permission[2])
-				if err != nil {
-					return nil, err
+				// 接口已被删除的权限需要跳过
+				systemPath, ok := systemPaths[permission[2]+" "+permission[1]]
+				if !ok {
+					continue
 				}
-				paths = append(paths, *name)
+				paths = append(paths, systemPath.Name)
 			}
 		}
 
@@ -78,6 +86,7 @@ func (u *group) GetGroupList(name string, page, limit int) (data *GroupList, err
 			ID:          group.ID,
 			Name:        group.Name,
 			IsRoleGroup: group.IsRoleGroup,
+			DynamicRule: group.DynamicRule,
 			Users:       make([]*UserBasicInfo, len(group.Users)), // 初始化用户列表切片，并指定长度为group.Users长度
 			Menus:       menus,
 			Paths:       paths,
@@ -122,6 +131,27 @@ func (u *group) UpdateGroup(tx *gorm.DB, data *model.AuthGroup) (*model.AuthGrou
 	return &updatedGroup, nil
 }
 
+// UpdateGroupRule 更新动态分组规则，规则为空时Updates会忽略该字段，需要单独更新
+func (u *group) UpdateGroupRule(tx *gorm.DB, id uint, rule string) (err error) {
+	return tx.Model(&model.AuthGroup{}).Where("id = ?", id).Update("dynamic_rule", rule).Error
+}
+
+// GetDynamicGroups 获取所有动态分组
+func (u *group) GetDynamicGroups() (groups []*model.AuthGroup, err error) {
+	if err := global.MySQLClient.Where("dynamic_rule <> ''").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// GetGroupUsers 获取组内用户
+func (u *group) GetGroupUsers(tx *gorm.DB, group *model.AuthGroup) (users []model.AuthUser, err error) {
+	if err := tx.Model(&group).Association("Users").Find(&users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 // DeleteGroup 删除
 func (u *group) DeleteGroup(tx *gorm.DB, group *model.AuthGroup) (err error) {
 
@@ -145,6 +175,19 @@ func (u *group) UpdateGroupUser(tx *gorm.DB, group *model.AuthGroup, users []mod
 	return group, nil
 }
 
+// GetGroupUsernames 获取组内用户的用户名
+func (u *group) GetGroupUsernames(tx *gorm.DB, group *model.AuthGroup) ([]string, error) {
+	var users []model.AuthUser
+	if err := tx.Model(&group).Association("Users").Find(&users); err != nil {
+		return nil, err
+	}
+	usernames := make([]string, 0, len(users))
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+	return usernames, nil
+}
+
 // ClearGroupUser 清空组用户
 func (u *group) ClearGroupUser(tx *gorm.DB, group *model.AuthGroup) (err error) {
 	return tx.Model(&group).Association("Users").Clear()
@@ -0,0 +1,193 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"time"
+)
+
+var Guide guide
+
+type guide struct{}
+
+// GuideStepList 返回给前端表格的数据结构体
+type GuideStepList struct {
+	Items []*GuideStepItem `json:"items"`
+	Total int64            `json:"total"`
+}
+
+// GuideStepItem 引导步骤（表格）
+type GuideStepItem struct {
+	ID        uint     `json:"id"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Image     string   `json:"image"`
+	ImagePath string   `json:"image_path"`
+	Sort      uint     `json:"sort"`
+	Enabled   bool     `json:"enabled"`
+	Roles     []string `json:"roles"`
+}
+
+// GuideStepUpdate 更新引导步骤结构体
+type GuideStepUpdate struct {
+	ID      uint    `json:"id" binding:"required"`
+	Title   string  `json:"title" binding:"required"`
+	Content string  `json:"content"`
+	Image   *string `json:"image"`
+	Sort    uint    `json:"sort"`
+	Enabled *bool   `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+}
+
+// GuideStepSort 引导步骤排序结构体
+type GuideStepSort struct {
+	ID   uint `json:"id" binding:"required"`
+	Sort uint `json:"sort"`
+}
+
+// GetGuideStepList 获取引导步骤列表（表格）
+func (g *guide) GetGuideStepList(title string, page, limit int) (data *GuideStepList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		steps []*model.SiteGuideStep
+		total int64
+	)
+
+	tx := global.MySQLClient.Model(&model.SiteGuideStep{}).
+		Preload("Roles").
+		Where("title like ?", "%"+title+"%").
+		Count(&total).
+		Order("sort ASC, id ASC").
+		Limit(limit).
+		Offset(startSet).
+		Find(&steps)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	items := make([]*GuideStepItem, len(steps))
+	for i, step := range steps {
+		items[i] = g.toItem(step)
+	}
+
+	return &GuideStepList{
+		Items: items,
+		Total: total,
+	}, nil
+}
+
+// GetUserGuideSteps 获取用户可见的引导步骤（未配置角色的步骤所有用户可见）
+func (g *guide) GetUserGuideSteps(username string) (data []*GuideStepItem, err error) {
+
+	var steps []*model.SiteGuideStep
+
+	// 用户所属角色
+	roleIds := global.MySQLClient.Table("auth_user_groups").
+		Select("auth_user_groups.auth_group_id").
+		Joins("JOIN auth_user ON auth_user.id = auth_user_groups.auth_user_id").
+		Joins("JOIN auth_group ON auth_group.id = auth_user_groups.auth_group_id").
+		Where("auth_user.username = ? AND auth_group.is_role_group = ?", username, true)
+
+	// 配置了角色的步骤
+	restricted := global.MySQLClient.Table("site_guide_step_roles").Select("site_guide_step_id")
+	// 用户有权查看的步骤
+	allowed := global.MySQLClient.Table("site_guide_step_roles").Select("site_guide_step_id").Where("auth_group_id IN (?)", roleIds)
+
+	if err := global.MySQLClient.Model(&model.SiteGuideStep{}).
+		Where("enabled = ?", true).
+		Where("id NOT IN (?) OR id IN (?)", restricted, allowed).
+		Order("sort ASC, id ASC").
+		Find(&steps).Error; err != nil {
+		return nil, err
+	}
+
+	data = make([]*GuideStepItem, len(steps))
+	for i, step := range steps {
+		data[i] = g.toItem(step)
+	}
+
+	return data, nil
+}
+
+// GetGuideStep 获取单个引导步骤
+func (g *guide) GetGuideStep(id uint) (*model.SiteGuideStep, error) {
+	var step model.SiteGuideStep
+	if err := global.MySQLClient.First(&step, id).Error; err != nil {
+		return nil, err
+	}
+	return &step, nil
+}
+
+// AddGuideStep 新增引导步骤
+func (g *guide) AddGuideStep(tx *gorm.DB, data *model.SiteGuideStep) (step *model.SiteGuideStep, err error) {
+	if err := tx.Create(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateGuideStep 修改引导步骤
+func (g *guide) UpdateGuideStep(tx *gorm.DB, step *model.SiteGuideStep, data *GuideStepUpdate) (*model.SiteGuideStep, error) {
+	if err := tx.Model(step).Select("title", "content", "image", "sort", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return step, nil
+}
+
+// UpdateGuideStepRoles 更新引导步骤可见角色
+func (g *guide) UpdateGuideStepRoles(tx *gorm.DB, step *model.SiteGuideStep, roles []model.AuthGroup) error {
+	if len(roles) == 0 {
+		return tx.Model(step).Association("Roles").Clear()
+	}
+	return tx.Model(step).Association("Roles").Replace(roles)
+}
+
+// UpdateGuideStepSort 批量更新引导步骤排序
+func (g *guide) UpdateGuideStepSort(tx *gorm.DB, data []GuideStepSort) error {
+	for _, item := range data {
+		if err := tx.Model(&model.SiteGuideStep{}).Where("id = ?", item.ID).Update("sort", item.Sort).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteGuideStep 删除引导步骤
+func (g *guide) DeleteGuideStep(tx *gorm.DB, step *model.SiteGuideStep) error {
+
+	// 删除步骤关联的角色
+	if err := tx.Model(step).Association("Roles").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(step).Error
+}
+
+// toItem 转换为返回给前端的结构体，图片返回一个Minio中的临时URL链接
+func (g *guide) toItem(step *model.SiteGuideStep) *GuideStepItem {
+	item := &GuideStepItem{
+		ID:      step.ID,
+		Title:   step.Title,
+		Content: step.Content,
+		Sort:    step.Sort,
+		Enabled: step.Enabled,
+		Roles:   make([]string, len(step.Roles)),
+	}
+
+	if step.Image != nil && *step.Image != "" {
+		item.ImagePath = *step.Image
+		imageUrl, err := utils.GetObjectURL(*step.Image, 6*time.Hour)
+		if err == nil {
+			item.Image = imageUrl
+		}
+	}
+
+	for i, role := range step.Roles {
+		item.Roles[i] = role.Name
+	}
+
+	return item
+}
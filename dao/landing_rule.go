@@ -0,0 +1,122 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var LandingRule landingRule
+
+type landingRule struct{}
+
+// LandingRuleList 返回给前端表格的数据结构体
+type LandingRuleList struct {
+	Items []*model.LandingRule `json:"items"`
+	Total int64                `json:"total"`
+}
+
+// LandingRuleUpdate 更新登录后跳转规则结构体
+type LandingRuleUpdate struct {
+	ID          uint   `json:"id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	SiteID      *uint  `json:"site_id"`
+	URL         string `json:"url" binding:"required"`
+	Enabled     *bool  `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+}
+
+// GetLandingRuleList 获取登录后跳转规则列表（表格）
+func (l *landingRule) GetLandingRuleList(name string, page, limit int) (data *LandingRuleList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		rules []*model.LandingRule
+		total int64
+	)
+
+	tx := global.MySQLClient.Model(&model.LandingRule{}).
+		Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&rules)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &LandingRuleList{
+		Items: rules,
+		Total: total,
+	}, nil
+}
+
+// GetEnabledLandingRules 获取已启用的登录后跳转规则
+func (l *landingRule) GetEnabledLandingRules() (rules []*model.LandingRule, err error) {
+	if err := global.MySQLClient.
+		Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Select("id") }).
+		Where("enabled = ?", true).
+		Order("id").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetLandingRule 获取单个登录后跳转规则
+func (l *landingRule) GetLandingRule(id uint) (*model.LandingRule, error) {
+	var rule model.LandingRule
+	if err := global.MySQLClient.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// AddLandingRule 新增登录后跳转规则
+func (l *landingRule) AddLandingRule(tx *gorm.DB, data *model.LandingRule) (rule *model.LandingRule, err error) {
+	if err := tx.Create(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateLandingRule 修改登录后跳转规则
+func (l *landingRule) UpdateLandingRule(tx *gorm.DB, rule *model.LandingRule, data *LandingRuleUpdate) (*model.LandingRule, error) {
+	if err := tx.Model(rule).Select("name", "description", "site_id", "url", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateLandingRuleGroups 更新登录后跳转规则适用的分组
+func (l *landingRule) UpdateLandingRuleGroups(tx *gorm.DB, rule *model.LandingRule, groups []model.AuthGroup) error {
+	if len(groups) == 0 {
+		return tx.Model(rule).Association("Groups").Clear()
+	}
+	return tx.Model(rule).Association("Groups").Replace(groups)
+}
+
+// DeleteLandingRule 删除登录后跳转规则
+func (l *landingRule) DeleteLandingRule(tx *gorm.DB, rule *model.LandingRule) error {
+
+	// 删除规则关联的分组
+	if err := tx.Model(rule).Association("Groups").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(rule).Error
+}
+
+// GetSiteAddresses 获取已配置访问地址的站点，用于判断跳转地址是否属于已注册的应用
+func (l *landingRule) GetSiteAddresses() (sites []*model.Site, err error) {
+	if err := global.MySQLClient.
+		Select("id", "name", "address", "all_open").
+		Where("address <> ''").
+		Find(&sites).Error; err != nil {
+		return nil, err
+	}
+	return sites, nil
+}
@@ -0,0 +1,98 @@
+package dao
+
+import (
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var LoginHook loginHook
+
+type loginHook struct{}
+
+// LoginHookList 返回给前端表格的数据结构体
+type LoginHookList struct {
+	Items []*model.LoginHook `json:"items"`
+	Total int64              `json:"total"`
+}
+
+// LoginHookUpdate 更新登录扩展结构体
+type LoginHookUpdate struct {
+	ID          uint   `json:"id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Stage       string `json:"stage" binding:"required,oneof=pre_auth post_auth pre_token"`
+	URL         string `json:"url" binding:"required,url"`
+	Secret      string `json:"secret"`
+	Timeout     uint   `json:"timeout" binding:"required,min=1,max=30"`
+	FailOpen    *bool  `json:"fail_open" binding:"required"`
+	Sort        int    `json:"sort"`
+	Enabled     *bool  `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+}
+
+// GetLoginHookList 获取登录扩展列表（表格）
+func (l *loginHook) GetLoginHookList(name string, page, limit int) (data *LoginHookList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		hooks []*model.LoginHook
+		total int64
+	)
+
+	tx := global.MySQLClient.Model(&model.LoginHook{}).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("stage, sort, id").
+		Find(&hooks)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &LoginHookList{
+		Items: hooks,
+		Total: total,
+	}, nil
+}
+
+// GetEnabledLoginHooks 获取指定扩展点已启用的登录扩展
+func (l *loginHook) GetEnabledLoginHooks(stage string) (hooks []*model.LoginHook, err error) {
+	if err := global.MySQLClient.
+		Where("stage = ? AND enabled = ?", stage, true).
+		Order("sort, id").
+		Find(&hooks).Error; err != nil {
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// GetLoginHook 获取单个登录扩展
+func (l *loginHook) GetLoginHook(id uint) (*model.LoginHook, error) {
+	var hook model.LoginHook
+	if err := global.MySQLClient.First(&hook, id).Error; err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// AddLoginHook 新增登录扩展
+func (l *loginHook) AddLoginHook(data *model.LoginHook) (*model.LoginHook, error) {
+	if err := global.MySQLClient.Create(data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateLoginHook 修改登录扩展
+func (l *loginHook) UpdateLoginHook(hook *model.LoginHook, data *LoginHookUpdate) (*model.LoginHook, error) {
+	if err := global.MySQLClient.Model(hook).Select("name", "description", "stage", "url", "secret", "timeout", "fail_open", "sort", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// DeleteLoginHook 删除登录扩展
+func (l *loginHook) DeleteLoginHook(hook *model.LoginHook) error {
+	return global.MySQLClient.Unscoped().Delete(hook).Error
+}
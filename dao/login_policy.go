@@ -0,0 +1,281 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var LoginPolicy loginPolicy
+
+type loginPolicy struct{}
+
+// LoginPolicyList 返回给前端表格的数据结构体
+type LoginPolicyList struct {
+	Items []*model.LoginPolicy `json:"items"`
+	Total int64                `json:"total"`
+}
+
+// LoginPolicyUpdate 更新登录策略结构体
+type LoginPolicyUpdate struct {
+	ID          uint   `json:"id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Countries   string `json:"countries"`
+	IPRanges    string `json:"ip_ranges"`
+	Action      uint   `json:"action" binding:"required,oneof=1 2"`
+	Enabled     *bool  `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+}
+
+// LoginTimePolicyList 返回给前端表格的数据结构体
+type LoginTimePolicyList struct {
+	Items []*model.LoginTimePolicy `json:"items"`
+	Total int64                    `json:"total"`
+}
+
+// LoginTimePolicyUpdate 更新登录时间策略结构体
+type LoginTimePolicyUpdate struct {
+	ID           uint   `json:"id" binding:"required"`
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	Weekdays     string `json:"weekdays"`
+	WorkdaysOnly *bool  `json:"workdays_only" binding:"required"`
+	StartTime    string `json:"start_time" binding:"required"`
+	EndTime      string `json:"end_time" binding:"required"`
+	Enabled      *bool  `json:"enabled" binding:"required"`
+}
+
+// GetLoginPolicyList 获取登录策略列表（表格）
+func (l *loginPolicy) GetLoginPolicyList(name string, page, limit int) (data *LoginPolicyList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		policies []*model.LoginPolicy
+		total    int64
+	)
+
+	tx := global.MySQLClient.Model(&model.LoginPolicy{}).
+		Preload("ExemptUsers", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name", "username") }).
+		Preload("ExemptGroups", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&policies)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &LoginPolicyList{
+		Items: policies,
+		Total: total,
+	}, nil
+}
+
+// GetEnabledLoginPolicies 获取已启用的登录策略
+func (l *loginPolicy) GetEnabledLoginPolicies() (policies []*model.LoginPolicy, err error) {
+	if err := global.MySQLClient.
+		Preload("ExemptUsers", func(db *gorm.DB) *gorm.DB { return db.Select("id") }).
+		Preload("ExemptGroups", func(db *gorm.DB) *gorm.DB { return db.Select("id") }).
+		Where("enabled = ?", true).
+		Order("id").
+		Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetLoginPolicy 获取单个登录策略
+func (l *loginPolicy) GetLoginPolicy(id uint) (*model.LoginPolicy, error) {
+	var policy model.LoginPolicy
+	if err := global.MySQLClient.First(&policy, id).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// AddLoginPolicy 新增登录策略
+func (l *loginPolicy) AddLoginPolicy(tx *gorm.DB, data *model.LoginPolicy) (policy *model.LoginPolicy, err error) {
+	if err := tx.Create(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateLoginPolicy 修改登录策略
+func (l *loginPolicy) UpdateLoginPolicy(tx *gorm.DB, policy *model.LoginPolicy, data *LoginPolicyUpdate) (*model.LoginPolicy, error) {
+	if err := tx.Model(policy).Select("name", "description", "countries", "ip_ranges", "action", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdateLoginPolicyExemptions 更新登录策略豁免的用户及分组
+func (l *loginPolicy) UpdateLoginPolicyExemptions(tx *gorm.DB, policy *model.LoginPolicy, users []model.AuthUser, groups []model.AuthGroup) error {
+	if len(users) == 0 {
+		if err := tx.Model(policy).Association("ExemptUsers").Clear(); err != nil {
+			return err
+		}
+	} else if err := tx.Model(policy).Association("ExemptUsers").Replace(users); err != nil {
+		return err
+	}
+
+	if len(groups) == 0 {
+		return tx.Model(policy).Association("ExemptGroups").Clear()
+	}
+	return tx.Model(policy).Association("ExemptGroups").Replace(groups)
+}
+
+// DeleteLoginPolicy 删除登录策略
+func (l *loginPolicy) DeleteLoginPolicy(tx *gorm.DB, policy *model.LoginPolicy) error {
+
+	// 删除策略关联的豁免用户及分组
+	if err := tx.Model(policy).Association("ExemptUsers").Clear(); err != nil {
+		return err
+	}
+	if err := tx.Model(policy).Association("ExemptGroups").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(policy).Error
+}
+
+// GetUserGroupIds 获取用户所属的分组ID
+func (l *loginPolicy) GetUserGroupIds(userId uint) (groupIds []uint, err error) {
+	if err := global.MySQLClient.Table("auth_user_groups").
+		Where("auth_user_id = ?", userId).
+		Pluck("auth_group_id", &groupIds).Error; err != nil {
+		return nil, err
+	}
+	return groupIds, nil
+}
+
+// GetLoginTimePolicyList 获取登录时间策略列表（表格）
+func (l *loginPolicy) GetLoginTimePolicyList(name string, page, limit int) (data *LoginTimePolicyList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		policies []*model.LoginTimePolicy
+		total    int64
+	)
+
+	tx := global.MySQLClient.Model(&model.LoginTimePolicy{}).
+		Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&policies)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &LoginTimePolicyList{
+		Items: policies,
+		Total: total,
+	}, nil
+}
+
+// GetUserLoginTimePolicies 获取适用于指定分组的已启用登录时间策略
+func (l *loginPolicy) GetUserLoginTimePolicies(groupIds []uint) (policies []*model.LoginTimePolicy, err error) {
+	if len(groupIds) == 0 {
+		return nil, nil
+	}
+
+	policyIds := global.MySQLClient.Table("login_time_policy_groups").
+		Select("login_time_policy_id").
+		Where("auth_group_id IN ?", groupIds)
+
+	if err := global.MySQLClient.
+		Where("enabled = ? AND id IN (?)", true, policyIds).
+		Order("id").
+		Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetLoginTimePolicy 获取单个登录时间策略
+func (l *loginPolicy) GetLoginTimePolicy(id uint) (*model.LoginTimePolicy, error) {
+	var policy model.LoginTimePolicy
+	if err := global.MySQLClient.First(&policy, id).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// AddLoginTimePolicy 新增登录时间策略
+func (l *loginPolicy) AddLoginTimePolicy(tx *gorm.DB, data *model.LoginTimePolicy) (policy *model.LoginTimePolicy, err error) {
+	if err := tx.Create(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateLoginTimePolicy 修改登录时间策略
+func (l *loginPolicy) UpdateLoginTimePolicy(tx *gorm.DB, policy *model.LoginTimePolicy, data *LoginTimePolicyUpdate) (*model.LoginTimePolicy, error) {
+	if err := tx.Model(policy).Select("name", "description", "weekdays", "workdays_only", "start_time", "end_time", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdateLoginTimePolicyGroups 更新登录时间策略适用的分组
+func (l *loginPolicy) UpdateLoginTimePolicyGroups(tx *gorm.DB, policy *model.LoginTimePolicy, groups []model.AuthGroup) error {
+	if len(groups) == 0 {
+		return tx.Model(policy).Association("Groups").Clear()
+	}
+	return tx.Model(policy).Association("Groups").Replace(groups)
+}
+
+// DeleteLoginTimePolicy 删除登录时间策略
+func (l *loginPolicy) DeleteLoginTimePolicy(tx *gorm.DB, policy *model.LoginTimePolicy) error {
+
+	// 删除策略关联的分组
+	if err := tx.Model(policy).Association("Groups").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(policy).Error
+}
+
+// GetHolidayList 获取指定年份的节假日日历
+func (l *loginPolicy) GetHolidayList(year string) (holidays []*model.Holiday, err error) {
+	if err := global.MySQLClient.
+		Where("date like ?", year+"-%").
+		Order("date").
+		Find(&holidays).Error; err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
+
+// GetHoliday 获取指定日期的节假日信息，不存在时返回nil
+func (l *loginPolicy) GetHoliday(date string) (*model.Holiday, error) {
+	var holidays []*model.Holiday
+	if err := global.MySQLClient.Where("date = ?", date).Limit(1).Find(&holidays).Error; err != nil {
+		return nil, err
+	}
+	if len(holidays) == 0 {
+		return nil, nil
+	}
+	return holidays[0], nil
+}
+
+// SaveHolidays 批量保存节假日，日期已存在时更新
+func (l *loginPolicy) SaveHolidays(tx *gorm.DB, holidays []*model.Holiday) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "workday", "updated_at"}),
+	}).Create(&holidays).Error
+}
+
+// DeleteHoliday 删除节假日
+func (l *loginPolicy) DeleteHoliday(id uint) error {
+	return global.MySQLClient.Unscoped().Delete(&model.Holiday{}, id).Error
+}
@@ -95,8 +95,10 @@ func (m *menu) GetUserMenu(tx *gorm.DB, username string) (data []*MenuItem, err
 		menuItems []*MenuItem
 	)
 
-	// 获取一级菜单
-	if err := tx.Order("sort").Find(&menus).Error; err != nil {
+	// 获取一级菜单，同时预加载二级菜单，避免逐个菜单查询
+	if err := tx.Preload("SubMenus", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort")
+	}).Order("sort").Find(&menus).Error; err != nil {
 		return nil, err
 	}
 
@@ -117,12 +119,8 @@ func (m *menu) GetUserMenu(tx *gorm.DB, username string) (data []*MenuItem, err
 				Children: nil,
 			}
 
-			// 获取一级菜单对应的二级菜单
-			var subMenus []*model.SubMenu
-			if err := tx.Where("menu_id = ?", menu.Id).Order("sort").Find(&subMenus).Error; err != nil {
-				return nil, err
-			}
-			for _, subMenu := range subMenus {
+			// 遍历一级菜单对应的二级菜单
+			for _, subMenu := range menu.SubMenus {
 				// 判断用户是否拥有该菜单权限
 				ok, _ := global.CasBinServer.Enforce(username, subMenu.Name, "read")
 				if ok {
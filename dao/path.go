@@ -111,6 +111,22 @@ func (p *path) GetPathInfo(name string) (data *model.SystemPath, err error) {
 	return path, nil
 }
 
+// GetPathMap 获取所有接口，以“请求方法 接口路径”为Key，用于批量转换权限名称
+func (p *path) GetPathMap() (data map[string]*model.SystemPath, err error) {
+	var paths []*model.SystemPath
+
+	if err := global.MySQLClient.Find(&paths).Error; err != nil {
+		return nil, err
+	}
+
+	data = make(map[string]*model.SystemPath, len(paths))
+	for _, item := range paths {
+		data[item.Method+" "+item.Path] = item
+	}
+
+	return data, nil
+}
+
 // GetPathName 根据接口路径Path和请求方法Method获取接口Name
 func (p *path) GetPathName(path, method string) (title *string, err error) {
 	var systemPath model.SystemPath
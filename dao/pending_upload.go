@@ -0,0 +1,50 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var PendingUpload pendingUpload
+
+type pendingUpload struct{}
+
+// SavePendingUpload 暂存待上传文件，同一对象已存在时使用新的内容覆盖，同时删除该用户暂存的其它头像
+func (p *pendingUpload) SavePendingUpload(data *model.PendingUpload) error {
+	return global.MySQLClient.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("username = ? AND object <> ?", data.Username, data.Object).Delete(&model.PendingUpload{}).Error; err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "object"}},
+			DoUpdates: clause.AssignmentColumns([]string{"content_type", "data", "username", "attempts", "last_error", "created_at"}),
+		}).Create(data).Error
+	})
+}
+
+// GetPendingUploads 获取待上传文件，按暂存时间排序
+func (p *pendingUpload) GetPendingUploads(limit int) (items []*model.PendingUpload, err error) {
+	if err := global.MySQLClient.Order("id").Limit(limit).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// CountPendingUploads 获取待上传文件数量
+func (p *pendingUpload) CountPendingUploads() (total int64, err error) {
+	err = global.MySQLClient.Model(&model.PendingUpload{}).Count(&total).Error
+	return total, err
+}
+
+// RecordPendingUploadFailure 记录上传失败的原因
+func (p *pendingUpload) RecordPendingUploadFailure(id uint, reason string) error {
+	return global.MySQLClient.Model(&model.PendingUpload{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"attempts": gorm.Expr("attempts + 1"), "last_error": reason}).Error
+}
+
+// DeletePendingUpload 上传完成后删除暂存的文件，暂存后内容被覆盖（时间不同）时不删除
+func (p *pendingUpload) DeletePendingUpload(data *model.PendingUpload) error {
+	return global.MySQLClient.Where("id = ? AND created_at = ?", data.ID, data.CreatedAt).Delete(&model.PendingUpload{}).Error
+}
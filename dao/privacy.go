@@ -0,0 +1,228 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var Privacy privacy
+
+type privacy struct{}
+
+// UserErasureList 用户数据擦除记录列表
+type UserErasureList struct {
+	Items []*model.UserErasure `json:"items"`
+	Total int64                `json:"total"`
+}
+
+// OAuthGrant 用户授予应用的离线访问授权
+type OAuthGrant struct {
+	Application string     `json:"application"`
+	ClientID    string     `json:"client_id"`
+	Scope       string     `json:"scope"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+}
+
+// anonymizeTarget 擦除用户数据时需要匿名化的历史记录
+type anonymizeTarget struct {
+	name   string
+	model  interface{}
+	where  string
+	args   []interface{}
+	fields map[string]interface{}
+}
+
+// GetUserGroups 获取用户所属的分组名称
+func (p *privacy) GetUserGroups(userId uint) (names []string, err error) {
+	if err := global.MySQLClient.Table("auth_group").
+		Joins("JOIN auth_user_groups ON auth_user_groups.auth_group_id = auth_group.id").
+		Where("auth_user_groups.auth_user_id = ?", userId).
+		Pluck("auth_group.name", &names).Error; err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// GetUserSites 获取授权用户访问的站点名称
+func (p *privacy) GetUserSites(userId uint) (names []string, err error) {
+	if err := global.MySQLClient.Table("site").
+		Joins("JOIN site_users ON site_users.site_id = site.id").
+		Where("site_users.auth_user_id = ?", userId).
+		Pluck("site.name", &names).Error; err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// GetUserLoginHistory 获取用户的登录记录
+func (p *privacy) GetUserLoginHistory(username string) (logs []*model.LogLogin, err error) {
+	if err := global.MySQLClient.Where("username = ?", username).Order("id desc").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// GetUserOAuthGrants 获取用户授予应用的离线访问授权，同一次授权轮换产生的刷新令牌仅返回最新一条
+func (p *privacy) GetUserOAuthGrants(userId uint) (grants []*OAuthGrant, err error) {
+	latest := global.MySQLClient.Model(&model.SsoOAuthRefreshToken{}).
+		Select("MAX(id)").
+		Where("user_id = ?", userId).
+		Group("session_id")
+	if err := global.MySQLClient.Table("sso_oauth_refresh_token AS t").
+		Select("site.name AS application, t.client_id, t.scope, t.created_at, t.expires_at, t.revoked_at").
+		Joins("LEFT JOIN site ON site.client_id = t.client_id").
+		Where("t.id IN (?)", latest).
+		Order("t.id desc").
+		Scan(&grants).Error; err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// GetUserTermsAcceptances 获取用户的使用条款接受记录
+func (p *privacy) GetUserTermsAcceptances(userId uint) (items []*model.TermsAcceptance, err error) {
+	if err := global.MySQLClient.Where("user_id = ?", userId).Order("id desc").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetOwnedAccounts 获取用户作为负责人的账号
+func (p *privacy) GetOwnedAccounts(userId uint) (accounts []*model.Account, err error) {
+	if err := global.MySQLClient.Where("owner_user_id = ?", userId).Order("id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// CountOwnedAccounts 获取用户作为负责人的账号数量
+func (p *privacy) CountOwnedAccounts(userId uint) (count int64, err error) {
+	if err := global.MySQLClient.Model(&model.Account{}).Where("owner_user_id = ?", userId).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// AnonymizeUser 擦除用户的个人信息，历史记录中的用户名替换为化名，IP地址、User-Agent等个人数据清空，
+// 保留记录本身（时间、结果、应用等）以确保审计记录的完整性，返回各类数据的处理数量
+func (p *privacy) AnonymizeUser(tx *gorm.DB, user *model.AuthUser, pseudonym, name, password string) (map[string]int64, error) {
+
+	counts := make(map[string]int64)
+
+	// 用户信息
+	if err := tx.Model(&model.AuthUser{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"username":         pseudonym,
+		"name":             name,
+		"password":         password,
+		"email":            "",
+		"phone_number":     "",
+		"phone_national":   "",
+		"phone_display":    "",
+		"ww_id":            nil,
+		"ctyun_id":         nil,
+		"avatar":           nil,
+		"mfa_code":         nil,
+		"external_id":      nil,
+		"department":       "",
+		"title":            "",
+		"language":         "",
+		"timezone":         "",
+		"required_actions": "",
+		"is_active":        false,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	// 分组、站点及共享账号关联关系
+	if err := tx.Model(user).Association("Groups").Clear(); err != nil {
+		return nil, err
+	}
+	if err := tx.Exec("DELETE FROM site_users WHERE auth_user_id = ?", user.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Exec("DELETE FROM account_users WHERE auth_user_id = ?", user.ID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("ptype = ? AND v0 = ?", "g", user.Username).Delete(&model.CasbinRule{}).Error; err != nil {
+		return nil, err
+	}
+
+	// 登录设备
+	result := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&model.UserDevice{})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	counts["devices"] = result.RowsAffected
+	if err := tx.Unscoped().Where("user_id = ?", user.ID).Delete(&model.UserLoginCountry{}).Error; err != nil {
+		return nil, err
+	}
+
+	// 应用授权记录
+	consents, err := Consent.DeleteUserConsents(tx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	counts["consents"] = consents
+
+	// 历史记录
+	updates := []*anonymizeTarget{
+		{"login_logs", &model.LogLogin{}, "username = ?", []interface{}{user.Username},
+			map[string]interface{}{"username": pseudonym, "source_ip": "", "user_agent": "", "city": ""}},
+		{"operation_logs", &model.LogOplog{}, "username = ?", []interface{}{user.Username},
+			map[string]interface{}{"username": pseudonym, "client_ip": "", "user_agent": ""}},
+		{"scim_logs", &model.LogSCIM{}, "resource_type = ? AND resource_name = ?", []interface{}{"User", user.Username},
+			map[string]interface{}{"resource_name": pseudonym}},
+		{"sessions", &model.UserSession{}, "user_id = ?", []interface{}{user.ID},
+			map[string]interface{}{"username": pseudonym, "device": "", "fingerprint": "", "client_ip": "", "user_agent": ""}},
+		{"terms_acceptances", &model.TermsAcceptance{}, "user_id = ?", []interface{}{user.ID},
+			map[string]interface{}{"username": pseudonym, "client_ip": "", "user_agent": ""}},
+	}
+	if user.PhoneNumber != "" {
+		updates = append(updates, &anonymizeTarget{"sms_logs", &model.LogSMS{}, "receiver = ?", []interface{}{user.PhoneNumber},
+			map[string]interface{}{"receiver": ""}})
+	}
+	for _, item := range updates {
+		result := tx.Model(item.model).Where(item.where, item.args...).Updates(item.fields)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		counts[item.name] = result.RowsAffected
+	}
+
+	return counts, nil
+}
+
+// AddErasure 保存用户数据擦除记录
+func (p *privacy) AddErasure(tx *gorm.DB, data *model.UserErasure) error {
+	return tx.Create(data).Error
+}
+
+// GetErasureList 获取用户数据擦除记录（表格）
+func (p *privacy) GetErasureList(pseudonym string, page, limit int) (data *UserErasureList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		items []*model.UserErasure
+		total int64
+	)
+
+	if err := global.MySQLClient.Model(&model.UserErasure{}).
+		Where("pseudonym like ?", "%"+pseudonym+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return &UserErasureList{
+		Items: items,
+		Total: total,
+	}, nil
+}
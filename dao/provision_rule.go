@@ -0,0 +1,139 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var ProvisionRule provisionRule
+
+type provisionRule struct{}
+
+// ProvisionRuleList 返回给前端表格的数据结构体
+type ProvisionRuleList struct {
+	Items []*model.ProvisionRule `json:"items"`
+	Total int64                  `json:"total"`
+}
+
+// ProvisionRuleUpdate 更新自动分配规则结构体
+type ProvisionRuleUpdate struct {
+	ID          uint   `json:"id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	MatchType   uint   `json:"match_type" binding:"required,oneof=1 2"`
+	MatchValue  string `json:"match_value" binding:"required"`
+	Enabled     *bool  `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+}
+
+// GetProvisionRuleList 获取自动分配规则列表（表格）
+func (p *provisionRule) GetProvisionRuleList(name string, page, limit int) (data *ProvisionRuleList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		rules []*model.ProvisionRule
+		total int64
+	)
+
+	tx := global.MySQLClient.Model(&model.ProvisionRule{}).
+		Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Preload("Sites", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&rules)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &ProvisionRuleList{
+		Items: rules,
+		Total: total,
+	}, nil
+}
+
+// GetEnabledProvisionRules 获取已启用的自动分配规则
+func (p *provisionRule) GetEnabledProvisionRules(tx *gorm.DB) (rules []*model.ProvisionRule, err error) {
+	if err := tx.
+		Preload("Groups").
+		Preload("Sites", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Where("enabled = ?", true).
+		Order("id").
+		Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetProvisionRule 获取单个自动分配规则
+func (p *provisionRule) GetProvisionRule(id uint) (*model.ProvisionRule, error) {
+	var rule model.ProvisionRule
+	if err := global.MySQLClient.First(&rule, id).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// AddProvisionRule 新增自动分配规则
+func (p *provisionRule) AddProvisionRule(tx *gorm.DB, data *model.ProvisionRule) (rule *model.ProvisionRule, err error) {
+	if err := tx.Create(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateProvisionRule 修改自动分配规则
+func (p *provisionRule) UpdateProvisionRule(tx *gorm.DB, rule *model.ProvisionRule, data *ProvisionRuleUpdate) (*model.ProvisionRule, error) {
+	if err := tx.Model(rule).Select("name", "description", "match_type", "match_value", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// UpdateProvisionRuleTargets 更新自动分配规则的分组及站点
+func (p *provisionRule) UpdateProvisionRuleTargets(tx *gorm.DB, rule *model.ProvisionRule, groups []model.AuthGroup, sites []model.Site) error {
+	if len(groups) == 0 {
+		if err := tx.Model(rule).Association("Groups").Clear(); err != nil {
+			return err
+		}
+	} else if err := tx.Model(rule).Association("Groups").Replace(groups); err != nil {
+		return err
+	}
+
+	if len(sites) == 0 {
+		return tx.Model(rule).Association("Sites").Clear()
+	}
+	return tx.Model(rule).Association("Sites").Replace(sites)
+}
+
+// DeleteProvisionRule 删除自动分配规则
+func (p *provisionRule) DeleteProvisionRule(tx *gorm.DB, rule *model.ProvisionRule) error {
+
+	// 删除规则关联的分组及站点
+	if err := tx.Model(rule).Association("Groups").Clear(); err != nil {
+		return err
+	}
+	if err := tx.Model(rule).Association("Sites").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(rule).Error
+}
+
+// AssignUser 将用户加入分组并授权站点，已存在的关联不会重复添加
+func (p *provisionRule) AssignUser(tx *gorm.DB, user *model.AuthUser, groups []*model.AuthGroup, sites []*model.Site) error {
+	if len(groups) > 0 {
+		if err := tx.Model(user).Association("Groups").Append(groups); err != nil {
+			return err
+		}
+	}
+	for _, site := range sites {
+		if err := tx.Model(site).Association("Users").Append(user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
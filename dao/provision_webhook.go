@@ -0,0 +1,99 @@
+package dao
+
+import (
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var ProvisionWebhook provisionWebhook
+
+type provisionWebhook struct{}
+
+// ProvisionWebhookList 返回给前端表格的数据结构体
+type ProvisionWebhookList struct {
+	Items []*model.ProvisionWebhook `json:"items"`
+	Total int64                     `json:"total"`
+}
+
+// ProvisionWebhookUpdate 更新身份事件推送结构体
+type ProvisionWebhookUpdate struct {
+	ID          uint   `json:"id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	URL         string `json:"url" binding:"required,url"`
+	Method      string `json:"method" binding:"required,oneof=POST PUT"`
+	ContentType string `json:"content_type" binding:"required"`
+	Events      string `json:"events"`
+	Template    string `json:"template"`
+	Secret      string `json:"secret"`
+	Timeout     uint   `json:"timeout" binding:"required,min=1,max=30"`
+	Enabled     *bool  `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+}
+
+// GetProvisionWebhookList 获取身份事件推送列表（表格）
+func (p *provisionWebhook) GetProvisionWebhookList(name string, page, limit int) (data *ProvisionWebhookList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		webhooks []*model.ProvisionWebhook
+		total    int64
+	)
+
+	tx := global.MySQLClient.Model(&model.ProvisionWebhook{}).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&webhooks)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &ProvisionWebhookList{
+		Items: webhooks,
+		Total: total,
+	}, nil
+}
+
+// GetEnabledProvisionWebhooks 获取已启用的身份事件推送
+func (p *provisionWebhook) GetEnabledProvisionWebhooks() (webhooks []*model.ProvisionWebhook, err error) {
+	if err := global.MySQLClient.
+		Where("enabled = ?", true).
+		Order("id").
+		Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+// GetProvisionWebhook 获取单个身份事件推送
+func (p *provisionWebhook) GetProvisionWebhook(id uint) (*model.ProvisionWebhook, error) {
+	var webhook model.ProvisionWebhook
+	if err := global.MySQLClient.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// AddProvisionWebhook 新增身份事件推送
+func (p *provisionWebhook) AddProvisionWebhook(data *model.ProvisionWebhook) (*model.ProvisionWebhook, error) {
+	if err := global.MySQLClient.Create(data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateProvisionWebhook 修改身份事件推送
+func (p *provisionWebhook) UpdateProvisionWebhook(webhook *model.ProvisionWebhook, data *ProvisionWebhookUpdate) (*model.ProvisionWebhook, error) {
+	if err := global.MySQLClient.Model(webhook).Select("name", "description", "url", "method", "content_type", "events", "template", "secret", "timeout", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// DeleteProvisionWebhook 删除身份事件推送
+func (p *provisionWebhook) DeleteProvisionWebhook(webhook *model.ProvisionWebhook) error {
+	return global.MySQLClient.Unscoped().Delete(webhook).Error
+}
@@ -0,0 +1,142 @@
+package dao
+
+import (
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var ComplianceReport complianceReport
+
+type complianceReport struct{}
+
+// ComplianceReportList 返回给前端表格的数据结构体
+type ComplianceReportList struct {
+	Items []*model.ComplianceReport `json:"items"`
+	Total int64                     `json:"total"`
+}
+
+// SiteUserAccess 应用授权用户
+type SiteUserAccess struct {
+	Site     string `json:"site"`
+	AllOpen  bool   `json:"all_open"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	IsActive bool   `json:"is_active"`
+}
+
+// UserLoginSummary 用户登录汇总
+type UserLoginSummary struct {
+	Username    string    `json:"username"`
+	Success     int64     `json:"success"`
+	Failed      int64     `json:"failed"`
+	LastLoginAt time.Time `json:"last_login_at"`
+}
+
+// GetReportList 获取合规报告列表（表格）
+func (r *complianceReport) GetReportList(period string, page, limit int) (data *ComplianceReportList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		reports []*model.ComplianceReport
+		total   int64
+	)
+
+	tx := global.MySQLClient.Model(&model.ComplianceReport{}).
+		Where("period like ?", "%"+period+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id DESC").
+		Find(&reports)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &ComplianceReportList{
+		Items: reports,
+		Total: total,
+	}, nil
+}
+
+// GetReport 获取合规报告
+func (r *complianceReport) GetReport(id uint) (data *model.ComplianceReport, err error) {
+	if err := global.MySQLClient.First(&data, id).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// CreateReport 新增合规报告记录
+func (r *complianceReport) CreateReport(data *model.ComplianceReport) error {
+	return global.MySQLClient.Create(data).Error
+}
+
+// DeleteReport 删除合规报告记录
+func (r *complianceReport) DeleteReport(data *model.ComplianceReport) error {
+	return global.MySQLClient.Unscoped().Delete(data).Error
+}
+
+// GetSiteUserAccess 获取各应用的授权用户，对所有人开放的应用不列出用户
+func (r *complianceReport) GetSiteUserAccess() (data []*SiteUserAccess, err error) {
+	if err := global.MySQLClient.Table("site").
+		Select("site.name AS site, site.all_open, COALESCE(auth_user.username, '') AS username, COALESCE(auth_user.name, '') AS name, COALESCE(auth_user.is_active, false) AS is_active").
+		Joins("LEFT JOIN site_users ON site_users.site_id = site.id AND site.all_open = ?", false).
+		Joins("LEFT JOIN auth_user ON auth_user.id = site_users.auth_user_id AND auth_user.deleted_at IS NULL").
+		Order("site.name, auth_user.username").
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetDormantUsers 获取指定时间之后未登录过的启用用户
+func (r *complianceReport) GetDormantUsers(before time.Time) (data []*model.AuthUser, err error) {
+	if err := global.MySQLClient.Model(&model.AuthUser{}).
+		Select("id", "name", "username", "email", "user_from", "created_at", "last_login_at").
+		Where("is_active = ? AND (last_login_at IS NULL OR last_login_at < ?) AND created_at < ?", true, before, before).
+		Order("last_login_at").
+		Find(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetOplogRange 获取指定时间范围内的操作日志
+func (r *complianceReport) GetOplogRange(start, end time.Time) (data []*model.LogOplog, err error) {
+	if err := global.MySQLClient.Model(&model.LogOplog{}).
+		Select("id", "created_at", "username", "endpoint", "method", "client_ip").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Order("id").
+		Find(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetUserLoginSummary 按用户汇总指定时间范围内的登录次数
+func (r *complianceReport) GetUserLoginSummary(start, end time.Time) (data []*UserLoginSummary, err error) {
+	if err := global.MySQLClient.Model(&model.LogLogin{}).
+		Select("username, SUM(CASE WHEN status = 1 THEN 1 ELSE 0 END) AS success, SUM(CASE WHEN status = 1 THEN 0 ELSE 1 END) AS failed, MAX(created_at) AS last_login_at").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Group("username").
+		Order("username").
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetUserLoginRollup 按用户汇总指定日期范围内（包含结束日期）的登录统计日汇总数据
+func (r *complianceReport) GetUserLoginRollup(start, end string) (data []*UserLoginSummary, err error) {
+	if err := global.MySQLClient.Model(&model.StatUserLoginDaily{}).
+		Select("username, SUM(success) AS success, SUM(failed) AS failed, MAX(last_login_at) AS last_login_at").
+		Where("date >= ? AND date <= ?", start, end).
+		Group("username").
+		Order("username").
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,103 @@
+package dao
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var SCIM scim
+
+type scim struct{}
+
+// ScimFilter SCIM过滤条件，Column需来自字段白名单
+type ScimFilter struct {
+	Column string
+	Value  interface{}
+}
+
+// GetUserList 获取用户列表，limit=0时仅返回总数
+func (s *scim) GetUserList(filter *ScimFilter, offset, limit int) (users []*model.AuthUser, total int64, err error) {
+
+	tx := global.MySQLClient.Model(&model.AuthUser{})
+	if filter != nil {
+		tx = tx.Where(fmt.Sprintf("`%s` = ?", filter.Column), filter.Value)
+	}
+
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit == 0 {
+		return users, total, nil
+	}
+
+	if err := tx.Preload("Groups").Order("id asc").Offset(offset).Limit(limit).Find(&users).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+// GetUser 获取用户及其所属分组
+func (s *scim) GetUser(id uint) (*model.AuthUser, error) {
+	var user model.AuthUser
+	if err := global.MySQLClient.Preload("Groups").First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// UpdateUser 更新用户字段
+func (s *scim) UpdateUser(tx *gorm.DB, user *model.AuthUser, fields map[string]interface{}) error {
+	return tx.Model(user).Updates(fields).Error
+}
+
+// GetGroupList 获取分组列表，limit=0时仅返回总数
+func (s *scim) GetGroupList(filter *ScimFilter, offset, limit int) (groups []*model.AuthGroup, total int64, err error) {
+
+	tx := global.MySQLClient.Model(&model.AuthGroup{})
+	if filter != nil {
+		tx = tx.Where(fmt.Sprintf("`%s` = ?", filter.Column), filter.Value)
+	}
+
+	if err := tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if limit == 0 {
+		return groups, total, nil
+	}
+
+	if err := tx.Preload("Users").Order("id asc").Offset(offset).Limit(limit).Find(&groups).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return groups, total, nil
+}
+
+// GetGroup 获取分组及其成员
+func (s *scim) GetGroup(id uint) (*model.AuthGroup, error) {
+	var group model.AuthGroup
+	if err := global.MySQLClient.Preload("Users").First(&group, id).Error; err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+// IsUsernameExist 判断用户名是否已被其他用户使用
+func (s *scim) IsUsernameExist(username string, excludeId uint) (bool, error) {
+	var count int64
+	if err := global.MySQLClient.Model(&model.AuthUser{}).Where("username = ? AND id <> ?", username, excludeId).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// IsGroupNameExist 判断分组名称是否已被其他分组使用
+func (s *scim) IsGroupNameExist(name string, excludeId uint) (bool, error) {
+	var count int64
+	if err := global.MySQLClient.Model(&model.AuthGroup{}).Where("name = ? AND id <> ?", name, excludeId).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
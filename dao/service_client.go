@@ -0,0 +1,101 @@
+package dao
+
+import (
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var ServiceClient serviceClient
+
+type serviceClient struct{}
+
+// ServiceClientList 返回给前端表格的数据结构体
+type ServiceClientList struct {
+	Items []*model.ServiceClient `json:"items"`
+	Total int64                  `json:"total"`
+}
+
+// ServiceClientUpdate 更新服务客户端结构体
+type ServiceClientUpdate struct {
+	ID          uint   `json:"id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Scopes      string `json:"scopes" binding:"required"`
+	Enabled     *bool  `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+}
+
+// GetServiceClientList 获取服务客户端列表（表格）
+func (s *serviceClient) GetServiceClientList(name string, page, limit int) (data *ServiceClientList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		clients []*model.ServiceClient
+		total   int64
+	)
+
+	tx := global.MySQLClient.Model(&model.ServiceClient{}).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&clients)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &ServiceClientList{
+		Items: clients,
+		Total: total,
+	}, nil
+}
+
+// GetServiceClient 获取单个服务客户端
+func (s *serviceClient) GetServiceClient(conditions interface{}) (*model.ServiceClient, error) {
+	var client model.ServiceClient
+	if err := global.MySQLClient.Where(conditions).First(&client).Error; err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// GetSystemPaths 根据名称获取系统接口
+func (s *serviceClient) GetSystemPaths(names []string) (paths []*model.SystemPath, err error) {
+	if err := global.MySQLClient.Where("name IN ?", names).Find(&paths).Error; err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// AddServiceClient 新增服务客户端
+func (s *serviceClient) AddServiceClient(data *model.ServiceClient) (*model.ServiceClient, error) {
+	if err := global.MySQLClient.Create(data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateServiceClient 修改服务客户端
+func (s *serviceClient) UpdateServiceClient(client *model.ServiceClient, data *ServiceClientUpdate) (*model.ServiceClient, error) {
+	if err := global.MySQLClient.Model(client).Select("name", "description", "scopes", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// UpdateServiceClientSecret 修改服务客户端密钥
+func (s *serviceClient) UpdateServiceClientSecret(client *model.ServiceClient, secret string) error {
+	return global.MySQLClient.Model(client).Update("client_secret", secret).Error
+}
+
+// UpdateLastIssuedAt 记录服务客户端最近一次签发服务令牌的时间
+func (s *serviceClient) UpdateLastIssuedAt(id uint, issuedAt time.Time) error {
+	return global.MySQLClient.Model(&model.ServiceClient{}).Where("id = ?", id).Update("last_issued_at", issuedAt).Error
+}
+
+// DeleteServiceClient 删除服务客户端
+func (s *serviceClient) DeleteServiceClient(client *model.ServiceClient) error {
+	return global.MySQLClient.Unscoped().Delete(client).Error
+}
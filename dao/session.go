@@ -0,0 +1,227 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var Session session
+
+type session struct{}
+
+// SessionPolicyList 返回给前端表格的数据结构体
+type SessionPolicyList struct {
+	Items []*model.SessionPolicy `json:"items"`
+	Total int64                  `json:"total"`
+}
+
+// SessionPolicyUpdate 更新会话并发策略结构体
+type SessionPolicyUpdate struct {
+	ID            uint   `json:"id" binding:"required"`
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	MaxSessions   *uint  `json:"max_sessions" binding:"required"`
+	PerDeviceType *bool  `json:"per_device_type" binding:"required"`
+	Action        uint   `json:"action" binding:"required,oneof=1 2"`
+	Enabled       *bool  `json:"enabled" binding:"required"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+}
+
+// UserSessionList 返回给前端表格的数据结构体
+type UserSessionList struct {
+	Items []*model.UserSession `json:"items"`
+	Total int64                `json:"total"`
+}
+
+// GetSessionPolicyList 获取会话并发策略列表（表格）
+func (s *session) GetSessionPolicyList(name string, page, limit int) (data *SessionPolicyList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		policies []*model.SessionPolicy
+		total    int64
+	)
+
+	tx := global.MySQLClient.Model(&model.SessionPolicy{}).
+		Preload("Groups", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&policies)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &SessionPolicyList{
+		Items: policies,
+		Total: total,
+	}, nil
+}
+
+// GetUserSessionPolicy 获取适用于指定分组的已启用会话并发策略，多个策略同时适用时使用最先创建的策略，不存在时返回nil
+func (s *session) GetUserSessionPolicy(groupIds []uint) (*model.SessionPolicy, error) {
+	if len(groupIds) == 0 {
+		return nil, nil
+	}
+
+	policyIds := global.MySQLClient.Table("session_policy_groups").
+		Select("session_policy_id").
+		Where("auth_group_id IN ?", groupIds)
+
+	var policies []*model.SessionPolicy
+	if err := global.MySQLClient.
+		Where("enabled = ? AND id IN (?)", true, policyIds).
+		Order("id").
+		Limit(1).
+		Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	if len(policies) == 0 {
+		return nil, nil
+	}
+	return policies[0], nil
+}
+
+// GetSessionPolicy 获取单个会话并发策略
+func (s *session) GetSessionPolicy(id uint) (*model.SessionPolicy, error) {
+	var policy model.SessionPolicy
+	if err := global.MySQLClient.First(&policy, id).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// AddSessionPolicy 新增会话并发策略
+func (s *session) AddSessionPolicy(tx *gorm.DB, data *model.SessionPolicy) (policy *model.SessionPolicy, err error) {
+	if err := tx.Create(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateSessionPolicy 修改会话并发策略
+func (s *session) UpdateSessionPolicy(tx *gorm.DB, policy *model.SessionPolicy, data *SessionPolicyUpdate) (*model.SessionPolicy, error) {
+	if err := tx.Model(policy).Select("name", "description", "max_sessions", "per_device_type", "action", "enabled").Updates(data).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdateSessionPolicyGroups 更新会话并发策略适用的分组
+func (s *session) UpdateSessionPolicyGroups(tx *gorm.DB, policy *model.SessionPolicy, groups []model.AuthGroup) error {
+	if len(groups) == 0 {
+		return tx.Model(policy).Association("Groups").Clear()
+	}
+	return tx.Model(policy).Association("Groups").Replace(groups)
+}
+
+// DeleteSessionPolicy 删除会话并发策略
+func (s *session) DeleteSessionPolicy(tx *gorm.DB, policy *model.SessionPolicy) error {
+
+	// 删除策略关联的分组
+	if err := tx.Model(policy).Association("Groups").Clear(); err != nil {
+		return err
+	}
+
+	return tx.Unscoped().Delete(policy).Error
+}
+
+// GetUserSessionList 获取用户会话列表（表格），active为true时仅返回在线的会话
+func (s *session) GetUserSessionList(username string, active bool, page, limit int) (data *UserSessionList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		sessions []*model.UserSession
+		total    int64
+	)
+
+	tx := global.MySQLClient.Model(&model.UserSession{}).
+		Where("username like ?", "%"+username+"%")
+	if active {
+		tx = tx.Where("revoked_at IS NULL AND expires_at > ?", time.Now())
+	}
+	tx = tx.Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&sessions)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &UserSessionList{
+		Items: sessions,
+		Total: total,
+	}, nil
+}
+
+// GetUserSessions 获取用户最近的会话（包括已注销的会话）
+func (s *session) GetUserSessions(userId uint, limit int) (sessions []*model.UserSession, err error) {
+	if err := global.MySQLClient.
+		Where("user_id = ?", userId).
+		Order("id desc").
+		Limit(limit).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetActiveUserSessions 获取用户在线的会话，按登录时间排序
+func (s *session) GetActiveUserSessions(userId uint) (sessions []*model.UserSession, err error) {
+	if err := global.MySQLClient.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now()).
+		Order("created_at, id").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetUserSession 根据会话ID获取会话记录
+func (s *session) GetUserSession(sessionId string) (*model.UserSession, error) {
+	var userSession model.UserSession
+	if err := global.MySQLClient.Where("session_id = ?", sessionId).First(&userSession).Error; err != nil {
+		return nil, err
+	}
+	return &userSession, nil
+}
+
+// GetActiveDeviceSessions 获取用户在指定设备上在线的会话
+func (s *session) GetActiveDeviceSessions(userId uint, fingerprint string) (sessions []*model.UserSession, err error) {
+	if err := global.MySQLClient.
+		Where("user_id = ? AND fingerprint = ? AND revoked_at IS NULL AND expires_at > ?", userId, fingerprint, time.Now()).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// AddUserSession 新增会话记录
+func (s *session) AddUserSession(data *model.UserSession) error {
+	return global.MySQLClient.Create(data).Error
+}
+
+// RenewUserSession 更新会话过期时间
+func (s *session) RenewUserSession(sessionId string, expiresAt time.Time) error {
+	return global.MySQLClient.Model(&model.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionId).
+		Update("expires_at", expiresAt).Error
+}
+
+// RevokeUserSession 标记会话已注销，会话已注销时不覆盖原注销原因
+func (s *session) RevokeUserSession(sessionId, reason string) error {
+	return global.MySQLClient.Model(&model.UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionId).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoke_reason": reason}).Error
+}
+
+// DeleteExpiredUserSessions 删除用户在指定时间之前过期的会话记录
+func (s *session) DeleteExpiredUserSessions(userId uint, before time.Time) error {
+	return global.MySQLClient.Where("user_id = ? AND expires_at < ?", userId, before).Delete(&model.UserSession{}).Error
+}
@@ -10,11 +10,20 @@ var Settings settings
 
 type settings struct{}
 
+// SensitiveSettings 敏感配置项，获取配置及查看修改记录时不返回其值
+var SensitiveSettings = []string{"ldapBindPassword", "mailPassword", "smsAppSecret", "dingdingAppSecret", "feishuAppSecret", "wechatSecret", "scimToken", "ldapServerBindPassword", "ldapServerPrivateKey", "oidcRegistrationToken", "itsmJiraToken", "itsmWebhookToken"}
+
+// SettingsRevisionList 返回给前端表格的数据结构体
+type SettingsRevisionList struct {
+	Items []*model.SettingsRevision `json:"items"`
+	Total int64                     `json:"total"`
+}
+
 // GetAllSettings 获取所有配置
 func (s *settings) GetAllSettings() ([]model.Settings, error) {
 	var settings []model.Settings
 	// 获取所有配置，排队敏感信息
-	if err := global.MySQLClient.Not("`key` IN ?", []string{"ldapBindPassword", "mailPassword", "smsAppSecret", "dingdingAppSecret", "feishuAppSecret", "wechatSecret"}).Find(&settings).Error; err != nil {
+	if err := global.MySQLClient.Not("`key` IN ?", SensitiveSettings).Find(&settings).Error; err != nil {
 		return nil, err
 	}
 	return settings, nil
@@ -56,6 +65,55 @@ func (s *settings) UpdateSettings(db *gorm.DB, settings map[string]interface{})
 	return updatedSettings, nil
 }
 
+// GetSettingsByKeys 获取指定的配置（包括敏感信息）
+func (s *settings) GetSettingsByKeys(keys []string) ([]model.Settings, error) {
+	var settings []model.Settings
+	if err := global.MySQLClient.Where("`key` IN ?", keys).Find(&settings).Error; err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// GetSettingsRevisionList 获取配置修改记录列表（表格）
+func (s *settings) GetSettingsRevisionList(page, limit int) (data *SettingsRevisionList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		revisions []*model.SettingsRevision
+		total     int64
+	)
+
+	tx := global.MySQLClient.Model(&model.SettingsRevision{}).
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id DESC").
+		Find(&revisions)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &SettingsRevisionList{
+		Items: revisions,
+		Total: total,
+	}, nil
+}
+
+// GetSettingsRevision 获取单个配置修改记录
+func (s *settings) GetSettingsRevision(id uint) (*model.SettingsRevision, error) {
+	var revision model.SettingsRevision
+	if err := global.MySQLClient.First(&revision, id).Error; err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// AddSettingsRevision 新增配置修改记录
+func (s *settings) AddSettingsRevision(tx *gorm.DB, revision *model.SettingsRevision) error {
+	return tx.Create(revision).Error
+}
+
 func getKeys(settings map[string]interface{}) []string {
 	keys := make([]string, 0, len(settings))
 	for key := range settings {
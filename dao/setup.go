@@ -0,0 +1,69 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+)
+
+var Setup setup
+
+type setup struct{}
+
+// IsInitialized 判断系统是否已初始化，存在任意用户（包括已删除的用户）即视为已初始化，
+// 避免管理员重命名或删除后重新开放初始化接口
+func (s *setup) IsInitialized() (bool, error) {
+	var count int64
+	if err := global.MySQLClient.Unscoped().Model(&model.AuthUser{}).Limit(1).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetOrCreateRole 获取角色，不存在时创建
+func (s *setup) GetOrCreateRole(name string) (*model.AuthGroup, error) {
+	role := &model.AuthGroup{}
+	if err := global.MySQLClient.Where(model.AuthGroup{Name: name}).
+		Attrs(model.AuthGroup{IsRoleGroup: true}).
+		FirstOrCreate(role).Error; err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+// GetMenuNames 获取所有一级及二级菜单名称，用于角色的菜单权限
+func (s *setup) GetMenuNames() ([]string, error) {
+	var menus, subMenus []string
+	if err := global.MySQLClient.Model(&model.Menu{}).Pluck("name", &menus).Error; err != nil {
+		return nil, err
+	}
+	if err := global.MySQLClient.Model(&model.SubMenu{}).Pluck("name", &subMenus).Error; err != nil {
+		return nil, err
+	}
+	return append(menus, subMenus...), nil
+}
+
+// GetPaths 获取所有接口，用于角色的接口权限
+func (s *setup) GetPaths() (paths []*model.SystemPath, err error) {
+	if err := global.MySQLClient.Find(&paths).Error; err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// CreateAdmin 创建超级管理员并加入管理员角色，用户名已存在时返回错误
+func (s *setup) CreateAdmin(user *model.AuthUser, role *model.AuthGroup) error {
+	return global.MySQLClient.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		if role == nil {
+			return nil
+		}
+		if err := tx.Model(role).Association("Users").Append(user); err != nil {
+			return err
+		}
+		return tx.Where(model.CasbinRule{Ptype: "g", V0: user.Username, V1: role.Name}).
+			FirstOrCreate(&model.CasbinRule{}).Error
+	})
+}
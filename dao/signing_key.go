@@ -0,0 +1,55 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var SigningKey signingKey
+
+type signingKey struct{}
+
+// GetSigningKeyList 获取签名密钥列表
+func (s *signingKey) GetSigningKeyList() (keys []*model.SigningKey, err error) {
+	if err := global.MySQLClient.Order("id desc").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// GetSigningKey 获取签名密钥
+func (s *signingKey) GetSigningKey(id uint) (*model.SigningKey, error) {
+	var key model.SigningKey
+	if err := global.MySQLClient.First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// AddSigningKey 新增签名密钥
+func (s *signingKey) AddSigningKey(data *model.SigningKey) (*model.SigningKey, error) {
+	if err := global.MySQLClient.Create(data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ActivateSigningKey 启用签名密钥，当前启用（状态为active）的签名密钥同时停用（状态修改为retired）
+func (s *signingKey) ActivateSigningKey(key *model.SigningKey, active, retired string) error {
+	now := time.Now()
+	return global.MySQLClient.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SigningKey{}).
+			Where("status = ?", active).
+			Updates(map[string]interface{}{"status": retired, "retired_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Model(key).Updates(map[string]interface{}{"status": active, "activated_at": now}).Error
+	})
+}
+
+// RetireSigningKey 停用签名密钥
+func (s *signingKey) RetireSigningKey(key *model.SigningKey, status string) error {
+	return global.MySQLClient.Model(key).Updates(map[string]interface{}{"status": status, "retired_at": time.Now()}).Error
+}
@@ -2,6 +2,7 @@ package dao
 
 import (
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
 	"ops-api/global"
 	"ops-api/model"
@@ -40,25 +41,65 @@ type SiteGuideGroup struct {
 
 // SiteItem 站点（表格）
 type SiteItem struct {
-	ID           uint             `json:"id"`
-	Name         string           `json:"name"`
-	Icon         string           `json:"icon"`
-	Address      string           `json:"address"`
-	AllOpen      bool             `json:"all_open"`
-	Description  string           `json:"description"`
-	SSO          bool             `json:"sso"`
-	SSOType      uint             `json:"sso_type"`
-	ClientId     string           `json:"client_id"`
-	ClientSecret string           `json:"client_secret"`
-	CallbackUrl  string           `json:"callback_url"`
-	EntityId     string           `json:"entity_id"`
-	Certificate  string           `json:"certificate"`
-	DomainId     string           `json:"domain_id"`
-	RedirectUrl  string           `json:"redirect_url"`
-	HelperUrl    string           `json:"helper_url"`
-	IDPName      string           `json:"idp_name"`
-	Users        []*UserBasicInfo `json:"users"`
-	Tags         []*string        `json:"tags"`
+	ID               uint             `json:"id"`
+	Name             string           `json:"name"`
+	Icon             string           `json:"icon"`
+	Address          string           `json:"address"`
+	AllOpen          bool             `json:"all_open"`
+	Description      string           `json:"description"`
+	SSO              bool             `json:"sso"`
+	SSOType          uint             `json:"sso_type"`
+	ClientId         string           `json:"client_id"`
+	ClientSecret     string           `json:"client_secret"`
+	CallbackUrl      string           `json:"callback_url"`
+	EntityId         string           `json:"entity_id"`
+	Certificate      string           `json:"certificate"`
+	MetadataUrl      string           `json:"metadata_url"`
+	MetadataSyncAt   *time.Time       `json:"metadata_sync_at"`
+	MetadataError    string           `json:"metadata_error"`
+	AcsUrls          string           `json:"acs_urls"`
+	SloUrl           string           `json:"slo_url"`
+	DomainId         string           `json:"domain_id"`
+	RedirectUrl      string           `json:"redirect_url"`
+	HelperUrl        string           `json:"helper_url"`
+	IDPName          string           `json:"idp_name"`
+	ClaimMapping     string           `json:"claim_mapping"`
+	SAMLAttributes   string           `json:"saml_attributes"`
+	EncryptAssertion bool             `json:"encrypt_assertion"`
+	EncryptionCert   string           `json:"encryption_certificate"`
+	KeyEncryption    string           `json:"key_encryption_algorithm"`
+	DataEncryption   string           `json:"data_encryption_algorithm"`
+	SignatureAlg     string           `json:"signature_algorithm"`
+	DigestAlg        string           `json:"digest_algorithm"`
+	SigningLevel     string           `json:"signing_level"`
+	SubjectType      string           `json:"subject_type"`
+	SectorId         string           `json:"sector_identifier"`
+	NginxTTL         uint             `json:"nginx_ttl"`
+	GrantTypes       string           `json:"grant_types"`
+	RespTypes        string           `json:"response_types"`
+	Scopes           string           `json:"scopes"`
+	Resources        string           `json:"resources"`
+	PKCE             string           `json:"pkce"`
+	RefreshTTL       uint             `json:"refresh_token_ttl"`
+	RefreshMaxTTL    uint             `json:"refresh_token_max_ttl"`
+	CodeTTL          uint             `json:"code_ttl"`
+	AccessTTL        uint             `json:"access_token_ttl"`
+	TicketTTL        uint             `json:"ticket_ttl"`
+	TokenAuthMethod  string           `json:"token_endpoint_auth_method"`
+	JwtPublicKey     string           `json:"jwt_public_key"`
+	ExternalId       *string          `json:"external_id"`
+	CASProfile       string           `json:"cas_profile"`
+	CASLogout        bool             `json:"cas_logout"`
+	PublicDir        bool             `json:"public_directory"`
+	LaunchHook       string           `json:"launch_hook"`
+	LaunchSecret     string           `json:"launch_secret"`
+	Consent          bool             `json:"consent"`
+	Sunset           string           `json:"sunset"`
+	OwnerEmail       string           `json:"owner_email"`
+	NginxRenewal     bool             `json:"nginx_renewal"`
+	NginxGrace       uint             `json:"nginx_grace"`
+	Users            []*UserBasicInfo `json:"users"`
+	Tags             []*string        `json:"tags"`
 }
 
 // SiteGuideItem 站点（站点导航）
@@ -72,20 +113,108 @@ type SiteGuideItem struct {
 	Tags        []*string `json:"tags"`
 }
 
+// PublicDirectoryItem 站点（公开应用目录），仅包含可公开的信息
+type PublicDirectoryItem struct {
+	Name        string `json:"name"`
+	Icon        string `json:"icon"`
+	Description string `json:"description"`
+	LoginUrl    string `json:"login_url"`
+	Group       string `json:"group"`
+}
+
 // UpdateSite 更新站点结构体，定义新增时的字段信息
 type UpdateSite struct {
-	ID          uint    `json:"id"`
-	Name        string  `json:"name"`
-	Address     string  `json:"address"`
-	SSO         *bool   `json:"sso"`      // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
-	AllOpen     *bool   `json:"all_open"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
-	SSOType     uint    `json:"sso_type"`
-	Icon        string  `json:"icon"`
-	EntityId    *string `json:"entity_id"` // 指针类型，可以确保使用Updates方法更新时，如果值为空时也能更新成功
-	CallbackUrl *string `json:"callback_url"`
-	HelperUrl   string  `json:"helper_url"`
-	Certificate *string `json:"certificate"`
-	Description string  `json:"description"`
+	ID               uint    `json:"id"`
+	Name             string  `json:"name"`
+	Address          string  `json:"address"`
+	SSO              *bool   `json:"sso"`      // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+	AllOpen          *bool   `json:"all_open"` // 指针类型，可以确保使用Updates方法更新时，如果值为false时也能更新成功
+	SSOType          uint    `json:"sso_type"`
+	Icon             string  `json:"icon"`
+	EntityId         *string `json:"entity_id"` // 指针类型，可以确保使用Updates方法更新时，如果值为空时也能更新成功
+	CallbackUrl      *string `json:"callback_url"`
+	HelperUrl        string  `json:"helper_url"`
+	Certificate      *string `json:"certificate"`
+	MetadataUrl      *string `json:"metadata_url"`
+	MetadataXML      string  `json:"metadata_xml" gorm:"-"` // SP Metadata XML，仅用于获取未配置的EntityID、证书、ACS地址及单点注销地址，不保存
+	AcsUrls          *string `json:"acs_urls"`
+	SloUrl           *string `json:"slo_url"`
+	ClaimMapping     *string `json:"claim_mapping"`
+	SAMLAttributes   *string `json:"saml_attributes"`
+	EncryptAssertion *bool   `json:"encrypt_assertion"`
+	EncryptionCert   *string `json:"encryption_certificate"`
+	KeyEncryption    *string `json:"key_encryption_algorithm"`
+	DataEncryption   *string `json:"data_encryption_algorithm"`
+	SignatureAlg     *string `json:"signature_algorithm"`
+	DigestAlg        *string `json:"digest_algorithm"`
+	SigningLevel     *string `json:"signing_level"`
+	SubjectType      string  `json:"subject_type" binding:"omitempty,oneof=public pairwise"`
+	SectorId         *string `json:"sector_identifier"`
+	NginxTTL         uint    `json:"nginx_ttl"`
+	GrantTypes       *string `json:"grant_types"`
+	RespTypes        *string `json:"response_types"`
+	Scopes           *string `json:"scopes"`
+	Resources        *string `json:"resources"`
+	PKCE             *string `json:"pkce"`
+	RefreshTTL       *uint   `json:"refresh_token_ttl"`
+	RefreshMaxTTL    *uint   `json:"refresh_token_max_ttl"`
+	CodeTTL          *uint   `json:"code_ttl"`
+	AccessTTL        *uint   `json:"access_token_ttl"`
+	TicketTTL        *uint   `json:"ticket_ttl"`
+	TokenAuthMethod  *string `json:"token_endpoint_auth_method"`
+	JwtPublicKey     *string `json:"jwt_public_key"`
+	NginxRenewal     *bool   `json:"nginx_renewal"`
+	NginxGrace       *uint   `json:"nginx_grace"`
+	CASProfile       *string `json:"cas_profile"`
+	CASLogout        *bool   `json:"cas_logout"`
+	PublicDir        *bool   `json:"public_directory"`
+	LaunchHook       *string `json:"launch_hook"`
+	LaunchSecret     *string `json:"launch_secret"`
+	Consent          *bool   `json:"consent"`
+	Sunset           *string `json:"sunset"`
+	OwnerEmail       *string `json:"owner_email"`
+	Description      string  `json:"description"`
+	SiteGroupID      uint    `json:"site_group_id"`
+}
+
+// GetPublicDirectory 获取公开应用目录中的站点
+func (s *site) GetPublicDirectory() (data []*PublicDirectoryItem, err error) {
+
+	var sites []*model.Site
+	if err := global.MySQLClient.Where("public_dir = ?", true).Order("site_group_id, id").Find(&sites).Error; err != nil {
+		return nil, err
+	}
+
+	// 站点分组名称
+	var groups []*model.SiteGroup
+	if err := global.MySQLClient.Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	groupNames := make(map[uint]string)
+	for _, group := range groups {
+		groupNames[group.ID] = group.Name
+	}
+
+	data = make([]*PublicDirectoryItem, 0, len(sites))
+	for _, s := range sites {
+		item := &PublicDirectoryItem{
+			Name:        s.Name,
+			Description: s.Description,
+			LoginUrl:    s.Address,
+			Group:       groupNames[s.SiteGroupID],
+		}
+
+		// 对站点图标进行特殊处理，返回一个Minio中的临时URL链接
+		if s.Icon != nil {
+			if iconUrl, err := utils.GetObjectURL(*s.Icon, 6*time.Hour); err == nil {
+				item.Icon = iconUrl
+			}
+		}
+
+		data = append(data, item)
+	}
+
+	return data, nil
 }
 
 // GetSiteGuideList 获取站点列表（站点导航）
@@ -136,11 +265,11 @@ func (s *site) GetSiteGuideList(name string) (data *SiteGuideList, err error) {
 
 			// 对站点图标进行特殊处理，返回一个Minio中的临时URL链接
 			if s.Icon != nil {
-				iconUrl, err := utils.GetPresignedURL(*s.Icon, 6*time.Hour)
+				iconUrl, err := utils.GetObjectURL(*s.Icon, 6*time.Hour)
 				if err != nil {
 					siteItem.Icon = ""
 				} else {
-					siteItem.Icon = iconUrl.String()
+					siteItem.Icon = iconUrl
 				}
 			}
 
@@ -175,7 +304,7 @@ func (s *site) GetSiteList(groupName, siteName string, page, limit int) (data *S
 	// 获取分组列表
 	tx := global.MySQLClient.Model(&model.SiteGroup{}).
 		Preload("Sites", "name like ? OR description like ?", "%"+siteName+"%", "%"+siteName+"%"). // 预加载分组包含的站点
-		Preload("Sites.Users").                                                                    // 预加载站点用户
+		Preload("Sites.Users", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).     // 预加载站点用户（仅需要ID和姓名）
 		Preload("Sites.Tags").                                                                     // 预加载站点标签
 		Where("name like ?", "%"+groupName+"%").
 		Count(&total). // 获取总数
@@ -201,31 +330,71 @@ func (s *site) GetSiteList(groupName, siteName string, page, limit int) (data *S
 		}
 		for j, s := range sg.Sites {
 			siteItem := &SiteItem{
-				ID:           s.ID,
-				Name:         s.Name,
-				Address:      s.Address,
-				AllOpen:      s.AllOpen,
-				Description:  s.Description,
-				SSO:          s.SSO,
-				SSOType:      s.SSOType,
-				ClientId:     s.ClientId,
-				ClientSecret: s.ClientSecret,
-				CallbackUrl:  s.CallbackUrl,
-				EntityId:     s.EntityId,
-				Certificate:  s.Certificate,
-				DomainId:     s.DomainId,
-				RedirectUrl:  s.RedirectUrl,
-				IDPName:      s.IDPName,
-				HelperUrl:    s.HelperUrl,
+				ID:               s.ID,
+				Name:             s.Name,
+				Address:          s.Address,
+				AllOpen:          s.AllOpen,
+				Description:      s.Description,
+				SSO:              s.SSO,
+				SSOType:          s.SSOType,
+				ClientId:         s.ClientId,
+				ClientSecret:     s.ClientSecret,
+				CallbackUrl:      s.CallbackUrl,
+				EntityId:         s.EntityId,
+				Certificate:      s.Certificate,
+				MetadataUrl:      s.MetadataUrl,
+				MetadataSyncAt:   s.MetadataSyncAt,
+				MetadataError:    s.MetadataError,
+				AcsUrls:          s.AcsUrls,
+				SloUrl:           s.SloUrl,
+				DomainId:         s.DomainId,
+				RedirectUrl:      s.RedirectUrl,
+				IDPName:          s.IDPName,
+				ClaimMapping:     s.ClaimMapping,
+				SAMLAttributes:   s.SAMLAttributes,
+				EncryptAssertion: s.EncryptAssertion,
+				EncryptionCert:   s.EncryptionCert,
+				KeyEncryption:    s.KeyEncryption,
+				DataEncryption:   s.DataEncryption,
+				SignatureAlg:     s.SignatureAlg,
+				DigestAlg:        s.DigestAlg,
+				SigningLevel:     s.SigningLevel,
+				SubjectType:      s.SubjectType,
+				SectorId:         s.SectorId,
+				NginxTTL:         s.NginxTTL,
+				GrantTypes:       s.GrantTypes,
+				RespTypes:        s.RespTypes,
+				Scopes:           s.Scopes,
+				Resources:        s.Resources,
+				PKCE:             s.PKCE,
+				RefreshTTL:       s.RefreshTTL,
+				RefreshMaxTTL:    s.RefreshMaxTTL,
+				CodeTTL:          s.CodeTTL,
+				AccessTTL:        s.AccessTTL,
+				TicketTTL:        s.TicketTTL,
+				TokenAuthMethod:  s.TokenAuthMethod,
+				JwtPublicKey:     s.JwtPublicKey,
+				ExternalId:       s.ExternalId,
+				CASProfile:       s.CASProfile,
+				CASLogout:        s.CASLogout,
+				PublicDir:        s.PublicDir,
+				LaunchHook:       s.LaunchHook,
+				LaunchSecret:     s.LaunchSecret,
+				Consent:          s.Consent,
+				Sunset:           s.Sunset,
+				OwnerEmail:       s.OwnerEmail,
+				NginxRenewal:     s.NginxRenewal,
+				NginxGrace:       s.NginxGrace,
+				HelperUrl:        s.HelperUrl,
 			}
 
 			// 对站点图标进行特殊处理，返回一个Minio中的临时URL链接
 			if s.Icon != nil {
-				iconUrl, err := utils.GetPresignedURL(*s.Icon, 6*time.Hour)
+				iconUrl, err := utils.GetObjectURL(*s.Icon, 6*time.Hour)
 				if err != nil {
 					siteItem.Icon = ""
 				} else {
-					siteItem.Icon = iconUrl.String()
+					siteItem.Icon = iconUrl
 				}
 			}
 
@@ -271,6 +440,30 @@ func (s *site) AddSite(tx *gorm.DB, data *model.Site) (site *model.Site, err err
 	return data, nil
 }
 
+// GetOrCreateGroup 获取指定名称的站点分组，不存在时创建
+func (s *site) GetOrCreateGroup(tx *gorm.DB, name string) (*model.SiteGroup, error) {
+	group := &model.SiteGroup{}
+	if err := tx.Where(model.SiteGroup{Name: name}).FirstOrCreate(group).Error; err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// UpdateClientCredentials 修改站点的OAuth2.0客户端凭据，ClientId已被其它站点使用时返回错误
+func (s *site) UpdateClientCredentials(site *model.Site, clientId, clientSecret string) error {
+	var count int64
+	if err := global.MySQLClient.Model(&model.Site{}).Where("client_id = ? AND id <> ?", clientId, site.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("client_id %s 已被其它站点使用", clientId)
+	}
+	return global.MySQLClient.Model(site).Updates(map[string]interface{}{
+		"client_id":     clientId,
+		"client_secret": clientSecret,
+	}).Error
+}
+
 // UpdateGroup 修改站点分组
 func (s *site) UpdateGroup(data *model.SiteGroup) (*model.SiteGroup, error) {
 	if err := global.MySQLClient.Model(&model.SiteGroup{}).Where("id = ?", data.ID).Updates(data).Error; err != nil {
@@ -321,6 +514,12 @@ func (s *site) DeleteSite(site *model.Site) (err error) {
 		return err
 	}
 
+	// 删除站点的SP证书
+	if err := tx.Where("site_id = ?", site.ID).Delete(&model.SiteCertificate{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// 删除站点
 	if err := tx.Unscoped().Delete(&site).Error; err != nil {
 		tx.Rollback()
@@ -336,6 +535,73 @@ func (s *site) DeleteSite(site *model.Site) (err error) {
 	return nil
 }
 
+// GetSiteCertificates 获取站点的所有SP证书
+func (s *site) GetSiteCertificates(siteId uint) (certificates []*model.SiteCertificate, err error) {
+	if err := global.MySQLClient.Where("site_id = ?", siteId).Order("not_after desc").Find(&certificates).Error; err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// GetActiveSiteCertificates 获取站点已启用且在有效期内的SP证书，按过期时间倒序，优先使用最新的证书
+func (s *site) GetActiveSiteCertificates(siteId uint, now time.Time) (certificates []*model.SiteCertificate, err error) {
+	if err := global.MySQLClient.
+		Where("site_id = ? AND enabled = ? AND not_before <= ? AND not_after >= ?", siteId, true, now, now).
+		Order("not_after desc").
+		Find(&certificates).Error; err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// GetExpiringSiteCertificates 获取已启用且在指定时间前过期的SP证书
+func (s *site) GetExpiringSiteCertificates(before time.Time) (certificates []*model.SiteCertificate, err error) {
+	if err := global.MySQLClient.
+		Where("enabled = ? AND not_after <= ?", true, before).
+		Order("not_after").
+		Find(&certificates).Error; err != nil {
+		return nil, err
+	}
+	return certificates, nil
+}
+
+// GetSiteCertificate 获取单个SP证书
+func (s *site) GetSiteCertificate(id uint) (*model.SiteCertificate, error) {
+	var certificate model.SiteCertificate
+	if err := global.MySQLClient.First(&certificate, id).Error; err != nil {
+		return nil, err
+	}
+	return &certificate, nil
+}
+
+// AddSiteCertificate 新增SP证书
+func (s *site) AddSiteCertificate(data *model.SiteCertificate) (*model.SiteCertificate, error) {
+	if err := global.MySQLClient.Create(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateSiteCertificate 修改SP证书名称及启用状态
+func (s *site) UpdateSiteCertificate(id uint, name string, enabled bool) error {
+	return global.MySQLClient.Model(&model.SiteCertificate{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{"name": name, "enabled": enabled}).Error
+}
+
+// DeleteSiteCertificate 删除SP证书
+func (s *site) DeleteSiteCertificate(id uint) error {
+	return global.MySQLClient.Delete(&model.SiteCertificate{}, id).Error
+}
+
+// GetSamlSites 获取所有使用SAML2认证的站点
+func (s *site) GetSamlSites() (sites []*model.Site, err error) {
+	if err := global.MySQLClient.Where("sso = true AND sso_type = 3").Find(&sites).Error; err != nil {
+		return nil, err
+	}
+	return sites, nil
+}
+
 // GetNginxSite 获取单个使用Nginx认证的站点
 func (s *site) GetNginxSite(callbackUrl string) (data *model.Site, err error) {
 	var site *model.Site
@@ -380,6 +646,31 @@ func (s *site) GetSamlSite(issuer string) (data *model.Site, err error) {
 	return site, nil
 }
 
+// GetWsFedSite 获取单个使用WS-Fed认证的站点
+func (s *site) GetWsFedSite(realm string) (data *model.Site, err error) {
+	var site *model.Site
+
+	if err := global.MySQLClient.Where("entity_id = ? AND sso = true AND sso_type = 5", realm).First(&site).Error; err != nil {
+		return nil, err
+	}
+
+	return site, nil
+}
+
+// GetConflictSite 获取相同单点登录类型中指定字段（callback_url、entity_id）值相同的其它站点，不存在时返回nil
+func (s *site) GetConflictSite(ssoType uint, column, value string, excludeId uint) (*model.Site, error) {
+	var sites []*model.Site
+
+	if err := global.MySQLClient.Where(column+" = ? AND sso_type = ? AND id <> ?", value, ssoType, excludeId).Limit(1).Find(&sites).Error; err != nil {
+		return nil, err
+	}
+	if len(sites) == 0 {
+		return nil, nil
+	}
+
+	return sites[0], nil
+}
+
 // UpdateSiteUser 更新站点用户
 func (s *site) UpdateSiteUser(site *model.Site, users []model.AuthUser) (*model.Site, error) {
 	if err := global.MySQLClient.Model(&site).Association("Users").Replace(users); err != nil {
@@ -388,6 +679,11 @@ func (s *site) UpdateSiteUser(site *model.Site, users []model.AuthUser) (*model.
 	return site, nil
 }
 
+// AddSiteUser 向站点中添加用户
+func (s *site) AddSiteUser(site *model.Site, user *model.AuthUser) error {
+	return global.MySQLClient.Model(site).Association("Users").Append(user)
+}
+
 // UpdateSiteTag 更新站点标签
 func (s *site) UpdateSiteTag(tx *gorm.DB, site *model.Site, tags []model.Tag) (*model.Site, error) {
 	if err := tx.Model(&site).Association("Tags").Replace(tags); err != nil {
@@ -0,0 +1,86 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var SiteGrant siteGrant
+
+type siteGrant struct{}
+
+// SiteGrantItem 用户访问站点授权的有效期
+type SiteGrantItem struct {
+	SiteID     uint      `json:"site_id"`
+	SiteName   string    `json:"site_name"`
+	OwnerEmail string    `json:"owner_email"`
+	UserID     uint      `json:"user_id"`
+	Username   string    `json:"username"`
+	Name       string    `json:"name"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Operator   string    `json:"operator"`
+}
+
+// GetGrantedUserIds 获取指定用户中已授权访问站点的用户ID
+func (g *siteGrant) GetGrantedUserIds(siteId uint, userIds []uint) (ids []uint, err error) {
+	if err := global.MySQLClient.Table("site_users").
+		Where("site_id = ? AND auth_user_id IN ?", siteId, userIds).
+		Pluck("auth_user_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// SaveSiteGrants 设置用户访问站点授权的有效期，已存在时更新，expiresAt 为nil时删除有效期（永久有效）
+func (g *siteGrant) SaveSiteGrants(siteId uint, userIds []uint, expiresAt *time.Time, operator string) error {
+
+	if expiresAt == nil {
+		return global.MySQLClient.Where("site_id = ? AND user_id IN ?", siteId, userIds).Delete(&model.SiteGrant{}).Error
+	}
+
+	grants := make([]*model.SiteGrant, 0, len(userIds))
+	for _, userId := range userIds {
+		grants = append(grants, &model.SiteGrant{SiteID: siteId, UserID: userId, ExpiresAt: *expiresAt, Operator: operator})
+	}
+	return global.MySQLClient.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "site_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at", "operator", "updated_at"}),
+	}).Create(&grants).Error
+}
+
+// GetExpiringSiteGrants 获取在 deadline 之前到期的授权（包含已到期的授权），用户已不在站点中的授权不返回
+func (g *siteGrant) GetExpiringSiteGrants(deadline time.Time) (items []*SiteGrantItem, err error) {
+	if err := global.MySQLClient.Table("site_grant AS t").
+		Select("t.site_id, site.name AS site_name, site.owner_email, t.user_id, auth_user.username, auth_user.name, t.expires_at, t.operator").
+		Joins("JOIN site_users ON site_users.site_id = t.site_id AND site_users.auth_user_id = t.user_id").
+		Joins("JOIN site ON site.id = t.site_id").
+		Joins("JOIN auth_user ON auth_user.id = t.user_id AND auth_user.deleted_at IS NULL").
+		Where("t.expires_at <= ?", deadline).
+		Order("t.expires_at, t.site_id").
+		Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// RevokeExpiredSiteGrant 撤销已到期的用户访问站点授权并删除有效期记录，有效期已被延长时不撤销并返回false
+func (g *siteGrant) RevokeExpiredSiteGrant(siteId, userId uint, now time.Time) (revoked bool, err error) {
+	err = global.MySQLClient.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("site_id = ? AND user_id = ? AND expires_at <= ?", siteId, userId, now).Delete(&model.SiteGrant{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		revoked = true
+		return tx.Exec("DELETE FROM site_users WHERE site_id = ? AND auth_user_id = ?", siteId, userId).Error
+	})
+	return revoked, err
+}
+
+// DeleteOrphanSiteGrants 删除用户已不在站点中（已被移出站点或站点已删除）的有效期记录
+func (g *siteGrant) DeleteOrphanSiteGrants() error {
+	return global.MySQLClient.Exec("DELETE FROM site_grant WHERE NOT EXISTS " +
+		"(SELECT 1 FROM site_users WHERE site_users.site_id = site_grant.site_id AND site_users.auth_user_id = site_grant.user_id)").Error
+}
@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"gorm.io/gorm"
 	"ops-api/global"
 	"ops-api/model"
 	"time"
@@ -36,9 +37,10 @@ func (l *sso) GetAuthorizeCode(code string) (data *model.SsoOAuthTicket, err err
 	}
 
 	// 票据使用过后，进行使用标记（确保票据只能使用一次）
-	if err := global.MySQLClient.Model(&ticket).Update("consumed_at", now).Error; err != nil {
+	if err := consumeTicket(&model.SsoOAuthTicket{}, ticket.ID, now); err != nil {
 		return nil, err
 	}
+	ticket.ConsumedAt = &now
 
 	return ticket, nil
 }
@@ -56,6 +58,27 @@ func (l *sso) GetAuthorizeToken(token string) (data *model.SsoNginxTicket, err e
 	return ticket, nil
 }
 
+// RotateAuthorizeToken 续期授权码（Nginx），旧授权码仅在宽限时间内有效，并发续期时仅有一个请求能续期成功
+func (l *sso) RotateAuthorizeToken(old *model.SsoNginxTicket, graceExpiresAt time.Time, data *model.SsoNginxTicket) (rotated bool, err error) {
+	err = global.MySQLClient.Transaction(func(tx *gorm.DB) error {
+		if graceExpiresAt.After(old.ExpiresAt) {
+			graceExpiresAt = old.ExpiresAt
+		}
+		result := tx.Model(&model.SsoNginxTicket{}).
+			Where("id = ? AND rotated_at IS NULL", old.ID).
+			Updates(map[string]interface{}{"rotated_at": time.Now(), "expires_at": graceExpiresAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		rotated = true
+		return tx.Create(data).Error
+	})
+	return rotated, err
+}
+
 // GetAuthorizeTicket 仅获取有效票据（CAS3.0）
 func (l *sso) GetAuthorizeTicket(st string) (data *model.SsoCASTicket, err error) {
 	var ticket *model.SsoCASTicket
@@ -67,9 +90,263 @@ func (l *sso) GetAuthorizeTicket(st string) (data *model.SsoCASTicket, err error
 	}
 
 	// 票据使用过后，进行使用标记（确保票据只能使用一次）
-	if err := global.MySQLClient.Model(&ticket).Update("consumed_at", now).Error; err != nil {
+	if err := consumeTicket(&model.SsoCASTicket{}, ticket.ID, now); err != nil {
 		return nil, err
 	}
+	ticket.ConsumedAt = &now
 
 	return ticket, nil
 }
+
+// consumeTicket 标记票据已使用，仅更新未使用的票据，并发校验同一票据时仅有一个请求能标记成功，其它请求返回 gorm.ErrRecordNotFound
+func consumeTicket(table interface{}, id uint, consumedAt time.Time) error {
+	result := global.MySQLClient.Model(table).
+		Where("id = ? AND consumed_at IS NULL", id).
+		Update("consumed_at", consumedAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ClaimCASLogoutTickets 获取会话中已校验且未发送单点注销请求的票据（CAS3.0），仅包含开启单点注销的站点，
+// 获取后标记为已发送，多个实例同时注销同一会话时每个票据仅有一个实例能获取到
+func (l *sso) ClaimCASLogoutTickets(sessionId string) ([]*model.SsoCASTicket, error) {
+	var tickets []*model.SsoCASTicket
+	if err := global.MySQLClient.
+		Joins("JOIN site ON site.id = sso_cas_ticket.site_id AND site.cas_logout = ?", true).
+		Where("sso_cas_ticket.session_id = ? AND sso_cas_ticket.consumed_at IS NOT NULL AND sso_cas_ticket.logout_at IS NULL", sessionId).
+		Find(&tickets).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var claimed []*model.SsoCASTicket
+	for _, ticket := range tickets {
+		result := global.MySQLClient.Model(&model.SsoCASTicket{}).
+			Where("id = ? AND logout_at IS NULL", ticket.ID).
+			Update("logout_at", now)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected > 0 {
+			ticket.LogoutAt = &now
+			claimed = append(claimed, ticket)
+		}
+	}
+	return claimed, nil
+}
+
+// CreateSAMLSession 记录SAML2断言对应的会话
+func (l *sso) CreateSAMLSession(data *model.SsoSAMLSession) (err error) {
+	return global.MySQLClient.Create(data).Error
+}
+
+// ClaimSAMLSession 获取SP发起单点注销的会话（SAML2），获取后标记为已注销，同一会话仅能注销一次
+func (l *sso) ClaimSAMLSession(siteId uint, sessionIndex, nameId string) (*model.SsoSAMLSession, error) {
+	var session *model.SsoSAMLSession
+	if err := global.MySQLClient.
+		Where("site_id = ? AND session_index = ? AND name_id = ? AND logout_at IS NULL", siteId, sessionIndex, nameId).
+		First(&session).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := global.MySQLClient.Model(&model.SsoSAMLSession{}).
+		Where("id = ? AND logout_at IS NULL", session.ID).
+		Update("logout_at", now)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	session.LogoutAt = &now
+	return session, nil
+}
+
+// ClaimSAMLLogoutSessions 获取会话中未注销的SAML2会话，仅包含配置了单点注销地址的站点，
+// 获取后标记为已注销，多个实例同时注销同一会话时每个SAML2会话仅有一个实例能获取到
+func (l *sso) ClaimSAMLLogoutSessions(sessionId string) ([]*model.SsoSAMLSession, error) {
+	var sessions []*model.SsoSAMLSession
+	if err := global.MySQLClient.
+		Joins("JOIN site ON site.id = sso_saml_session.site_id AND site.slo_url IS NOT NULL AND site.slo_url <> ''").
+		Where("sso_saml_session.session_id = ? AND sso_saml_session.logout_at IS NULL", sessionId).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var claimed []*model.SsoSAMLSession
+	for _, session := range sessions {
+		result := global.MySQLClient.Model(&model.SsoSAMLSession{}).
+			Where("id = ? AND logout_at IS NULL", session.ID).
+			Update("logout_at", now)
+		if result.Error != nil {
+			return claimed, result.Error
+		}
+		if result.RowsAffected > 0 {
+			session.LogoutAt = &now
+			claimed = append(claimed, session)
+		}
+	}
+	return claimed, nil
+}
+
+// RevokeSessionTickets 使会话签发的所有未使用票据失效
+func (l *sso) RevokeSessionTickets(sessionId string) error {
+	now := time.Now()
+	return global.MySQLClient.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.SsoOAuthTicket{}).
+			Where("session_id = ? AND consumed_at IS NULL", sessionId).
+			Update("consumed_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.SsoCASTicket{}).
+			Where("session_id = ? AND consumed_at IS NULL", sessionId).
+			Update("consumed_at", now).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.SsoOAuthDeviceCode{}).
+			Where("session_id = ? AND status = ? AND expires_at > ?", sessionId, "approved", now).
+			Update("expires_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.SsoNginxTicket{}).
+			Where("session_id = ? AND expires_at > ?", sessionId, now).
+			Update("expires_at", now).Error
+	})
+}
+
+// CreateRefreshToken 创建刷新令牌（OAuth2.0）
+func (l *sso) CreateRefreshToken(data *model.SsoOAuthRefreshToken) (err error) {
+	return global.MySQLClient.Create(&data).Error
+}
+
+// GetRefreshToken 根据摘要获取刷新令牌（包括已轮换及已注销的令牌）
+func (l *sso) GetRefreshToken(tokenHash string) (data *model.SsoOAuthRefreshToken, err error) {
+	var token *model.SsoOAuthRefreshToken
+	if err := global.MySQLClient.Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RotateRefreshToken 轮换刷新令牌，并发轮换时仅有一个请求能轮换成功
+func (l *sso) RotateRefreshToken(old *model.SsoOAuthRefreshToken, data *model.SsoOAuthRefreshToken) (rotated bool, err error) {
+	err = global.MySQLClient.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.SsoOAuthRefreshToken{}).
+			Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", old.ID).
+			Update("rotated_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		rotated = true
+		return tx.Create(data).Error
+	})
+	return rotated, err
+}
+
+// RevokeRefreshTokens 注销同一次授权签发的所有刷新令牌
+func (l *sso) RevokeRefreshTokens(sessionId string) error {
+	return global.MySQLClient.Model(&model.SsoOAuthRefreshToken{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionId).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeClientRefreshTokens 注销会话中签发给指定客户端的所有刷新令牌
+func (l *sso) RevokeClientRefreshTokens(sessionId, clientId string) error {
+	return global.MySQLClient.Model(&model.SsoOAuthRefreshToken{}).
+		Where("session_id = ? AND client_id = ? AND revoked_at IS NULL", sessionId, clientId).
+		Update("revoked_at", time.Now()).Error
+}
+
+// GetDeviceRefreshSessions 获取设备上未注销的刷新令牌对应的会话ID
+func (l *sso) GetDeviceRefreshSessions(deviceId uint) (sessionIds []string, err error) {
+	if err := global.MySQLClient.Model(&model.SsoOAuthRefreshToken{}).
+		Where("device_id = ? AND revoked_at IS NULL AND expires_at > ?", deviceId, time.Now()).
+		Distinct().
+		Pluck("session_id", &sessionIds).Error; err != nil {
+		return nil, err
+	}
+	return sessionIds, nil
+}
+
+// GetUserRefreshSessions 获取用户未注销的刷新令牌对应的会话ID
+func (l *sso) GetUserRefreshSessions(userId uint) (sessionIds []string, err error) {
+	if err := global.MySQLClient.Model(&model.SsoOAuthRefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now()).
+		Distinct().
+		Pluck("session_id", &sessionIds).Error; err != nil {
+		return nil, err
+	}
+	return sessionIds, nil
+}
+
+// RevokeDeviceRefreshTokens 注销设备上的所有刷新令牌
+func (l *sso) RevokeDeviceRefreshTokens(deviceId uint) error {
+	return global.MySQLClient.Model(&model.SsoOAuthRefreshToken{}).
+		Where("device_id = ? AND revoked_at IS NULL", deviceId).
+		Update("revoked_at", time.Now()).Error
+}
+
+// CreateDeviceCode 创建设备授权票据（OAuth2.0）
+func (l *sso) CreateDeviceCode(data *model.SsoOAuthDeviceCode) (err error) {
+	return global.MySQLClient.Create(&data).Error
+}
+
+// GetDeviceCode 根据device_code摘要获取设备授权票据（包括已过期及已使用的票据）
+func (l *sso) GetDeviceCode(deviceCodeHash string) (data *model.SsoOAuthDeviceCode, err error) {
+	var ticket *model.SsoOAuthDeviceCode
+	if err := global.MySQLClient.Where("device_code_hash = ?", deviceCodeHash).First(&ticket).Error; err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// GetPendingDeviceCode 根据user_code获取等待用户确认且在有效期内的设备授权票据
+func (l *sso) GetPendingDeviceCode(userCode string) (data *model.SsoOAuthDeviceCode, err error) {
+	var ticket *model.SsoOAuthDeviceCode
+	if err := global.MySQLClient.
+		Where("user_code = ? AND status = ? AND expires_at > ?", userCode, "pending", time.Now()).
+		First(&ticket).Error; err != nil {
+		return nil, err
+	}
+	return ticket, nil
+}
+
+// ConfirmDeviceCode 用户确认或拒绝设备授权，票据已确认或已过期时返回false
+func (l *sso) ConfirmDeviceCode(id uint, status string, userId uint, sessionId string) (confirmed bool, err error) {
+	result := global.MySQLClient.Model(&model.SsoOAuthDeviceCode{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, "pending", time.Now()).
+		Updates(map[string]interface{}{
+			"status":     status,
+			"user_id":    userId,
+			"session_id": sessionId,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+// PollDeviceCode 记录设备的轮询时间及轮询间隔
+func (l *sso) PollDeviceCode(id uint, polledAt time.Time, interval int) error {
+	return global.MySQLClient.Model(&model.SsoOAuthDeviceCode{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_polled_at": polledAt,
+			"interval":       interval,
+		}).Error
+}
+
+// ConsumeDeviceCode 使用已授权的设备授权票据，并发轮询时仅有一个请求能使用成功
+func (l *sso) ConsumeDeviceCode(id uint) (consumed bool, err error) {
+	result := global.MySQLClient.Model(&model.SsoOAuthDeviceCode{}).
+		Where("id = ? AND status = ? AND expires_at > ?", id, "approved", time.Now()).
+		Update("status", "consumed")
+	return result.RowsAffected > 0, result.Error
+}
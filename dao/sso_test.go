@@ -0,0 +1,134 @@
+package dao
+
+import (
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"ops-api/global"
+	"ops-api/model"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// consumeConcurrency 并发使用同一票据的请求数量
+const consumeConcurrency = 32
+
+// queryBarrier 并发使用票据时，所有请求查询到票据后才继续执行，确保每个请求查询时票据均未使用
+var queryBarrier atomic.Pointer[sync.WaitGroup]
+
+// setupTicketDB 使用临时SQLite数据库替换全局数据库客户端
+func setupTicketDB(t *testing.T) {
+	t.Helper()
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "sso.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&model.SsoOAuthTicket{}, &model.SsoCASTicket{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Query().After("gorm:query").Register("test:barrier", func(*gorm.DB) {
+		if barrier := queryBarrier.Load(); barrier != nil {
+			barrier.Done()
+			barrier.Wait()
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(consumeConcurrency)
+
+	old := global.MySQLClient
+	global.MySQLClient = db
+	t.Cleanup(func() {
+		global.MySQLClient = old
+		_ = sqlDB.Close()
+	})
+}
+
+// redeemConcurrently 并发执行redeem，返回成功的次数
+func redeemConcurrently(redeem func() error) int64 {
+
+	var (
+		wg      sync.WaitGroup
+		barrier sync.WaitGroup
+		start   = make(chan struct{})
+		success int64
+	)
+	barrier.Add(consumeConcurrency)
+	queryBarrier.Store(&barrier)
+	defer queryBarrier.Store(nil)
+
+	for i := 0; i < consumeConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if redeem() == nil {
+				atomic.AddInt64(&success, 1)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	return success
+}
+
+func TestGetAuthorizeCodeConcurrent(t *testing.T) {
+	setupTicketDB(t)
+
+	nonce := ""
+	if err := SSO.CreateAuthorizeCode(&model.SsoOAuthTicket{
+		Code:      "code",
+		UserID:    1,
+		Nonce:     &nonce,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	success := redeemConcurrently(func() error {
+		_, err := SSO.GetAuthorizeCode("code")
+		return err
+	})
+	if success != 1 {
+		t.Fatalf("授权码被使用%d次，期望1次", success)
+	}
+
+	if _, err := SSO.GetAuthorizeCode("code"); err == nil {
+		t.Fatal("已使用的授权码仍然可以使用")
+	}
+}
+
+func TestGetAuthorizeTicketConcurrent(t *testing.T) {
+	setupTicketDB(t)
+
+	if err := SSO.CreateAuthorizeTicket(&model.SsoCASTicket{
+		Ticket:    "ST-ticket",
+		Service:   "https://app.example.com/cas",
+		UserID:    1,
+		ExpiresAt: time.Now().Add(time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	success := redeemConcurrently(func() error {
+		_, err := SSO.GetAuthorizeTicket("ST-ticket")
+		return err
+	})
+	if success != 1 {
+		t.Fatalf("票据被使用%d次，期望1次", success)
+	}
+
+	if _, err := SSO.GetAuthorizeTicket("ST-ticket"); err == nil {
+		t.Fatal("已使用的票据仍然可以使用")
+	}
+}
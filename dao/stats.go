@@ -0,0 +1,242 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var Stats stats
+
+type stats struct{}
+
+// LoginStatCount 登录次数统计
+type LoginStatCount struct {
+	Date        string `json:"date"`
+	AuthMethod  string `json:"auth_method"`
+	Application string `json:"application"`
+	Status      int    `json:"status"`
+	Reason      string `json:"reason"`
+	Count       int64  `json:"count"`
+}
+
+// UserLoginStatCount 用户登录次数统计
+type UserLoginStatCount struct {
+	Username    string     `json:"username"`
+	Success     int64      `json:"success"`
+	Failed      int64      `json:"failed"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// OplogStatCount 操作次数统计
+type OplogStatCount struct {
+	Date     string `json:"date,omitempty"`
+	Username string `json:"username,omitempty"`
+	Method   string `json:"method,omitempty"`
+	Count    int64  `json:"count"`
+}
+
+// RollupLogin 汇总指定日期的登录日志，重复执行时覆盖已有数据
+func (s *stats) RollupLogin(tx *gorm.DB, date time.Time) error {
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	day := start.Format("2006-01-02")
+
+	var rows []*model.StatLoginDaily
+	if err := tx.Model(&model.LogLogin{}).
+		Select("? AS date, auth_method, application, status, failed_reason, COUNT(*) AS count", day).
+		Where("created_at >= ? AND created_at < ?", start, start.AddDate(0, 0, 1)).
+		Group("auth_method, application, status, failed_reason").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("date = ?", day).Delete(&model.StatLoginDaily{}).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return tx.CreateInBatches(rows, 200).Error
+}
+
+// RollupUserLogin 按用户汇总指定日期的登录日志，重复执行时覆盖已有数据
+func (s *stats) RollupUserLogin(tx *gorm.DB, date time.Time) error {
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	day := start.Format("2006-01-02")
+
+	var rows []*model.StatUserLoginDaily
+	if err := tx.Model(&model.LogLogin{}).
+		Select("? AS date, username, SUM(CASE WHEN status = 1 THEN 1 ELSE 0 END) AS success, SUM(CASE WHEN status = 1 THEN 0 ELSE 1 END) AS failed, MAX(created_at) AS last_login_at", day).
+		Where("created_at >= ? AND created_at < ?", start, start.AddDate(0, 0, 1)).
+		Group("username").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("date = ?", day).Delete(&model.StatUserLoginDaily{}).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return tx.CreateInBatches(rows, 200).Error
+}
+
+// RollupOplog 按操作人及请求方法汇总指定日期的操作日志，重复执行时覆盖已有数据
+func (s *stats) RollupOplog(tx *gorm.DB, date time.Time) error {
+
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	day := start.Format("2006-01-02")
+
+	var rows []*model.StatOplogDaily
+	if err := tx.Model(&model.LogOplog{}).
+		Select("? AS date, username, method, COUNT(*) AS count", day).
+		Where("created_at >= ? AND created_at < ?", start, start.AddDate(0, 0, 1)).
+		Group("username, method").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("date = ?", day).Delete(&model.StatOplogDaily{}).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return tx.CreateInBatches(rows, 200).Error
+}
+
+// SnapshotUsers 保存用户数量快照
+func (s *stats) SnapshotUsers(tx *gorm.DB, date time.Time) error {
+
+	snapshot := &model.StatUserDaily{Date: date.Format("2006-01-02")}
+	if err := tx.Model(&model.AuthUser{}).Count(&snapshot.TotalUsers).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.AuthUser{}).Where("is_active = ?", true).Count(&snapshot.ActiveUsers).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.AuthUser{}).Where("is_active = ? AND mfa_code IS NOT NULL AND mfa_code != ''", true).Count(&snapshot.MFAUsers).Error; err != nil {
+		return err
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"total_users", "active_users", "mfa_users"}),
+	}).Create(snapshot).Error
+}
+
+// GetLoginCountByMethod 按日期及认证方式统计登录次数
+func (s *stats) GetLoginCountByMethod(start, end string) (data []*LoginStatCount, err error) {
+	if err := global.MySQLClient.Model(&model.StatLoginDaily{}).
+		Select("date, auth_method, status, SUM(count) AS count").
+		Where("date >= ? AND date <= ?", start, end).
+		Group("date, auth_method, status").
+		Order("date").
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetAppLaunchCount 统计各应用登录成功次数
+func (s *stats) GetAppLaunchCount(start, end string, limit int) (data []*LoginStatCount, err error) {
+	if err := global.MySQLClient.Model(&model.StatLoginDaily{}).
+		Select("application, SUM(count) AS count").
+		Where("date >= ? AND date <= ? AND status = ? AND application != ''", start, end, 1).
+		Group("application").
+		Order("count DESC").
+		Limit(limit).
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetLoginCountByStatus 按状态统计登录次数
+func (s *stats) GetLoginCountByStatus(start, end string) (data []*LoginStatCount, err error) {
+	if err := global.MySQLClient.Model(&model.StatLoginDaily{}).
+		Select("status, SUM(count) AS count").
+		Where("date >= ? AND date <= ?", start, end).
+		Group("status").
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetFailureReasonCount 统计登录失败原因
+func (s *stats) GetFailureReasonCount(start, end string, limit int) (data []*LoginStatCount, err error) {
+	if err := global.MySQLClient.Model(&model.StatLoginDaily{}).
+		Select("failed_reason AS reason, SUM(count) AS count").
+		Where("date >= ? AND date <= ? AND status = ?", start, end, 2).
+		Group("failed_reason").
+		Order("count DESC").
+		Limit(limit).
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetUserSnapshots 获取用户数量快照
+func (s *stats) GetUserSnapshots(start, end string) (data []*model.StatUserDaily, err error) {
+	if err := global.MySQLClient.
+		Where("date >= ? AND date <= ?", start, end).
+		Order("date").
+		Find(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetUserLoginCount 按用户统计登录次数，orderBy 为排序字段（success、failed）
+func (s *stats) GetUserLoginCount(start, end, orderBy string, limit int) (data []*UserLoginStatCount, err error) {
+	tx := global.MySQLClient.Model(&model.StatUserLoginDaily{}).
+		Select("username, SUM(success) AS success, SUM(failed) AS failed, MAX(last_login_at) AS last_login_at").
+		Where("date >= ? AND date <= ?", start, end).
+		Group("username")
+	if orderBy != "" {
+		tx = tx.Order(orderBy + " DESC").Limit(limit)
+	} else {
+		tx = tx.Order("username")
+	}
+	if err := tx.Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetOplogCountByDate 按日期及请求方法统计操作次数
+func (s *stats) GetOplogCountByDate(start, end string) (data []*OplogStatCount, err error) {
+	if err := global.MySQLClient.Model(&model.StatOplogDaily{}).
+		Select("date, method, SUM(count) AS count").
+		Where("date >= ? AND date <= ?", start, end).
+		Group("date, method").
+		Order("date").
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetOplogCountByUser 统计各操作人的操作次数
+func (s *stats) GetOplogCountByUser(start, end string, limit int) (data []*OplogStatCount, err error) {
+	if err := global.MySQLClient.Model(&model.StatOplogDaily{}).
+		Select("username, SUM(count) AS count").
+		Where("date >= ? AND date <= ?", start, end).
+		Group("username").
+		Order("count DESC").
+		Limit(limit).
+		Scan(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
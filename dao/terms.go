@@ -0,0 +1,175 @@
+package dao
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ops-api/global"
+	"ops-api/model"
+	"time"
+)
+
+var Terms terms
+
+type terms struct{}
+
+// TermsList 返回给前端表格的数据结构体
+type TermsList struct {
+	Items []*model.Terms `json:"items"`
+	Total int64          `json:"total"`
+}
+
+// TermsAcceptanceList 返回给前端表格的数据结构体
+type TermsAcceptanceList struct {
+	Items []*model.TermsAcceptance `json:"items"`
+	Total int64                    `json:"total"`
+}
+
+// GetTermsList 获取使用条款列表（表格）
+func (t *terms) GetTermsList(name string, page, limit int) (data *TermsList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		items []*model.Terms
+		total int64
+	)
+
+	tx := global.MySQLClient.Model(&model.Terms{}).
+		Preload("Sites", func(db *gorm.DB) *gorm.DB { return db.Select("id", "name") }).
+		Where("name like ?", "%"+name+"%").
+		Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id").
+		Find(&items)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &TermsList{
+		Items: items,
+		Total: total,
+	}, nil
+}
+
+// GetTerms 获取单个使用条款
+func (t *terms) GetTerms(id uint) (*model.Terms, error) {
+	var item model.Terms
+	if err := global.MySQLClient.First(&item, id).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// GetSiteTerms 获取访问应用需要接受的使用条款
+func (t *terms) GetSiteTerms(siteId uint) (items []*model.Terms, err error) {
+	if err := global.MySQLClient.
+		Where("enabled = ?", true).
+		Where("all_sites = ? OR id IN (?)", true, global.MySQLClient.Table("terms_sites").Select("terms_id").Where("site_id = ?", siteId)).
+		Order("id").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// AddTerms 新增使用条款
+func (t *terms) AddTerms(tx *gorm.DB, data *model.Terms) (*model.Terms, error) {
+	if err := tx.Create(data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// UpdateTerms 修改使用条款
+func (t *terms) UpdateTerms(tx *gorm.DB, item *model.Terms) error {
+	return tx.Model(item).Select("name", "version", "content", "enabled", "all_sites").Updates(item).Error
+}
+
+// UpdateTermsSites 更新使用条款关联的应用
+func (t *terms) UpdateTermsSites(tx *gorm.DB, item *model.Terms, sites []model.Site) error {
+	if len(sites) == 0 {
+		return tx.Model(item).Association("Sites").Clear()
+	}
+	return tx.Model(item).Association("Sites").Replace(sites)
+}
+
+// DeleteTerms 删除使用条款，历史版本及接受记录保留用于合规审计
+func (t *terms) DeleteTerms(tx *gorm.DB, item *model.Terms) error {
+	if err := tx.Model(item).Association("Sites").Clear(); err != nil {
+		return err
+	}
+	return tx.Unscoped().Delete(item).Error
+}
+
+// CreateTermsVersion 保存使用条款版本
+func (t *terms) CreateTermsVersion(tx *gorm.DB, item *model.Terms) error {
+	return tx.Create(&model.TermsVersion{
+		TermsID: item.ID,
+		Version: item.Version,
+		Content: item.Content,
+	}).Error
+}
+
+// GetTermsVersions 获取使用条款的历史版本
+func (t *terms) GetTermsVersions(termsId uint) (data []*model.TermsVersion, err error) {
+	if err := global.MySQLClient.Where("terms_id = ?", termsId).Order("version desc").Find(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// IsAccepted 判断用户是否已接受使用条款的指定版本
+func (t *terms) IsAccepted(termsId, version, userId uint) (bool, error) {
+	var count int64
+	if err := global.MySQLClient.Model(&model.TermsAcceptance{}).
+		Where("terms_id = ? AND version = ? AND user_id = ?", termsId, version, userId).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CreateAcceptances 保存使用条款接受记录，已接受的版本不重复记录
+func (t *terms) CreateAcceptances(data []*model.TermsAcceptance) error {
+	return global.MySQLClient.Clauses(clause.OnConflict{DoNothing: true}).Create(&data).Error
+}
+
+// GetAcceptanceList 获取使用条款接受记录（表格）
+func (t *terms) GetAcceptanceList(termsId uint, username string, page, limit int) (data *TermsAcceptanceList, err error) {
+	// 定义数据的起始位置
+	startSet := (page - 1) * limit
+
+	var (
+		items []*model.TermsAcceptance
+		total int64
+	)
+
+	tx := global.MySQLClient.Model(&model.TermsAcceptance{}).Where("username like ?", "%"+username+"%")
+	if termsId != 0 {
+		tx = tx.Where("terms_id = ?", termsId)
+	}
+	if err := tx.Count(&total).
+		Limit(limit).
+		Offset(startSet).
+		Order("id desc").
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return &TermsAcceptanceList{
+		Items: items,
+		Total: total,
+	}, nil
+}
+
+// GetAcceptanceRange 获取指定时间范围内的使用条款接受记录，用于合规报告
+func (t *terms) GetAcceptanceRange(start, end time.Time) (data []*model.TermsAcceptance, err error) {
+	if err := global.MySQLClient.
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Order("id").
+		Find(&data).Error; err != nil {
+		return nil, err
+	}
+	return data, nil
+}
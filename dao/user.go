@@ -1,11 +1,14 @@
 package dao
 
 import (
+	"encoding/json"
+	"fmt"
 	"gorm.io/gorm"
 	"ops-api/config"
 	"ops-api/global"
 	"ops-api/model"
 	"ops-api/utils"
+	"strconv"
 	"time"
 )
 
@@ -13,6 +16,11 @@ var User user
 
 type user struct{}
 
+const (
+	userInfoCacheVersionKey = "user_info_version"
+	userInfoCacheTTL        = 5 * time.Minute // 最后登录时间等非关键字段允许在缓存有效期内存在延迟
+)
+
 // UserList 返回给前端表格的数据结构体
 type UserList struct {
 	Items []*UserInfo `json:"items"`
@@ -35,12 +43,19 @@ type UserInfo struct {
 	WwId              string     `json:"ww_id"`
 	CtyunId           string     `json:"ctyun_id"`
 	PhoneNumber       string     `json:"phone_number"`
+	PhoneNational     string     `json:"phone_national"`
+	PhoneDisplay      string     `json:"phone_display"`
 	IsActive          bool       `json:"is_active"`
 	Email             string     `json:"email"`
 	Avatar            string     `json:"avatar"`
 	LastLoginAt       *time.Time `json:"last_login_at"`
 	PasswordExpiredAt *time.Time `json:"password_expired_at"`
 	UserFrom          string     `json:"user_from"`
+	Language          string     `json:"language"`
+	Timezone          string     `json:"timezone"`
+	Department        string     `json:"department"`
+	Title             string     `json:"title"`
+	BreakGlass        bool       `json:"break_glass"`
 }
 
 // UserListAll 返回给前端下拉框或穿梭框的数据结构体
@@ -59,6 +74,8 @@ type PasswordExpiredUserList struct {
 	Name              string    `json:"name"`
 	Username          string    `json:"username"`
 	Email             string    `json:"email"`
+	Language          string    `json:"language"`
+	Timezone          string    `json:"timezone"`
 	PasswordExpiredAt time.Time `json:"password_expired_at"`
 }
 
@@ -70,6 +87,12 @@ type UserUpdate struct {
 	PhoneNumber string  `json:"phone_number" validate:"omitempty,phone"`
 	Email       string  `json:"email" validate:"omitempty,email"`
 	IsActive    bool    `json:"is_active" validate:"omitempty"`
+	Department  string  `json:"department"`
+	Title       string  `json:"title"`
+
+	// 由手机号标准化生成，不接收前端传入
+	PhoneNational string `json:"-"`
+	PhoneDisplay  string `json:"-"`
 }
 
 // UserPasswordUpdate 更改密码结构体
@@ -87,6 +110,8 @@ type UserCreate struct {
 	PhoneNumber string `json:"phone_number" binding:"required" validate:"phone"`
 	Email       string `json:"email" binding:"required" validate:"email"`
 	UserFrom    string `json:"user_from"`
+	Department  string `json:"department"`
+	Title       string `json:"title"`
 }
 
 // GetUserListAll 获取所有用户
@@ -146,17 +171,63 @@ func (u *user) GetUser(conditions interface{}) (*model.AuthUser, error) {
 	return &user, nil
 }
 
-// GetUserInfo 获取用户信息
+// GetUserInfo 获取用户信息（优先从缓存中获取）
 func (u *user) GetUserInfo(userid uint) (userinfo *UserInfoWithMenu, err error) {
 
+	key := u.userInfoCacheKey(userid)
+
+	// 读取缓存，缓存不可用时直接查询数据库
+	if data, err := global.Cache.Get(key); err == nil {
+		if err := json.Unmarshal([]byte(data), &userinfo); err == nil {
+			return userinfo, nil
+		}
+	}
+
+	userinfo, err = u.getUserInfo(userid)
+	if err != nil {
+		return nil, err
+	}
+
+	// 写入缓存
+	if data, err := json.Marshal(userinfo); err == nil {
+		global.Cache.Set(key, string(data), userInfoCacheTTL)
+	}
+
+	return userinfo, nil
+}
+
+// ClearUserInfoCache 清除指定用户的信息缓存，用户修改、删除、禁用后调用
+func (u *user) ClearUserInfoCache(userIds ...uint) {
+	for _, userId := range userIds {
+		global.Cache.Del(u.userInfoCacheKey(userId))
+	}
+}
+
+// ClearAllUserInfoCache 清除所有用户的信息缓存，角色、权限或批量同步用户后调用
+func (u *user) ClearAllUserInfoCache() {
+	// 通过递增缓存版本号使所有旧缓存失效，旧缓存在过期后自动删除
+	global.Cache.Incr(userInfoCacheVersionKey, 0)
+}
+
+// userInfoCacheKey 获取用户信息缓存Key
+func (u *user) userInfoCacheKey(userid uint) string {
+	value, _ := global.Cache.Get(userInfoCacheVersionKey)
+	version, _ := strconv.ParseInt(value, 10, 64)
+	return fmt.Sprintf("user_info:%d:%d", version, userid)
+}
+
+// getUserInfo 从数据库中获取用户信息
+func (u *user) getUserInfo(userid uint) (userinfo *UserInfoWithMenu, err error) {
+
 	var (
 		userInfo *UserInfo
 		user     model.AuthUser
 		roles    []string
 	)
 
-	// 开启事务
+	// 开启事务（只读查询，结束后回滚释放连接）
 	tx := global.MySQLClient.Begin()
+	defer tx.Rollback()
 
 	// 获取用户信息
 	if err := tx.Model(&model.AuthUser{}).Where("id = ?", userid).Find(&userInfo).Error; err != nil {
@@ -164,11 +235,11 @@ func (u *user) GetUserInfo(userid uint) (userinfo *UserInfoWithMenu, err error)
 	}
 
 	// 从OSS中获取头像临时访问URL，临时URL的过期时间与用户Token过期时间保持一致
-	avatarURL, err := utils.GetPresignedURL(userInfo.Avatar, 6*time.Hour)
+	avatarURL, err := utils.GetObjectURL(userInfo.Avatar, 6*time.Hour)
 	if err != nil {
 		userInfo.Avatar = ""
 	} else {
-		userInfo.Avatar = avatarURL.String()
+		userInfo.Avatar = avatarURL
 	}
 
 	// 获取用户菜单
@@ -210,8 +281,8 @@ func (u *user) AddUser(data *model.AuthUser) (user *model.AuthUser, err error) {
 	return data, nil
 }
 
-// SyncUsers 用户同步
-func (u *user) SyncUsers(users []*model.AuthUser) (err error) {
+// SyncUsers 用户同步，返回新创建的用户
+func (u *user) SyncUsers(users []*model.AuthUser) (created []*model.AuthUser, err error) {
 
 	// 使用事务（Transaction）来处理批量插入，这样可以确保数据一致性，要么全部成功，要么全部失败
 	if err := global.MySQLClient.Transaction(func(tx *gorm.DB) error {
@@ -223,14 +294,14 @@ func (u *user) SyncUsers(users []*model.AuthUser) (err error) {
 				// 如果用户已存在则更新
 				if utils.IsDuplicateEntryError(err) {
 					// 仅更新来源为LDAP的用户，则进行用户更新
-					if err := tx.Select("email", "phone_number", "password_expired_at", "is_active").Where("username = ? AND user_from = ?", user.Username, user.UserFrom).Updates(user).Error; err != nil {
+					if err := tx.Select("email", "phone_number", "phone_national", "phone_display", "department", "title", "password_expired_at", "is_active").Where("username = ? AND user_from = ?", user.Username, user.UserFrom).Updates(user).Error; err != nil {
 						return err
 					}
 				} else {
 					return err
 				}
 			} else {
-				continue
+				created = append(created, user)
 			}
 		}
 
@@ -257,9 +328,13 @@ func (u *user) SyncUsers(users []*model.AuthUser) (err error) {
 
 		return nil
 	}); err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+
+	// 批量同步后清除所有用户信息缓存
+	u.ClearAllUserInfoCache()
+
+	return created, nil
 }
 
 // UpdateUser 修改
@@ -280,12 +355,146 @@ func (u *user) UpdateUser(user *model.AuthUser, data *UserUpdate) (*model.AuthUs
 		return nil, err
 	}
 
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(user.ID)
+
 	return user, nil
 }
 
+// UpdateUserBreakGlass 设置或取消应急账号，同时清除首次使用时间
+func (u *user) UpdateUserBreakGlass(userId uint, enabled bool) (err error) {
+	if err := global.MySQLClient.Model(&model.AuthUser{}).Where("id = ?", userId).
+		Updates(map[string]interface{}{"break_glass": enabled, "break_glass_used_at": nil}).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(userId)
+
+	return nil
+}
+
+// MarkBreakGlassUsed 记录应急账号首次使用时间，已记录时不更新
+func (u *user) MarkBreakGlassUsed(userId uint, usedAt time.Time) (err error) {
+	return global.MySQLClient.Model(&model.AuthUser{}).
+		Where("id = ? AND break_glass = ? AND break_glass_used_at IS NULL", userId, true).
+		Update("break_glass_used_at", usedAt).Error
+}
+
+// GetExpiredBreakGlassUsers 获取在指定时间之前开始使用且仍处于启用状态的应急账号
+func (u *user) GetExpiredBreakGlassUsers(usedBefore time.Time) (users []*model.AuthUser, err error) {
+	if err := global.MySQLClient.
+		Where("break_glass = ? AND is_active = ? AND break_glass_used_at < ?", true, true, usedBefore).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// DisableBreakGlassUser 禁用应急账号并清除首次使用时间，重新启用后可再次使用
+func (u *user) DisableBreakGlassUser(userId uint) (err error) {
+	if err := global.MySQLClient.Model(&model.AuthUser{}).Where("id = ?", userId).
+		Updates(map[string]interface{}{"is_active": false, "break_glass_used_at": nil}).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(userId)
+
+	return nil
+}
+
 // UpdateUserPasswordExpiredAt 修改用户密码过期时间
 func (u *user) UpdateUserPasswordExpiredAt(userId uint, passwordExpiredAt *time.Time) (err error) {
-	return global.MySQLClient.Model(&model.AuthUser{}).Where("id = ?", userId).Update("password_expired_at", passwordExpiredAt).Error
+	if err := global.MySQLClient.Model(&model.AuthUser{}).Where("id = ?", userId).Update("password_expired_at", passwordExpiredAt).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(userId)
+
+	return nil
+}
+
+// UpdateUserAvatar 修改用户头像
+func (u *user) UpdateUserAvatar(username, avatar string) (err error) {
+	var user model.AuthUser
+	if err := global.MySQLClient.Where("username = ?", username).First(&user).Error; err != nil {
+		return err
+	}
+	if err := global.MySQLClient.Model(&user).Update("avatar", avatar).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(user.ID)
+
+	return nil
+}
+
+// UpdateUserTimezone 更新用户时区
+func (u *user) UpdateUserTimezone(username, timezone string) (err error) {
+	var user model.AuthUser
+	if err := global.MySQLClient.Where("username = ?", username).First(&user).Error; err != nil {
+		return err
+	}
+	if err := global.MySQLClient.Model(&user).Update("timezone", timezone).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(user.ID)
+
+	return nil
+}
+
+// UpdateUserPhone 更新用户标准化后的手机号
+func (u *user) UpdateUserPhone(userId uint, phoneNumber, phoneNational, phoneDisplay string) (err error) {
+	if err := global.MySQLClient.Model(&model.AuthUser{}).Where("id = ?", userId).
+		Updates(map[string]interface{}{"phone_number": phoneNumber, "phone_national": phoneNational, "phone_display": phoneDisplay}).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(userId)
+
+	return nil
+}
+
+// GetUnnormalizedPhoneUsers 获取手机号未标准化的用户
+func (u *user) GetUnnormalizedPhoneUsers() (users []*model.AuthUser, err error) {
+	if err := global.MySQLClient.Where("phone_number <> '' AND (phone_national IS NULL OR phone_national = '')").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// UpdateUserLanguage 更新用户首选语言
+func (u *user) UpdateUserLanguage(username, language string) (err error) {
+	var user model.AuthUser
+	if err := global.MySQLClient.Where("username = ?", username).First(&user).Error; err != nil {
+		return err
+	}
+	if err := global.MySQLClient.Model(&user).Update("language", language).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(user.ID)
+
+	return nil
+}
+
+// UpdateUserRequiredActions 更新用户登录时必须完成的操作
+func (u *user) UpdateUserRequiredActions(userId uint, actions string) (err error) {
+	if err := global.MySQLClient.Model(&model.AuthUser{}).Where("id = ?", userId).Update("required_actions", actions).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(userId)
+
+	return nil
 }
 
 // DeleteUser 删除
@@ -304,11 +513,18 @@ func (u *user) UpdateUserPassword(user *model.AuthUser, data *UserPasswordUpdate
 
 	// 指定新密码有效期
 	currentTime := time.Now()
-	passwordExpiredAtDays := config.Conf.Settings["passwordExpireDays"].(int)
+	passwordExpiredAtDays := config.GetInt("passwordExpireDays")
 	passwordExpiredAt := currentTime.AddDate(0, 0, passwordExpiredAtDays)
 
 	// 更新密码
-	return global.MySQLClient.Model(&user).Updates(map[string]interface{}{"password": cipherText, "password_expired_at": passwordExpiredAt}).Error
+	if err := global.MySQLClient.Model(&user).Updates(map[string]interface{}{"password": cipherText, "password_expired_at": passwordExpiredAt}).Error; err != nil {
+		return err
+	}
+
+	// 清除用户信息缓存
+	u.ClearUserInfoCache(user.ID)
+
+	return nil
 }
 
 // ResetUserMFA 重置MFA
@@ -321,7 +537,7 @@ func (u *user) ResetUserMFA(data *model.AuthUser) (err error) {
 // GetPasswordExpiredUserList 获取密码过期用户列表
 func (u *user) GetPasswordExpiredUserList() (userList []*PasswordExpiredUserList, err error) {
 
-	passwordExpiryReminderDays := config.Conf.Settings["passwordExpiryReminderDays"].(int)
+	passwordExpiryReminderDays := config.GetInt("passwordExpiryReminderDays")
 
 	var (
 		results        []*PasswordExpiredUserList
@@ -329,7 +545,7 @@ func (u *user) GetPasswordExpiredUserList() (userList []*PasswordExpiredUserList
 		sevenDaysLater = now.Add(time.Duration(passwordExpiryReminderDays) * 24 * time.Hour)
 	)
 
-	if err := global.MySQLClient.Model(&model.AuthUser{}).Select("name, username, email, password_expired_at").
+	if err := global.MySQLClient.Model(&model.AuthUser{}).Select("name, username, email, language, timezone, password_expired_at").
 		Where("is_active = ?", true).
 		Where("password_expired_at IS NOT NULL AND (password_expired_at < ? OR password_expired_at BETWEEN ? AND ?)", now, now, sevenDaysLater).
 		Where("email IS NOT NULL").
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"fmt"
+	"github.com/wonderivan/logger"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/utils/cache"
+)
+
+// CacheInit 缓存初始化，根据配置文件中的 cache.type 使用Redis（默认）、内存或Memcached
+func CacheInit() error {
+	switch config.Conf.Cache.Type {
+	case "", "redis":
+		if err := RedisInit(); err != nil {
+			return err
+		}
+		global.Cache = cache.NewRedis(global.RedisClient)
+	case "memory":
+		global.Cache = cache.NewMemory()
+		logger.Warn("使用内存缓存，仅适用于单实例部署，服务重启后会话及验证码等缓存数据将丢失.")
+	case "memcached":
+		client, err := cache.NewMemcached(config.Conf.Cache.Servers, redisDialTimeout)
+		if err != nil {
+			return err
+		}
+		if err := client.Ping(); err != nil {
+			return err
+		}
+		global.Cache = client
+		logger.Info("Memcached客户端初始化成功.")
+	default:
+		return fmt.Errorf("不支持的缓存类型：%s，可选值为：redis、memory、memcached", config.Conf.Cache.Type)
+	}
+	return nil
+}
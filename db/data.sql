@@ -87,6 +87,117 @@ INSERT INTO `system_path` VALUES (59, 'GetUrlList', '/api/v1/urls', 'GET', 'Site
 INSERT INTO `system_path` VALUES (60, 'AddUrl', '/api/v1/url', 'POST', 'SiteMonitoring', '新增站点');
 INSERT INTO `system_path` VALUES (61, 'DeleteUrl', '/api/v1/url/:id', 'DELETE', 'SiteMonitoring', '删除站点');
 INSERT INTO `system_path` VALUES (62, 'UpdateUrl', '/api/v1/url', 'PUT', 'SiteMonitoring', '修改站点');
+INSERT INTO `system_path` VALUES (63, 'GetGuideStepList', '/api/v1/guides', 'GET', 'SiteManagement', '获取引导步骤列表');
+INSERT INTO `system_path` VALUES (64, 'AddGuideStep', '/api/v1/guide', 'POST', 'SiteManagement', '新增引导步骤');
+INSERT INTO `system_path` VALUES (65, 'UpdateGuideStep', '/api/v1/guide', 'PUT', 'SiteManagement', '修改引导步骤');
+INSERT INTO `system_path` VALUES (66, 'UpdateGuideStepSort', '/api/v1/guide/sort', 'PUT', 'SiteManagement', '修改引导步骤排序');
+INSERT INTO `system_path` VALUES (67, 'DeleteGuideStep', '/api/v1/guide/:id', 'DELETE', 'SiteManagement', '删除引导步骤');
+INSERT INTO `system_path` VALUES (68, 'UploadGuideImage', '/api/v1/guide/imageUpload', 'POST', 'SiteManagement', '上传引导步骤图片');
+INSERT INTO `system_path` VALUES (69, 'GetSCIMRecordList', '/api/v1/audit/scim', 'GET', 'AuditOplog', '获取SCIM同步记录');
+INSERT INTO `system_path` VALUES (70, 'ImportKeycloakRealm', '/api/v1/import/keycloak', 'POST', 'UserManagement', '导入Keycloak数据');
+INSERT INTO `system_path` VALUES (71, 'GetSiteTemplateList', '/api/v1/site/templates', 'GET', 'SiteManagement', '获取站点集成模板');
+INSERT INTO `system_path` VALUES (72, 'GetLoginPolicyList', '/api/v1/login_policies', 'GET', 'ConfManagement', '获取登录策略列表');
+INSERT INTO `system_path` VALUES (73, 'AddLoginPolicy', '/api/v1/login_policy', 'POST', 'ConfManagement', '新增登录策略');
+INSERT INTO `system_path` VALUES (74, 'UpdateLoginPolicy', '/api/v1/login_policy', 'PUT', 'ConfManagement', '修改登录策略');
+INSERT INTO `system_path` VALUES (75, 'DeleteLoginPolicy', '/api/v1/login_policy/:id', 'DELETE', 'ConfManagement', '删除登录策略');
+INSERT INTO `system_path` VALUES (76, 'GetLoginTimePolicyList', '/api/v1/login_time_policies', 'GET', 'ConfManagement', '获取登录时间策略列表');
+INSERT INTO `system_path` VALUES (77, 'AddLoginTimePolicy', '/api/v1/login_time_policy', 'POST', 'ConfManagement', '新增登录时间策略');
+INSERT INTO `system_path` VALUES (78, 'UpdateLoginTimePolicy', '/api/v1/login_time_policy', 'PUT', 'ConfManagement', '修改登录时间策略');
+INSERT INTO `system_path` VALUES (79, 'DeleteLoginTimePolicy', '/api/v1/login_time_policy/:id', 'DELETE', 'ConfManagement', '删除登录时间策略');
+INSERT INTO `system_path` VALUES (80, 'GetHolidayList', '/api/v1/holidays', 'GET', 'ConfManagement', '获取节假日日历');
+INSERT INTO `system_path` VALUES (81, 'SaveHolidays', '/api/v1/holidays', 'POST', 'ConfManagement', '保存节假日');
+INSERT INTO `system_path` VALUES (82, 'DeleteHoliday', '/api/v1/holiday/:id', 'DELETE', 'ConfManagement', '删除节假日');
+INSERT INTO `system_path` VALUES (83, 'GetLoginTrend', '/api/v1/stats/logins', 'GET', 'AuditLoginRecord', '获取登录趋势');
+INSERT INTO `system_path` VALUES (84, 'GetAppLaunches', '/api/v1/stats/apps', 'GET', 'AuditLoginRecord', '获取应用登录次数排行');
+INSERT INTO `system_path` VALUES (85, 'GetFailureStats', '/api/v1/stats/failures', 'GET', 'AuditLoginRecord', '获取登录失败统计');
+INSERT INTO `system_path` VALUES (86, 'GetMFAAdoption', '/api/v1/stats/mfa', 'GET', 'AuditLoginRecord', '获取MFA使用率趋势');
+INSERT INTO `system_path` VALUES (87, 'RollupStats', '/api/v1/stats/rollup', 'POST', 'AuditLoginRecord', '回填统计数据');
+INSERT INTO `system_path` VALUES (88, 'GetReportList', '/api/v1/reports', 'GET', 'AuditOplog', '获取合规报告列表');
+INSERT INTO `system_path` VALUES (89, 'CreateReport', '/api/v1/report', 'POST', 'AuditOplog', '生成合规报告');
+INSERT INTO `system_path` VALUES (90, 'GetReportURL', '/api/v1/report/:id/download', 'GET', 'AuditOplog', '获取合规报告下载地址');
+INSERT INTO `system_path` VALUES (91, 'DeleteReport', '/api/v1/report/:id', 'DELETE', 'AuditOplog', '删除合规报告');
+INSERT INTO `system_path` VALUES (92, 'GetLoginHookList', '/api/v1/login_hooks', 'GET', 'ConfManagement', '获取登录扩展列表');
+INSERT INTO `system_path` VALUES (93, 'GetLoginHookPlugins', '/api/v1/login_hooks/plugins', 'GET', 'ConfManagement', '获取已注册的内置扩展');
+INSERT INTO `system_path` VALUES (94, 'AddLoginHook', '/api/v1/login_hook', 'POST', 'ConfManagement', '新增登录扩展');
+INSERT INTO `system_path` VALUES (95, 'UpdateLoginHook', '/api/v1/login_hook', 'PUT', 'ConfManagement', '修改登录扩展');
+INSERT INTO `system_path` VALUES (96, 'DeleteLoginHook', '/api/v1/login_hook/:id', 'DELETE', 'ConfManagement', '删除登录扩展');
+INSERT INTO `system_path` VALUES (97, 'GetFeatureFlagList', '/api/v1/feature_flags', 'GET', 'ConfManagement', '获取功能开关列表');
+INSERT INTO `system_path` VALUES (98, 'AddFeatureFlag', '/api/v1/feature_flag', 'POST', 'ConfManagement', '新增功能开关');
+INSERT INTO `system_path` VALUES (99, 'UpdateFeatureFlag', '/api/v1/feature_flag', 'PUT', 'ConfManagement', '修改功能开关');
+INSERT INTO `system_path` VALUES (100, 'DeleteFeatureFlag', '/api/v1/feature_flag/:id', 'DELETE', 'ConfManagement', '删除功能开关');
+INSERT INTO `system_path` VALUES (101, 'LogoutUser', '/api/v1/user/logout/:id', 'PUT', 'UserManagement', '强制下线');
+INSERT INTO `system_path` VALUES (102, 'GetDeviceList', '/api/v1/devices', 'GET', 'UserManagement', '获取登录设备列表');
+INSERT INTO `system_path` VALUES (103, 'GetSiteCertificates', '/api/v1/site/certificates', 'GET', 'SiteManagement', '获取站点SP证书');
+INSERT INTO `system_path` VALUES (104, 'AddSiteCertificate', '/api/v1/site/certificate', 'POST', 'SiteManagement', '新增站点SP证书');
+INSERT INTO `system_path` VALUES (105, 'UpdateSiteCertificate', '/api/v1/site/certificate', 'PUT', 'SiteManagement', '修改站点SP证书');
+INSERT INTO `system_path` VALUES (106, 'DeleteSiteCertificate', '/api/v1/site/certificate/:id', 'DELETE', 'SiteManagement', '删除站点SP证书');
+INSERT INTO `system_path` VALUES (107, 'GetSiteErrorReport', '/api/v1/site/sso_errors', 'GET', 'SiteManagement', '获取站点单点登录错误报告');
+INSERT INTO `system_path` VALUES (108, 'GetExternalUser', '/api/v1/external/user/:external_id', 'GET', 'UserManagement', '根据外部标识获取用户');
+INSERT INTO `system_path` VALUES (109, 'PutExternalUser', '/api/v1/external/user/:external_id', 'PUT', 'UserManagement', '根据外部标识创建或更新用户');
+INSERT INTO `system_path` VALUES (110, 'DeleteExternalUser', '/api/v1/external/user/:external_id', 'DELETE', 'UserManagement', '根据外部标识删除用户');
+INSERT INTO `system_path` VALUES (111, 'GetExternalGroup', '/api/v1/external/group/:external_id', 'GET', 'GroupManagement', '根据外部标识获取分组');
+INSERT INTO `system_path` VALUES (112, 'PutExternalGroup', '/api/v1/external/group/:external_id', 'PUT', 'GroupManagement', '根据外部标识创建或更新分组');
+INSERT INTO `system_path` VALUES (113, 'DeleteExternalGroup', '/api/v1/external/group/:external_id', 'DELETE', 'GroupManagement', '根据外部标识删除分组');
+INSERT INTO `system_path` VALUES (114, 'GetExternalSite', '/api/v1/external/site/:external_id', 'GET', 'SiteManagement', '根据外部标识获取站点');
+INSERT INTO `system_path` VALUES (115, 'PutExternalSite', '/api/v1/external/site/:external_id', 'PUT', 'SiteManagement', '根据外部标识创建或更新站点');
+INSERT INTO `system_path` VALUES (116, 'DeleteExternalSite', '/api/v1/external/site/:external_id', 'DELETE', 'SiteManagement', '根据外部标识删除站点');
+INSERT INTO `system_path` VALUES (117, 'GetProvisionRuleList', '/api/v1/provision_rules', 'GET', 'UserManagement', '获取自动分配规则列表（表格）');
+INSERT INTO `system_path` VALUES (118, 'AddProvisionRule', '/api/v1/provision_rule', 'POST', 'UserManagement', '新增自动分配规则');
+INSERT INTO `system_path` VALUES (119, 'UpdateProvisionRule', '/api/v1/provision_rule', 'PUT', 'UserManagement', '修改自动分配规则');
+INSERT INTO `system_path` VALUES (120, 'DeleteProvisionRule', '/api/v1/provision_rule/:id', 'DELETE', 'UserManagement', '删除自动分配规则');
+INSERT INTO `system_path` VALUES (121, 'GetSessionList', '/api/v1/sessions', 'GET', 'UserManagement', '获取会话列表');
+INSERT INTO `system_path` VALUES (122, 'GetSessionPolicyList', '/api/v1/session_policies', 'GET', 'ConfManagement', '获取会话并发策略列表');
+INSERT INTO `system_path` VALUES (123, 'AddSessionPolicy', '/api/v1/session_policy', 'POST', 'ConfManagement', '新增会话并发策略');
+INSERT INTO `system_path` VALUES (124, 'UpdateSessionPolicy', '/api/v1/session_policy', 'PUT', 'ConfManagement', '修改会话并发策略');
+INSERT INTO `system_path` VALUES (125, 'DeleteSessionPolicy', '/api/v1/session_policy/:id', 'DELETE', 'ConfManagement', '删除会话并发策略');
+INSERT INTO `system_path` VALUES (126, 'WipeDevice', '/api/v1/device/:id/wipe', 'POST', 'UserManagement', '擦除登录设备');
+INSERT INTO `system_path` VALUES (127, 'UpdateBreakGlass', '/api/v1/user/break_glass', 'PUT', 'UserManagement', '设置应急账号');
+INSERT INTO `system_path` VALUES (128, 'GetLandingRuleList', '/api/v1/landing_rules', 'GET', 'ConfManagement', '获取登录后跳转规则列表');
+INSERT INTO `system_path` VALUES (129, 'AddLandingRule', '/api/v1/landing_rule', 'POST', 'ConfManagement', '新增登录后跳转规则');
+INSERT INTO `system_path` VALUES (130, 'UpdateLandingRule', '/api/v1/landing_rule', 'PUT', 'ConfManagement', '修改登录后跳转规则');
+INSERT INTO `system_path` VALUES (131, 'DeleteLandingRule', '/api/v1/landing_rule/:id', 'DELETE', 'ConfManagement', '删除登录后跳转规则');
+INSERT INTO `system_path` VALUES (132, 'GetProvisionWebhookList', '/api/v1/provision_webhooks', 'GET', 'ConfManagement', '获取身份事件推送列表');
+INSERT INTO `system_path` VALUES (133, 'AddProvisionWebhook', '/api/v1/provision_webhook', 'POST', 'ConfManagement', '新增身份事件推送');
+INSERT INTO `system_path` VALUES (134, 'UpdateProvisionWebhook', '/api/v1/provision_webhook', 'PUT', 'ConfManagement', '修改身份事件推送');
+INSERT INTO `system_path` VALUES (135, 'DeleteProvisionWebhook', '/api/v1/provision_webhook/:id', 'DELETE', 'ConfManagement', '删除身份事件推送');
+INSERT INTO `system_path` VALUES (136, 'PreviewProvisionWebhook', '/api/v1/provision_webhook/preview', 'POST', 'ConfManagement', '预览身份事件推送内容');
+INSERT INTO `system_path` VALUES (137, 'UpdateMaintenance', '/api/v1/maintenance', 'PUT', 'ConfManagement', '开启或关闭维护模式');
+INSERT INTO `system_path` VALUES (138, 'PreviewSettings', '/api/v1/settings/preview', 'POST', 'ConfManagement', '预览配置修改');
+INSERT INTO `system_path` VALUES (139, 'GetSettingsRevisionList', '/api/v1/settings/revisions', 'GET', 'ConfManagement', '获取配置修改记录');
+INSERT INTO `system_path` VALUES (140, 'RollbackSettings', '/api/v1/settings/revision/:id/rollback', 'POST', 'ConfManagement', '回滚配置修改');
+INSERT INTO `system_path` VALUES (141, 'GetAlertList', '/api/v1/alerts', 'GET', 'ConfManagement', '获取告警状态');
+INSERT INTO `system_path` VALUES (142, 'GetAlertRules', '/api/v1/alert/rules', 'GET', 'ConfManagement', '导出Prometheus告警规则');
+INSERT INTO `system_path` VALUES (143, 'GetTermsList', '/api/v1/terms', 'GET', 'ConfManagement', '获取使用条款列表');
+INSERT INTO `system_path` VALUES (144, 'AddTerms', '/api/v1/terms', 'POST', 'ConfManagement', '新增使用条款');
+INSERT INTO `system_path` VALUES (145, 'UpdateTerms', '/api/v1/terms', 'PUT', 'ConfManagement', '修改使用条款');
+INSERT INTO `system_path` VALUES (146, 'DeleteTerms', '/api/v1/terms/:id', 'DELETE', 'ConfManagement', '删除使用条款');
+INSERT INTO `system_path` VALUES (147, 'GetTermsVersions', '/api/v1/terms/:id/versions', 'GET', 'ConfManagement', '获取使用条款历史版本');
+INSERT INTO `system_path` VALUES (148, 'GetTermsAcceptanceList', '/api/v1/terms/acceptances', 'GET', 'ConfManagement', '获取使用条款接受记录');
+INSERT INTO `system_path` VALUES (149, 'EraseUser', '/api/v1/user/erase', 'POST', 'UserManagement', '擦除用户个人数据');
+INSERT INTO `system_path` VALUES (150, 'GetUserErasureList', '/api/v1/user/erasures', 'GET', 'UserManagement', '获取用户数据擦除记录');
+INSERT INTO `system_path` VALUES (151, 'GetServiceClientList', '/api/v1/service_clients', 'GET', 'ConfManagement', '获取服务客户端列表');
+INSERT INTO `system_path` VALUES (152, 'AddServiceClient', '/api/v1/service_client', 'POST', 'ConfManagement', '新增服务客户端');
+INSERT INTO `system_path` VALUES (153, 'UpdateServiceClient', '/api/v1/service_client', 'PUT', 'ConfManagement', '修改服务客户端');
+INSERT INTO `system_path` VALUES (154, 'DeleteServiceClient', '/api/v1/service_client/:id', 'DELETE', 'ConfManagement', '删除服务客户端');
+INSERT INTO `system_path` VALUES (155, 'ResetServiceClientSecret', '/api/v1/service_client/:id/secret', 'PUT', 'ConfManagement', '重置服务客户端密钥');
+INSERT INTO `system_path` VALUES (156, 'GetSigningKeyList', '/api/v1/signing_keys', 'GET', 'ConfManagement', '获取签名密钥列表');
+INSERT INTO `system_path` VALUES (157, 'AddSigningKey', '/api/v1/signing_key', 'POST', 'ConfManagement', '生成签名密钥');
+INSERT INTO `system_path` VALUES (158, 'ActivateSigningKey', '/api/v1/signing_key/:id/activate', 'PUT', 'ConfManagement', '启用签名密钥');
+INSERT INTO `system_path` VALUES (159, 'RetireSigningKey', '/api/v1/signing_key/:id/retire', 'PUT', 'ConfManagement', '停用签名密钥');
+INSERT INTO `system_path` VALUES (160, 'ValidateSite', '/api/v1/site/validate', 'POST', 'SiteManagement', '站点配置预检');
+INSERT INTO `system_path` VALUES (161, 'GetAccessRequestList', '/api/v1/access_requests', 'GET', 'UserManagement', '获取访问申请列表（表格）');
+INSERT INTO `system_path` VALUES (162, 'ResolveAccessRequest', '/api/v1/access_request/:id/resolve', 'PUT', 'UserManagement', '手动处理访问申请');
+INSERT INTO `system_path` VALUES (163, 'GetAuditorGrantList', '/api/v1/auditors', 'GET', 'UserManagement', '获取审计员列表（表格）');
+INSERT INTO `system_path` VALUES (164, 'AddAuditorGrant', '/api/v1/auditor', 'POST', 'UserManagement', '授予审计员');
+INSERT INTO `system_path` VALUES (165, 'RevokeAuditorGrant', '/api/v1/auditor/:id/revoke', 'PUT', 'UserManagement', '撤销审计员');
+INSERT INTO `system_path` VALUES (166, 'UpdateSiteGrantExpiry', '/api/v1/site/grant/expiry', 'PUT', 'SiteManagement', '设置站点授权有效期');
+INSERT INTO `system_path` VALUES (167, 'GetExpiringSiteGrants', '/api/v1/site/grants/expiring', 'GET', 'SiteManagement', '获取即将到期的站点授权');
+INSERT INTO `system_path` VALUES (168, 'GetSiteIntegration', '/api/v1/site/integration', 'GET', 'SiteManagement', '获取站点集成描述');
+INSERT INTO `system_path` VALUES (169, 'DownloadSiteIntegrationFile', '/api/v1/site/integration/file', 'GET', 'SiteManagement', '下载站点集成配置文件');
+INSERT INTO `system_path` VALUES (170, 'GetConsoleEvents', '/api/v1/console/events', 'GET', 'ConfManagement', '订阅管理控制台实时事件');
+INSERT INTO `system_path` VALUES (171, 'GetUserLoginStats', '/api/v1/stats/users', 'GET', 'AuditLoginRecord', '获取用户登录次数排行');
+INSERT INTO `system_path` VALUES (172, 'GetOplogStats', '/api/v1/stats/operations', 'GET', 'AuditLoginRecord', '获取操作日志统计');
+INSERT INTO `system_path` VALUES (173, 'RunConformance', '/api/v1/conformance/run', 'POST', 'ConfManagement', '执行协议一致性测试');
 
 # 系统默认配置
 INSERT INTO `settings` VALUES (1, 'externalUrl', 'https://example.idsphere.cn', 'string');
@@ -183,4 +294,75 @@ INSERT INTO `settings` VALUES (36, 'wechatAgentId', null, 'int');
 INSERT INTO `settings` VALUES (37, 'wechatSecret', null, 'string');
 INSERT INTO `settings` VALUES (38, 'tokenExpiresTime', 12, 'int');
 INSERT INTO `settings` VALUES (39, 'swagger','true', 'boolean');
-INSERT INTO `settings` VALUES (40, 'passwordMailResetOff','false', 'boolean');
\ No newline at end of file
+INSERT INTO `settings` VALUES (40, 'passwordMailResetOff','false', 'boolean');
+INSERT INTO `settings` VALUES (41, 'passwordBreachCheck', 'false', 'boolean');
+INSERT INTO `settings` VALUES (42, 'passwordBreachMode', 'online', 'string');
+INSERT INTO `settings` VALUES (43, 'passwordBreachCorpus', 'config/breached_passwords.txt', 'string');
+INSERT INTO `settings` VALUES (44, 'scimToken', null, 'string');
+INSERT INTO `settings` VALUES (45, 'ldapServer', 'false', 'boolean');
+INSERT INTO `settings` VALUES (46, 'ldapServerAddress', ':1389', 'string');
+INSERT INTO `settings` VALUES (47, 'ldapServerBaseDn', 'dc=idsphere,dc=cn', 'string');
+INSERT INTO `settings` VALUES (48, 'ldapServerBindDn', 'cn=readonly,dc=idsphere,dc=cn', 'string');
+INSERT INTO `settings` VALUES (49, 'ldapServerBindPassword', null, 'string');
+INSERT INTO `settings` VALUES (50, 'ldapServerLockoutThreshold', '5', 'int');
+INSERT INTO `settings` VALUES (51, 'ldapServerLockoutMinutes', '15', 'int');
+INSERT INTO `settings` VALUES (52, 'ossPublicUrl', null, 'string');
+INSERT INTO `settings` VALUES (53, 'dormantAccountDays', '90', 'int');
+INSERT INTO `settings` VALUES (54, 'defaultLanguage', 'en-US', 'string');
+INSERT INTO `settings` VALUES (55, 'smsTemplateIdEn', null, 'string');
+INSERT INTO `settings` VALUES (56, 'endpointAllowlist', null, 'list');
+INSERT INTO `settings` VALUES (57, 'publicRateLimit', '120', 'int');
+INSERT INTO `settings` VALUES (58, 'securityNotifyDigest', 'true', 'boolean');
+INSERT INTO `settings` VALUES (59, 'oidcIssuer', null, 'string');
+INSERT INTO `settings` VALUES (60, 'oidcAuthorizationEndpoint', null, 'string');
+INSERT INTO `settings` VALUES (61, 'oidcTokenEndpoint', null, 'string');
+INSERT INTO `settings` VALUES (62, 'oidcUserinfoEndpoint', null, 'string');
+INSERT INTO `settings` VALUES (63, 'oidcJwksUri', null, 'string');
+INSERT INTO `settings` VALUES (64, 'trustedNetworks', null, 'list');
+INSERT INTO `settings` VALUES (65, 'trustedRateLimit', '600', 'int');
+INSERT INTO `settings` VALUES (66, 'publicDirectory', 'false', 'boolean');
+INSERT INTO `settings` VALUES (67, 'breakGlassNetworks', null, 'list');
+INSERT INTO `settings` VALUES (68, 'breakGlassWindow', '60', 'int');
+INSERT INTO `settings` VALUES (69, 'redirectAllowlist', null, 'list');
+INSERT INTO `settings` VALUES (70, 'uploadScanner', null, 'string');
+INSERT INTO `settings` VALUES (71, 'uploadScanAddress', null, 'string');
+INSERT INTO `settings` VALUES (72, 'uploadMaxSize', '2048', 'int');
+INSERT INTO `settings` VALUES (73, 'uploadMaxDimension', '4096', 'int');
+INSERT INTO `settings` VALUES (74, 'alertWindow', '15', 'int');
+INSERT INTO `settings` VALUES (75, 'alertLoginFailureThreshold', '50', 'int');
+INSERT INTO `settings` VALUES (76, 'alertSmsErrorRate', '20', 'int');
+INSERT INTO `settings` VALUES (77, 'alertCertificateDays', '30', 'int');
+INSERT INTO `settings` VALUES (78, 'oidcDeviceEndpoint', null, 'string');
+INSERT INTO `settings` VALUES (79, 'oidcVerificationUri', null, 'string');
+INSERT INTO `settings` VALUES (80, 'firstLoginActions', null, 'list');
+INSERT INTO `settings` VALUES (81, 'firstLoginTerms', null, 'string');
+INSERT INTO `settings` VALUES (82, 'oidcRegistration', 'false', 'boolean');
+INSERT INTO `settings` VALUES (83, 'oidcRegistrationToken', null, 'string');
+INSERT INTO `settings` VALUES (84, 'timezone', null, 'string');
+INSERT INTO `settings` VALUES (85, 'smsRegion', 'CN', 'string');
+INSERT INTO `settings` VALUES (86, 'serviceTokenTTL', '300', 'int');
+INSERT INTO `settings` VALUES (87, 'ldapStartTLS', 'false', 'boolean');
+INSERT INTO `settings` VALUES (88, 'ldapSkipVerify', 'true', 'boolean');
+INSERT INTO `settings` VALUES (89, 'ldapFollowReferrals', 'false', 'boolean');
+INSERT INTO `settings` VALUES (90, 'ldapTimeout', '10', 'int');
+INSERT INTO `settings` VALUES (91, 'ldapPoolSize', '5', 'int');
+INSERT INTO `settings` VALUES (92, 'ldapRateLimit', '0', 'int');
+INSERT INTO `settings` VALUES (93, 'protocolSunset', null, 'list');
+INSERT INTO `settings` VALUES (94, 'itsmProvider', null, 'string');
+INSERT INTO `settings` VALUES (95, 'itsmJiraAddress', null, 'string');
+INSERT INTO `settings` VALUES (96, 'itsmJiraUser', null, 'string');
+INSERT INTO `settings` VALUES (97, 'itsmJiraToken', null, 'string');
+INSERT INTO `settings` VALUES (98, 'itsmJiraServiceDesk', null, 'string');
+INSERT INTO `settings` VALUES (99, 'itsmJiraRequestType', null, 'string');
+INSERT INTO `settings` VALUES (100, 'itsmFeishuApprovalCode', null, 'string');
+INSERT INTO `settings` VALUES (101, 'itsmFeishuWidget', 'widget1', 'string');
+INSERT INTO `settings` VALUES (102, 'itsmWebhookToken', null, 'string');
+INSERT INTO `settings` VALUES (103, 'auditorMaxDays', '30', 'int');
+INSERT INTO `settings` VALUES (104, 'smsTemplates', null, 'string');
+INSERT INTO `settings` VALUES (105, 'tokenRevocationFailOpen', 'false', 'boolean');
+INSERT INTO `settings` VALUES (106, 'corsPolicies', null, 'string');
+INSERT INTO `settings` VALUES (107, 'legacyStatusCode', 'true', 'boolean');
+INSERT INTO `settings` VALUES (108, 'ldapServerTlsAddress', ':1636', 'string');
+INSERT INTO `settings` VALUES (109, 'ldapServerCertificate', null, 'string');
+INSERT INTO `settings` VALUES (110, 'ldapServerPrivateKey', null, 'string');
+INSERT INTO `settings` VALUES (111, 'trustedProxies', null, 'list');
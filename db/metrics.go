@@ -0,0 +1,102 @@
+package db
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+	"time"
+)
+
+const queryMetricsStartKey = "query_metrics:start"
+
+// queryMetrics GORM查询指标插件，按数据表及操作类型统计SQL执行次数和耗时，用于发现列表接口中的N+1查询
+type queryMetrics struct {
+	total    *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// Name 插件名称
+func (q *queryMetrics) Name() string {
+	return "query_metrics"
+}
+
+// Initialize 注册指标及回调
+func (q *queryMetrics) Initialize(db *gorm.DB) error {
+	q.total = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gorm_query_total",
+		Help: "SQL执行次数",
+	}, []string{"table", "operation"})
+	q.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gorm_query_duration_seconds",
+		Help:    "SQL执行耗时（秒）",
+		Buckets: []float64{0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1, 5},
+	}, []string{"table", "operation"})
+	if err := prometheus.Register(q.total); err != nil {
+		return err
+	}
+	if err := prometheus.Register(q.duration); err != nil {
+		return err
+	}
+
+	callback := db.Callback()
+	if err := callback.Query().Before("gorm:query").Register("query_metrics:before_query", q.before); err != nil {
+		return err
+	}
+	if err := callback.Query().After("gorm:after_query").Register("query_metrics:after_query", q.after("query")); err != nil {
+		return err
+	}
+	if err := callback.Create().Before("gorm:begin_transaction").Register("query_metrics:before_create", q.before); err != nil {
+		return err
+	}
+	if err := callback.Create().After("gorm:commit_or_rollback_transaction").Register("query_metrics:after_create", q.after("create")); err != nil {
+		return err
+	}
+	if err := callback.Update().Before("gorm:begin_transaction").Register("query_metrics:before_update", q.before); err != nil {
+		return err
+	}
+	if err := callback.Update().After("gorm:commit_or_rollback_transaction").Register("query_metrics:after_update", q.after("update")); err != nil {
+		return err
+	}
+	if err := callback.Delete().Before("gorm:begin_transaction").Register("query_metrics:before_delete", q.before); err != nil {
+		return err
+	}
+	if err := callback.Delete().After("gorm:commit_or_rollback_transaction").Register("query_metrics:after_delete", q.after("delete")); err != nil {
+		return err
+	}
+	if err := callback.Row().Before("gorm:row").Register("query_metrics:before_row", q.before); err != nil {
+		return err
+	}
+	if err := callback.Row().After("gorm:row").Register("query_metrics:after_row", q.after("row")); err != nil {
+		return err
+	}
+	if err := callback.Raw().Before("gorm:raw").Register("query_metrics:before_raw", q.before); err != nil {
+		return err
+	}
+	return callback.Raw().After("gorm:raw").Register("query_metrics:after_raw", q.after("raw"))
+}
+
+// before 记录SQL开始执行时间
+func (q *queryMetrics) before(db *gorm.DB) {
+	db.InstanceSet(queryMetricsStartKey, time.Now())
+}
+
+// after 统计SQL执行次数及耗时
+func (q *queryMetrics) after(operation string) func(db *gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(queryMetricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+
+		q.total.WithLabelValues(table, operation).Inc()
+		q.duration.WithLabelValues(table, operation).Observe(time.Since(start).Seconds())
+	}
+}
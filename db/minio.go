@@ -1,11 +1,14 @@
 package db
 
 import (
+	"fmt"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/wonderivan/logger"
+	"net/http"
 	"ops-api/config"
 	"ops-api/global"
+	"ops-api/utils/breaker"
 )
 
 func MinioInit() error {
@@ -15,10 +18,16 @@ func MinioInit() error {
 	accessKey := config.Conf.OSS.AccessKey
 	secretKey := config.Conf.OSS.SecretKey
 
+	transport, err := minio.DefaultTransport(config.Conf.OSS.SSL)
+	if err != nil {
+		return err
+	}
+
 	// 客户端初始化
 	client, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
-		Secure: config.Conf.OSS.SSL,
+		Creds:     credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure:    config.Conf.OSS.SSL,
+		Transport: &minioTransport{next: transport},
 	})
 	if err != nil {
 		return err
@@ -29,3 +38,27 @@ func MinioInit() error {
 
 	return nil
 }
+
+// minioTransport 记录MinIO请求结果，MinIO连续不可用时熔断，熔断期间请求直接返回 breaker.ErrOpen
+type minioTransport struct {
+	next http.RoundTripper
+}
+
+// RoundTrip 发送请求，网络错误及5xx响应视为MinIO不可用
+func (t *minioTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !global.MinioBreaker.Allow() {
+		return nil, breaker.ErrOpen
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		global.MinioBreaker.Failure(err)
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		global.MinioBreaker.Failure(fmt.Errorf("MinIO返回错误，HTTP状态码：%d", resp.StatusCode))
+	} else {
+		global.MinioBreaker.Success()
+	}
+	return resp, nil
+}
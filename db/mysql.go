@@ -1,12 +1,14 @@
 package db
 
 import (
+	"errors"
 	"fmt"
 	"github.com/wonderivan/logger"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	logger2 "gorm.io/gorm/logger"
 	"gorm.io/plugin/prometheus"
+	"net/url"
 	"ops-api/config"
 	"ops-api/global"
 	"ops-api/model"
@@ -19,18 +21,30 @@ import (
 // MySQLInit MySQL初始化
 func MySQLInit() error {
 
+	// 数据库时间的存储时区，未配置时使用服务器本地时区，与旧版本保持一致；配置为UTC时展示按系统或用户时区转换
+	loc := config.Conf.MySQL.Loc
+	if loc == "" {
+		loc = "Local"
+	}
+	location, err := config.LoadLocation(loc)
+	if err != nil {
+		return err
+	}
+
 	// 组装数据库连接配置
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=Local",
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=utf8mb4&parseTime=True&loc=%s",
 		config.Conf.MySQL.User,
 		config.Conf.MySQL.Password,
 		config.Conf.MySQL.Host,
 		config.Conf.MySQL.Port,
 		config.Conf.MySQL.DB,
+		url.QueryEscape(loc),
 	)
 
 	// 建立数据库连接，并生成*gorm.DB对象
 	client, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger2.Default.LogMode(logger2.Silent),
+		Logger:  logger2.Default.LogMode(logger2.Silent),
+		NowFunc: func() time.Time { return time.Now().In(location) },
 	})
 	if err != nil {
 		return err
@@ -47,6 +61,12 @@ func MySQLInit() error {
 		return err
 	}
 
+	// 启动 SQL 执行次数及耗时统计（按数据表）
+	if err := client.Use(&queryMetrics{}); err != nil {
+		logger.Error("启动 SQL 查询指标统计失败：", err.Error())
+		return err
+	}
+
 	// 表迁移
 	_ = client.AutoMigrate(
 		&model.AuthUser{},
@@ -54,14 +74,20 @@ func MySQLInit() error {
 		&model.SiteGroup{},
 		&model.Tag{},
 		&model.Site{},
+		&model.SiteCertificate{},
 		&model.Menu{},
 		&model.SubMenu{},
 		&model.SystemPath{},
 		&model.LogSMS{},
 		&model.LogLogin{},
 		&model.LogOplog{},
+		&model.LogSCIM{},
+		&model.LogSSOError{},
 		&model.SsoOAuthTicket{},
+		&model.SsoOAuthRefreshToken{},
+		&model.SsoOAuthDeviceCode{},
 		&model.SsoCASTicket{},
+		&model.SsoSAMLSession{},
 		&model.SsoNginxTicket{},
 		&model.ScheduledTask{},
 		&model.ScheduledTaskExecLog{},
@@ -72,6 +98,36 @@ func MySQLInit() error {
 		&model.DomainCertificate{},
 		&model.DomainCertificateMonitor{},
 		&k8s.Cluster{},
+		&model.SiteGuideStep{},
+		&model.LoginPolicy{},
+		&model.LoginTimePolicy{},
+		&model.Holiday{},
+		&model.StatLoginDaily{},
+		&model.StatUserDaily{},
+		&model.StatUserLoginDaily{},
+		&model.StatOplogDaily{},
+		&model.ComplianceReport{},
+		&model.LoginHook{},
+		&model.FeatureFlag{},
+		&model.UserDevice{},
+		&model.UserLoginCountry{},
+		&model.ProvisionRule{},
+		&model.SessionPolicy{},
+		&model.UserSession{},
+		&model.LandingRule{},
+		&model.ProvisionWebhook{},
+		&model.SettingsRevision{},
+		&model.Terms{},
+		&model.TermsVersion{},
+		&model.TermsAcceptance{},
+		&model.UserErasure{},
+		&model.ServiceClient{},
+		&model.UserConsent{},
+		&model.SigningKey{},
+		&model.AccessRequest{},
+		&model.AuditorGrant{},
+		&model.PendingUpload{},
+		&model.SiteGrant{},
 	)
 
 	// 设置数据库连接池
@@ -97,11 +153,6 @@ func MySQLInit() error {
 		return err
 	}
 
-	// 创建超级用户
-	if err := createSuperUser(client); err != nil {
-		return err
-	}
-
 	// 初始化定时任务
 	if err := InitializeScheduledTask(client); err != nil {
 		return err
@@ -112,25 +163,6 @@ func MySQLInit() error {
 	return nil
 }
 
-// 创建超级用户
-func createSuperUser(client *gorm.DB) error {
-	user := model.AuthUser{
-		Name:     "管理员",
-		Username: "admin",
-		IsActive: true,
-		Password: "admin@123...",
-	}
-
-	result := client.FirstOrCreate(&user, model.AuthUser{Username: "admin"})
-
-	if result.RowsAffected == 0 {
-		logger.Warn("admin用户已存在.")
-	} else {
-		logger.Info("admin用户创建成功.")
-	}
-	return nil
-}
-
 // 初始化站点信息
 func initializeSites(client *gorm.DB) error {
 	var count int64
@@ -237,6 +269,102 @@ func InitializeScheduledTask(client *gorm.DB) error {
 		return err
 	}
 
+	// 登录统计汇总任务，统计看板依赖该任务，已部署的环境也需要创建
+	rollupTask := model.ScheduledTask{
+		Name:          "登录统计汇总",
+		Type:          2,
+		CronExpr:      "5 * * * *",
+		BuiltInMethod: "login_stats_rollup",
+		Enabled:       true,
+	}
+	if err := client.FirstOrCreate(&rollupTask, model.ScheduledTask{BuiltInMethod: rollupTask.BuiltInMethod}).Error; err != nil {
+		return err
+	}
+
+	// 合规报告任务，需配置通知方式及接收人后启用
+	reportTask := model.ScheduledTask{
+		Name:          "合规报告",
+		Type:          2,
+		CronExpr:      "0 3 1 * *",
+		BuiltInMethod: "compliance_report",
+		Enabled:       false,
+	}
+	if err := client.FirstOrCreate(&reportTask, model.ScheduledTask{BuiltInMethod: reportTask.BuiltInMethod}).Error; err != nil {
+		return err
+	}
+
+	// 安全事件通知任务，通知方式及接收人即安全事件的接收人，需配置后启用；开启汇总模式时按周期汇总发送
+	securityTask := model.ScheduledTask{
+		Name:          "安全事件通知",
+		Type:          2,
+		CronExpr:      "0 * * * *",
+		BuiltInMethod: "security_event_notify",
+		Enabled:       false,
+	}
+	if err := client.FirstOrCreate(&securityTask, model.ScheduledTask{BuiltInMethod: securityTask.BuiltInMethod}).Error; err != nil {
+		return err
+	}
+
+	// SAML应用SP证书过期提醒任务，需配置通知方式及接收人后启用
+	spCertificateTask := model.ScheduledTask{
+		Name:          "SP证书过期提醒",
+		Type:          2,
+		CronExpr:      "0 9 * * *",
+		BuiltInMethod: "sp_certificate_expire_notify",
+		Enabled:       false,
+	}
+	if err := client.FirstOrCreate(&spCertificateTask, model.ScheduledTask{BuiltInMethod: spCertificateTask.BuiltInMethod}).Error; err != nil {
+		return err
+	}
+
+	// 告警通知任务，通知方式及接收人即内置告警的接收人，告警触发及恢复时立即通知，并按任务周期重复提醒未恢复的告警，需配置后启用
+	alertTask := model.ScheduledTask{
+		Name:          "告警通知",
+		Type:          2,
+		CronExpr:      "0 */4 * * *",
+		BuiltInMethod: "alert_notify",
+		Enabled:       false,
+	}
+	if err := client.FirstOrCreate(&alertTask, model.ScheduledTask{BuiltInMethod: alertTask.BuiltInMethod}).Error; err != nil {
+		return err
+	}
+
+	// 动态分组同步任务，定期根据用户属性重新计算动态分组成员，用户属性变更时也会实时计算
+	dynamicGroupTask := model.ScheduledTask{
+		Name:          "动态分组同步",
+		Type:          2,
+		CronExpr:      "30 * * * *",
+		BuiltInMethod: "dynamic_group_sync",
+		Enabled:       true,
+	}
+	if err := client.FirstOrCreate(&dynamicGroupTask, model.ScheduledTask{BuiltInMethod: dynamicGroupTask.BuiltInMethod}).Error; err != nil {
+		return err
+	}
+
+	// 站点授权到期撤销任务，撤销已到期的授权并通知应用负责人，未设置授权有效期时不做任何处理
+	siteGrantTask := model.ScheduledTask{
+		Name:          "站点授权到期撤销",
+		Type:          2,
+		CronExpr:      "15 * * * *",
+		BuiltInMethod: "site_grant_expire",
+		Enabled:       true,
+	}
+	if err := client.FirstOrCreate(&siteGrantTask, model.ScheduledTask{BuiltInMethod: siteGrantTask.BuiltInMethod}).Error; err != nil {
+		return err
+	}
+
+	// SP Metadata自动刷新任务，定时刷新配置了Metadata地址的SAML2站点的EntityID及SP证书，配置通知方式及接收人后通知变更及刷新失败
+	spMetadataTask := model.ScheduledTask{
+		Name:          "SP Metadata自动刷新",
+		Type:          2,
+		CronExpr:      "0 */6 * * *",
+		BuiltInMethod: "sp_metadata_refresh",
+		Enabled:       true,
+	}
+	if err := client.FirstOrCreate(&spMetadataTask, model.ScheduledTask{BuiltInMethod: spMetadataTask.BuiltInMethod}).Error; err != nil {
+		return err
+	}
+
 	if count > 0 {
 		return nil
 	}
@@ -269,20 +397,29 @@ func InitializeScheduledTask(client *gorm.DB) error {
 func InitConfig(client *gorm.DB) error {
 
 	// 加载所有设置项
-	settings := make(map[string]interface{})
+	values := make(map[string]interface{})
+	invalid := make(map[string]string)
 	var configItems []model.Settings
 	if err := client.Find(&configItems).Error; err != nil {
 		return err
 	}
 
-	// 保存配置项
+	// 解析配置项，解析失败的配置项在校验时一并返回
 	for _, item := range configItems {
 		if err := item.ParseValue(); err != nil {
+			invalid[item.Key] = err.Error()
 			continue
 		}
-		settings[item.Key] = item.ParsedValue
+		values[item.Key] = item.ParsedValue
 	}
 
+	// 校验配置项并补充默认值，可选配置项无效时使用默认值并记录警告，必须配置的配置项缺少或无效时返回错误
+	settings, err := config.LoadSettings(values, invalid)
 	config.Conf.Settings = settings
-	return nil
+	var settingsErr *config.SettingsError
+	if errors.As(err, &settingsErr) && !settingsErr.Fatal {
+		logger.Warn(err.Error())
+		return nil
+	}
+	return err
 }
@@ -1,18 +1,50 @@
 package db
 
 import (
+	"errors"
 	"github.com/go-redis/redis"
 	"github.com/wonderivan/logger"
+	"io"
+	"net"
 	"ops-api/config"
 	"ops-api/global"
+	"ops-api/utils/breaker"
+	"time"
+)
+
+const (
+	redisDialTimeout = 2 * time.Second // 连接超时时间，Redis不可用时尽快失败
+	redisIOTimeout   = time.Second     // 读写超时时间
 )
 
 // RedisInit Redis初始化
 func RedisInit() error {
 	client := redis.NewClient(&redis.Options{
-		Addr:     config.Conf.Redis.Host,
-		Password: config.Conf.Redis.Password,
-		DB:       config.Conf.Redis.DB,
+		Addr:         config.Conf.Redis.Host,
+		Password:     config.Conf.Redis.Password,
+		DB:           config.Conf.Redis.DB,
+		DialTimeout:  redisDialTimeout,
+		ReadTimeout:  redisIOTimeout,
+		WriteTimeout: redisIOTimeout,
+		PoolTimeout:  redisDialTimeout,
+		MaxRetries:   0,
+		Dialer:       redisDialer,
+	})
+
+	// 记录命令执行结果，Redis连续不可用时熔断，熔断期间不再建立连接，命令直接返回错误
+	client.WrapProcess(func(process func(cmd redis.Cmder) error) func(cmd redis.Cmder) error {
+		return func(cmd redis.Cmder) error {
+			err := process(cmd)
+			recordRedisResult(err)
+			return err
+		}
+	})
+	client.WrapProcessPipeline(func(process func(cmds []redis.Cmder) error) func(cmds []redis.Cmder) error {
+		return func(cmds []redis.Cmder) error {
+			err := process(cmds)
+			recordRedisResult(err)
+			return err
+		}
 	})
 
 	_, err := client.Ping().Result()
@@ -25,3 +57,28 @@ func RedisInit() error {
 
 	return nil
 }
+
+// redisDialer 建立Redis连接，熔断期间直接返回 breaker.ErrOpen
+func redisDialer() (net.Conn, error) {
+	if !global.RedisBreaker.Allow() {
+		return nil, breaker.ErrOpen
+	}
+	conn, err := net.DialTimeout("tcp", config.Conf.Redis.Host, redisDialTimeout)
+	if err != nil {
+		global.RedisBreaker.Failure(err)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// recordRedisResult 记录命令执行结果，仅网络错误视为Redis不可用，Key不存在及Redis返回的错误（如类型错误）说明Redis可用
+func recordRedisResult(err error) {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		global.RedisBreaker.Failure(err)
+		return
+	}
+	if !errors.Is(err, breaker.ErrOpen) {
+		global.RedisBreaker.Success()
+	}
+}
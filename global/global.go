@@ -7,6 +7,9 @@ import (
 	"github.com/robfig/cron/v3"
 	"gorm.io/gorm"
 	"ops-api/kubernetes"
+	"ops-api/utils/breaker"
+	"ops-api/utils/cache"
+	"time"
 )
 
 // 全局变量
@@ -17,4 +20,11 @@ var (
 	CasBinServer      *casbin.Enforcer
 	CornSchedule      *cron.Cron
 	KubernetesClients *kubernetes.Clients
+	Cache             cache.Cache
+)
+
+// 依赖服务熔断器，Redis、MinIO不可用时快速失败并降级
+var (
+	RedisBreaker = breaker.New("redis", 3, 10*time.Second)
+	MinioBreaker = breaker.New("minio", 3, 30*time.Second)
 )
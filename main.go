@@ -11,8 +11,13 @@ import (
 	"ops-api/kubernetes"
 	"ops-api/middleware"
 	"ops-api/service"
+	"ops-api/utils"
+	"strings"
 )
 
+// @title IDSphere 统一认证中心接口文档
+// @version v1
+// @description 接口响应中code为0时请求成功，90400为参数错误，90500为服务端错误
 func main() {
 
 	// 配置文件初始化
@@ -30,12 +35,29 @@ func main() {
 		return
 	}
 
-	// 初始 Redis
-	if err := db.RedisInit(); err != nil {
-		logger.Error("Redis初始化失败：", err.Error())
+	// 校验OIDC签发者及端点配置
+	if err := middleware.ValidateOIDCConfig(); err != nil {
+		logger.Error("OIDC配置校验失败：", err.Error())
 		return
 	}
 
+	// 加载密钥及证书，加载失败时在证书更新后重新加载
+	if err := utils.LoadKeyStore(); err != nil {
+		logger.Error("密钥加载失败：", err.Error())
+	}
+
+	// 初始化缓存（Redis、内存或Memcached）
+	if err := db.CacheInit(); err != nil {
+		logger.Error("缓存初始化失败：", err.Error())
+		return
+	}
+
+	// 订阅 Token 注销通知
+	middleware.TokenBlacklistInit()
+
+	// 订阅其它实例发布的管理控制台实时事件
+	service.ConsoleEventInit()
+
 	// 初始化 Kubernetes
 	global.KubernetesClients = &kubernetes.Clients{}
 	if err := global.KubernetesClients.KubernetesInit(global.MySQLClient); err != nil {
@@ -48,40 +70,141 @@ func main() {
 		return
 	}
 
+	// 首次启动时创建管理员及默认角色，未配置初始管理员密码时输出初始化令牌
+	if err := service.SetupInit(); err != nil {
+		logger.Error("系统初始化失败：", err.Error())
+	}
+
 	// 初始化定时任务
 	if err := service.TaskInit(); err != nil {
 		logger.Error("定时任务初始化失败：", err.Error())
 		return
 	}
 
+	// 将历史手机号标准化为E.164格式，失败不影响服务启动
+	if err := service.NormalizePhoneNumbers(); err != nil {
+		logger.Error("手机号标准化失败：", err.Error())
+	}
+
+	// 导入配置文件中声明的应用，失败不影响服务启动
+	if err := service.ImportBootstrap(); err != nil {
+		logger.Error("应用配置导入失败：", err.Error())
+	}
+
+	// 定时禁用超过使用时间窗口的应急账号
+	service.BreakGlassInit()
+
+	// 定时查询提交到外部ITSM中审批的访问申请，审批通过后自动授权
+	service.AccessRequestInit()
+
+	// 定时上传MinIO不可用期间暂存的头像
+	service.UploadQueueInit()
+
+	// 定时检查内置告警规则（登录失败激增、短信发送失败率、证书过期、定时任务未执行）
+	service.AlertInit()
+
+	// 初始化LDAP目录服务，启动失败不影响其它服务
+	if err := service.DirectoryInit(); err != nil {
+		logger.Error("LDAP目录服务初始化失败：", err.Error())
+	}
+
 	r := gin.Default()
 
+	// 仅信任可信代理转发的客户端IP（X-Forwarded-For、X-Real-IP），未配置时使用连接的来源地址，防止伪造请求头绕过网络限制及访问频率限制
+	var proxies []string
+	for _, proxy := range config.GetList("trustedProxies") {
+		proxies = append(proxies, strings.TrimSpace(proxy))
+	}
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		logger.Error("可信代理配置错误，使用连接的来源地址：", err.Error())
+		_ = r.SetTrustedProxies(nil)
+	}
+
 	// 加载跨域中间件
 	r.Use(middleware.Cors())
+	// 加载公开接口防护中间件，按配置的IP白名单限制接口访问，并对 Protect() 方法指定的公开接口限制访问频率，支持前缀匹配；
+	// ProtectStrict() 方法指定的凭据校验接口对可信网络同样使用公开接口的频率限制
+	r.Use(middleware.GuardBuilder().
+		ProtectStrict("/api/auth/login").
+		ProtectStrict("/api/v1/user/mfa_auth").
+		Protect("/api/auth/").
+		Protect("/api/v1/sms/").
+		Protect("/api/v1/reset_password").
+		Protect("/api/v1/secure_account").
+		Protect("/api/v1/user/mfa_qrcode").
+		Protect("/api/v1/user/required_actions").
+		Protect("/api/v1/sso/oauth/token").
+		Protect("/api/v1/sso/oauth/device_authorization").
+		Protect("/api/v1/sso/oidc/register").
+		Protect("/p3/serviceValidate").
+		Protect("/serviceValidate").
+		Protect("/validate").
+		Protect("/api/v1/sso/saml/slo").
+		Protect("/api/v1/site/directory").
+		Protect("/api/v1/setup").
+		Build())
 	// 加载登录中间件，其中 IgnorePaths() 方法可以忽略不需要登录认证的路由，支持前缀匹配
 	r.Use(middleware.LoginBuilder().
 		IgnorePaths("/api/auth/login").
 		IgnorePaths("/health").
 		IgnorePaths("/swagger/").
+		IgnorePaths("/openapi/").
 		IgnorePaths("/debug/pprof/").
 		IgnorePaths("/api/v1/sms/huawei/callback").
 		IgnorePaths("/api/v1/sms/reset_password").
 		IgnorePaths("/api/v1/reset_password").
+		IgnorePaths("/api/v1/secure_account").
 		IgnorePaths("/api/v1/user/mfa_qrcode").
 		IgnorePaths("/api/v1/user/mfa_auth").
+		IgnorePaths("/api/v1/user/required_actions").
 		IgnorePaths("/api/v1/sso/oauth/token").
+		IgnorePaths("/api/v1/sso/oauth/device_authorization").
 		IgnorePaths("/api/v1/sso/oauth/userinfo").
 		IgnorePaths("/p3/serviceValidate").
+		IgnorePaths("/serviceValidate").
+		IgnorePaths("/validate").
 		IgnorePaths("/api/v1/sso/saml/metadata").
 		IgnorePaths("/api/v1/sso/saml/post").
+		IgnorePaths("/api/v1/sso/saml/slo").
 		IgnorePaths("/api/v1/sso/saml/authorize").
+		IgnorePaths("/api/v1/sso/wsfed/authorize").
+		IgnorePaths("/FederationMetadata/2007-06/FederationMetadata.xml").
 		IgnorePaths("/.well-known/openid-configuration").
 		IgnorePaths("/api/v1/sso/oidc/jwks").
+		IgnorePaths("/api/v1/sso/oidc/register").
 		IgnorePaths("/api/v1/sso/cookie/auth").
 		IgnorePaths("/api/auth/dingtalk_login").
 		IgnorePaths("/api/auth/ww_login").
 		IgnorePaths("/api/auth/feishu_login").
 		IgnorePaths("/api/v1/site/guide").
+		IgnorePaths("/api/v1/site/directory").
+		IgnorePaths("/scim/v2/").
+		IgnorePaths("/api/v1/maintenance/status").
+		IgnorePaths("/api/v1/itsm/webhook").
+		IgnorePaths("/api/v1/setup").
+		Build())
+	// 加载维护模式中间件，维护期间仅管理员可以访问，其中 AllowPaths() 方法指定维护期间仍允许访问的路由（如登录、应用后端调用的接口），支持前缀匹配
+	r.Use(middleware.MaintenanceBuilder().
+		AllowPaths("/health").
+		AllowPaths("/api/v1/maintenance/status").
+		AllowPaths("/api/auth/").
+		AllowPaths("/api/v1/user/mfa_qrcode").
+		AllowPaths("/api/v1/user/mfa_auth").
+		AllowPaths("/api/v1/user/required_actions").
+		AllowPaths("/api/v1/secure_account").
+		AllowPaths("/api/v1/settings/site/logo").
+		AllowPaths("/api/v1/sso/oauth/token").
+		AllowPaths("/api/v1/sso/oauth/device_authorization").
+		AllowPaths("/api/v1/sso/oauth/userinfo").
+		AllowPaths("/p3/serviceValidate").
+		AllowPaths("/serviceValidate").
+		AllowPaths("/validate").
+		AllowPaths("/.well-known/openid-configuration").
+		AllowPaths("/api/v1/sso/oidc/jwks").
+		AllowPaths("/api/v1/sso/saml/metadata").
+		AllowPaths("/api/v1/sso/saml/slo").
+		AllowPaths("/FederationMetadata/").
+		AllowPaths("/api/v1/setup").
 		Build())
 	// 加载权限中间件
 	r.Use(middleware.PermissionCheck())
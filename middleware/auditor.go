@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"sync"
+	"time"
+)
+
+// 审计模式：有效期内的审计员只能调用只读（GET）接口，除自身权限允许的接口外，还可以查看 auditorCategories 中的接口；
+// 返回数据中的邮箱、手机号脱敏并移除密钥等敏感字段，审计员的查看操作均记录到操作日志中
+
+const (
+	auditorKey      = "auditor"        // 上下文中标识审计员请求的Key
+	auditorCacheTTL = 10 * time.Second // 审计员授权的本地缓存时间，其它实例授权或撤销后在缓存过期后生效
+)
+
+// auditorCategories 审计员可以查看的接口分类（system_path的menu_name）：用户、用户组及权限、站点、系统配置及审计日志
+var auditorCategories = []string{"UserManagement", "GroupManagement", "SiteManagement", "ConfManagement", "AuditLoginRecord", "AuditOplog", "AuditSMSRecord"}
+
+// 审计员查看的数据中需要脱敏的个人信息字段及需要移除的敏感字段（utils.FilterFields 过滤的字段之外）
+var (
+	auditorEmailFields   = map[string]bool{"email": true, "owner_email": true}
+	auditorPhoneFields   = map[string]bool{"phone_number": true, "phone_national": true, "phone_display": true, "phone": true}
+	auditorRemovedFields = map[string]bool{"secret": true, "privateKey": true, "private_key": true, "client_secret": true}
+)
+
+// auditorCache 审计员授权本地缓存，用户名 -> 到期时间
+var auditorCache struct {
+	mutex   sync.RWMutex
+	grants  map[string]time.Time
+	expires time.Time
+}
+
+// IsAuditor 判断用户是否为有效期内的审计员
+func IsAuditor(username string) bool {
+	auditorCache.mutex.RLock()
+	grants, expires := auditorCache.grants, auditorCache.expires
+	auditorCache.mutex.RUnlock()
+
+	if time.Now().After(expires) {
+		grants = ReloadAuditors()
+	}
+
+	expiresAt, ok := grants[username]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// ReloadAuditors 重新加载有效期内的审计员授权，授权或撤销审计员后调用，数据库不可用时继续使用已加载的授权
+func ReloadAuditors() map[string]time.Time {
+	auditorCache.mutex.Lock()
+	defer auditorCache.mutex.Unlock()
+
+	var items []*model.AuditorGrant
+	if err := global.MySQLClient.Where("revoked_at IS NULL AND expires_at > ?", time.Now()).Find(&items).Error; err != nil {
+		logger.Error("审计员授权加载失败：" + err.Error())
+		auditorCache.expires = time.Now().Add(auditorCacheTTL)
+		return auditorCache.grants
+	}
+
+	grants := make(map[string]time.Time, len(items))
+	for _, item := range items {
+		if item.ExpiresAt.After(grants[item.Username]) {
+			grants[item.Username] = item.ExpiresAt
+		}
+	}
+	auditorCache.grants = grants
+	auditorCache.expires = time.Now().Add(auditorCacheTTL)
+	return grants
+}
+
+// auditorPathAllowed 判断审计员是否可以查看该接口
+func auditorPathAllowed(path string) (bool, error) {
+
+	var paths []*model.SystemPath
+	if err := global.MySQLClient.Where("method = ? AND menu_name IN ?", http.MethodGet, auditorCategories).Find(&paths).Error; err != nil {
+		return false, err
+	}
+
+	// 与权限规则使用相同的匹配方式
+	for _, p := range paths {
+		if util.KeyMatch2(path, p.Path) || util.KeyMatch(path, p.Path) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// auditorWriter 缓存审计员请求的响应数据，脱敏后再返回
+type auditorWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *auditorWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *auditorWriter) WriteString(data string) (int, error) {
+	return w.body.WriteString(data)
+}
+
+// auditorNext 处理审计员请求，返回的JSON数据脱敏，非JSON数据（如文件下载）不返回
+func auditorNext(c *gin.Context) {
+
+	c.Set(auditorKey, true)
+	writer := &auditorWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	if writer.body.Len() == 0 {
+		return
+	}
+
+	header := writer.Header()
+	header.Del("Content-Length")
+
+	var data interface{}
+	decoder := json.NewDecoder(&writer.body)
+	decoder.UseNumber()
+	body, err := []byte(nil), decoder.Decode(&data)
+	if err == nil {
+		if item, ok := data.(map[string]interface{}); ok {
+			utils.FilterFields(item)
+		}
+		maskAuditorData(data)
+		body, err = json.Marshal(data)
+	}
+	if err != nil {
+		header.Del("Content-Disposition")
+		header.Set("Content-Type", "application/json; charset=utf-8")
+		body, _ = json.Marshal(gin.H{"code": 90403, "msg": "审计模式下不能下载文件"})
+	}
+
+	if _, err := writer.ResponseWriter.Write(body); err != nil {
+		logger.Error("审计员请求响应失败：" + err.Error())
+	}
+}
+
+// maskAuditorData 递归脱敏邮箱、手机号并移除密钥等敏感字段
+func maskAuditorData(data interface{}) {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		for key, item := range value {
+			if auditorRemovedFields[key] {
+				delete(value, key)
+				continue
+			}
+			if text, ok := item.(string); ok {
+				if auditorEmailFields[key] {
+					value[key] = utils.MaskEmail(text)
+				} else if auditorPhoneFields[key] {
+					value[key] = utils.MaskPhone(text)
+				}
+				continue
+			}
+			maskAuditorData(item)
+		}
+	case []interface{}:
+		for _, item := range value {
+			maskAuditorData(item)
+		}
+	}
+}
@@ -2,17 +2,13 @@ package middleware
 
 import (
 	"crypto/sha256"
-	"crypto/x509"
 	"encoding/base64"
 	"errors"
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/wonderivan/logger"
-	"net/http"
 	"ops-api/config"
-	"ops-api/global"
-	"ops-api/utils"
 	"strings"
 	"time"
 )
@@ -24,27 +20,47 @@ type Login struct {
 
 // UserClaims 保存需要保存到JWT中的信息结构体
 type UserClaims struct {
-	ID       uint   `json:"id"`
-	Name     string `json:"name"`
-	Username string `json:"username"`
+	ID        uint     `json:"id"`
+	Name      string   `json:"name"`
+	Username  string   `json:"username"`
+	SessionID string   `json:"sid,omitempty"` // 会话ID
+	AMR       []string `json:"amr,omitempty"` // 会话已完成的认证方式
 	jwt.RegisteredClaims
 }
 
 // OAuthClaims 保存需要保存到JWT中的信息结构体，适用于OAuth2认证
 type OAuthClaims struct {
-	ID                uint   `json:"id"`
-	Name              string `json:"name"`
-	Username          string `json:"username"`
-	PreferredUsername string `json:"preferred_username"`
-	Azp               string `json:"azp"`
-	Policy            string `json:"policy"`
-	Nonce             string `json:"nonce"`
+	ID                uint     `json:"id"`
+	Name              string   `json:"name"`
+	Username          string   `json:"username"`
+	PreferredUsername string   `json:"preferred_username"`
+	Azp               string   `json:"azp"`
+	Policy            string   `json:"policy"`
+	Nonce             string   `json:"nonce,omitempty"`
+	SessionID         string   `json:"sid,omitempty"` // 签发授权码时的用户会话ID
+	ACR               string   `json:"acr,omitempty"` // 认证等级
+	AMR               []string `json:"amr,omitempty"` // 认证方式
 	RealmAccess       struct {
 		Roles []string `json:"roles"`
 	} `json:"realm_access"`
+	AuthTime *jwt.NumericDate `json:"auth_time,omitempty"` // 用户完成认证的时间
+	AtHash   string           `json:"at_hash,omitempty"`   // 同时签发的Access Token的哈希值
+	CHash    string           `json:"c_hash,omitempty"`    // 同时签发的授权码的哈希值（Hybrid Flow）
 	jwt.RegisteredClaims
 }
 
+// OAuthAccessClaims 保存需要保存到Access Token中的信息结构体（RFC 9068），仅用于访问资源（如：userinfo接口），不包含用户的身份信息
+type OAuthAccessClaims struct {
+	ID        uint   `json:"id"`
+	ClientID  string `json:"client_id"`
+	Scope     string `json:"scope"`
+	SessionID string `json:"sid,omitempty"` // 签发授权码时的用户会话ID
+	jwt.RegisteredClaims
+}
+
+// Access Token的JWT类型（RFC 9068），用于区分ID Token及平台登录Token
+const accessTokenType = "at+jwt"
+
 func LoginBuilder() *Login {
 	return &Login{}
 }
@@ -66,14 +82,27 @@ func (l *Login) Build() gin.HandlerFunc {
 
 		// 获取Token
 		token := c.Request.Header.Get("Authorization")
+
+		// 内部服务使用服务令牌调用接口，权限由令牌的Scope决定
+		if IsServiceToken(token) {
+			sc, err := ValidateServiceToken(token)
+			if err != nil {
+				logger.Error("ERROR：", err)
+				AbortWithCode(c, 90514, err.Error())
+				return
+			}
+			c.Set("id", uint(0))
+			c.Set("name", sc.Name)
+			c.Set("username", ServiceUsernamePrefix+sc.ClientID)
+			c.Set(serviceScopeKey, sc.Scope)
+			c.Next()
+			return
+		}
+
 		mc, err := ValidateJWT(token)
 		if err != nil {
 			logger.Error("ERROR：", err)
-			c.JSON(http.StatusOK, gin.H{
-				"code": 90514,
-				"msg":  err.Error(),
-			})
-			c.Abort()
+			AbortWithCode(c, 90514, err.Error())
 			return
 		}
 
@@ -88,82 +117,168 @@ func (l *Login) Build() gin.HandlerFunc {
 
 // ValidateJWT 校验Token
 func ValidateJWT(token string) (mc *UserClaims, err error) {
+	raw, err := bearerToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Token解析
+	mc, err = ParseToken(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTokenRevoked(raw, mc.SessionID); err != nil {
+		return nil, err
+	}
+
+	return mc, nil
+}
+
+// ValidateAccessToken 校验OAuth2.0客户端使用的Access Token，ID Token及平台登录Token均无法通过校验
+func ValidateAccessToken(token string) (mc *OAuthAccessClaims, err error) {
+	raw, err := bearerToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Token解析
+	mc, err = ParseAccessToken(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkTokenRevoked(raw, mc.SessionID); err != nil {
+		return nil, err
+	}
+
+	return mc, nil
+}
+
+// bearerToken 从Authorization请求头中获取Token
+func bearerToken(token string) (string, error) {
 	// 如果Token为空，则表示未认证
 	if token == "" {
-		return nil, errors.New("未认证")
+		return "", errors.New("未认证")
 	}
 
 	// Token校验
 	// parts[0] == "token" 为了满足JumpServer进行OAuth2认证
 	parts := strings.SplitN(token, " ", 2)
 	if !(len(parts) == 2 && (parts[0] == "Bearer" || parts[0] == "token")) {
-		return nil, errors.New("token无效")
+		return "", errors.New("token无效")
 	}
 
-	// Token解析
-	mc, err = ParseToken(parts[1])
+	return parts[1], nil
+}
+
+// checkTokenRevoked 判断Token或Token所属会话是否已注销，Redis不可用时按系统配置 tokenRevocationFailOpen 处理：
+// 开启时跳过注销校验（已注销的Token在Redis恢复前仍可使用），否则拒绝所有请求
+func checkTokenRevoked(token, sessionId string) error {
+	revoked, err := isTokenOrSessionRevoked(token, sessionId)
 	if err != nil {
-		return nil, err
+		if config.GetBool("tokenRevocationFailOpen") {
+			logger.Warn("Token注销状态查询失败，已跳过注销校验：" + err.Error())
+			return nil
+		}
+		logger.Error("Token注销状态查询失败：" + err.Error())
+		return errors.New("服务暂不可用，请稍后重试")
 	}
+	return revoked
+}
 
-	// 判断Token是否已注销
-	val, err := global.RedisClient.Exists(parts[1]).Result()
+// isTokenOrSessionRevoked 查询Token或Token所属会话是否已注销，已注销时返回对应的错误
+// Token和会话的注销状态通过一次缓存请求查询
+func isTokenOrSessionRevoked(token, sessionId string) (revokedErr error, err error) {
+	keys := []string{token}
+	if sessionId != "" {
+		keys = append(keys, sessionRevokedKey(sessionId))
+	}
+
+	revoked, err := areTokensRevoked(keys...)
 	if err != nil {
 		return nil, err
 	}
-	if val == 1 {
-		return nil, errors.New("token无效")
+
+	// 判断Token是否已注销
+	if revoked[0] {
+		return errors.New("token无效"), nil
 	}
 
-	return mc, nil
+	// 判断Token所属会话是否已注销
+	if sessionId != "" && revoked[1] {
+		return errors.New("会话已失效，请重新登录"), nil
+	}
+
+	return nil, nil
 }
 
-// GenerateJWT 生成Token
-func GenerateJWT(id uint, name, username string) (string, error) {
-
-	var (
-		externalUrl      = config.Conf.Settings["externalUrl"].(string)
-		tokenExpiresTime = config.Conf.Settings["tokenExpiresTime"].(int)
-		privateKeySrt    = config.Conf.Settings["privateKey"].(string)
-	)
-
-	claims := UserClaims{
-		id,
-		name,
-		username,
-		jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(tokenExpiresTime) * time.Hour)), // 过期时间
-			IssuedAt:  jwt.NewNumericDate(time.Now()),                                                  // 签发时间
-			NotBefore: jwt.NewNumericDate(time.Now()),                                                  // 生效时间
-			Issuer:    externalUrl,                                                                     // 签发者
-		},
+// GenerateJWT 生成Token，每次生成Token时创建新的会话，amr 为本次登录使用的认证方式
+func GenerateJWT(id uint, name, username string, amr []string) (token, sessionId string, err error) {
+
+	tokenExpiresTime := config.SSO().TokenExpiresTime
+
+	sessionId = uuid.NewString()
+	token, err = signUserToken(UserClaims{ID: id, Name: name, Username: username, SessionID: sessionId, AMR: amr})
+	if err != nil {
+		return "", "", err
 	}
 
-	// 使用RS256签名算法生成Token
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	// 记录用户会话，Redis不可用且开启了 tokenRevocationFailOpen 时不影响登录，但会话无法强制下线
+	if err := addUserSession(id, sessionId, amr, time.Duration(tokenExpiresTime)*time.Hour); err != nil {
+		if !config.GetBool("tokenRevocationFailOpen") {
+			return "", "", err
+		}
+		logger.Warn("用户会话记录失败，会话无法强制下线：" + err.Error())
+	}
+
+	return token, sessionId, nil
+}
+
+// StepUpJWT 升级认证，为当前会话追加认证方式并重新签发Token，会话ID保持不变
+func StepUpJWT(mc *UserClaims, methods ...string) (string, error) {
+
+	if mc.SessionID == "" {
+		return "", errors.New("会话已失效，请重新登录")
+	}
 
-	// 私钥字符串转换为字节切片
-	privateKeyData := []byte(privateKeySrt)
+	tokenExpiresTime := config.SSO().TokenExpiresTime
 
-	// 解析私钥
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyData)
+	amr := mergeAMR(GetSessionAMR(mc.SessionID), methods...)
+	token, err := signUserToken(UserClaims{ID: mc.ID, Name: mc.Name, Username: mc.Username, SessionID: mc.SessionID, AMR: amr})
 	if err != nil {
 		return "", err
 	}
 
-	// 返回Token字符串（使用密钥签名）
-	return token.SignedString(privateKey)
+	// 更新会话认证方式，会话有效期从升级认证时重新计算
+	if err := addUserSession(mc.ID, mc.SessionID, amr, time.Duration(tokenExpiresTime)*time.Hour); err != nil {
+		return "", err
+	}
+
+	return token, nil
 }
 
-// GenerateOAuthToken 生成GenerateOAuthToken
-func GenerateOAuthToken(id uint, name, username, clientId, policy, nonce string) (string, error) {
+// signUserToken 签发用户Token
+func signUserToken(claims UserClaims) (string, error) {
 
-	var (
-		externalUrl      = config.Conf.Settings["externalUrl"].(string)
-		tokenExpiresTime = config.Conf.Settings["tokenExpiresTime"].(int)
-		privateKeySrt    = config.Conf.Settings["privateKey"].(string)
-	)
+	tokenExpiresTime := config.SSO().TokenExpiresTime
 
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(tokenExpiresTime) * time.Hour)), // 过期时间
+		IssuedAt:  jwt.NewNumericDate(time.Now()),                                                  // 签发时间
+		NotBefore: jwt.NewNumericDate(time.Now()),                                                  // 生效时间
+		Issuer:    OIDCIssuer(),                                                                    // 签发者
+	}
+
+	// 使用RS256签名算法生成Token（使用当前签名密钥签名）
+	return signToken(jwt.NewWithClaims(jwt.SigningMethodRS256, claims))
+}
+
+// GenerateOAuthToken 生成OIDC ID Token，subject 为用户在客户端中的sub标识，sessionId 为签发授权码时的用户会话ID，Token中的acr、amr及auth_time取自该会话；
+// accessToken、code 为同时签发的Access Token及授权码，不为空时ID Token中包含对应的at_hash、c_hash声明，ttl 为ID Token的有效期
+func GenerateOAuthToken(id uint, name, username, subject, clientId, policy, nonce, sessionId, accessToken, code string, ttl time.Duration) (string, error) {
+
+	amr := GetSessionAMR(sessionId)
 	claims := OAuthClaims{
 		ID:                id,
 		Name:              name,
@@ -172,83 +287,106 @@ func GenerateOAuthToken(id uint, name, username, clientId, policy, nonce string)
 		Azp:               clientId, // 授权谁给，通常是客户端ID
 		Policy:            policy,   // 授权的策略，具体看客户端定义
 		Nonce:             nonce,
+		SessionID:         sessionId,
+		ACR:               ACR(amr),
+		AMR:               amr,
 		RealmAccess: struct {
 			Roles []string `json:"roles"`
 		}{
 			Roles: []string{},
 		},
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(tokenExpiresTime) * time.Hour)), // 过期时间
-			IssuedAt:  jwt.NewNumericDate(time.Now()),                                                  // 签发时间
-			NotBefore: jwt.NewNumericDate(time.Now()),                                                  // 生效时间
-			Issuer:    externalUrl,                                                                     // 签发者
-			Audience:  []string{clientId},                                                              // 令牌的受众，这里返回客户端 ID
-			Subject:   fmt.Sprintf("user-%d", id),                                                      // 令牌主题，通常是用户的唯一标识符
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)), // 过期时间
+			IssuedAt:  jwt.NewNumericDate(time.Now()),          // 签发时间
+			NotBefore: jwt.NewNumericDate(time.Now()),          // 生效时间
+			Issuer:    OIDCIssuer(),                            // 签发者
+			Audience:  []string{clientId},                      // 令牌的受众，这里返回客户端 ID
+			Subject:   subject,                                 // 令牌主题，用户在客户端中的唯一标识符
 		},
 	}
+	if authTime := GetSessionAuthTime(sessionId); !authTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(authTime)
+	}
+	if accessToken != "" {
+		claims.AtHash = OIDCTokenHash(accessToken)
+	}
+	if code != "" {
+		claims.CHash = OIDCTokenHash(code)
+	}
 
-	// 使用RS256签名算法生成Token
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	// 私钥字符串转换为字节切片
-	privateKeyData := []byte(privateKeySrt)
+	return signToken(jwt.NewWithClaims(jwt.SigningMethodRS256, claims))
+}
 
-	// 解析私钥
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyData)
-	if err != nil {
-		return "", err
-	}
+// OIDCTokenHash 计算ID Token中at_hash、c_hash声明的值：使用与ID Token签名算法（RS256）对应的SHA-256计算哈希值，
+// 取左半部分进行base64url编码（不填充）
+func OIDCTokenHash(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
 
-	// 读取公钥文件
-	pubKey, err := utils.LoadPublicKey()
-	if err != nil {
-		return "", err
-	}
+// GenerateOAuthAccessToken 生成OAuth2.0 Access Token，scope 为本次授权授予的Scope，audience 为Token的受众（授予的资源或客户端ID），
+// ttl 为有效期，Token中不包含用户的身份信息
+func GenerateOAuthAccessToken(id uint, subject, clientId, scope, sessionId string, audience []string, ttl time.Duration) (string, error) {
 
-	// 将公钥转换为PKIX格式的字节
-	pubKeyBytes, err := x509.MarshalPKIXPublicKey(pubKey)
-	if err != nil {
-		return "", err
+	claims := OAuthAccessClaims{
+		ID:        id,
+		ClientID:  clientId,
+		Scope:     scope,
+		SessionID: sessionId,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)), // 过期时间
+			IssuedAt:  jwt.NewNumericDate(time.Now()),          // 签发时间
+			NotBefore: jwt.NewNumericDate(time.Now()),          // 生效时间
+			Issuer:    OIDCIssuer(),                            // 签发者
+			Audience:  audience,                                // 令牌的受众，授予了资源（RFC 8707）时为资源，否则为客户端 ID
+			Subject:   subject,                                 // 令牌主题，用户在客户端中的唯一标识符
+			ID:        uuid.NewString(),                        // 令牌ID
+		},
 	}
 
-	// 基于公钥内容生成kid
-	hash := sha256.Sum256(pubKeyBytes)
-	kid := base64.URLEncoding.EncodeToString(hash[:])
-
-	// 设置kid
-	token.Header["kid"] = kid
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["typ"] = accessTokenType
 
-	// 返回Token字符串（使用密钥签名）
-	return token.SignedString(privateKey)
+	return signToken(token)
 }
 
 // ParseToken 解析Token
 func ParseToken(tokenString string) (*UserClaims, error) {
-	var (
-		mc           = new(UserClaims)
-		publicKeySrt = config.Conf.Settings["publicKey"].(string)
-	)
-
-	// 公钥字符串转换为字节切片
-	publicKeyData := []byte(publicKeySrt)
+	var mc = new(UserClaims)
 
-	// 解析公钥
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyData)
+	// 根据kid选择公钥校验签名
+	token, err := jwt.ParseWithClaims(tokenString, mc, verificationKey, jwt.WithIssuer(OIDCIssuer()))
 	if err != nil {
 		return nil, err
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, mc, func(token *jwt.Token) (i interface{}, err error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, errors.New(fmt.Sprintf("unexpected signing method: %v", token.Header["alg"]))
-		}
-		return publicKey, nil
-	})
+	// 签发给OAuth2.0客户端的Access Token及ID Token不能用于访问平台接口
+	if token.Header["typ"] == accessTokenType || len(mc.Audience) > 0 {
+		return nil, errors.New("token无效")
+	}
+
+	// 对token对象中的Claim进行类型断言，校验Token
+	if token.Valid {
+		return mc, nil
+	}
+
+	return nil, errors.New("token无效")
+}
+
+// ParseAccessToken 解析Access Token，仅接受类型为at+jwt的Token
+func ParseAccessToken(tokenString string) (*OAuthAccessClaims, error) {
+	var mc = new(OAuthAccessClaims)
+
+	// 根据kid选择公钥校验签名
+	token, err := jwt.ParseWithClaims(tokenString, mc, verificationKey, jwt.WithIssuer(OIDCIssuer()))
 	if err != nil {
 		return nil, err
 	}
 
-	// 对token对象中的Claim进行类型断言，校验Token
+	if token.Header["typ"] != accessTokenType {
+		return nil, errors.New("token无效")
+	}
+
 	if token.Valid {
 		return mc, nil
 	}
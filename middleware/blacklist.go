@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"github.com/wonderivan/logger"
+	"ops-api/global"
+	"sync"
+	"time"
+)
+
+const (
+	tokenRevokedChannel     = "token_revoked"  // Token注销通知频道
+	tokenNegativeCacheTTL   = 5 * time.Second  // 未注销Token的本地缓存时间
+	tokenRevokedCacheTTL    = 24 * time.Hour   // 已注销Token在Redis及本地的保存时间
+	tokenLocalCacheMaxItems = 100000           // 本地缓存的最大条数，超过后清空重建
+	tokenCacheCleanInterval = 10 * time.Minute // 本地缓存过期数据清理间隔
+)
+
+// tokenBlacklist Token黑名单本地缓存，减少每次请求对Redis的访问
+type tokenBlacklist struct {
+	mutex    sync.RWMutex
+	valid    map[string]time.Time // 已确认未注销的Token及缓存过期时间
+	revoked  map[string]time.Time // 已注销的Token及过期时间
+	onceInit sync.Once
+}
+
+var blacklist = &tokenBlacklist{
+	valid:   make(map[string]time.Time),
+	revoked: make(map[string]time.Time),
+}
+
+// TokenBlacklistInit 订阅Token注销通知，其它实例注销Token后立即清除本地缓存
+func TokenBlacklistInit() {
+	blacklist.onceInit.Do(func() {
+		if err := global.Cache.Subscribe(tokenRevokedChannel, blacklist.markRevoked); err != nil {
+			logger.Error("订阅Token注销通知失败，连接恢复后自动重新订阅，在此之前其它实例注销的Token在本地缓存过期后生效：" + err.Error())
+		}
+		go blacklist.cleanup()
+	})
+}
+
+// RevokeToken 注销Token，写入缓存并通知所有实例
+func RevokeToken(token string) error {
+	if err := global.Cache.Set(token, "1", tokenRevokedCacheTTL); err != nil {
+		return err
+	}
+	if err := global.Cache.Publish(tokenRevokedChannel, token); err != nil {
+		return err
+	}
+
+	blacklist.markRevoked(token)
+	return nil
+}
+
+// IsTokenRevoked 判断Token是否已注销，优先使用本地缓存
+func IsTokenRevoked(token string) (bool, error) {
+	revoked, err := areTokensRevoked(token)
+	if err != nil {
+		return false, err
+	}
+	return revoked[0], nil
+}
+
+// areTokensRevoked 分别判断多个Token是否已注销，优先使用本地缓存，本地缓存未命中的Token通过一次缓存请求查询
+func areTokensRevoked(tokens ...string) ([]bool, error) {
+	now := time.Now()
+	revoked := make([]bool, len(tokens))
+
+	var missed []string
+	blacklist.mutex.RLock()
+	for i, token := range tokens {
+		if until, ok := blacklist.revoked[token]; ok && now.Before(until) {
+			revoked[i] = true
+			continue
+		}
+		if until, ok := blacklist.valid[token]; ok && now.Before(until) {
+			continue
+		}
+		missed = append(missed, token)
+	}
+	blacklist.mutex.RUnlock()
+
+	if len(missed) == 0 {
+		return revoked, nil
+	}
+
+	// 本地缓存未命中，查询缓存
+	exists, err := global.Cache.ExistsEach(missed...)
+	if err != nil {
+		return nil, err
+	}
+	found := make(map[string]bool, len(missed))
+	for i, token := range missed {
+		found[token] = exists[i]
+		if exists[i] {
+			blacklist.markRevoked(token)
+		} else {
+			blacklist.markValid(token, now.Add(tokenNegativeCacheTTL))
+		}
+	}
+	for i, token := range tokens {
+		if !revoked[i] {
+			revoked[i] = found[token]
+		}
+	}
+
+	return revoked, nil
+}
+
+// markValid 将Token标记为未注销，缓存至 until
+func (b *tokenBlacklist) markValid(token string, until time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if len(b.valid) >= tokenLocalCacheMaxItems {
+		b.valid = make(map[string]time.Time)
+	}
+	b.valid[token] = until
+}
+
+// markRevoked 将Token标记为已注销
+func (b *tokenBlacklist) markRevoked(token string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	delete(b.valid, token)
+	if len(b.revoked) >= tokenLocalCacheMaxItems {
+		b.revoked = make(map[string]time.Time)
+	}
+	b.revoked[token] = time.Now().Add(tokenRevokedCacheTTL)
+}
+
+// cleanup 定期清理本地缓存中已过期的数据
+func (b *tokenBlacklist) cleanup() {
+	ticker := time.NewTicker(tokenCacheCleanInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		now := time.Now()
+		b.mutex.Lock()
+		for token, until := range b.valid {
+			if now.After(until) {
+				delete(b.valid, token)
+			}
+		}
+		for token, until := range b.revoked {
+			if now.After(until) {
+				delete(b.revoked, token)
+			}
+		}
+		b.mutex.Unlock()
+	}
+}
@@ -50,6 +50,22 @@ func PermissionCheck() gin.HandlerFunc {
 		// 请求访问
 		method := c.Request.Method
 
+		// 服务令牌仅能调用令牌Scope中的接口
+		if scope, ok := c.Get(serviceScopeKey); ok {
+			allowed, err := serviceScopeAllowed(scope.(string), path, method)
+			if err != nil {
+				logger.Error("ERROR：", err.Error())
+				AbortWithCode(c, 90500, err.Error())
+				return
+			}
+			if !allowed {
+				AbortWithCode(c, 90403, "该资源您无权访问")
+				return
+			}
+			c.Next()
+			return
+		}
+
 		// 排除不需要权限验证的接口，支持前缀匹配
 		ignorePath := []string{
 			"/api/auth/login",                   // 账号密码登录接口
@@ -60,25 +76,52 @@ func PermissionCheck() gin.HandlerFunc {
 			"/health",                           // 预留健身检查接口
 			"/api/v1/user/info",                 // 用户登录成功后获取用户信息接口
 			"/api/v1/user/avatarUpload",         // 用户头像上传接口
+			"/api/v1/user/avatarUploadUrl",      // 获取头像临时上传链接
+			"/api/v1/user/avatar",               // 头像更新
+			"/api/v1/user/features",             // 获取对当前用户开放的功能
+			"/api/v1/user/language",             // 设置首选语言
+			"/api/v1/user/timezone",             // 设置时区
+			"/api/v1/user/personal_data",        // 导出当前用户的个人数据
+			"/api/v1/user/devices",              // 获取当前用户的登录设备
+			"/api/v1/user/device/",              // 删除当前用户的登录设备
+			"/api/v1/user/sessions",             // 获取当前用户的会话
+			"/api/v1/user/landing",              // 获取登录后跳转地址
+			"/api/v1/user/terms/accept",         // 接受使用条款
+			"/api/v1/user/consents",             // 获取当前用户的应用授权记录
+			"/api/v1/user/consent/",             // 撤销当前用户对应用的授权
+			"/api/v1/user/access_request",       // 提交及获取当前用户的访问申请
 			"/swagger/",                         // Swagger 接口
+			"/openapi/",                         // OpenAPI 接口文档
 			"/debug/pprof/",                     // pprof 相关接口
 			"/api/v1/settings/site/logo",        // 获取 Logo
 			"/api/v1/sms/huawei/callback",       // 华为云短信回调接口
 			"/api/v1/sms/reset_password",        // 获取重置密码验证码
 			"/api/v1/reset_password",            // 密码自助重置接口
+			"/api/v1/secure_account",            // 保护账号（登录提醒邮件）
 			"/api/v1/user/mfa_qrcode",           // 获取 MFA 二维码
 			"/api/v1/user/mfa_auth",             // MFA 认证
+			"/api/v1/user/required_actions",     // 首次登录需要完成的操作
+			"/api/v1/user/step_up",              // 升级认证
 			"/api/v1/site/logoUpload",           // 站点图片上传
 			"/api/v1/site/guide",                // 获取导航站点信息
+			"/api/v1/site/directory",            // 获取公开应用目录
+			"/api/v1/maintenance/status",        // 获取维护模式状态
 			"/p3/serviceValidate",               // CAS3.0 票据校验
+			"/serviceValidate",                  // CAS2.0 票据校验
+			"/validate",                         // CAS1.0 票据校验
 			"/api/v1/sso/",                      // 单点登录相关接口
 			"/.well-known/openid-configuration", // OIDC 配置
 			"/api/v1/sso/oidc/jwks",             // OIDC JWKS 配置
 			"/api/v1/sso/cookie/auth",           // Cookie 认证
+			"/FederationMetadata/",              // WS-Fed 联合元数据
 			"/api/v1/audit/sms/receipt",         // 获取短信回执
 			"/api/v1/tag/list",                  // 获取标签列表
 			"/api/v1/account",                   // 账号管理相关接口
 			"/api/v1/url/check",                 // 站点 HTTPS 检测
+			"/api/v1/guide/steps",               // 获取当前用户可见的引导步骤
+			"/scim/v2/",                         // SCIM 用户同步接口
+			"/api/v1/itsm/webhook",              // ITSM审批结果通知
+			"/api/v1/setup",                     // 系统初始化
 		}
 		for _, item := range ignorePath {
 			if strings.HasPrefix(path, item) {
@@ -87,23 +130,36 @@ func PermissionCheck() gin.HandlerFunc {
 			}
 		}
 
+		// 审计员只能调用只读接口，可以查看审计范围内的接口
+		auditor := IsAuditor(c.GetString("username"))
+		if auditor {
+			if method != http.MethodGet {
+				AbortWithCode(c, 90403, "审计模式下只能查看，不能修改")
+				return
+			}
+			allowed, err := auditorPathAllowed(path)
+			if err != nil {
+				logger.Error("ERROR：", err.Error())
+				AbortWithCode(c, 90500, err.Error())
+				return
+			}
+			if allowed {
+				auditorNext(c)
+				return
+			}
+		}
+
 		// 检查用户权限
 		ok, err := global.CasBinServer.Enforce(username, path, method)
 		if err != nil {
 			logger.Error("ERROR：", err.Error())
-			c.JSON(http.StatusOK, gin.H{
-				"code": 90500,
-				"msg":  err.Error(),
-			})
-			c.Abort()
+			AbortWithCode(c, 90500, err.Error())
 			return
 		} else if !ok {
-			c.JSON(http.StatusOK, gin.H{
-				"code": 90403,
-				"msg":  "该资源您无权访问",
-			})
-			c.Abort()
+			AbortWithCode(c, 90403, "该资源您无权访问")
 			return
+		} else if auditor {
+			auditorNext(c)
 		} else {
 			c.Next()
 		}
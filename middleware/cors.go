@@ -1,25 +1,215 @@
 package middleware
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"net/http"
+	"net/url"
+	"ops-api/config"
+	"strconv"
+	"strings"
+	"sync"
 )
 
+// 跨域策略：系统配置 corsPolicies 为JSON数组，按接口路径前缀（如管理接口 /api/、公开的单点登录接口 /api/v1/sso/）分别配置
+// 允许的来源、方法、请求头、是否允许携带凭证及预检请求缓存时间，按顺序使用第一个匹配的策略；
+// 来源支持通配子域名（如：https://*.example.com），未配置或没有匹配的策略时允许所有来源
+
+const (
+	corsDefaultMethods = "POST, GET, OPTIONS, PUT, DELETE, UPDATE, PATCH"
+	corsDefaultHeaders = "X-Token, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Max"
+	corsDefaultMaxAge  = 86400 // 预检请求默认缓存时间（秒）
+)
+
+// CorsPolicy 跨域策略
+type CorsPolicy struct {
+	Paths         []string `json:"paths"`          // 接口路径前缀，为空时匹配所有接口
+	Origins       []string `json:"origins"`        // 允许的来源，* 为所有来源，支持通配子域名
+	Methods       []string `json:"methods"`        // 允许的方法，为空时使用默认值
+	Headers       []string `json:"headers"`        // 允许的请求头，为空时使用默认值
+	ExposeHeaders []string `json:"expose_headers"` // 允许浏览器读取的响应头
+	Credentials   bool     `json:"credentials"`    // 是否允许携带凭证（Cookie），允许时来源不能为 *
+	MaxAge        *int     `json:"max_age"`        // 预检请求缓存时间（秒），为空时为86400秒
+}
+
+// corsPolicyCache 已解析的跨域策略，配置变化时重新解析
+var corsPolicyCache struct {
+	sync.RWMutex
+	raw      string
+	policies []*CorsPolicy
+}
+
+// defaultCorsPolicy 未配置跨域策略时允许所有来源
+var defaultCorsPolicy = &CorsPolicy{Origins: []string{"*"}}
+
 // Cors 处理跨域请求
 func Cors() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 获取请求方法
-		method := c.Request.Method
-		// 添加跨域响应头
+
 		c.Header("Content-Type", "application/json")
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Max-Age", "86400")
-		c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE, UPDATE, PATCH")
-		c.Header("Access-Control-Allow-Headers", "X-Token, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-Max")
-		c.Header("Access-Control-Allow-Credentials", "false")
-		// 放行所有OPTIONS方法
-		if method == "OPTIONS" {
+
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions
+		if origin == "" {
+			// 非跨域请求，放行所有OPTIONS方法
+			if preflight {
+				c.AbortWithStatus(http.StatusNoContent)
+			}
+			return
+		}
+
+		policy := matchCorsPolicy(c.Request.URL.Path)
+		if !policy.allowOrigin(origin) {
+			// 来源不允许时不返回跨域响应头，由浏览器拦截响应
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+			}
+			return
+		}
+
+		if policy.Credentials || !policy.anyOrigin() {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		} else {
+			c.Header("Access-Control-Allow-Origin", "*")
+		}
+		c.Header("Access-Control-Allow-Credentials", strconv.FormatBool(policy.Credentials))
+		if len(policy.ExposeHeaders) > 0 {
+			c.Header("Access-Control-Expose-Headers", strings.Join(policy.ExposeHeaders, ", "))
+		}
+
+		if preflight {
+			methods, headers, maxAge := corsDefaultMethods, corsDefaultHeaders, corsDefaultMaxAge
+			if len(policy.Methods) > 0 {
+				methods = strings.Join(policy.Methods, ", ")
+			}
+			if len(policy.Headers) > 0 {
+				headers = strings.Join(policy.Headers, ", ")
+			}
+			if policy.MaxAge != nil {
+				maxAge = *policy.MaxAge
+			}
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			c.Header("Access-Control-Max-Age", strconv.Itoa(maxAge))
 			c.AbortWithStatus(http.StatusNoContent)
 		}
 	}
 }
+
+// ParseCorsPolicies 解析并校验跨域策略配置
+func ParseCorsPolicies(raw string) ([]*CorsPolicy, error) {
+
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+
+	var policies []*CorsPolicy
+	if err := json.Unmarshal([]byte(raw), &policies); err != nil {
+		return nil, errors.New("跨域策略格式错误，应为JSON数组")
+	}
+	for i, policy := range policies {
+		if policy == nil || len(policy.Origins) == 0 {
+			return nil, fmt.Errorf("第%d条跨域策略未配置允许的来源", i+1)
+		}
+		for _, path := range policy.Paths {
+			if !strings.HasPrefix(path, "/") {
+				return nil, fmt.Errorf("跨域策略的接口路径必须以/开头：%s", path)
+			}
+		}
+		for _, origin := range policy.Origins {
+			if origin == "*" {
+				if policy.Credentials {
+					return nil, fmt.Errorf("第%d条跨域策略允许携带凭证，来源不能为*", i+1)
+				}
+				continue
+			}
+			u, err := url.Parse(origin)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+				return nil, fmt.Errorf("跨域策略的来源格式错误：%s", origin)
+			}
+			if strings.Contains(strings.TrimPrefix(u.Host, "*."), "*") {
+				return nil, fmt.Errorf("跨域策略的来源仅支持通配子域名：%s", origin)
+			}
+		}
+		for _, method := range policy.Methods {
+			if method == "" || strings.ToUpper(method) != method {
+				return nil, fmt.Errorf("跨域策略的方法必须为大写：%s", method)
+			}
+		}
+		if policy.MaxAge != nil && *policy.MaxAge < 0 {
+			return nil, fmt.Errorf("第%d条跨域策略的预检请求缓存时间不能小于0", i+1)
+		}
+	}
+	return policies, nil
+}
+
+// matchCorsPolicy 获取接口路径匹配的第一个跨域策略，配置错误时使用默认策略
+func matchCorsPolicy(path string) *CorsPolicy {
+	for _, policy := range corsPolicies() {
+		if len(policy.Paths) == 0 {
+			return policy
+		}
+		for _, prefix := range policy.Paths {
+			if strings.HasPrefix(path, prefix) {
+				return policy
+			}
+		}
+	}
+	return defaultCorsPolicy
+}
+
+// corsPolicies 获取已解析的跨域策略
+func corsPolicies() []*CorsPolicy {
+
+	raw := config.GetString("corsPolicies")
+
+	corsPolicyCache.RLock()
+	if raw == corsPolicyCache.raw {
+		defer corsPolicyCache.RUnlock()
+		return corsPolicyCache.policies
+	}
+	corsPolicyCache.RUnlock()
+
+	policies, err := ParseCorsPolicies(raw)
+	if err != nil {
+		policies = nil
+	}
+
+	corsPolicyCache.Lock()
+	defer corsPolicyCache.Unlock()
+	corsPolicyCache.raw, corsPolicyCache.policies = raw, policies
+	return policies
+}
+
+// anyOrigin 是否允许所有来源
+func (p *CorsPolicy) anyOrigin() bool {
+	for _, item := range p.Origins {
+		if item == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allowOrigin 判断来源是否允许，通配子域名（https://*.example.com）匹配任意层级的子域名，不匹配 example.com 本身
+func (p *CorsPolicy) allowOrigin(origin string) bool {
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	for _, item := range p.Origins {
+		item = strings.ToLower(strings.TrimSuffix(item, "/"))
+		if item == "*" || item == origin {
+			return true
+		}
+		scheme, pattern, found := strings.Cut(item, "://*.")
+		if !found {
+			continue
+		}
+		prefix := scheme + "://"
+		if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, "."+pattern) && len(origin) > len(prefix)+len(pattern)+1 {
+			return true
+		}
+	}
+	return false
+}
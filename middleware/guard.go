@@ -0,0 +1,233 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"net"
+	"ops-api/config"
+	"ops-api/global"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Guard 保存需要限制访问频率的公开接口
+type Guard struct {
+	rules []rateRule
+}
+
+// rateRule 访问频率限制规则，strict 为true时可信网络也使用公开接口的频率限制
+type rateRule struct {
+	path   string
+	strict bool
+}
+
+// allowRule 接口IP白名单规则
+type allowRule struct {
+	path     string
+	networks []*net.IPNet
+}
+
+// allowRuleCache 已解析的接口IP白名单，配置变化时重新解析，避免每次请求重复解析
+var allowRuleCache struct {
+	mutex sync.RWMutex
+	items []string
+	rules []allowRule
+}
+
+// networkCache 已解析的IP或网段配置（配置项 -> 已解析的网段），配置变化时重新解析
+var networkCache = struct {
+	mutex sync.RWMutex
+	items map[string]cachedNetworks
+}{items: make(map[string]cachedNetworks)}
+
+type cachedNetworks struct {
+	items    []string
+	networks []*net.IPNet
+}
+
+func GuardBuilder() *Guard {
+	return &Guard{}
+}
+
+// Protect 保存需要限制访问频率的URL到结构体，支持前缀匹配
+func (g *Guard) Protect(path string) *Guard {
+	g.rules = append(g.rules, rateRule{path: path})
+	return g
+}
+
+// ProtectStrict 保存需要限制访问频率且不对可信网络放宽限制的URL（如账号密码登录），支持前缀匹配，需在匹配范围更大的 Protect() 之前调用
+func (g *Guard) ProtectStrict(path string) *Guard {
+	g.rules = append(g.rules, rateRule{path: path, strict: true})
+	return g
+}
+
+// Build 公开接口防护：配置了IP白名单的接口仅允许白名单内的地址访问，公开接口按客户端IP限制每分钟的请求次数，可信网络使用单独的频率限制；
+// 客户端IP仅从可信代理（trustedProxies）转发的请求头中获取，未配置可信代理时为连接的来源地址
+func (g *Guard) Build() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		path := c.Request.URL.Path
+		clientIP := net.ParseIP(c.ClientIP())
+
+		// IP白名单
+		for _, rule := range endpointAllowRules() {
+			if strings.HasPrefix(path, rule.path) && !ipAllowed(clientIP, rule.networks) {
+				logger.Warn(fmt.Sprintf("IP（%s）不在接口（%s）的访问白名单中", c.ClientIP(), path))
+				AbortWithCode(c, 90403, "IP不在访问白名单中")
+				return
+			}
+		}
+
+		// 访问频率限制
+		for _, item := range g.rules {
+			if strings.HasPrefix(path, item.path) {
+				if allowed, retryAfter := rateAllowed(item, c.ClientIP()); !allowed {
+					SetRetryAfter(c, retryAfter)
+					AbortWithCode(c, 90429, "请求过于频繁，请稍后再试")
+					return
+				}
+				break
+			}
+		}
+	}
+}
+
+// endpointAllowRules 获取接口IP白名单规则，配置未变化时使用已解析的规则
+func endpointAllowRules() []allowRule {
+
+	items := config.GetList("endpointAllowlist")
+
+	allowRuleCache.mutex.RLock()
+	cached, rules := slices.Equal(allowRuleCache.items, items), allowRuleCache.rules
+	allowRuleCache.mutex.RUnlock()
+	if cached {
+		return rules
+	}
+
+	rules = parseAllowRules(items)
+	allowRuleCache.mutex.Lock()
+	allowRuleCache.items, allowRuleCache.rules = items, rules
+	allowRuleCache.mutex.Unlock()
+	return rules
+}
+
+// parseAllowRules 解析接口IP白名单配置，每条规则格式为：接口路径=IP或网段,IP或网段
+func parseAllowRules(items []string) []allowRule {
+
+	var rules []allowRule
+	for _, item := range items {
+		parts := strings.SplitN(item, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		rule := allowRule{path: strings.TrimSpace(parts[0])}
+		for _, cidr := range strings.Split(parts[1], ",") {
+			if network := parseNetwork(strings.TrimSpace(cidr)); network != nil {
+				rule.networks = append(rule.networks, network)
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// IsTrustedNetwork 判断客户端IP是否属于可信网络（如办公网、VPN），可信网络使用单独的访问频率限制
+func IsTrustedNetwork(clientIP string) bool {
+	return InConfigNetworks(clientIP, "trustedNetworks")
+}
+
+// InConfigNetworks 判断客户端IP是否属于配置项中指定的IP或网段
+func InConfigNetworks(clientIP, key string) bool {
+	networks := configNetworks(key)
+	if len(networks) == 0 {
+		return false
+	}
+	return ipAllowed(net.ParseIP(clientIP), networks)
+}
+
+// configNetworks 获取配置项中已解析的IP或网段，配置未变化时使用已解析的网段
+func configNetworks(key string) []*net.IPNet {
+
+	items := config.GetList(key)
+
+	networkCache.mutex.RLock()
+	cached, ok := networkCache.items[key]
+	networkCache.mutex.RUnlock()
+	if ok && slices.Equal(cached.items, items) {
+		return cached.networks
+	}
+
+	var networks []*net.IPNet
+	for _, item := range items {
+		if network := parseNetwork(strings.TrimSpace(item)); network != nil {
+			networks = append(networks, network)
+		}
+	}
+	networkCache.mutex.Lock()
+	networkCache.items[key] = cachedNetworks{items: items, networks: networks}
+	networkCache.mutex.Unlock()
+	return networks
+}
+
+// parseNetwork 解析IP或网段，单个IP视为掩码全为1的网段
+func parseNetwork(cidr string) *net.IPNet {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return nil
+		}
+		bits := 8 * len(ip.To16())
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil
+	}
+	return network
+}
+
+// ipAllowed 判断IP是否在白名单中
+func ipAllowed(ip net.IP, networks []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateAllowed 固定窗口计数，判断客户端IP在当前分钟内对接口的请求次数是否超过限制，超过时同时返回距下一个窗口的时间，Redis异常时放行
+func rateAllowed(rule rateRule, clientIP string) (bool, time.Duration) {
+
+	path := rule.path
+	limit := config.GetInt("publicRateLimit")
+	if !rule.strict && IsTrustedNetwork(clientIP) {
+		limit = config.GetInt("trustedRateLimit")
+	}
+	if limit <= 0 {
+		return true, 0
+	}
+
+	now := time.Now()
+	key := fmt.Sprintf("rate_limit:%s:%s:%d", path, clientIP, now.Unix()/60)
+	count, err := global.Cache.Incr(key, time.Minute)
+	if err != nil {
+		logger.Error("ERROR：访问频率统计失败，", err.Error())
+		return true, 0
+	}
+
+	if count > int64(limit) {
+		logger.Warn(fmt.Sprintf("IP（%s）访问接口（%s）过于频繁", clientIP, path))
+		return false, now.Truncate(time.Minute).Add(time.Minute).Sub(now)
+	}
+	return true, 0
+}
@@ -10,12 +10,84 @@ import (
 	"net/http"
 	"ops-api/model"
 	"ops-api/utils"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
+const (
+	oplogQueueSize     = 10000           // 日志队列长度，队列满时丢弃新日志，避免阻塞请求
+	oplogBatchSize     = 200             // 单次批量写入的最大条数
+	oplogFlushInterval = 2 * time.Second // 日志未达到批量条数时的最长写入间隔
+)
+
+// oplogWriter 操作日志异步批量写入
+type oplogWriter struct {
+	db      *gorm.DB
+	queue   chan *model.LogOplog
+	dropped uint64 // 队列满时丢弃的日志条数
+}
+
+// newOplogWriter 创建操作日志写入器并启动后台写入协程
+func newOplogWriter(db *gorm.DB) *oplogWriter {
+	w := &oplogWriter{
+		db:    db,
+		queue: make(chan *model.LogOplog, oplogQueueSize),
+	}
+	go w.run()
+	return w
+}
+
+// push 将日志放入队列，队列已满时直接丢弃
+func (w *oplogWriter) push(log *model.LogOplog) {
+	// 使用请求完成的时间作为日志时间，而不是批量写入的时间
+	log.CreatedAt = time.Now()
+
+	select {
+	case w.queue <- log:
+	default:
+		atomic.AddUint64(&w.dropped, 1)
+	}
+}
+
+// run 按批量条数或时间间隔将日志写入数据库
+func (w *oplogWriter) run() {
+	ticker := time.NewTicker(oplogFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*model.LogOplog, 0, oplogBatchSize)
+	for {
+		select {
+		case log := <-w.queue:
+			batch = append(batch, log)
+			if len(batch) >= oplogBatchSize {
+				batch = w.flush(batch)
+			}
+		case <-ticker.C:
+			batch = w.flush(batch)
+		}
+	}
+}
+
+// flush 批量写入日志，返回清空后的切片
+func (w *oplogWriter) flush(batch []*model.LogOplog) []*model.LogOplog {
+	if dropped := atomic.SwapUint64(&w.dropped, 0); dropped > 0 {
+		logger.Warn("操作日志队列已满，丢弃日志%d条", dropped)
+	}
+	if len(batch) == 0 {
+		return batch
+	}
+	if err := w.db.CreateInBatches(batch, oplogBatchSize).Error; err != nil {
+		logger.Warn("保存操作日志失败: %v", err)
+	}
+	return batch[:0]
+}
+
 // ExcludedPaths 不记录日志的接口
 var ExcludedPaths = map[string]bool{
 	"/api/auth/login":                   true,
 	"/api/v1/sso/saml/post":             true,
+	"/api/v1/sso/saml/slo":              true,
 	"/api/auth/logout":                  true,
 	"/api/auth/ww_login":                true,
 	"/api/auth/dingtalk_login":          true,
@@ -24,10 +96,13 @@ var ExcludedPaths = map[string]bool{
 	"/api/v1/user/sync/ad":              true,
 	"/api/v1/reset_password":            true,
 	"/api/v1/site/logoUpload":           true,
+	"/api/v1/guide/imageUpload":         true,
+	"/api/v1/import/keycloak":           true,
 	"/api/v1/sms/huawei/callback":       true,
 	"/api/v1/sms/reset_password":        true,
 	"/api/v1/user/mfa_qrcode":           true,
 	"/api/v1/user/mfa_auth":             true,
+	"/api/v1/user/required_actions":     true,
 	"/api/v1/sso/oauth/authorize":       true,
 	"/api/v1/sso/oauth/token":           true,
 	"/api/v1/sso/oauth/userinfo":        true,
@@ -37,13 +112,16 @@ var ExcludedPaths = map[string]bool{
 	"/api/v1/account/code_verification": true,
 }
 
-// Oplog 操作日志
+// Oplog 操作日志（异步批量写入数据库）
 func Oplog(db *gorm.DB) gin.HandlerFunc {
+	writer := newOplogWriter(db)
+
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
 
-		// 只记录POST、PUT和DELETE请求
-		if c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPut && c.Request.Method != http.MethodDelete {
+		// 只记录POST、PUT和DELETE请求，审计员的查看操作也需要记录
+		auditor := c.GetBool(auditorKey)
+		if !auditor && c.Request.Method != http.MethodPost && c.Request.Method != http.MethodPut && c.Request.Method != http.MethodDelete {
 			c.Next()
 			return
 		}
@@ -67,6 +145,15 @@ func Oplog(db *gorm.DB) gin.HandlerFunc {
 			utils.FilterFields(requestData)
 		}
 
+		// 审计员的查看操作记录查询参数
+		if auditor && c.Request.Method == http.MethodGet {
+			requestData = make(map[string]interface{})
+			for key, values := range c.Request.URL.Query() {
+				requestData[key] = strings.Join(values, ",")
+			}
+			utils.FilterFields(requestData)
+		}
+
 		// 将请求参数转换为JSON字符串
 		requestDataStr, err := json.Marshal(requestData)
 		if err != nil {
@@ -76,8 +163,12 @@ func Oplog(db *gorm.DB) gin.HandlerFunc {
 		// 获取客户端信息
 		clientIP := c.ClientIP()
 		userAgent := c.Request.UserAgent()
-		// 获取当前登录用户的用户名
-		username, _ := c.Get("username")
+		// 获取当前登录用户的用户名，未经过登录认证的请求（如SCIM）不记录操作日志
+		username, ok := c.Get("username")
+		if !ok {
+			c.Next()
+			return
+		}
 
 		// 执行请求
 		c.Next()
@@ -106,7 +197,7 @@ func Oplog(db *gorm.DB) gin.HandlerFunc {
 		}
 
 		// 记录操作日志
-		log := model.LogOplog{
+		log := &model.LogOplog{
 			Username:      username.(string),
 			Endpoint:      path,
 			Method:        c.Request.Method,
@@ -116,9 +207,7 @@ func Oplog(db *gorm.DB) gin.HandlerFunc {
 			UserAgent:     userAgent,
 		}
 
-		// 将日志保存到数据库
-		if err := db.Create(&log).Error; err != nil {
-			logger.Warn("保存操作日志失败: %v", err)
-		}
+		// 将日志放入队列，由后台协程批量保存到数据库
+		writer.push(log)
 	}
 }
@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"ops-api/global"
+	"ops-api/utils/cache"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maintenanceKey           = "maintenance_mode"    // 维护模式在Redis中的Key
+	maintenanceCacheTTL      = 3 * time.Second       // 维护模式状态的本地缓存时间
+	maintenanceAdminPath     = "/api/v1/maintenance" // 有权限设置维护模式的用户视为管理员，维护期间仍可登录及访问
+	MaintenanceDefaultNotice = "系统维护中，请稍后再试"         // 未配置提示信息时使用的默认提示
+)
+
+// MaintenanceStatus 维护模式状态
+type MaintenanceStatus struct {
+	Enabled   bool       `json:"enabled"`
+	Message   string     `json:"message"`    // 返回给用户的提示信息
+	Operator  string     `json:"operator"`   // 开启维护模式的管理员
+	StartedAt *time.Time `json:"started_at"` // 开启时间
+}
+
+// Maintenance 保存维护期间仍允许访问的URL
+type Maintenance struct {
+	paths []string
+}
+
+// maintenanceCache 维护模式状态本地缓存，减少每次请求对Redis的访问
+var maintenanceCache struct {
+	mutex   sync.RWMutex
+	status  *MaintenanceStatus
+	expires time.Time
+}
+
+func MaintenanceBuilder() *Maintenance {
+	return &Maintenance{}
+}
+
+// AllowPaths 保存维护期间仍允许访问的URL到结构体，支持前缀匹配
+func (m *Maintenance) AllowPaths(path string) *Maintenance {
+	m.paths = append(m.paths, path)
+	return m
+}
+
+// Build 维护模式：开启后除允许访问的URL及管理员外，所有请求返回503及维护提示信息，需在登录中间件之后加载
+func (m *Maintenance) Build() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		status := GetMaintenance()
+		if !status.Enabled {
+			return
+		}
+
+		for _, path := range m.paths {
+			if strings.HasPrefix(c.Request.URL.Path, path) {
+				return
+			}
+		}
+
+		if username := c.GetString("username"); username != "" && IsMaintenanceAdmin(username) {
+			return
+		}
+
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"code": 90503,
+			"msg":  status.Message,
+		})
+		c.Abort()
+	}
+}
+
+// GetMaintenance 获取维护模式状态，优先使用本地缓存，Redis不可用时视为未开启
+func GetMaintenance() *MaintenanceStatus {
+	now := time.Now()
+
+	maintenanceCache.mutex.RLock()
+	status, expires := maintenanceCache.status, maintenanceCache.expires
+	maintenanceCache.mutex.RUnlock()
+	if status != nil && now.Before(expires) {
+		return status
+	}
+
+	status = &MaintenanceStatus{}
+	value, err := global.Cache.Get(maintenanceKey)
+	if err != nil && !errors.Is(err, cache.ErrNotFound) {
+		logger.Error("ERROR：获取维护模式状态失败，", err.Error())
+		return status
+	}
+	if value != "" {
+		if err := json.Unmarshal([]byte(value), status); err != nil {
+			logger.Error("ERROR：维护模式状态解析失败，", err.Error())
+		}
+	}
+	if status.Enabled && status.Message == "" {
+		status.Message = MaintenanceDefaultNotice
+	}
+
+	maintenanceCache.mutex.Lock()
+	maintenanceCache.status, maintenanceCache.expires = status, now.Add(maintenanceCacheTTL)
+	maintenanceCache.mutex.Unlock()
+
+	return status
+}
+
+// SetMaintenance 开启或关闭维护模式，其它实例在本地缓存过期后生效
+func SetMaintenance(status *MaintenanceStatus) error {
+
+	if status.Enabled {
+		data, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+		if err := global.Cache.Set(maintenanceKey, string(data), 0); err != nil {
+			return err
+		}
+	} else if _, err := global.Cache.Del(maintenanceKey); err != nil {
+		return err
+	}
+
+	maintenanceCache.mutex.Lock()
+	maintenanceCache.status = nil
+	maintenanceCache.mutex.Unlock()
+
+	return nil
+}
+
+// IsMaintenanceAdmin 判断用户是否有权限设置维护模式
+func IsMaintenanceAdmin(username string) bool {
+	ok, err := global.CasBinServer.Enforce(username, maintenanceAdminPath, http.MethodPut)
+	if err != nil {
+		logger.Error("ERROR：", err.Error())
+		return false
+	}
+	return ok
+}
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/wonderivan/logger"
+	"net/url"
+	"ops-api/config"
+	"strings"
+)
+
+// OIDC端点配置项及默认地址（相对于签发者）
+var oidcEndpoints = []struct {
+	key         string
+	defaultPath string
+}{
+	{"oidcAuthorizationEndpoint", "/login"},
+	{"oidcTokenEndpoint", "/api/v1/sso/oauth/token"},
+	{"oidcUserinfoEndpoint", "/api/v1/sso/oauth/userinfo"},
+	{"oidcJwksUri", "/api/v1/sso/oidc/jwks"},
+	{"oidcDeviceEndpoint", "/api/v1/sso/oauth/device_authorization"},
+	{"oidcVerificationUri", "/device"},
+}
+
+// OIDCIssuer 获取Token签发者，未配置oidcIssuer时使用externalUrl，签发的Token及OIDC配置信息中的iss均使用该值
+func OIDCIssuer() string {
+	issuer := config.GetString("oidcIssuer")
+	if strings.TrimSpace(issuer) == "" {
+		issuer = config.GetString("externalUrl")
+	}
+	return strings.TrimRight(strings.TrimSpace(issuer), "/")
+}
+
+// OIDCEndpoint 获取OIDC端点地址，未配置时使用签发者地址加默认路径
+func OIDCEndpoint(key string) string {
+	if endpoint := config.GetString(key); strings.TrimSpace(endpoint) != "" {
+		return strings.TrimSpace(endpoint)
+	}
+	for _, item := range oidcEndpoints {
+		if item.key == key {
+			return OIDCIssuer() + item.defaultPath
+		}
+	}
+	return ""
+}
+
+// ValidateOIDCConfig 启动时校验签发者及OIDC端点配置，签发者必须为不包含查询参数及片段的http(s)地址，端点必须为完整的http(s)地址
+func ValidateOIDCConfig() error {
+
+	issuer := OIDCIssuer()
+	if err := ValidateOIDCIssuer(issuer); err != nil {
+		return err
+	}
+	u, _ := url.Parse(issuer)
+	if u.Scheme != "https" {
+		logger.Warn(fmt.Sprintf("签发者（%s）未使用https", issuer))
+	}
+
+	// OIDC配置信息接口注册在根路径下，签发者包含路径时需由反向代理转发
+	if u.Path != "" {
+		logger.Warn(fmt.Sprintf("签发者（%s）包含路径，请确认 %s/.well-known/openid-configuration 可以正常访问", issuer, issuer))
+	}
+
+	// 签发者与externalUrl不一致时，Token中的iss与前端访问地址不同
+	if externalUrl := config.GetString("externalUrl"); externalUrl != "" {
+		if e, err := url.Parse(externalUrl); err == nil && e.Host != u.Host {
+			logger.Warn(fmt.Sprintf("签发者（%s）与系统外部访问地址（%s）不一致", issuer, externalUrl))
+		}
+	}
+
+	for _, item := range oidcEndpoints {
+		if err := ValidateOIDCEndpoint(item.key, OIDCEndpoint(item.key)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateOIDCIssuer 校验签发者地址
+func ValidateOIDCIssuer(issuer string) error {
+	u, err := url.Parse(issuer)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("签发者（%s）必须为完整的http(s)地址", issuer)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("签发者（%s）不能包含查询参数或片段", issuer)
+	}
+	return nil
+}
+
+// ValidateOIDCEndpoint 校验OIDC端点地址
+func ValidateOIDCEndpoint(key, endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("%s（%s）必须为完整的http(s)地址", key, endpoint)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"ops-api/config"
+	"ops-api/utils"
+	"strings"
+)
+
+// SCIMAuth SCIM接口认证，使用系统配置中的scimToken作为Bearer令牌
+func SCIMAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		// 未配置令牌时不开放SCIM接口
+		cipherText := config.GetString("scimToken")
+		if cipherText == "" {
+			scimUnauthorized(c, "SCIM服务未启用")
+			return
+		}
+
+		// 获取Token
+		parts := strings.SplitN(c.Request.Header.Get("Authorization"), " ", 2)
+		if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+			scimUnauthorized(c, "未认证")
+			return
+		}
+
+		// 对配置中的令牌解密
+		token, err := utils.Decrypt(cipherText)
+		if err != nil {
+			logger.Error("ERROR：SCIM令牌解密失败，", err.Error())
+			scimUnauthorized(c, "token无效")
+			return
+		}
+
+		// 使用常量时间比较，避免时序攻击
+		if subtle.ConstantTimeCompare([]byte(parts[1]), []byte(token)) != 1 {
+			scimUnauthorized(c, "token无效")
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// scimUnauthorized 按SCIM协议格式返回认证失败
+func scimUnauthorized(c *gin.Context, detail string) {
+	c.Header("WWW-Authenticate", `Bearer realm="SCIM"`)
+	c.Header("Content-Type", "application/scim+json")
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"schemas": []string{"urn:ietf:params:scim:api:messages:2.0:Error"},
+		"status":  "401",
+		"detail":  detail,
+	})
+}
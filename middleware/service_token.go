@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/casbin/casbin/v2/util"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/wonderivan/logger"
+	"ops-api/global"
+	"ops-api/model"
+	"strings"
+	"time"
+)
+
+// 内部服务令牌：其它内部平台使用服务客户端的client_id、client_secret通过client_credentials获取短期服务令牌，
+// 令牌的受众为平台接口，仅能调用令牌Scope中的接口，服务客户端被禁用、删除或修改后此前签发的令牌立即失效；
+// 签发的令牌按jti记录在服务客户端的令牌集合中，注销服务客户端时逐个注销，不依赖签发时间（精度为秒）比较
+
+const (
+	serviceTokenType      = "svc+jwt"          // 服务令牌的JWT类型，用于区分用户Token及OAuth2.0 Token
+	ServiceTokenMaxTTL    = time.Hour          // 服务令牌的最长有效期
+	ServiceUsernamePrefix = "service:"         // 服务客户端调用接口时记录的用户名前缀
+	serviceScopeKey       = "service_scope"    // 服务令牌的Scope在请求上下文中的Key
+	serviceTokensPrefix   = "service_tokens:"  // 服务客户端已签发令牌的jti集合Key前缀
+	serviceRevokedPrefix  = "service_revoked:" // 已注销的服务令牌jti Key前缀
+)
+
+// ServiceClaims 保存需要保存到服务令牌中的信息结构体
+type ServiceClaims struct {
+	ClientID string `json:"client_id"`
+	Name     string `json:"name"`  // 服务客户端名称
+	Scope    string `json:"scope"` // 允许调用的接口名称，多个以空格分隔
+	jwt.RegisteredClaims
+}
+
+// ServiceTokenAudience 服务令牌的受众（平台接口）
+func ServiceTokenAudience() string {
+	return OIDCIssuer() + "/api"
+}
+
+// GenerateServiceToken 生成服务令牌，每个令牌具有唯一的jti，签发前记录jti，记录失败时不签发
+func GenerateServiceToken(clientId, name, scope string, ttl time.Duration) (string, error) {
+
+	jti := uuid.NewString()
+	if err := global.Cache.SAdd(serviceTokensPrefix+clientId, jti, ServiceTokenMaxTTL); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := ServiceClaims{
+		ClientID: clientId,
+		Name:     name,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)), // 过期时间
+			IssuedAt:  jwt.NewNumericDate(now),          // 签发时间
+			NotBefore: jwt.NewNumericDate(now),          // 生效时间
+			Issuer:    OIDCIssuer(),                     // 签发者
+			Audience:  []string{ServiceTokenAudience()}, // 令牌的受众
+			Subject:   ServiceUsernamePrefix + clientId, // 令牌主题
+			ID:        jti,                              // 令牌ID
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["typ"] = serviceTokenType
+
+	return signToken(token)
+}
+
+// IsServiceToken 判断Authorization请求头中的Token是否为服务令牌，不校验签名
+func IsServiceToken(token string) bool {
+	raw, err := bearerToken(token)
+	if err != nil {
+		return false
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(raw, &ServiceClaims{})
+	if err != nil {
+		return false
+	}
+	return parsed.Header["typ"] == serviceTokenType
+}
+
+// ValidateServiceToken 校验服务令牌的签名、签发者、受众及有效期，并判断令牌或服务客户端是否已注销，
+// 缓存不可用时拒绝请求，不受 tokenRevocationFailOpen 影响
+func ValidateServiceToken(token string) (*ServiceClaims, error) {
+	raw, err := bearerToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	mc := new(ServiceClaims)
+	parsed, err := jwt.ParseWithClaims(raw, mc, verificationKey,
+		jwt.WithIssuer(OIDCIssuer()), jwt.WithAudience(ServiceTokenAudience()), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+	if !parsed.Valid || parsed.Header["typ"] != serviceTokenType || mc.ClientID == "" || mc.ID == "" || mc.IssuedAt == nil {
+		return nil, errors.New("token无效")
+	}
+
+	// 令牌有效期不能超过最长有效期，避免长期有效的令牌被重放
+	if mc.ExpiresAt.Sub(mc.IssuedAt.Time) > ServiceTokenMaxTTL {
+		return nil, errors.New("token无效")
+	}
+
+	// 判断令牌是否已注销（包括单独注销的令牌及服务客户端注销时注销的令牌）
+	revokedErr, err := isTokenOrSessionRevoked(raw, "")
+	if err == nil && revokedErr == nil {
+		var revoked bool
+		if revoked, err = global.Cache.Exists(serviceRevokedPrefix + mc.ID); revoked {
+			revokedErr = errors.New("token无效")
+		}
+	}
+	if err != nil {
+		logger.Error("服务令牌注销状态查询失败：" + err.Error())
+		return nil, errors.New("服务暂不可用，请稍后重试")
+	}
+	if revokedErr != nil {
+		return nil, revokedErr
+	}
+
+	return mc, nil
+}
+
+// RevokeServiceClient 注销服务客户端此前签发的所有服务令牌，服务客户端被禁用、删除或修改时调用
+func RevokeServiceClient(clientId string) error {
+
+	key := serviceTokensPrefix + clientId
+	jtis, err := global.Cache.SMembers(key)
+	if err != nil {
+		return err
+	}
+
+	// 逐个注销并从集合中删除，注销后签发的令牌不受影响
+	for _, jti := range jtis {
+		if err := global.Cache.Set(serviceRevokedPrefix+jti, "1", ServiceTokenMaxTTL); err != nil {
+			return err
+		}
+		if err := global.Cache.SRem(key, jti); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serviceScopeAllowed 判断服务令牌的Scope是否允许调用该接口，Scope为系统接口（system_path）的名称
+func serviceScopeAllowed(scope, path, method string) (bool, error) {
+
+	names := strings.Fields(scope)
+	if len(names) == 0 {
+		return false, nil
+	}
+
+	var paths []*model.SystemPath
+	if err := global.MySQLClient.Where("name IN ?", names).Find(&paths).Error; err != nil {
+		return false, err
+	}
+
+	// 与权限规则使用相同的匹配方式
+	for _, p := range paths {
+		if p.Method == method && (util.KeyMatch2(path, p.Path) || util.KeyMatch(path, p.Path)) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"fmt"
+	"ops-api/global"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 每次登录签发的用户Token对应一个会话，会话ID写入Token的sid声明，
+// 会话期间签发的单点登录票据及OAuth2.0 Token均关联该会话，会话注销后一并失效
+
+// 会话认证方式（RFC 8176），写入Token的amr声明
+const (
+	AMRPassword     = "pwd" // 账号密码
+	AMROTP          = "otp" // 动态口令（MFA）
+	AMRMultiFactor  = "mfa" // 多因子认证
+	AMRMultiChannel = "mca" // 扫码登录（移动端已完成身份确认）
+)
+
+// 会话认证等级，写入Token的acr声明，客户端可通过 acr_values 参数要求更高的认证等级
+const (
+	ACRSingleFactor = "1" // 单因子认证
+	ACRMultiFactor  = "2" // 多因子认证
+)
+
+// ACR 根据认证方式获取认证等级，扫码登录与登录策略保持一致视为已完成多因子认证
+func ACR(amr []string) string {
+	for _, method := range amr {
+		if method == AMRMultiFactor || method == AMRMultiChannel {
+			return ACRMultiFactor
+		}
+	}
+	return ACRSingleFactor
+}
+
+// userSessionsKey 用户会话列表Key
+func userSessionsKey(userId uint) string {
+	return fmt.Sprintf("user_sessions:%d", userId)
+}
+
+// sessionAMRKey 会话认证方式Key
+func sessionAMRKey(sessionId string) string {
+	return "session_amr:" + sessionId
+}
+
+// sessionAuthTimeKey 会话认证时间Key
+func sessionAuthTimeKey(sessionId string) string {
+	return "session_auth_time:" + sessionId
+}
+
+// sessionRevokedKey 已注销会话Key
+func sessionRevokedKey(sessionId string) string {
+	return "session_revoked:" + sessionId
+}
+
+// addUserSession 记录用户会话、认证方式及认证时间，用于强制下线时获取用户的所有会话
+func addUserSession(userId uint, sessionId string, amr []string, ttl time.Duration) error {
+	if err := global.Cache.SAdd(userSessionsKey(userId), sessionId, ttl); err != nil {
+		return err
+	}
+	if err := global.Cache.Set(sessionAMRKey(sessionId), strings.Join(amr, ","), ttl); err != nil {
+		return err
+	}
+	return global.Cache.Set(sessionAuthTimeKey(sessionId), strconv.FormatInt(time.Now().Unix(), 10), ttl)
+}
+
+// GetSessionAMR 获取会话已完成的认证方式，会话不存在时返回空
+func GetSessionAMR(sessionId string) []string {
+	if sessionId == "" {
+		return nil
+	}
+	val, err := global.Cache.Get(sessionAMRKey(sessionId))
+	if err != nil || val == "" {
+		return nil
+	}
+	return strings.Split(val, ",")
+}
+
+// GetSessionAuthTime 获取会话最近一次完成认证的时间（登录或升级认证），会话不存在时返回零值
+func GetSessionAuthTime(sessionId string) time.Time {
+	if sessionId == "" {
+		return time.Time{}
+	}
+	value, _ := global.Cache.Get(sessionAuthTimeKey(sessionId))
+	val, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || val == 0 {
+		return time.Time{}
+	}
+	return time.Unix(val, 0)
+}
+
+// mergeAMR 合并认证方式并去重
+func mergeAMR(amr []string, methods ...string) []string {
+	result := append([]string{}, amr...)
+	for _, method := range methods {
+		exist := false
+		for _, m := range result {
+			if m == method {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			result = append(result, method)
+		}
+	}
+	return result
+}
+
+// GetUserSessions 获取用户的会话ID列表（包括已过期的会话）
+func GetUserSessions(userId uint) ([]string, error) {
+	return global.Cache.SMembers(userSessionsKey(userId))
+}
+
+// RevokeSession 注销会话，会话签发的所有Token均失效
+func RevokeSession(userId uint, sessionId string) error {
+	if err := RevokeToken(sessionRevokedKey(sessionId)); err != nil {
+		return err
+	}
+	return global.Cache.SRem(userSessionsKey(userId), sessionId)
+}
+
+// IsSessionRevoked 判断会话是否已注销
+func IsSessionRevoked(sessionId string) (bool, error) {
+	return IsTokenRevoked(sessionRevokedKey(sessionId))
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/wonderivan/logger"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"sync"
+	"time"
+)
+
+// JWT签名密钥轮换：签名密钥保存在数据库中，新生成的密钥（pending）先发布在JWKS中，客户端刷新JWKS后再启用（active），
+// 启用后原签名密钥停用（retired），停用的密钥在其签发的Token过期前仍发布在JWKS中并可用于校验；
+// 没有启用的签名密钥时使用系统配置的密钥签名，系统配置的密钥同时用于数据加密及SAML2签名，始终发布在JWKS中
+
+const (
+	SigningKeyPending = "pending" // 已发布未启用
+	SigningKeyActive  = "active"  // 当前签名使用
+	SigningKeyRetired = "retired" // 已停用
+
+	signingKeyCacheTTL       = 30 * time.Second // 签名密钥本地缓存时间，其它实例轮换密钥后在缓存过期后生效
+	signingKeyReloadInterval = 5 * time.Second  // 遇到未知kid时重新加载的最小间隔
+)
+
+// signingKeySet 已解析的签名密钥
+type signingKeySet struct {
+	kid        string                    // 签名使用的kid
+	privateKey *rsa.PrivateKey           // 签名使用的私钥
+	publicKeys map[string]*rsa.PublicKey // 可用于校验的公钥，kid -> 公钥
+	defaultKid string                    // 系统配置的密钥的kid
+	jwks       []byte                    // 序列化后的JWK Set
+	loadedAt   time.Time
+}
+
+var (
+	signingKeyMutex    sync.Mutex
+	currentSigningKeys *signingKeySet
+)
+
+// SigningKeyRetention 签名密钥停用后仍可用于校验的时间，为Token的最长有效期
+func SigningKeyRetention() time.Duration {
+	retention := time.Duration(config.SSO().TokenExpiresTime) * time.Hour
+	if retention < ServiceTokenMaxTTL {
+		retention = ServiceTokenMaxTTL
+	}
+	return retention
+}
+
+// ReloadSigningKeys 重新加载签名密钥，签名密钥变更或系统配置的密钥更新后调用
+func ReloadSigningKeys() error {
+	signingKeyMutex.Lock()
+	defer signingKeyMutex.Unlock()
+
+	_, err := reloadSigningKeys()
+	return err
+}
+
+// getSigningKeys 获取签名密钥，缓存过期或force为true（遇到未知kid）时重新加载
+func getSigningKeys(force bool) (*signingKeySet, error) {
+	signingKeyMutex.Lock()
+	defer signingKeyMutex.Unlock()
+
+	if current := currentSigningKeys; current != nil {
+		age := time.Since(current.loadedAt)
+		if age < signingKeyReloadInterval || (!force && age < signingKeyCacheTTL) {
+			return current, nil
+		}
+	}
+
+	keys, err := reloadSigningKeys()
+	if err != nil {
+		// 数据库暂时不可用时继续使用已加载的密钥
+		if currentSigningKeys != nil {
+			logger.Error("签名密钥加载失败：" + err.Error())
+			currentSigningKeys.loadedAt = time.Now()
+			return currentSigningKeys, nil
+		}
+		return nil, err
+	}
+	return keys, nil
+}
+
+// reloadSigningKeys 从系统配置及数据库中加载签名密钥，调用方需持有 signingKeyMutex
+func reloadSigningKeys() (*signingKeySet, error) {
+
+	keys := &signingKeySet{publicKeys: make(map[string]*rsa.PublicKey), loadedAt: time.Now()}
+
+	// 系统配置的密钥
+	privateKey, err := utils.LoadIdpPrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	if keys.defaultKid, err = utils.LoadIdpKeyId(); err != nil {
+		return nil, err
+	}
+	keys.kid, keys.privateKey = keys.defaultKid, privateKey
+	keys.publicKeys[keys.defaultKid] = &privateKey.PublicKey
+
+	// 数据库中未停用及停用后仍在保留期内的密钥
+	var items []*model.SigningKey
+	if err := global.MySQLClient.
+		Where("status IN ? OR (status = ? AND retired_at > ?)", []string{SigningKeyPending, SigningKeyActive}, SigningKeyRetired, time.Now().Add(-SigningKeyRetention())).
+		Find(&items).Error; err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		publicKey, err := utils.ParseRSAPublicKey(item.PublicKey)
+		if err != nil {
+			logger.Error(fmt.Sprintf("签名密钥%s解析失败：%s", item.Kid, err.Error()))
+			continue
+		}
+		keys.publicKeys[item.Kid] = publicKey
+
+		if item.Status == SigningKeyActive {
+			activeKey, err := utils.ParseRSAPrivateKey(item.PrivateKey)
+			if err != nil {
+				logger.Error(fmt.Sprintf("签名密钥%s解析失败：%s", item.Kid, err.Error()))
+				continue
+			}
+			keys.kid, keys.privateKey = item.Kid, activeKey
+		}
+	}
+
+	if keys.jwks, err = utils.MarshalJwks(keys.publicKeys); err != nil {
+		return nil, err
+	}
+
+	currentSigningKeys = keys
+	return keys, nil
+}
+
+// JWKS 获取发布的JWK Set，包括当前签名使用、待启用及停用后仍在保留期内的密钥
+func JWKS() ([]byte, error) {
+	keys, err := getSigningKeys(false)
+	if err != nil {
+		return nil, err
+	}
+	return keys.jwks, nil
+}
+
+// signToken 使用当前签名密钥及RS256签名算法签发Token，并在Header中设置kid
+func signToken(token *jwt.Token) (string, error) {
+	keys, err := getSigningKeys(false)
+	if err != nil {
+		return "", err
+	}
+	token.Header["kid"] = keys.kid
+	return token.SignedString(keys.privateKey)
+}
+
+// verificationKey 根据Token Header中的kid获取校验使用的公钥，未携带kid的Token（轮换前签发）使用系统配置的密钥校验
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+		return nil, errors.New(fmt.Sprintf("unexpected signing method: %v", token.Header["alg"]))
+	}
+
+	keys, err := getSigningKeys(false)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = keys.defaultKid
+	}
+	if publicKey, ok := keys.publicKeys[kid]; ok {
+		return publicKey, nil
+	}
+
+	// 其它实例刚生成的密钥，重新加载后再查找
+	if keys, err = getSigningKeys(true); err != nil {
+		return nil, err
+	}
+	if publicKey, ok := keys.publicKeys[kid]; ok {
+		return publicKey, nil
+	}
+	return nil, fmt.Errorf("unknown kid: %s", kid)
+}
@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"ops-api/config"
+	"strconv"
+	"time"
+)
+
+// 接口错误响应的HTTP状态码：兼容模式（legacyStatusCode）默认开启，所有响应均返回HTTP 200，客户端仅根据响应体中的code判断结果；
+// 关闭兼容模式后响应体格式保持不变（code、msg），响应码 90400~90599 返回对应的HTTP状态码（如 90404 返回404、
+// 90409 返回409、90429 返回429），令牌无效（90514）返回401，便于网关及客户端按状态码区分错误并重试
+
+// StatusCode 获取响应码对应的HTTP状态码
+func StatusCode(code int) int {
+	if code == 0 || config.GetBool("legacyStatusCode") {
+		return http.StatusOK
+	}
+	if code == 90514 {
+		return http.StatusUnauthorized
+	}
+	if status := code - 90000; status >= 400 && status < 600 && http.StatusText(status) != "" {
+		return status
+	}
+	return http.StatusOK
+}
+
+// AbortWithCode 返回错误信息并终止请求
+func AbortWithCode(c *gin.Context, code int, msg string) {
+	c.JSON(StatusCode(code), gin.H{
+		"code": code,
+		"msg":  msg,
+	})
+	c.Abort()
+}
+
+// SetRetryAfter 设置限流响应的重试等待时间（Retry-After，单位为秒，不足1秒按1秒）
+func SetRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	c.Header("Retry-After", strconv.Itoa(seconds))
+}
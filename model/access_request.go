@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// AccessRequest 用户提交的访问申请（申请访问应用或加入用户组），在外部ITSM（Jira Service Management、飞书审批）中审批，审批通过后自动授权
+type AccessRequest struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID      uint       `json:"user_id" gorm:"index"`
+	Username    string     `json:"username"`
+	Type        string     `json:"type" gorm:"size:16"` // 申请类型：site 访问应用，group 加入用户组（角色）
+	TargetID    uint       `json:"target_id"`           // 应用或用户组ID
+	TargetName  string     `json:"target_name"`
+	Reason      string     `json:"reason" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"size:16;index"` // 状态：pending 审批中，approved 已授权，rejected 已拒绝，failed 提交或授权失败
+	Provider    string     `json:"provider" gorm:"size:16"`     // 审批使用的ITSM：jira、feishu
+	ExternalID  string     `json:"external_id" gorm:"size:128;index"`
+	ExternalURL string     `json:"external_url"`
+	Approver    string     `json:"approver"` // ITSM中的审批人
+	Message     string     `json:"message"`  // 审批意见或失败原因
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+}
+
+func (*AccessRequest) TableName() (name string) {
+	return "access_request"
+}
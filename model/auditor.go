@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// AuditorGrant 审计员授权，有效期内用户只能以只读方式查看用户、权限、登录记录及系统配置，返回数据中的个人信息脱敏
+type AuditorGrant struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	Username  string     `json:"username" gorm:"index"`
+	Reason    string     `json:"reason"`     // 审计事由，如审计项目名称
+	ExpiresAt time.Time  `json:"expires_at"` // 到期时间，到期后自动失效
+	GrantedBy string     `json:"granted_by"`
+	RevokedAt *time.Time `json:"revoked_at"` // 提前撤销时间
+	RevokedBy string     `json:"revoked_by"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (*AuditorGrant) TableName() (name string) {
+	return "auditor_grant"
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// UserConsent 用户对应用的授权记录，同一用户对同一应用只保存一条，Scope为用户已确认授权的所有Scope
+type UserConsent struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_user_consent"`
+	ClientID  string    `json:"client_id" gorm:"size:64;uniqueIndex:idx_user_consent"`
+	Scope     string    `json:"scope"` // 已授权的Scope，多个以空格分隔
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (*UserConsent) TableName() (name string) {
+	return "user_consent"
+}
@@ -0,0 +1,19 @@
+package model
+
+import "gorm.io/gorm"
+
+// FeatureFlag 功能开关，用于新功能按用户、分组或比例逐步开放
+type FeatureFlag struct {
+	gorm.Model
+	Key         string       `json:"key" gorm:"size:64;unique"`                   // 功能标识，如：consent_screen
+	Name        string       `json:"name"`                                        // 功能名称
+	Description string       `json:"description"`                                 // 描述
+	Enabled     bool         `json:"enabled"`                                     // 总开关，关闭时对所有用户关闭
+	Percentage  uint         `json:"percentage"`                                  // 灰度比例（0-100），按用户名哈希分桶，100表示对所有用户开放
+	Users       []*AuthUser  `json:"users" gorm:"many2many:feature_flag_users"`   // 指定开放的用户
+	Groups      []*AuthGroup `json:"groups" gorm:"many2many:feature_flag_groups"` // 指定开放的分组
+}
+
+func (*FeatureFlag) TableName() (name string) {
+	return "feature_flag"
+}
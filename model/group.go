@@ -7,6 +7,8 @@ type AuthGroup struct {
 	gorm.Model
 	Name        string      `json:"name" gorm:"unique"`
 	IsRoleGroup bool        `json:"is_role_group" gorm:"default:false"`
+	ExternalId  *string     `json:"external_id" gorm:"size:128;unique"` // 外部系统（如Terraform）中的资源标识
+	DynamicRule string      `json:"dynamic_rule" gorm:"type:text"`      // 动态分组规则，为空表示静态分组，否则成员根据用户属性自动计算
 	Users       []*AuthUser `json:"users" gorm:"many2many:auth_user_groups"`
 }
 
@@ -0,0 +1,18 @@
+package model
+
+import "gorm.io/gorm"
+
+// SiteGuideStep 站点导航引导步骤
+type SiteGuideStep struct {
+	gorm.Model
+	Title   string       `json:"title"`
+	Content string       `json:"content" gorm:"type:text"`
+	Image   *string      `json:"image" gorm:"default:null"` // 图片在MinIO中的存储路径
+	Sort    uint         `json:"sort" gorm:"default:0"`
+	Enabled bool         `json:"enabled" gorm:"default:true"`
+	Roles   []*AuthGroup `json:"roles" gorm:"many2many:site_guide_step_roles"` // 可见角色，为空则所有用户可见
+}
+
+func (*SiteGuideStep) TableName() (name string) {
+	return "site_guide_step"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "gorm.io/gorm"
+
+// LandingRule 登录后跳转规则，控制台直接登录后按用户所属分组确定默认跳转地址，或在跳转目标为指定应用时覆盖跳转地址
+type LandingRule struct {
+	gorm.Model
+	Name        string       `json:"name" gorm:"unique"`
+	Description string       `json:"description"`
+	SiteID      *uint        `json:"site_id" gorm:"default:null"`                 // 应用ID，为空时为分组默认跳转地址，不为空时仅在跳转目标为该应用时生效
+	URL         string       `json:"url"`                                         // 跳转地址，站内路径（如：/dashboard）或已允许的外部地址
+	Enabled     bool         `json:"enabled" gorm:"default:true"`                 // 是否启用
+	Groups      []*AuthGroup `json:"groups" gorm:"many2many:landing_rule_groups"` // 规则适用的分组，为空时适用于所有用户
+}
+
+func (*LandingRule) TableName() (name string) {
+	return "landing_rule"
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/oschwald/geoip2-golang"
 	"gorm.io/gorm"
 	"net"
+	"time"
 )
 
 // LogSMS 短信发送日志表
@@ -81,3 +82,34 @@ func (l *LogLogin) BeforeCreate(tx *gorm.DB) (err error) {
 
 	return nil
 }
+
+// LogSCIM SCIM同步日志表
+type LogSCIM struct {
+	gorm.Model
+	ResourceType string `json:"resource_type"` // User、Group
+	ResourceId   string `json:"resource_id"`
+	ResourceName string `json:"resource_name"`
+	Operation    string `json:"operation"` // create、update、delete
+	ClientIP     string `json:"client_ip"`
+	UserAgent    string `json:"user_agent"`
+	Status       int    `json:"status"` // 1：成功，2：失败
+	FailedReason string `json:"failed_reason"`
+}
+
+func (*LogSCIM) TableName() (name string) {
+	return "log_scim"
+}
+
+// LogSSOError 单点登录协议错误日志表
+type LogSSOError struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	SiteID    uint      `json:"site_id" gorm:"index"` // 应用未注册时为0
+	Protocol  string    `json:"protocol" gorm:"size:16"`
+	Reason    string    `json:"reason" gorm:"size:32"`
+	Detail    string    `json:"detail"` // 错误详情，应用未注册时记录请求中的应用标识
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+func (*LogSSOError) TableName() (name string) {
+	return "log_sso_error"
+}
@@ -0,0 +1,21 @@
+package model
+
+import "gorm.io/gorm"
+
+// LoginHook 登录流程扩展（Webhook），在指定扩展点调用外部接口进行自定义校验
+type LoginHook struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"unique"`
+	Description string `json:"description"`
+	Stage       string `json:"stage"`                       // 扩展点：pre_auth、post_auth、pre_token
+	URL         string `json:"url"`                         // Webhook地址
+	Secret      string `json:"secret"`                      // 签名密钥，为空则不签名
+	Timeout     uint   `json:"timeout" gorm:"default:3"`    // 超时时间（秒）
+	FailOpen    bool   `json:"fail_open"`                   // 调用失败（超时、非200响应）时是否放行
+	Sort        int    `json:"sort" gorm:"default:0"`       // 执行顺序，数值越小越先执行
+	Enabled     bool   `json:"enabled" gorm:"default:true"` // 是否启用
+}
+
+func (*LoginHook) TableName() (name string) {
+	return "login_hook"
+}
@@ -0,0 +1,49 @@
+package model
+
+import "gorm.io/gorm"
+
+// LoginPolicy 登录限制策略
+type LoginPolicy struct {
+	gorm.Model
+	Name         string       `json:"name" gorm:"unique"`
+	Description  string       `json:"description"`
+	Countries    string       `json:"countries"`                                                 // 国家/地区代码（ISO 3166-1），多个使用逗号分隔，如：US,RU
+	IPRanges     string       `json:"ip_ranges" gorm:"type:text"`                                // IP地址段（CIDR），多个使用逗号或换行分隔，用于匹配代理、VPN等匿名网络
+	Action       uint         `json:"action"`                                                    // 命中后的动作：1：拒绝登录，2：要求MFA认证
+	Enabled      bool         `json:"enabled" gorm:"default:true"`                               // 是否启用
+	ExemptUsers  []*AuthUser  `json:"exempt_users" gorm:"many2many:login_policy_exempt_users"`   // 豁免用户
+	ExemptGroups []*AuthGroup `json:"exempt_groups" gorm:"many2many:login_policy_exempt_groups"` // 豁免分组
+}
+
+func (*LoginPolicy) TableName() (name string) {
+	return "login_policy"
+}
+
+// LoginTimePolicy 登录时间策略，限制指定分组的用户仅能在允许的时间段内登录
+type LoginTimePolicy struct {
+	gorm.Model
+	Name         string       `json:"name" gorm:"unique"`
+	Description  string       `json:"description"`
+	Weekdays     string       `json:"weekdays"`                                         // 允许登录的星期，0-6分别表示星期日至星期六，多个使用逗号分隔，为空则不限制（仅工作日登录时忽略）
+	WorkdaysOnly bool         `json:"workdays_only"`                                    // 仅允许在工作日登录，按节假日日历处理法定节假日及调休
+	StartTime    string       `json:"start_time"`                                       // 每日允许登录的开始时间，格式：HH:MM
+	EndTime      string       `json:"end_time"`                                         // 每日允许登录的结束时间，格式：HH:MM，小于开始时间时表示跨天
+	Enabled      bool         `json:"enabled" gorm:"default:true"`                      // 是否启用
+	Groups       []*AuthGroup `json:"groups" gorm:"many2many:login_time_policy_groups"` // 策略适用的分组
+}
+
+func (*LoginTimePolicy) TableName() (name string) {
+	return "login_time_policy"
+}
+
+// Holiday 节假日日历
+type Holiday struct {
+	gorm.Model
+	Date    string `json:"date" gorm:"unique;size:10"` // 日期，格式：YYYY-MM-DD
+	Name    string `json:"name"`                       // 节假日名称
+	Workday bool   `json:"workday"`                    // 是否为调休工作日，false表示放假
+}
+
+func (*Holiday) TableName() (name string) {
+	return "holiday"
+}
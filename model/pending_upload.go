@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// PendingUpload MinIO不可用时暂存的待上传文件，MinIO恢复后由后台任务上传，每个对象只保留最后一次上传的内容
+type PendingUpload struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Object      string    `json:"object" gorm:"size:255;uniqueIndex"` // 对象存储路径
+	ContentType string    `json:"content_type"`
+	Data        []byte    `json:"-" gorm:"type:mediumblob"`
+	Username    string    `json:"username" gorm:"index"` // 上传完成后更新该用户的头像
+	Attempts    int       `json:"attempts"`              // 已尝试上传的次数
+	LastError   string    `json:"last_error" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (*PendingUpload) TableName() (name string) {
+	return "pending_upload"
+}
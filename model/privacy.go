@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// UserErasure 用户个人数据擦除记录，擦除后用户的历史记录使用化名（Pseudonym）关联，保留审计记录的完整性
+type UserErasure struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex"`
+	Pseudonym string    `json:"pseudonym" gorm:"size:64"` // 擦除后使用的化名，替换历史记录中的用户名
+	Operator  string    `json:"operator"`                 // 发起擦除的管理员
+	Reason    string    `json:"reason"`                   // 擦除原因，如：用户申请、离职
+	Detail    string    `json:"detail" gorm:"type:text"`  // 各类数据的处理数量
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*UserErasure) TableName() (name string) {
+	return "user_erasure"
+}
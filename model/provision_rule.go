@@ -0,0 +1,19 @@
+package model
+
+import "gorm.io/gorm"
+
+// ProvisionRule 用户自动分配规则，新用户创建（本地创建、SCIM、LDAP同步、Keycloak导入）时根据邮箱域名或LDAP OU自动加入分组并授权站点
+type ProvisionRule struct {
+	gorm.Model
+	Name        string       `json:"name" gorm:"unique"`
+	Description string       `json:"description"`
+	MatchType   uint         `json:"match_type"`                                    // 匹配类型：1：邮箱域名，2：LDAP OU
+	MatchValue  string       `json:"match_value" gorm:"type:text"`                  // 匹配值，多个使用换行分隔，如：example.com、*.example.com、OU=研发部,DC=example,DC=com
+	Enabled     bool         `json:"enabled" gorm:"default:true"`                   // 是否启用
+	Groups      []*AuthGroup `json:"groups" gorm:"many2many:provision_rule_groups"` // 自动加入的分组
+	Sites       []*Site      `json:"sites" gorm:"many2many:provision_rule_sites"`   // 自动授权的站点
+}
+
+func (*ProvisionRule) TableName() (name string) {
+	return "provision_rule"
+}
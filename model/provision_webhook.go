@@ -0,0 +1,22 @@
+package model
+
+import "gorm.io/gorm"
+
+// ProvisionWebhook 身份事件推送订阅，用户或分组变更时将事件推送至下游系统，可通过模板转换为接收方要求的格式
+type ProvisionWebhook struct {
+	gorm.Model
+	Name        string `json:"name" gorm:"unique"`
+	Description string `json:"description"`
+	URL         string `json:"url"`                                          // 推送地址
+	Method      string `json:"method" gorm:"size:8;default:POST"`            // 请求方法：POST、PUT
+	ContentType string `json:"content_type" gorm:"default:application/json"` // 请求体类型，例如：application/json、application/xml
+	Events      string `json:"events"`                                       // 订阅的事件，多个事件使用英文逗号分隔，为空时订阅所有事件
+	Template    string `json:"template" gorm:"type:text"`                    // 请求体模板（Go Template），为空时推送原始事件（JSON）
+	Secret      string `json:"secret"`                                       // 签名密钥，为空则不签名
+	Timeout     uint   `json:"timeout" gorm:"default:5"`                     // 超时时间（秒）
+	Enabled     bool   `json:"enabled" gorm:"default:true"`                  // 是否启用
+}
+
+func (*ProvisionWebhook) TableName() (name string) {
+	return "provision_webhook"
+}
@@ -0,0 +1,17 @@
+package model
+
+import "gorm.io/gorm"
+
+// ComplianceReport 合规报告表，报告文件保存在OSS中
+type ComplianceReport struct {
+	gorm.Model
+	Name   string `json:"name"`                       // 报告文件名称
+	Period string `json:"period" gorm:"size:7;index"` // 报告周期，格式：YYYY-MM
+	Format string `json:"format"`                     // 文件格式：xlsx、pdf
+	Object string `json:"object"`                     // OSS对象名称
+	Size   int64  `json:"size"`                       // 文件大小（字节）
+}
+
+func (*ComplianceReport) TableName() (name string) {
+	return "compliance_report"
+}
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// ServiceClient 内部服务客户端，其它内部平台使用client_credentials获取短期服务令牌后调用平台接口，
+// 可调用的接口由Scopes限定，Scope为系统接口（system_path）的名称
+type ServiceClient struct {
+	gorm.Model
+	Name         string     `json:"name" gorm:"unique"`
+	Description  string     `json:"description"`
+	ClientId     string     `json:"client_id" gorm:"unique;size:32"`
+	ClientSecret string     `json:"-"`                           // 客户端密钥（加密存储），仅创建及重置时返回
+	Scopes       string     `json:"scopes" gorm:"type:text"`     // 允许调用的接口名称，多个以空格分隔
+	Enabled      bool       `json:"enabled" gorm:"default:true"` // 是否启用
+	LastIssuedAt *time.Time `json:"last_issued_at"`              // 最近一次签发服务令牌的时间
+}
+
+func (*ServiceClient) TableName() (name string) {
+	return "service_client"
+}
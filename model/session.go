@@ -0,0 +1,43 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// SessionPolicy 会话并发策略，限制指定分组的用户同时在线的会话数量
+type SessionPolicy struct {
+	gorm.Model
+	Name          string       `json:"name" gorm:"unique"`
+	Description   string       `json:"description"`
+	MaxSessions   uint         `json:"max_sessions"`                                  // 最大会话数量，为0则不限制
+	PerDeviceType bool         `json:"per_device_type"`                               // 每种设备类型（桌面端、移动端）仅允许一个会话
+	Action        uint         `json:"action"`                                        // 超出限制时的动作：1：拒绝新的登录，2：注销最早的会话
+	Enabled       bool         `json:"enabled" gorm:"default:true"`                   // 是否启用
+	Groups        []*AuthGroup `json:"groups" gorm:"many2many:session_policy_groups"` // 策略适用的分组
+}
+
+func (*SessionPolicy) TableName() (name string) {
+	return "session_policy"
+}
+
+// UserSession 用户会话记录，每次登录签发的用户Token对应一个会话
+type UserSession struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	SessionID    string     `json:"session_id" gorm:"size:64;uniqueIndex"`
+	UserID       uint       `json:"user_id" gorm:"index"`
+	Username     string     `json:"username" gorm:"index"`
+	DeviceType   string     `json:"device_type" gorm:"size:16"` // 设备类型：desktop、mobile
+	Device       string     `json:"device"`                     // 设备名称，例如：Chrome on Windows
+	Fingerprint  string     `json:"fingerprint" gorm:"size:64"` // 设备指纹，对应登录设备记录
+	ClientIP     string     `json:"client_ip"`
+	UserAgent    string     `json:"user_agent" gorm:"size:512"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	RevokeReason string     `json:"revoke_reason" gorm:"size:16"` // 注销原因：logout：用户注销，revoked：强制下线，evicted：超出会话数量被挤下线，denied：超出会话数量拒绝登录，wiped：设备已擦除
+}
+
+func (*UserSession) TableName() (name string) {
+	return "user_session"
+}
@@ -15,6 +15,19 @@ type Settings struct {
 	ParsedValue interface{} `gorm:"-" json:"parsed_value"`
 }
 
+// SettingsRevision 配置修改记录，用于查看修改历史及回滚
+type SettingsRevision struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Operator   string    `json:"operator"`
+	Changes    string    `json:"changes" gorm:"type:text"` // 修改的配置项（JSON数组），包括修改前后的值
+	RollbackOf *uint     `json:"rollback_of"`              // 回滚的修改记录ID，为空时为普通修改
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (*SettingsRevision) TableName() (name string) {
+	return "settings_revision"
+}
+
 // ParseValue 值转换
 func (s *Settings) ParseValue() error {
 
@@ -40,11 +53,11 @@ func (s *Settings) ParseValue() error {
 		s.ParsedValue = intValue
 	default:
 		if s.Key == "logo" || s.Key == "icon" {
-			logoPreview, err := utils.GetPresignedURL(*s.Value, 6*time.Hour)
+			logoPreview, err := utils.GetObjectURL(*s.Value, 6*time.Hour)
 			if err != nil {
 				return err
 			}
-			s.ParsedValue = logoPreview.String()
+			s.ParsedValue = logoPreview
 			return nil
 		} else {
 			s.ParsedValue = *s.Value
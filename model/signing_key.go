@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// SigningKey JWT签名密钥，多个密钥可同时发布在JWKS中，用于在不影响客户端的情况下轮换签名密钥
+type SigningKey struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Kid         string     `json:"kid" gorm:"size:64;unique"` // 基于公钥内容生成的Key ID
+	KeySize     int        `json:"key_size"`
+	PrivateKey  string     `json:"-" gorm:"type:text"`          // PKCS#8 PEM格式私钥
+	PublicKey   string     `json:"public_key" gorm:"type:text"` // PKIX PEM格式公钥
+	Status      string     `json:"status" gorm:"size:16;index"` // pending 已发布未启用，active 当前签名使用，retired 已停用
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at"`
+	RetiredAt   *time.Time `json:"retired_at"` // 停用后在有效期内签发的Token过期前仍发布在JWKS中
+}
+
+func (*SigningKey) TableName() (name string) {
+	return "signing_key"
+}
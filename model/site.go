@@ -3,6 +3,7 @@ package model
 import (
 	"gorm.io/gorm"
 	"ops-api/utils"
+	"time"
 )
 
 // SiteGroup 站点分组
@@ -18,26 +19,66 @@ func (*SiteGroup) TableName() (name string) {
 
 // Site 站点
 type Site struct {
-	ID           uint        `json:"id" gorm:"primaryKey;autoIncrement"`
-	Name         string      `json:"name"`
-	Icon         *string     `json:"icon" gorm:"default:null"`
-	Address      string      `json:"address"`
-	AllOpen      bool        `json:"all_open" gorm:"default:false"`
-	Description  string      `json:"description"`
-	HelperUrl    string      `json:"helper_url"`
-	SSO          bool        `json:"sso"`
-	SSOType      uint        `json:"sso_type" gorm:"default:null"`
-	ClientId     string      `json:"client_id"`                                    // OAuth2.0 ClientID
-	ClientSecret string      `json:"client_secret"`                                // OAuth2.0 ClientSecret
-	CallbackUrl  string      `json:"callback_url" gorm:"default:null"`             // OAuth2.0 And CAS3.0 Client CallbackUrl
-	EntityId     string      `json:"entity_id" gorm:"default:null"`                // SAML2.0 SP EntityID
-	Certificate  string      `json:"certificate" gorm:"default:null;type:text"`    // SAML2.0 SP Certificate
-	DomainId     string      `json:"domain_id" gorm:"default:null"`                // SAML2.0 SP 华为云相关
-	RedirectUrl  string      `json:"redirect_url" gorm:"default:null"`             // SAML2.0 SP 华为云相关
-	IDPName      string      `json:"idp_name" gorm:"default:null;column:idp_name"` // SAML2.0 SP 华为云相关
-	SiteGroupID  uint        `json:"site_group_id"`
-	Users        []*AuthUser `json:"users" gorm:"many2many:site_users"`
-	Tags         []*Tag      `json:"tags" gorm:"many2many:site_tags"`
+	ID               uint        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Name             string      `json:"name"`
+	Icon             *string     `json:"icon" gorm:"default:null"`
+	Address          string      `json:"address"`
+	AllOpen          bool        `json:"all_open" gorm:"default:false"`
+	Description      string      `json:"description"`
+	HelperUrl        string      `json:"helper_url"`
+	SSO              bool        `json:"sso"`
+	SSOType          uint        `json:"sso_type" gorm:"default:null"`
+	ClientId         string      `json:"client_id"`                                              // OAuth2.0 ClientID
+	ClientSecret     string      `json:"client_secret"`                                          // OAuth2.0 ClientSecret
+	CallbackUrl      string      `json:"callback_url" gorm:"default:null"`                       // OAuth2.0 And CAS3.0 Client CallbackUrl
+	EntityId         string      `json:"entity_id" gorm:"default:null"`                          // SAML2.0 SP EntityID
+	Certificate      string      `json:"certificate" gorm:"default:null;type:text"`              // SAML2.0 SP Certificate
+	MetadataUrl      string      `json:"metadata_url" gorm:"default:null"`                       // SAML2.0 SP Metadata地址，配置后定时自动刷新EntityID及SP证书
+	MetadataSyncAt   *time.Time  `json:"metadata_sync_at"`                                       // SAML2.0 SP Metadata最后一次刷新成功的时间
+	MetadataError    string      `json:"metadata_error" gorm:"default:null;type:text"`           // SAML2.0 SP Metadata最后一次刷新失败的原因，刷新成功后清空
+	AcsUrls          string      `json:"acs_urls" gorm:"default:null;type:text"`                 // SAML2.0 SP 已注册的ACS地址（JSON数组），第一个为默认地址
+	SloUrl           string      `json:"slo_url" gorm:"default:null"`                            // SAML2.0 SP 单点注销地址（HTTP-POST），为空时不通知SP注销
+	DomainId         string      `json:"domain_id" gorm:"default:null"`                          // SAML2.0 SP 华为云相关
+	RedirectUrl      string      `json:"redirect_url" gorm:"default:null"`                       // SAML2.0 SP 华为云相关
+	IDPName          string      `json:"idp_name" gorm:"default:null;column:idp_name"`           // SAML2.0 SP 华为云相关
+	ClaimMapping     string      `json:"claim_mapping" gorm:"default:null;type:text"`            // 属性映射（JSON，应用侧属性名 -> 用户属性或属性转换表达式），用于WS-Fed、SAML2及CAS3.0兼容格式
+	SAMLAttributes   string      `json:"saml_attributes" gorm:"default:null;type:text"`          // SAML2.0 属性发布策略（JSON数组），为空时发布默认属性
+	EncryptAssertion bool        `json:"encrypt_assertion" gorm:"default:false"`                 // SAML2.0 是否加密断言
+	EncryptionCert   string      `json:"encryption_certificate" gorm:"default:null;type:text"`   // SAML2.0 SP 加密证书，为空时使用SP证书
+	KeyEncryption    string      `json:"key_encryption_algorithm" gorm:"size:32;default:null"`   // SAML2.0 密钥加密算法：rsa-oaep-mgf1p、rsa-1_5，为空时为rsa-oaep-mgf1p
+	DataEncryption   string      `json:"data_encryption_algorithm" gorm:"size:32;default:null"`  // SAML2.0 数据加密算法：aes128-cbc、aes256-cbc、aes128-gcm、aes256-gcm，为空时为aes256-cbc
+	SignatureAlg     string      `json:"signature_algorithm" gorm:"size:32;default:null"`        // SAML2.0 签名算法：rsa-sha1、rsa-sha256、rsa-sha384、rsa-sha512，为空时为rsa-sha256
+	DigestAlg        string      `json:"digest_algorithm" gorm:"size:32;default:null"`           // SAML2.0 摘要算法：sha1、sha256、sha384、sha512，为空时为sha256
+	SigningLevel     string      `json:"signing_level" gorm:"size:16;default:null"`              // SAML2.0 签名位置：assertion、response、both，为空时仅签名断言
+	SubjectType      string      `json:"subject_type" gorm:"size:16;default:public"`             // OIDC sub类型：public、pairwise
+	SectorId         string      `json:"sector_identifier" gorm:"default:null"`                  // OIDC pairwise扇区标识，为空时使用回调地址的主机名
+	NginxTTL         uint        `json:"nginx_ttl" gorm:"default:12"`                            // Nginx 票据有效期（小时）
+	GrantTypes       string      `json:"grant_types" gorm:"default:null"`                        // OAuth2.0 允许使用的授权类型，多个以空格分隔，为空时不限制
+	RespTypes        string      `json:"response_types" gorm:"default:null"`                     // OAuth2.0 允许使用的响应类型，多个以逗号分隔（如：code,code id_token），为空时仅允许code
+	Scopes           string      `json:"scopes" gorm:"default:null"`                             // OAuth2.0 允许申请的Scope，多个以空格分隔，为空时不限制
+	Resources        string      `json:"resources" gorm:"default:null;type:text"`                // OAuth2.0 允许申请的资源（RFC 8707），多个以空格分隔，为空时不允许申请资源
+	PKCE             string      `json:"pkce" gorm:"size:16;default:null"`                       // OAuth2.0 PKCE：为空时可选，required 必须使用，public 为公共客户端（不校验ClientSecret，必须使用PKCE）
+	RefreshTTL       uint        `json:"refresh_token_ttl" gorm:"default:null"`                  // OAuth2.0 刷新令牌有效期（天），为空时为30天
+	RefreshMaxTTL    uint        `json:"refresh_token_max_ttl" gorm:"default:null"`              // OAuth2.0 刷新令牌自授权起的最长有效期（天），轮换不延长，为空时为365天
+	CodeTTL          uint        `json:"code_ttl" gorm:"default:null"`                           // OAuth2.0 授权码有效期（秒），为空时为10秒
+	AccessTTL        uint        `json:"access_token_ttl" gorm:"default:null"`                   // OAuth2.0 Access Token及ID Token有效期（秒），为空时与平台登录Token的有效期一致
+	TicketTTL        uint        `json:"ticket_ttl" gorm:"default:null"`                         // CAS3.0 票据有效期（秒），为空时为10秒
+	TokenAuthMethod  string      `json:"token_endpoint_auth_method" gorm:"size:32;default:null"` // OAuth2.0 Token接口客户端认证方式：为空时允许client_secret_basic及client_secret_post，指定后只能使用指定的方式
+	JwtPublicKey     string      `json:"jwt_public_key" gorm:"default:null;type:text"`           // OAuth2.0 private_key_jwt 客户端公钥（PEM格式的公钥或证书）
+	NginxRenewal     bool        `json:"nginx_renewal" gorm:"default:false"`                     // Nginx 票据超过一半有效期后自动续期
+	NginxGrace       uint        `json:"nginx_grace" gorm:"default:60"`                          // Nginx 票据续期后旧票据的宽限时间（秒）
+	ExternalId       *string     `json:"external_id" gorm:"size:128;unique"`                     // 外部系统（如Terraform）中的资源标识
+	CASProfile       string      `json:"cas_profile" gorm:"size:16;default:null"`                // CAS3.0 票据校验响应格式：为空时使用默认格式，apereo、name_value、dual 用于兼容旧CAS服务端
+	CASLogout        bool        `json:"cas_logout" gorm:"default:false"`                        // CAS3.0 单点注销：用户注销或会话被注销时向票据的回调地址发送注销请求
+	PublicDir        bool        `json:"public_directory" gorm:"default:false"`                  // 是否在公开应用目录中展示，公开应用目录无需登录即可访问
+	LaunchHook       string      `json:"launch_hook" gorm:"default:null"`                        // 用户单点登录该应用成功后异步通知的Webhook地址，为空时不通知
+	LaunchSecret     string      `json:"launch_secret" gorm:"default:null"`                      // Webhook签名密钥，为空时不签名
+	Consent          bool        `json:"consent" gorm:"default:false"`                           // OAuth2.0 授权前需要用户确认授权的Scope，确认后记住授权
+	Sunset           string      `json:"sunset" gorm:"size:10;default:null"`                     // 单点登录停用日期（YYYY-MM-DD），停用前为弃用期，停用后无法单点登录
+	OwnerEmail       string      `json:"owner_email" gorm:"default:null"`                        // 应用负责人邮箱，多个以逗号分隔，用于接收协议弃用提醒
+	SiteGroupID      uint        `json:"site_group_id"`
+	Users            []*AuthUser `json:"users" gorm:"many2many:site_users"`
+	Tags             []*Tag      `json:"tags" gorm:"many2many:site_tags"`
 }
 
 func (*Site) TableName() (name string) {
@@ -50,3 +91,20 @@ func (s *Site) BeforeCreate(tx *gorm.DB) (err error) {
 	s.ClientSecret = utils.GenerateRandomString(32)
 	return nil
 }
+
+// SiteCertificate SAML2.0 SP证书，SP轮换证书期间可同时配置多个证书，在有效期内的证书均可用于校验SP请求签名
+type SiteCertificate struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	SiteID      uint      `json:"site_id" gorm:"index"`
+	Name        string    `json:"name"`
+	Certificate string    `json:"certificate" gorm:"type:text"`
+	Fingerprint string    `json:"fingerprint" gorm:"size:64"` // 证书SHA256指纹
+	NotBefore   time.Time `json:"not_before"`                 // 证书生效时间
+	NotAfter    time.Time `json:"not_after"`                  // 证书过期时间
+	Enabled     bool      `json:"enabled" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (*SiteCertificate) TableName() (name string) {
+	return "site_certificate"
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// SiteGrant 用户访问站点授权的有效期，同一用户对同一站点只保存一条，没有记录的授权永久有效
+type SiteGrant struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	SiteID    uint      `json:"site_id" gorm:"uniqueIndex:idx_site_grant"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_site_grant"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"` // 授权到期时间，到期后由定时任务撤销授权
+	Operator  string    `json:"operator"`                // 设置有效期的操作人
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (*SiteGrant) TableName() (name string) {
+	return "site_grant"
+}
@@ -10,16 +10,67 @@ type SsoOAuthTicket struct {
 	*gorm.Model
 	ExpiresAt   time.Time  `json:"expires_at"`
 	Code        string     `json:"code"`
-	RedirectURI string     `json:"redirect_uri"`
+	RedirectURI string     `json:"redirect_uri"`                   // 授权请求中的redirect_uri，未携带时为空
+	ClientId    string     `json:"client_id" gorm:"size:64;index"` // 签发授权码的客户端，仅该客户端可以使用授权码
 	ConsumedAt  *time.Time `json:"consumed_at"`
 	UserID      uint       `json:"user_id"`
+	SessionID   string     `json:"session_id" gorm:"size:64;index"` // 签发票据的用户会话ID，会话注销后票据失效
 	Nonce       *string    `json:"nonce"`
+	Scope       string     `json:"scope"`                     // 授予客户端的Scope
+	Resource    string     `json:"resource" gorm:"type:text"` // 授予客户端的资源（RFC 8707），多个以空格分隔
+	// PKCE（RFC 7636）授权请求中的code_challenge及其计算方式（S256、plain），为空时未使用PKCE
+	CodeChallenge       string `json:"code_challenge" gorm:"size:128"`
+	CodeChallengeMethod string `json:"code_challenge_method" gorm:"size:8"`
 }
 
 func (*SsoOAuthTicket) TableName() (name string) {
 	return "sso_oauth_ticket"
 }
 
+// SsoOAuthRefreshToken OAuth2.0离线访问（offline_access）签发的刷新令牌，绑定授权时用户登录的设备，
+// 每次使用后轮换，同一次授权轮换产生的刷新令牌使用相同的会话ID，设备擦除后一并失效
+type SsoOAuthRefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"` // 刷新令牌的SHA256摘要，数据库中不保存明文
+	UserID    uint       `json:"user_id" gorm:"index"`
+	ClientID  string     `json:"client_id"`
+	DeviceID  uint       `json:"device_id" gorm:"index"`          // 绑定的登录设备，非离线访问且无法确定登录设备时为0
+	SessionID string     `json:"session_id" gorm:"size:64;index"` // 刷新后签发的Token使用的会话ID，离线访问时与用户登录会话相互独立，否则为用户登录会话
+	Scope     string     `json:"scope"`                           // 授予客户端的Scope
+	Resource  string     `json:"resource" gorm:"type:text"`       // 授予客户端的资源（RFC 8707），多个以空格分隔
+	GrantedAt *time.Time `json:"granted_at"`                      // 用户授权时间，轮换后保持不变，用于计算最长有效期
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RotatedAt *time.Time `json:"rotated_at"` // 轮换时间，轮换后的刷新令牌再次使用时视为泄露，注销同一次授权的所有令牌
+	RevokedAt *time.Time `json:"revoked_at"`
+}
+
+func (*SsoOAuthRefreshToken) TableName() (name string) {
+	return "sso_oauth_refresh_token"
+}
+
+// SsoOAuthDeviceCode OAuth2.0设备授权（RFC 8628）票据，设备使用device_code轮询Token接口，
+// 用户在验证页面输入user_code并确认授权后，设备下一次轮询时获取Token
+type SsoOAuthDeviceCode struct {
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	DeviceCodeHash string     `json:"-" gorm:"size:64;uniqueIndex"` // device_code的SHA256摘要，数据库中不保存明文
+	UserCode       string     `json:"user_code" gorm:"size:16;index"`
+	ClientID       string     `json:"client_id"`
+	Scope          string     `json:"scope"`                     // 授予客户端的Scope
+	Resource       string     `json:"resource" gorm:"type:text"` // 授予客户端的资源（RFC 8707），多个以空格分隔
+	Status         string     `json:"status" gorm:"size:16"`     // pending：等待用户确认，approved：已授权，denied：已拒绝，consumed：已签发Token
+	UserID         uint       `json:"user_id"`
+	SessionID      string     `json:"session_id" gorm:"size:64;index"` // 确认授权的用户会话ID，会话注销后票据失效
+	Interval       int        `json:"interval"`                        // 最小轮询间隔（秒），轮询过快时增加
+	LastPolledAt   *time.Time `json:"last_polled_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+}
+
+func (*SsoOAuthDeviceCode) TableName() (name string) {
+	return "sso_oauth_device_code"
+}
+
 // SsoCASTicket CAS认证票据
 type SsoCASTicket struct {
 	*gorm.Model
@@ -28,18 +79,40 @@ type SsoCASTicket struct {
 	Service    string     `json:"service"`
 	ConsumedAt *time.Time `json:"consumed_at"`
 	UserID     uint       `json:"user_id"`
+	SessionID  string     `json:"session_id" gorm:"size:64;index"` // 签发票据的用户会话ID，会话注销后票据失效
+	SiteID     uint       `json:"site_id"`                         // 签发票据的站点，用于单点注销
+	LogoutAt   *time.Time `json:"logout_at"`                       // 发送单点注销请求的时间，为空时未发送
 }
 
 func (*SsoCASTicket) TableName() (name string) {
 	return "sso_cas_ticket"
 }
 
+// SsoSAMLSession SAML2断言对应的会话，用于单点注销时根据 SessionIndex 找到平台会话及需要通知的SP
+type SsoSAMLSession struct {
+	ID           uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	SessionIndex string     `json:"session_index" gorm:"size:64;uniqueIndex"` // 断言中的 SessionIndex
+	SessionID    string     `json:"session_id" gorm:"size:64;index"`          // 签发断言的用户会话ID
+	SiteID       uint       `json:"site_id"`
+	UserID       uint       `json:"user_id"`
+	NameID       string     `json:"name_id"`   // 断言中的 NameID
+	LogoutAt     *time.Time `json:"logout_at"` // 单点注销的时间，为空时未注销
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (*SsoSAMLSession) TableName() (name string) {
+	return "sso_saml_session"
+}
+
 // SsoNginxTicket Nginx认证票据
 type SsoNginxTicket struct {
 	*gorm.Model
-	ExpiresAt time.Time `json:"expires_at"`
-	Token     string    `json:"code"`
-	UserID    uint      `json:"user_id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	Token     string     `json:"code"`
+	UserID    uint       `json:"user_id"`
+	SiteID    uint       `json:"site_id"`                         // 签发票据的站点，用于获取站点的续期配置
+	SessionID string     `json:"session_id" gorm:"size:64;index"` // 签发票据的用户会话ID，会话注销后票据失效
+	RotatedAt *time.Time `json:"rotated_at"`                      // 续期时间，票据续期后仅在宽限时间内有效
 }
 
 func (*SsoNginxTicket) TableName() (name string) {
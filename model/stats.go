@@ -0,0 +1,58 @@
+package model
+
+import "time"
+
+// StatLoginDaily 登录统计日汇总表，由定时任务根据登录日志汇总
+type StatLoginDaily struct {
+	ID           uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	Date         string `json:"date" gorm:"size:10;index"` // 日期，格式：YYYY-MM-DD
+	AuthMethod   string `json:"auth_method"`               // 认证方式
+	Application  string `json:"application"`               // 登录的应用
+	Status       int    `json:"status"`                    // 1：成功，2：失败
+	FailedReason string `json:"failed_reason"`             // 失败原因
+	Count        int64  `json:"count"`                     // 登录次数
+}
+
+func (*StatLoginDaily) TableName() (name string) {
+	return "stat_login_daily"
+}
+
+// StatUserDaily 用户统计日快照表
+type StatUserDaily struct {
+	ID          uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	Date        string `json:"date" gorm:"size:10;unique"` // 日期，格式：YYYY-MM-DD
+	TotalUsers  int64  `json:"total_users"`                // 用户总数
+	ActiveUsers int64  `json:"active_users"`               // 启用的用户数
+	MFAUsers    int64  `json:"mfa_users"`                  // 已绑定MFA的用户数
+}
+
+func (*StatUserDaily) TableName() (name string) {
+	return "stat_user_daily"
+}
+
+// StatUserLoginDaily 用户登录统计日汇总表，由定时任务根据登录日志汇总
+type StatUserLoginDaily struct {
+	ID          uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Date        string     `json:"date" gorm:"size:10;index"` // 日期，格式：YYYY-MM-DD
+	Username    string     `json:"username" gorm:"index"`     // 用户名
+	Success     int64      `json:"success"`                   // 登录成功次数
+	Failed      int64      `json:"failed"`                    // 登录失败次数
+	LastLoginAt *time.Time `json:"last_login_at"`             // 当天最后一次登录（含失败）的时间
+}
+
+func (*StatUserLoginDaily) TableName() (name string) {
+	return "stat_user_login_daily"
+}
+
+// StatOplogDaily 操作日志统计日汇总表，由定时任务根据操作日志汇总
+type StatOplogDaily struct {
+	ID       uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	Date     string `json:"date" gorm:"size:10;index"` // 日期，格式：YYYY-MM-DD
+	Username string `json:"username"`                  // 操作人
+	Method   string `json:"method"`                    // 请求方法
+	Count    int64  `json:"count"`                     // 操作次数
+}
+
+func (*StatOplogDaily) TableName() (name string) {
+	return "stat_oplog_daily"
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Terms 使用条款（可接受使用政策），内容变更时版本号递增，用户需要重新接受最新版本后才能继续单点登录
+type Terms struct {
+	gorm.Model
+	Name     string  `json:"name" gorm:"unique"`
+	Version  uint    `json:"version" gorm:"default:1"`           // 当前版本号
+	Content  string  `json:"content" gorm:"type:longtext"`       // 条款内容，支持Markdown
+	Enabled  bool    `json:"enabled" gorm:"default:true"`        // 是否启用
+	AllSites bool    `json:"all_sites" gorm:"default:false"`     // 是否对所有应用生效
+	Sites    []*Site `json:"sites" gorm:"many2many:terms_sites"` // 需要接受条款的应用，AllSites为true时忽略
+}
+
+func (*Terms) TableName() (name string) {
+	return "terms"
+}
+
+// TermsVersion 使用条款历史版本
+type TermsVersion struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TermsID   uint      `json:"terms_id" gorm:"index"`
+	Version   uint      `json:"version"`
+	Content   string    `json:"content" gorm:"type:longtext"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*TermsVersion) TableName() (name string) {
+	return "terms_version"
+}
+
+// TermsAcceptance 使用条款接受记录，同一用户对同一版本只记录一次
+type TermsAcceptance struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	TermsID   uint      `json:"terms_id" gorm:"uniqueIndex:idx_terms_acceptance"`
+	Version   uint      `json:"version" gorm:"uniqueIndex:idx_terms_acceptance"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_terms_acceptance"`
+	Name      string    `json:"name"` // 接受时的条款名称
+	Username  string    `json:"username"`
+	Site      string    `json:"site"` // 触发接受的应用名称
+	ClientIP  string    `json:"client_ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (*TermsAcceptance) TableName() (name string) {
+	return "terms_acceptance"
+}
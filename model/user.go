@@ -17,13 +17,23 @@ type AuthUser struct {
 	Username          string       `json:"username" gorm:"unique"`
 	Avatar            *string      `json:"avatar"`
 	Password          string       `json:"password"`
-	PhoneNumber       string       `json:"phone_number"`
+	PhoneNumber       string       `json:"phone_number"`   // 手机号，E.164格式，如：+8613800000000
+	PhoneNational     string       `json:"phone_national"` // 手机号国内格式，用于展示
+	PhoneDisplay      string       `json:"phone_display"`  // 手机号国际格式，用于展示
 	IsActive          bool         `json:"is_active"`
 	Email             string       `json:"email"`
 	LastLoginAt       *time.Time   `json:"last_login_at"`
 	MFACode           *string      `json:"mfa_code"`
 	PasswordExpiredAt *time.Time   `json:"password_expired_at"`
 	UserFrom          string       `json:"user_from" gorm:"default:本地"`
+	Language          string       `json:"language" gorm:"size:16"`            // 首选语言，如：zh-CN、en-US，为空时使用系统默认语言
+	Timezone          string       `json:"timezone" gorm:"size:64"`            // 时区，如：Asia/Shanghai，为空时使用系统时区
+	Department        string       `json:"department"`                         // 部门，可用于动态分组规则
+	Title             string       `json:"title"`                              // 职位，可用于动态分组规则
+	RequiredActions   string       `json:"required_actions"`                   // 登录时必须完成的操作，如：change_password，多个使用逗号分隔
+	ExternalId        *string      `json:"external_id" gorm:"size:128;unique"` // 外部系统（如Terraform）中的资源标识
+	BreakGlass        bool         `json:"break_glass"`                        // 是否为应急账号，仅允许从指定网络使用账号密码及MFA登录
+	BreakGlassUsedAt  *time.Time   `json:"break_glass_used_at"`                // 应急账号首次使用时间，超过使用时间窗口后自动禁用
 	Groups            []*AuthGroup `json:"groups" gorm:"many2many:auth_user_groups"`
 	Accounts          []*Account   `gorm:"many2many:account_users"`
 }
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// UserDevice 用户登录设备，同一用户的设备以设备指纹区分
+type UserDevice struct {
+	gorm.Model
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_user_device"`
+	Username    string    `json:"username" gorm:"index"`
+	Fingerprint string    `json:"fingerprint" gorm:"size:64;uniqueIndex:idx_user_device"` // 设备指纹（SHA256）
+	Browser     string    `json:"browser"`                                                // 浏览器
+	Platform    string    `json:"platform"`                                               // 操作系统
+	Mobile      bool      `json:"mobile"`                                                 // 是否为移动设备
+	UserAgent   string    `json:"user_agent" gorm:"size:512"`
+	FirstIP     string    `json:"first_ip"`
+	LastIP      string    `json:"last_ip"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+	Trusted     bool      `json:"trusted"` // 是否为受信任设备，预留给自适应认证使用
+}
+
+func (*UserDevice) TableName() (name string) {
+	return "user_device"
+}
+
+// UserLoginCountry 用户登录过的国家/地区，用于识别异地登录
+type UserLoginCountry struct {
+	gorm.Model
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_user_country"`
+	Country     string    `json:"country" gorm:"size:8;uniqueIndex:idx_user_country"` // 国家/地区代码（ISO 3166-1）
+	FirstIP     string    `json:"first_ip"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+func (*UserLoginCountry) TableName() (name string) {
+	return "user_login_country"
+}
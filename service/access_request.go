@@ -0,0 +1,361 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"time"
+)
+
+var AccessRequest accessRequest
+
+type accessRequest struct{}
+
+// 访问申请：用户申请访问应用（site）或加入用户组（group，角色分组即为提权），申请提交到外部ITSM中审批，
+// 定时查询审批结果（ITSM也可以通过 /api/v1/itsm/webhook 通知立即查询），审批通过后自动授权，并在操作日志中记录与ITSM工单的对应关系
+
+const (
+	AccessRequestSite  = "site"
+	AccessRequestGroup = "group"
+
+	AccessRequestPending  = "pending"
+	AccessRequestApproved = "approved"
+	AccessRequestRejected = "rejected"
+	AccessRequestFailed   = "failed"
+
+	accessRequestPollInterval = time.Minute
+	accessRequestAuditUser    = "ITSM" // 自动授权时操作日志中的操作人
+)
+
+// AccessRequestCreate 提交访问申请结构体
+type AccessRequestCreate struct {
+	Type     string `json:"type" binding:"required,oneof=site group"`
+	TargetID uint   `json:"target_id" binding:"required"`
+	Reason   string `json:"reason" binding:"required,max=1000"`
+}
+
+// AccessRequestResolve 管理员处理访问申请结构体，用于ITSM故障或提交失败时手动处理
+type AccessRequestResolve struct {
+	Approved *bool  `json:"approved" binding:"required"`
+	Message  string `json:"message"`
+}
+
+// ITSMWebhook ITSM审批结果通知，仅用于触发查询审批结果，审批结果以ITSM接口返回的为准
+type ITSMWebhook struct {
+	ExternalID string `json:"external_id" binding:"required"` // 工单标识：Jira为Issue Key，飞书为审批实例Code
+}
+
+// AccessRequestInit 定时查询审批中的访问申请
+func AccessRequestInit() {
+	go func() {
+		ticker := time.NewTicker(accessRequestPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			AccessRequest.poll()
+		}
+	}()
+}
+
+// GetAccessRequestList 获取访问申请列表
+func (a *accessRequest) GetAccessRequestList(name, status string, page, limit int) (*dao.AccessRequestList, error) {
+	return dao.AccessRequest.GetAccessRequestList(name, status, page, limit)
+}
+
+// GetUserAccessRequests 获取用户提交的访问申请
+func (a *accessRequest) GetUserAccessRequests(userId uint) ([]*model.AccessRequest, error) {
+	return dao.AccessRequest.GetUserAccessRequests(userId)
+}
+
+// AddAccessRequest 提交访问申请并在ITSM中创建审批工单，工单创建失败时申请状态为failed
+func (a *accessRequest) AddAccessRequest(data *AccessRequestCreate, userId uint) (*model.AccessRequest, error) {
+
+	provider := config.GetString("itsmProvider")
+	itsm, err := NewITSMProvider(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := dao.User.GetUser(map[string]interface{}{"id": userId})
+	if err != nil {
+		return nil, err
+	}
+
+	targetName, err := a.checkTarget(data.Type, data.TargetID, user)
+	if err != nil {
+		return nil, err
+	}
+
+	pending, err := dao.AccessRequest.HasPendingAccessRequest(user.ID, data.Type, data.TargetID, AccessRequestPending)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, errors.New("已存在审批中的申请，请勿重复提交")
+	}
+
+	request, err := dao.AccessRequest.AddAccessRequest(&model.AccessRequest{
+		UserID:     user.ID,
+		Username:   user.Username,
+		Type:       data.Type,
+		TargetID:   data.TargetID,
+		TargetName: targetName,
+		Reason:     data.Reason,
+		Status:     AccessRequestPending,
+		Provider:   provider,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	summary := fmt.Sprintf("%s（%s）申请%s：%s", user.Name, user.Username, accessRequestTypeName(data.Type), targetName)
+	description := fmt.Sprintf("申请人：%s（%s）\n申请内容：%s%s\n申请原因：%s\n申请编号：%d\n来源：%s",
+		user.Name, user.Username, accessRequestTypeName(data.Type), targetName, data.Reason, request.ID, config.GetString("issuer"))
+
+	ticket, err := itsm.CreateTicket(request, user, summary, description)
+	if err != nil {
+		logger.Error(fmt.Sprintf("访问申请%d提交到ITSM失败：%s", request.ID, err.Error()))
+		request.Status, request.Message = AccessRequestFailed, "提交审批失败："+err.Error()
+		if err := dao.AccessRequest.UpdateAccessRequest(request.ID, map[string]interface{}{"status": request.Status, "message": request.Message}); err != nil {
+			return nil, err
+		}
+		ConsoleEvent.Publish(ConsoleEventAccessRequest, request)
+		return nil, errors.New(request.Message)
+	}
+
+	request.ExternalID, request.ExternalURL = ticket.ID, ticket.URL
+	if err := dao.AccessRequest.UpdateAccessRequest(request.ID, map[string]interface{}{"external_id": ticket.ID, "external_url": ticket.URL}); err != nil {
+		return nil, err
+	}
+
+	// 推送到管理控制台，提醒管理员关注审批
+	ConsoleEvent.Publish(ConsoleEventAccessRequest, request)
+
+	return request, nil
+}
+
+// ResolveAccessRequest 管理员手动处理审批中或提交失败的访问申请
+func (a *accessRequest) ResolveAccessRequest(id uint, data *AccessRequestResolve, operator string) error {
+
+	request, err := dao.AccessRequest.GetAccessRequest(map[string]interface{}{"id": id})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("访问申请不存在")
+		}
+		return err
+	}
+	if request.Status != AccessRequestPending && request.Status != AccessRequestFailed {
+		return errors.New("访问申请已处理")
+	}
+
+	result := &ITSMResult{Status: AccessRequestRejected, Approver: operator, Message: data.Message}
+	if *data.Approved {
+		result.Status = AccessRequestApproved
+	}
+	return a.resolve(request, request.Status, result)
+}
+
+// Webhook ITSM通知审批结果后立即查询对应的访问申请
+func (a *accessRequest) Webhook(data *ITSMWebhook) error {
+
+	request, err := dao.AccessRequest.GetAccessRequest(map[string]interface{}{"external_id": data.ExternalID, "status": AccessRequestPending})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return a.check(request)
+}
+
+// VerifyWebhookToken 校验ITSM通知的令牌，未配置 itsmWebhookToken 时拒绝所有通知
+func (a *accessRequest) VerifyWebhookToken(value string) bool {
+
+	cipherText := config.GetString("itsmWebhookToken")
+	if cipherText == "" || value == "" {
+		return false
+	}
+	token, err := utils.Decrypt(cipherText)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+}
+
+// poll 查询所有审批中的访问申请
+func (a *accessRequest) poll() {
+
+	requests, err := dao.AccessRequest.GetPendingAccessRequests(AccessRequestPending)
+	if err != nil {
+		logger.Error("获取审批中的访问申请失败：" + err.Error())
+		return
+	}
+
+	for _, request := range requests {
+		if err := a.check(request); err != nil {
+			logger.Error(fmt.Sprintf("查询访问申请%d（%s）的审批结果失败：%s", request.ID, request.ExternalID, err.Error()))
+		}
+	}
+}
+
+// check 查询访问申请在ITSM中的审批结果，审批完成时处理
+func (a *accessRequest) check(request *model.AccessRequest) error {
+
+	itsm, err := NewITSMProvider(request.Provider)
+	if err != nil {
+		return err
+	}
+
+	result, err := itsm.GetResult(request.ExternalID)
+	if err != nil {
+		return err
+	}
+	if result.Status == AccessRequestPending {
+		return nil
+	}
+
+	return a.resolve(request, AccessRequestPending, result)
+}
+
+// resolve 更新访问申请的审批结果，审批通过时授权，授权失败时状态为failed；
+// 仅当申请仍为 from 状态时处理，多个实例或Webhook与定时查询同时处理时只授权一次
+func (a *accessRequest) resolve(request *model.AccessRequest, from string, result *ITSMResult) error {
+
+	ok, err := dao.AccessRequest.ResolveAccessRequest(request.ID, from, result.Status, result.Approver, result.Message)
+	if err != nil || !ok {
+		return err
+	}
+
+	detail := fmt.Sprintf("访问申请%d（%s%s）审批结果：%s", request.ID, accessRequestTypeName(request.Type), request.TargetName, result.Status)
+	if result.Status == AccessRequestApproved {
+		if err := a.grant(request, result.Approver); err != nil {
+			logger.Error(fmt.Sprintf("访问申请%d授权失败：%s", request.ID, err.Error()))
+			detail = fmt.Sprintf("访问申请%d（%s%s）审批通过，授权失败：%s", request.ID, accessRequestTypeName(request.Type), request.TargetName, err.Error())
+			if err := dao.AccessRequest.UpdateAccessRequest(request.ID, map[string]interface{}{"status": AccessRequestFailed, "message": "授权失败：" + err.Error()}); err != nil {
+				logger.Error(fmt.Sprintf("更新访问申请%d状态失败：%s", request.ID, err.Error()))
+			}
+		}
+	}
+
+	a.audit(request, result, detail)
+	return nil
+}
+
+// grant 审批通过后授权：将用户添加到应用或用户组中
+func (a *accessRequest) grant(request *model.AccessRequest, approver string) error {
+
+	user, err := dao.User.GetUser(map[string]interface{}{"id": request.UserID})
+	if err != nil {
+		return err
+	}
+
+	switch request.Type {
+	case AccessRequestSite:
+		var site model.Site
+		if err := global.MySQLClient.First(&site, request.TargetID).Error; err != nil {
+			return err
+		}
+		return dao.Site.AddSiteUser(&site, user)
+	case AccessRequestGroup:
+		var group model.AuthGroup
+		if err := global.MySQLClient.First(&group, request.TargetID).Error; err != nil {
+			return err
+		}
+		users, err := dao.Group.GetGroupUsers(global.MySQLClient, &group)
+		if err != nil {
+			return err
+		}
+		userIds := []uint{user.ID}
+		for _, item := range users {
+			if item.ID == user.ID {
+				return nil
+			}
+			userIds = append(userIds, item.ID)
+		}
+		// 复用修改用户组成员的逻辑，同步角色权限、通知安全管理员及下游系统
+		operator := accessRequestAuditUser
+		if approver != "" {
+			operator = fmt.Sprintf("%s（%s）", accessRequestAuditUser, approver)
+		}
+		_, err = Group.UpdateGroupUser(&GroupUpdateUser{ID: group.ID, Users: userIds}, operator)
+		return err
+	}
+
+	return fmt.Errorf("不支持的申请类型：%s", request.Type)
+}
+
+// audit 在操作日志中记录审批结果，与ITSM工单交叉引用
+func (a *accessRequest) audit(request *model.AccessRequest, result *ITSMResult, detail string) {
+
+	params, _ := json.Marshal(map[string]interface{}{
+		"id":           request.ID,
+		"username":     request.Username,
+		"type":         request.Type,
+		"target_id":    request.TargetID,
+		"target_name":  request.TargetName,
+		"provider":     request.Provider,
+		"external_id":  request.ExternalID,
+		"external_url": request.ExternalURL,
+		"approver":     result.Approver,
+	})
+
+	if err := dao.AccessRequest.AddAuditLog(&model.LogOplog{
+		Username:      accessRequestAuditUser,
+		Endpoint:      fmt.Sprintf("/api/v1/access_request/%d", request.ID),
+		Method:        "ITSM",
+		RequestParams: string(params),
+		ResponseData:  detail,
+	}); err != nil {
+		logger.Error(fmt.Sprintf("记录访问申请%d操作日志失败：%s", request.ID, err.Error()))
+	}
+}
+
+// checkTarget 校验申请的应用或用户组，返回名称
+func (a *accessRequest) checkTarget(requestType string, targetId uint, user *model.AuthUser) (string, error) {
+
+	switch requestType {
+	case AccessRequestSite:
+		var site model.Site
+		if err := global.MySQLClient.First(&site, targetId).Error; err != nil {
+			return "", errors.New("应用不存在")
+		}
+		if site.AllOpen || dao.Site.IsUserInSite(user.ID, &site) {
+			return "", errors.New("您已有该应用的访问权限")
+		}
+		return site.Name, nil
+	case AccessRequestGroup:
+		var group model.AuthGroup
+		if err := global.MySQLClient.First(&group, targetId).Error; err != nil {
+			return "", errors.New("用户组不存在")
+		}
+		if group.DynamicRule != "" {
+			return "", errors.New("动态分组的成员由规则自动计算，不支持申请加入")
+		}
+		usernames, err := dao.Group.GetGroupUsernames(global.MySQLClient, &group)
+		if err != nil {
+			return "", err
+		}
+		if utils.Contains(usernames, user.Username) {
+			return "", errors.New("您已在该用户组中")
+		}
+		return group.Name, nil
+	}
+
+	return "", fmt.Errorf("不支持的申请类型：%s", requestType)
+}
+
+// accessRequestTypeName 申请类型名称
+func accessRequestTypeName(requestType string) string {
+	if requestType == AccessRequestGroup {
+		return "加入用户组"
+	}
+	return "访问应用"
+}
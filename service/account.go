@@ -9,6 +9,7 @@ import (
 	"ops-api/global"
 	"ops-api/model"
 	"ops-api/utils"
+	"ops-api/utils/i18n"
 	"ops-api/utils/mail"
 	"strconv"
 	"time"
@@ -214,12 +215,11 @@ func (a *account) GetAccountPassword(id uint, username string, userId uint) (pas
 
 	// 判断是否需要认证，Redis缓存中指定的Key是否存在，存在则不需要认证，否则需要认证
 	var keyName = fmt.Sprintf("%s_get_account_password_enabled", username)
-	val, err := global.RedisClient.Exists(keyName).Result()
+	exists, err := global.Cache.Exists(keyName)
 	if err != nil {
 		return nil, err
 	}
-	// 0 表示不存在
-	if val == 0 {
+	if !exists {
 		return nil, err
 	}
 
@@ -254,19 +254,18 @@ func (a *account) GetSMSCode(data *GetVerification, userID uint) (err error) {
 	var keyName = fmt.Sprintf("%s_get_account_password_verification_code", user.Username)
 
 	// 判断Redis缓存中指定的Key是否存在
-	val, err := global.RedisClient.Exists(keyName).Result()
+	exists, err := global.Cache.Exists(keyName)
 	if err != nil {
 		return err
 	}
-	// 1 表示已存在
-	if val == 1 {
+	if exists {
 		// 判断Key的有效期，如果Key的有效期大于4分钟，表示在1分钟内发送过验证码，提示用户请勿频繁发送校验码
-		ttl, err := global.RedisClient.TTL(keyName).Result()
+		ttl, err := global.Cache.TTL(keyName)
 		if err != nil {
 			return err
 		}
 		if ttl.Seconds() > 240 {
-			return errors.New("请勿频繁发送校验码")
+			return NewThrottleError(ttl-240*time.Second, "请勿频繁发送校验码")
 		}
 	}
 
@@ -276,7 +275,7 @@ func (a *account) GetSMSCode(data *GetVerification, userID uint) (err error) {
 		if user.PhoneNumber == "" {
 			return errors.New("账号未绑定手机号")
 		}
-		number, err := SMS.SMSSend(user.PhoneNumber, "密码查询")
+		number, err := SMS.SMSSend(user.PhoneNumber, "密码查询", userLocale(user))
 		if err != nil {
 			return err
 		}
@@ -285,7 +284,7 @@ func (a *account) GetSMSCode(data *GetVerification, userID uint) (err error) {
 	if data.ValidateType == 3 {
 
 		// 判断是否开启此功能
-		if config.Conf.Settings["passwordMailResetOff"].(bool) == false {
+		if config.GetBool("passwordMailResetOff") == false {
 			return errors.New("功能未启用，请联系管理员")
 		}
 
@@ -295,41 +294,25 @@ func (a *account) GetSMSCode(data *GetVerification, userID uint) (err error) {
 
 		// 生成HTML内容
 		code = strconv.Itoa(utils.GenerateRandomNumber())
-		htmlBody := GetPasswordHTML(code)
+		locale := userLocale(user)
+		htmlBody := GetPasswordHTML(locale, code)
 
 		// 发送邮件
-		if err := mail.Email.SendMsg([]string{user.Email}, nil, nil, "密码获取", htmlBody, "html"); err != nil {
+		if err := mail.Email.SendMsg([]string{user.Email}, nil, nil, i18n.T(locale, "password_query.subject"), htmlBody, "html"); err != nil {
 			return err
 		}
 	}
 
 	// 将验证码写入Redis缓存，如果已存在则会更新Key的值并刷新TTL
-	if err := global.RedisClient.Set(keyName, code, 5*time.Minute).Err(); err != nil {
+	if err := global.Cache.Set(keyName, code, 5*time.Minute); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func GetPasswordHTML(number string) string {
-
-	issuer := config.Conf.Settings["issuer"].(string)
-
-	return fmt.Sprintf(`
-		<!DOCTYPE html>
-		<html>
-		<head>
-			<meta charset="UTF-8">
-			<title>密码获取</title>
-		</head>
-		<body>
-			<p>您的验证码为：%s，请妥善保管，切勿泄露。</p>
-			<br>
-			<p>此致，<br>%s</p>
-			<p style="color: red">此邮件为系统自动发送，请勿回复此邮件。</p>
-		</body>
-		</html>
-	`, number, issuer)
+func GetPasswordHTML(locale, number string) string {
+	return verificationCodeHTML(locale, i18n.T(locale, "password_query.subject"), number)
 }
 
 // CodeVerification 校验验证码
@@ -348,7 +331,7 @@ func (a *account) CodeVerification(userId uint, data *CodeVerification) (err err
 	if data.ValidateType == 1 || data.ValidateType == 3 {
 
 		// 从缓存中获取验证码
-		result, err := global.RedisClient.Get(fmt.Sprintf("%s_get_account_password_verification_code", user.Username)).Result()
+		result, err := global.Cache.Get(fmt.Sprintf("%s_get_account_password_verification_code", user.Username))
 		if err != nil {
 			return err
 		}
@@ -360,18 +343,18 @@ func (a *account) CodeVerification(userId uint, data *CodeVerification) (err err
 		// MFA验证码校验
 		// 获取Secret
 		if user.MFACode == nil {
-			return errors.New("您还未绑定MFA")
+			return errors.New(i18n.T(userLocale(user), "mfa.not_bound"))
 		}
 
 		// 校验MFA
 		valid := totp.Validate(data.Code, *user.MFACode)
 		if !valid {
-			return errors.New("校验码错误")
+			return errors.New(i18n.T(userLocale(user), "mfa.invalid_code"))
 		}
 	} else {
 		return errors.New("验证码类型错误")
 	}
 
 	// 写入允许用户获取密码的Redis缓存
-	return global.RedisClient.Set(fmt.Sprintf("%s_get_account_password_enabled", user.Username), true, 5*time.Minute).Err()
+	return global.Cache.Set(fmt.Sprintf("%s_get_account_password_enabled", user.Username), "1", 5*time.Minute)
 }
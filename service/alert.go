@@ -0,0 +1,598 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/robfig/cron/v3"
+	"github.com/wonderivan/logger"
+	"gopkg.in/yaml.v3"
+	"html"
+	"math"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/cache"
+	"ops-api/utils/notify"
+	"strings"
+	"time"
+)
+
+var Alert alert
+
+type alert struct{}
+
+// 内置告警规则
+const (
+	AlertLoginFailureSpike   = "login_failure_spike"  // 登录失败次数激增
+	AlertSMSErrorRate        = "sms_error_rate"       // 短信发送失败率过高
+	AlertCertificateExpiring = "certificate_expiring" // 证书即将过期
+	AlertCronJobMissed       = "cron_job_missed"      // 定时任务未按计划执行
+)
+
+const (
+	alertCheckInterval = time.Minute     // 告警规则的检查间隔
+	alertStateKey      = "alert_state:"  // 正在触发的告警的触发时间在缓存中的Key前缀，后接规则名称
+	alertSMSMinSamples = 10              // 统计窗口内短信数量少于该值时不计算失败率，避免少量失败触发告警
+	alertCronGrace     = 5 * time.Minute // 定时任务超过计划执行时间该时长仍未执行时视为错过执行
+)
+
+// alertFiring、alertValue 内置告警状态及当前指标值，部署了Prometheus时可直接使用导出的告警规则
+var (
+	alertFiring = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "alert_firing",
+		Help: "内置告警是否正在触发（1：触发，0：未触发）",
+	}, []string{"alert", "severity"})
+	alertValue = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "alert_value",
+		Help: "内置告警的当前指标值",
+	}, []string{"alert"})
+)
+
+// alertRule 内置告警规则，指标值与阈值按 Operator 比较，阈值小于等于0时不检查该规则
+type alertRule struct {
+	Name      string
+	Alert     string // Prometheus 告警名称
+	Summary   string
+	Severity  string
+	Operator  string // >= 或 <
+	Threshold func() float64
+	Evaluate  func(now time.Time) (value float64, details []string, err error)
+}
+
+// alertRules 内置告警规则
+var alertRules = []*alertRule{
+	{
+		Name:     AlertLoginFailureSpike,
+		Alert:    "IDSphereLoginFailureSpike",
+		Summary:  "登录失败次数激增",
+		Severity: "warning",
+		Operator: ">=",
+		Threshold: func() float64 {
+			return float64(config.GetInt("alertLoginFailureThreshold"))
+		},
+		Evaluate: Alert.loginFailures,
+	},
+	{
+		Name:     AlertSMSErrorRate,
+		Alert:    "IDSphereSMSErrorRate",
+		Summary:  "短信发送失败率过高",
+		Severity: "warning",
+		Operator: ">=",
+		Threshold: func() float64 {
+			return float64(config.GetInt("alertSmsErrorRate"))
+		},
+		Evaluate: Alert.smsErrorRate,
+	},
+	{
+		Name:     AlertCertificateExpiring,
+		Alert:    "IDSphereCertificateExpiring",
+		Summary:  "证书即将过期",
+		Severity: "warning",
+		Operator: "<",
+		Threshold: func() float64 {
+			return float64(config.GetInt("alertCertificateDays"))
+		},
+		Evaluate: Alert.certificateExpiring,
+	},
+	{
+		Name:     AlertCronJobMissed,
+		Alert:    "IDSphereCronJobMissed",
+		Summary:  "定时任务未按计划执行",
+		Severity: "warning",
+		Operator: ">=",
+		Threshold: func() float64 {
+			return 1
+		},
+		Evaluate: Alert.cronJobMissed,
+	},
+}
+
+// AlertStatus 告警状态
+type AlertStatus struct {
+	Name      string     `json:"name"`
+	Summary   string     `json:"summary"`
+	Severity  string     `json:"severity"`
+	Firing    bool       `json:"firing"`
+	Value     float64    `json:"value"`     // 当前指标值
+	Threshold float64    `json:"threshold"` // 告警阈值
+	Details   []string   `json:"details"`
+	Since     *time.Time `json:"since"` // 触发时间，未触发时为空
+}
+
+// alertNotice 告警通知，Resolved 为true时告警已恢复
+type alertNotice struct {
+	Status   *AlertStatus `json:"status"`
+	Resolved bool         `json:"resolved"`
+}
+
+// AlertInit 定时检查内置告警规则，更新告警指标，告警触发及恢复时按“告警通知”任务配置的通知方式发送通知
+func AlertInit() {
+	go func() {
+		ticker := time.NewTicker(alertCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			Alert.check()
+		}
+	}()
+}
+
+// GetAlertList 获取内置告警规则的当前状态
+func (a *alert) GetAlertList() ([]*AlertStatus, error) {
+
+	list := a.evaluate(time.Now())
+
+	for _, status := range list {
+		if !status.Firing {
+			continue
+		}
+		value, err := global.Cache.Get(alertStateKey + status.Name)
+		if errors.Is(err, cache.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if since, err := time.Parse(time.RFC3339, value); err == nil {
+			status.Since = &since
+		}
+	}
+	return list, nil
+}
+
+// AlertNotice 发送正在触发的告警（定时任务调用），用于按任务周期重复提醒未处理的告警
+func (a *alert) AlertNotice(task *model.ScheduledTask) error {
+
+	if task.NotifyType == nil || task.Receiver == nil || *task.Receiver == "" {
+		return errors.New("未配置通知方式及接收人")
+	}
+
+	list, err := a.GetAlertList()
+	if err != nil {
+		return err
+	}
+
+	var notices []*alertNotice
+	for _, status := range list {
+		if status.Firing {
+			notices = append(notices, &alertNotice{Status: status})
+		}
+	}
+	if len(notices) == 0 {
+		logger.Info("告警状态正常.")
+		return nil
+	}
+
+	return a.send(task, notices)
+}
+
+// PrometheusRules 导出内置告警规则对应的Prometheus告警规则（YAML），阈值使用当前配置
+func (a *alert) PrometheusRules() ([]byte, error) {
+
+	type rule struct {
+		Alert       string            `yaml:"alert"`
+		Expr        string            `yaml:"expr"`
+		Labels      map[string]string `yaml:"labels"`
+		Annotations map[string]string `yaml:"annotations"`
+	}
+	type group struct {
+		Name  string  `yaml:"name"`
+		Rules []*rule `yaml:"rules"`
+	}
+
+	rules := make([]*rule, 0, len(alertRules))
+	for _, item := range alertRules {
+		threshold := item.Threshold()
+		if threshold <= 0 {
+			continue
+		}
+		rules = append(rules, &rule{
+			Alert:  item.Alert,
+			Expr:   fmt.Sprintf(`alert_value{alert="%s"} %s %v`, item.Name, item.Operator, threshold),
+			Labels: map[string]string{"severity": item.Severity},
+			Annotations: map[string]string{
+				"summary":     item.Summary,
+				"description": fmt.Sprintf("%s（当前值：{{ $value }}，阈值：%v）", item.Summary, threshold),
+			},
+		})
+	}
+
+	return yaml.Marshal(map[string][]*group{
+		"groups": {{Name: "idsphere", Rules: rules}},
+	})
+}
+
+// check 检查告警规则，告警触发或恢复时发送通知，多个实例同时检查时只有一个实例发送通知
+func (a *alert) check() {
+
+	now := time.Now()
+
+	var notices []*alertNotice
+	for _, status := range a.evaluate(now) {
+		if status.Firing {
+			ok, err := global.Cache.SetNX(alertStateKey+status.Name, now.Format(time.RFC3339), 0)
+			if err != nil {
+				logger.Error("ERROR：保存告警状态失败，", err.Error())
+				continue
+			}
+			if ok {
+				status.Since = &now
+				notices = append(notices, &alertNotice{Status: status})
+			}
+			continue
+		}
+
+		count, err := global.Cache.Del(alertStateKey + status.Name)
+		if err != nil {
+			logger.Error("ERROR：保存告警状态失败，", err.Error())
+			continue
+		}
+		if count > 0 {
+			notices = append(notices, &alertNotice{Status: status, Resolved: true})
+		}
+	}
+
+	if len(notices) == 0 {
+		return
+	}
+	for _, notice := range notices {
+		if notice.Resolved {
+			logger.Info(fmt.Sprintf("告警恢复：%s", notice.Status.Summary))
+		} else {
+			logger.Warn(fmt.Sprintf("告警触发：%s，当前值：%v，阈值：%v", notice.Status.Summary, notice.Status.Value, notice.Status.Threshold))
+		}
+		ConsoleEvent.Publish(ConsoleEventAlert, notice)
+	}
+
+	task, ok := a.notifyTask()
+	if !ok {
+		return
+	}
+	if err := a.send(task, notices); err != nil {
+		logger.Error("ERROR：告警通知发送失败，", err.Error())
+	}
+}
+
+// evaluate 计算所有已启用告警规则的状态并更新告警指标，计算失败的规则视为未触发
+func (a *alert) evaluate(now time.Time) []*AlertStatus {
+
+	list := make([]*AlertStatus, 0, len(alertRules))
+	for _, rule := range alertRules {
+		threshold := rule.Threshold()
+		if threshold <= 0 {
+			alertFiring.DeleteLabelValues(rule.Name, rule.Severity)
+			alertValue.DeleteLabelValues(rule.Name)
+			continue
+		}
+
+		status := &AlertStatus{
+			Name:      rule.Name,
+			Summary:   rule.Summary,
+			Severity:  rule.Severity,
+			Threshold: threshold,
+		}
+		value, details, err := rule.Evaluate(now)
+		if err != nil {
+			logger.Error(fmt.Sprintf("ERROR：告警规则（%s）检查失败，%s", rule.Name, err.Error()))
+			continue
+		}
+		status.Value, status.Details = value, details
+		if rule.Operator == "<" {
+			status.Firing = value < threshold
+		} else {
+			status.Firing = value >= threshold
+		}
+
+		firing := 0.0
+		if status.Firing {
+			firing = 1
+		}
+		alertFiring.WithLabelValues(rule.Name, rule.Severity).Set(firing)
+		alertValue.WithLabelValues(rule.Name).Set(value)
+
+		list = append(list, status)
+	}
+	return list
+}
+
+// alertWindow 登录失败及短信失败率的统计窗口
+func alertWindow() time.Duration {
+	minutes := config.GetInt("alertWindow")
+	if minutes <= 0 {
+		minutes = 15
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// loginFailures 统计窗口内的登录失败次数
+func (a *alert) loginFailures(now time.Time) (float64, []string, error) {
+	window := alertWindow()
+	count, err := dao.Alert.CountLoginFailures(now.Add(-window))
+	if err != nil {
+		return 0, nil, err
+	}
+	return float64(count), []string{fmt.Sprintf("最近%d分钟登录失败%d次", int(window.Minutes()), count)}, nil
+}
+
+// smsErrorRate 统计窗口内的短信发送失败率（百分比）
+func (a *alert) smsErrorRate(now time.Time) (float64, []string, error) {
+	window := alertWindow()
+	total, failed, err := dao.Alert.CountSMS(now.Add(-window))
+	if err != nil {
+		return 0, nil, err
+	}
+	details := []string{fmt.Sprintf("最近%d分钟发送短信%d条，失败%d条", int(window.Minutes()), total, failed)}
+	if total < alertSMSMinSamples {
+		return 0, details, nil
+	}
+	return math.Round(float64(failed)*10000/float64(total)) / 100, details, nil
+}
+
+// certificateExpiring 检查IDP证书、域名证书及SAML应用SP证书，返回最早过期证书的剩余天数，均未进入告警期时返回阈值
+func (a *alert) certificateExpiring(now time.Time) (float64, []string, error) {
+
+	var (
+		days     = config.GetInt("alertCertificateDays")
+		deadline = now.AddDate(0, 0, days)
+		value    = float64(days)
+		details  []string
+	)
+	expiring := func(name string, notAfter time.Time) {
+		remaining := math.Floor(notAfter.Sub(now).Hours() / 24)
+		if remaining < value {
+			value = remaining
+		}
+		if notAfter.Before(now) {
+			details = append(details, fmt.Sprintf("%s已于%s过期", name, utils.FormatTime(notAfter)))
+		} else {
+			details = append(details, fmt.Sprintf("%s将于%s过期", name, utils.FormatTime(notAfter)))
+		}
+	}
+
+	if crt, err := utils.LoadIdpCertificate(); err == nil && crt.NotAfter.Before(deadline) {
+		expiring("IDP证书", crt.NotAfter)
+	}
+
+	certificates, err := dao.Alert.GetExpiringDomainCertificates(deadline)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, item := range certificates {
+		expiring(fmt.Sprintf("域名证书（%s）", item.Domain), *item.ExpirationAt)
+	}
+
+	siteCertificates, err := dao.Site.GetExpiringSiteCertificates(deadline)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, item := range siteCertificates {
+		expiring(fmt.Sprintf("SP证书（%s）", item.Name), item.NotAfter)
+	}
+
+	return value, details, nil
+}
+
+// cronJobMissed 检查已启用的定时任务是否按计划执行，返回错过执行的任务数量
+func (a *alert) cronJobMissed(now time.Time) (float64, []string, error) {
+
+	tasks, err := dao.Alert.GetEnabledTasks()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var details []string
+	for _, task := range tasks {
+		schedule, err := cron.ParseStandard(task.CronExpr)
+		if err != nil {
+			details = append(details, fmt.Sprintf("任务（%s）的执行周期无效", task.Name))
+			continue
+		}
+
+		// 未执行过的任务从最后一次修改时间开始计算
+		last := task.UpdatedAt
+		if task.LastRunAt != nil && task.LastRunAt.After(last) {
+			last = *task.LastRunAt
+		}
+		if next := schedule.Next(last); next.Add(alertCronGrace).Before(now) {
+			details = append(details, fmt.Sprintf("任务（%s）计划于%s执行，但未执行", task.Name, utils.FormatTime(next)))
+		}
+	}
+
+	return float64(len(details)), details, nil
+}
+
+// notifyTask 获取已启用并配置了通知方式的告警通知任务
+func (a *alert) notifyTask() (*model.ScheduledTask, bool) {
+	var task model.ScheduledTask
+	if err := global.MySQLClient.Where("built_in_method = ?", "alert_notify").First(&task).Error; err != nil {
+		return nil, false
+	}
+	if !task.Enabled || task.NotifyType == nil || task.Receiver == nil || *task.Receiver == "" {
+		return nil, false
+	}
+	return &task, true
+}
+
+// send 按任务配置的通知方式发送告警（1：邮件 HTML，3：飞书富文本，其它： Markdown 文档）
+func (a *alert) send(task *model.ScheduledTask, notices []*alertNotice) error {
+
+	var message string
+	switch *task.NotifyType {
+	case 1:
+		message = alertNoticeHTML(notices)
+	case 3:
+		jsonBytes, _ := json.Marshal(alertNoticePost(notices))
+		message = string(jsonBytes)
+	default:
+		message = alertNoticeMarkdown(notices)
+	}
+
+	notifier := notify.GetNotifier(*task)
+	if notifier == nil {
+		return errors.New("不支持的通知方式")
+	}
+	return notifier.SendNotify(message, "告警通知")
+}
+
+// stateText 告警状态
+func (n *alertNotice) stateText() string {
+	if n.Resolved {
+		return "已恢复"
+	}
+	return "触发中"
+}
+
+// sinceText 告警触发时间
+func (n *alertNotice) sinceText() string {
+	if n.Status.Since == nil {
+		return "-"
+	}
+	return utils.FormatTime(*n.Status.Since)
+}
+
+// alertNoticePost 生成飞书 Post 格式的富文本内容
+func alertNoticePost(notices []*alertNotice) map[string]interface{} {
+	var (
+		issuer  = config.GetString("issuer")
+		content = make([][]map[string]interface{}, 0)
+	)
+
+	for i, notice := range notices {
+		stateColor := "red"
+		if notice.Resolved {
+			stateColor = "green"
+		}
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("%d. 告警：", i+1)},
+			{"tag": "text", "text": notice.Status.Summary, "bold": true},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": "   状态："},
+			{"tag": "text", "text": notice.stateText(), "text_color": stateColor},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("   当前值：%v，阈值：%v", notice.Status.Value, notice.Status.Threshold)},
+		})
+		for _, detail := range notice.Status.Details {
+			content = append(content, []map[string]interface{}{
+				{"tag": "text", "text": fmt.Sprintf("   %s", detail)},
+			})
+		}
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("   触发时间：%s", notice.sinceText())},
+		})
+	}
+
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": "--------------------------------\n"},
+	})
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": fmt.Sprintf("来源：%s", issuer)},
+	})
+
+	return map[string]interface{}{
+		"msg_type": "post",
+		"content": map[string]interface{}{
+			"post": map[string]interface{}{
+				"zh_cn": map[string]interface{}{
+					"title":   "告警通知：",
+					"content": content,
+				},
+			},
+		},
+	}
+}
+
+// alertNoticeMarkdown 生成告警通知 Markdown 文档
+func alertNoticeMarkdown(notices []*alertNotice) string {
+	var (
+		builder = &strings.Builder{}
+		issuer  = config.GetString("issuer")
+	)
+
+	builder.WriteString("**告警通知：**\n\n")
+
+	for i, notice := range notices {
+		color := "warning"
+		if notice.Resolved {
+			color = "info"
+		}
+		builder.WriteString(fmt.Sprintf("%d. 告警：**%s**\n\n", i+1, notice.Status.Summary))
+		builder.WriteString(fmt.Sprintf("   状态：<font color=\"%s\">%s</font>\n\n", color, notice.stateText()))
+		builder.WriteString(fmt.Sprintf("   当前值：%v，阈值：%v\n\n", notice.Status.Value, notice.Status.Threshold))
+		for _, detail := range notice.Status.Details {
+			builder.WriteString(fmt.Sprintf("   %s\n\n", detail))
+		}
+		builder.WriteString(fmt.Sprintf("   触发时间：%s\n\n", notice.sinceText()))
+	}
+
+	builder.WriteString("--------------------------------\n")
+	builder.WriteString(fmt.Sprintf("来源：%s\n", issuer))
+
+	return builder.String()
+}
+
+// alertNoticeHTML 告警通知 HTML
+func alertNoticeHTML(notices []*alertNotice) string {
+
+	issuer := config.GetString("issuer")
+
+	var rows strings.Builder
+	for _, notice := range notices {
+		details := make([]string, 0, len(notice.Status.Details))
+		for _, detail := range notice.Status.Details {
+			details = append(details, html.EscapeString(detail))
+		}
+		rows.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%v</td><td>%v</td><td>%s</td><td>%s</td></tr>",
+			notice.Status.Summary,
+			notice.stateText(),
+			notice.Status.Value,
+			notice.Status.Threshold,
+			strings.Join(details, "<br>"),
+			notice.sinceText()))
+	}
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="UTF-8">
+			<title>告警通知</title>
+		</head>
+		<body>
+			<p>以下告警状态发生变化或仍未恢复，请及时处理：</p>
+			<table border="1" cellspacing="0" cellpadding="6">
+				<tr><th>告警</th><th>状态</th><th>当前值</th><th>阈值</th><th>详情</th><th>触发时间</th></tr>
+				%s
+			</table>
+			<br>
+			<p>来源：%s</p>
+			<p style="color: red">此邮件为系统自动发送，请勿回复此邮件。</p>
+		</body>
+		</html>
+	`, rows.String(), html.EscapeString(issuer))
+}
@@ -52,7 +52,7 @@ type ResponseBody struct {
 // GetSMSReceipt 获取短信回执
 func (a *audit) GetSMSReceipt(smsId int) (err error) {
 
-	smsProvider := config.Conf.Settings["smsProvider"].(string)
+	smsProvider := config.SMS().Provider
 
 	// 华为云不需要
 	if smsProvider != "aliyun" {
@@ -141,6 +141,15 @@ func (a *audit) GetOplogList(name string, page, limit int) (data *dao.OplogList,
 	return data, nil
 }
 
+// GetSCIMRecordList 获取SCIM同步记录
+func (a *audit) GetSCIMRecordList(name string, page, limit int) (data *dao.SCIMRecordList, err error) {
+	data, err = dao.Audit.GetSCIMRecordList(name, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 // AddLoginFailedRecord 新增系统登录失败记录
 func (a *audit) AddLoginFailedRecord(tx *gorm.DB, username, userAgent, clientIP, loginMethod, application string, failedReason error) (err error) {
 
@@ -155,7 +164,13 @@ func (a *audit) AddLoginFailedRecord(tx *gorm.DB, username, userAgent, clientIP,
 	}
 
 	// 记录登录客户端信息
-	return dao.Audit.AddLoginRecord(tx, loginRecord)
+	if err := dao.Audit.AddLoginRecord(tx, loginRecord); err != nil {
+		return err
+	}
+
+	// 推送到管理控制台
+	ConsoleEvent.Publish(ConsoleEventLoginFailed, loginRecord)
+	return nil
 }
 
 // AddLoginSuccessRecord 新增系统登录成功记录
@@ -172,5 +187,11 @@ func (a *audit) AddLoginSuccessRecord(tx *gorm.DB, username, userAgent, clientIP
 	}
 
 	// 记录登录客户端信息
-	return dao.Audit.AddLoginRecord(tx, loginRecord)
+	if err := dao.Audit.AddLoginRecord(tx, loginRecord); err != nil {
+		return err
+	}
+
+	// 推送到管理控制台
+	ConsoleEvent.Publish(ConsoleEventLogin, loginRecord)
+	return nil
 }
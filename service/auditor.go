@@ -0,0 +1,101 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/middleware"
+	"ops-api/model"
+	"time"
+)
+
+var Auditor auditor
+
+type auditor struct{}
+
+// AuditorGrantCreate 授予审计员结构体
+type AuditorGrantCreate struct {
+	UserID    uint      `json:"user_id" binding:"required"`
+	Reason    string    `json:"reason" binding:"required,max=255"`
+	ExpiresAt time.Time `json:"expires_at" binding:"required"` // 到期时间，最长为系统配置的 auditorMaxDays 天
+}
+
+// GetAuditorGrantList 获取审计员授权列表
+func (a *auditor) GetAuditorGrantList(name string, page, limit int) (*dao.AuditorGrantList, error) {
+	return dao.Auditor.GetAuditorGrantList(name, page, limit)
+}
+
+// AddAuditorGrant 授予审计员，有效期内用户只能以只读方式查看用户、权限、登录记录及系统配置（个人信息脱敏），到期后自动失效
+func (a *auditor) AddAuditorGrant(data *AuditorGrantCreate, operator string) (*model.AuditorGrant, error) {
+
+	now := time.Now()
+	maxDays := config.GetInt("auditorMaxDays")
+	if !data.ExpiresAt.After(now) {
+		return nil, errors.New("到期时间必须晚于当前时间")
+	}
+	if data.ExpiresAt.After(now.AddDate(0, 0, maxDays)) {
+		return nil, fmt.Errorf("审计员有效期不能超过%d天", maxDays)
+	}
+
+	user, err := dao.User.GetUser(map[string]interface{}{"id": data.UserID})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	if user.Username == "admin" {
+		return nil, errors.New("不能将超级管理员设置为审计员")
+	}
+	if !user.IsActive {
+		return nil, errors.New("用户已禁用")
+	}
+
+	exists, err := dao.Auditor.HasActiveAuditorGrant(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, errors.New("用户已是审计员，请先撤销后再重新授予")
+	}
+
+	grant, err := dao.Auditor.AddAuditorGrant(&model.AuditorGrant{
+		UserID:    user.ID,
+		Username:  user.Username,
+		Reason:    data.Reason,
+		ExpiresAt: data.ExpiresAt,
+		GrantedBy: operator,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	middleware.ReloadAuditors()
+	SecurityEvent.Publish(SecurityEventAuditorChanged, operator, user.Username, fmt.Sprintf("授予审计员，有效期至%s，事由：%s", data.ExpiresAt.Format("2006-01-02 15:04"), data.Reason))
+	return grant, nil
+}
+
+// RevokeAuditorGrant 提前撤销审计员
+func (a *auditor) RevokeAuditorGrant(id uint, operator string) error {
+
+	grant, err := dao.Auditor.GetAuditorGrant(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("审计员授权不存在")
+		}
+		return err
+	}
+	if grant.RevokedAt != nil {
+		return errors.New("审计员授权已撤销")
+	}
+
+	if err := dao.Auditor.RevokeAuditorGrant(grant.ID, operator); err != nil {
+		return err
+	}
+
+	middleware.ReloadAuditors()
+	SecurityEvent.Publish(SecurityEventAuditorChanged, operator, grant.Username, "撤销审计员")
+	return nil
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gopkg.in/yaml.v3"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"os"
+	"strings"
+)
+
+// 启动时导入应用配置：配置文件（config.yaml）中的 bootstrap 指定的文件中声明的站点（CAS服务、OAuth2.0客户端、SAML2 SP等）
+// 在服务启动时同步到数据库，站点以 id 作为外部标识匹配，不存在时创建、已存在时更新，文件中删除的站点不会从数据库中删除；
+// 文件内容支持 ${ENV} 形式引用环境变量，用于避免在文件中保存客户端密钥
+
+// bootstrapProtocols 站点协议名称与单点登录类型的对应关系
+var bootstrapProtocols = map[string]uint{
+	"cas":   1,
+	"oauth": 2,
+	"saml":  3,
+	"nginx": 4,
+	"wsfed": 5,
+}
+
+// BootstrapFile 启动时导入的应用配置文件
+type BootstrapFile struct {
+	Sites []*BootstrapSite `yaml:"sites"`
+}
+
+// BootstrapSite 启动时导入的站点
+type BootstrapSite struct {
+	ID          string   `yaml:"id"`    // 唯一标识，绑定为站点的外部标识，用于匹配已导入的站点
+	Group       string   `yaml:"group"` // 站点分组名称，不存在时自动创建
+	Name        string   `yaml:"name"`
+	Address     string   `yaml:"address"`
+	Description string   `yaml:"description"`
+	Protocol    string   `yaml:"protocol"` // 单点登录协议：cas、oauth、saml、nginx、wsfed，为空时不开启单点登录
+	AllOpen     bool     `yaml:"all_open"`
+	CallbackUrl string   `yaml:"callback_url"`
+	EntityId    string   `yaml:"entity_id"`
+	Certificate string   `yaml:"certificate"`
+	AcsUrls     []string `yaml:"acs_urls"`
+	GrantTypes  string   `yaml:"grant_types"`
+	RespTypes   string   `yaml:"response_types"`
+	Scopes      string   `yaml:"scopes"`
+	CASProfile  string   `yaml:"cas_profile"`
+	// OAuth2.0 客户端凭据，为空时使用创建站点时生成的凭据，指定后各环境可使用相同的凭据
+	ClientId     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+}
+
+// ImportBootstrap 导入配置文件中声明的站点，单个站点导入失败时记录日志并继续导入其它站点
+func ImportBootstrap() error {
+
+	if config.Conf == nil || config.Conf.Bootstrap == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(config.Conf.Bootstrap)
+	if err != nil {
+		return err
+	}
+
+	var file BootstrapFile
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(content))), &file); err != nil {
+		return fmt.Errorf("解析文件%s失败：%s", config.Conf.Bootstrap, err.Error())
+	}
+
+	var created, updated, failed int
+	for _, item := range file.Sites {
+		isNew, err := importBootstrapSite(item)
+		if err != nil {
+			logger.Error(fmt.Sprintf("导入站点%s（%s）失败：%s", item.Name, item.ID, err.Error()))
+			failed++
+			continue
+		}
+		if isNew {
+			created++
+		} else {
+			updated++
+		}
+	}
+
+	logger.Info(fmt.Sprintf("应用配置导入完成，新建 %d 个站点，更新 %d 个站点，失败 %d 个站点", created, updated, failed))
+	return nil
+}
+
+// importBootstrapSite 创建或更新站点，返回站点是否为新建
+func importBootstrapSite(item *BootstrapSite) (bool, error) {
+
+	if item.ID == "" || item.Name == "" || item.Address == "" || item.Group == "" {
+		return false, errors.New("id、name、address、group 不能为空")
+	}
+	if (item.ClientId == "") != (item.ClientSecret == "") {
+		return false, errors.New("client_id 与 client_secret 需要同时指定")
+	}
+
+	data := &ExternalSite{
+		Name:        item.Name,
+		Address:     item.Address,
+		Description: item.Description,
+		AllOpen:     item.AllOpen,
+		CallbackUrl: item.CallbackUrl,
+		EntityId:    item.EntityId,
+		Certificate: item.Certificate,
+		GrantTypes:  item.GrantTypes,
+		RespTypes:   item.RespTypes,
+		Scopes:      item.Scopes,
+		CASProfile:  item.CASProfile,
+	}
+	if data.Description == "" {
+		data.Description = item.Name
+	}
+	if item.Protocol != "" {
+		ssoType, ok := bootstrapProtocols[strings.ToLower(item.Protocol)]
+		if !ok {
+			return false, fmt.Errorf("不支持的协议：%s", item.Protocol)
+		}
+		data.SSO = true
+		data.SSOType = ssoType
+	}
+	if len(item.AcsUrls) > 0 {
+		acsUrls, err := json.Marshal(item.AcsUrls)
+		if err != nil {
+			return false, err
+		}
+		data.AcsUrls = string(acsUrls)
+	}
+
+	group, err := dao.Site.GetOrCreateGroup(global.MySQLClient, item.Group)
+	if err != nil {
+		return false, err
+	}
+	data.SiteGroupID = group.ID
+
+	_, _, created, err := External.PutSite(item.ID, &Preconditions{}, data)
+	if err != nil {
+		return false, err
+	}
+
+	// 使用指定的客户端凭据
+	if item.ClientId != "" {
+		site, err := dao.External.GetSite(item.ID)
+		if err != nil {
+			return created, err
+		}
+		if site.ClientId != item.ClientId || site.ClientSecret != item.ClientSecret {
+			if err := dao.Site.UpdateClientCredentials(site, item.ClientId, item.ClientSecret); err != nil {
+				return created, err
+			}
+		}
+	}
+
+	return created, nil
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/middleware"
+	"ops-api/model"
+	"ops-api/utils"
+	"time"
+)
+
+var BreakGlass breakGlass
+
+type breakGlass struct{}
+
+// breakGlassCheckInterval 检查应急账号使用时间窗口的间隔
+const breakGlassCheckInterval = time.Minute
+
+// BreakGlassUpdate 设置应急账号结构体
+type BreakGlassUpdate struct {
+	ID      uint  `json:"id" binding:"required"`
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// BreakGlassInit 定时禁用超过使用时间窗口的应急账号
+func BreakGlassInit() {
+	go func() {
+		ticker := time.NewTicker(breakGlassCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			BreakGlass.disableExpired()
+		}
+	}()
+}
+
+// Update 设置或取消应急账号，应急账号必须为本地账号并已绑定MFA，上游身份源（LDAP、Keycloak等）故障时仍可登录
+func (b *breakGlass) Update(data *BreakGlassUpdate, operator string) error {
+
+	user, err := dao.User.GetUser(map[string]interface{}{"id": data.ID})
+	if err != nil {
+		return err
+	}
+
+	if *data.Enabled {
+		if user.UserFrom != "本地" {
+			return errors.New("应急账号必须为本地账号")
+		}
+		if user.MFACode == nil {
+			return errors.New("请先为该账号绑定MFA")
+		}
+	}
+
+	if err := dao.User.UpdateUserBreakGlass(user.ID, *data.Enabled); err != nil {
+		return err
+	}
+
+	detail := "设置为应急账号"
+	if !*data.Enabled {
+		detail = "取消应急账号"
+	}
+	SecurityEvent.Publish(SecurityEventBreakGlassChanged, operator, user.Username, detail)
+
+	return nil
+}
+
+// Check 应急账号登录检查：仅允许从指定网络登录、必须已绑定MFA且未超过使用时间窗口，被拒绝时发送告警
+func (b *breakGlass) Check(user *model.AuthUser, clientIP string) error {
+
+	if !middleware.InConfigNetworks(clientIP, "breakGlassNetworks") {
+		SecurityEvent.Publish(SecurityEventBreakGlassDenied, user.Username, user.Username, fmt.Sprintf("来源IP（%s）不在允许登录的网络中", clientIP))
+		return errors.New("拒绝登录，请联系管理员")
+	}
+
+	if user.MFACode == nil {
+		SecurityEvent.Publish(SecurityEventBreakGlassDenied, user.Username, user.Username, fmt.Sprintf("账号未绑定MFA，来源IP：%s", clientIP))
+		return errors.New("拒绝登录，请联系管理员")
+	}
+
+	if user.BreakGlassUsedAt != nil && time.Now().After(user.BreakGlassUsedAt.Add(b.window())) {
+		SecurityEvent.Publish(SecurityEventBreakGlassDenied, user.Username, user.Username, fmt.Sprintf("已超过使用时间窗口，来源IP：%s", clientIP))
+		return errors.New("拒绝登录，请联系管理员")
+	}
+
+	return nil
+}
+
+// Activate 应急账号登录成功后记录首次使用时间并发送告警
+func (b *breakGlass) Activate(user *model.AuthUser, clientIP string) {
+
+	usedAt := time.Now()
+	if user.BreakGlassUsedAt != nil {
+		usedAt = *user.BreakGlassUsedAt
+	} else if err := dao.User.MarkBreakGlassUsed(user.ID, usedAt); err != nil {
+		logger.Error("ERROR：记录应急账号使用时间失败，", err.Error())
+	}
+
+	SecurityEvent.Publish(SecurityEventBreakGlassLogin, user.Username, user.Username,
+		fmt.Sprintf("来源IP：%s，账号将于 %s 自动禁用", clientIP, utils.FormatTime(usedAt.Add(b.window()))))
+}
+
+// window 应急账号使用时间窗口
+func (b *breakGlass) window() time.Duration {
+	minutes := config.GetInt("breakGlassWindow")
+	if minutes <= 0 {
+		minutes = 60
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// disableExpired 禁用超过使用时间窗口的应急账号并注销其所有会话，多个实例同时执行时仅更新一次
+func (b *breakGlass) disableExpired() {
+
+	// 多实例部署时仅由一个实例执行
+	if ok, err := global.Cache.SetNX("break_glass_check", "1", breakGlassCheckInterval/2); err != nil || !ok {
+		return
+	}
+
+	users, err := dao.User.GetExpiredBreakGlassUsers(time.Now().Add(-b.window()))
+	if err != nil {
+		logger.Error("ERROR：获取应急账号失败，", err.Error())
+		return
+	}
+
+	for _, user := range users {
+		if err := dao.User.DisableBreakGlassUser(user.ID); err != nil {
+			logger.Error("ERROR：禁用应急账号失败，", err.Error())
+			continue
+		}
+		if err := Session.RevokeUser(user.ID); err != nil {
+			logger.Error("ERROR：注销应急账号会话失败，", err.Error())
+		}
+		SecurityEvent.Publish(SecurityEventBreakGlassDisabled, "system", user.Username,
+			fmt.Sprintf("首次使用时间：%s，已超过使用时间窗口，账号已禁用并强制下线", utils.FormatTime(*user.BreakGlassUsedAt)))
+	}
+}
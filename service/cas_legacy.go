@@ -0,0 +1,62 @@
+package service
+
+import (
+	"errors"
+)
+
+// CAS1.0及CAS2.0票据校验：复用CAS3.0的票据校验，CAS1.0返回纯文本（yes/no及用户名），
+// CAS2.0返回不含用户属性的XML，校验失败时返回 authenticationFailure 及错误码
+
+// CAS票据校验失败的错误码
+const (
+	CASInvalidRequest = "INVALID_REQUEST" // 缺少必需的参数
+	CASInvalidTicket  = "INVALID_TICKET"  // 票据不存在、已过期、已使用或签名错误
+	CASInvalidService = "INVALID_SERVICE" // 应用未注册
+	CASInternalError  = "INTERNAL_ERROR"  // 服务端错误
+)
+
+var (
+	ErrCASInvalidService = errors.New("service string is invalid")
+	ErrCASInvalidTicket  = errors.New("ticket string is invalid")
+)
+
+// Validate CAS1.0客户端票据校验，返回用户名
+func (s *sso) Validate(param *CASServiceValidate) (username string, err error) {
+	response, err := s.ServiceValidate(param)
+	if err != nil {
+		return "", err
+	}
+	return response.AuthenticationSuccess.User, nil
+}
+
+// ServiceValidateV2 CAS2.0客户端票据校验，响应中不包含用户属性，校验失败时返回包含错误码的响应
+func (s *sso) ServiceValidateV2(param *CASServiceValidate) *CASServiceResponse {
+	response, err := s.ServiceValidate(param)
+	if err != nil {
+		return CASFailureResponse(casErrorCode(err), err.Error())
+	}
+	response.AuthenticationSuccess.Attributes = nil
+	return response
+}
+
+// CASFailureResponse 票据校验失败响应
+func CASFailureResponse(code, message string) *CASServiceResponse {
+	return &CASServiceResponse{
+		Xmlns: "http://www.yale.edu/tp/cas",
+		AuthenticationFailure: &AuthenticationFailure{
+			Code:    code,
+			Message: message,
+		},
+	}
+}
+
+// casErrorCode 获取票据校验错误对应的错误码
+func casErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrCASInvalidService):
+		return CASInvalidService
+	case errors.Is(err, ErrCASInvalidTicket):
+		return CASInvalidTicket
+	}
+	return CASInternalError
+}
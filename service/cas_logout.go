@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"net/url"
+	"ops-api/dao"
+	"ops-api/model"
+	"strings"
+	"time"
+)
+
+var CASLogout casLogout
+
+type casLogout struct{}
+
+const (
+	casLogoutTimeout = 5 * time.Second // 注销请求超时时间
+	casLogoutRetries = 3               // 注销请求失败时的最大尝试次数
+)
+
+// Notify CAS单点注销：用户注销或会话被注销时，向会话中已校验票据的回调地址发送注销请求（POST logoutRequest），
+// 应用根据 SessionIndex 中的票据注销对应的本地会话；仅通知开启单点注销的站点，通知失败不影响会话注销
+func (c *casLogout) Notify(sessionId string) {
+
+	if sessionId == "" {
+		return
+	}
+
+	tickets, err := dao.SSO.ClaimCASLogoutTickets(sessionId)
+	if err != nil {
+		logger.Error("ERROR：获取CAS单点注销票据失败，", err.Error())
+	}
+
+	for _, ticket := range tickets {
+		go c.send(ticket)
+	}
+}
+
+// send 发送注销请求，失败时重试
+func (c *casLogout) send(ticket *model.SsoCASTicket) {
+	for i := 1; ; i++ {
+		err := c.request(ticket)
+		if err == nil {
+			return
+		}
+		if i >= casLogoutRetries {
+			logger.Error(fmt.Sprintf("ERROR：CAS单点注销请求（%s）发送失败，%s", ticket.Service, err.Error()))
+			return
+		}
+		time.Sleep(time.Duration(i) * time.Second)
+	}
+}
+
+// request 以表单方式发送 SAML LogoutRequest 格式的注销请求，返回2xx或3xx状态码时视为发送成功
+func (c *casLogout) request(ticket *model.SsoCASTicket) error {
+
+	form := url.Values{"logoutRequest": {casLogoutRequest(ticket.Ticket, time.Now())}}
+	req, err := http.NewRequest(http.MethodPost, ticket.Service, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{
+		Timeout: casLogoutTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// casLogoutRequest 生成注销请求，格式参考CAS协议规范（SAML 2.0 LogoutRequest），SessionIndex 为签发的票据
+func casLogoutRequest(st string, now time.Time) string {
+	var index bytes.Buffer
+	_ = xml.EscapeText(&index, []byte(st))
+
+	return fmt.Sprintf(`<samlp:LogoutRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="LR-%s" Version="2.0" IssueInstant="%s"><saml:NameID>@NOT_USED@</saml:NameID><samlp:SessionIndex>%s</samlp:SessionIndex></samlp:LogoutRequest>`,
+		uuid.NewString(), now.UTC().Format("2006-01-02T15:04:05Z"), index.String())
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"ops-api/model"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// CAS3.0 票据校验响应格式，用于从旧CAS服务端迁移时按应用逐个切换
+const (
+	CASProfileDefault   = ""           // 默认格式：<cas:attributes> 中的属性不带命名空间前缀
+	CASProfileApereo    = "apereo"     // Apereo CAS 格式：属性带 cas: 前缀，如 <cas:email>
+	CASProfileNameValue = "name_value" // JA-SIG 旧版格式：<cas:attribute name="email" value="..."/>
+	CASProfileDual      = "dual"       // 兼容模式：同时返回默认格式及 Apereo CAS 格式的属性
+)
+
+// casDefaultAttributes 未配置属性映射时兼容格式返回的属性：应用侧属性名 -> 用户属性
+var casDefaultAttributes = map[string]string{
+	"id":           "id",
+	"name":         "name",
+	"username":     "username",
+	"email":        "email",
+	"phone_number": "phone_number",
+}
+
+// casAttributeNamePattern 作为XML元素名的属性名格式
+var casAttributeNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// casAttribute 兼容格式的单个属性值，多值属性按值拆分为多个
+type casAttribute struct {
+	name  string
+	value string
+}
+
+// validateCASProfile 校验站点配置的CAS3.0响应格式
+func validateCASProfile(profile string) error {
+	switch profile {
+	case CASProfileDefault, CASProfileApereo, CASProfileNameValue, CASProfileDual:
+		return nil
+	}
+	return fmt.Errorf("不支持的CAS响应格式：%s", profile)
+}
+
+// casCompatAttributes 获取兼容格式返回的属性，站点配置了属性映射时使用属性映射，属性名按字母顺序排列
+func casCompatAttributes(site *model.Site, user *model.AuthUser) ([]casAttribute, error) {
+
+	mapping := casDefaultAttributes
+	if strings.TrimSpace(site.ClaimMapping) != "" {
+		mapping = make(map[string]string)
+		if err := json.Unmarshal([]byte(site.ClaimMapping), &mapping); err != nil {
+			return nil, errors.New("应用属性映射配置错误")
+		}
+	}
+
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var attributes []casAttribute
+	for _, name := range names {
+		for _, value := range getUserClaimValues(user, mapping[name]) {
+			attributes = append(attributes, casAttribute{name: name, value: value})
+		}
+	}
+	return attributes, nil
+}
+
+// MarshalXML 按站点配置的CAS3.0响应格式输出用户属性
+func (a Attributes) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+
+	// 默认格式
+	if a.profile == CASProfileDefault || a.profile == CASProfileDual {
+		for _, item := range []casAttribute{
+			{"id", fmt.Sprintf("%d", a.Id)},
+			{"name", a.Name},
+			{"username", a.Username},
+			{"email", a.Email},
+			{"phone_number", a.PhoneNumber},
+		} {
+			if err := e.EncodeElement(item.value, xml.StartElement{Name: xml.Name{Local: item.name}}); err != nil {
+				return err
+			}
+		}
+	}
+
+	// 兼容格式
+	for _, item := range a.compat {
+		element := xml.StartElement{Name: xml.Name{Local: "cas:" + item.name}}
+		value := item.value
+		if a.profile == CASProfileNameValue {
+			element = xml.StartElement{
+				Name: xml.Name{Local: "cas:attribute"},
+				Attr: []xml.Attr{{Name: xml.Name{Local: "name"}, Value: item.name}, {Name: xml.Name{Local: "value"}, Value: item.value}},
+			}
+			value = ""
+		} else if !casAttributeNamePattern.MatchString(item.name) {
+			// 属性名不能作为XML元素名时跳过
+			continue
+		}
+		if err := e.EncodeElement(value, element); err != nil {
+			return err
+		}
+	}
+
+	return e.EncodeToken(start.End())
+}
@@ -23,6 +23,7 @@ import (
 	"ops-api/dao"
 	"ops-api/global"
 	"ops-api/model"
+	"ops-api/utils"
 	"ops-api/utils/notify"
 	"os"
 	"strings"
@@ -342,7 +343,7 @@ func (c *certificate) CertificateExpiredNotice(task *model.ScheduledTask) error
 func certificateExpiredNoticePost(certs []*model.DomainCertificate) map[string]interface{} {
 	var (
 		now     = time.Now()
-		issuer  = config.Conf.Settings["issuer"].(string)
+		issuer  = config.GetString("issuer")
 		content = make([][]map[string]interface{}, 0)
 	)
 
@@ -354,7 +355,7 @@ func certificateExpiredNoticePost(certs []*model.DomainCertificate) map[string]i
 		)
 
 		if crt.ExpirationAt != nil {
-			expiredAt = crt.ExpirationAt.Format("2006-01-02 15:04:05")
+			expiredAt = utils.FormatTime(*crt.ExpirationAt)
 			if crt.ExpirationAt.Before(now) {
 				statusText = "已过期"
 				statusColor = "red"
@@ -405,9 +406,9 @@ func certificateExpiredNoticePost(certs []*model.DomainCertificate) map[string]i
 // certificateExpiredNoticeMarkdown 生成证书过期通知 Markdown 文档
 func certificateExpiredNoticeMarkdown(certs []*model.DomainCertificate) string {
 	var (
-		builder   = &strings.Builder{}
-		now       = time.Now()
-		issuer, _ = config.Conf.Settings["issuer"].(string)
+		builder = &strings.Builder{}
+		now     = time.Now()
+		issuer  = config.GetString("issuer")
 	)
 
 	builder.WriteString("**证书异常提醒：**\n\n")
@@ -424,7 +425,7 @@ func certificateExpiredNoticeMarkdown(certs []*model.DomainCertificate) string {
 			}
 		}
 
-		expiredAt := crt.ExpirationAt.Format("2006-01-02 15:04:05")
+		expiredAt := utils.FormatTime(*crt.ExpirationAt)
 		builder.WriteString(fmt.Sprintf("%d. 域名：%s\n\n", i+1, crt.Domain))
 		builder.WriteString(fmt.Sprintf("   到期时间：%s\n\n", expiredAt))
 		builder.WriteString(fmt.Sprintf("   状态：%s\n\n", statusText))
@@ -440,7 +441,7 @@ func certificateExpiredNoticeMarkdown(certs []*model.DomainCertificate) string {
 func certificateExpiredNoticeHTML(certificates []*model.DomainCertificate) string {
 
 	var (
-		issuer = config.Conf.Settings["issuer"].(string)
+		issuer = config.GetString("issuer")
 		now    = time.Now()
 	)
 
@@ -581,7 +582,7 @@ func generateCertificateRows(certificates []*model.DomainCertificate, now time.T
 		}
 
 		// 格式化过期时间
-		expiredAt := certificate.ExpirationAt.Format("2006-01-02 15:04:05")
+		expiredAt := utils.FormatTime(*certificate.ExpirationAt)
 
 		rows.WriteString(fmt.Sprintf(`
             <tr>
@@ -0,0 +1,484 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ops-api/model"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 属性转换表达式：站点属性映射（claim_mapping）的值除用户属性名外，还可以使用表达式在签发时计算属性值，如：
+// lower(username) + "@corp.com"、substring(phone_number, 3)、default(email, username + "@corp.com")；
+// 表达式仅支持字符串字面量、用户属性、+ 拼接及内置函数，不支持循环及访问其它数据，签发时按用户属性计算，
+// 多值属性（groups）按值分别计算，计算结果为空时不返回该属性
+
+const (
+	claimExprMaxLength = 512 // 表达式最大长度
+	claimExprMaxDepth  = 16  // 表达式最大嵌套层数
+)
+
+// claimFields 属性转换表达式支持的用户属性，groups 为多值属性
+var claimFields = map[string]func(user *model.AuthUser) []string{
+	"id":           func(user *model.AuthUser) []string { return []string{strconv.FormatUint(uint64(user.ID), 10)} },
+	"username":     func(user *model.AuthUser) []string { return []string{user.Username} },
+	"name":         func(user *model.AuthUser) []string { return []string{user.Name} },
+	"email":        func(user *model.AuthUser) []string { return []string{user.Email} },
+	"phone_number": func(user *model.AuthUser) []string { return []string{user.PhoneNumber} },
+	"department":   func(user *model.AuthUser) []string { return []string{user.Department} },
+	"title":        func(user *model.AuthUser) []string { return []string{user.Title} },
+	"ctyun_id": func(user *model.AuthUser) []string {
+		if user.CtyunId == nil {
+			return nil
+		}
+		return []string{*user.CtyunId}
+	},
+	"groups": func(user *model.AuthUser) []string {
+		groups := make([]string, 0, len(user.Groups))
+		for _, group := range user.Groups {
+			groups = append(groups, group.Name)
+		}
+		return groups
+	},
+}
+
+// claimFunction 属性转换表达式内置函数，fn 对单个值进行转换，返回空字符串表示没有值
+type claimFunction struct {
+	args  []string // 参数类型：value 为任意表达式，string 为字符串字面量，int 为整数字面量
+	extra int      // 可选参数个数（位于参数列表末尾）
+	fn    func(value string, args []string, ints []int) string
+}
+
+// claimFunctions 属性转换表达式支持的内置函数，default 及 join 在 evaluate 中单独处理
+var claimFunctions = map[string]*claimFunction{
+	"lower": {args: []string{"value"}, fn: func(value string, _ []string, _ []int) string { return strings.ToLower(value) }},
+	"upper": {args: []string{"value"}, fn: func(value string, _ []string, _ []int) string { return strings.ToUpper(value) }},
+	"trim":  {args: []string{"value"}, fn: func(value string, _ []string, _ []int) string { return strings.TrimSpace(value) }},
+	"replace": {args: []string{"value", "string", "string"}, fn: func(value string, args []string, _ []int) string {
+		return strings.ReplaceAll(value, args[0], args[1])
+	}},
+	// substring(value, start[, end])，按字符截取，start从0开始，超出范围时截取到末尾
+	"substring": {args: []string{"value", "int", "int"}, extra: 1, fn: func(value string, _ []string, ints []int) string {
+		runes := []rune(value)
+		start, end := ints[0], len(runes)
+		if len(ints) > 1 && ints[1] < end {
+			end = ints[1]
+		}
+		if start >= end {
+			return ""
+		}
+		return string(runes[start:end])
+	}},
+	// split(value, sep, index)，按分隔符拆分后取第index段（从0开始），如：split(email, "@", 0)
+	"split": {args: []string{"value", "string", "int"}, fn: func(value string, args []string, ints []int) string {
+		parts := strings.Split(value, args[0])
+		if ints[0] >= len(parts) {
+			return ""
+		}
+		return parts[ints[0]]
+	}},
+	// default(value, fallback)，value 为空时使用 fallback
+	"default": {args: []string{"value", "value"}},
+	// join(value, sep)，将多值属性合并为一个值
+	"join": {args: []string{"value", "string"}},
+}
+
+// claimExpr 属性转换表达式节点
+type claimExpr interface {
+	evaluate(user *model.AuthUser) []string
+	multiple() bool // 是否可能返回多个值
+}
+
+// claimLiteral 字符串字面量
+type claimLiteral struct {
+	value string
+}
+
+func (e *claimLiteral) evaluate(*model.AuthUser) []string {
+	if e.value == "" {
+		return nil
+	}
+	return []string{e.value}
+}
+
+func (e *claimLiteral) multiple() bool { return false }
+
+// claimField 用户属性
+type claimField struct {
+	name string
+}
+
+func (e *claimField) evaluate(user *model.AuthUser) []string {
+	var values []string
+	for _, value := range claimFields[e.name](user) {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func (e *claimField) multiple() bool { return e.name == "groups" }
+
+// claimConcat 拼接，任一部分为空时结果为空（可以使用default函数设置默认值），多值属性与单值拼接时按值分别拼接
+type claimConcat struct {
+	left, right claimExpr
+}
+
+func (e *claimConcat) evaluate(user *model.AuthUser) []string {
+	left, right := e.left.evaluate(user), e.right.evaluate(user)
+	if len(left) == 0 || len(right) == 0 {
+		return nil
+	}
+	var values []string
+	for _, l := range left {
+		for _, r := range right {
+			values = append(values, l+r)
+		}
+	}
+	return values
+}
+
+func (e *claimConcat) multiple() bool { return e.left.multiple() || e.right.multiple() }
+
+// claimCall 函数调用
+type claimCall struct {
+	name  string
+	value claimExpr
+	other claimExpr // default函数的默认值
+	args  []string  // 字符串字面量参数
+	ints  []int     // 整数字面量参数
+}
+
+func (e *claimCall) evaluate(user *model.AuthUser) []string {
+	values := e.value.evaluate(user)
+
+	switch e.name {
+	case "default":
+		if len(values) == 0 {
+			return e.other.evaluate(user)
+		}
+		return values
+	case "join":
+		if len(values) == 0 {
+			return nil
+		}
+		return []string{strings.Join(values, e.args[0])}
+	}
+
+	var result []string
+	for _, value := range values {
+		if value = claimFunctions[e.name].fn(value, e.args, e.ints); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func (e *claimCall) multiple() bool {
+	switch e.name {
+	case "join":
+		return false
+	case "default":
+		return e.value.multiple() || e.other.multiple()
+	}
+	return e.value.multiple()
+}
+
+// claimExprCache 已解析的属性转换表达式，签发时避免重复解析
+var claimExprCache sync.Map
+
+// parseClaimExpression 解析并校验属性转换表达式
+func parseClaimExpression(expression string) (claimExpr, error) {
+
+	if cached, ok := claimExprCache.Load(expression); ok {
+		return cached.(claimExpr), nil
+	}
+
+	if strings.TrimSpace(expression) == "" {
+		return nil, errors.New("表达式不能为空")
+	}
+	if len(expression) > claimExprMaxLength {
+		return nil, fmt.Errorf("表达式长度不能超过%d个字符", claimExprMaxLength)
+	}
+
+	tokens, err := tokenizeClaimExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	p := &claimParser{tokens: tokens}
+	expr, err := p.parseConcat(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("表达式在“%s”处有多余的内容", p.tokens[p.pos].text)
+	}
+
+	claimExprCache.Store(expression, expr)
+	return expr, nil
+}
+
+// validateClaimMapping 校验站点属性映射，为JSON对象（应用侧属性名 -> 用户属性或属性转换表达式）
+func validateClaimMapping(content string) error {
+
+	if strings.TrimSpace(content) == "" {
+		return nil
+	}
+
+	var mapping map[string]string
+	if err := json.Unmarshal([]byte(content), &mapping); err != nil {
+		return errors.New("属性映射格式错误，格式为JSON对象，如：{\"email\":\"lower(username) + \\\"@corp.com\\\"\"}")
+	}
+
+	names := make([]string, 0, len(mapping))
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if strings.TrimSpace(name) == "" {
+			return errors.New("属性映射中的属性名不能为空")
+		}
+		if _, err := parseClaimExpression(mapping[name]); err != nil {
+			return fmt.Errorf("属性%s的映射错误：%s", name, err.Error())
+		}
+	}
+	return nil
+}
+
+// claimTokenKind 属性转换表达式词法单元类型
+type claimTokenKind int
+
+const (
+	claimTokenIdent claimTokenKind = iota
+	claimTokenString
+	claimTokenInt
+	claimTokenSymbol // ( ) , +
+)
+
+type claimToken struct {
+	kind claimTokenKind
+	text string
+}
+
+// tokenizeClaimExpression 词法分析，字符串字面量使用单引号或双引号，支持 \ 转义
+func tokenizeClaimExpression(expression string) ([]claimToken, error) {
+
+	var tokens []claimToken
+	runes := []rune(expression)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',' || r == '+':
+			tokens = append(tokens, claimToken{kind: claimTokenSymbol, text: string(r)})
+			i++
+		case r == '"' || r == '\'':
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(runes) && runes[j] != r; j++ {
+				if runes[j] == '\\' && j+1 < len(runes) {
+					j++
+				}
+				b.WriteRune(runes[j])
+			}
+			if j >= len(runes) {
+				return nil, errors.New("字符串缺少结束引号")
+			}
+			tokens = append(tokens, claimToken{kind: claimTokenString, text: b.String()})
+			i = j + 1
+		case r >= '0' && r <= '9':
+			j := i
+			for j < len(runes) && runes[j] >= '0' && runes[j] <= '9' {
+				j++
+			}
+			tokens = append(tokens, claimToken{kind: claimTokenInt, text: string(runes[i:j])})
+			i = j
+		case r == '_' || unicode.IsLetter(r):
+			j := i
+			for j < len(runes) && (runes[j] == '_' || unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+				j++
+			}
+			tokens = append(tokens, claimToken{kind: claimTokenIdent, text: string(runes[i:j])})
+			i = j
+		default:
+			return nil, fmt.Errorf("表达式包含不支持的字符：%s", string(r))
+		}
+	}
+	return tokens, nil
+}
+
+// claimParser 属性转换表达式语法分析：
+// concat := term { "+" term }
+// term   := string | field | function "(" args ")" | "(" concat ")"
+type claimParser struct {
+	tokens []claimToken
+	pos    int
+}
+
+// peek 获取下一个词法单元，没有时返回空
+func (p *claimParser) peek() *claimToken {
+	if p.pos >= len(p.tokens) {
+		return nil
+	}
+	return &p.tokens[p.pos]
+}
+
+// expect 读取指定的符号
+func (p *claimParser) expect(symbol string) error {
+	token := p.peek()
+	if token == nil || token.kind != claimTokenSymbol || token.text != symbol {
+		return fmt.Errorf("表达式缺少“%s”", symbol)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *claimParser) parseConcat(depth int) (claimExpr, error) {
+
+	if depth > claimExprMaxDepth {
+		return nil, fmt.Errorf("表达式嵌套不能超过%d层", claimExprMaxDepth)
+	}
+
+	expr, err := p.parseTerm(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		token := p.peek()
+		if token == nil || token.kind != claimTokenSymbol || token.text != "+" {
+			return expr, nil
+		}
+		p.pos++
+		right, err := p.parseTerm(depth)
+		if err != nil {
+			return nil, err
+		}
+		if expr.multiple() && right.multiple() {
+			return nil, errors.New("两个多值属性不能拼接，请先使用join函数合并")
+		}
+		expr = &claimConcat{left: expr, right: right}
+	}
+}
+
+func (p *claimParser) parseTerm(depth int) (claimExpr, error) {
+
+	token := p.peek()
+	if token == nil {
+		return nil, errors.New("表达式不完整")
+	}
+	p.pos++
+
+	switch token.kind {
+	case claimTokenString:
+		return &claimLiteral{value: token.text}, nil
+	case claimTokenInt:
+		return nil, fmt.Errorf("数字%s只能作为函数参数，字符串需要使用引号", token.text)
+	case claimTokenSymbol:
+		if token.text != "(" {
+			return nil, fmt.Errorf("表达式在“%s”处格式错误", token.text)
+		}
+		expr, err := p.parseConcat(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expect(")")
+	}
+
+	// 函数调用
+	if next := p.peek(); next != nil && next.kind == claimTokenSymbol && next.text == "(" {
+		p.pos++
+		return p.parseCall(token.text, depth+1)
+	}
+
+	// 用户属性
+	if _, ok := claimFields[token.text]; !ok {
+		return nil, fmt.Errorf("不支持的用户属性：%s，可选值为：%s", token.text, strings.Join(claimFieldNames(), "、"))
+	}
+	return &claimField{name: token.text}, nil
+}
+
+// parseCall 解析函数参数，左括号已读取
+func (p *claimParser) parseCall(name string, depth int) (claimExpr, error) {
+
+	function, ok := claimFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的函数：%s", name)
+	}
+	if depth > claimExprMaxDepth {
+		return nil, fmt.Errorf("表达式嵌套不能超过%d层", claimExprMaxDepth)
+	}
+
+	call := &claimCall{name: name}
+	for i, kind := range function.args {
+		if i > 0 {
+			if token := p.peek(); i >= len(function.args)-function.extra && token != nil && token.kind == claimTokenSymbol && token.text == ")" {
+				break
+			}
+			if err := p.expect(","); err != nil {
+				return nil, fmt.Errorf("函数%s的参数个数错误", name)
+			}
+		}
+
+		switch kind {
+		case "value":
+			expr, err := p.parseConcat(depth)
+			if err != nil {
+				return nil, err
+			}
+			if call.value == nil {
+				call.value = expr
+			} else {
+				call.other = expr
+			}
+		case "string":
+			token := p.peek()
+			if token == nil || token.kind != claimTokenString {
+				return nil, fmt.Errorf("函数%s的第%d个参数必须为字符串", name, i+1)
+			}
+			p.pos++
+			call.args = append(call.args, token.text)
+		case "int":
+			token := p.peek()
+			if token == nil || token.kind != claimTokenInt {
+				return nil, fmt.Errorf("函数%s的第%d个参数必须为整数", name, i+1)
+			}
+			p.pos++
+			value, err := strconv.Atoi(token.text)
+			if err != nil {
+				return nil, fmt.Errorf("函数%s的第%d个参数超出范围", name, i+1)
+			}
+			call.ints = append(call.ints, value)
+		}
+	}
+
+	if err := p.expect(")"); err != nil {
+		return nil, fmt.Errorf("函数%s的参数个数错误", name)
+	}
+	return call, nil
+}
+
+// claimFieldNames 属性转换表达式支持的用户属性名
+func claimFieldNames() []string {
+	names := make([]string, 0, len(claimFields))
+	for name := range claimFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getUserClaimValues 根据用户属性名或属性转换表达式获取属性值（WS-Fed声明、SAML2及CAS3.0属性映射共用），表达式错误时返回空
+func getUserClaimValues(user *model.AuthUser, expression string) []string {
+	expr, err := parseClaimExpression(expression)
+	if err != nil {
+		return nil
+	}
+	return expr.evaluate(user)
+}
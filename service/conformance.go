@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+	"github.com/wonderivan/logger"
+	"net"
+	"net/http"
+	"ops-api/config"
+	"ops-api/utils/conformance"
+	"time"
+)
+
+var Conformance conformanceTest
+
+type conformanceTest struct{}
+
+// conformanceTimeout 一致性测试单个请求的超时时间
+const conformanceTimeout = 10 * time.Second
+
+// ConformanceRun 一致性测试请求参数
+type ConformanceRun struct {
+	Capabilities []string `json:"capabilities"` // 需要测试的能力，为空时测试所有能力
+}
+
+// Run 对本服务的单点登录接口执行协议一致性测试，请求直接发送到本服务的监听地址，不经过反向代理
+func (c *conformanceTest) Run(data *ConformanceRun) (*conformance.Report, error) {
+
+	client := &http.Client{
+		Timeout: conformanceTimeout,
+		// 不跟随跳转，跳转本身即为需要检查的响应
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	report, err := conformance.Run(localURL(), client, data.Capabilities)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("协议一致性测试完成，通过 %d 项，未通过 %d 项", report.Passed, report.Failed))
+	return report, nil
+}
+
+// localURL 本服务的访问地址，监听所有地址时使用127.0.0.1
+func localURL() string {
+	host, port, err := net.SplitHostPort(config.Conf.Server)
+	if err != nil {
+		return "http://" + config.Conf.Server
+	}
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
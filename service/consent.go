@@ -0,0 +1,120 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"ops-api/dao"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+)
+
+var Consent consent
+
+type consent struct{}
+
+// ConsentPage 单点登录需要用户确认授权时返回给前端的页面，前端使用返回的Token重新发起授权请求获取需要确认的Scope
+const ConsentPage = "OAUTH_CONSENT"
+
+// oauthScopeDescriptions 授权确认页面展示的Scope说明
+var oauthScopeDescriptions = map[string]string{
+	"openid":         "使用您的账号登录",
+	"profile":        "获取您的姓名、用户名等基本信息",
+	"email":          "获取您的邮箱地址",
+	"phone":          "获取您的手机号",
+	"offline_access": "在您离线时保持访问",
+}
+
+// ConsentScope 需要用户确认的Scope
+type ConsentScope struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// ConsentRequiredError 访问应用前需要用户确认授权
+type ConsentRequiredError struct {
+	Application string          `json:"application"`
+	ClientId    string          `json:"client_id"`
+	Scopes      []*ConsentScope `json:"scopes"`  // 本次申请的Scope
+	Granted     []string        `json:"granted"` // 此前已授权的Scope
+}
+
+func (e *ConsentRequiredError) Error() string {
+	return "访问该应用需要先确认授权"
+}
+
+// consentPending 判断单点登录是否因需要用户确认授权而中断
+func consentPending(err error) bool {
+	var consentErr *ConsentRequiredError
+	return errors.As(err, &consentErr)
+}
+
+// Check 判断用户是否已授权应用申请的所有Scope，未授权时返回 *ConsentRequiredError；
+// approved为true时表示用户已在确认页面同意授权，保存授权记录（与此前已授权的Scope合并）
+func (c *consent) Check(site *model.Site, userId uint, scope string, approved bool) error {
+
+	var granted []string
+	record, err := dao.Consent.GetUserConsent(userId, site.ClientId)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if record != nil {
+		granted = strings.Fields(record.Scope)
+	}
+
+	var missing []string
+	for _, item := range strings.Fields(scope) {
+		if !utils.Contains(granted, item) {
+			missing = append(missing, item)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if !approved {
+		var scopes []*ConsentScope
+		for _, item := range strings.Fields(scope) {
+			scopes = append(scopes, &ConsentScope{Scope: item, Description: oauthScopeDescriptions[item]})
+		}
+		return &ConsentRequiredError{Application: site.Name, ClientId: site.ClientId, Scopes: scopes, Granted: granted}
+	}
+
+	return dao.Consent.SaveUserConsent(&model.UserConsent{
+		UserID:   userId,
+		ClientID: site.ClientId,
+		Scope:    strings.Join(append(granted, missing...), " "),
+	})
+}
+
+// GetUserConsents 获取用户的应用授权记录
+func (c *consent) GetUserConsents(userId uint) ([]*dao.UserConsentItem, error) {
+	return dao.Consent.GetUserConsents(userId)
+}
+
+// Revoke 撤销用户对应用的授权，同时注销应用的离线访问授权，再次访问应用时需要重新确认授权
+func (c *consent) Revoke(userId, id uint) error {
+
+	record, err := dao.Consent.DeleteUserConsent(userId, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("授权记录不存在")
+		}
+		return err
+	}
+
+	sessionIds, err := dao.Consent.GetUserClientRefreshSessions(userId, record.ClientID)
+	if err != nil {
+		return err
+	}
+	for _, sessionId := range sessionIds {
+		if err := Session.revokeOffline(userId, sessionId); err != nil {
+			return err
+		}
+	}
+
+	logger.Info(fmt.Sprintf("用户ID %d 撤销了对应用%s的授权，共注销 %d 个离线访问会话", userId, record.ClientID, len(sessionIds)))
+	return nil
+}
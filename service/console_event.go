@@ -0,0 +1,153 @@
+package service
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/wonderivan/logger"
+	"ops-api/global"
+	"sync"
+	"time"
+)
+
+var ConsoleEvent consoleEvent
+
+// 管理控制台实时事件：登录成功及失败、告警触发及恢复（如登录失败激增）、定时任务执行完成、新的访问申请等事件
+// 通过 /api/v1/console/events（Server-Sent Events）实时推送到管理控制台；事件通过缓存的发布订阅（Redis）通知所有实例，
+// 每个实例只向连接到本实例的客户端推送，使用Memcached缓存时不支持发布订阅，只能收到本实例产生的事件
+
+// 控制台事件类型
+const (
+	ConsoleEventLogin         = "login"          // 登录成功
+	ConsoleEventLoginFailed   = "login_failed"   // 登录失败
+	ConsoleEventAlert         = "alert"          // 告警触发或恢复
+	ConsoleEventTask          = "task"           // 定时任务执行完成
+	ConsoleEventAccessRequest = "access_request" // 新的访问申请
+)
+
+const (
+	consoleEventChannel   = "console_event"  // 控制台事件通知频道
+	consoleEventBuffer    = 64               // 每个客户端缓存的事件数，客户端处理不及时时丢弃新的事件
+	ConsoleEventHeartbeat = 30 * time.Second // 无事件时发送心跳的间隔，避免代理断开空闲连接
+)
+
+// consoleEventNames 控制台事件类型名称
+var consoleEventNames = map[string]string{
+	ConsoleEventLogin:         "登录成功",
+	ConsoleEventLoginFailed:   "登录失败",
+	ConsoleEventAlert:         "告警",
+	ConsoleEventTask:          "定时任务执行完成",
+	ConsoleEventAccessRequest: "访问申请",
+}
+
+// ConsoleEventItem 控制台事件
+type ConsoleEventItem struct {
+	ID       string          `json:"id"`
+	Type     string          `json:"type"`
+	Data     json.RawMessage `json:"data"`
+	Time     time.Time       `json:"time"`
+	Instance string          `json:"instance,omitempty"` // 产生事件的实例，用于忽略本实例发布的消息
+}
+
+// ConsoleEventClient 连接到本实例的控制台客户端
+type ConsoleEventClient struct {
+	Events chan *ConsoleEventItem
+	types  map[string]bool // 订阅的事件类型，为空时订阅所有类型
+}
+
+type consoleEvent struct {
+	mutex    sync.RWMutex
+	clients  map[*ConsoleEventClient]struct{}
+	instance string
+	onceInit sync.Once
+}
+
+// ConsoleEventInit 订阅其它实例发布的控制台事件
+func ConsoleEventInit() {
+	ConsoleEvent.onceInit.Do(func() {
+		ConsoleEvent.instance = uuid.New().String()
+		if err := global.Cache.Subscribe(consoleEventChannel, ConsoleEvent.receive); err != nil {
+			logger.Error("ERROR：订阅控制台事件失败，连接恢复后自动重新订阅，", err.Error())
+		}
+	})
+}
+
+// Publish 发布控制台事件，推送到所有实例的控制台客户端
+func (e *consoleEvent) Publish(eventType string, data interface{}) {
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		logger.Error("ERROR：控制台事件序列化失败，", err.Error())
+		return
+	}
+
+	event := &ConsoleEventItem{
+		ID:       uuid.New().String(),
+		Type:     eventType,
+		Data:     payload,
+		Time:     time.Now(),
+		Instance: e.instance,
+	}
+	e.broadcast(event)
+
+	message, _ := json.Marshal(event)
+	if err := global.Cache.Publish(consoleEventChannel, string(message)); err != nil {
+		logger.Error("ERROR：控制台事件发布失败，", err.Error())
+	}
+}
+
+// Subscribe 注册控制台客户端，types 为订阅的事件类型，为空时订阅所有类型
+func (e *consoleEvent) Subscribe(types []string) *ConsoleEventClient {
+
+	client := &ConsoleEventClient{
+		Events: make(chan *ConsoleEventItem, consoleEventBuffer),
+		types:  make(map[string]bool, len(types)),
+	}
+	for _, item := range types {
+		if _, ok := consoleEventNames[item]; ok {
+			client.types[item] = true
+		}
+	}
+
+	e.mutex.Lock()
+	if e.clients == nil {
+		e.clients = make(map[*ConsoleEventClient]struct{})
+	}
+	e.clients[client] = struct{}{}
+	e.mutex.Unlock()
+	return client
+}
+
+// Unsubscribe 注销控制台客户端
+func (e *consoleEvent) Unsubscribe(client *ConsoleEventClient) {
+	e.mutex.Lock()
+	delete(e.clients, client)
+	e.mutex.Unlock()
+}
+
+// receive 处理其它实例发布的控制台事件，忽略本实例发布的事件
+func (e *consoleEvent) receive(message string) {
+	var event ConsoleEventItem
+	if err := json.Unmarshal([]byte(message), &event); err != nil {
+		return
+	}
+	if event.Instance == e.instance {
+		return
+	}
+	e.broadcast(&event)
+}
+
+// broadcast 将事件推送到本实例订阅了该类型的客户端，客户端缓存已满时丢弃事件
+func (e *consoleEvent) broadcast(event *ConsoleEventItem) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	for client := range e.clients {
+		if len(client.types) > 0 && !client.types[event.Type] {
+			continue
+		}
+		select {
+		case client.Events <- event:
+		default:
+		}
+	}
+}
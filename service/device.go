@@ -0,0 +1,229 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"ops-api/dao"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+	"time"
+)
+
+var Device device
+
+type device struct{}
+
+// DeviceInfo 登录设备信息，从User-Agent、Client Hints（Sec-CH-UA-*）及前端提交的设备指纹中获取
+type DeviceInfo struct {
+	UserAgent         string
+	Browser           string
+	Platform          string
+	Mobile            bool
+	ClientFingerprint string // 前端生成的设备指纹（X-Device-Fingerprint请求头），可选
+}
+
+// NewDeviceInfo 根据请求头获取登录设备信息
+func NewDeviceInfo(header http.Header) *DeviceInfo {
+
+	userAgent := header.Get("User-Agent")
+
+	info := &DeviceInfo{
+		UserAgent: userAgent,
+		Browser:   parseBrowser(userAgent),
+		Platform:  strings.Trim(header.Get("Sec-CH-UA-Platform"), `"`),
+		Mobile:    header.Get("Sec-CH-UA-Mobile") == "?1",
+	}
+
+	// 浏览器不支持Client Hints时从User-Agent中获取
+	if info.Platform == "" {
+		info.Platform = parsePlatform(userAgent)
+	}
+	if !info.Mobile {
+		info.Mobile = strings.Contains(userAgent, "Mobile")
+	}
+
+	// 前端指纹仅用于区分设备，限制长度防止写入异常数据
+	if fingerprint := strings.TrimSpace(header.Get("X-Device-Fingerprint")); len(fingerprint) <= 128 {
+		info.ClientFingerprint = fingerprint
+	}
+
+	return info
+}
+
+// Fingerprint 生成设备指纹，不包含浏览器版本号，避免浏览器升级后被识别为新设备
+func (d *DeviceInfo) Fingerprint() string {
+	mobile := "0"
+	if d.Mobile {
+		mobile = "1"
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{d.Browser, d.Platform, mobile, d.ClientFingerprint}, "|")))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeviceType 设备类型，用于会话并发策略按设备类型限制会话数量
+func (d *DeviceInfo) DeviceType() string {
+	if d.Mobile {
+		return SessionDeviceMobile
+	}
+	return SessionDeviceDesktop
+}
+
+// Name 设备名称，例如：Chrome on Windows
+func (d *DeviceInfo) Name() string {
+	return fmt.Sprintf("%s on %s", d.Browser, d.Platform)
+}
+
+// parseBrowser 从User-Agent中获取浏览器名称，需要先匹配基于Chromium或WebKit的浏览器
+func parseBrowser(userAgent string) string {
+	browsers := []struct{ token, name string }{
+		{"DingTalk", "DingTalk"},
+		{"wxwork", "WeCom"},
+		{"MicroMessenger", "WeChat"},
+		{"Lark", "Feishu"},
+		{"Edg", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"CriOS/", "Chrome"},
+		{"Safari/", "Safari"},
+	}
+	for _, browser := range browsers {
+		if strings.Contains(userAgent, browser.token) {
+			return browser.name
+		}
+	}
+	return "Unknown"
+}
+
+// parsePlatform 从User-Agent中获取操作系统名称
+func parsePlatform(userAgent string) string {
+	platforms := []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"CrOS", "Chrome OS"},
+		{"Linux", "Linux"},
+	}
+	for _, platform := range platforms {
+		if strings.Contains(userAgent, platform.token) {
+			return platform.name
+		}
+	}
+	return "Unknown"
+}
+
+// Record 登录成功后记录登录设备，新设备登录时邮件通知用户（用户首次登录不通知）
+func (d *device) Record(username string, info *DeviceInfo, clientIP string) error {
+
+	user, err := dao.User.GetUser(map[string]interface{}{"username": username})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	fingerprint := info.Fingerprint()
+
+	// 已登录过的设备仅更新最后登录时间
+	userDevice, err := dao.Device.GetUserDevice(user.ID, fingerprint)
+	if err == nil {
+		return dao.Device.TouchUserDevice(userDevice.ID, clientIP, info.UserAgent, now)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	total, err := dao.Device.CountUserDevices(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := dao.Device.AddUserDevice(&model.UserDevice{
+		UserID:      user.ID,
+		Username:    user.Username,
+		Fingerprint: fingerprint,
+		Browser:     info.Browser,
+		Platform:    info.Platform,
+		Mobile:      info.Mobile,
+		UserAgent:   info.UserAgent,
+		FirstIP:     clientIP,
+		LastIP:      clientIP,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}); err != nil {
+		return err
+	}
+
+	// 发送新设备登录提醒
+	if total > 0 {
+		country, _ := utils.LookupCountry(clientIP)
+		d.notify(user, "new_device.body", info.Name(), clientIP, country, now)
+	}
+
+	return nil
+}
+
+// GetDeviceList 获取登录设备列表（表格）
+func (d *device) GetDeviceList(username string, page, limit int) (data *dao.DeviceList, err error) {
+	return dao.Device.GetDeviceList(username, page, limit)
+}
+
+// GetUserDevices 获取用户的登录设备
+func (d *device) GetUserDevices(userId uint) ([]*model.UserDevice, error) {
+	return dao.Device.GetUserDevices(userId)
+}
+
+// DeleteUserDevice 删除用户的登录设备
+func (d *device) DeleteUserDevice(userId, id uint) error {
+	if err := dao.Device.DeleteUserDevice(userId, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("设备不存在")
+		}
+		return err
+	}
+	return nil
+}
+
+// Wipe 擦除设备，注销设备上在线的登录会话及离线访问的刷新令牌，设备记录保留，用户可重新登录
+func (d *device) Wipe(id uint) error {
+
+	userDevice, err := dao.Device.GetDevice(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("设备不存在")
+		}
+		return err
+	}
+
+	// 注销离线访问的刷新令牌
+	refreshSessionIds, err := dao.SSO.GetDeviceRefreshSessions(userDevice.ID)
+	if err != nil {
+		return err
+	}
+	for _, sessionId := range refreshSessionIds {
+		if err := Session.revokeOffline(userDevice.UserID, sessionId); err != nil {
+			return err
+		}
+	}
+
+	// 注销设备上在线的登录会话
+	sessions, err := dao.Session.GetActiveDeviceSessions(userDevice.UserID, userDevice.Fingerprint)
+	if err != nil {
+		return err
+	}
+	for _, item := range sessions {
+		if err := Session.revoke(item.UserID, item.SessionID, SessionRevokeWiped); err != nil {
+			return err
+		}
+	}
+
+	logger.Info(fmt.Sprintf("用户%s的设备（%s on %s）已擦除，共注销 %d 个登录会话、%d 个离线访问会话", userDevice.Username, userDevice.Browser, userDevice.Platform, len(sessions), len(refreshSessionIds)))
+	return nil
+}
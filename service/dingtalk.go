@@ -43,8 +43,8 @@ func NewDingTalkClient() (*DingTalkClient, error) {
 func (client *DingTalkClient) GetUserAccessToken(code string) (userAccessToken string, err error) {
 
 	var (
-		dingdingAppKey    = config.Conf.Settings["dingdingAppKey"].(string)
-		dingdingAppSecret = config.Conf.Settings["dingdingAppSecret"].(string)
+		dingdingAppKey    = config.GetString("dingdingAppKey")
+		dingdingAppSecret = config.GetString("dingdingAppSecret")
 	)
 
 	// 解密
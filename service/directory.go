@@ -0,0 +1,744 @@
+package service
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/wonderivan/logger"
+	"net"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/middleware"
+	"ops-api/model"
+	"ops-api/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var Directory directory
+
+// directory LDAP目录服务，对外以只读方式提供用户、分组信息，并使用IDSphere账号完成简单绑定认证
+type directory struct{}
+
+const (
+	directoryIdleTimeout = 5 * time.Minute // 连接空闲超时时间
+	directoryUserAgent   = "LDAP Client"
+	directoryApplication = "LDAP目录服务"
+	directoryCacheTTL    = 30 * time.Second         // 目录条目缓存时间
+	directoryStartTLSOID = "1.3.6.1.4.1.1466.20037" // StartTLS扩展操作（RFC 4511 4.14）
+)
+
+// directoryTLS 目录服务TLS配置，未配置证书时为nil，此时仅允许匿名绑定
+var directoryTLS *tls.Config
+
+// directoryCache 目录条目本地缓存，避免每次查询都从数据库加载所有用户及分组
+var directoryCache struct {
+	mutex   sync.Mutex
+	baseDN  string
+	entries []*directoryEntry
+	expires time.Time
+}
+
+// directoryEntry 目录条目
+type directoryEntry struct {
+	DN         string
+	Attributes map[string][]string // 属性名使用小写作为键
+	Names      map[string]string   // 小写属性名与原始属性名的映射
+}
+
+// directorySession LDAP连接会话
+type directorySession struct {
+	conn     net.Conn
+	clientIP string
+	tls      bool // 连接是否已加密（LDAPS或StartTLS）
+	bound    bool // 是否已使用服务账号绑定，只有服务账号允许查询目录
+}
+
+// DirectoryInit 启动LDAP目录服务
+func DirectoryInit() error {
+
+	enabled := config.GetBool("ldapServer")
+	if !enabled {
+		return nil
+	}
+
+	address := config.GetString("ldapServerAddress")
+	if address == "" {
+		address = ":1389"
+	}
+
+	// 加载TLS证书，配置后支持LDAPS及StartTLS
+	tlsConfig, err := directoryTLSConfig()
+	if err != nil {
+		return err
+	}
+	directoryTLS = tlsConfig
+	if directoryTLS == nil {
+		logger.Warn("LDAP目录服务未配置TLS证书，仅允许匿名绑定查询RootDSE")
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	logger.Info("LDAP目录服务已启动，监听地址：", address)
+	go Directory.accept(listener, false)
+
+	// LDAPS
+	tlsAddress := config.GetString("ldapServerTlsAddress")
+	if directoryTLS != nil && tlsAddress != "" {
+		tlsListener, err := tls.Listen("tcp", tlsAddress, directoryTLS)
+		if err != nil {
+			return err
+		}
+		logger.Info("LDAP目录服务（LDAPS）已启动，监听地址：", tlsAddress)
+		go Directory.accept(tlsListener, true)
+	}
+
+	return nil
+}
+
+// directoryTLSConfig 使用配置的证书及私钥生成TLS配置，未配置证书时返回nil
+func directoryTLSConfig() (*tls.Config, error) {
+
+	certificate := config.GetString("ldapServerCertificate")
+	privateKey := config.GetString("ldapServerPrivateKey")
+	if certificate == "" || privateKey == "" {
+		return nil, nil
+	}
+
+	pair, err := tls.X509KeyPair([]byte(certificate), []byte(privateKey))
+	if err != nil {
+		return nil, fmt.Errorf("LDAP目录服务TLS证书加载失败：%w", err)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// accept 接收连接，secure表示监听器为LDAPS
+func (d *directory) accept(listener net.Listener, secure bool) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			logger.Error("ERROR：LDAP目录服务连接失败，", err.Error())
+			continue
+		}
+		go d.serve(conn, secure)
+	}
+}
+
+// serve 处理单个LDAP连接
+func (d *directory) serve(conn net.Conn, secure bool) {
+
+	clientIP, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	session := &directorySession{conn: conn, clientIP: clientIP, tls: secure}
+	defer func() { _ = session.conn.Close() }()
+
+	for {
+		_ = session.conn.SetReadDeadline(time.Now().Add(directoryIdleTimeout))
+		packet, err := ber.ReadPacket(session.conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+
+		messageID, ok := packet.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+		request := packet.Children[1]
+
+		switch request.Tag {
+		case ldap.ApplicationBindRequest:
+			d.handleBind(session, messageID, request)
+		case ldap.ApplicationSearchRequest:
+			d.handleSearch(session, messageID, request)
+		case ldap.ApplicationUnbindRequest:
+			return
+		case ldap.ApplicationAbandonRequest:
+			// 查询为同步执行，无需处理
+		case ldap.ApplicationExtendedRequest:
+			if !d.handleExtended(session, messageID, request) {
+				return
+			}
+		case ldap.ApplicationModifyRequest, ldap.ApplicationAddRequest, ldap.ApplicationDelRequest, ldap.ApplicationModifyDNRequest, ldap.ApplicationCompareRequest:
+			// 请求对应的响应Tag为请求Tag+1
+			d.write(session, directoryResult(messageID, request.Tag+1, ldap.LDAPResultUnwillingToPerform, "目录为只读模式"))
+		default:
+			return
+		}
+	}
+}
+
+// handleExtended 处理扩展操作，仅支持StartTLS，返回false时关闭连接
+func (d *directory) handleExtended(session *directorySession, messageID int64, request *ber.Packet) bool {
+
+	if len(request.Children) == 0 || request.Children[0].Data.String() != directoryStartTLSOID {
+		d.write(session, directoryResult(messageID, ldap.ApplicationExtendedResponse, ldap.LDAPResultProtocolError, "不支持扩展操作"))
+		return true
+	}
+	if directoryTLS == nil {
+		d.write(session, directoryResult(messageID, ldap.ApplicationExtendedResponse, ldap.LDAPResultUnavailable, "未配置TLS证书"))
+		return true
+	}
+	if session.tls {
+		d.write(session, directoryResult(messageID, ldap.ApplicationExtendedResponse, ldap.LDAPResultOperationsError, "连接已加密"))
+		return true
+	}
+
+	// 响应成功后在当前连接上进行TLS握手，握手失败时关闭连接
+	response := directoryResult(messageID, ldap.ApplicationExtendedResponse, ldap.LDAPResultSuccess, "")
+	response.Children[1].AppendChild(ber.NewString(ber.ClassContext, ber.TypePrimitive, 10, directoryStartTLSOID, "Response Name"))
+	d.write(session, response)
+
+	conn := tls.Server(session.conn, directoryTLS)
+	_ = conn.SetDeadline(time.Now().Add(directoryIdleTimeout))
+	if err := conn.Handshake(); err != nil {
+		logger.Warn("LDAP目录服务StartTLS握手失败：%v，来源：%s", err, session.clientIP)
+		return false
+	}
+	_ = conn.SetDeadline(time.Time{})
+
+	session.conn = conn
+	session.tls = true
+	session.bound = false
+	return true
+}
+
+// handleBind 处理简单绑定请求
+func (d *directory) handleBind(session *directorySession, messageID int64, request *ber.Packet) {
+
+	session.bound = false
+
+	if len(request.Children) < 3 {
+		d.write(session, directoryResult(messageID, ldap.ApplicationBindResponse, ldap.LDAPResultProtocolError, "请求格式错误"))
+		return
+	}
+
+	bindDN := request.Children[1].Data.String()
+	auth := request.Children[2]
+
+	// 仅支持简单绑定
+	if auth.ClassType != ber.ClassContext || auth.Tag != 0 {
+		d.write(session, directoryResult(messageID, ldap.ApplicationBindResponse, ldap.LDAPResultAuthMethodNotSupported, "仅支持简单绑定"))
+		return
+	}
+	password := auth.Data.String()
+
+	// 匿名绑定，不允许查询目录
+	if bindDN == "" && password == "" {
+		d.write(session, directoryResult(messageID, ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, ""))
+		return
+	}
+
+	// 非匿名绑定必须使用加密连接，避免密码明文传输
+	if !session.tls {
+		d.write(session, directoryResult(messageID, ldap.ApplicationBindResponse, ldap.LDAPResultConfidentialityRequired, "请使用LDAPS或StartTLS加密连接后绑定"))
+		return
+	}
+
+	// 服务账号绑定
+	serviceDN := config.GetString("ldapServerBindDn")
+	if serviceDN != "" && dnEqualFold(bindDN, serviceDN) {
+		if err := checkServicePassword(password); err != nil {
+			logger.Warn("LDAP目录服务账号绑定失败：%s，来源：%s", err.Error(), session.clientIP)
+			d.write(session, directoryResult(messageID, ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, "认证失败"))
+			return
+		}
+		session.bound = true
+		d.write(session, directoryResult(messageID, ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, ""))
+		return
+	}
+
+	// 用户绑定
+	username := directoryUsername(bindDN)
+	if err := d.authenticate(username, password, session.clientIP); err != nil {
+		d.write(session, directoryResult(messageID, ldap.ApplicationBindResponse, ldap.LDAPResultInvalidCredentials, err.Error()))
+		return
+	}
+	d.write(session, directoryResult(messageID, ldap.ApplicationBindResponse, ldap.LDAPResultSuccess, ""))
+}
+
+// authenticate 使用IDSphere账号认证，并记录登录日志，连续失败达到阈值后锁定
+func (d *directory) authenticate(username, password, clientIP string) (err error) {
+
+	// 记录登录信息
+	defer func() {
+		if err := User.RecordLoginInfo("LDAP", username, directoryUserAgent, clientIP, directoryApplication, err); err != nil {
+			logger.Error("ERROR：LDAP登录日志记录失败，", err.Error())
+		}
+	}()
+
+	if username == "" || password == "" {
+		return errors.New("用户名或密码不能为空")
+	}
+
+	// 判断是否已锁定
+	threshold := config.GetInt("ldapServerLockoutThreshold")
+	lockKey := "ldap_bind_failed:" + username
+	if threshold > 0 {
+		value, _ := global.Cache.Get(lockKey)
+		failed, _ := strconv.Atoi(value)
+		if failed >= threshold {
+			return errors.New("认证失败次数过多，账号已锁定")
+		}
+	}
+
+	var user model.AuthUser
+	err = User.AuthenticateUser(&UserLogin{Username: username, Password: password}, &user)
+	if err == nil && !user.IsActive {
+		err = errors.New("拒绝登录，请联系管理员")
+	}
+	if err == nil && user.PasswordExpiredAt != nil && user.PasswordExpiredAt.Before(time.Now()) {
+		err = errors.New("密码已过期")
+	}
+
+	// 统计失败次数
+	if err != nil {
+		if threshold > 0 {
+			minutes := config.GetInt("ldapServerLockoutMinutes")
+			if minutes <= 0 {
+				minutes = 15
+			}
+			global.Cache.Incr(lockKey, time.Duration(minutes)*time.Minute)
+		}
+		return err
+	}
+	global.Cache.Del(lockKey)
+
+	// 应急账号必须从指定网络登录并进行MFA认证，LDAP绑定无法完成MFA认证，不允许使用
+	if user.BreakGlass {
+		return errors.New("应急账号不允许通过LDAP登录")
+	}
+
+	// 维护期间仅允许管理员登录
+	if status := middleware.GetMaintenance(); status.Enabled && !middleware.IsMaintenanceAdmin(user.Username) {
+		return &MaintenanceError{Message: status.Message}
+	}
+
+	// 登录策略检查，LDAP绑定无法完成MFA认证，命中MFA策略时拒绝登录
+	mfaRequired, err := LoginPolicy.CheckLoginPolicy(&user, clientIP)
+	if err != nil {
+		return err
+	}
+	if mfaRequired {
+		return errors.New("当前网络环境需要MFA认证，不允许通过LDAP登录")
+	}
+
+	return nil
+}
+
+// handleSearch 处理查询请求
+func (d *directory) handleSearch(session *directorySession, messageID int64, request *ber.Packet) {
+
+	if len(request.Children) < 8 {
+		d.write(session, directoryResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultProtocolError, "请求格式错误"))
+		return
+	}
+
+	baseDN := request.Children[0].Data.String()
+	scope, _ := request.Children[1].Value.(int64)
+	sizeLimit, _ := request.Children[3].Value.(int64)
+	typesOnly, _ := request.Children[5].Value.(bool)
+	filter := request.Children[6]
+	var attributes []string
+	for _, attribute := range request.Children[7].Children {
+		attributes = append(attributes, strings.ToLower(attribute.Data.String()))
+	}
+
+	// RootDSE允许匿名查询
+	if baseDN == "" && scope == ldap.ScopeBaseObject {
+		entry := d.rootDSE()
+		if matchFilter(entry, filter) {
+			d.write(session, entry.packet(messageID, attributes, typesOnly))
+		}
+		d.write(session, directoryResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, ""))
+		return
+	}
+
+	if !session.bound {
+		d.write(session, directoryResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultInsufficientAccessRights, "请使用服务账号绑定后查询"))
+		return
+	}
+
+	base, err := ldap.ParseDN(baseDN)
+	if err != nil {
+		d.write(session, directoryResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultInvalidDNSyntax, err.Error()))
+		return
+	}
+
+	entries, err := d.entries()
+	if err != nil {
+		logger.Error("ERROR：LDAP目录查询失败，", err.Error())
+		d.write(session, directoryResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultOperationsError, "目录查询失败"))
+		return
+	}
+
+	found := false
+	count := int64(0)
+	for _, entry := range entries {
+		dn, err := ldap.ParseDN(entry.DN)
+		if err != nil {
+			continue
+		}
+
+		// 判断条目是否在查询范围内
+		inScope := false
+		switch scope {
+		case ldap.ScopeBaseObject:
+			inScope = base.EqualFold(dn)
+		case ldap.ScopeSingleLevel:
+			inScope = base.AncestorOfFold(dn) && len(dn.RDNs) == len(base.RDNs)+1
+		default:
+			inScope = base.EqualFold(dn) || base.AncestorOfFold(dn)
+		}
+		if base.EqualFold(dn) {
+			found = true
+		}
+		if !inScope || !matchFilter(entry, filter) {
+			continue
+		}
+
+		if sizeLimit > 0 && count >= sizeLimit {
+			d.write(session, directoryResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSizeLimitExceeded, ""))
+			return
+		}
+		d.write(session, entry.packet(messageID, attributes, typesOnly))
+		count++
+	}
+
+	if !found {
+		d.write(session, directoryResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultNoSuchObject, ""))
+		return
+	}
+	d.write(session, directoryResult(messageID, ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess, ""))
+}
+
+// entries 获取目录中的所有条目，使用本地缓存，用户及分组变更最长在缓存时间后生效
+func (d *directory) entries() ([]*directoryEntry, error) {
+
+	baseDN := directoryBaseDN()
+
+	directoryCache.mutex.Lock()
+	defer directoryCache.mutex.Unlock()
+	if directoryCache.baseDN == baseDN && time.Now().Before(directoryCache.expires) {
+		return directoryCache.entries, nil
+	}
+
+	entries, err := d.load(baseDN)
+	if err != nil {
+		return nil, err
+	}
+	directoryCache.baseDN = baseDN
+	directoryCache.entries = entries
+	directoryCache.expires = time.Now().Add(directoryCacheTTL)
+
+	return entries, nil
+}
+
+// load 从数据库加载目录中的所有条目
+func (d *directory) load(baseDN string) ([]*directoryEntry, error) {
+
+	usersDN := "ou=users," + baseDN
+	groupsDN := "ou=groups," + baseDN
+
+	var users []*model.AuthUser
+	if err := global.MySQLClient.Preload("Groups").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	var groups []*model.AuthGroup
+	if err := global.MySQLClient.Preload("Users").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+
+	// 根节点及组织单元
+	issuer := config.GetString("issuer")
+	base := newDirectoryEntry(baseDN)
+	base.add("objectClass", "top", "dcObject", "organization")
+	base.add("o", issuer)
+	if rdn := strings.SplitN(strings.SplitN(baseDN, ",", 2)[0], "=", 2); len(rdn) == 2 {
+		base.add(rdn[0], rdn[1])
+	}
+	usersOU := newDirectoryEntry(usersDN)
+	usersOU.add("objectClass", "top", "organizationalUnit")
+	usersOU.add("ou", "users")
+	groupsOU := newDirectoryEntry(groupsDN)
+	groupsOU.add("objectClass", "top", "organizationalUnit")
+	groupsOU.add("ou", "groups")
+
+	entries := []*directoryEntry{base, usersOU, groupsOU}
+
+	for _, user := range users {
+		entry := newDirectoryEntry(fmt.Sprintf("uid=%s,%s", ldap.EscapeDN(user.Username), usersDN))
+		entry.add("objectClass", "top", "person", "organizationalPerson", "inetOrgPerson")
+		entry.add("uid", user.Username)
+		entry.add("cn", user.Name)
+		entry.add("sn", user.Name)
+		entry.add("displayName", user.Name)
+		entry.add("mail", user.Email)
+		entry.add("mobile", user.PhoneNumber)
+		entry.add("telephoneNumber", user.PhoneNumber)
+		entry.add("entryUUID", fmt.Sprintf("%d", user.ID))
+		if user.IsActive {
+			entry.add("accountStatus", "active")
+		} else {
+			entry.add("accountStatus", "disabled")
+		}
+		for _, group := range user.Groups {
+			entry.add("memberOf", fmt.Sprintf("cn=%s,%s", ldap.EscapeDN(group.Name), groupsDN))
+		}
+		entries = append(entries, entry)
+	}
+
+	for _, group := range groups {
+		entry := newDirectoryEntry(fmt.Sprintf("cn=%s,%s", ldap.EscapeDN(group.Name), groupsDN))
+		entry.add("objectClass", "top", "groupOfNames", "groupOfUniqueNames")
+		entry.add("cn", group.Name)
+		entry.add("entryUUID", fmt.Sprintf("%d", group.ID))
+		for _, user := range group.Users {
+			member := fmt.Sprintf("uid=%s,%s", ldap.EscapeDN(user.Username), usersDN)
+			entry.add("member", member)
+			entry.add("uniqueMember", member)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// rootDSE 根DSE条目
+func (d *directory) rootDSE() *directoryEntry {
+	entry := newDirectoryEntry("")
+	entry.add("objectClass", "top")
+	entry.add("namingContexts", directoryBaseDN())
+	entry.add("supportedLDAPVersion", "3")
+	entry.add("vendorName", "IDSphere")
+	if directoryTLS != nil {
+		entry.add("supportedExtension", directoryStartTLSOID)
+	}
+	return entry
+}
+
+// write 发送响应
+func (d *directory) write(session *directorySession, packet *ber.Packet) {
+	if _, err := session.conn.Write(packet.Bytes()); err != nil {
+		logger.Warn("LDAP目录服务响应失败：%v", err)
+	}
+}
+
+func newDirectoryEntry(dn string) *directoryEntry {
+	return &directoryEntry{
+		DN:         dn,
+		Attributes: make(map[string][]string),
+		Names:      make(map[string]string),
+	}
+}
+
+// add 添加属性值，忽略空值
+func (e *directoryEntry) add(name string, values ...string) {
+	key := strings.ToLower(name)
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		e.Names[key] = name
+		e.Attributes[key] = append(e.Attributes[key], value)
+	}
+}
+
+// packet 生成查询结果条目，attributes为空或包含“*”时返回所有属性，“1.1”表示不返回属性
+func (e *directoryEntry) packet(messageID int64, attributes []string, typesOnly bool) *ber.Packet {
+
+	all := len(attributes) == 0
+	for _, attribute := range attributes {
+		if attribute == "*" {
+			all = true
+		}
+	}
+
+	entry := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	entry.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.DN, "Object Name"))
+	attributesPacket := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attributes")
+	for key, values := range e.Attributes {
+		if !all && !utils.Contains(attributes, key) {
+			continue
+		}
+		attribute := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.Names[key], "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		if !typesOnly {
+			for _, value := range values {
+				set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+			}
+		}
+		attribute.AppendChild(set)
+		attributesPacket.AppendChild(attribute)
+	}
+	entry.AppendChild(attributesPacket)
+
+	return directoryEnvelope(messageID, entry)
+}
+
+// matchFilter 判断条目是否匹配过滤条件，属性值匹配均不区分大小写
+func matchFilter(entry *directoryEntry, filter *ber.Packet) bool {
+
+	switch filter.Tag {
+	case ldap.FilterAnd:
+		for _, child := range filter.Children {
+			if !matchFilter(entry, child) {
+				return false
+			}
+		}
+		return true
+	case ldap.FilterOr:
+		for _, child := range filter.Children {
+			if matchFilter(entry, child) {
+				return true
+			}
+		}
+		return false
+	case ldap.FilterNot:
+		return len(filter.Children) == 1 && !matchFilter(entry, filter.Children[0])
+	case ldap.FilterPresent:
+		return len(entry.Attributes[strings.ToLower(filter.Data.String())]) > 0
+	case ldap.FilterEqualityMatch, ldap.FilterApproxMatch, ldap.FilterGreaterOrEqual, ldap.FilterLessOrEqual:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name := strings.ToLower(filter.Children[0].Data.String())
+		expected := strings.ToLower(filter.Children[1].Data.String())
+		for _, value := range entry.Attributes[name] {
+			value = strings.ToLower(value)
+			switch filter.Tag {
+			case ldap.FilterGreaterOrEqual:
+				if value >= expected {
+					return true
+				}
+			case ldap.FilterLessOrEqual:
+				if value <= expected {
+					return true
+				}
+			default:
+				if value == expected {
+					return true
+				}
+			}
+		}
+		return false
+	case ldap.FilterSubstrings:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name := strings.ToLower(filter.Children[0].Data.String())
+		for _, value := range entry.Attributes[name] {
+			if matchSubstrings(strings.ToLower(value), filter.Children[1].Children) {
+				return true
+			}
+		}
+		return false
+	default:
+		// 不支持扩展匹配
+		return false
+	}
+}
+
+// matchSubstrings 子串匹配
+func matchSubstrings(value string, parts []*ber.Packet) bool {
+	for _, part := range parts {
+		str := strings.ToLower(part.Data.String())
+		switch part.Tag {
+		case ldap.FilterSubstringsInitial:
+			if !strings.HasPrefix(value, str) {
+				return false
+			}
+			value = value[len(str):]
+		case ldap.FilterSubstringsAny:
+			index := strings.Index(value, str)
+			if index < 0 {
+				return false
+			}
+			value = value[index+len(str):]
+		case ldap.FilterSubstringsFinal:
+			if !strings.HasSuffix(value, str) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// directoryResult 生成操作结果响应
+func directoryResult(messageID int64, tag ber.Tag, resultCode uint16, message string) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Response")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "Result Code"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, message, "Diagnostic Message"))
+	return directoryEnvelope(messageID, response)
+}
+
+// directoryEnvelope 封装LDAP消息
+func directoryEnvelope(messageID int64, op *ber.Packet) *ber.Packet {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+	packet.AppendChild(op)
+	return packet
+}
+
+// directoryBaseDN 获取目录根DN
+func directoryBaseDN() string {
+	baseDN := config.GetString("ldapServerBaseDn")
+	if baseDN == "" {
+		baseDN = "dc=idsphere,dc=cn"
+	}
+	return baseDN
+}
+
+// directoryUsername 从绑定DN中获取用户名，支持 uid=xxx,ou=users,... 以及直接使用用户名绑定
+func directoryUsername(bindDN string) string {
+	dn, err := ldap.ParseDN(bindDN)
+	if err != nil || len(dn.RDNs) == 0 {
+		return bindDN
+	}
+	for _, attribute := range dn.RDNs[0].Attributes {
+		if strings.EqualFold(attribute.Type, "uid") || strings.EqualFold(attribute.Type, "cn") {
+			return attribute.Value
+		}
+	}
+	return bindDN
+}
+
+// checkServicePassword 校验服务账号密码
+func checkServicePassword(password string) error {
+	cipherText := config.GetString("ldapServerBindPassword")
+	if cipherText == "" {
+		return errors.New("未配置服务账号密码")
+	}
+	str, err := utils.Decrypt(cipherText)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(str), []byte(password)) != 1 {
+		return errors.New("服务账号密码错误")
+	}
+	return nil
+}
+
+// dnEqualFold 判断两个DN是否相同，不区分大小写
+func dnEqualFold(a, b string) bool {
+	dnA, err := ldap.ParseDN(a)
+	if err != nil {
+		return strings.EqualFold(a, b)
+	}
+	dnB, err := ldap.ParseDN(b)
+	if err != nil {
+		return strings.EqualFold(a, b)
+	}
+	return dnA.EqualFold(dnB)
+}
@@ -399,7 +399,7 @@ func (d *domain) DomainExpiredNotice(task *model.ScheduledTask) error {
 func domainExpiredNoticePost(domains []*model.Domain) map[string]interface{} {
 	var (
 		now     = time.Now()
-		issuer  = config.Conf.Settings["issuer"].(string)
+		issuer  = config.GetString("issuer")
 		content = make([][]map[string]interface{}, 0)
 	)
 
@@ -411,7 +411,7 @@ func domainExpiredNoticePost(domains []*model.Domain) map[string]interface{} {
 		)
 
 		if d.ExpirationAt != nil {
-			expiredAt = d.ExpirationAt.Format("2006-01-02 15:04:05")
+			expiredAt = utils.FormatTime(*d.ExpirationAt)
 			if d.ExpirationAt.Before(now) {
 				statusText = "已过期"
 				statusColor = "red"
@@ -466,9 +466,9 @@ func domainExpiredNoticePost(domains []*model.Domain) map[string]interface{} {
 // domainExpiredNoticeMarkdown 生成域名过期通知 Markdown 文档
 func domainExpiredNoticeMarkdown(domains []*model.Domain) string {
 	var (
-		builder   = &strings.Builder{}
-		now       = time.Now()
-		issuer, _ = config.Conf.Settings["issuer"].(string)
+		builder = &strings.Builder{}
+		now     = time.Now()
+		issuer  = config.GetString("issuer")
 	)
 
 	builder.WriteString("**证书异常提醒：**\n\n")
@@ -485,7 +485,7 @@ func domainExpiredNoticeMarkdown(domains []*model.Domain) string {
 			}
 		}
 
-		expiredAt := d.ExpirationAt.Format("2006-01-02 15:04:05")
+		expiredAt := utils.FormatTime(*d.ExpirationAt)
 		builder.WriteString(fmt.Sprintf("%d. 域名：%s\n\n", i+1, d.Name))
 		builder.WriteString(fmt.Sprintf("   域名服务商：%s\n\n", d.DomainServiceProvider.Name))
 		builder.WriteString(fmt.Sprintf("   到期时间：%s\n\n", expiredAt))
@@ -502,7 +502,7 @@ func domainExpiredNoticeMarkdown(domains []*model.Domain) string {
 func domainExpiredNoticeHTML(domains []*model.Domain) string {
 
 	var (
-		issuer = config.Conf.Settings["issuer"].(string)
+		issuer = config.GetString("issuer")
 		now    = time.Now()
 	)
 
@@ -644,7 +644,7 @@ func generateDomainRows(domains []*model.Domain, now time.Time) string {
 		}
 
 		// 格式化过期时间
-		expiredAt := domain.ExpirationAt.Format("2006-01-02 15:04:05")
+		expiredAt := utils.FormatTime(*domain.ExpirationAt)
 
 		rows.WriteString(fmt.Sprintf(`
             <tr>
@@ -0,0 +1,351 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var DynamicGroup dynamicGroup
+
+type dynamicGroup struct{}
+
+// DynamicGroupOperator 动态分组成员变更时记录到安全事件中的操作人
+const DynamicGroupOperator = "动态分组规则"
+
+// DynamicRule 动态分组规则，如：{"match":"all","conditions":[{"attribute":"department","operator":"eq","value":"SRE"}]}
+type DynamicRule struct {
+	Match      string                  `json:"match"` // all：满足所有条件，any：满足任一条件
+	Conditions []*DynamicRuleCondition `json:"conditions"`
+}
+
+// DynamicRuleCondition 动态分组规则条件，匹配时不区分大小写
+type DynamicRuleCondition struct {
+	Attribute string `json:"attribute"`
+	Operator  string `json:"operator"`
+	Value     string `json:"value"` // in操作符使用逗号分隔多个值，is_active属性使用true或false
+
+	pattern *regexp.Regexp
+}
+
+// dynamicRuleAttributes 动态分组规则支持的用户属性
+var dynamicRuleAttributes = map[string]func(user *model.AuthUser) string{
+	"username":     func(user *model.AuthUser) string { return user.Username },
+	"name":         func(user *model.AuthUser) string { return user.Name },
+	"email":        func(user *model.AuthUser) string { return user.Email },
+	"email_domain": func(user *model.AuthUser) string { return user.Email[strings.LastIndex(user.Email, "@")+1:] },
+	"phone_number": func(user *model.AuthUser) string { return user.PhoneNumber },
+	"department":   func(user *model.AuthUser) string { return user.Department },
+	"title":        func(user *model.AuthUser) string { return user.Title },
+	"user_from":    func(user *model.AuthUser) string { return user.UserFrom },
+	"language":     func(user *model.AuthUser) string { return user.Language },
+	"is_active":    func(user *model.AuthUser) string { return strconv.FormatBool(user.IsActive) },
+}
+
+// dynamicRuleOperators 动态分组规则支持的操作符
+var dynamicRuleOperators = []string{"eq", "ne", "contains", "not_contains", "prefix", "suffix", "in", "regex"}
+
+// ParseRule 解析并校验动态分组规则
+func (d *dynamicGroup) ParseRule(content string) (*DynamicRule, error) {
+
+	rule := &DynamicRule{}
+	if err := json.Unmarshal([]byte(content), rule); err != nil {
+		return nil, errors.New("动态分组规则格式错误：" + err.Error())
+	}
+
+	if rule.Match == "" {
+		rule.Match = "all"
+	}
+	if rule.Match != "all" && rule.Match != "any" {
+		return nil, errors.New("动态分组规则match仅支持all或any")
+	}
+	if len(rule.Conditions) == 0 {
+		return nil, errors.New("动态分组规则至少需要包含一个条件")
+	}
+
+	for _, condition := range rule.Conditions {
+		if _, ok := dynamicRuleAttributes[condition.Attribute]; !ok {
+			return nil, fmt.Errorf("动态分组规则不支持的用户属性：%s", condition.Attribute)
+		}
+		if !utils.Contains(dynamicRuleOperators, condition.Operator) {
+			return nil, fmt.Errorf("动态分组规则不支持的操作符：%s", condition.Operator)
+		}
+		if condition.Operator == "regex" {
+			pattern, err := regexp.Compile("(?i)" + condition.Value)
+			if err != nil {
+				return nil, fmt.Errorf("动态分组规则正则表达式%s错误：%s", condition.Value, err.Error())
+			}
+			condition.pattern = pattern
+		}
+	}
+
+	return rule, nil
+}
+
+// Matches 判断用户是否满足规则
+func (r *DynamicRule) Matches(user *model.AuthUser) bool {
+	for _, condition := range r.Conditions {
+		matched := condition.match(user)
+		if r.Match == "any" && matched {
+			return true
+		}
+		if r.Match == "all" && !matched {
+			return false
+		}
+	}
+	return r.Match == "all"
+}
+
+// match 判断用户是否满足条件
+func (c *DynamicRuleCondition) match(user *model.AuthUser) bool {
+
+	actual := strings.ToLower(strings.TrimSpace(dynamicRuleAttributes[c.Attribute](user)))
+	value := strings.ToLower(strings.TrimSpace(c.Value))
+
+	switch c.Operator {
+	case "eq":
+		return actual == value
+	case "ne":
+		return actual != value
+	case "contains":
+		return strings.Contains(actual, value)
+	case "not_contains":
+		return !strings.Contains(actual, value)
+	case "prefix":
+		return strings.HasPrefix(actual, value)
+	case "suffix":
+		return strings.HasSuffix(actual, value)
+	case "in":
+		for _, item := range strings.Split(value, ",") {
+			if actual == strings.TrimSpace(item) {
+				return true
+			}
+		}
+	case "regex":
+		return c.pattern.MatchString(actual)
+	}
+	return false
+}
+
+// NormalizeRule 校验并格式化动态分组规则，规则为空表示静态分组
+func (d *dynamicGroup) NormalizeRule(content string) (string, error) {
+
+	if strings.TrimSpace(content) == "" {
+		return "", nil
+	}
+
+	rule, err := d.ParseRule(content)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return "", err
+	}
+
+	return string(data), nil
+}
+
+// SyncAll 重新计算所有动态分组的成员，用于定时任务及批量同步用户后调用
+func (d *dynamicGroup) SyncAll() error {
+
+	groups, err := dao.Group.GetDynamicGroups()
+	if err != nil || len(groups) == 0 {
+		return err
+	}
+
+	var users []*model.AuthUser
+	if err := global.MySQLClient.Find(&users).Error; err != nil {
+		return err
+	}
+
+	var failed []string
+	for _, group := range groups {
+		if err := d.syncGroup(group, users); err != nil {
+			logger.Error(fmt.Sprintf("ERROR：动态分组%s同步失败：%s", group.Name, err.Error()))
+			failed = append(failed, group.Name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("动态分组同步失败：%s", strings.Join(failed, "、"))
+	}
+
+	return nil
+}
+
+// SyncGroup 重新计算单个动态分组的成员
+func (d *dynamicGroup) SyncGroup(group *model.AuthGroup) error {
+
+	if group.DynamicRule == "" {
+		return nil
+	}
+
+	var users []*model.AuthUser
+	if err := global.MySQLClient.Find(&users).Error; err != nil {
+		return err
+	}
+
+	return d.syncGroup(group, users)
+}
+
+// SyncUser 用户属性变更后重新计算用户所属的动态分组，失败时仅记录日志，不影响用户的创建及修改
+func (d *dynamicGroup) SyncUser(user *model.AuthUser) {
+
+	groups, err := dao.Group.GetDynamicGroups()
+	if err != nil {
+		logger.Error("ERROR：" + err.Error())
+		return
+	}
+
+	for _, group := range groups {
+		rule, err := d.ParseRule(group.DynamicRule)
+		if err != nil {
+			logger.Error(fmt.Sprintf("ERROR：动态分组%s规则错误：%s", group.Name, err.Error()))
+			continue
+		}
+
+		members, err := dao.Group.GetGroupUsers(global.MySQLClient, group)
+		if err != nil {
+			logger.Error("ERROR：" + err.Error())
+			continue
+		}
+
+		// 仅在用户的成员关系发生变化时更新分组
+		isMember := false
+		users := make([]model.AuthUser, 0, len(members)+1)
+		for _, member := range members {
+			if member.ID == user.ID {
+				isMember = true
+				continue
+			}
+			users = append(users, member)
+		}
+		matched := rule.Matches(user)
+		if matched == isMember {
+			continue
+		}
+		if matched {
+			users = append(users, *user)
+		}
+
+		if err := d.saveMembers(group, members, users); err != nil {
+			logger.Error(fmt.Sprintf("ERROR：动态分组%s更新用户%s失败：%s", group.Name, user.Username, err.Error()))
+		}
+	}
+}
+
+// syncGroup 根据规则从用户列表中计算分组成员并保存
+func (d *dynamicGroup) syncGroup(group *model.AuthGroup, all []*model.AuthUser) error {
+
+	rule, err := d.ParseRule(group.DynamicRule)
+	if err != nil {
+		return err
+	}
+
+	members, err := dao.Group.GetGroupUsers(global.MySQLClient, group)
+	if err != nil {
+		return err
+	}
+
+	users := make([]model.AuthUser, 0)
+	for _, user := range all {
+		if rule.Matches(user) {
+			users = append(users, *user)
+		}
+	}
+
+	return d.saveMembers(group, members, users)
+}
+
+// saveMembers 保存动态分组成员，成员未发生变化时跳过，角色分组同步更新CasBin策略表
+func (d *dynamicGroup) saveMembers(group *model.AuthGroup, members, users []model.AuthUser) error {
+
+	// 对比更新前后的成员，记录成员发生变化的用户
+	oldUsernames := make([]string, 0, len(members))
+	for _, member := range members {
+		oldUsernames = append(oldUsernames, member.Username)
+	}
+	usernames := make([]string, 0, len(users))
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+	changed := make(map[uint]bool)
+	for _, member := range members {
+		if !utils.Contains(usernames, member.Username) {
+			changed[member.ID] = true
+		}
+	}
+	for _, user := range users {
+		if !utils.Contains(oldUsernames, user.Username) {
+			changed[user.ID] = true
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	// 更新组内用户信息
+	if len(users) == 0 {
+		if err := dao.Group.ClearGroupUser(tx, group); err != nil {
+			tx.Rollback()
+			return err
+		}
+	} else if _, err := dao.Group.UpdateGroupUser(tx, group, users); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 同步角色用户组信息到CasBin策略表
+	if group.IsRoleGroup {
+		if err := dao.CasBin.UpdateRoleUser(tx, group.Name, usernames); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if group.IsRoleGroup {
+		// 重新加载策略
+		if err := global.CasBinServer.LoadPolicy(); err != nil {
+			return err
+		}
+
+		// 角色变更后清除所有用户信息缓存
+		dao.User.ClearAllUserInfoCache()
+
+		// 通知安全管理员
+		publishRoleGranted(group.Name, DynamicGroupOperator, oldUsernames, users)
+	} else {
+		// 分组变更后清除成员发生变化的用户信息缓存
+		userIds := make([]uint, 0, len(changed))
+		for id := range changed {
+			userIds = append(userIds, id)
+		}
+		dao.User.ClearUserInfoCache(userIds...)
+	}
+
+	logger.Info(fmt.Sprintf("动态分组%s成员已更新，共%d个用户", group.Name, len(users)))
+
+	// 推送至下游系统
+	ProvisionWebhook.PublishGroupMembers(group, oldUsernames, users)
+
+	return nil
+}
@@ -0,0 +1,530 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+	"net/http"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/check"
+	"strings"
+	"time"
+)
+
+var External external
+
+type external struct{}
+
+// externalLockTTL 外部资源操作锁的有效期，同一资源的修改操作串行执行
+const externalLockTTL = 30 * time.Second
+
+// ExternalError 外部资源接口错误信息
+type ExternalError struct {
+	code int
+	msg  string
+}
+
+func (e *ExternalError) Error() string { return e.msg }
+
+// StatusCode 返回HTTP状态码
+func (e *ExternalError) StatusCode() int { return e.code }
+
+// NewExternalError 创建外部资源接口错误
+func NewExternalError(code int, msg string) *ExternalError {
+	return &ExternalError{code: code, msg: msg}
+}
+
+// ExternalUser 外部系统管理的用户，Password 仅在创建用户时使用
+type ExternalUser struct {
+	ExternalId  string `json:"external_id"`
+	Name        string `json:"name" binding:"required"`
+	Username    string `json:"username" binding:"required"`
+	Password    string `json:"password,omitempty"`
+	PhoneNumber string `json:"phone_number" validate:"omitempty,phone"`
+	Email       string `json:"email" validate:"omitempty,email"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+// ExternalGroup 外部系统管理的分组
+type ExternalGroup struct {
+	ExternalId  string `json:"external_id"`
+	Name        string `json:"name" binding:"required"`
+	IsRoleGroup bool   `json:"is_role_group"`
+}
+
+// ExternalSite 外部系统管理的站点
+type ExternalSite struct {
+	ExternalId  string `json:"external_id"`
+	Name        string `json:"name" binding:"required"`
+	Address     string `json:"address" binding:"required"`
+	Description string `json:"description" binding:"required"`
+	SiteGroupID uint   `json:"site_group_id" binding:"required"`
+	SSO         bool   `json:"sso"`
+	SSOType     uint   `json:"sso_type"`
+	AllOpen     bool   `json:"all_open"`
+	CallbackUrl string `json:"callback_url"`
+	EntityId    string `json:"entity_id"`
+	Certificate string `json:"certificate"`
+	AcsUrls     string `json:"acs_urls"`
+	GrantTypes  string `json:"grant_types"`
+	RespTypes   string `json:"response_types"`
+	Scopes      string `json:"scopes"`
+	CASProfile  string `json:"cas_profile"`
+}
+
+// Preconditions 条件请求头
+type Preconditions struct {
+	IfMatch     string
+	IfNoneMatch string
+}
+
+// externalETag 根据资源内容生成ETag
+func externalETag(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return fmt.Sprintf("\"%s\"", hex.EncodeToString(sum[:16]))
+}
+
+// check 校验条件请求：If-None-Match: * 仅在资源不存在时执行，If-Match 仅在资源存在且ETag一致时执行
+func (p *Preconditions) check(exists bool, etag string) error {
+	if strings.TrimSpace(p.IfNoneMatch) == "*" && exists {
+		return NewExternalError(http.StatusPreconditionFailed, "资源已存在")
+	}
+	ifMatch := strings.TrimSpace(p.IfMatch)
+	if ifMatch == "" {
+		return nil
+	}
+	if !exists {
+		return NewExternalError(http.StatusPreconditionFailed, "资源不存在")
+	}
+	if ifMatch != "*" && strings.TrimPrefix(ifMatch, "W/") != etag {
+		return NewExternalError(http.StatusPreconditionFailed, "资源已被修改，请重新获取后再试")
+	}
+	return nil
+}
+
+// lock 获取资源操作锁，返回释放锁的方法
+func (e *external) lock(kind, externalId string) (func(), error) {
+
+	if strings.TrimSpace(externalId) == "" || len(externalId) > 128 {
+		return nil, NewExternalError(http.StatusBadRequest, "外部标识不能为空且长度不能超过128")
+	}
+
+	key := fmt.Sprintf("external_lock:%s:%s", kind, externalId)
+	token := utils.GenerateRandomString(16)
+	ok, err := global.Cache.SetNX(key, token, externalLockTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, NewExternalError(http.StatusConflict, "资源正在被其它请求修改，请稍后再试")
+	}
+
+	return func() {
+		if value, _ := global.Cache.Get(key); value == token {
+			global.Cache.Del(key)
+		}
+	}, nil
+}
+
+// notFound 判断是否为资源不存在错误
+func notFound(err error) bool {
+	return errors.Is(err, gorm.ErrRecordNotFound)
+}
+
+// toExternalUser 用户转换为外部资源
+func toExternalUser(user *model.AuthUser) *ExternalUser {
+	isActive := user.IsActive
+	data := &ExternalUser{
+		Name:        user.Name,
+		Username:    user.Username,
+		PhoneNumber: user.PhoneNumber,
+		Email:       user.Email,
+		IsActive:    &isActive,
+	}
+	if user.ExternalId != nil {
+		data.ExternalId = *user.ExternalId
+	}
+	return data
+}
+
+// toExternalGroup 分组转换为外部资源
+func toExternalGroup(group *model.AuthGroup) *ExternalGroup {
+	data := &ExternalGroup{
+		Name:        group.Name,
+		IsRoleGroup: group.IsRoleGroup,
+	}
+	if group.ExternalId != nil {
+		data.ExternalId = *group.ExternalId
+	}
+	return data
+}
+
+// toExternalSite 站点转换为外部资源
+func toExternalSite(site *model.Site) *ExternalSite {
+	data := &ExternalSite{
+		Name:        site.Name,
+		Address:     site.Address,
+		Description: site.Description,
+		SiteGroupID: site.SiteGroupID,
+		SSO:         site.SSO,
+		SSOType:     site.SSOType,
+		AllOpen:     site.AllOpen,
+		CallbackUrl: site.CallbackUrl,
+		EntityId:    site.EntityId,
+		Certificate: site.Certificate,
+		AcsUrls:     site.AcsUrls,
+		GrantTypes:  site.GrantTypes,
+		RespTypes:   site.RespTypes,
+		Scopes:      site.Scopes,
+		CASProfile:  site.CASProfile,
+	}
+	if site.ExternalId != nil {
+		data.ExternalId = *site.ExternalId
+	}
+	return data
+}
+
+// GetUser 根据外部标识获取用户及ETag
+func (e *external) GetUser(externalId string) (*ExternalUser, string, error) {
+	user, err := dao.External.GetUser(externalId)
+	if err != nil {
+		if notFound(err) {
+			return nil, "", NewExternalError(http.StatusNotFound, "用户不存在")
+		}
+		return nil, "", err
+	}
+	data := toExternalUser(user)
+	return data, externalETag(data), nil
+}
+
+// PutUser 根据外部标识创建或更新用户，外部标识不存在时纳管同名且未绑定外部标识的用户，返回资源是否为新建
+func (e *external) PutUser(externalId string, pre *Preconditions, data *ExternalUser) (*ExternalUser, string, bool, error) {
+
+	unlock, err := e.lock("user", externalId)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer unlock()
+
+	// 标准化手机号
+	var phoneNational, phoneDisplay string
+	if err := normalizeUserPhone(&data.PhoneNumber, &phoneNational, &phoneDisplay); err != nil {
+		return nil, "", false, NewExternalError(http.StatusBadRequest, err.Error())
+	}
+
+	// 字段校验
+	validate := validator.New()
+	if err := validate.RegisterValidation("phone", check.PhoneNumberCheck); err != nil {
+		return nil, "", false, err
+	}
+	if err := validate.Struct(data); err != nil {
+		return nil, "", false, NewExternalError(http.StatusBadRequest, err.Error())
+	}
+
+	user, err := dao.External.GetUser(externalId)
+	if notFound(err) {
+		user, err = dao.External.GetUnboundUser(data.Username)
+	}
+	if err != nil && !notFound(err) {
+		return nil, "", false, err
+	}
+
+	exists := user != nil
+	var etag string
+	if exists {
+		current := toExternalUser(user)
+		current.ExternalId = externalId
+		etag = externalETag(current)
+	}
+	if err := pre.check(exists, etag); err != nil {
+		return nil, "", false, err
+	}
+
+	if !exists {
+		if data.Password == "" {
+			return nil, "", false, NewExternalError(http.StatusBadRequest, "创建用户时密码不能为空")
+		}
+		user, err = User.AddUser(&dao.UserCreate{
+			Name:        data.Name,
+			Username:    data.Username,
+			Password:    data.Password,
+			PhoneNumber: data.PhoneNumber,
+			Email:       data.Email,
+			UserFrom:    "本地",
+		})
+		if err != nil {
+			return nil, "", false, NewExternalError(http.StatusBadRequest, err.Error())
+		}
+	} else if user.Username != data.Username {
+		return nil, "", false, NewExternalError(http.StatusBadRequest, "用户名不允许修改")
+	}
+
+	// 更新用户信息并绑定外部标识
+	isActive := user.IsActive || !exists
+	if data.IsActive != nil {
+		isActive = *data.IsActive
+	}
+	if err := dao.External.UpdateUser(user, map[string]interface{}{
+		"name":           data.Name,
+		"phone_number":   data.PhoneNumber,
+		"phone_national": phoneNational,
+		"phone_display":  phoneDisplay,
+		"email":          data.Email,
+		"is_active":      isActive,
+		"external_id":    externalId,
+	}); err != nil {
+		return nil, "", false, err
+	}
+
+	result := toExternalUser(user)
+	return result, externalETag(result), !exists, nil
+}
+
+// DeleteUser 根据外部标识删除用户
+func (e *external) DeleteUser(externalId string, pre *Preconditions) error {
+
+	unlock, err := e.lock("user", externalId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	user, err := dao.External.GetUser(externalId)
+	if err != nil {
+		if notFound(err) {
+			return NewExternalError(http.StatusNotFound, "用户不存在")
+		}
+		return err
+	}
+	if err := pre.check(true, externalETag(toExternalUser(user))); err != nil {
+		return err
+	}
+
+	return User.DeleteUser(int(user.ID))
+}
+
+// GetGroup 根据外部标识获取分组及ETag
+func (e *external) GetGroup(externalId string) (*ExternalGroup, string, error) {
+	group, err := dao.External.GetGroup(externalId)
+	if err != nil {
+		if notFound(err) {
+			return nil, "", NewExternalError(http.StatusNotFound, "分组不存在")
+		}
+		return nil, "", err
+	}
+	data := toExternalGroup(group)
+	return data, externalETag(data), nil
+}
+
+// PutGroup 根据外部标识创建或更新分组，外部标识不存在时纳管同名且未绑定外部标识的分组，返回资源是否为新建
+func (e *external) PutGroup(externalId string, pre *Preconditions, data *ExternalGroup) (*ExternalGroup, string, bool, error) {
+
+	unlock, err := e.lock("group", externalId)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer unlock()
+
+	group, err := dao.External.GetGroup(externalId)
+	if notFound(err) {
+		group, err = dao.External.GetUnboundGroup(data.Name)
+	}
+	if err != nil && !notFound(err) {
+		return nil, "", false, err
+	}
+
+	exists := group != nil
+	var etag string
+	if exists {
+		current := toExternalGroup(group)
+		current.ExternalId = externalId
+		etag = externalETag(current)
+	}
+	if err := pre.check(exists, etag); err != nil {
+		return nil, "", false, err
+	}
+
+	if !exists {
+		group, err = Group.AddGroup(&GroupCreate{Name: data.Name, IsRoleGroup: data.IsRoleGroup})
+		if err != nil {
+			return nil, "", false, NewExternalError(http.StatusBadRequest, err.Error())
+		}
+	} else {
+		// 角色分组与普通分组的权限模型不同，不允许互相转换
+		if group.IsRoleGroup != data.IsRoleGroup {
+			return nil, "", false, NewExternalError(http.StatusBadRequest, "分组类型不允许修改")
+		}
+		if group.Name != data.Name {
+			if group, err = Group.UpdateGroup(&GroupUpdate{ID: group.ID, Name: data.Name}); err != nil {
+				return nil, "", false, NewExternalError(http.StatusBadRequest, err.Error())
+			}
+		}
+	}
+
+	// 绑定外部标识
+	if group.ExternalId == nil || *group.ExternalId != externalId {
+		if err := dao.External.BindExternalId(group, externalId); err != nil {
+			return nil, "", false, err
+		}
+		group.ExternalId = &externalId
+	}
+
+	result := toExternalGroup(group)
+	return result, externalETag(result), !exists, nil
+}
+
+// DeleteGroup 根据外部标识删除分组
+func (e *external) DeleteGroup(externalId string, pre *Preconditions) error {
+
+	unlock, err := e.lock("group", externalId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	group, err := dao.External.GetGroup(externalId)
+	if err != nil {
+		if notFound(err) {
+			return NewExternalError(http.StatusNotFound, "分组不存在")
+		}
+		return err
+	}
+	if err := pre.check(true, externalETag(toExternalGroup(group))); err != nil {
+		return err
+	}
+
+	return Group.DeleteGroup(int(group.ID))
+}
+
+// GetSite 根据外部标识获取站点及ETag
+func (e *external) GetSite(externalId string) (*ExternalSite, string, error) {
+	site, err := dao.External.GetSite(externalId)
+	if err != nil {
+		if notFound(err) {
+			return nil, "", NewExternalError(http.StatusNotFound, "站点不存在")
+		}
+		return nil, "", err
+	}
+	data := toExternalSite(site)
+	return data, externalETag(data), nil
+}
+
+// PutSite 根据外部标识创建或更新站点，外部标识不存在时纳管唯一同名且未绑定外部标识的站点，返回资源是否为新建
+func (e *external) PutSite(externalId string, pre *Preconditions, data *ExternalSite) (*ExternalSite, string, bool, error) {
+
+	unlock, err := e.lock("site", externalId)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer unlock()
+
+	site, err := dao.External.GetSite(externalId)
+	if notFound(err) {
+		var sites []*model.Site
+		if sites, err = dao.External.GetUnboundSites(data.Name); err == nil && len(sites) == 1 {
+			site = sites[0]
+		}
+	}
+	if err != nil && !notFound(err) {
+		return nil, "", false, err
+	}
+
+	exists := site != nil
+	var etag string
+	if exists {
+		current := toExternalSite(site)
+		current.ExternalId = externalId
+		etag = externalETag(current)
+	}
+	if err := pre.check(exists, etag); err != nil {
+		return nil, "", false, err
+	}
+
+	if !exists {
+		sso := data.SSO
+		site, err = Site.AddSite(&SiteCreate{
+			Name:        data.Name,
+			Address:     data.Address,
+			SSO:         &sso,
+			SSOType:     data.SSOType,
+			CallbackUrl: data.CallbackUrl,
+			EntityId:    data.EntityId,
+			Certificate: data.Certificate,
+			AcsUrls:     data.AcsUrls,
+			Description: data.Description,
+			SiteGroupID: data.SiteGroupID,
+			GrantTypes:  data.GrantTypes,
+			RespTypes:   data.RespTypes,
+			Scopes:      data.Scopes,
+			CASProfile:  data.CASProfile,
+		})
+		if err != nil {
+			return nil, "", false, NewExternalError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	// 更新站点信息，新建站点时用于设置创建接口不支持的字段
+	site, err = Site.UpdateSite(&dao.UpdateSite{
+		ID:          site.ID,
+		Name:        data.Name,
+		Address:     data.Address,
+		SSO:         &data.SSO,
+		AllOpen:     &data.AllOpen,
+		SSOType:     data.SSOType,
+		EntityId:    &data.EntityId,
+		CallbackUrl: &data.CallbackUrl,
+		Certificate: &data.Certificate,
+		AcsUrls:     &data.AcsUrls,
+		GrantTypes:  &data.GrantTypes,
+		RespTypes:   &data.RespTypes,
+		Scopes:      &data.Scopes,
+		CASProfile:  &data.CASProfile,
+		Description: data.Description,
+		SiteGroupID: data.SiteGroupID,
+	})
+	if err != nil {
+		return nil, "", false, NewExternalError(http.StatusBadRequest, err.Error())
+	}
+
+	// 绑定外部标识
+	if site.ExternalId == nil || *site.ExternalId != externalId {
+		if err := dao.External.BindExternalId(site, externalId); err != nil {
+			return nil, "", false, err
+		}
+		site.ExternalId = &externalId
+	}
+
+	result := toExternalSite(site)
+	return result, externalETag(result), !exists, nil
+}
+
+// DeleteSite 根据外部标识删除站点
+func (e *external) DeleteSite(externalId string, pre *Preconditions) error {
+
+	unlock, err := e.lock("site", externalId)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	site, err := dao.External.GetSite(externalId)
+	if err != nil {
+		if notFound(err) {
+			return NewExternalError(http.StatusNotFound, "站点不存在")
+		}
+		return err
+	}
+	if err := pre.check(true, externalETag(toExternalSite(site))); err != nil {
+		return err
+	}
+
+	return Site.DeleteSite(int(site.ID))
+}
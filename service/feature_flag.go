@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"hash/fnv"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"regexp"
+)
+
+var FeatureFlag featureFlag
+
+type featureFlag struct{}
+
+// featureFlagKeyPattern 功能标识格式
+var featureFlagKeyPattern = regexp.MustCompile(`^[a-z0-9_.-]{1,64}$`)
+
+// FeatureFlagCreate 创建功能开关结构体
+type FeatureFlagCreate struct {
+	Key         string `json:"key" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Enabled     *bool  `json:"enabled" binding:"required"`
+	Percentage  *uint  `json:"percentage" binding:"required,max=100"`
+	Users       []uint `json:"users"`
+	Groups      []uint `json:"groups"`
+}
+
+// FeatureFlagUpdate 更新功能开关结构体
+type FeatureFlagUpdate struct {
+	dao.FeatureFlagUpdate
+	Users  []uint `json:"users"`
+	Groups []uint `json:"groups"`
+}
+
+// GetFeatureFlagList 获取功能开关列表（表格）
+func (f *featureFlag) GetFeatureFlagList(name string, page, limit int) (*dao.FeatureFlagList, error) {
+	return dao.FeatureFlag.GetFeatureFlagList(name, page, limit)
+}
+
+// AddFeatureFlag 创建功能开关
+func (f *featureFlag) AddFeatureFlag(data *FeatureFlagCreate) (*model.FeatureFlag, error) {
+
+	if !featureFlagKeyPattern.MatchString(data.Key) {
+		return nil, errors.New("功能标识只能包含小写字母、数字、下划线、点和中划线，且不能超过64个字符")
+	}
+
+	flag := &model.FeatureFlag{
+		Key:         data.Key,
+		Name:        data.Name,
+		Description: data.Description,
+		Enabled:     *data.Enabled,
+		Percentage:  *data.Percentage,
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.FeatureFlag.AddFeatureFlag(tx, flag)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 设置指定开放的用户及分组
+	if err := f.updateTargets(tx, result, data.Users, data.Groups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 清除不存在时写入的缓存
+	dao.FeatureFlag.ClearFeatureFlagCache(result.Key)
+
+	return result, nil
+}
+
+// UpdateFeatureFlag 更新功能开关
+func (f *featureFlag) UpdateFeatureFlag(data *FeatureFlagUpdate) (*model.FeatureFlag, error) {
+
+	if !featureFlagKeyPattern.MatchString(data.Key) {
+		return nil, errors.New("功能标识只能包含小写字母、数字、下划线、点和中划线，且不能超过64个字符")
+	}
+
+	// 查询要修改的功能开关
+	flag, err := dao.FeatureFlag.GetFeatureFlag(data.ID)
+	if err != nil {
+		return nil, err
+	}
+	oldKey := flag.Key
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.FeatureFlag.UpdateFeatureFlag(tx, flag, &data.FeatureFlagUpdate)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 更新指定开放的用户及分组
+	if err := f.updateTargets(tx, result, data.Users, data.Groups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	dao.FeatureFlag.ClearFeatureFlagCache(oldKey, result.Key)
+
+	return result, nil
+}
+
+// DeleteFeatureFlag 删除功能开关
+func (f *featureFlag) DeleteFeatureFlag(id int) error {
+
+	flag, err := dao.FeatureFlag.GetFeatureFlag(uint(id))
+	if err != nil {
+		return err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.FeatureFlag.DeleteFeatureFlag(tx, flag); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	dao.FeatureFlag.ClearFeatureFlagCache(flag.Key)
+
+	return nil
+}
+
+// IsEnabled 判断功能是否对指定用户开放，功能开关不存在或查询失败时视为关闭
+// 判定顺序：总开关关闭则关闭；用户或用户所属分组在指定开放范围内则开放；否则按灰度比例分桶
+func (f *featureFlag) IsEnabled(key string, userId uint, username string) bool {
+
+	rule, err := dao.FeatureFlag.GetFeatureFlagRule(key)
+	if err != nil {
+		logger.Error(fmt.Sprintf("获取功能开关 %s 失败：%s", key, err.Error()))
+		return false
+	}
+	if !rule.Enabled {
+		return false
+	}
+
+	for _, id := range rule.UserIds {
+		if id == userId {
+			return true
+		}
+	}
+
+	if len(rule.GroupIds) > 0 {
+		groupIds, err := dao.LoginPolicy.GetUserGroupIds(userId)
+		if err != nil {
+			logger.Error(fmt.Sprintf("获取用户 %s 所属分组失败：%s", username, err.Error()))
+			return false
+		}
+		for _, groupId := range groupIds {
+			for _, id := range rule.GroupIds {
+				if id == groupId {
+					return true
+				}
+			}
+		}
+	}
+
+	return f.bucket(key, username) < rule.Percentage
+}
+
+// GetUserFeatures 获取对指定用户开放的功能标识，用于前端控制功能展示
+func (f *featureFlag) GetUserFeatures(userId uint, username string) ([]string, error) {
+
+	keys, err := dao.FeatureFlag.GetEnabledFeatureFlagKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	features := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if f.IsEnabled(key, userId, username) {
+			features = append(features, key)
+		}
+	}
+	return features, nil
+}
+
+// bucket 计算用户在指定功能下的分桶（0-99），同一用户对同一功能的分桶结果固定，提高比例时已开放的用户保持开放
+func (f *featureFlag) bucket(key, username string) uint {
+	h := fnv.New32a()
+	h.Write([]byte(key + ":" + username))
+	return uint(h.Sum32() % 100)
+}
+
+// updateTargets 更新功能开关指定开放的用户及分组
+func (f *featureFlag) updateTargets(tx *gorm.DB, flag *model.FeatureFlag, userIds, groupIds []uint) error {
+
+	var (
+		users  []model.AuthUser
+		groups []model.AuthGroup
+	)
+	if len(userIds) > 0 {
+		if err := tx.Where("id IN ?", userIds).Find(&users).Error; err != nil {
+			return err
+		}
+	}
+	if len(groupIds) > 0 {
+		if err := tx.Where("id IN ?", groupIds).Find(&groups).Error; err != nil {
+			return err
+		}
+	}
+
+	return dao.FeatureFlag.UpdateFeatureFlagTargets(tx, flag, users, groups)
+}
@@ -19,8 +19,8 @@ type FeishuClient struct {
 func NewFeishuClient() (*FeishuClient, error) {
 
 	var (
-		feishuAppId     = config.Conf.Settings["feishuAppId"].(string)
-		feishuAppSecret = config.Conf.Settings["feishuAppSecret"].(string)
+		feishuAppId     = config.GetString("feishuAppId")
+		feishuAppSecret = config.GetString("feishuAppSecret")
 	)
 
 	// 解密
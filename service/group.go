@@ -2,10 +2,12 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"gorm.io/gorm"
 	"ops-api/dao"
 	"ops-api/global"
 	"ops-api/model"
+	"ops-api/utils"
 )
 
 var Group group
@@ -16,12 +18,14 @@ type group struct{}
 type GroupCreate struct {
 	Name        string `json:"name" binding:"required"`
 	IsRoleGroup bool   `json:"is_role_group" default:"false"`
+	DynamicRule string `json:"dynamic_rule"` // 动态分组规则，为空表示静态分组
 }
 
 // GroupUpdate 更新分组名称构体
 type GroupUpdate struct {
-	ID   uint   `json:"id" binding:"required"`
-	Name string `json:"name" binding:"required"`
+	ID          uint    `json:"id" binding:"required"`
+	Name        string  `json:"name" binding:"required"`
+	DynamicRule *string `json:"dynamic_rule"` // 不传时保持原有规则，传空字符串时转换为静态分组
 }
 
 // GroupUpdateUser 更新分组用户构体
@@ -49,12 +53,28 @@ func (u *group) GetGroupList(name string, page, limit int) (data *dao.GroupList,
 // AddGroup 创建分组
 func (u *group) AddGroup(data *GroupCreate) (authGroup *model.AuthGroup, err error) {
 
+	rule, err := DynamicGroup.NormalizeRule(data.DynamicRule)
+	if err != nil {
+		return nil, err
+	}
+
 	group := &model.AuthGroup{
 		Name:        data.Name,
 		IsRoleGroup: data.IsRoleGroup,
+		DynamicRule: rule,
+	}
+
+	result, err := dao.Group.AddGroup(group)
+	if err != nil {
+		return nil, err
+	}
+
+	// 根据规则计算动态分组成员
+	if err := DynamicGroup.SyncGroup(result); err != nil {
+		return nil, err
 	}
 
-	return dao.Group.AddGroup(group)
+	return result, nil
 }
 
 // DeleteGroup 删除分组
@@ -92,6 +112,9 @@ func (u *group) DeleteGroup(id int) (err error) {
 		return err
 	}
 
+	// 角色变更后清除所有用户信息缓存
+	dao.User.ClearAllUserInfoCache()
+
 	return nil
 }
 
@@ -114,8 +137,27 @@ func (u *group) UpdateGroup(data *GroupUpdate) (*model.AuthGroup, error) {
 
 	// 更新分组名称
 	group.Name = data.Name
-	result, err := dao.Group.UpdateGroup(tx, group)
-	if err != nil {
+	if _, err := dao.Group.UpdateGroup(tx, group); err != nil {
+		return nil, err
+	}
+
+	// 更新动态分组规则
+	if data.DynamicRule != nil {
+		rule, err := DynamicGroup.NormalizeRule(*data.DynamicRule)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := dao.Group.UpdateGroupRule(tx, group.ID, rule); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	// 获取更新后的分组
+	result := &model.AuthGroup{}
+	if err := tx.First(result, group.ID).Error; err != nil {
+		tx.Rollback()
 		return nil, err
 	}
 
@@ -130,6 +172,14 @@ func (u *group) UpdateGroup(data *GroupUpdate) (*model.AuthGroup, error) {
 		return nil, err
 	}
 
+	// 角色变更后清除所有用户信息缓存
+	dao.User.ClearAllUserInfoCache()
+
+	// 根据规则重新计算动态分组成员
+	if err := DynamicGroup.SyncGroup(result); err != nil {
+		return nil, err
+	}
+
 	return result, nil
 }
 
@@ -156,11 +206,14 @@ func (u *group) UpdateGroupPermission(data *GroupUpdatePermission) (err error) {
 		return err
 	}
 
+	// 角色变更后清除所有用户信息缓存
+	dao.User.ClearAllUserInfoCache()
+
 	return nil
 }
 
 // UpdateGroupUser 更新组内用户，如果是角色用户组则支持同步用户信息到CasBin策略表
-func (u *group) UpdateGroupUser(data *GroupUpdateUser) (*model.AuthGroup, error) {
+func (u *group) UpdateGroupUser(data *GroupUpdateUser, operator string) (*model.AuthGroup, error) {
 
 	// 开启事务
 	tx := global.MySQLClient.Begin()
@@ -172,6 +225,11 @@ func (u *group) UpdateGroupUser(data *GroupUpdateUser) (*model.AuthGroup, error)
 		return nil, err
 	}
 
+	if group.DynamicRule != "" {
+		tx.Rollback()
+		return nil, errors.New("动态分组的成员由规则自动计算，不允许手动修改")
+	}
+
 	// Users=0需要执行清空操作
 	if len(data.Users) == 0 {
 		return nil, errors.New("角色分组需要至少保留一个用户")
@@ -184,6 +242,13 @@ func (u *group) UpdateGroupUser(data *GroupUpdateUser) (*model.AuthGroup, error)
 		return nil, err
 	}
 
+	// 更新前的组内用户，用于判断新授予角色的用户
+	oldUsernames, err := dao.Group.GetGroupUsernames(tx, group)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// 更新组内用户信息
 	result, err := dao.Group.UpdateGroupUser(tx, group, users)
 	if err != nil {
@@ -213,9 +278,29 @@ func (u *group) UpdateGroupUser(data *GroupUpdateUser) (*model.AuthGroup, error)
 		return nil, err
 	}
 
+	// 角色变更后清除所有用户信息缓存
+	dao.User.ClearAllUserInfoCache()
+
+	// 通知安全管理员
+	if group.IsRoleGroup {
+		publishRoleGranted(group.Name, operator, oldUsernames, users)
+	}
+
+	// 推送至下游系统
+	ProvisionWebhook.PublishGroupMembers(group, oldUsernames, users)
+
 	return result, nil
 }
 
+// publishRoleGranted 对比更新前后的组内用户，为新授予角色的用户发布安全事件
+func publishRoleGranted(role, operator string, oldUsernames []string, users []model.AuthUser) {
+	for _, user := range users {
+		if !utils.Contains(oldUsernames, user.Username) {
+			SecurityEvent.Publish(SecurityEventRoleGranted, operator, user.Username, fmt.Sprintf("授予角色：%s", role))
+		}
+	}
+}
+
 // GetUserNamesFromIDs 根据用户ID列表返回对应的用户名列表
 func GetUserNamesFromIDs(tx *gorm.DB, userIDs []uint) ([]string, error) {
 	var usernames []string
@@ -0,0 +1,212 @@
+package service
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"mime/multipart"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"path/filepath"
+)
+
+var Guide guide
+
+type guide struct{}
+
+// GuideStepCreate 创建引导步骤结构体
+type GuideStepCreate struct {
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content"`
+	Image   string `json:"image"`
+	Sort    uint   `json:"sort"`
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Roles   []uint `json:"roles"`
+}
+
+// GuideStepUpdate 更新引导步骤结构体
+type GuideStepUpdate struct {
+	dao.GuideStepUpdate
+	Roles []uint `json:"roles"`
+}
+
+// GuideStepSort 引导步骤排序结构体
+type GuideStepSort struct {
+	Items []dao.GuideStepSort `json:"items" binding:"required"`
+}
+
+// GetGuideStepList 获取引导步骤列表（表格）
+func (g *guide) GetGuideStepList(title string, page, limit int) (data *dao.GuideStepList, err error) {
+	return dao.Guide.GetGuideStepList(title, page, limit)
+}
+
+// GetUserGuideSteps 获取当前用户可见的引导步骤
+func (g *guide) GetUserGuideSteps(username string) (data []*dao.GuideStepItem, err error) {
+	return dao.Guide.GetUserGuideSteps(username)
+}
+
+// UploadImage 上传引导步骤图片
+func (g *guide) UploadImage(image *multipart.FileHeader) (path string, err error) {
+
+	// 打开上传的图片
+	src, err := image.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	// 拼接存储的路径
+	path = fmt.Sprintf("site/guide/%s%s", uuid.New(), filepath.Ext(image.Filename))
+
+	if err := utils.FileUpload(path, image.Header.Get("Content-Type"), src, image.Size); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// AddGuideStep 创建引导步骤
+func (g *guide) AddGuideStep(data *GuideStepCreate) (*model.SiteGuideStep, error) {
+
+	step := &model.SiteGuideStep{
+		Title:   data.Title,
+		Content: data.Content,
+		Sort:    data.Sort,
+		Enabled: *data.Enabled,
+	}
+	if data.Image != "" {
+		step.Image = &data.Image
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.Guide.AddGuideStep(tx, step)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 设置可见角色
+	if err := g.updateRoles(tx, result, data.Roles); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateGuideStep 更新引导步骤
+func (g *guide) UpdateGuideStep(data *GuideStepUpdate) (*model.SiteGuideStep, error) {
+
+	// 查询要修改的步骤
+	step, err := dao.Guide.GetGuideStep(data.ID)
+	if err != nil {
+		return nil, err
+	}
+	oldImage := step.Image
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.Guide.UpdateGuideStep(tx, step, &data.GuideStepUpdate)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 更新可见角色
+	if err := g.updateRoles(tx, result, data.Roles); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 图片变更后删除旧图片
+	if oldImage != nil && (data.Image == nil || *data.Image != *oldImage) {
+		if err := utils.RemoveObject(*oldImage); err != nil {
+			logger.Warn("删除引导步骤图片失败：" + err.Error())
+		}
+	}
+
+	return result, nil
+}
+
+// UpdateGuideStepSort 更新引导步骤排序
+func (g *guide) UpdateGuideStepSort(data *GuideStepSort) error {
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.Guide.UpdateGuideStepSort(tx, data.Items); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// DeleteGuideStep 删除引导步骤
+func (g *guide) DeleteGuideStep(id int) error {
+
+	step, err := dao.Guide.GetGuideStep(uint(id))
+	if err != nil {
+		return err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.Guide.DeleteGuideStep(tx, step); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 删除图片
+	if step.Image != nil {
+		if err := utils.RemoveObject(*step.Image); err != nil {
+			logger.Warn("删除引导步骤图片失败：" + err.Error())
+		}
+	}
+
+	return nil
+}
+
+// updateRoles 更新引导步骤可见角色（仅允许角色类型的分组）
+func (g *guide) updateRoles(tx *gorm.DB, step *model.SiteGuideStep, roleIds []uint) error {
+
+	var roles []model.AuthGroup
+	if len(roleIds) > 0 {
+		if err := tx.Where("id IN ? AND is_role_group = ?", roleIds, true).Find(&roles).Error; err != nil {
+			return err
+		}
+	}
+
+	return dao.Guide.UpdateGuideStepRoles(tx, step, roles)
+}
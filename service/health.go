@@ -0,0 +1,62 @@
+package service
+
+import (
+	"ops-api/global"
+	"ops-api/utils/breaker"
+)
+
+// 服务就绪检查：MySQL不可用时服务不可用；Redis、MinIO不可用时服务降级运行（令牌吊销检查按配置放行或拒绝，头像暂存后上传）
+
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+var Health health
+
+type health struct{}
+
+// HealthStatus 服务就绪状态
+type HealthStatus struct {
+	Status         string          `json:"status"` // ok、degraded、unavailable
+	MySQL          ComponentStatus `json:"mysql"`
+	Redis          breaker.Status  `json:"redis"`
+	Minio          breaker.Status  `json:"minio"`
+	PendingUploads int64           `json:"pending_uploads"` // MinIO不可用期间暂存的待上传文件数量
+}
+
+// ComponentStatus 依赖服务状态
+type ComponentStatus struct {
+	Available bool   `json:"available"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Ready 获取服务就绪状态
+func (h *health) Ready() *HealthStatus {
+
+	status := &HealthStatus{
+		Status: HealthOK,
+		Redis:  global.RedisBreaker.Status(),
+		Minio:  global.MinioBreaker.Status(),
+	}
+
+	status.MySQL.Available = true
+	db, err := global.MySQLClient.DB()
+	if err == nil {
+		err = db.Ping()
+	}
+	if err != nil {
+		status.MySQL = ComponentStatus{Available: false, LastError: err.Error()}
+		status.Status = HealthUnavailable
+		return status
+	}
+
+	if count, err := UploadQueue.PendingCount(); err == nil {
+		status.PendingUploads = count
+	}
+	if !status.Redis.Available || !status.Minio.Available {
+		status.Status = HealthDegraded
+	}
+	return status
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	lark "github.com/larksuite/oapi-sdk-go/v3"
+	larkapproval "github.com/larksuite/oapi-sdk-go/v3/service/approval/v4"
+	larkcontact "github.com/larksuite/oapi-sdk-go/v3/service/contact/v3"
+	"io"
+	"net/http"
+	"ops-api/config"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+	"time"
+)
+
+// 外部审批（ITSM）：访问申请提交到系统配置 itsmProvider 指定的ITSM中审批，
+// jira：在 Jira Service Management 中创建服务请求，审批结果取自请求的审批（Approval）；
+// feishu：使用飞书自建应用（feishuAppId）创建审批实例，审批定义中需要包含一个多行文本控件（itsmFeishuWidget）用于填写申请内容
+
+const (
+	ITSMJira   = "jira"
+	ITSMFeishu = "feishu"
+
+	itsmTimeout = 10 * time.Second
+)
+
+// ITSMTicket ITSM中创建的工单
+type ITSMTicket struct {
+	ID  string // 工单标识：Jira为Issue Key，飞书为审批实例Code
+	URL string // 工单地址
+}
+
+// ITSMResult ITSM中的审批结果
+type ITSMResult struct {
+	Status   string // 审批状态：pending、approved、rejected
+	Approver string // 审批人
+	Message  string // 审批说明
+}
+
+// ITSMProvider 外部审批相关接口
+type ITSMProvider interface {
+	CreateTicket(request *model.AccessRequest, user *model.AuthUser, summary, description string) (*ITSMTicket, error)
+	GetResult(ticketId string) (*ITSMResult, error)
+}
+
+// NewITSMProvider 根据系统配置获取外部审批，未配置时返回错误
+func NewITSMProvider(provider string) (ITSMProvider, error) {
+	switch provider {
+	case ITSMJira:
+		if config.GetString("itsmJiraAddress") == "" || config.GetString("itsmJiraToken") == "" {
+			return nil, errors.New("未配置Jira Service Management地址或访问令牌")
+		}
+		return &jiraITSM{client: &http.Client{Timeout: itsmTimeout}}, nil
+	case ITSMFeishu:
+		if config.GetString("itsmFeishuApprovalCode") == "" {
+			return nil, errors.New("未配置飞书审批定义Code")
+		}
+		feishu, err := NewFeishuClient()
+		if err != nil {
+			return nil, err
+		}
+		return &feishuITSM{client: feishu.client}, nil
+	case "":
+		return nil, errors.New("未开启外部审批，请联系管理员")
+	}
+	return nil, fmt.Errorf("不支持的外部审批：%s", provider)
+}
+
+// jiraITSM Jira Service Management，参考文档：https://developer.atlassian.com/cloud/jira/service-desk/rest/
+type jiraITSM struct {
+	client *http.Client
+}
+
+// jiraRequest Jira Service Management 服务请求
+type jiraRequest struct {
+	IssueKey string `json:"issueKey"`
+	Links    struct {
+		Web string `json:"web"`
+	} `json:"_links"`
+	CurrentStatus struct {
+		Status         string `json:"status"`
+		StatusCategory string `json:"statusCategory"`
+	} `json:"currentStatus"`
+}
+
+// jiraApprovals 服务请求的审批
+type jiraApprovals struct {
+	Values []struct {
+		Name          string `json:"name"`
+		FinalDecision string `json:"finalDecision"` // approved、declined、pending
+		Approvers     []struct {
+			Approver struct {
+				DisplayName string `json:"displayName"`
+			} `json:"approver"`
+			ApproverDecision string `json:"approverDecision"`
+		} `json:"approvers"`
+	} `json:"values"`
+}
+
+// CreateTicket 创建服务请求，申请人信息写入描述中（申请人可能不是Jira用户）
+func (j *jiraITSM) CreateTicket(request *model.AccessRequest, user *model.AuthUser, summary, description string) (*ITSMTicket, error) {
+
+	body := map[string]interface{}{
+		"serviceDeskId": config.GetString("itsmJiraServiceDesk"),
+		"requestTypeId": config.GetString("itsmJiraRequestType"),
+		"requestFieldValues": map[string]string{
+			"summary":     summary,
+			"description": description,
+		},
+	}
+
+	var resp jiraRequest
+	if err := j.do(http.MethodPost, "/rest/servicedeskapi/request", body, &resp); err != nil {
+		return nil, err
+	}
+
+	return &ITSMTicket{ID: resp.IssueKey, URL: resp.Links.Web}, nil
+}
+
+// GetResult 获取审批结果：任一审批被拒绝时为拒绝，所有审批通过时为通过；
+// 服务请求没有审批且已关闭时视为拒绝，避免未经审批授权
+func (j *jiraITSM) GetResult(ticketId string) (*ITSMResult, error) {
+
+	var approvals jiraApprovals
+	if err := j.do(http.MethodGet, "/rest/servicedeskapi/request/"+ticketId+"/approval", nil, &approvals); err != nil {
+		return nil, err
+	}
+
+	if len(approvals.Values) == 0 {
+		var request jiraRequest
+		if err := j.do(http.MethodGet, "/rest/servicedeskapi/request/"+ticketId, nil, &request); err != nil {
+			return nil, err
+		}
+		if request.CurrentStatus.StatusCategory == "DONE" {
+			return &ITSMResult{Status: AccessRequestRejected, Message: "工单已关闭（" + request.CurrentStatus.Status + "），未经过审批"}, nil
+		}
+		return &ITSMResult{Status: AccessRequestPending}, nil
+	}
+
+	var approvers []string
+	for _, approval := range approvals.Values {
+		for _, item := range approval.Approvers {
+			if item.ApproverDecision == approval.FinalDecision {
+				approvers = append(approvers, item.Approver.DisplayName)
+			}
+		}
+		switch approval.FinalDecision {
+		case "declined":
+			return &ITSMResult{Status: AccessRequestRejected, Approver: strings.Join(approvers, ","), Message: approval.Name + "被拒绝"}, nil
+		case "approved":
+		default:
+			return &ITSMResult{Status: AccessRequestPending}, nil
+		}
+	}
+
+	return &ITSMResult{Status: AccessRequestApproved, Approver: strings.Join(approvers, ",")}, nil
+}
+
+// do 调用Jira接口，配置了用户名时使用Basic认证（Cloud：邮箱+API Token），否则使用Bearer认证（Data Center：个人访问令牌）
+func (j *jiraITSM) do(method, path string, body, result interface{}) error {
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, strings.TrimRight(config.GetString("itsmJiraAddress"), "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	token, err := utils.Decrypt(config.GetString("itsmJiraToken"))
+	if err != nil {
+		return errors.New("Jira访问令牌解密失败")
+	}
+	if username := config.GetString("itsmJiraUser"); username != "" {
+		req.SetBasicAuth(username, token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("Jira接口返回错误，HTTP状态码：%d，%s", resp.StatusCode, string(data))
+	}
+
+	return json.Unmarshal(data, result)
+}
+
+// feishuITSM 飞书审批，参考文档：https://open.feishu.cn/document/server-docs/approval-v4/instance/create
+type feishuITSM struct {
+	client *lark.Client
+}
+
+// CreateTicket 以申请人的身份创建审批实例，申请人按手机号匹配飞书用户
+func (f *feishuITSM) CreateTicket(request *model.AccessRequest, user *model.AuthUser, summary, description string) (*ITSMTicket, error) {
+
+	if user.PhoneNumber == "" {
+		return nil, errors.New("用户未绑定手机号，无法匹配飞书用户")
+	}
+
+	// 获取申请人的飞书用户ID，中国大陆手机号不需要国际区号
+	idResp, err := f.client.Contact.User.BatchGetId(context.Background(), larkcontact.NewBatchGetIdUserReqBuilder().
+		UserIdType("open_id").
+		Body(larkcontact.NewBatchGetIdUserReqBodyBuilder().
+			Mobiles([]string{strings.TrimPrefix(user.PhoneNumber, "+86")}).
+			Build()).
+		Build())
+	if err != nil {
+		return nil, err
+	}
+	if !idResp.Success() {
+		return nil, errors.New(idResp.Msg)
+	}
+	if len(idResp.Data.UserList) == 0 || idResp.Data.UserList[0].UserId == nil {
+		return nil, errors.New("未找到手机号对应的飞书用户")
+	}
+
+	widget := config.GetString("itsmFeishuWidget")
+	if widget == "" {
+		widget = "widget1"
+	}
+	form, err := json.Marshal([]map[string]string{{"id": widget, "type": "textarea", "value": description}})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Approval.Instance.Create(context.Background(), larkapproval.NewCreateInstanceReqBuilder().
+		InstanceCreate(larkapproval.NewInstanceCreateBuilder().
+			ApprovalCode(config.GetString("itsmFeishuApprovalCode")).
+			OpenId(*idResp.Data.UserList[0].UserId).
+			Form(string(form)).
+			Title(summary).
+			Uuid(fmt.Sprintf("idsphere-access-request-%d", request.ID)).
+			Build()).
+		Build())
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success() {
+		return nil, errors.New(resp.Msg)
+	}
+
+	return &ITSMTicket{ID: *resp.Data.InstanceCode}, nil
+}
+
+// GetResult 获取审批实例状态：APPROVED 为通过，REJECTED、CANCELED、DELETED 为拒绝，其它为审批中
+func (f *feishuITSM) GetResult(ticketId string) (*ITSMResult, error) {
+
+	resp, err := f.client.Approval.Instance.Get(context.Background(), larkapproval.NewGetInstanceReqBuilder().
+		InstanceId(ticketId).
+		UserIdType("open_id").
+		Build())
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success() {
+		return nil, errors.New(resp.Msg)
+	}
+
+	status := ""
+	if resp.Data.Status != nil {
+		status = *resp.Data.Status
+	}
+
+	// 审批人取最后一个已处理的审批任务
+	var approver string
+	for _, task := range resp.Data.TaskList {
+		if task.Status != nil && (*task.Status == "APPROVED" || *task.Status == "REJECTED") && task.OpenId != nil {
+			approver = "飞书用户 " + *task.OpenId
+		}
+	}
+
+	switch status {
+	case "APPROVED":
+		return &ITSMResult{Status: AccessRequestApproved, Approver: approver}, nil
+	case "REJECTED", "CANCELED", "DELETED":
+		return &ITSMResult{Status: AccessRequestRejected, Approver: approver, Message: "审批实例状态：" + status}, nil
+	}
+	return &ITSMResult{Status: AccessRequestPending}, nil
+}
@@ -0,0 +1,534 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var Keycloak keycloak
+
+type keycloak struct{}
+
+// keycloakBuiltinClients Keycloak内置客户端，不需要导入
+var keycloakBuiltinClients = map[string]bool{
+	"account":                true,
+	"account-console":        true,
+	"admin-cli":              true,
+	"broker":                 true,
+	"realm-management":       true,
+	"security-admin-console": true,
+}
+
+// KeycloakRealm Keycloak Realm导出文件结构（仅包含需要导入的字段）
+type KeycloakRealm struct {
+	Realm   string            `json:"realm"`
+	Users   []*KeycloakUser   `json:"users"`
+	Groups  []*KeycloakGroup  `json:"groups"`
+	Clients []*KeycloakClient `json:"clients"`
+	Roles   struct {
+		Realm  []*KeycloakRole            `json:"realm"`
+		Client map[string][]*KeycloakRole `json:"client"`
+	} `json:"roles"`
+}
+
+type KeycloakUser struct {
+	Username               string                `json:"username"`
+	FirstName              string                `json:"firstName"`
+	LastName               string                `json:"lastName"`
+	Email                  string                `json:"email"`
+	Enabled                bool                  `json:"enabled"`
+	Attributes             map[string][]string   `json:"attributes"`
+	Credentials            []*KeycloakCredential `json:"credentials"`
+	RealmRoles             []string              `json:"realmRoles"`
+	ClientRoles            map[string][]string   `json:"clientRoles"`
+	Groups                 []string              `json:"groups"`
+	RequiredActions        []string              `json:"requiredActions"`
+	ServiceAccountClientId string                `json:"serviceAccountClientId"`
+}
+
+type KeycloakCredential struct {
+	Type      string `json:"type"`
+	Value     string `json:"value"` // 仅明文导出或手工构造的文件包含该字段
+	Temporary bool   `json:"temporary"`
+}
+
+type KeycloakGroup struct {
+	Name        string              `json:"name"`
+	Path        string              `json:"path"`
+	RealmRoles  []string            `json:"realmRoles"`
+	ClientRoles map[string][]string `json:"clientRoles"`
+	SubGroups   []*KeycloakGroup    `json:"subGroups"`
+}
+
+type KeycloakRole struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Composite   bool   `json:"composite"`
+}
+
+type KeycloakClient struct {
+	ClientId     string            `json:"clientId"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	RootUrl      string            `json:"rootUrl"`
+	BaseUrl      string            `json:"baseUrl"`
+	RedirectUris []string          `json:"redirectUris"`
+	Secret       string            `json:"secret"`
+	Protocol     string            `json:"protocol"`
+	Enabled      bool              `json:"enabled"`
+	BearerOnly   bool              `json:"bearerOnly"`
+	PublicClient bool              `json:"publicClient"`
+	Attributes   map[string]string `json:"attributes"`
+}
+
+// KeycloakImportItem 导入映射明细
+type KeycloakImportItem struct {
+	Type   string `json:"type"`   // user、group、role、client
+	Source string `json:"source"` // Keycloak中的名称
+	Target string `json:"target"` // IDSphere中的名称
+	Status string `json:"status"` // created、exists、skipped、failed
+	Note   string `json:"note"`
+}
+
+// KeycloakImportReport 导入报告
+type KeycloakImportReport struct {
+	Realm    string                `json:"realm"`
+	DryRun   bool                  `json:"dry_run"`
+	Summary  map[string]int        `json:"summary"`
+	Items    []*KeycloakImportItem `json:"items"`
+	Warnings []string              `json:"warnings"`
+}
+
+// add 添加映射明细并统计
+func (r *KeycloakImportReport) add(itemType, source, target, status, note string) {
+	r.Items = append(r.Items, &KeycloakImportItem{
+		Type:   itemType,
+		Source: source,
+		Target: target,
+		Status: status,
+		Note:   note,
+	})
+	r.Summary[itemType+"_"+status]++
+}
+
+// warn 添加告警信息
+func (r *KeycloakImportReport) warn(format string, a ...interface{}) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, a...))
+}
+
+// ImportRealm 导入Keycloak Realm导出文件，dryRun=true时仅生成映射报告，不写入数据
+func (k *keycloak) ImportRealm(content []byte, dryRun bool) (*KeycloakImportReport, error) {
+
+	var realm KeycloakRealm
+	if err := json.Unmarshal(content, &realm); err != nil {
+		return nil, errors.New("Realm导出文件解析失败：" + err.Error())
+	}
+	if realm.Realm == "" {
+		return nil, errors.New("文件中缺少realm字段，请确认是否为Keycloak Realm导出文件")
+	}
+
+	report := &KeycloakImportReport{
+		Realm:    realm.Realm,
+		DryRun:   dryRun,
+		Summary:  make(map[string]int),
+		Items:    []*KeycloakImportItem{},
+		Warnings: []string{},
+	}
+
+	// 开启事务，预览模式在最后回滚
+	tx := global.MySQLClient.Begin()
+
+	// 导入用户组（Realm角色、Keycloak分组均映射为IDSphere普通分组）
+	groups := make(map[string]*model.AuthGroup)
+	k.importRoles(tx, &realm, groups, report)
+	k.importGroups(tx, realm.Groups, groups, report)
+
+	// 导入用户
+	users := k.importUsers(tx, &realm, report)
+
+	// 导入客户端
+	sites := k.importClients(tx, &realm, report)
+
+	// 关联用户与分组、站点
+	if err := k.importMemberships(tx, &realm, users, groups, sites, report); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if dryRun {
+		tx.Rollback()
+		return report, nil
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 根据导入的用户属性重新计算动态分组
+	if err := DynamicGroup.SyncAll(); err != nil {
+		report.warn("%s", err.Error())
+	}
+
+	return report, nil
+}
+
+// importRoles 将Realm角色映射为分组
+func (k *keycloak) importRoles(tx *gorm.DB, realm *KeycloakRealm, groups map[string]*model.AuthGroup, report *KeycloakImportReport) {
+	for _, role := range realm.Roles.Realm {
+		// 跳过Keycloak默认角色
+		if role.Name == "offline_access" || role.Name == "uma_authorization" || role.Name == "default-roles-"+realm.Realm {
+			report.add("role", role.Name, "", "skipped", "Keycloak内置角色")
+			continue
+		}
+		if role.Composite {
+			report.warn("角色 %s 为组合角色，子角色不会展开，请导入后手动核对分组成员", role.Name)
+		}
+		group, status, err := k.firstOrCreateGroup(tx, role.Name)
+		if err != nil {
+			report.add("role", role.Name, role.Name, "failed", err.Error())
+			continue
+		}
+		groups["role:"+role.Name] = group
+		report.add("role", role.Name, group.Name, status, "Realm角色映射为普通分组")
+	}
+}
+
+// importGroups 递归导入分组，使用去除前导“/”的完整路径作为分组名称
+func (k *keycloak) importGroups(tx *gorm.DB, kcGroups []*KeycloakGroup, groups map[string]*model.AuthGroup, report *KeycloakImportReport) {
+	for _, kcGroup := range kcGroups {
+		path := kcGroup.Path
+		if path == "" {
+			path = "/" + kcGroup.Name
+		}
+		name := strings.TrimPrefix(path, "/")
+
+		group, status, err := k.firstOrCreateGroup(tx, name)
+		if err != nil {
+			report.add("group", path, name, "failed", err.Error())
+		} else {
+			groups[path] = group
+			report.add("group", path, group.Name, status, "")
+		}
+
+		k.importGroups(tx, kcGroup.SubGroups, groups, report)
+	}
+}
+
+// importUsers 导入用户，已存在的同名用户不会被修改
+func (k *keycloak) importUsers(tx *gorm.DB, realm *KeycloakRealm, report *KeycloakImportReport) map[string]*model.AuthUser {
+
+	users := make(map[string]*model.AuthUser)
+	passwordExpiredAtDays := config.GetInt("passwordExpireDays")
+
+	for _, kcUser := range realm.Users {
+		// 服务账号属于客户端凭据，不需要导入
+		if kcUser.ServiceAccountClientId != "" {
+			report.add("user", kcUser.Username, "", "skipped", "客户端服务账号")
+			continue
+		}
+
+		// 已存在的用户仅用于关联分组和站点
+		var existing model.AuthUser
+		if err := tx.Where("username = ?", kcUser.Username).First(&existing).Error; err == nil {
+			users[kcUser.Username] = &existing
+			report.add("user", kcUser.Username, existing.Username, "exists", "用户已存在，仅同步分组和站点关联")
+			continue
+		}
+
+		// 导出文件中的密码为哈希值，无法还原，仅支持明文凭据
+		note := ""
+		password := ""
+		for _, credential := range kcUser.Credentials {
+			if credential.Type == "password" && credential.Value != "" && !credential.Temporary {
+				password = credential.Value
+			}
+		}
+		passwordExpiredAt := time.Now().AddDate(0, 0, passwordExpiredAtDays)
+		if password == "" {
+			// 随机密码并立即过期，用户需通过密码重置功能设置新密码
+			password = utils.GenerateRandomString(32)
+			passwordExpiredAt = time.Now()
+			note = "无法导入原密码，需重置密码"
+		}
+
+		user := &model.AuthUser{
+			Name:              keycloakUserName(kcUser),
+			Username:          kcUser.Username,
+			Password:          password,
+			PhoneNumber:       keycloakAttribute(kcUser.Attributes, "phoneNumber", "phone_number", "mobile", "phone"),
+			IsActive:          kcUser.Enabled,
+			Email:             kcUser.Email,
+			Department:        keycloakAttribute(kcUser.Attributes, "department"),
+			Title:             keycloakAttribute(kcUser.Attributes, "title", "jobTitle"),
+			RequiredActions:   RequiredAction.FromKeycloak(kcUser.RequiredActions),
+			UserFrom:          "Keycloak",
+			PasswordExpiredAt: &passwordExpiredAt,
+		}
+		setUserPhone(user)
+		if err := tx.Create(user).Error; err != nil {
+			report.add("user", kcUser.Username, kcUser.Username, "failed", err.Error())
+			continue
+		}
+
+		users[kcUser.Username] = user
+		report.add("user", kcUser.Username, user.Username, "created", note)
+
+		// 根据自动分配规则分配分组及站点
+		if err := ProvisionRule.Apply(tx, []*model.AuthUser{user}, nil); err != nil {
+			report.warn("%s", err.Error())
+		}
+	}
+
+	return users
+}
+
+// importClients 将客户端映射为站点，OIDC客户端保留原ClientId和ClientSecret
+func (k *keycloak) importClients(tx *gorm.DB, realm *KeycloakRealm, report *KeycloakImportReport) map[string]*model.Site {
+
+	sites := make(map[string]*model.Site)
+
+	// 站点分组
+	siteGroup := &model.SiteGroup{}
+	groupName := "Keycloak-" + realm.Realm
+	if err := tx.Where("name = ?", groupName).FirstOrCreate(siteGroup, model.SiteGroup{Name: groupName}).Error; err != nil {
+		report.warn("站点分组 %s 创建失败：%s，客户端不会被导入", groupName, err.Error())
+		return sites
+	}
+
+	for _, client := range realm.Clients {
+		if keycloakBuiltinClients[client.ClientId] {
+			report.add("client", client.ClientId, "", "skipped", "Keycloak内置客户端")
+			continue
+		}
+		if client.BearerOnly {
+			report.add("client", client.ClientId, "", "skipped", "bearer-only客户端不需要登录")
+			continue
+		}
+
+		name := client.Name
+		if name == "" || strings.HasPrefix(name, "${") {
+			name = client.ClientId
+		}
+		description := client.Description
+		if description == "" {
+			description = "从Keycloak导入：" + client.ClientId
+		}
+		address := client.BaseUrl
+		if client.RootUrl != "" && !strings.HasPrefix(address, "http") {
+			address = strings.TrimRight(client.RootUrl, "/") + address
+		}
+		redirectUri := ""
+		if len(client.RedirectUris) > 0 {
+			redirectUri = strings.TrimSuffix(client.RedirectUris[0], "*")
+		}
+
+		site := &model.Site{
+			Name:        name,
+			Address:     address,
+			Description: description,
+			SSO:         true,
+			SiteGroupID: siteGroup.ID,
+		}
+
+		note := ""
+		switch client.Protocol {
+		case "saml":
+			site.SSOType = 3
+			site.EntityId = client.ClientId
+			site.Certificate = client.Attributes["saml.signing.certificate"]
+			site.CallbackUrl = client.Attributes["saml_assertion_consumer_url_post"]
+			if site.CallbackUrl == "" {
+				site.CallbackUrl = redirectUri
+			}
+			if site.Certificate == "" {
+				note = "未找到SP证书，请手动补充"
+			}
+		case "", "openid-connect":
+			site.SSOType = 2
+			site.CallbackUrl = redirectUri
+			if len(client.RedirectUris) > 1 {
+				note = fmt.Sprintf("存在%d个回调地址，仅导入第一个", len(client.RedirectUris))
+			}
+		default:
+			report.add("client", client.ClientId, "", "skipped", "不支持的协议："+client.Protocol)
+			continue
+		}
+
+		// 未定义客户端角色时，Keycloak默认允许所有用户访问
+		if len(realm.Roles.Client[client.ClientId]) == 0 {
+			site.AllOpen = true
+		}
+
+		result, err := dao.Site.AddSite(tx, site)
+		if err != nil {
+			report.add("client", client.ClientId, name, "failed", err.Error())
+			continue
+		}
+
+		// 创建时会生成新的凭据，OIDC客户端需要恢复为原凭据，避免应用侧修改配置
+		if site.SSOType == 2 {
+			credential := map[string]interface{}{"client_id": client.ClientId}
+			if client.Secret != "" && !strings.HasPrefix(client.Secret, "**") {
+				credential["client_secret"] = client.Secret
+			} else {
+				note = strings.TrimLeft(note+"；ClientSecret未导出，已重新生成", "；")
+			}
+			if err := tx.Model(result).Updates(credential).Error; err != nil {
+				report.add("client", client.ClientId, name, "failed", err.Error())
+				continue
+			}
+		}
+		if client.PublicClient {
+			report.warn("客户端 %s 为公共客户端，IDSphere将要求使用ClientSecret", client.ClientId)
+		}
+
+		sites[client.ClientId] = result
+		report.add("client", client.ClientId, name, "created", note)
+	}
+
+	return sites
+}
+
+// importMemberships 根据用户的分组、角色关联分组和站点
+func (k *keycloak) importMemberships(tx *gorm.DB, realm *KeycloakRealm, users map[string]*model.AuthUser, groups map[string]*model.AuthGroup, sites map[string]*model.Site, report *KeycloakImportReport) error {
+
+	// 分组路径与分组定义映射，用于获取通过分组继承的角色
+	kcGroups := make(map[string]*KeycloakGroup)
+	var walk func(items []*KeycloakGroup)
+	walk = func(items []*KeycloakGroup) {
+		for _, item := range items {
+			kcGroups[item.Path] = item
+			walk(item.SubGroups)
+		}
+	}
+	walk(realm.Groups)
+
+	groupUsers := make(map[*model.AuthGroup][]*model.AuthUser)
+	siteUsers := make(map[*model.Site][]*model.AuthUser)
+
+	for _, kcUser := range realm.Users {
+		user, ok := users[kcUser.Username]
+		if !ok {
+			continue
+		}
+
+		realmRoles := append([]string{}, kcUser.RealmRoles...)
+		clientRoles := make(map[string]bool)
+		for clientId, roles := range kcUser.ClientRoles {
+			if len(roles) > 0 {
+				clientRoles[clientId] = true
+			}
+		}
+
+		for _, path := range kcUser.Groups {
+			if group, ok := groups[path]; ok {
+				groupUsers[group] = append(groupUsers[group], user)
+			} else {
+				report.warn("用户 %s 所属分组 %s 未导入", kcUser.Username, path)
+			}
+			// 继承分组及上级分组的角色
+			for p := path; p != ""; {
+				if kcGroup, ok := kcGroups[p]; ok {
+					realmRoles = append(realmRoles, kcGroup.RealmRoles...)
+					for clientId, roles := range kcGroup.ClientRoles {
+						if len(roles) > 0 {
+							clientRoles[clientId] = true
+						}
+					}
+				}
+				index := strings.LastIndex(p, "/")
+				if index < 0 {
+					break
+				}
+				p = p[:index]
+			}
+		}
+
+		for _, role := range realmRoles {
+			if group, ok := groups["role:"+role]; ok && !containsUser(groupUsers[group], user) {
+				groupUsers[group] = append(groupUsers[group], user)
+			}
+		}
+
+		for clientId := range clientRoles {
+			if site, ok := sites[clientId]; ok && !site.AllOpen {
+				siteUsers[site] = append(siteUsers[site], user)
+			}
+		}
+	}
+
+	for group, members := range groupUsers {
+		if err := tx.Model(group).Association("Users").Append(members); err != nil {
+			return err
+		}
+	}
+	for site, members := range siteUsers {
+		if err := tx.Model(site).Association("Users").Append(members); err != nil {
+			return err
+		}
+		report.warn("站点 %s 已按客户端角色授权 %d 个用户访问，客户端角色本身不会导入", site.Name, len(members))
+	}
+
+	return nil
+}
+
+// firstOrCreateGroup 获取或创建普通分组
+func (k *keycloak) firstOrCreateGroup(tx *gorm.DB, name string) (*model.AuthGroup, string, error) {
+	group := &model.AuthGroup{}
+	err := tx.Where("name = ?", name).First(group).Error
+	if err == nil {
+		return group, "exists", nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, "", err
+	}
+	group = &model.AuthGroup{Name: name}
+	if err := tx.Create(group).Error; err != nil {
+		return nil, "", err
+	}
+	return group, "created", nil
+}
+
+// keycloakUserName 拼接用户姓名，中文姓名按“姓+名”的顺序
+func keycloakUserName(user *KeycloakUser) string {
+	if user.FirstName == "" && user.LastName == "" {
+		return user.Username
+	}
+	for _, r := range user.FirstName + user.LastName {
+		if r > unicode.MaxASCII {
+			return user.LastName + user.FirstName
+		}
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+// keycloakAttribute 按顺序获取第一个存在的用户属性
+func keycloakAttribute(attributes map[string][]string, keys ...string) string {
+	for _, key := range keys {
+		if values := attributes[key]; len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+func containsUser(users []*model.AuthUser, user *model.AuthUser) bool {
+	for _, item := range users {
+		if item.ID == user.ID {
+			return true
+		}
+	}
+	return false
+}
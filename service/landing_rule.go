@@ -0,0 +1,319 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"net/url"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"strings"
+)
+
+var LandingRule landingRule
+
+type landingRule struct{}
+
+// LandingRuleCreate 创建登录后跳转规则结构体
+type LandingRuleCreate struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	SiteID      *uint  `json:"site_id"`
+	URL         string `json:"url" binding:"required"`
+	Enabled     *bool  `json:"enabled" binding:"required"`
+	Groups      []uint `json:"groups"`
+}
+
+// LandingRuleUpdate 更新登录后跳转规则结构体
+type LandingRuleUpdate struct {
+	dao.LandingRuleUpdate
+	Groups []uint `json:"groups"`
+}
+
+// GetLandingRuleList 获取登录后跳转规则列表（表格）
+func (l *landingRule) GetLandingRuleList(name string, page, limit int) (data *dao.LandingRuleList, err error) {
+	return dao.LandingRule.GetLandingRuleList(name, page, limit)
+}
+
+// AddLandingRule 创建登录后跳转规则
+func (l *landingRule) AddLandingRule(data *LandingRuleCreate) (*model.LandingRule, error) {
+
+	if err := l.validate(data.SiteID, data.URL); err != nil {
+		return nil, err
+	}
+
+	rule := &model.LandingRule{
+		Name:        data.Name,
+		Description: data.Description,
+		SiteID:      data.SiteID,
+		URL:         strings.TrimSpace(data.URL),
+		Enabled:     *data.Enabled,
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.LandingRule.AddLandingRule(tx, rule)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 设置规则适用的分组
+	if err := l.updateGroups(tx, result, data.Groups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateLandingRule 更新登录后跳转规则
+func (l *landingRule) UpdateLandingRule(data *LandingRuleUpdate) (*model.LandingRule, error) {
+
+	if err := l.validate(data.SiteID, data.URL); err != nil {
+		return nil, err
+	}
+	data.URL = strings.TrimSpace(data.URL)
+
+	// 查询要修改的规则
+	rule, err := dao.LandingRule.GetLandingRule(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.LandingRule.UpdateLandingRule(tx, rule, &data.LandingRuleUpdate)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 更新规则适用的分组
+	if err := l.updateGroups(tx, result, data.Groups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteLandingRule 删除登录后跳转规则
+func (l *landingRule) DeleteLandingRule(id int) error {
+
+	rule, err := dao.LandingRule.GetLandingRule(uint(id))
+	if err != nil {
+		return err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.LandingRule.DeleteLandingRule(tx, rule); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// Resolve 获取控制台直接登录后的跳转地址：
+// 1、next 为站内路径时跳转至 next；
+// 2、next 为用户有权访问的已注册应用地址时跳转至 next，应用配置了适用于该用户的跳转规则时跳转至规则地址；
+// 3、next 的域名在允许跳转的域名（redirectAllowlist）中时跳转至 next；
+// 4、其它情况跳转至用户所属分组的默认跳转地址，未配置时返回空，由前端跳转至首页
+func (l *landingRule) Resolve(userId uint, next string) (string, error) {
+
+	groupIds, err := dao.LoginPolicy.GetUserGroupIds(userId)
+	if err != nil {
+		return "", err
+	}
+	rules, err := dao.LandingRule.GetEnabledLandingRules()
+	if err != nil {
+		return "", err
+	}
+
+	next = strings.TrimSpace(next)
+	if next != "" {
+		if isLocalPath(next) {
+			return next, nil
+		}
+
+		target, err := url.Parse(next)
+		if err == nil && (target.Scheme == "http" || target.Scheme == "https") && target.Hostname() != "" {
+
+			// 已注册的应用
+			site, err := l.matchSite(target)
+			if err != nil {
+				return "", err
+			}
+			if site != nil && (site.AllOpen || dao.Site.IsUserInSite(userId, site)) {
+				if rule := l.matchRule(rules, groupIds, &site.ID); rule != nil {
+					return rule.URL, nil
+				}
+				return next, nil
+			}
+
+			// 允许跳转的域名
+			if hostAllowed(target.Hostname()) {
+				return next, nil
+			}
+		}
+
+		logger.Warn(fmt.Sprintf("登录后跳转地址不在允许范围内，已忽略（用户ID：%d）：%s", userId, next))
+	}
+
+	// 分组默认跳转地址
+	if rule := l.matchRule(rules, groupIds, nil); rule != nil {
+		return rule.URL, nil
+	}
+	return "", nil
+}
+
+// matchSite 获取跳转地址所属的应用，按访问地址的协议及域名匹配，不存在时返回nil
+func (l *landingRule) matchSite(target *url.URL) (*model.Site, error) {
+	sites, err := dao.LandingRule.GetSiteAddresses()
+	if err != nil {
+		return nil, err
+	}
+	for _, site := range sites {
+		address, err := url.Parse(site.Address)
+		if err != nil {
+			continue
+		}
+		if strings.EqualFold(address.Scheme, target.Scheme) && strings.EqualFold(address.Host, target.Host) {
+			return site, nil
+		}
+	}
+	return nil, nil
+}
+
+// matchRule 获取适用于用户的第一个跳转规则，siteId为nil时匹配分组默认跳转规则
+func (l *landingRule) matchRule(rules []*model.LandingRule, groupIds []uint, siteId *uint) *model.LandingRule {
+	for _, rule := range rules {
+		if (siteId == nil) != (rule.SiteID == nil) || (siteId != nil && *siteId != *rule.SiteID) {
+			continue
+		}
+		if len(rule.Groups) == 0 {
+			return rule
+		}
+		for _, group := range rule.Groups {
+			for _, id := range groupIds {
+				if group.ID == id {
+					return rule
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// validate 校验跳转规则的应用及跳转地址，跳转地址仅允许站内路径、已注册应用的地址或允许跳转的域名
+func (l *landingRule) validate(siteId *uint, value string) error {
+
+	if siteId != nil {
+		sites, err := dao.LandingRule.GetSiteAddresses()
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, site := range sites {
+			if site.ID == *siteId {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.New("应用不存在或未配置访问地址")
+		}
+	}
+
+	value = strings.TrimSpace(value)
+	if isLocalPath(value) {
+		return nil
+	}
+
+	target, err := url.Parse(value)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Hostname() == "" {
+		return errors.New("跳转地址必须为站内路径（以/开头）或http(s)地址")
+	}
+	if hostAllowed(target.Hostname()) {
+		return nil
+	}
+	site, err := l.matchSite(target)
+	if err != nil {
+		return err
+	}
+	if site == nil {
+		return fmt.Errorf("跳转地址的域名%s不属于已注册的应用，请先添加至允许跳转的域名", target.Hostname())
+	}
+	return nil
+}
+
+// updateGroups 更新登录后跳转规则适用的分组
+func (l *landingRule) updateGroups(tx *gorm.DB, rule *model.LandingRule, groupIds []uint) error {
+
+	var groups []model.AuthGroup
+	if len(groupIds) > 0 {
+		if err := tx.Where("id IN ?", groupIds).Find(&groups).Error; err != nil {
+			return err
+		}
+	}
+
+	return dao.LandingRule.UpdateLandingRuleGroups(tx, rule, groups)
+}
+
+// isLocalPath 判断是否为站内路径，以 // 或 /\ 开头的地址会被浏览器视为其它域名
+func isLocalPath(value string) bool {
+	if !strings.HasPrefix(value, "/") || strings.HasPrefix(value, "//") || strings.HasPrefix(value, "/\\") {
+		return false
+	}
+	if strings.ContainsAny(value, "\r\n\t") {
+		return false
+	}
+	target, err := url.Parse(value)
+	return err == nil && target.Scheme == "" && target.Host == ""
+}
+
+// hostAllowed 判断域名是否在允许跳转的域名中，*.example.com 匹配所有子域名
+func hostAllowed(host string) bool {
+	host = strings.ToLower(host)
+	items := config.GetList("redirectAllowlist")
+	for _, item := range items {
+		item = strings.ToLower(strings.TrimSpace(item))
+		if item == "" {
+			continue
+		}
+		if strings.HasPrefix(item, "*.") {
+			if strings.HasSuffix(host, item[1:]) {
+				return true
+			}
+		} else if host == item {
+			return true
+		}
+	}
+	return false
+}
@@ -2,16 +2,16 @@ package service
 
 import (
 	"crypto/sha1"
-	"crypto/tls"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"github.com/go-ldap/ldap/v3"
+	"github.com/wonderivan/logger"
 	"golang.org/x/text/encoding/unicode"
 	"ops-api/config"
 	"ops-api/dao"
+	"ops-api/global"
 	"ops-api/model"
-	"ops-api/utils"
 	"strconv"
 	"strings"
 	"time"
@@ -21,17 +21,8 @@ var AD ad
 
 type ad struct{}
 
-type LDAPServer struct {
-	Conn   *ldap.Conn
-	Config LDAPConfig
-}
-
-type LDAPConfig struct {
-	Addr             string
-	BindUserDN       string
-	BindUserPassword string
-	SearchDN         string
-}
+// ldapSyncPageSize 同步用户时分页查找的每页数量
+const ldapSyncPageSize = 500
 
 // UserList 用户同步结构体，用于LDAP用户同步
 type UserList struct {
@@ -41,59 +32,21 @@ type UserList struct {
 	IsActive          bool       `json:"is_active"`
 	PhoneNumber       string     `json:"phone_number"`
 	Email             string     `json:"email"`
+	Department        string     `json:"department"`
+	Title             string     `json:"title"`
 	UserFrom          string     `json:"user_from"`
 	PasswordExpiredAt *time.Time `json:"password_expired_at"`
-}
-
-// Connect 建立LDAP连接
-func (a *ad) Connect() (*LDAPServer, error) {
-
-	var (
-		host     = config.Conf.Settings["ldapAddress"].(string)
-		userDn   = config.Conf.Settings["ldapBindDn"].(string)
-		password = config.Conf.Settings["ldapBindPassword"].(string)
-		searchDn = config.Conf.Settings["ldapSearchDn"].(string)
-	)
-
-	// 密码解密
-	str, _ := utils.Decrypt(password)
-
-	conf := LDAPConfig{
-		Addr:             host,
-		BindUserDN:       userDn,
-		BindUserPassword: str,
-		SearchDN:         searchDn,
-	}
-
-	conn, err := ldap.DialURL(conf.Addr, ldap.DialWithTLSConfig(&tls.Config{InsecureSkipVerify: true}))
-	if err != nil {
-		return nil, err
-	}
-	_, err = conn.SimpleBind(&ldap.SimpleBindRequest{
-		Username: conf.BindUserDN,
-		Password: conf.BindUserPassword,
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	return &LDAPServer{Conn: conn, Config: conf}, nil
+	DN                string     `json:"dn"`
 }
 
 // LDAPUserSearch 根据用户名查找用户信息
 func (a *ad) LDAPUserSearch(username string) (result *ldap.SearchResult, err error) {
 
 	var (
-		userAttribute = config.Conf.Settings["ldapFilterAttribute"].(string)
-		searchDn      = config.Conf.Settings["ldapSearchDn"].(string)
+		userAttribute = config.GetString("ldapFilterAttribute")
+		searchDn      = config.GetString("ldapSearchDn")
 	)
 
-	// 建立LDAP连接
-	l, err := a.Connect()
-	if err != nil {
-		return nil, err
-	}
-
 	// 查找用户
 	searchDN := strings.Split(searchDn, "&")
 	for _, dn := range searchDN {
@@ -106,13 +59,13 @@ func (a *ad) LDAPUserSearch(username string) (result *ldap.SearchResult, err err
 			0,
 			0,
 			false,
-			fmt.Sprintf("(&(objectClass=person)(%s=%s))", userAttribute, username),
+			fmt.Sprintf("(&(objectClass=person)(%s=%s))", userAttribute, ldap.EscapeFilter(username)),
 			[]string{},
 			nil,
 		)
 
 		// 执行查找
-		searchResult, err := l.Conn.Search(searchRequest)
+		searchResult, err := ldapSearch(searchRequest, 0)
 		if err != nil {
 			return nil, err
 		}
@@ -132,12 +85,6 @@ func (a *ad) LDAPUserSearch(username string) (result *ldap.SearchResult, err err
 // LDAPUserAuthentication 用户认证
 func (a *ad) LDAPUserAuthentication(username, password string) (result *ldap.SearchResult, err error) {
 
-	// 建立LDAP连接
-	l, err := a.Connect()
-	if err != nil {
-		return nil, err
-	}
-
 	// 获取用户信息
 	searchResult, err := a.LDAPUserSearch(username)
 
@@ -146,7 +93,7 @@ func (a *ad) LDAPUserAuthentication(username, password string) (result *ldap.Sea
 	}
 
 	// 检查账号和密码是否过期
-	userAttribute := config.Conf.Settings["ldapFilterAttribute"].(string)
+	userAttribute := config.GetString("ldapFilterAttribute")
 	if userAttribute == "uid" {
 		// 获取当前日期距离1970年1月1日之间的天数
 		currentDays := daysSinceEpoch()
@@ -187,8 +134,11 @@ func (a *ad) LDAPUserAuthentication(username, password string) (result *ldap.Sea
 
 	// 密码认证
 	userDN := searchResult.Entries[0].DN
-	err = l.Conn.Bind(userDN, password)
+	ok, err := ldapBindUser(userDN, password)
 	if err != nil {
+		return nil, err
+	}
+	if !ok {
 		return nil, errors.New("用户或密码错误")
 	}
 
@@ -198,12 +148,6 @@ func (a *ad) LDAPUserAuthentication(username, password string) (result *ldap.Sea
 
 // LDAPUserResetPassword 重置用户密码
 func (a *ad) LDAPUserResetPassword(username, password string) (err error) {
-	// 建立LDAP连接
-	l, err := a.Connect()
-	if err != nil {
-		return err
-	}
-
 	// 获取用户信息
 	searchResult, err := a.LDAPUserSearch(username)
 	if err != nil {
@@ -217,7 +161,7 @@ func (a *ad) LDAPUserResetPassword(username, password string) (err error) {
 	// 密码修改
 	var (
 		passwordExpiredAt *time.Time
-		userAttribute     = config.Conf.Settings["ldapFilterAttribute"].(string)
+		userAttribute     = config.GetString("ldapFilterAttribute")
 	)
 	if userAttribute == "uid" {
 		// 使用 SHA1 算法对密码进行哈希处理
@@ -248,7 +192,7 @@ func (a *ad) LDAPUserResetPassword(username, password string) (err error) {
 		passwordExpiredAt = expiredAt
 
 		// 执行修改请求
-		if err := l.Conn.Modify(req); err != nil {
+		if err := ldapDo("modify", func(conn *ldap.Conn) error { return conn.Modify(req) }); err != nil {
 			return err
 		}
 
@@ -261,7 +205,7 @@ func (a *ad) LDAPUserResetPassword(username, password string) (err error) {
 		req.Replace("unicodePwd", []string{pwdEncoded})
 
 		// 执行修改请求，注意：修改用户密码需要确保BindUserDN账号具备修改用户密码权限，以及需要使用ldaps方式连接，ldaps默认端口号为636，如：ldaps://192.168.200.13:636
-		if err := l.Conn.Modify(req); err != nil {
+		if err := ldapDo("modify", func(conn *ldap.Conn) error { return conn.Modify(req) }); err != nil {
 			return err
 		}
 
@@ -313,16 +257,10 @@ func (a *ad) LDAPUserSync() (err error) {
 	var (
 		userList               []UserList
 		createOrUpdateUserList []*model.AuthUser
-		searchDn               = config.Conf.Settings["ldapSearchDn"].(string)
-		userAttribute          = config.Conf.Settings["ldapFilterAttribute"].(string)
+		searchDn               = config.GetString("ldapSearchDn")
+		userAttribute          = config.GetString("ldapFilterAttribute")
 	)
 
-	// 建立LDAP连接
-	l, err := a.Connect()
-	if err != nil {
-		return err
-	}
-
 	// 获取所有用户
 	searchDN := strings.Split(searchDn, "&")
 	for _, dn := range searchDN {
@@ -339,8 +277,8 @@ func (a *ad) LDAPUserSync() (err error) {
 			nil,
 		)
 
-		// 执行查找
-		searchResult, err := l.Conn.Search(searchRequest)
+		// 执行分页查找，避免超出服务器单次返回的数量限制（Windows AD默认为1000）
+		searchResult, err := ldapSearch(searchRequest, ldapSyncPageSize)
 		if err != nil {
 			return err
 		}
@@ -417,8 +355,11 @@ func (a *ad) LDAPUserSync() (err error) {
 				IsActive:          isActive,
 				PhoneNumber:       value.GetAttributeValue("mobile"),
 				Email:             value.GetAttributeValue("mail"),
+				Department:        value.GetAttributeValue("department"),
+				Title:             value.GetAttributeValue("title"),
 				UserFrom:          "LDAP",
 				PasswordExpiredAt: passwordExpiredAt,
+				DN:                value.DN,
 			}
 			// 将用户信息追加到结构体
 			userList = append(userList, *userInfo)
@@ -426,27 +367,49 @@ func (a *ad) LDAPUserSync() (err error) {
 	}
 
 	// 同步所有用户
+	dns := make(map[string]string)
 	for _, user := range userList {
-		createOrUpdateUserList = append(createOrUpdateUserList, &model.AuthUser{
+		dns[user.Username] = user.DN
+		authUser := &model.AuthUser{
 			Username:          user.Username,
 			Name:              user.Name,
 			Email:             user.Email,
 			Password:          user.Password,
 			IsActive:          user.IsActive,
 			PhoneNumber:       user.PhoneNumber,
+			Department:        user.Department,
+			Title:             user.Title,
+			RequiredActions:   RequiredAction.Initial(), // 仅在新建用户时写入
 			UserFrom:          user.UserFrom,
 			PasswordExpiredAt: user.PasswordExpiredAt,
-		})
+		}
+		setUserPhone(authUser)
+		createOrUpdateUserList = append(createOrUpdateUserList, authUser)
+	}
+	created, err := dao.User.SyncUsers(createOrUpdateUserList)
+	if err != nil {
+		return err
 	}
-	return dao.User.SyncUsers(createOrUpdateUserList)
+
+	// 根据自动分配规则为新同步的用户分配分组及站点
+	if err := ProvisionRule.Apply(global.MySQLClient, created, dns); err != nil {
+		logger.Error("ERROR：" + err.Error())
+	}
+
+	// 用户属性变更后重新计算动态分组
+	if err := DynamicGroup.SyncAll(); err != nil {
+		logger.Error("ERROR：" + err.Error())
+	}
+
+	return nil
 }
 
 // getPasswordExpiredAt 获取密码过期时间
 func getPasswordExpiredAt(lastChangeString, passwordExpiredString *string) (passwordExpiredAt *time.Time, err error) {
 
 	var (
-		userAttribute = config.Conf.Settings["ldapFilterAttribute"].(string)
-		passwordAge   = config.Conf.Settings["ldapUserPasswordExpireDays"].(int)
+		userAttribute = config.GetString("ldapFilterAttribute")
+		passwordAge   = config.GetInt("ldapUserPasswordExpireDays")
 	)
 
 	if userAttribute == "uid" {
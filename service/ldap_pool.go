@@ -0,0 +1,418 @@
+package service
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/wonderivan/logger"
+	"net"
+	"net/url"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LDAP连接池：用户同步、LDAP账号密码登录及密码回写共用连接池中使用管理账号绑定的连接，
+// 连接空闲超过一定时间后使用前进行健康检查，LDAP配置修改后自动重建连接池；
+// 配置了ldapRateLimit时，所有实例对LDAP服务器的操作次数通过Redis统一限制
+
+const (
+	ldapHealthCheckIdle = 30 * time.Second // 连接空闲超过该时间后使用前进行健康检查
+	ldapReferralHops    = 1                // 跟随引用（Referral）的最大次数
+)
+
+var (
+	ldapOperations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "ldap_operations_total",
+		Help: "LDAP操作次数",
+	}, []string{"operation", "result"})
+	ldapOperationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ldap_operation_duration_seconds",
+		Help:    "LDAP操作耗时",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"operation"})
+	ldapPoolConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "ldap_pool_connections",
+		Help: "LDAP连接池连接数",
+	}, []string{"state"})
+)
+
+// ldapPoolConfig LDAP连接池配置，任意配置修改后重建连接池
+type ldapPoolConfig struct {
+	Addr            string
+	BindDN          string
+	BindPassword    string
+	StartTLS        bool // 使用ldap://地址时通过StartTLS升级为加密连接
+	SkipVerify      bool // 不校验服务器证书
+	FollowReferrals bool // 跟随搜索结果中的引用（Referral）
+	Timeout         time.Duration
+	Size            int // 最大连接数
+}
+
+// ldapConn 连接池中的连接
+type ldapConn struct {
+	*ldap.Conn
+	lastUsed time.Time
+}
+
+// ldapPool LDAP连接池
+type ldapPool struct {
+	conf  ldapPoolConfig
+	idle  chan *ldapConn
+	slots chan struct{} // 已使用的连接数，用于限制最大连接数
+}
+
+var (
+	ldapPoolMutex   sync.Mutex
+	ldapPoolCurrent *ldapPool
+)
+
+// currentLDAPConfig 获取当前的LDAP配置
+func currentLDAPConfig() ldapPoolConfig {
+
+	// 密码解密
+	password, _ := utils.Decrypt(config.GetString("ldapBindPassword"))
+
+	conf := ldapPoolConfig{
+		Addr:            config.GetString("ldapAddress"),
+		BindDN:          config.GetString("ldapBindDn"),
+		BindPassword:    password,
+		StartTLS:        config.GetBool("ldapStartTLS"),
+		SkipVerify:      config.GetBool("ldapSkipVerify"),
+		FollowReferrals: config.GetBool("ldapFollowReferrals"),
+		Timeout:         time.Duration(config.GetInt("ldapTimeout")) * time.Second,
+		Size:            config.GetInt("ldapPoolSize"),
+	}
+	if conf.Timeout <= 0 {
+		conf.Timeout = 10 * time.Second
+	}
+	if conf.Size <= 0 {
+		conf.Size = 1
+	}
+	return conf
+}
+
+// getLDAPPool 获取LDAP连接池，LDAP配置修改后关闭原连接池中的空闲连接并重建连接池
+func getLDAPPool() *ldapPool {
+
+	conf := currentLDAPConfig()
+
+	ldapPoolMutex.Lock()
+	defer ldapPoolMutex.Unlock()
+
+	if ldapPoolCurrent != nil && ldapPoolCurrent.conf == conf {
+		return ldapPoolCurrent
+	}
+	if ldapPoolCurrent != nil {
+		ldapPoolCurrent.closeIdle()
+	}
+	ldapPoolCurrent = &ldapPool{
+		conf:  conf,
+		idle:  make(chan *ldapConn, conf.Size),
+		slots: make(chan struct{}, conf.Size),
+	}
+	return ldapPoolCurrent
+}
+
+// ldapDo 从连接池获取连接执行LDAP操作，operation 用于记录监控指标
+func ldapDo(operation string, fn func(conn *ldap.Conn) error) error {
+	return getLDAPPool().do(operation, fn)
+}
+
+// do 从连接池获取连接执行LDAP操作，连接异常时关闭连接，否则放回连接池
+func (p *ldapPool) do(operation string, fn func(conn *ldap.Conn) error) error {
+
+	if p.conf.Addr == "" {
+		return errors.New("未配置LDAP服务器地址")
+	}
+
+	if err := ldapRateWait(p.conf.Timeout); err != nil {
+		ldapOperations.WithLabelValues(operation, "rate_limited").Inc()
+		return err
+	}
+
+	conn, err := p.get()
+	if err != nil {
+		ldapOperations.WithLabelValues(operation, "connect_error").Inc()
+		return err
+	}
+
+	start := time.Now()
+	err = fn(conn.Conn)
+	ldapOperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+
+	if err != nil {
+		ldapOperations.WithLabelValues(operation, "error").Inc()
+	} else {
+		ldapOperations.WithLabelValues(operation, "success").Inc()
+	}
+
+	// 网络错误或超时的连接不再复用
+	p.put(conn, ldap.IsErrorWithCode(err, ldap.ErrorNetwork))
+	return err
+}
+
+// get 从连接池获取连接，没有可用的空闲连接时新建连接，连接数达到上限时等待其它连接释放
+func (p *ldapPool) get() (*ldapConn, error) {
+
+	select {
+	case p.slots <- struct{}{}:
+	case <-time.After(p.conf.Timeout):
+		return nil, errors.New("LDAP连接池已满，请稍后再试")
+	}
+	ldapPoolConnections.WithLabelValues("in_use").Inc()
+
+	for {
+		var conn *ldapConn
+		select {
+		case conn = <-p.idle:
+			ldapPoolConnections.WithLabelValues("idle").Dec()
+		default:
+		}
+		if conn == nil {
+			break
+		}
+		if p.healthy(conn) {
+			return conn, nil
+		}
+		conn.Close()
+	}
+
+	conn, err := p.dial(p.conf.Addr)
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+	return &ldapConn{Conn: conn}, nil
+}
+
+// put 将连接放回连接池，discard 为true、连接池已满或连接池已重建时关闭连接
+func (p *ldapPool) put(conn *ldapConn, discard bool) {
+
+	defer p.release()
+
+	ldapPoolMutex.Lock()
+	stale := ldapPoolCurrent != p
+	ldapPoolMutex.Unlock()
+
+	if discard || stale || conn.IsClosing() {
+		conn.Close()
+		return
+	}
+
+	conn.lastUsed = time.Now()
+	select {
+	case p.idle <- conn:
+		ldapPoolConnections.WithLabelValues("idle").Inc()
+	default:
+		conn.Close()
+	}
+}
+
+// release 释放连接数
+func (p *ldapPool) release() {
+	<-p.slots
+	ldapPoolConnections.WithLabelValues("in_use").Dec()
+}
+
+// healthy 连接健康检查，空闲时间较短的连接不检查，否则查询RootDSE判断连接是否可用
+func (p *ldapPool) healthy(conn *ldapConn) bool {
+
+	if conn.IsClosing() {
+		return false
+	}
+	if time.Since(conn.lastUsed) < ldapHealthCheckIdle {
+		return true
+	}
+
+	_, err := conn.Search(ldap.NewSearchRequest("", ldap.ScopeBaseObject, ldap.NeverDerefAliases, 1, int(p.conf.Timeout.Seconds()), false, "(objectClass=*)", []string{"1.1"}, nil))
+	if err != nil {
+		logger.Warn("LDAP连接健康检查失败，" + err.Error())
+		return false
+	}
+	return true
+}
+
+// closeIdle 关闭连接池中的空闲连接
+func (p *ldapPool) closeIdle() {
+	for {
+		select {
+		case conn := <-p.idle:
+			ldapPoolConnections.WithLabelValues("idle").Dec()
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
+// dial 建立LDAP连接并使用管理账号绑定，支持ldaps://及StartTLS
+func (p *ldapPool) dial(addr string) (*ldap.Conn, error) {
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.conf.SkipVerify}
+	if u, err := url.Parse(addr); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	conn, err := ldap.DialURL(addr,
+		ldap.DialWithTLSConfig(tlsConfig),
+		ldap.DialWithDialer(&net.Dialer{Timeout: p.conf.Timeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(p.conf.Timeout)
+
+	if p.conf.StartTLS && strings.HasPrefix(strings.ToLower(addr), "ldap://") {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("LDAP StartTLS失败：%s", err.Error())
+		}
+	}
+
+	if err := p.bind(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// bind 使用管理账号绑定，用户认证后需重新绑定才能放回连接池
+func (p *ldapPool) bind(conn *ldap.Conn) error {
+	_, err := conn.SimpleBind(&ldap.SimpleBindRequest{
+		Username: p.conf.BindDN,
+		Password: p.conf.BindPassword,
+	})
+	return err
+}
+
+// ldapBindUser 使用用户的DN及密码进行认证，认证后连接重新使用管理账号绑定，用户名或密码错误时返回false
+func ldapBindUser(userDN, password string) (bool, error) {
+
+	var (
+		pool    = getLDAPPool()
+		authErr error
+	)
+	err := pool.do("bind", func(conn *ldap.Conn) error {
+		authErr = conn.Bind(userDN, password)
+		// 重新绑定失败时关闭连接，避免使用用户身份的连接被放回连接池
+		if err := pool.bind(conn); err != nil {
+			conn.Close()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	if ldap.IsErrorWithCode(authErr, ldap.ErrorNetwork) {
+		return false, authErr
+	}
+	return authErr == nil, nil
+}
+
+// ldapSearch 执行LDAP查找，paging 大于0时使用分页查找，开启跟随引用时查找引用的服务器并合并结果
+func ldapSearch(req *ldap.SearchRequest, paging uint32) (result *ldap.SearchResult, err error) {
+
+	pool := getLDAPPool()
+	err = pool.do("search", func(conn *ldap.Conn) error {
+		if paging > 0 {
+			result, err = conn.SearchWithPaging(req, paging)
+		} else {
+			result, err = conn.Search(req)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if pool.conf.FollowReferrals {
+		pool.followReferrals(req, result, paging, ldapReferralHops)
+	}
+
+	return result, nil
+}
+
+// followReferrals 查找搜索结果中引用的服务器并合并结果，引用的服务器不可用时忽略
+func (p *ldapPool) followReferrals(req *ldap.SearchRequest, result *ldap.SearchResult, paging uint32, hops int) {
+
+	if hops <= 0 {
+		return
+	}
+
+	referrals := result.Referrals
+	result.Referrals = nil
+	for _, referral := range referrals {
+		u, err := url.Parse(referral)
+		if err != nil || u.Host == "" {
+			continue
+		}
+
+		// 引用地址中的DN为查找范围，未指定时使用原查找范围
+		baseDN := req.BaseDN
+		if dn := strings.TrimPrefix(u.Path, "/"); dn != "" {
+			baseDN, _ = url.PathUnescape(dn)
+		}
+		addr := u.Scheme + "://" + u.Host
+
+		sub, err := p.searchReferral(addr, baseDN, req, paging)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("LDAP引用（%s）查找失败，%s", referral, err.Error()))
+			continue
+		}
+		p.followReferrals(req, sub, paging, hops-1)
+		result.Entries = append(result.Entries, sub.Entries...)
+		result.Referrals = append(result.Referrals, sub.Referrals...)
+	}
+}
+
+// searchReferral 在引用的服务器上执行查找，引用的服务器不使用连接池
+func (p *ldapPool) searchReferral(addr, baseDN string, req *ldap.SearchRequest, paging uint32) (*ldap.SearchResult, error) {
+
+	conn, err := p.dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	sub := ldap.NewSearchRequest(baseDN, req.Scope, req.DerefAliases, req.SizeLimit, req.TimeLimit, req.TypesOnly, req.Filter, req.Attributes, nil)
+	if paging > 0 {
+		return conn.SearchWithPaging(sub, paging)
+	}
+	return conn.Search(sub)
+}
+
+// ldapRateWait 限制所有实例对LDAP服务器的每秒操作次数，超出限制时等待至下一秒，超过timeout仍未获取到配额时返回错误，Redis异常时放行
+func ldapRateWait(timeout time.Duration) error {
+
+	limit := config.GetInt("ldapRateLimit")
+	if limit <= 0 {
+		return nil
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		now := time.Now()
+		key := fmt.Sprintf("ldap_rate_limit:%d", now.Unix())
+		count, err := global.Cache.Incr(key, 2*time.Second)
+		if err != nil {
+			logger.Error("ERROR：LDAP操作频率统计失败，", err.Error())
+			return nil
+		}
+		if count <= int64(limit) {
+			return nil
+		}
+
+		next := now.Truncate(time.Second).Add(time.Second)
+		if next.After(deadline) {
+			return errors.New("LDAP操作过于频繁，请稍后再试")
+		}
+		time.Sleep(time.Until(next))
+	}
+}
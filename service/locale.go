@@ -0,0 +1,104 @@
+package service
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"ops-api/config"
+	"ops-api/model"
+	"ops-api/utils/i18n"
+	"time"
+)
+
+// defaultLocale 获取系统默认语言
+func defaultLocale() string {
+	lang := config.GetString("defaultLanguage")
+	if locale := i18n.Normalize(lang); locale != "" {
+		return locale
+	}
+	return i18n.DefaultLocale
+}
+
+// userLocale 获取用户的首选语言，用户未设置时使用系统默认语言
+func userLocale(user *model.AuthUser) string {
+	if user == nil {
+		return defaultLocale()
+	}
+	return languageLocale(user.Language)
+}
+
+// languageLocale 将用户语言属性转换为支持的语言，为空或不支持时使用系统默认语言
+func languageLocale(language string) string {
+	if locale := i18n.Normalize(language); locale != "" {
+		return locale
+	}
+	return defaultLocale()
+}
+
+// userLocation 获取用户的时区，用户未设置时使用系统时区
+func userLocation(user *model.AuthUser) *time.Location {
+	if user == nil {
+		return config.Location()
+	}
+	return timezoneLocation(user.Timezone)
+}
+
+// timezoneLocation 将用户时区属性转换为时区，为空或无效时使用系统时区
+func timezoneLocation(timezone string) *time.Location {
+	if timezone == "" {
+		return config.Location()
+	}
+	if loc, err := config.LoadLocation(timezone); err == nil {
+		return loc
+	}
+	return config.Location()
+}
+
+// RequestLocale 获取未登录请求的语言（Accept-Language请求头），无法识别时使用系统默认语言
+func RequestLocale(acceptLanguage string) string {
+	if locale := i18n.FromAcceptLanguage(acceptLanguage); locale != "" {
+		return locale
+	}
+	return defaultLocale()
+}
+
+// PostFormText 单点登录自动提交表单中展示给用户的文本
+type PostFormText struct {
+	Lang     string
+	Note     template.HTML // 浏览器不支持JavaScript时的提示
+	Continue string
+}
+
+// newPostFormText 生成指定语言的表单文本
+func newPostFormText(locale string) PostFormText {
+	return PostFormText{
+		Lang:     locale,
+		Note:     template.HTML(i18n.T(locale, "sso.noscript")),
+		Continue: i18n.T(locale, "sso.continue"),
+	}
+}
+
+// verificationCodeHTML 验证码邮件HTML
+func verificationCodeHTML(locale, title, number string) string {
+
+	issuer := config.GetString("issuer")
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html lang="%s">
+		<head>
+			<meta charset="UTF-8">
+			<title>%s</title>
+		</head>
+		<body>
+			<p>%s</p>
+			<br>
+			<p>%s</p>
+			<p style="color: red">%s</p>
+		</body>
+		</html>
+	`, locale, title,
+		i18n.T(locale, "mail.code", number),
+		i18n.T(locale, "mail.signature", html.EscapeString(issuer)),
+		i18n.T(locale, "mail.no_reply"))
+}
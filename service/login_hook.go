@@ -0,0 +1,210 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"io"
+	"net/http"
+	"ops-api/dao"
+	"ops-api/model"
+	"ops-api/utils/hook"
+	"strconv"
+	"time"
+)
+
+var LoginHook loginHook
+
+type loginHook struct{}
+
+// LoginHookCreate 创建登录扩展结构体
+type LoginHookCreate struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Stage       string `json:"stage" binding:"required,oneof=pre_auth post_auth pre_token"`
+	URL         string `json:"url" binding:"required,url"`
+	Secret      string `json:"secret"`
+	Timeout     uint   `json:"timeout" binding:"required,min=1,max=30"`
+	FailOpen    *bool  `json:"fail_open" binding:"required"`
+	Sort        int    `json:"sort"`
+	Enabled     *bool  `json:"enabled" binding:"required"`
+}
+
+// LoginHookPlugin 内置扩展信息
+type LoginHookPlugin struct {
+	Name  string `json:"name"`
+	Stage string `json:"stage"`
+}
+
+// loginHookResult Webhook响应结构体
+type loginHookResult struct {
+	Allow   bool   `json:"allow"`   // 是否允许登录
+	Message string `json:"message"` // 拒绝登录时返回给用户的提示信息
+}
+
+// GetLoginHookList 获取登录扩展列表（表格）
+func (l *loginHook) GetLoginHookList(name string, page, limit int) (data *dao.LoginHookList, err error) {
+	return dao.LoginHook.GetLoginHookList(name, page, limit)
+}
+
+// GetLoginHookPlugins 获取已注册的内置扩展
+func (l *loginHook) GetLoginHookPlugins() []*LoginHookPlugin {
+	plugins := make([]*LoginHookPlugin, 0)
+	for _, stage := range hook.Stages {
+		for _, p := range hook.Plugins(stage) {
+			plugins = append(plugins, &LoginHookPlugin{Name: p.Name, Stage: string(p.Stage)})
+		}
+	}
+	return plugins
+}
+
+// AddLoginHook 创建登录扩展
+func (l *loginHook) AddLoginHook(data *LoginHookCreate) (*model.LoginHook, error) {
+	return dao.LoginHook.AddLoginHook(&model.LoginHook{
+		Name:        data.Name,
+		Description: data.Description,
+		Stage:       data.Stage,
+		URL:         data.URL,
+		Secret:      data.Secret,
+		Timeout:     data.Timeout,
+		FailOpen:    *data.FailOpen,
+		Sort:        data.Sort,
+		Enabled:     *data.Enabled,
+	})
+}
+
+// UpdateLoginHook 更新登录扩展
+func (l *loginHook) UpdateLoginHook(data *dao.LoginHookUpdate) (*model.LoginHook, error) {
+	h, err := dao.LoginHook.GetLoginHook(data.ID)
+	if err != nil {
+		return nil, err
+	}
+	return dao.LoginHook.UpdateLoginHook(h, data)
+}
+
+// DeleteLoginHook 删除登录扩展
+func (l *loginHook) DeleteLoginHook(id int) error {
+	h, err := dao.LoginHook.GetLoginHook(uint(id))
+	if err != nil {
+		return err
+	}
+	return dao.LoginHook.DeleteLoginHook(h)
+}
+
+// Run 依次执行扩展点的内置扩展及Webhook，任一扩展拒绝时返回错误
+func (l *loginHook) Run(ctx *hook.Context) error {
+
+	for _, p := range hook.Plugins(ctx.Stage) {
+		if err := p.Handler(ctx); err != nil {
+			logger.Warn(fmt.Sprintf("登录扩展 %s 拒绝用户 %s 登录：%s", p.Name, ctx.Username, err.Error()))
+			return err
+		}
+	}
+
+	hooks, err := dao.LoginHook.GetEnabledLoginHooks(string(ctx.Stage))
+	if err != nil {
+		return err
+	}
+	for _, h := range hooks {
+		if err := l.call(h, ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// call 调用Webhook
+// 请求体为登录上下文（JSON），配置了密钥时使用 HMAC-SHA256 对 "时间戳.请求体" 签名，
+// 签名及时间戳分别通过请求头 X-Hook-Signature、X-Hook-Timestamp 传递；
+// Webhook 返回 {"allow": false, "message": "..."} 时拒绝登录
+func (l *loginHook) call(h *model.LoginHook, ctx *hook.Context) error {
+
+	result, err := l.request(h, ctx)
+	if err != nil {
+		logger.Error(fmt.Sprintf("登录扩展 %s 调用失败：%s", h.Name, err.Error()))
+		if h.FailOpen {
+			return nil
+		}
+		return errors.New("登录校验服务异常，请稍后再试")
+	}
+
+	if !result.Allow {
+		logger.Warn(fmt.Sprintf("登录扩展 %s 拒绝用户 %s 登录：%s", h.Name, ctx.Username, result.Message))
+		if result.Message == "" {
+			return errors.New("拒绝登录，请联系管理员")
+		}
+		return errors.New(result.Message)
+	}
+
+	return nil
+}
+
+// request 发送Webhook请求并解析响应
+func (l *loginHook) request(h *model.LoginHook, ctx *hook.Context) (*loginHookResult, error) {
+
+	body, err := json.Marshal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hook-Timestamp", timestamp)
+	if h.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(h.Secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		req.Header.Set("X-Hook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := &http.Client{Timeout: time.Duration(h.Timeout) * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("返回状态码 %d", resp.StatusCode)
+	}
+
+	result := &loginHookResult{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(result); err != nil {
+		return nil, fmt.Errorf("响应解析失败：%s", err.Error())
+	}
+
+	return result, nil
+}
+
+// newHookContext 生成登录扩展上下文
+func newHookContext(stage hook.Stage, authMethod string, user *model.AuthUser, clientIP string, params AuthorizeParam) *hook.Context {
+	ctx := &hook.Context{
+		Stage:       stage,
+		AuthMethod:  authMethod,
+		Username:    user.Username,
+		Name:        user.Name,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		UserFrom:    user.UserFrom,
+		ClientIP:    clientIP,
+	}
+	if params != nil {
+		for _, application := range []string{params.GetClientId(), params.GetService(), params.GetWtrealm(), params.GetNginxRedirectURI()} {
+			if application != "" {
+				ctx.Application = application
+				break
+			}
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"html"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/i18n"
+	"ops-api/utils/mail"
+	"time"
+)
+
+// 登录提醒：用户在未登录过的设备或国家/地区登录成功后，邮件通知用户确认是否为本人操作（用户首次登录不通知）；
+// 邮件中包含“保护我的账号”链接，非本人操作时用户点击链接即可注销所有登录会话及离线访问会话，并在下次登录时强制修改密码。
+// 同一用户同一IP在通知间隔内仅通知一次，避免新设备及新国家/地区同时触发时重复通知
+
+const (
+	loginNoticeInterval      = 10 * time.Minute // 同一用户同一IP的通知间隔
+	secureAccountTokenTTL    = 24 * time.Hour   // 保护账号链接有效期
+	loginNoticeKeyFmt        = "login_notice:%d:%s"
+	secureAccountTokenKeyFmt = "secure_account_token:%s"
+)
+
+// RecordCountry 登录成功后记录登录的国家/地区，新国家/地区登录时通知用户；内网地址或GeoIP数据库不可用时不记录
+func (d *device) RecordCountry(user *model.AuthUser, userAgent, clientIP string) error {
+
+	country, _ := utils.LookupCountry(clientIP)
+	if country == "" {
+		return nil
+	}
+
+	now := time.Now()
+
+	// 已登录过的国家/地区仅更新最后登录时间
+	loginCountry, err := dao.Device.GetUserCountry(user.ID, country)
+	if err == nil {
+		return dao.Device.TouchUserCountry(loginCountry.ID, now)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	total, err := dao.Device.CountUserCountries(user.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := dao.Device.AddUserCountry(&model.UserLoginCountry{
+		UserID:      user.ID,
+		Country:     country,
+		FirstIP:     clientIP,
+		FirstSeenAt: now,
+		LastSeenAt:  now,
+	}); err != nil {
+		return err
+	}
+
+	if total > 0 {
+		info := &DeviceInfo{Browser: parseBrowser(userAgent), Platform: parsePlatform(userAgent)}
+		d.notify(user, "new_device.body_country", info.Name(), clientIP, country, now)
+	}
+
+	return nil
+}
+
+// notify 发送登录提醒邮件，不影响用户登录
+func (d *device) notify(user *model.AuthUser, bodyKey, deviceName, clientIP, country string, loginTime time.Time) {
+
+	if user.Email == "" {
+		return
+	}
+
+	if ok, err := global.Cache.SetNX(fmt.Sprintf(loginNoticeKeyFmt, user.ID, clientIP), "1", loginNoticeInterval); err == nil && !ok {
+		return
+	}
+
+	token := utils.GenerateRandomString(32)
+	if err := global.Cache.Set(fmt.Sprintf(secureAccountTokenKeyFmt, token), user.Username, secureAccountTokenTTL); err != nil {
+		logger.Error(fmt.Sprintf("登录提醒发送失败（%s）：%s", user.Username, err.Error()))
+		return
+	}
+
+	go func() {
+		locale := userLocale(user)
+		htmlBody := loginNoticeHTML(locale, bodyKey, user.Name, deviceName, clientIP, country,
+			utils.FormatTimeIn(loginTime, userLocation(user)), secureAccountURL(token))
+		if err := mail.Email.SendMsg([]string{user.Email}, nil, nil, i18n.T(locale, "new_device.subject"), htmlBody, "html"); err != nil {
+			logger.Error(fmt.Sprintf("登录提醒发送失败（%s）：%s", user.Username, err.Error()))
+		}
+	}()
+}
+
+// SecureAccount 用户通过登录提醒邮件中的链接保护账号：注销所有登录会话及离线访问会话，并要求下次登录时修改密码，链接仅能使用一次
+func (d *device) SecureAccount(token, clientIP string) error {
+
+	if token == "" {
+		return errors.New("链接无效或已过期")
+	}
+
+	tokenKey := fmt.Sprintf(secureAccountTokenKeyFmt, token)
+	username, err := global.Cache.Get(tokenKey)
+	if err != nil || username == "" {
+		return errors.New("链接无效或已过期")
+	}
+	global.Cache.Del(tokenKey)
+
+	user, err := dao.User.GetUser(map[string]interface{}{"username": username})
+	if err != nil {
+		return err
+	}
+
+	if err := Session.RevokeUser(user.ID); err != nil {
+		return err
+	}
+	if err := RequiredAction.Add(user, RequiredActionChangePassword); err != nil {
+		return err
+	}
+
+	logger.Warn(fmt.Sprintf("用户%s通过登录提醒保护账号，已注销所有会话并要求修改密码，操作IP：%s", user.Username, clientIP))
+	SecurityEvent.Publish(SecurityEventAccountSecured, user.Username, user.Username, fmt.Sprintf("用户确认登录非本人操作，已注销所有会话并要求下次登录时修改密码，操作IP：%s", clientIP))
+
+	return nil
+}
+
+// secureAccountURL 保护账号页面地址，由前端页面确认后调用保护账号接口，避免邮件安全网关预取链接时误触发
+func secureAccountURL(token string) string {
+	url := config.GetString("externalUrl")
+	if url != "" && url[len(url)-1] != '/' {
+		url += "/"
+	}
+	return url + "secure_account?token=" + token
+}
+
+// loginNoticeHTML 登录提醒正文
+func loginNoticeHTML(locale, bodyKey, name, deviceName, clientIP, country, loginTime, secureURL string) string {
+
+	issuer := config.GetString("issuer")
+
+	var location string
+	if country != "" {
+		location = "<br>" + i18n.T(locale, "new_device.location", html.EscapeString(country))
+	}
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html lang="%s">
+		<head>
+			<meta charset="UTF-8">
+			<title>%s</title>
+		</head>
+		<body>
+			<p>%s</p>
+			<p>%s</p>
+			<p>%s<br>%s<br>%s%s</p>
+			<p>%s</p>
+			<br>
+			<p>%s</p>
+			<p style="color: red">%s</p>
+		</body>
+		</html>
+	`, locale,
+		i18n.T(locale, "new_device.subject"),
+		i18n.T(locale, "new_device.greeting", html.EscapeString(name)),
+		i18n.T(locale, bodyKey),
+		i18n.T(locale, "new_device.time", loginTime),
+		i18n.T(locale, "new_device.device", html.EscapeString(deviceName)),
+		i18n.T(locale, "new_device.ip", html.EscapeString(clientIP)),
+		location,
+		i18n.T(locale, "new_device.action", html.EscapeString(secureURL)),
+		i18n.T(locale, "mail.signature", html.EscapeString(issuer)),
+		i18n.T(locale, "mail.no_reply"))
+}
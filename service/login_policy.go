@@ -0,0 +1,637 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"net"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var LoginPolicy loginPolicy
+
+type loginPolicy struct{}
+
+const (
+	LoginPolicyActionDeny = 1 // 拒绝登录
+	LoginPolicyActionMFA  = 2 // 要求MFA认证
+)
+
+// LoginPolicyCreate 创建登录策略结构体
+type LoginPolicyCreate struct {
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	Countries    string `json:"countries"`
+	IPRanges     string `json:"ip_ranges"`
+	Action       uint   `json:"action" binding:"required,oneof=1 2"`
+	Enabled      *bool  `json:"enabled" binding:"required"`
+	ExemptUsers  []uint `json:"exempt_users"`
+	ExemptGroups []uint `json:"exempt_groups"`
+}
+
+// LoginPolicyUpdate 更新登录策略结构体
+type LoginPolicyUpdate struct {
+	dao.LoginPolicyUpdate
+	ExemptUsers  []uint `json:"exempt_users"`
+	ExemptGroups []uint `json:"exempt_groups"`
+}
+
+// LoginTimePolicyCreate 创建登录时间策略结构体
+type LoginTimePolicyCreate struct {
+	Name         string `json:"name" binding:"required"`
+	Description  string `json:"description"`
+	Weekdays     string `json:"weekdays"`
+	WorkdaysOnly *bool  `json:"workdays_only" binding:"required"`
+	StartTime    string `json:"start_time" binding:"required"`
+	EndTime      string `json:"end_time" binding:"required"`
+	Enabled      *bool  `json:"enabled" binding:"required"`
+	Groups       []uint `json:"groups"`
+}
+
+// LoginTimePolicyUpdate 更新登录时间策略结构体
+type LoginTimePolicyUpdate struct {
+	dao.LoginTimePolicyUpdate
+	Groups []uint `json:"groups"`
+}
+
+// HolidaySave 批量保存节假日结构体
+type HolidaySave struct {
+	Items []*HolidayItem `json:"items" binding:"required,dive"`
+}
+
+// HolidayItem 节假日
+type HolidayItem struct {
+	Date    string `json:"date" binding:"required"` // 格式：YYYY-MM-DD
+	Name    string `json:"name"`
+	Workday bool   `json:"workday"` // 是否为调休工作日
+}
+
+// weekdayNames 星期名称
+var weekdayNames = []string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"}
+
+// GetLoginPolicyList 获取登录策略列表（表格）
+func (l *loginPolicy) GetLoginPolicyList(name string, page, limit int) (data *dao.LoginPolicyList, err error) {
+	return dao.LoginPolicy.GetLoginPolicyList(name, page, limit)
+}
+
+// AddLoginPolicy 创建登录策略
+func (l *loginPolicy) AddLoginPolicy(data *LoginPolicyCreate) (*model.LoginPolicy, error) {
+
+	countries, ipRanges, err := l.normalize(data.Countries, data.IPRanges)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &model.LoginPolicy{
+		Name:        data.Name,
+		Description: data.Description,
+		Countries:   countries,
+		IPRanges:    ipRanges,
+		Action:      data.Action,
+		Enabled:     *data.Enabled,
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.LoginPolicy.AddLoginPolicy(tx, policy)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 设置豁免用户及分组
+	if err := l.updateExemptions(tx, result, data.ExemptUsers, data.ExemptGroups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateLoginPolicy 更新登录策略
+func (l *loginPolicy) UpdateLoginPolicy(data *LoginPolicyUpdate) (*model.LoginPolicy, error) {
+
+	countries, ipRanges, err := l.normalize(data.Countries, data.IPRanges)
+	if err != nil {
+		return nil, err
+	}
+	data.Countries = countries
+	data.IPRanges = ipRanges
+
+	// 查询要修改的策略
+	policy, err := dao.LoginPolicy.GetLoginPolicy(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.LoginPolicy.UpdateLoginPolicy(tx, policy, &data.LoginPolicyUpdate)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 更新豁免用户及分组
+	if err := l.updateExemptions(tx, result, data.ExemptUsers, data.ExemptGroups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteLoginPolicy 删除登录策略
+func (l *loginPolicy) DeleteLoginPolicy(id int) error {
+
+	policy, err := dao.LoginPolicy.GetLoginPolicy(uint(id))
+	if err != nil {
+		return err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.LoginPolicy.DeleteLoginPolicy(tx, policy); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// CheckLoginPolicy 检查用户是否允许登录，命中拒绝策略或不在允许的登录时间内时返回错误，命中MFA策略时返回mfaRequired
+func (l *loginPolicy) CheckLoginPolicy(user *model.AuthUser, clientIP string) (mfaRequired bool, err error) {
+
+	// 用户所属分组
+	groupIds, err := dao.LoginPolicy.GetUserGroupIds(user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	// 登录时间检查
+	if err := l.checkLoginTime(user, groupIds, time.Now().In(config.Location())); err != nil {
+		return false, err
+	}
+
+	policies, err := dao.LoginPolicy.GetEnabledLoginPolicies()
+	if err != nil {
+		return false, err
+	}
+	if len(policies) == 0 {
+		return false, nil
+	}
+
+	// 查询客户端IP所属国家/地区，GeoIP数据库不可用时仅匹配IP地址段
+	country, err := utils.LookupCountry(clientIP)
+	if err != nil {
+		logger.Warn("客户端IP地区查询失败：" + err.Error())
+	}
+
+	for _, policy := range policies {
+		if !l.match(policy, country, clientIP) || l.isExempt(policy, user.ID, groupIds) {
+			continue
+		}
+
+		if policy.Action == LoginPolicyActionDeny {
+			logger.Warn(fmt.Sprintf("登录策略【%s】拒绝用户%s登录，客户端IP：%s，地区：%s", policy.Name, user.Username, clientIP, country))
+			return false, errors.New("当前网络环境不允许登录，请联系管理员")
+		}
+		if policy.Action == LoginPolicyActionMFA {
+			mfaRequired = true
+		}
+	}
+
+	return mfaRequired, nil
+}
+
+// checkLoginTime 检查当前时间是否允许用户登录，用户所属分组关联了多个时间策略时需要同时满足
+func (l *loginPolicy) checkLoginTime(user *model.AuthUser, groupIds []uint, now time.Time) error {
+
+	policies, err := dao.LoginPolicy.GetUserLoginTimePolicies(groupIds)
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range policies {
+		allowed, err := l.inTimeWindow(policy, now)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			logger.Warn(fmt.Sprintf("登录时间策略【%s】拒绝用户%s登录，当前时间：%s", policy.Name, user.Username, now.Format("2006-01-02 15:04")))
+			return fmt.Errorf("当前时间不允许登录，允许登录的时间为：%s", l.describe(policy))
+		}
+	}
+
+	return nil
+}
+
+// inTimeWindow 判断指定时间是否在策略允许的时间段内
+func (l *loginPolicy) inTimeWindow(policy *model.LoginTimePolicy, now time.Time) (bool, error) {
+	start, err := time.Parse("15:04", policy.StartTime)
+	if err != nil {
+		return false, err
+	}
+	end, err := time.Parse("15:04", policy.EndTime)
+	if err != nil {
+		return false, err
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+	startMinutes := start.Hour()*60 + start.Minute()
+	endMinutes := end.Hour()*60 + end.Minute()
+
+	// 当天时间段
+	if startMinutes < endMinutes {
+		if minutes < startMinutes || minutes >= endMinutes {
+			return false, nil
+		}
+		return l.isAllowedDay(policy, now)
+	}
+
+	// 跨天时间段，凌晨部分属于前一天的时间段
+	if minutes >= startMinutes {
+		return l.isAllowedDay(policy, now)
+	}
+	if minutes < endMinutes {
+		return l.isAllowedDay(policy, now.AddDate(0, 0, -1))
+	}
+
+	return false, nil
+}
+
+// isAllowedDay 判断指定日期是否允许登录
+func (l *loginPolicy) isAllowedDay(policy *model.LoginTimePolicy, date time.Time) (bool, error) {
+	if policy.WorkdaysOnly {
+		return l.IsWorkday(date)
+	}
+
+	weekdays := splitList(policy.Weekdays)
+	if len(weekdays) == 0 {
+		return true, nil
+	}
+	for _, weekday := range weekdays {
+		if weekday == strconv.Itoa(int(date.Weekday())) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// IsWorkday 判断指定日期是否为工作日，节假日日历中未配置的日期按周一至周五为工作日处理
+func (l *loginPolicy) IsWorkday(date time.Time) (bool, error) {
+	holiday, err := dao.LoginPolicy.GetHoliday(date.Format("2006-01-02"))
+	if err != nil {
+		return false, err
+	}
+	if holiday != nil {
+		return holiday.Workday, nil
+	}
+
+	return date.Weekday() != time.Saturday && date.Weekday() != time.Sunday, nil
+}
+
+// describe 策略允许登录时间的描述
+func (l *loginPolicy) describe(policy *model.LoginTimePolicy) string {
+	days := "每天"
+	if policy.WorkdaysOnly {
+		days = "工作日"
+	} else if weekdays := splitList(policy.Weekdays); len(weekdays) > 0 {
+		names := make([]string, 0, len(weekdays))
+		for _, weekday := range weekdays {
+			if index, err := strconv.Atoi(weekday); err == nil && index >= 0 && index < len(weekdayNames) {
+				names = append(names, weekdayNames[index])
+			}
+		}
+		days = strings.Join(names, "、")
+	}
+
+	return fmt.Sprintf("%s %s-%s", days, policy.StartTime, policy.EndTime)
+}
+
+// match 判断客户端是否命中策略
+func (l *loginPolicy) match(policy *model.LoginPolicy, country, clientIP string) bool {
+	if country != "" {
+		for _, code := range splitList(policy.Countries) {
+			if strings.EqualFold(code, country) {
+				return true
+			}
+		}
+	}
+
+	ip := net.ParseIP(clientIP)
+	if ip == nil {
+		return false
+	}
+	for _, ipRange := range splitList(policy.IPRanges) {
+		_, network, err := net.ParseCIDR(ipRange)
+		if err == nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isExempt 判断用户是否被豁免
+func (l *loginPolicy) isExempt(policy *model.LoginPolicy, userId uint, groupIds []uint) bool {
+	for _, user := range policy.ExemptUsers {
+		if user.ID == userId {
+			return true
+		}
+	}
+	for _, group := range policy.ExemptGroups {
+		for _, groupId := range groupIds {
+			if group.ID == groupId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalize 校验并格式化国家/地区代码及IP地址段
+func (l *loginPolicy) normalize(countries, ipRanges string) (string, string, error) {
+	codes := splitList(countries)
+	for i, code := range codes {
+		if len(code) != 2 {
+			return "", "", fmt.Errorf("国家/地区代码%s格式错误，请使用两位ISO 3166-1代码", code)
+		}
+		codes[i] = strings.ToUpper(code)
+	}
+
+	ranges := splitList(ipRanges)
+	for i, ipRange := range ranges {
+		// 单个IP地址转换为CIDR格式
+		if ip := net.ParseIP(ipRange); ip != nil {
+			if ip.To4() != nil {
+				ipRange += "/32"
+			} else {
+				ipRange += "/128"
+			}
+		}
+		if _, _, err := net.ParseCIDR(ipRange); err != nil {
+			return "", "", fmt.Errorf("IP地址段%s格式错误", ipRange)
+		}
+		ranges[i] = ipRange
+	}
+
+	if len(codes) == 0 && len(ranges) == 0 {
+		return "", "", errors.New("国家/地区和IP地址段不能同时为空")
+	}
+
+	return strings.Join(codes, ","), strings.Join(ranges, "\n"), nil
+}
+
+// updateExemptions 更新登录策略豁免的用户及分组
+func (l *loginPolicy) updateExemptions(tx *gorm.DB, policy *model.LoginPolicy, userIds, groupIds []uint) error {
+
+	var (
+		users  []model.AuthUser
+		groups []model.AuthGroup
+	)
+	if len(userIds) > 0 {
+		if err := tx.Where("id IN ?", userIds).Find(&users).Error; err != nil {
+			return err
+		}
+	}
+	if len(groupIds) > 0 {
+		if err := tx.Where("id IN ?", groupIds).Find(&groups).Error; err != nil {
+			return err
+		}
+	}
+
+	return dao.LoginPolicy.UpdateLoginPolicyExemptions(tx, policy, users, groups)
+}
+
+// GetLoginTimePolicyList 获取登录时间策略列表（表格）
+func (l *loginPolicy) GetLoginTimePolicyList(name string, page, limit int) (data *dao.LoginTimePolicyList, err error) {
+	return dao.LoginPolicy.GetLoginTimePolicyList(name, page, limit)
+}
+
+// AddLoginTimePolicy 创建登录时间策略
+func (l *loginPolicy) AddLoginTimePolicy(data *LoginTimePolicyCreate) (*model.LoginTimePolicy, error) {
+
+	weekdays, err := l.normalizeTimeWindow(data.Weekdays, data.StartTime, data.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &model.LoginTimePolicy{
+		Name:         data.Name,
+		Description:  data.Description,
+		Weekdays:     weekdays,
+		WorkdaysOnly: *data.WorkdaysOnly,
+		StartTime:    data.StartTime,
+		EndTime:      data.EndTime,
+		Enabled:      *data.Enabled,
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.LoginPolicy.AddLoginTimePolicy(tx, policy)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 设置适用的分组
+	if err := l.updateTimePolicyGroups(tx, result, data.Groups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateLoginTimePolicy 更新登录时间策略
+func (l *loginPolicy) UpdateLoginTimePolicy(data *LoginTimePolicyUpdate) (*model.LoginTimePolicy, error) {
+
+	weekdays, err := l.normalizeTimeWindow(data.Weekdays, data.StartTime, data.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	data.Weekdays = weekdays
+
+	// 查询要修改的策略
+	policy, err := dao.LoginPolicy.GetLoginTimePolicy(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.LoginPolicy.UpdateLoginTimePolicy(tx, policy, &data.LoginTimePolicyUpdate)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 更新适用的分组
+	if err := l.updateTimePolicyGroups(tx, result, data.Groups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteLoginTimePolicy 删除登录时间策略
+func (l *loginPolicy) DeleteLoginTimePolicy(id int) error {
+
+	policy, err := dao.LoginPolicy.GetLoginTimePolicy(uint(id))
+	if err != nil {
+		return err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.LoginPolicy.DeleteLoginTimePolicy(tx, policy); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// GetHolidayList 获取节假日日历
+func (l *loginPolicy) GetHolidayList(year string) ([]*model.Holiday, error) {
+	if year == "" {
+		year = strconv.Itoa(time.Now().Year())
+	}
+	return dao.LoginPolicy.GetHolidayList(year)
+}
+
+// SaveHolidays 批量保存节假日，用于导入国务院办公厅发布的节假日安排
+func (l *loginPolicy) SaveHolidays(data *HolidaySave) error {
+
+	holidays := make([]*model.Holiday, len(data.Items))
+	for i, item := range data.Items {
+		if _, err := time.Parse("2006-01-02", item.Date); err != nil {
+			return fmt.Errorf("日期%s格式错误，请使用YYYY-MM-DD格式", item.Date)
+		}
+		holidays[i] = &model.Holiday{
+			Date:    item.Date,
+			Name:    item.Name,
+			Workday: item.Workday,
+		}
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.LoginPolicy.SaveHolidays(tx, holidays); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// DeleteHoliday 删除节假日
+func (l *loginPolicy) DeleteHoliday(id int) error {
+	return dao.LoginPolicy.DeleteHoliday(uint(id))
+}
+
+// normalizeTimeWindow 校验登录时间段并格式化星期配置
+func (l *loginPolicy) normalizeTimeWindow(weekdays, startTime, endTime string) (string, error) {
+	if _, err := time.Parse("15:04", startTime); err != nil {
+		return "", errors.New("开始时间格式错误，请使用HH:MM格式")
+	}
+	if _, err := time.Parse("15:04", endTime); err != nil {
+		return "", errors.New("结束时间格式错误，请使用HH:MM格式")
+	}
+	if startTime == endTime {
+		return "", errors.New("开始时间和结束时间不能相同")
+	}
+
+	days := splitList(weekdays)
+	for _, day := range days {
+		if index, err := strconv.Atoi(day); err != nil || index < 0 || index > 6 {
+			return "", errors.New("星期配置错误，请使用0-6表示星期日至星期六")
+		}
+	}
+
+	return strings.Join(days, ","), nil
+}
+
+// updateTimePolicyGroups 更新登录时间策略适用的分组
+func (l *loginPolicy) updateTimePolicyGroups(tx *gorm.DB, policy *model.LoginTimePolicy, groupIds []uint) error {
+
+	var groups []model.AuthGroup
+	if len(groupIds) > 0 {
+		if err := tx.Where("id IN ?", groupIds).Find(&groups).Error; err != nil {
+			return err
+		}
+	}
+
+	return dao.LoginPolicy.UpdateLoginTimePolicyGroups(tx, policy, groups)
+}
+
+// splitList 拆分使用逗号或换行分隔的配置
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' || r == '\r' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
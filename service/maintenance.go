@@ -0,0 +1,87 @@
+package service
+
+import (
+	"errors"
+	"ops-api/middleware"
+	"strings"
+	"time"
+)
+
+var Maintenance maintenance
+
+type maintenance struct{}
+
+// MaintenanceUpdate 设置维护模式结构体
+type MaintenanceUpdate struct {
+	Enabled *bool  `json:"enabled" binding:"required"`
+	Message string `json:"message"` // 返回给用户的提示信息，为空时使用默认提示
+}
+
+// MaintenanceError 维护期间拒绝非管理员登录
+type MaintenanceError struct {
+	Message string
+}
+
+func (e *MaintenanceError) Error() string { return e.Message }
+
+// Get 获取维护模式状态
+func (m *maintenance) Get() *middleware.MaintenanceStatus {
+	return middleware.GetMaintenance()
+}
+
+// Update 开启或关闭维护模式
+func (m *maintenance) Update(data *MaintenanceUpdate, operator string) error {
+
+	status := &middleware.MaintenanceStatus{Enabled: *data.Enabled}
+	if status.Enabled {
+		now := time.Now()
+		status.Message = strings.TrimSpace(data.Message)
+		status.Operator = operator
+		status.StartedAt = &now
+	}
+
+	if err := middleware.SetMaintenance(status); err != nil {
+		return err
+	}
+
+	detail := "开启维护模式"
+	if !status.Enabled {
+		detail = "关闭维护模式"
+	}
+	SecurityEvent.Publish(SecurityEventMaintenanceChanged, operator, "-", detail)
+
+	return nil
+}
+
+// Admit 维护期间仅允许管理员登录，拒绝时注销已签发的Token
+func (m *maintenance) Admit(token string) error {
+
+	status := middleware.GetMaintenance()
+	if !status.Enabled {
+		return nil
+	}
+
+	mc, err := middleware.ParseToken(token)
+	if err != nil {
+		return err
+	}
+	if middleware.IsMaintenanceAdmin(mc.Username) {
+		return nil
+	}
+
+	if err := middleware.RevokeToken(token); err != nil {
+		return err
+	}
+	if mc.SessionID != "" {
+		if err := middleware.RevokeSession(mc.ID, mc.SessionID); err != nil {
+			return err
+		}
+	}
+	return &MaintenanceError{Message: status.Message}
+}
+
+// IsMaintenanceError 判断是否为维护期间拒绝登录的错误
+func IsMaintenanceError(err error) bool {
+	var target *MaintenanceError
+	return errors.As(err, &target)
+}
@@ -7,9 +7,12 @@ import (
 	"gorm.io/gorm"
 	"image/png"
 	"ops-api/config"
+	"ops-api/dao"
 	"ops-api/global"
 	"ops-api/middleware"
 	"ops-api/model"
+	"ops-api/utils/hook"
+	"ops-api/utils/i18n"
 )
 
 var MFA mfa
@@ -21,30 +24,41 @@ type MFAValidate struct {
 	Username         string `json:"username" binding:"required"`
 	Code             string `json:"code" binding:"required"`
 	Token            string `json:"token" binding:"required"`
-	ResponseType     string `json:"response_type"`      // OAuth2.0客户端：授权类型，固定值：code
+	ResponseType     string `json:"response_type"`      // OAuth2.0客户端：响应类型，如：code、id_token、code id_token
 	ClientId         string `json:"client_id"`          // OAuth2.0客户端：客户端ID
 	RedirectURI      string `json:"redirect_uri"`       // OAuth2.0客户端：重定向URL
 	State            string `json:"state"`              // OAuth2.0客户端：客户端状态码
 	Scope            string `json:"scope"`              // OAuth2.0客户端：申请权限范围
+	AcrValues        string `json:"acr_values"`         // OIDC客户端：要求的认证等级
+	Resource         string `json:"resource"`           // OAuth2.0客户端：申请访问的资源（RFC 8707）
+	Audience         string `json:"audience"`           // OAuth2.0客户端：申请访问的资源，与resource作用相同
 	Service          string `json:"service"`            // CAS3.0客户端：回调地址
 	SAMLRequest      string `json:"SAMLRequest"`        // SAML2客户端：SAMLRequest
 	RelayState       string `json:"RelayState"`         // SAML2客户端：客户端状态码
 	SigAlg           string `json:"SigAlg"`             // SAML2客户端：签名算法
 	Signature        string `json:"Signature"`          // SAML2客户端：签名
 	NginxRedirectURI string `json:"nginx_redirect_uri"` // Nginx代理客户端：回调地址
+	Wtrealm          string `json:"wtrealm"`            // WS-Fed客户端：RP标识
+	Wreply           string `json:"wreply"`             // WS-Fed客户端：RP回调地址
+	Wctx             string `json:"wctx"`               // WS-Fed客户端：RP状态信息
+}
+
+// MFAStepUp 升级认证请求参数
+type MFAStepUp struct {
+	Code string `json:"code" binding:"required"`
 }
 
 // GetGoogleQrcode 生成Google MFA认证二维码
 func (m *mfa) GetGoogleQrcode(token string) (image []byte, err error) {
 
 	// 获取登录用户名
-	username, err := global.RedisClient.Get(token).Result()
+	username, err := global.Cache.Get(token)
 	if err != nil {
 		return nil, err
 	}
 
 	// 创建TOTP
-	issuer := config.Conf.Settings["issuer"].(string)
+	issuer := config.GetString("issuer")
 	key, err := totp.Generate(totp.GenerateOpts{
 		Issuer:      issuer,
 		AccountName: username,
@@ -64,7 +78,7 @@ func (m *mfa) GetGoogleQrcode(token string) (image []byte, err error) {
 	}
 
 	// 将mfaSecret更新至缓存，如果用户MFA检验成功则将mfaSecret与用户进行绑定
-	if err := global.RedisClient.Set(token, mfaSecret, 0).Err(); err != nil {
+	if err := global.Cache.Set(token, mfaSecret, 0); err != nil {
 		return nil, err
 	}
 
@@ -72,7 +86,8 @@ func (m *mfa) GetGoogleQrcode(token string) (image []byte, err error) {
 }
 
 // GoogleQrcodeValidate Google MFA认证校验
-func (m *mfa) GoogleQrcodeValidate(params *MFAValidate) (jwtToken, redirectUri, application string, err error) {
+// 用户还需要完成首次登录操作时返回临时Token及对应的页面
+func (m *mfa) GoogleQrcodeValidate(params *MFAValidate, clientIP string) (jwtToken, redirectUri, application string, nextPage *string, err error) {
 
 	var (
 		user   model.AuthUser
@@ -82,62 +97,145 @@ func (m *mfa) GoogleQrcodeValidate(params *MFAValidate) (jwtToken, redirectUri,
 	// 获取登录用户信息
 	tx := global.MySQLClient.First(&user, "username = ?", params.Username)
 	if errors.Is(tx.Error, gorm.ErrRecordNotFound) {
-		return "", "", "", errors.New("用户不存在")
+		return "", "", "", nil, errors.New("用户不存在")
 	}
 
 	// 获取Secret，如果用户还没有绑定MFA，则从Redis中获取Secret
 	if user.MFACode == nil {
-		srt, err := global.RedisClient.Get(params.Token).Result()
+		srt, err := global.Cache.Get(params.Token)
 		if err != nil {
-			return "", "", "", err
+			return "", "", "", nil, err
 		}
 		secret = srt
 	} else {
 		secret = *user.MFACode
 	}
 
+	// 应急账号再次检查来源网络及使用时间窗口，并确认已通过账号密码认证
+	if user.BreakGlass {
+		if !user.IsActive {
+			return "", "", "", nil, errors.New("拒绝登录，请联系管理员")
+		}
+		if err := BreakGlass.Check(&user, clientIP); err != nil {
+			return "", "", "", nil, err
+		}
+		if username, err := global.Cache.Get(params.Token); err != nil || username != user.Username {
+			return "", "", "", nil, errors.New("认证已过期，请重新登录")
+		}
+	}
+
 	// 校验MFA
 	valid := totp.Validate(params.Code, secret)
 	if !valid {
-		return "", "", "", errors.New("验证码错误")
+		return "", "", "", nil, errors.New(i18n.T(userLocale(&user), "mfa.invalid_code"))
 	}
 
-	// 生成用户Token
-	jwtToken, err = middleware.GenerateJWT(user.ID, user.Name, user.Username)
-	if err != nil {
-		return "", "", "", err
+	loginParams := &UserLogin{
+		ResponseType: params.ResponseType,
+		ClientId:     params.ClientId,
+		RedirectURI:  params.RedirectURI,
+		State:        params.State,
+		Scope:        params.Scope,
+		AcrValues:    params.AcrValues,
+		Resource:     params.Resource,
+		Audience:     params.Audience,
+		Service:      params.Service,
+		SAMLRequest:  params.SAMLRequest,
+		RelayState:   params.RelayState,
+		SigAlg:       params.SigAlg,
+		Signature:    params.Signature,
+		Wtrealm:      params.Wtrealm,
+		Wreply:       params.Wreply,
+		Wctx:         params.Wctx,
 	}
 
 	// 更新用户MFA绑定信息
 	if user.MFACode == nil {
 		user.MFACode = &secret
 		if err := tx.Save(&user).Error; err != nil {
-			return "", "", "", err
+			return "", "", "", nil, err
 		}
 	}
 
-	// 处理单点登录请求
-	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" {
-		loginParams := &UserLogin{
-			ResponseType: params.ResponseType,
-			ClientId:     params.ClientId,
-			RedirectURI:  params.RedirectURI,
-			State:        params.State,
-			Scope:        params.Scope,
-			Service:      params.Service,
-			SAMLRequest:  params.SAMLRequest,
-			RelayState:   params.RelayState,
-			SigAlg:       params.SigAlg,
-			Signature:    params.Signature,
+	// MFA认证通过后执行首次登录需要完成的操作（应急账号不执行）
+	if !user.BreakGlass && RequiredAction.Required(&user) {
+		token, nextPage, err := RequiredAction.Begin(&user, true)
+		if err != nil {
+			return "", "", "", nil, err
 		}
+		return token, "", "", nextPage, nil
+	}
+
+	// 执行签发Token前的登录扩展（应急账号不执行）
+	if !user.BreakGlass {
+		if err := LoginHook.Run(newHookContext(hook.PreToken, "双因子", &user, clientIP, loginParams)); err != nil {
+			return "", "", "", nil, err
+		}
+	}
+
+	// 生成用户Token
+	jwtToken, sessionId, err := middleware.GenerateJWT(user.ID, user.Name, user.Username, []string{middleware.AMRPassword, middleware.AMROTP, middleware.AMRMultiFactor})
+	if err != nil {
+		return "", "", "", nil, err
+	}
+
+	// 应急账号登录告警
+	if user.BreakGlass {
+		BreakGlass.Activate(&user, clientIP)
+	}
 
-		callbackData, siteName, err := SSO.Login(loginParams, user)
+	// 处理单点登录请求
+	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" || params.Wtrealm != "" {
+		callbackData, siteName, err := SSO.Login(loginParams, user, sessionId)
+		if termsPending(err) {
+			// 需要先接受使用条款，返回用户Token，由前端跳转至条款页面
+			page := TermsAcceptPage
+			return jwtToken, "", siteName, &page, nil
+		}
+		if consentPending(err) {
+			// 需要先确认授权，返回用户Token，由前端跳转至授权确认页面
+			page := ConsentPage
+			return jwtToken, "", siteName, &page, nil
+		}
 		if err != nil {
-			return "", "", siteName, err
+			return "", "", siteName, nil, err
 		}
-		return jwtToken, callbackData, siteName, nil
+		return jwtToken, callbackData, siteName, nil, nil
+	}
+
+	return jwtToken, "", "", nil, nil
+
+}
+
+// StepUp 升级认证，已登录用户在执行敏感操作前（如客户端通过 acr_values 要求多因子认证）校验MFA，
+// 校验通过后当前会话升级为多因子认证，返回包含新认证方式的Token
+func (m *mfa) StepUp(token string, data *MFAStepUp) (string, error) {
+
+	mc, err := middleware.ValidateJWT(token)
+	if err != nil {
+		return "", err
+	}
+
+	user, err := dao.User.GetUser(map[string]interface{}{"id": mc.ID})
+	if err != nil {
+		return "", err
+	}
+	if user.MFACode == nil {
+		return "", errors.New(i18n.T(userLocale(user), "mfa.not_bound"))
+	}
+
+	// 校验MFA
+	if !totp.Validate(data.Code, *user.MFACode) {
+		return "", errors.New(i18n.T(userLocale(user), "mfa.invalid_code"))
+	}
+
+	stepUpToken, err := middleware.StepUpJWT(mc, middleware.AMROTP, middleware.AMRMultiFactor)
+	if err != nil {
+		return "", err
 	}
 
-	return jwtToken, "", "", nil
+	// 同步更新会话记录的过期时间
+	Session.Renew(stepUpToken)
 
+	return stepUpToken, nil
 }
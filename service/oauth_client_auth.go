@@ -0,0 +1,248 @@
+package service
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"net/http"
+	"net/url"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/middleware"
+	"ops-api/model"
+	"ops-api/utils"
+	"time"
+)
+
+// Token接口及设备授权接口的客户端认证（OIDC Core 9），站点未指定认证方式时允许 client_secret_basic 及 client_secret_post，
+// 指定后只能使用指定的认证方式；private_key_jwt 使用站点登记的公钥校验客户端签发的JWT（RFC 7523）
+
+const (
+	tokenAuthClientSecretBasic = "client_secret_basic"
+	tokenAuthPrivateKeyJWT     = "private_key_jwt"
+
+	clientAssertionTypeJWT     = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	clientAssertionKeyPrefix   = "client_assertion:" // 已使用的client_assertion（jti）Key前缀，用于防重放
+	clientAssertionMaxLifetime = time.Hour           // client_assertion的最长有效期
+)
+
+// clientAssertionAlgs private_key_jwt 支持的签名算法
+var clientAssertionAlgs = []string{"RS256", "RS384", "RS512", "PS256", "ES256"}
+
+// OAuthClientAuth 客户端认证参数，使用HTTP Basic认证时由 UseBasicAuth 填充
+type OAuthClientAuth struct {
+	ClientId            string `form:"client_id"`
+	ClientSecret        string `form:"client_secret"`
+	ClientAssertionType string `form:"client_assertion_type"` // private_key_jwt：固定为 urn:ietf:params:oauth:client-assertion-type:jwt-bearer
+	ClientAssertion     string `form:"client_assertion"`      // private_key_jwt：客户端使用私钥签发的JWT
+	basic               bool
+}
+
+// UseBasicAuth 使用HTTP Basic认证中的客户端凭据，用户名及密码需要先进行URL编码（RFC 6749 2.3.1），
+// 同一请求不能同时使用多种认证方式
+func (a *OAuthClientAuth) UseBasicAuth(username, password string) *OAuthError {
+
+	clientId, err := url.QueryUnescape(username)
+	if err != nil {
+		return NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Malformed client credentials")
+	}
+	clientSecret, err := url.QueryUnescape(password)
+	if err != nil {
+		return NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Malformed client credentials")
+	}
+
+	if a.ClientSecret != "" || a.ClientAssertion != "" {
+		return NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Multiple client authentication methods are not allowed")
+	}
+	if a.ClientId != "" && a.ClientId != clientId {
+		return NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "client_id does not match the HTTP Basic credentials")
+	}
+
+	a.ClientId, a.ClientSecret, a.basic = clientId, clientSecret, true
+	return nil
+}
+
+// method 获取请求使用的客户端认证方式
+func (a *OAuthClientAuth) method() string {
+	switch {
+	case a.basic:
+		return tokenAuthClientSecretBasic
+	case a.ClientAssertion != "" || a.ClientAssertionType != "":
+		return tokenAuthPrivateKeyJWT
+	case a.ClientSecret != "":
+		return tokenAuthClientSecretPost
+	}
+	return tokenAuthNone
+}
+
+// resolveClientId 使用private_key_jwt且未传递client_id时，从client_assertion的iss中获取ClientId（RFC 7523 3）
+func (a *OAuthClientAuth) resolveClientId() string {
+	if a.ClientId == "" && a.ClientAssertion != "" {
+		claims := &jwt.RegisteredClaims{}
+		if _, _, err := jwt.NewParser().ParseUnverified(a.ClientAssertion, claims); err == nil {
+			a.ClientId = claims.Issuer
+		}
+	}
+	return a.ClientId
+}
+
+// authenticateClient 获取并认证客户端，公共客户端无法保存凭据，使用PKCE代替客户端认证
+func authenticateClient(auth *OAuthClientAuth) (*model.Site, *OAuthError) {
+
+	site, err := dao.Site.GetOAuthSite(auth.resolveClientId())
+	if err != nil {
+		recordSSOError(SSOProtocolOAuth, nil, SSOErrorUnregistered, auth.ClientId)
+		return nil, NewOAuthError(http.StatusUnauthorized, OAuthInvalidClient, "Client authentication failed")
+	}
+	if site.PKCE == PKCEPublic {
+		return site, nil
+	}
+
+	method := auth.method()
+	allowed := method == tokenAuthClientSecretBasic || method == tokenAuthClientSecretPost
+	if site.TokenAuthMethod != "" {
+		allowed = method == site.TokenAuthMethod
+	}
+	if !allowed {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidClient, "不允许使用的客户端认证方式："+method)
+		return nil, NewOAuthError(http.StatusUnauthorized, OAuthInvalidClient, "Client authentication failed")
+	}
+
+	if method == tokenAuthPrivateKeyJWT {
+		if err := verifyClientAssertion(site, auth); err != nil {
+			recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidClient, "client_assertion校验失败："+err.Error())
+			return nil, NewOAuthError(http.StatusUnauthorized, OAuthInvalidClient, "Client authentication failed")
+		}
+		return site, nil
+	}
+
+	if site.ClientSecret == "" || subtle.ConstantTimeCompare([]byte(site.ClientSecret), []byte(auth.ClientSecret)) != 1 {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidClient, "client_secret错误")
+		return nil, NewOAuthError(http.StatusUnauthorized, OAuthInvalidClient, "Client authentication failed")
+	}
+	return site, nil
+}
+
+// verifyClientAssertion 校验client_assertion：iss及sub必须为ClientId，aud必须包含签发者或Token、设备授权端点，
+// 必须携带exp及jti，有效期不超过1小时，同一jti在有效期内只能使用一次
+func verifyClientAssertion(site *model.Site, auth *OAuthClientAuth) error {
+
+	if auth.ClientAssertionType != clientAssertionTypeJWT {
+		return errors.New("不支持的client_assertion_type")
+	}
+
+	publicKey, err := parseClientPublicKey(site.JwtPublicKey)
+	if err != nil {
+		return err
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	if _, err := jwt.ParseWithClaims(auth.ClientAssertion, claims, func(token *jwt.Token) (interface{}, error) {
+		return publicKey, nil
+	},
+		jwt.WithValidMethods(clientAssertionAlgs),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuer(site.ClientId),
+		jwt.WithSubject(site.ClientId),
+		jwt.WithLeeway(30*time.Second),
+	); err != nil {
+		return err
+	}
+
+	audiences := []string{middleware.OIDCIssuer(), middleware.OIDCEndpoint("oidcTokenEndpoint"), middleware.OIDCEndpoint("oidcDeviceEndpoint")}
+	var audience bool
+	for _, item := range claims.Audience {
+		if utils.Contains(audiences, item) {
+			audience = true
+			break
+		}
+	}
+	if !audience {
+		return errors.New("aud不包含签发者或Token端点")
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl > clientAssertionMaxLifetime {
+		return errors.New("有效期超过1小时")
+	}
+	if claims.ID == "" {
+		return errors.New("缺少jti")
+	}
+
+	// 防重放：jti在有效期内只能使用一次
+	if ttl < time.Second {
+		ttl = time.Second
+	}
+	ok, err := global.Cache.SetNX(fmt.Sprintf("%s%s:%s", clientAssertionKeyPrefix, site.ClientId, claims.ID), "1", ttl)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("jti已被使用")
+	}
+
+	return nil
+}
+
+// parseClientPublicKey 解析站点登记的公钥，支持PEM格式的RSA、ECDSA公钥及X.509证书
+func parseClientPublicKey(value string) (interface{}, error) {
+
+	block, _ := pem.Decode([]byte(value))
+	if block == nil {
+		return nil, errors.New("未登记客户端公钥或公钥格式错误")
+	}
+
+	var publicKey interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.New("客户端证书格式错误")
+		}
+		publicKey = cert.PublicKey
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.New("客户端公钥格式错误")
+		}
+		publicKey = key
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, errors.New("客户端公钥格式错误")
+		}
+		publicKey = key
+	default:
+		return nil, errors.New("客户端公钥格式错误，仅支持PEM格式的公钥或证书")
+	}
+
+	switch publicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+		return publicKey, nil
+	}
+	return nil, errors.New("客户端公钥仅支持RSA及ECDSA")
+}
+
+// validateTokenAuthMethod 校验站点的客户端认证方式，private_key_jwt 需要登记客户端公钥
+func validateTokenAuthMethod(method, publicKey string) error {
+	switch method {
+	case "", tokenAuthClientSecretBasic, tokenAuthClientSecretPost:
+	case tokenAuthPrivateKeyJWT:
+		if publicKey == "" {
+			return errors.New("使用private_key_jwt认证时需要登记客户端公钥")
+		}
+	default:
+		return errors.New("客户端认证方式仅支持为空、client_secret_basic、client_secret_post、private_key_jwt")
+	}
+	if publicKey != "" {
+		if _, err := parseClientPublicKey(publicKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"math/big"
+	"net/http"
+	"net/url"
+	"ops-api/dao"
+	"ops-api/middleware"
+	"ops-api/model"
+	"strings"
+	"time"
+)
+
+// OAuthDeviceCodeGrantType 设备授权（RFC 8628）的授权类型
+const OAuthDeviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+
+const (
+	oauthDeviceCodeTTL      = 10 * time.Minute       // 设备授权票据有效期
+	oauthDevicePollInterval = 5                      // 设备轮询Token接口的最小间隔（秒）
+	oauthUserCodeCharset    = "BCDFGHJKLMNPQRSTVWXZ" // user_code字符集，不包含元音字母及易混淆的字符（RFC 8628 6.1）
+	oauthUserCodeLength     = 8
+)
+
+// 设备授权票据状态
+const (
+	deviceCodePending  = "pending"
+	deviceCodeApproved = "approved"
+	deviceCodeDenied   = "denied"
+	deviceCodeConsumed = "consumed"
+)
+
+// DeviceAuthorization 设备授权请求参数
+type DeviceAuthorization struct {
+	OAuthClientAuth
+	Scope string `form:"scope"`
+	// 资源指示器（RFC 8707）：申请访问的资源，可以传递多次
+	Resource []string `form:"resource"`
+	Audience string   `form:"audience"`
+}
+
+// ResponseDeviceAuthorization 返回给设备的授权信息，设备展示user_code及验证地址（或二维码）后使用device_code轮询Token接口
+type ResponseDeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// DeviceVerification 用户确认设备授权请求参数
+type DeviceVerification struct {
+	UserCode string `json:"user_code" binding:"required"`
+	Approve  bool   `json:"approve"` // true：允许，false：拒绝
+}
+
+// DeviceVerificationInfo 返回给验证页面的设备授权信息，用于用户确认前展示申请授权的应用及Scope
+type DeviceVerificationInfo struct {
+	UserCode  string    `json:"user_code"`
+	SiteName  string    `json:"site_name"`
+	ClientId  string    `json:"client_id"`
+	Scope     string    `json:"scope"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// normalizeUserCode 格式化用户输入的user_code，忽略大小写、空格及分隔符
+func normalizeUserCode(userCode string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToUpper(userCode) {
+		if strings.ContainsRune(oauthUserCodeCharset, r) {
+			builder.WriteRune(r)
+		}
+	}
+	code := builder.String()
+	if len(code) != oauthUserCodeLength {
+		return ""
+	}
+	return code[:oauthUserCodeLength/2] + "-" + code[oauthUserCodeLength/2:]
+}
+
+// newUserCode 生成随机user_code，格式为：XXXX-XXXX
+func newUserCode() (string, error) {
+	code := make([]byte, oauthUserCodeLength)
+	max := big.NewInt(int64(len(oauthUserCodeCharset)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = oauthUserCodeCharset[n.Int64()]
+	}
+	return normalizeUserCode(string(code)), nil
+}
+
+// DeviceAuthorize 设备申请授权，返回device_code及user_code，失败时返回 *OAuthError
+func (s *sso) DeviceAuthorize(param *DeviceAuthorization) (*ResponseDeviceAuthorization, error) {
+
+	// 客户端验证，公共客户端（如命令行工具）无法保存ClientSecret，不校验ClientSecret
+	site, authErr := authenticateClient(&param.OAuthClientAuth)
+	if authErr != nil {
+		return nil, authErr
+	}
+
+	// 应用使用的单点登录协议已停用时不再受理设备授权
+	if err := checkProtocolSunset(SSOProtocolOAuth, site); err != nil {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthUnauthorizedClient, "The OAuth2.0 protocol has been retired for this client")
+	}
+	if !siteAllowsGrantType(site, OAuthDeviceCodeGrantType) {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "应用不允许使用的grant_type："+OAuthDeviceCodeGrantType)
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthUnauthorizedClient, "The client is not allowed to use the device authorization grant")
+	}
+
+	// 判断申请的Scope
+	scope, err := grantedScope(site, param.Scope)
+	if err != nil {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "应用不允许申请的scope："+param.Scope)
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidScope, err.Error())
+	}
+
+	// 判断申请的资源
+	resources, oauthErr := grantedResources(site, requestedResources(param.Resource, param.Audience))
+	if oauthErr != nil {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "应用不允许申请的资源："+strings.Join(requestedResources(param.Resource, param.Audience), " "))
+		return nil, oauthErr
+	}
+
+	deviceCode, err := newRefreshToken()
+	if err != nil {
+		logger.Error("生成device_code失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+	userCode, err := newUserCode()
+	if err != nil {
+		logger.Error("生成user_code失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	now := time.Now()
+	if err := dao.SSO.CreateDeviceCode(&model.SsoOAuthDeviceCode{
+		DeviceCodeHash: hashRefreshToken(deviceCode),
+		UserCode:       userCode,
+		ClientID:       site.ClientId,
+		Scope:          scope,
+		Resource:       resources,
+		Status:         deviceCodePending,
+		Interval:       oauthDevicePollInterval,
+		CreatedAt:      now,
+		ExpiresAt:      now.Add(oauthDeviceCodeTTL),
+	}); err != nil {
+		logger.Error("保存设备授权票据失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	verificationURI := middleware.OIDCEndpoint("oidcVerificationUri")
+	separator := "?"
+	if strings.Contains(verificationURI, "?") {
+		separator = "&"
+	}
+
+	return &ResponseDeviceAuthorization{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + separator + "user_code=" + url.QueryEscape(userCode),
+		ExpiresIn:               int(oauthDeviceCodeTTL.Seconds()),
+		Interval:                oauthDevicePollInterval,
+	}, nil
+}
+
+// getPendingDeviceCode 获取等待用户确认的设备授权票据及申请授权的应用
+func (s *sso) getPendingDeviceCode(userCode string) (*model.SsoOAuthDeviceCode, *model.Site, error) {
+
+	code := normalizeUserCode(userCode)
+	if code == "" {
+		return nil, nil, errors.New("用户码格式错误")
+	}
+
+	ticket, err := dao.SSO.GetPendingDeviceCode(code)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("用户码无效或已过期")
+		}
+		return nil, nil, err
+	}
+
+	site, err := dao.Site.GetOAuthSite(ticket.ClientID)
+	if err != nil {
+		return nil, nil, errors.New("申请授权的应用不存在")
+	}
+
+	return ticket, site, nil
+}
+
+// GetDeviceVerification 获取用户码对应的设备授权信息
+func (s *sso) GetDeviceVerification(userCode string) (*DeviceVerificationInfo, error) {
+
+	ticket, site, err := s.getPendingDeviceCode(userCode)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeviceVerificationInfo{
+		UserCode:  ticket.UserCode,
+		SiteName:  site.Name,
+		ClientId:  site.ClientId,
+		Scope:     ticket.Scope,
+		ExpiresAt: ticket.ExpiresAt,
+	}, nil
+}
+
+// VerifyDevice 用户确认或拒绝设备授权，用户无权访问应用时拒绝授权并返回错误
+func (s *sso) VerifyDevice(data *DeviceVerification, userId uint, sessionId string) (siteName string, err error) {
+
+	ticket, site, err := s.getPendingDeviceCode(data.UserCode)
+	if err != nil {
+		return "", err
+	}
+
+	status := deviceCodeApproved
+	if !data.Approve {
+		status = deviceCodeDenied
+	}
+
+	// 判断用户是否有权限访问
+	if !site.AllOpen && !dao.Site.IsUserInSite(userId, site) {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorAccessDenied, fmt.Sprintf("用户ID：%d", userId))
+		if _, err := dao.SSO.ConfirmDeviceCode(ticket.ID, deviceCodeDenied, userId, sessionId); err != nil {
+			logger.Error("保存设备授权状态失败：" + err.Error())
+		}
+		return site.Name, errors.New("您无权访问该应用")
+	}
+
+	// 确认授权前需要先接受使用条款
+	if data.Approve {
+		if err := Terms.Check(site, userId); err != nil {
+			return site.Name, err
+		}
+	}
+
+	confirmed, err := dao.SSO.ConfirmDeviceCode(ticket.ID, status, userId, sessionId)
+	if err != nil {
+		return site.Name, err
+	}
+	if !confirmed {
+		return site.Name, errors.New("用户码无效或已过期")
+	}
+
+	// 通知应用用户已登录
+	if data.Approve {
+		SiteLaunchHook.Publish(site, SSOProtocolOAuth, userId)
+	}
+
+	return site.Name, nil
+}
+
+// deviceCodeToken 设备使用device_code获取Token（grant_type=urn:ietf:params:oauth:grant-type:device_code），
+// 用户确认前返回authorization_pending，轮询过快时返回slow_down并增加轮询间隔，失败时返回 *OAuthError
+func (s *sso) deviceCodeToken(site *model.Site, param *Token) (*ResponseToken, error) {
+
+	if param.DeviceCode == "" {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Missing required parameter: device_code")
+	}
+
+	invalidGrant := NewOAuthError(http.StatusBadRequest, OAuthInvalidGrant, "The device code is invalid or already used")
+
+	ticket, err := dao.SSO.GetDeviceCode(hashRefreshToken(param.DeviceCode))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("获取设备授权票据失败：" + err.Error())
+			return nil, NewOAuthServerError()
+		}
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorExpiredCode, "device_code不存在")
+		return nil, invalidGrant
+	}
+	if ticket.ClientID != site.ClientId {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "device_code不属于该应用")
+		return nil, invalidGrant
+	}
+
+	now := time.Now()
+	if ticket.ExpiresAt.Before(now) {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthExpiredToken, "The device code has expired")
+	}
+
+	// 轮询间隔小于要求的间隔时增加轮询间隔（RFC 8628 3.5）
+	interval := ticket.Interval
+	slowDown := ticket.LastPolledAt != nil && now.Sub(*ticket.LastPolledAt) < time.Duration(interval)*time.Second
+	if slowDown {
+		interval += oauthDevicePollInterval
+	}
+	if err := dao.SSO.PollDeviceCode(ticket.ID, now, interval); err != nil {
+		logger.Error("保存设备轮询时间失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	switch ticket.Status {
+	case deviceCodePending:
+		if slowDown {
+			return nil, NewOAuthError(http.StatusBadRequest, OAuthSlowDown, "Polling too frequently, increase the interval")
+		}
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthAuthorizationPending, "The user has not yet completed the authorization")
+	case deviceCodeDenied:
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthAccessDenied, "The user denied the authorization request")
+	case deviceCodeApproved:
+	default:
+		return nil, invalidGrant
+	}
+
+	// Access Token的资源只能在授权时授予的资源范围内缩小
+	resources, oauthErr := narrowResources(site, ticket.Resource, requestedResources(param.Resource, param.Audience))
+	if oauthErr != nil {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "申请的资源超出授权范围")
+		return nil, oauthErr
+	}
+
+	consumed, err := dao.SSO.ConsumeDeviceCode(ticket.ID)
+	if err != nil {
+		logger.Error("使用设备授权票据失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+	if !consumed {
+		return nil, invalidGrant
+	}
+
+	user, err := dao.User.GetUserInfo(ticket.UserID)
+	if err != nil {
+		logger.Error("获取用户信息失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+	idToken, accessToken, err := s.signOAuthTokens(site, user, ticket.UserID, ticket.Scope, resources, "", ticket.SessionID)
+	if err != nil {
+		logger.Error("生成Token失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	// 应用允许使用refresh_token时签发刷新令牌，授予了offline_access时绑定登录设备
+	refreshToken, err := s.issueRefreshToken(site, ticket.UserID, ticket.SessionID, ticket.Scope, ticket.Resource)
+	if err != nil {
+		logger.Error("生成刷新令牌失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	return &ResponseToken{
+		IdToken:      idToken,
+		AccessToken:  accessToken,
+		TokenType:    "bearer",
+		ExpiresIn:    int(accessTokenTTL(site).Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        ticket.Scope,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// OAuth2.0/OIDC协议错误码（RFC 6749 4.1.2.1、5.2，RFC 6750 3.1，RFC 8628 3.5，RFC 7591 3.2.2，OpenID Connect Core 3.1.2.6）
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthAccessDenied            = "access_denied"
+	OAuthServerError             = "server_error"
+	OAuthInvalidToken            = "invalid_token"
+	OAuthLoginRequired           = "login_required"
+	OAuthInteractionRequired     = "interaction_required"
+	OAuthUnmetAuthentication     = "unmet_authentication_requirements"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthAuthorizationPending    = "authorization_pending" // 设备授权（RFC 8628 3.5）
+	OAuthSlowDown                = "slow_down"
+	OAuthExpiredToken            = "expired_token"
+	OAuthInvalidRedirectURI      = "invalid_redirect_uri" // 动态客户端注册（RFC 7591 3.2.2）
+	OAuthInvalidClientMetadata   = "invalid_client_metadata"
+	OAuthInvalidTarget           = "invalid_target" // 资源指示器（RFC 8707 2）
+)
+
+// OAuthError OAuth2.0/OIDC协议错误信息，error_description 按协议要求只能包含ASCII字符
+type OAuthError struct {
+	ErrorCode   string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+	State       string `json:"state,omitempty"`
+	code        int
+	redirectURI string
+	fragment    bool // 错误信息通过回调地址的fragment返回（隐式及混合流程）
+}
+
+func (e *OAuthError) Error() string { return e.Description }
+
+// StatusCode 返回HTTP状态码
+func (e *OAuthError) StatusCode() int { return e.code }
+
+// RedirectURI 返回携带错误信息的客户端回调地址，客户端未通过校验时返回空字符串（此时不能重定向到客户端）
+func (e *OAuthError) RedirectURI() string {
+	if e.redirectURI == "" {
+		return ""
+	}
+	u, err := url.Parse(e.redirectURI)
+	if err != nil {
+		return ""
+	}
+	query := url.Values{}
+	if !e.fragment {
+		query = u.Query()
+	}
+	query.Set("error", e.ErrorCode)
+	if e.Description != "" {
+		query.Set("error_description", e.Description)
+	}
+	if e.State != "" {
+		query.Set("state", e.State)
+	}
+	if e.fragment {
+		u.Fragment = ""
+		return u.String() + "#" + query.Encode()
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// WithState 设置客户端传入的state，错误响应需原样返回
+func (e *OAuthError) WithState(state string) *OAuthError {
+	e.State = state
+	return e
+}
+
+// WithRedirect 设置客户端回调地址，仅在客户端及回调地址校验通过后设置
+func (e *OAuthError) WithRedirect(redirectURI string) *OAuthError {
+	e.redirectURI = redirectURI
+	return e
+}
+
+// WithFragment 设置错误信息是否通过回调地址的fragment返回
+func (e *OAuthError) WithFragment(fragment bool) *OAuthError {
+	e.fragment = fragment
+	return e
+}
+
+// NewOAuthError 创建OAuth2.0协议错误
+func NewOAuthError(code int, errorCode, description string) *OAuthError {
+	return &OAuthError{
+		ErrorCode:   errorCode,
+		Description: description,
+		code:        code,
+	}
+}
+
+// NewOAuthServerError 服务端内部错误，不向客户端暴露具体原因
+func NewOAuthServerError() *OAuthError {
+	return NewOAuthError(http.StatusInternalServerError, OAuthServerError, "The authorization server encountered an unexpected condition")
+}
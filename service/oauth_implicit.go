@@ -0,0 +1,57 @@
+package service
+
+import (
+	"net/url"
+	"ops-api/dao"
+	"ops-api/middleware"
+	"ops-api/model"
+	"strconv"
+)
+
+// responseTypeIncludes 判断响应类型中是否包含指定的值，如：code id_token 包含 id_token
+func responseTypeIncludes(responseType, value string) bool {
+	return scopeGranted(responseType, value)
+}
+
+// fragmentRedirect 隐式及混合流程（OpenID Connect Core 3.2、3.3）：在授权端点直接签发ID Token（及Access Token），
+// 通过回调地址的fragment返回；混合流程同时返回授权码，ID Token中包含c_hash，签发Access Token时包含at_hash；不签发刷新令牌
+func (s *sso) fragmentRedirect(site *model.Site, data *OAuthAuthorize, responseType, scope, resources, code string, userId uint, sessionId string) (string, error) {
+
+	user, err := dao.User.GetUserInfo(userId)
+	if err != nil {
+		return "", err
+	}
+	subject := oidcSubject(site, userId)
+
+	params := url.Values{}
+	var accessToken string
+	if responseTypeIncludes(responseType, "token") {
+		if accessToken, err = middleware.GenerateOAuthAccessToken(uint(user.ID), subject, site.ClientId, scope, sessionId, accessTokenAudience(site, resources), accessTokenTTL(site)); err != nil {
+			return "", err
+		}
+		params.Set("access_token", accessToken)
+		params.Set("token_type", "bearer")
+		params.Set("expires_in", strconv.Itoa(int(accessTokenTTL(site).Seconds())))
+		params.Set("scope", scope)
+	}
+	if responseTypeIncludes(responseType, "id_token") {
+		idToken, err := middleware.GenerateOAuthToken(uint(user.ID), user.Name, user.Username, subject, site.ClientId, "readwrite", data.Nonce, sessionId, accessToken, code, accessTokenTTL(site))
+		if err != nil {
+			return "", err
+		}
+		params.Set("id_token", idToken)
+	}
+	if code != "" {
+		params.Set("code", code)
+	}
+	if data.State != "" {
+		params.Set("state", data.State)
+	}
+
+	u, err := url.Parse(site.CallbackUrl)
+	if err != nil {
+		return "", err
+	}
+	u.Fragment = ""
+	return u.String() + "#" + params.Encode(), nil
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+	"ops-api/model"
+	"ops-api/utils"
+	"sort"
+	"strings"
+)
+
+// OAuth2.0支持的授权类型、响应类型及Scope，响应类型中的值按字母顺序排列
+var (
+	oauthSupportedGrantTypes    = []string{"authorization_code", "refresh_token", OAuthDeviceCodeGrantType}
+	oauthSupportedResponseTypes = []string{"code", "id_token", "id_token token", "code id_token"}
+	oauthSupportedScopes        = []string{"openid", "profile", "email", "phone", "offline_access"}
+)
+
+// oauthDefaultResponseTypes 站点未配置响应类型时允许使用的响应类型，隐式及混合流程需要在站点中单独开启
+var oauthDefaultResponseTypes = []string{"code"}
+
+// oauthDefaultScope 客户端未申请Scope时授予的Scope
+const oauthDefaultScope = "openid"
+
+// 控制userinfo接口返回用户联系方式的Scope
+const (
+	oauthEmailScope = "email" // 邮箱地址（email）
+	oauthPhoneScope = "phone" // 电话号码（phone_number）
+)
+
+// oauthAllowed 获取站点允许使用的值，站点未配置时允许使用所有支持的值
+func oauthAllowed(configured string, supported []string) []string {
+	if values := strings.Fields(configured); len(values) > 0 {
+		return values
+	}
+	return supported
+}
+
+// siteAllowsGrantType 判断站点是否允许使用该授权类型
+func siteAllowsGrantType(site *model.Site, grantType string) bool {
+	return utils.Contains(oauthAllowed(site.GrantTypes, oauthSupportedGrantTypes), grantType)
+}
+
+// siteAllowsResponseType 判断站点是否允许使用该响应类型，responseType 需为 normalizeResponseType 处理后的值
+func siteAllowsResponseType(site *model.Site, responseType string) bool {
+	allowed := oauthResponseTypes(site.RespTypes)
+	if len(allowed) == 0 {
+		allowed = oauthDefaultResponseTypes
+	}
+	return utils.Contains(allowed, responseType)
+}
+
+// oauthResponseTypes 解析站点配置的响应类型，多个以逗号分隔，如：code,code id_token
+func oauthResponseTypes(configured string) []string {
+	var values []string
+	for _, item := range strings.Split(configured, ",") {
+		if item = normalizeResponseType(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+// normalizeResponseType 响应类型中的值以空格分隔且与顺序无关（OAuth 2.0 Multiple Response Type Encoding Practices），按字母顺序排列后比较
+func normalizeResponseType(responseType string) string {
+	values := strings.Fields(responseType)
+	sort.Strings(values)
+	return strings.Join(values, " ")
+}
+
+// oauthFragmentResponse 判断响应类型是否通过回调地址的fragment返回，除code外均使用fragment，避免Token出现在服务端日志中
+func oauthFragmentResponse(responseType string) bool {
+	return responseType != "" && responseType != "code"
+}
+
+// grantedScope 计算授予客户端的Scope，不支持的Scope忽略，站点不允许使用的Scope返回错误，未申请Scope时授予openid
+func grantedScope(site *model.Site, requested string) (string, error) {
+
+	allowed := oauthAllowed(site.Scopes, oauthSupportedScopes)
+
+	var granted []string
+	for _, scope := range strings.Fields(requested) {
+		if !utils.Contains(oauthSupportedScopes, scope) || utils.Contains(granted, scope) {
+			continue
+		}
+		if !utils.Contains(allowed, scope) {
+			return "", fmt.Errorf("scope %s is not allowed for this client", scope)
+		}
+		granted = append(granted, scope)
+	}
+
+	if len(granted) == 0 {
+		return oauthDefaultScope, nil
+	}
+	return strings.Join(granted, " "), nil
+}
+
+// scopeGranted 判断授予客户端的Scope中是否包含指定的Scope
+func scopeGranted(granted, scope string) bool {
+	return utils.Contains(strings.Fields(granted), scope)
+}
+
+// validateOAuthPolicy 校验站点配置的授权类型、响应类型及Scope，授权类型及Scope多个以空格分隔，响应类型多个以逗号分隔，为空时不限制（响应类型为空时仅允许code）
+func validateOAuthPolicy(grantTypes, responseTypes, scopes string) error {
+	for _, item := range []struct {
+		name      string
+		values    []string
+		supported []string
+	}{
+		{"授权类型", strings.Fields(grantTypes), oauthSupportedGrantTypes},
+		{"响应类型", oauthResponseTypes(responseTypes), oauthSupportedResponseTypes},
+		{"Scope", strings.Fields(scopes), oauthSupportedScopes},
+	} {
+		for _, value := range item.values {
+			if !utils.Contains(item.supported, value) {
+				return fmt.Errorf("不支持的%s：%s", item.name, value)
+			}
+		}
+	}
+	return nil
+}
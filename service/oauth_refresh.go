@@ -0,0 +1,289 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"ops-api/dao"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+	"time"
+)
+
+const (
+	oauthOfflineScope       = "offline_access"    // 申请离线访问的Scope，签发的刷新令牌与用户登录会话相互独立
+	oauthRefreshTokenTTL    = 30 * 24 * time.Hour // 刷新令牌的默认有效期，每次刷新后重新计算
+	oauthRefreshTokenMaxTTL = 365                 // 站点可配置的刷新令牌最长有效期（天），同时为刷新令牌自授权起的默认最长有效期
+)
+
+// validateRefreshTokenTTL 校验站点的刷新令牌有效期
+func validateRefreshTokenTTL(days uint) error {
+	if days > oauthRefreshTokenMaxTTL {
+		return fmt.Errorf("刷新令牌有效期不能超过%d天", oauthRefreshTokenMaxTTL)
+	}
+	return nil
+}
+
+// refreshTokenTTL 获取站点的刷新令牌有效期，未配置时使用默认有效期
+func refreshTokenTTL(site *model.Site) time.Duration {
+	if site.RefreshTTL == 0 {
+		return oauthRefreshTokenTTL
+	}
+	return time.Duration(site.RefreshTTL) * 24 * time.Hour
+}
+
+// refreshTokenExpiresAt 计算刷新令牌的过期时间，轮换时重新计算有效期，但不超过自授权起的最长有效期
+func refreshTokenExpiresAt(site *model.Site, grantedAt, now time.Time) time.Time {
+	maxDays := site.RefreshMaxTTL
+	if maxDays == 0 {
+		maxDays = oauthRefreshTokenMaxTTL
+	}
+	expiresAt := now.Add(refreshTokenTTL(site))
+	if limit := grantedAt.AddDate(0, 0, int(maxDays)); expiresAt.After(limit) {
+		return limit
+	}
+	return expiresAt
+}
+
+// isOfflineRefreshToken 判断是否为离线访问（offline_access）签发的刷新令牌
+func isOfflineRefreshToken(token *model.SsoOAuthRefreshToken) bool {
+	return utils.Contains(strings.Fields(token.Scope), oauthOfflineScope)
+}
+
+// hashRefreshToken 计算刷新令牌的摘要
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newRefreshToken 生成随机刷新令牌
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// issueRefreshToken 为允许使用refresh_token授权类型的客户端签发刷新令牌，保留授权时授予的Scope及资源：
+// 授予了offline_access时刷新令牌绑定用户授权时登录的设备，使用独立的会话，用户退出登录后仍然有效，无法确定登录设备时不签发；
+// 否则刷新令牌使用用户的登录会话，会话注销或过期后失效
+func (s *sso) issueRefreshToken(site *model.Site, userId uint, sessionId, scope, resources string) (string, error) {
+
+	if sessionId == "" || !siteAllowsGrantType(site, "refresh_token") {
+		return "", nil
+	}
+	offline := utils.Contains(strings.Fields(scope), oauthOfflineScope)
+
+	// 获取用户登录会话，未找到会话时不签发刷新令牌
+	userSession, err := dao.Session.GetUserSession(sessionId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Warn(fmt.Sprintf("应用%s申请刷新令牌，但未找到会话（%s），不签发刷新令牌", site.Name, sessionId))
+			return "", nil
+		}
+		return "", err
+	}
+
+	// 根据会话记录的设备指纹获取登录设备
+	var deviceId uint
+	device, err := dao.Device.GetUserDevice(userId, userSession.Fingerprint)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", err
+	}
+	if err == nil {
+		deviceId = device.ID
+	}
+	if offline && deviceId == 0 {
+		logger.Warn(fmt.Sprintf("应用%s申请离线访问，但未找到会话（%s）的登录设备，不签发刷新令牌", site.Name, sessionId))
+		return "", nil
+	}
+
+	token, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	// 离线访问使用独立的会话，用户退出登录后不影响刷新令牌
+	refreshSessionId := sessionId
+	if offline {
+		refreshSessionId = uuid.NewString()
+	}
+
+	now := time.Now()
+	if err := dao.SSO.CreateRefreshToken(&model.SsoOAuthRefreshToken{
+		TokenHash: hashRefreshToken(token),
+		UserID:    userId,
+		ClientID:  site.ClientId,
+		DeviceID:  deviceId,
+		SessionID: refreshSessionId,
+		Scope:     scope,
+		Resource:  resources,
+		GrantedAt: &now,
+		CreatedAt: now,
+		ExpiresAt: refreshTokenExpiresAt(site, now, now),
+	}); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// refreshToken 使用刷新令牌获取新的Token（grant_type=refresh_token），刷新令牌使用后轮换，
+// 已轮换的刷新令牌再次使用时视为泄露，注销同一次授权签发的所有令牌，失败时返回 *OAuthError
+func (s *sso) refreshToken(site *model.Site, param *Token) (*ResponseToken, error) {
+
+	if param.RefreshToken == "" {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Missing required parameter: refresh_token")
+	}
+
+	invalidGrant := NewOAuthError(http.StatusBadRequest, OAuthInvalidGrant, "The refresh token is invalid, expired or revoked")
+
+	old, err := dao.SSO.GetRefreshToken(hashRefreshToken(param.RefreshToken))
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("获取刷新令牌失败：" + err.Error())
+			return nil, NewOAuthServerError()
+		}
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorExpiredCode, "刷新令牌不存在")
+		return nil, invalidGrant
+	}
+	if old.ClientID != site.ClientId {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "刷新令牌不属于该应用")
+		return nil, invalidGrant
+	}
+	if old.RevokedAt != nil || old.ExpiresAt.Before(time.Now()) {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorExpiredCode, "刷新令牌已过期或已注销")
+		return nil, invalidGrant
+	}
+	if old.RotatedAt != nil {
+		s.revokeRefreshSession(old)
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "已轮换的刷新令牌被再次使用，已注销该授权的所有令牌")
+		return nil, invalidGrant
+	}
+
+	if isOfflineRefreshToken(old) {
+		// 绑定的设备已删除时刷新令牌失效
+		if _, err := dao.Device.GetDevice(old.DeviceID); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Error("获取登录设备失败：" + err.Error())
+				return nil, NewOAuthServerError()
+			}
+			s.revokeRefreshSession(old)
+			recordSSOError(SSOProtocolOAuth, site, SSOErrorExpiredCode, "刷新令牌绑定的设备已删除")
+			return nil, invalidGrant
+		}
+
+		// 应用不再允许离线访问时刷新令牌失效
+		if !utils.Contains(oauthAllowed(site.Scopes, oauthSupportedScopes), oauthOfflineScope) {
+			recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "应用不允许使用的scope："+oauthOfflineScope)
+			return nil, invalidGrant
+		}
+	} else {
+		// 非离线访问的刷新令牌在用户登录会话注销或过期后失效
+		userSession, err := dao.Session.GetUserSession(old.SessionID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Error("获取用户会话失败：" + err.Error())
+			return nil, NewOAuthServerError()
+		}
+		if err != nil || userSession.RevokedAt != nil || userSession.ExpiresAt.Before(time.Now()) {
+			recordSSOError(SSOProtocolOAuth, site, SSOErrorExpiredCode, "刷新令牌所属的登录会话已注销或已过期")
+			return nil, invalidGrant
+		}
+	}
+
+	user, err := dao.User.GetUserInfo(old.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, invalidGrant
+		}
+		logger.Error("获取用户信息失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+	if !user.IsActive {
+		s.revokeRefreshSession(old)
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorAccessDenied, "用户已禁用："+user.Username)
+		return nil, invalidGrant
+	}
+
+	// 用户已被移出应用时刷新令牌失效
+	if !site.AllOpen && !dao.Site.IsUserInSite(old.UserID, site) {
+		s.revokeRefreshSession(old)
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorAccessDenied, fmt.Sprintf("用户ID：%d", old.UserID))
+		return nil, invalidGrant
+	}
+
+	// 本次签发的Access Token的资源只能在授权时授予的资源范围内缩小，刷新令牌保留授权时授予的资源
+	resources, oauthErr := narrowResources(site, old.Resource, requestedResources(param.Resource, param.Audience))
+	if oauthErr != nil {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "申请的资源超出授权范围")
+		return nil, oauthErr
+	}
+
+	// 轮换刷新令牌
+	token, err := newRefreshToken()
+	if err != nil {
+		logger.Error("生成刷新令牌失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+	now := time.Now()
+	grantedAt := old.CreatedAt
+	if old.GrantedAt != nil {
+		grantedAt = *old.GrantedAt
+	}
+	rotated, err := dao.SSO.RotateRefreshToken(old, &model.SsoOAuthRefreshToken{
+		TokenHash: hashRefreshToken(token),
+		UserID:    old.UserID,
+		ClientID:  old.ClientID,
+		DeviceID:  old.DeviceID,
+		SessionID: old.SessionID,
+		Scope:     old.Scope,
+		Resource:  old.Resource,
+		GrantedAt: &grantedAt,
+		CreatedAt: now,
+		ExpiresAt: refreshTokenExpiresAt(site, grantedAt, now),
+	})
+	if err != nil {
+		logger.Error("轮换刷新令牌失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+	if !rotated {
+		return nil, invalidGrant
+	}
+
+	idToken, accessToken, err := s.signOAuthTokens(site, user, old.UserID, old.Scope, resources, "", old.SessionID)
+	if err != nil {
+		logger.Error("生成Token失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	return &ResponseToken{
+		IdToken:      idToken,
+		AccessToken:  accessToken,
+		TokenType:    "bearer",
+		ExpiresIn:    int(accessTokenTTL(site).Seconds()),
+		RefreshToken: token,
+		Scope:        old.Scope,
+	}, nil
+}
+
+// revokeRefreshSession 注销同一次授权签发的刷新令牌及Token，非离线访问的刷新令牌使用用户登录会话，仅注销签发给该客户端的刷新令牌
+func (s *sso) revokeRefreshSession(token *model.SsoOAuthRefreshToken) {
+	if !isOfflineRefreshToken(token) {
+		if err := dao.SSO.RevokeClientRefreshTokens(token.SessionID, token.ClientID); err != nil {
+			logger.Error("注销刷新令牌失败：" + err.Error())
+		}
+		return
+	}
+	if err := Session.revokeOffline(token.UserID, token.SessionID); err != nil {
+		logger.Error("注销离线访问会话失败：" + err.Error())
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+)
+
+// 资源指示器（RFC 8707）：客户端调用多个API时，在授权请求及Token请求中通过 resource（或 audience）参数申请仅对指定API有效的Access Token，
+// 申请的资源必须在站点登记的资源列表中，Access Token的aud为授予的资源；未申请资源时aud为ClientId。
+// Token请求只能在授权时授予的资源范围内缩小资源，刷新令牌保留授权时授予的资源
+
+// oauthMaxResources 站点最多可登记的资源数量
+const oauthMaxResources = 20
+
+// validateResources 校验站点登记的资源列表，多个以空格分隔；资源为不含fragment的绝对URI（如：https://api.example.com）或API标识（如：orders-api）
+func validateResources(resources string) error {
+
+	values := strings.Fields(resources)
+	if len(values) > oauthMaxResources {
+		return fmt.Errorf("最多登记%d个资源", oauthMaxResources)
+	}
+	for _, value := range values {
+		if len(value) > 255 {
+			return fmt.Errorf("资源长度不能超过255个字符：%s", value)
+		}
+		if !strings.Contains(value, "://") {
+			continue
+		}
+		u, err := url.Parse(value)
+		if err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+			return fmt.Errorf("资源必须为不含fragment的绝对URI：%s", value)
+		}
+	}
+	return nil
+}
+
+// requestedResources 合并请求中的 resource 及 audience 参数，resource 可以传递多次，也可以使用空格分隔多个值
+func requestedResources(resource []string, audience string) []string {
+	var requested []string
+	for _, value := range append(resource, audience) {
+		for _, item := range strings.Fields(value) {
+			if !utils.Contains(requested, item) {
+				requested = append(requested, item)
+			}
+		}
+	}
+	return requested
+}
+
+// invalidTarget 申请的资源无效或不允许申请
+func invalidTarget(resource string) *OAuthError {
+	return NewOAuthError(http.StatusBadRequest, OAuthInvalidTarget, fmt.Sprintf("The requested resource %s is invalid or not allowed for this client", resource))
+}
+
+// grantedResources 校验申请的资源是否在站点登记的资源列表中，返回授予的资源（多个以空格分隔），未申请资源时返回空
+func grantedResources(site *model.Site, requested []string) (string, *OAuthError) {
+	allowed := strings.Fields(site.Resources)
+	for _, resource := range requested {
+		if !utils.Contains(allowed, resource) {
+			return "", invalidTarget(resource)
+		}
+	}
+	return strings.Join(requested, " "), nil
+}
+
+// narrowResources 计算Token请求授予的资源：授权时未授予资源时按站点登记的资源列表校验，否则只能在授权时授予的资源范围内缩小；
+// 未申请资源时使用授权时授予的资源，站点已移除的资源不再授予
+func narrowResources(site *model.Site, granted string, requested []string) (string, *OAuthError) {
+
+	if strings.TrimSpace(granted) == "" {
+		return grantedResources(site, requested)
+	}
+
+	allowed := strings.Fields(site.Resources)
+	if len(requested) == 0 {
+		var resources []string
+		for _, resource := range strings.Fields(granted) {
+			if utils.Contains(allowed, resource) {
+				resources = append(resources, resource)
+			}
+		}
+		return strings.Join(resources, " "), nil
+	}
+
+	for _, resource := range requested {
+		if !scopeGranted(granted, resource) || !utils.Contains(allowed, resource) {
+			return "", invalidTarget(resource)
+		}
+	}
+	return strings.Join(requested, " "), nil
+}
+
+// accessTokenAudience 获取Access Token的受众，授予了资源时为授予的资源，否则为ClientId
+func accessTokenAudience(site *model.Site, resources string) []string {
+	if audience := strings.Fields(resources); len(audience) > 0 {
+		return audience
+	}
+	return []string{site.ClientId}
+}
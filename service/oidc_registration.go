@@ -0,0 +1,200 @@
+package service
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"net/url"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/middleware"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+	"time"
+)
+
+// OIDCRegistrationGroup 动态注册的客户端所属的站点分组
+const OIDCRegistrationGroup = "动态注册应用"
+
+// 动态注册支持的客户端认证方式
+const (
+	tokenAuthClientSecretPost = "client_secret_post"
+	tokenAuthNone             = "none" // 公共客户端，必须使用PKCE
+)
+
+// ClientRegistration 动态客户端注册请求参数（RFC 7591 2）
+type ClientRegistration struct {
+	RedirectURIs            []string `json:"redirect_uris"`
+	ClientName              string   `json:"client_name"`
+	ClientURI               string   `json:"client_uri"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	Scope                   string   `json:"scope"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	SubjectType             string   `json:"subject_type"`
+}
+
+// ResponseClientRegistration 动态客户端注册响应（RFC 7591 3.2.1）
+type ResponseClientRegistration struct {
+	ClientId                string   `json:"client_id"`
+	ClientSecret            string   `json:"client_secret,omitempty"`
+	ClientIdIssuedAt        int64    `json:"client_id_issued_at"`
+	ClientSecretExpiresAt   int64    `json:"client_secret_expires_at"` // 0 表示永不过期
+	RedirectURIs            []string `json:"redirect_uris"`
+	ClientName              string   `json:"client_name"`
+	ClientURI               string   `json:"client_uri,omitempty"`
+	GrantTypes              []string `json:"grant_types"`
+	ResponseTypes           []string `json:"response_types"`
+	Scope                   string   `json:"scope,omitempty"`
+	TokenEndpointAuthMethod string   `json:"token_endpoint_auth_method"`
+	SubjectType             string   `json:"subject_type"`
+}
+
+// OIDCRegistrationEndpoint 获取动态客户端注册端点，未开启动态注册时返回空
+func OIDCRegistrationEndpoint() string {
+	if !config.GetBool("oidcRegistration") {
+		return ""
+	}
+	return middleware.OIDCIssuer() + "/api/v1/sso/oidc/register"
+}
+
+// checkInitialAccessToken 校验初始访问令牌（RFC 7591 3），未配置令牌时允许匿名注册
+func (s *sso) checkInitialAccessToken(authorization string) error {
+
+	cipherText := config.GetString("oidcRegistrationToken")
+	if cipherText == "" {
+		return nil
+	}
+
+	parts := strings.SplitN(authorization, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") || parts[1] == "" {
+		return NewOAuthError(http.StatusUnauthorized, OAuthInvalidToken, "Initial access token is required")
+	}
+
+	token, err := utils.Decrypt(cipherText)
+	if err != nil {
+		logger.Error("ERROR：动态注册初始访问令牌解密失败，", err.Error())
+		return NewOAuthServerError()
+	}
+
+	// 使用常量时间比较，避免时序攻击
+	if subtle.ConstantTimeCompare([]byte(parts[1]), []byte(token)) != 1 {
+		return NewOAuthError(http.StatusUnauthorized, OAuthInvalidToken, "Invalid initial access token")
+	}
+
+	return nil
+}
+
+// RegisterClient 动态注册OAuth2.0/OIDC客户端，创建的站点默认未授权任何用户，需要管理员授权后才能使用，失败时返回 *OAuthError
+func (s *sso) RegisterClient(data *ClientRegistration, authorization, clientIP string) (*ResponseClientRegistration, error) {
+
+	if !config.GetBool("oidcRegistration") {
+		return nil, NewOAuthError(http.StatusForbidden, OAuthAccessDenied, "Dynamic client registration is disabled")
+	}
+	if err := s.checkInitialAccessToken(authorization); err != nil {
+		return nil, err
+	}
+
+	// 校验回调地址，站点仅支持配置一个回调地址
+	if len(data.RedirectURIs) != 1 {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidRedirectURI, "Exactly one redirect_uri is required")
+	}
+	redirectURI, err := url.Parse(data.RedirectURIs[0])
+	if err != nil || (redirectURI.Scheme != "https" && redirectURI.Scheme != "http") || redirectURI.Host == "" || redirectURI.Fragment != "" {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidRedirectURI, "redirect_uri must be an absolute http(s) URL without fragment")
+	}
+
+	// 未指定时使用默认值（RFC 7591 2）
+	if len(data.GrantTypes) == 0 {
+		data.GrantTypes = []string{"authorization_code"}
+	}
+	if len(data.ResponseTypes) == 0 {
+		data.ResponseTypes = []string{"code"}
+	}
+	if data.TokenEndpointAuthMethod == "" {
+		data.TokenEndpointAuthMethod = tokenAuthClientSecretPost
+	}
+	if data.SubjectType == "" {
+		data.SubjectType = "public"
+	}
+	if data.ClientName == "" {
+		data.ClientName = redirectURI.Host
+	}
+
+	// 校验客户端元数据
+	if err := validateOAuthPolicy(strings.Join(data.GrantTypes, " "), strings.Join(data.ResponseTypes, ","), data.Scope); err != nil {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidClientMetadata, "Unsupported grant_types, response_types or scope")
+	}
+	if data.TokenEndpointAuthMethod != tokenAuthClientSecretPost && data.TokenEndpointAuthMethod != tokenAuthClientSecretBasic && data.TokenEndpointAuthMethod != tokenAuthNone {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidClientMetadata, "Unsupported token_endpoint_auth_method")
+	}
+	if data.SubjectType != "public" && data.SubjectType != "pairwise" {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidClientMetadata, "Unsupported subject_type")
+	}
+
+	site := &model.Site{
+		Name:        data.ClientName,
+		Address:     data.ClientURI,
+		Description: fmt.Sprintf("OIDC动态注册（%s）", clientIP),
+		SSO:         true,
+		SSOType:     2,
+		CallbackUrl: redirectURI.String(),
+		SubjectType: data.SubjectType,
+		GrantTypes:  strings.Join(data.GrantTypes, " "),
+		RespTypes:   strings.Join(data.ResponseTypes, ","),
+		Scopes:      strings.Join(strings.Fields(data.Scope), " "),
+	}
+	if data.TokenEndpointAuthMethod == tokenAuthNone {
+		site.PKCE = PKCEPublic
+	} else {
+		site.TokenAuthMethod = data.TokenEndpointAuthMethod
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	group, err := dao.Site.GetOrCreateGroup(tx, OIDCRegistrationGroup)
+	if err != nil {
+		tx.Rollback()
+		logger.Error("ERROR：", err.Error())
+		return nil, NewOAuthServerError()
+	}
+	site.SiteGroupID = group.ID
+
+	result, err := dao.Site.AddSite(tx, site)
+	if err != nil {
+		tx.Rollback()
+		logger.Error("ERROR：", err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		logger.Error("ERROR：", err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	logger.Info(fmt.Sprintf("客户端%s（%s）通过OIDC动态注册创建，来源IP：%s", result.Name, result.ClientId, clientIP))
+
+	response := &ResponseClientRegistration{
+		ClientId:                result.ClientId,
+		ClientIdIssuedAt:        time.Now().Unix(),
+		RedirectURIs:            []string{result.CallbackUrl},
+		ClientName:              result.Name,
+		ClientURI:               result.Address,
+		GrantTypes:              data.GrantTypes,
+		ResponseTypes:           data.ResponseTypes,
+		Scope:                   result.Scopes,
+		TokenEndpointAuthMethod: data.TokenEndpointAuthMethod,
+		SubjectType:             result.SubjectType,
+	}
+	if data.TokenEndpointAuthMethod != tokenAuthNone {
+		response.ClientSecret = result.ClientSecret
+	}
+
+	return response, nil
+}
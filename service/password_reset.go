@@ -0,0 +1,143 @@
+package service
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"html"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/i18n"
+	"ops-api/utils/mail"
+	"time"
+)
+
+const (
+	passwordResetTokenTTL      = 5 * time.Minute // 重置令牌有效期，与验证码有效期一致
+	passwordResetMaxAttempts   = 5               // 单个重置令牌允许的验证码错误次数
+	passwordResetTokenKeyFmt   = "reset_password_token:%s"
+	passwordResetAttemptKeyFmt = "reset_password_attempts:%s"
+)
+
+// passwordResetToken 密码重置令牌，与验证码及获取验证码的设备绑定
+type passwordResetToken struct {
+	Username    string `json:"username"`
+	Code        string `json:"code"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// newPasswordResetToken 生成重置令牌，userKey 记录用户当前有效的令牌，用于限制验证码发送频率及使旧令牌失效
+func newPasswordResetToken(userKey string, data *passwordResetToken) (string, error) {
+
+	// 使之前的令牌失效
+	if oldToken, err := global.Cache.Get(userKey); err == nil && oldToken != "" {
+		global.Cache.Del(fmt.Sprintf(passwordResetTokenKeyFmt, oldToken), fmt.Sprintf(passwordResetAttemptKeyFmt, oldToken))
+	}
+
+	token := utils.GenerateRandomString(32)
+	value, _ := json.Marshal(data)
+
+	if err := global.Cache.Set(fmt.Sprintf(passwordResetTokenKeyFmt, token), string(value), passwordResetTokenTTL); err != nil {
+		return "", err
+	}
+	if err := global.Cache.Set(userKey, token, passwordResetTokenTTL); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// usePasswordResetToken 校验重置令牌及验证码，校验通过后令牌立即失效，验证码错误次数过多时令牌失效
+func usePasswordResetToken(token, username, code, fingerprint string) error {
+
+	if token == "" {
+		return errors.New("重置令牌不能为空，请重新获取验证码")
+	}
+
+	tokenKey := fmt.Sprintf(passwordResetTokenKeyFmt, token)
+	attemptKey := fmt.Sprintf(passwordResetAttemptKeyFmt, token)
+
+	value, err := global.Cache.Get(tokenKey)
+	if err != nil {
+		return errors.New("验证码已过期，请重新获取")
+	}
+
+	var data passwordResetToken
+	if err := json.Unmarshal([]byte(value), &data); err != nil {
+		return err
+	}
+
+	// 令牌只能由申请重置的用户在获取验证码的设备上使用
+	if data.Username != username || data.Fingerprint != fingerprint {
+		return errors.New("重置令牌无效，请重新获取验证码")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(data.Code), []byte(code)) != 1 {
+		attempts, _ := global.Cache.Incr(attemptKey, passwordResetTokenTTL)
+		if attempts >= passwordResetMaxAttempts {
+			global.Cache.Del(tokenKey, attemptKey)
+			return errors.New("校验码错误次数过多，请重新获取")
+		}
+		return errors.New("校验码错误")
+	}
+
+	// 删除令牌，并发请求中只有一个能删除成功
+	deleted, err := global.Cache.Del(tokenKey)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errors.New("重置令牌已使用，请重新获取验证码")
+	}
+	global.Cache.Del(attemptKey)
+
+	return nil
+}
+
+// passwordResetNotice 密码重置成功后邮件通知用户，非本人操作时可及时联系管理员
+func passwordResetNotice(user *model.AuthUser, clientIP string) {
+	if user.Email == "" {
+		return
+	}
+	go func() {
+		locale := userLocale(user)
+		htmlBody := passwordResetNoticeHTML(locale, user.Username, clientIP, utils.FormatTimeIn(time.Now(), userLocation(user)))
+		if err := mail.Email.SendMsg([]string{user.Email}, nil, nil, i18n.T(locale, "password_changed.subject"), htmlBody, "html"); err != nil {
+			logger.Error(fmt.Sprintf("密码重置通知发送失败（%s）：%s", user.Username, err.Error()))
+		}
+	}()
+}
+
+// passwordResetNoticeHTML 密码重置通知正文
+func passwordResetNoticeHTML(locale, username, clientIP, resetTime string) string {
+
+	issuer := config.GetString("issuer")
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html lang="%s">
+		<head>
+			<meta charset="UTF-8">
+			<title>%s</title>
+		</head>
+		<body>
+			<p>%s</p>
+			<p>%s</p>
+			<p>%s</p>
+			<br>
+			<p>%s</p>
+			<p style="color: red">%s</p>
+		</body>
+		</html>
+	`, locale,
+		i18n.T(locale, "password_changed.subject"),
+		i18n.T(locale, "password_changed.body", html.EscapeString(username), resetTime, html.EscapeString(clientIP)),
+		i18n.T(locale, "password_changed.sessions"),
+		i18n.T(locale, "password_changed.action"),
+		i18n.T(locale, "mail.signature", html.EscapeString(issuer)),
+		i18n.T(locale, "mail.no_reply"))
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"fmt"
+	"github.com/wonderivan/logger"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/model"
+	"ops-api/utils/check"
+)
+
+// normalizePhone 标准化手机号，未携带国际区号时按系统配置的默认地区（smsRegion）解析
+func normalizePhone(number string) (*check.PhoneNumber, error) {
+	return check.ParsePhoneNumber(number, config.GetString("smsRegion"))
+}
+
+// normalizeUserPhone 标准化创建或修改用户时传入的手机号，号码无效时返回错误
+func normalizeUserPhone(number *string, phoneNational, phoneDisplay *string) error {
+
+	if *number == "" {
+		*phoneNational, *phoneDisplay = "", ""
+		return nil
+	}
+
+	phone, err := normalizePhone(*number)
+	if err != nil {
+		return err
+	}
+
+	*number, *phoneNational, *phoneDisplay = phone.E164, phone.National, phone.International
+	return nil
+}
+
+// setUserPhone 标准化用户手机号，用于从LDAP、Keycloak等外部身份源同步用户，号码无效时保留原始值，不影响用户同步
+func setUserPhone(user *model.AuthUser) {
+
+	if user.PhoneNumber == "" {
+		return
+	}
+
+	phone, err := normalizePhone(user.PhoneNumber)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("用户%s的%s", user.Username, err.Error()))
+		return
+	}
+
+	user.PhoneNumber = phone.E164
+	user.PhoneNational = phone.National
+	user.PhoneDisplay = phone.International
+}
+
+// NormalizePhoneNumbers 将未标准化的历史手机号转换为E.164格式，服务启动时执行
+func NormalizePhoneNumbers() error {
+
+	users, err := dao.User.GetUnnormalizedPhoneUsers()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		setUserPhone(user)
+		if user.PhoneNational == "" {
+			continue
+		}
+		if err := dao.User.UpdateUserPhone(user.ID, user.PhoneNumber, user.PhoneNational, user.PhoneDisplay); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
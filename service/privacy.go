@@ -0,0 +1,290 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+var Privacy privacy
+
+type privacy struct{}
+
+// UserErase 擦除用户个人数据结构体
+type UserErase struct {
+	ID     uint   `json:"id" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+// PersonalData 平台中保存的用户个人数据，用于用户导出自己的数据
+type PersonalData struct {
+	ExportedAt   time.Time                `json:"exported_at"`
+	Profile      *PersonalProfile         `json:"profile"`
+	Identities   []*PersonalIdentity      `json:"identities"`
+	Groups       []string                 `json:"groups"`
+	Sites        []string                 `json:"sites"` // 已授权访问的应用
+	Devices      []*model.UserDevice      `json:"devices"`
+	Sessions     []*model.UserSession     `json:"sessions"`
+	LoginHistory []*model.LogLogin        `json:"login_history"`
+	Consents     *PersonalConsents        `json:"consents"`
+	Accounts     []*PersonalAccountRecord `json:"accounts"` // 作为负责人管理的账号，不包含账号密码
+}
+
+// PersonalProfile 用户基本信息，不包含密码及MFA密钥
+type PersonalProfile struct {
+	ID                uint       `json:"id"`
+	Username          string     `json:"username"`
+	Name              string     `json:"name"`
+	Email             string     `json:"email"`
+	PhoneNumber       string     `json:"phone_number"`
+	Department        string     `json:"department"`
+	Title             string     `json:"title"`
+	Language          string     `json:"language"`
+	Timezone          string     `json:"timezone"`
+	IsActive          bool       `json:"is_active"`
+	MFAEnabled        bool       `json:"mfa_enabled"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastLoginAt       *time.Time `json:"last_login_at"`
+	PasswordExpiredAt *time.Time `json:"password_expired_at"`
+}
+
+// PersonalIdentity 用户关联的身份，如：用户来源、企业微信、天翼云及外部系统中的标识
+type PersonalIdentity struct {
+	Provider string `json:"provider"`
+	Subject  string `json:"subject"`
+}
+
+// PersonalConsents 用户对应用的授权及使用条款接受记录
+type PersonalConsents struct {
+	OAuthGrants []*dao.OAuthGrant        `json:"oauth_grants"`
+	Terms       []*model.TermsAcceptance `json:"terms"`
+
+	Applications []*dao.UserConsentItem `json:"applications"` // 用户在授权确认页面同意的应用授权
+}
+
+// PersonalAccountRecord 用户作为负责人管理的账号
+type PersonalAccountRecord struct {
+	Name      string    `json:"name"`
+	Username  string    `json:"username"`
+	LoginAddr string    `json:"login_address"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Export 导出用户的个人数据
+func (p *privacy) Export(userId uint) (*PersonalData, error) {
+
+	user, err := dao.User.GetUser(map[string]interface{}{"id": userId})
+	if err != nil {
+		return nil, err
+	}
+
+	data := &PersonalData{
+		ExportedAt: time.Now(),
+		Profile: &PersonalProfile{
+			ID:                user.ID,
+			Username:          user.Username,
+			Name:              user.Name,
+			Email:             user.Email,
+			PhoneNumber:       user.PhoneNumber,
+			Department:        user.Department,
+			Title:             user.Title,
+			Language:          user.Language,
+			Timezone:          user.Timezone,
+			IsActive:          user.IsActive,
+			MFAEnabled:        user.MFACode != nil,
+			CreatedAt:         user.CreatedAt,
+			LastLoginAt:       user.LastLoginAt,
+			PasswordExpiredAt: user.PasswordExpiredAt,
+		},
+		Identities: []*PersonalIdentity{{Provider: user.UserFrom, Subject: user.Username}},
+		Consents:   &PersonalConsents{},
+	}
+	if user.WwId != nil {
+		data.Identities = append(data.Identities, &PersonalIdentity{Provider: "企业微信", Subject: *user.WwId})
+	}
+	if user.CtyunId != nil {
+		data.Identities = append(data.Identities, &PersonalIdentity{Provider: "天翼云", Subject: *user.CtyunId})
+	}
+	if user.ExternalId != nil {
+		data.Identities = append(data.Identities, &PersonalIdentity{Provider: "外部系统", Subject: *user.ExternalId})
+	}
+
+	if data.Groups, err = dao.Privacy.GetUserGroups(userId); err != nil {
+		return nil, err
+	}
+	if data.Sites, err = dao.Privacy.GetUserSites(userId); err != nil {
+		return nil, err
+	}
+	if data.Devices, err = dao.Device.GetUserDevices(userId); err != nil {
+		return nil, err
+	}
+	if data.Sessions, err = dao.Session.GetUserSessions(userId, -1); err != nil {
+		return nil, err
+	}
+	if data.LoginHistory, err = dao.Privacy.GetUserLoginHistory(user.Username); err != nil {
+		return nil, err
+	}
+	if data.Consents.OAuthGrants, err = dao.Privacy.GetUserOAuthGrants(userId); err != nil {
+		return nil, err
+	}
+	if data.Consents.Terms, err = dao.Privacy.GetUserTermsAcceptances(userId); err != nil {
+		return nil, err
+	}
+	if data.Consents.Applications, err = dao.Consent.GetUserConsents(userId); err != nil {
+		return nil, err
+	}
+
+	accounts, err := dao.Privacy.GetOwnedAccounts(userId)
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range accounts {
+		data.Accounts = append(data.Accounts, &PersonalAccountRecord{
+			Name:      account.Name,
+			Username:  account.Username,
+			LoginAddr: account.LoginAddress,
+			CreatedAt: account.CreatedAt,
+		})
+	}
+
+	return data, nil
+}
+
+// ExportZip 导出用户的个人数据并打包为ZIP文件，每类数据保存为一个JSON文件
+func (p *privacy) ExportZip(userId uint) (*bytes.Buffer, error) {
+
+	data, err := p.Export(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	files := map[string]interface{}{
+		"profile.json":       data.Profile,
+		"identities.json":    data.Identities,
+		"groups.json":        data.Groups,
+		"sites.json":         data.Sites,
+		"devices.json":       data.Devices,
+		"sessions.json":      data.Sessions,
+		"login_history.json": data.LoginHistory,
+		"consents.json":      data.Consents,
+		"accounts.json":      data.Accounts,
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := new(bytes.Buffer)
+	zipWriter := zip.NewWriter(buf)
+	for _, name := range names {
+		content, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := addContentToZip(zipWriter, name, content); err != nil {
+			return nil, err
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// Erase 擦除用户的个人数据，用户信息及历史记录中的个人数据被匿名化，用户被禁用且无法再登录，
+// 登录、操作等历史记录保留并使用化名关联，确保审计记录的完整性；擦除后无法恢复
+func (p *privacy) Erase(data *UserErase, operator string) (*model.UserErasure, error) {
+
+	user, err := dao.User.GetUser(map[string]interface{}{"id": data.ID})
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == 1 || user.Username == "admin" {
+		return nil, errors.New("超级管理员不允许擦除")
+	}
+	if user.BreakGlass {
+		return nil, errors.New("请先取消该用户的应急账号设置")
+	}
+
+	// 用户作为负责人的账号需要先移交给其他用户
+	owned, err := dao.Privacy.CountOwnedAccounts(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if owned > 0 {
+		return nil, fmt.Errorf("该用户是%d个账号的负责人，请先移交账号", owned)
+	}
+
+	// 注销用户的所有会话及离线访问授权
+	if err := Session.RevokeUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	password, err := utils.Encrypt(utils.GenerateRandomString(32))
+	if err != nil {
+		return nil, err
+	}
+	pseudonym := fmt.Sprintf("erased-%d", user.ID)
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	counts, err := dao.Privacy.AnonymizeUser(tx, user, pseudonym, "已擦除用户", password)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var details []string
+	for _, name := range []string{"devices", "sessions", "login_logs", "operation_logs", "scim_logs", "sms_logs", "terms_acceptances", "consents"} {
+		if count, ok := counts[name]; ok {
+			details = append(details, fmt.Sprintf("%s=%d", name, count))
+		}
+	}
+	record := &model.UserErasure{
+		UserID:    user.ID,
+		Pseudonym: pseudonym,
+		Operator:  operator,
+		Reason:    data.Reason,
+		Detail:    strings.Join(details, ","),
+	}
+	if err := dao.Privacy.AddErasure(tx, record); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 重新加载策略并清除用户信息缓存
+	if err := global.CasBinServer.LoadPolicy(); err != nil {
+		logger.Error("ERROR：" + err.Error())
+	}
+	dao.User.ClearUserInfoCache(user.ID)
+
+	// 通知安全管理员，并通知下游系统删除该用户（事件中使用擦除前的用户信息，便于下游系统匹配用户）
+	SecurityEvent.Publish(SecurityEventUserErased, operator, pseudonym, "原因："+data.Reason)
+	ProvisionWebhook.PublishUser(ProvisionEventUserDeleted, user)
+
+	return record, nil
+}
+
+// GetErasureList 获取用户数据擦除记录
+func (p *privacy) GetErasureList(pseudonym string, page, limit int) (*dao.UserErasureList, error) {
+	return dao.Privacy.GetErasureList(pseudonym, page, limit)
+}
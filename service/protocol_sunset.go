@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/wonderivan/logger"
+	"html"
+	"net/mail"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/model"
+	mailer "ops-api/utils/mail"
+	"strings"
+	"time"
+)
+
+// 单点登录协议停用：系统配置 protocolSunset 可为整个协议设置停用日期（如：cas3=2026-12-31），站点也可单独设置停用日期，
+// 以较早的日期为准；停用日期之前为弃用期，单点登录仍可使用，但会记录告警并每天提醒一次应用负责人，停用后无法单点登录
+
+const (
+	sunsetDateLayout      = "2006-01-02"
+	sunsetNoticeKeyPrefix = "protocol_sunset_notice:" // 弃用提醒发送记录Key前缀，同一应用每天只提醒一次
+	sunsetNoticeInterval  = 24 * time.Hour
+)
+
+// ssoProtocols 站点单点登录类型与协议的对应关系
+var ssoProtocols = map[uint]string{
+	1: SSOProtocolCAS,
+	2: SSOProtocolOAuth,
+	3: SSOProtocolSAML,
+	4: SSOProtocolNginx,
+	5: SSOProtocolWsFed,
+}
+
+// ssoProtocolDeprecated 弃用期内单点登录次数，用于统计仍在使用即将停用协议的应用
+var ssoProtocolDeprecated = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "sso_protocol_deprecated_total",
+	Help: "弃用期内单点登录次数",
+}, []string{"protocol", "site"})
+
+// ProtocolRetiredError 应用使用的单点登录协议已停用
+type ProtocolRetiredError struct {
+	Application string `json:"application"`
+	Protocol    string `json:"protocol"`
+	Sunset      string `json:"sunset"` // 停用日期
+}
+
+func (e *ProtocolRetiredError) Error() string {
+	return fmt.Sprintf("该应用使用的单点登录方式已于%s停用，请联系应用管理员", e.Sunset)
+}
+
+// parseSunset 解析停用日期，停用日期当天0点起停用
+func parseSunset(value string) (time.Time, error) {
+	return time.ParseInLocation(sunsetDateLayout, strings.TrimSpace(value), time.Local)
+}
+
+// validateSunset 校验站点的停用日期及应用负责人邮箱
+func validateSunset(sunset, ownerEmail string) error {
+	if sunset != "" {
+		if _, err := parseSunset(sunset); err != nil {
+			return errors.New("单点登录停用日期格式错误，格式为：YYYY-MM-DD")
+		}
+	}
+	if ownerEmail != "" {
+		if _, err := mail.ParseAddressList(ownerEmail); err != nil {
+			return errors.New("应用负责人邮箱格式错误，多个以逗号分隔")
+		}
+	}
+	return nil
+}
+
+// validateProtocolSunset 校验系统配置的协议停用日期，每项格式为：协议=YYYY-MM-DD
+func validateProtocolSunset(rules []string) error {
+	for _, rule := range rules {
+		parts := strings.SplitN(rule, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("协议停用规则格式错误：%s", rule)
+		}
+		if !isSSOProtocol(strings.TrimSpace(parts[0])) {
+			return fmt.Errorf("不支持的协议：%s，可选值为：%s、%s、%s、%s、%s", parts[0],
+				SSOProtocolCAS, SSOProtocolOAuth, SSOProtocolSAML, SSOProtocolNginx, SSOProtocolWsFed)
+		}
+		if _, err := parseSunset(parts[1]); err != nil {
+			return fmt.Errorf("协议停用日期格式错误：%s，格式为：YYYY-MM-DD", parts[1])
+		}
+	}
+	return nil
+}
+
+// isSSOProtocol 判断是否为支持的单点登录协议
+func isSSOProtocol(protocol string) bool {
+	for _, item := range ssoProtocols {
+		if item == protocol {
+			return true
+		}
+	}
+	return false
+}
+
+// siteSunset 获取站点单点登录的停用日期，协议及站点均设置了停用日期时以较早的日期为准
+func siteSunset(protocol string, site *model.Site) (sunset time.Time, ok bool) {
+
+	dates := []string{site.Sunset}
+	for _, rule := range config.GetList("protocolSunset") {
+		if parts := strings.SplitN(rule, "=", 2); len(parts) == 2 && strings.TrimSpace(parts[0]) == protocol {
+			dates = append(dates, parts[1])
+		}
+	}
+
+	for _, value := range dates {
+		if value == "" {
+			continue
+		}
+		date, err := parseSunset(value)
+		if err != nil {
+			continue
+		}
+		if !ok || date.Before(sunset) {
+			sunset, ok = date, true
+		}
+	}
+	return sunset, ok
+}
+
+// checkProtocolSunset 判断站点使用的单点登录协议是否已停用，停用后返回 *ProtocolRetiredError；
+// 弃用期内记录告警并提醒应用负责人
+func checkProtocolSunset(protocol string, site *model.Site) error {
+
+	sunset, ok := siteSunset(protocol, site)
+	if !ok {
+		return nil
+	}
+
+	date := sunset.Format(sunsetDateLayout)
+	if !time.Now().Before(sunset) {
+		recordSSOError(protocol, site, SSOErrorProtocolRetired, "单点登录已于"+date+"停用")
+		return &ProtocolRetiredError{Application: site.Name, Protocol: protocol, Sunset: date}
+	}
+
+	ssoProtocolDeprecated.WithLabelValues(protocol, site.Name).Inc()
+	logger.Warn(fmt.Sprintf("应用%s使用的单点登录协议（%s）将于%s停用", site.Name, protocol, date))
+	go notifySunset(protocol, site, date)
+
+	return nil
+}
+
+// notifySunset 提醒应用负责人单点登录即将停用，所有实例中同一应用每天只提醒一次，未配置应用负责人邮箱时不提醒
+func notifySunset(protocol string, site *model.Site, date string) {
+
+	if site.OwnerEmail == "" {
+		return
+	}
+	ok, err := global.Cache.SetNX(fmt.Sprintf("%s%d", sunsetNoticeKeyPrefix, site.ID), date, sunsetNoticeInterval)
+	if err != nil || !ok {
+		return
+	}
+
+	var receivers []string
+	for _, item := range strings.Split(site.OwnerEmail, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			receivers = append(receivers, item)
+		}
+	}
+
+	if err := mailer.Email.SendMsg(receivers, nil, nil, "单点登录即将停用提醒", sunsetNoticeHTML(protocol, site.Name, date), "html"); err != nil {
+		logger.Error(fmt.Sprintf("应用%s单点登录停用提醒发送失败：%s", site.Name, err.Error()))
+	}
+}
+
+// sunsetNoticeHTML 单点登录即将停用提醒正文
+func sunsetNoticeHTML(protocol, siteName, date string) string {
+
+	issuer := config.GetString("issuer")
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="UTF-8">
+			<title>单点登录即将停用提醒</title>
+		</head>
+		<body>
+			<p>您负责的应用 <b>%s</b> 使用的单点登录协议（%s）将于 <b>%s</b> 停用，停用后用户将无法通过该协议登录应用。</p>
+			<p>请尽快将应用迁移至其它单点登录协议，如有疑问请联系统一认证平台管理员。</p>
+			<br>
+			<p>来源：%s</p>
+			<p style="color: red">此邮件为系统自动发送，请勿回复此邮件。</p>
+		</body>
+		</html>
+	`, html.EscapeString(siteName), html.EscapeString(protocol), date, html.EscapeString(issuer))
+}
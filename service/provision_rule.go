@@ -0,0 +1,321 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"strings"
+)
+
+var ProvisionRule provisionRule
+
+type provisionRule struct{}
+
+const (
+	ProvisionMatchEmailDomain = 1 // 按邮箱域名匹配
+	ProvisionMatchLDAPOU      = 2 // 按LDAP OU匹配
+)
+
+// ProvisionRuleCreate 创建自动分配规则结构体
+type ProvisionRuleCreate struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	MatchType   uint   `json:"match_type" binding:"required,oneof=1 2"`
+	MatchValue  string `json:"match_value" binding:"required"`
+	Enabled     *bool  `json:"enabled" binding:"required"`
+	Groups      []uint `json:"groups"`
+	Sites       []uint `json:"sites"`
+}
+
+// ProvisionRuleUpdate 更新自动分配规则结构体
+type ProvisionRuleUpdate struct {
+	dao.ProvisionRuleUpdate
+	Groups []uint `json:"groups"`
+	Sites  []uint `json:"sites"`
+}
+
+// GetProvisionRuleList 获取自动分配规则列表（表格）
+func (p *provisionRule) GetProvisionRuleList(name string, page, limit int) (data *dao.ProvisionRuleList, err error) {
+	return dao.ProvisionRule.GetProvisionRuleList(name, page, limit)
+}
+
+// AddProvisionRule 创建自动分配规则
+func (p *provisionRule) AddProvisionRule(data *ProvisionRuleCreate) (*model.ProvisionRule, error) {
+
+	matchValue, err := p.normalize(data.MatchType, data.MatchValue)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &model.ProvisionRule{
+		Name:        data.Name,
+		Description: data.Description,
+		MatchType:   data.MatchType,
+		MatchValue:  matchValue,
+		Enabled:     *data.Enabled,
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.ProvisionRule.AddProvisionRule(tx, rule)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 设置分组及站点
+	if err := p.updateTargets(tx, result, data.Groups, data.Sites); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateProvisionRule 更新自动分配规则
+func (p *provisionRule) UpdateProvisionRule(data *ProvisionRuleUpdate) (*model.ProvisionRule, error) {
+
+	matchValue, err := p.normalize(data.MatchType, data.MatchValue)
+	if err != nil {
+		return nil, err
+	}
+	data.MatchValue = matchValue
+
+	// 查询要修改的规则
+	rule, err := dao.ProvisionRule.GetProvisionRule(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.ProvisionRule.UpdateProvisionRule(tx, rule, &data.ProvisionRuleUpdate)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 更新分组及站点
+	if err := p.updateTargets(tx, result, data.Groups, data.Sites); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteProvisionRule 删除自动分配规则
+func (p *provisionRule) DeleteProvisionRule(id int) error {
+
+	rule, err := dao.ProvisionRule.GetProvisionRule(uint(id))
+	if err != nil {
+		return err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.ProvisionRule.DeleteProvisionRule(tx, rule); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// Apply 根据已启用的自动分配规则为新用户分配分组及站点，dns 为LDAP用户的用户名与DN的对应关系，非LDAP来源的用户传nil
+func (p *provisionRule) Apply(tx *gorm.DB, users []*model.AuthUser, dns map[string]string) error {
+
+	if len(users) == 0 {
+		return nil
+	}
+
+	rules, err := dao.ProvisionRule.GetEnabledProvisionRules(tx)
+	if err != nil || len(rules) == 0 {
+		return err
+	}
+
+	var userIds []uint
+	for _, user := range users {
+		var (
+			groups  []*model.AuthGroup
+			sites   []*model.Site
+			matched []string
+			seen    = make(map[string]bool)
+		)
+		for _, rule := range rules {
+			if !p.match(rule, user, dns[user.Username]) {
+				continue
+			}
+			matched = append(matched, rule.Name)
+
+			// 多个规则包含相同的分组或站点时只添加一次
+			for _, group := range rule.Groups {
+				if key := fmt.Sprintf("group:%d", group.ID); !seen[key] {
+					seen[key] = true
+					groups = append(groups, group)
+				}
+			}
+			for _, site := range rule.Sites {
+				if key := fmt.Sprintf("site:%d", site.ID); !seen[key] {
+					seen[key] = true
+					sites = append(sites, site)
+				}
+			}
+		}
+		if len(matched) == 0 {
+			continue
+		}
+
+		if err := dao.ProvisionRule.AssignUser(tx, user, groups, sites); err != nil {
+			return fmt.Errorf("用户%s自动分配失败：%s", user.Username, err.Error())
+		}
+		userIds = append(userIds, user.ID)
+		logger.Info(fmt.Sprintf("用户%s命中自动分配规则：%s", user.Username, strings.Join(matched, "、")))
+	}
+
+	// 分组或站点变更后清除用户信息缓存
+	if len(userIds) > 0 {
+		dao.User.ClearUserInfoCache(userIds...)
+	}
+
+	return nil
+}
+
+// ApplyToUser 为单个新用户执行自动分配，失败时仅记录日志，不影响用户创建
+func (p *provisionRule) ApplyToUser(user *model.AuthUser) {
+	if err := p.Apply(global.MySQLClient, []*model.AuthUser{user}, nil); err != nil {
+		logger.Error("ERROR：" + err.Error())
+	}
+}
+
+// match 判断用户是否命中规则
+func (p *provisionRule) match(rule *model.ProvisionRule, user *model.AuthUser, dn string) bool {
+	switch rule.MatchType {
+	case ProvisionMatchEmailDomain:
+		at := strings.LastIndex(user.Email, "@")
+		if at < 0 {
+			return false
+		}
+		domain := strings.ToLower(strings.TrimSpace(user.Email[at+1:]))
+		for _, value := range strings.Split(rule.MatchValue, "\n") {
+			// *.example.com 匹配所有子域名，不匹配 example.com 本身
+			if strings.HasPrefix(value, "*.") {
+				if strings.HasSuffix(domain, value[1:]) {
+					return true
+				}
+			} else if domain == value {
+				return true
+			}
+		}
+	case ProvisionMatchLDAPOU:
+		if dn == "" {
+			return false
+		}
+		userDN, err := ldap.ParseDN(dn)
+		if err != nil {
+			return false
+		}
+		for _, value := range strings.Split(rule.MatchValue, "\n") {
+			// 匹配该OU及其子OU下的所有用户
+			if ou, err := ldap.ParseDN(value); err == nil && ou.AncestorOfFold(userDN) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// normalize 校验并格式化匹配值，多个值使用换行分隔，邮箱域名同时支持逗号分隔
+func (p *provisionRule) normalize(matchType uint, matchValue string) (string, error) {
+
+	var values []string
+	switch matchType {
+	case ProvisionMatchEmailDomain:
+		for _, domain := range splitList(matchValue) {
+			domain = strings.ToLower(strings.TrimPrefix(domain, "@"))
+			if !strings.Contains(domain, ".") || strings.ContainsAny(domain, "@ /") || strings.Contains(domain[1:], "*") {
+				return "", fmt.Errorf("邮箱域名%s格式错误", domain)
+			}
+			if strings.HasPrefix(domain, "*") && !strings.HasPrefix(domain, "*.") {
+				return "", fmt.Errorf("邮箱域名%s格式错误，匹配子域名请使用*.example.com格式", domain)
+			}
+			values = append(values, domain)
+		}
+	case ProvisionMatchLDAPOU:
+		for _, value := range strings.FieldsFunc(matchValue, func(r rune) bool { return r == '\n' || r == '\r' }) {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			if dn, err := ldap.ParseDN(value); err != nil || len(dn.RDNs) == 0 {
+				return "", fmt.Errorf("LDAP OU %s 格式错误，请使用完整的DN，如：OU=研发部,DC=example,DC=com", value)
+			}
+			values = append(values, value)
+		}
+	default:
+		return "", errors.New("不支持的匹配类型")
+	}
+
+	if len(values) == 0 {
+		return "", errors.New("匹配值不能为空")
+	}
+
+	return strings.Join(values, "\n"), nil
+}
+
+// updateTargets 更新自动分配规则的分组及站点，角色分组涉及系统权限，不允许自动分配
+func (p *provisionRule) updateTargets(tx *gorm.DB, rule *model.ProvisionRule, groupIds, siteIds []uint) error {
+
+	var (
+		groups []model.AuthGroup
+		sites  []model.Site
+	)
+	if len(groupIds) > 0 {
+		if err := tx.Where("id IN ?", groupIds).Find(&groups).Error; err != nil {
+			return err
+		}
+		for _, group := range groups {
+			if group.IsRoleGroup {
+				return fmt.Errorf("分组%s为角色分组，不允许自动分配", group.Name)
+			}
+			if group.DynamicRule != "" {
+				return fmt.Errorf("分组%s为动态分组，不允许自动分配", group.Name)
+			}
+		}
+	}
+	if len(siteIds) > 0 {
+		if err := tx.Where("id IN ?", siteIds).Find(&sites).Error; err != nil {
+			return err
+		}
+	}
+	if len(groups) == 0 && len(sites) == 0 {
+		return errors.New("分组和站点不能同时为空")
+	}
+
+	return dao.ProvisionRule.UpdateProvisionRuleTargets(tx, rule, groups, sites)
+}
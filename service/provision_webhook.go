@@ -0,0 +1,388 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"ops-api/dao"
+	"ops-api/model"
+	"ops-api/utils"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var ProvisionWebhook provisionWebhook
+
+type provisionWebhook struct{}
+
+// 身份事件类型
+const (
+	ProvisionEventUserCreated         = "user.created"          // 创建用户
+	ProvisionEventUserUpdated         = "user.updated"          // 更新用户（包括禁用、启用）
+	ProvisionEventUserDeleted         = "user.deleted"          // 删除用户
+	ProvisionEventGroupMembersChanged = "group.members_changed" // 分组成员变更
+
+	provisionWebhookRetries = 3 // 推送失败时的最大尝试次数
+)
+
+// ProvisionEvents 支持订阅的身份事件
+var ProvisionEvents = []string{
+	ProvisionEventUserCreated,
+	ProvisionEventUserUpdated,
+	ProvisionEventUserDeleted,
+	ProvisionEventGroupMembersChanged,
+}
+
+// ProvisionWebhookCreate 创建身份事件推送结构体
+type ProvisionWebhookCreate struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	URL         string `json:"url" binding:"required,url"`
+	Method      string `json:"method" binding:"required,oneof=POST PUT"`
+	ContentType string `json:"content_type" binding:"required"`
+	Events      string `json:"events"`
+	Template    string `json:"template"`
+	Secret      string `json:"secret"`
+	Timeout     uint   `json:"timeout" binding:"required,min=1,max=30"`
+	Enabled     *bool  `json:"enabled" binding:"required"`
+}
+
+// ProvisionWebhookPreview 预览模板结构体
+type ProvisionWebhookPreview struct {
+	Event    string `json:"event" binding:"required"`
+	Template string `json:"template"`
+}
+
+// ProvisionEvent 推送至下游系统的身份事件，同时作为模板的数据
+type ProvisionEvent struct {
+	ID    string               `json:"id"`
+	Type  string               `json:"type"`
+	Time  time.Time            `json:"time"`
+	User  *ProvisionEventUser  `json:"user,omitempty"`
+	Group *ProvisionEventGroup `json:"group,omitempty"`
+}
+
+// ProvisionEventUser 事件中的用户信息
+type ProvisionEventUser struct {
+	ID          uint   `json:"id"`
+	Username    string `json:"username"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	PhoneNumber string `json:"phone_number"`
+	IsActive    bool   `json:"is_active"`
+	UserFrom    string `json:"user_from"`
+	ExternalId  string `json:"external_id,omitempty"`
+}
+
+// ProvisionEventGroup 事件中的分组信息
+type ProvisionEventGroup struct {
+	ID          uint     `json:"id"`
+	Name        string   `json:"name"`
+	IsRoleGroup bool     `json:"is_role_group"`
+	Added       []string `json:"added"`   // 新增的成员（用户名）
+	Removed     []string `json:"removed"` // 移除的成员（用户名）
+}
+
+// provisionTemplateFuncs 模板中可使用的函数，例如：{{ .User.Username | upper }}、{{ json .User }}、{{ date "2006-01-02" .Time }}
+var provisionTemplateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"xml": func(s string) string {
+		var buf bytes.Buffer
+		_ = xml.EscapeText(&buf, []byte(s))
+		return buf.String()
+	},
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"replace": strings.ReplaceAll,
+	"join": func(sep string, items []string) string {
+		return strings.Join(items, sep)
+	},
+	"default": func(def, value string) string {
+		if value == "" {
+			return def
+		}
+		return value
+	},
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+}
+
+// GetProvisionWebhookList 获取身份事件推送列表（表格）
+func (p *provisionWebhook) GetProvisionWebhookList(name string, page, limit int) (data *dao.ProvisionWebhookList, err error) {
+	return dao.ProvisionWebhook.GetProvisionWebhookList(name, page, limit)
+}
+
+// AddProvisionWebhook 创建身份事件推送
+func (p *provisionWebhook) AddProvisionWebhook(data *ProvisionWebhookCreate) (*model.ProvisionWebhook, error) {
+
+	events, err := p.validate(data.Events, data.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	return dao.ProvisionWebhook.AddProvisionWebhook(&model.ProvisionWebhook{
+		Name:        data.Name,
+		Description: data.Description,
+		URL:         data.URL,
+		Method:      data.Method,
+		ContentType: data.ContentType,
+		Events:      events,
+		Template:    data.Template,
+		Secret:      data.Secret,
+		Timeout:     data.Timeout,
+		Enabled:     *data.Enabled,
+	})
+}
+
+// UpdateProvisionWebhook 更新身份事件推送
+func (p *provisionWebhook) UpdateProvisionWebhook(data *dao.ProvisionWebhookUpdate) (*model.ProvisionWebhook, error) {
+
+	events, err := p.validate(data.Events, data.Template)
+	if err != nil {
+		return nil, err
+	}
+	data.Events = events
+
+	webhook, err := dao.ProvisionWebhook.GetProvisionWebhook(data.ID)
+	if err != nil {
+		return nil, err
+	}
+	return dao.ProvisionWebhook.UpdateProvisionWebhook(webhook, data)
+}
+
+// DeleteProvisionWebhook 删除身份事件推送
+func (p *provisionWebhook) DeleteProvisionWebhook(id int) error {
+	webhook, err := dao.ProvisionWebhook.GetProvisionWebhook(uint(id))
+	if err != nil {
+		return err
+	}
+	return dao.ProvisionWebhook.DeleteProvisionWebhook(webhook)
+}
+
+// Preview 使用示例事件渲染模板，用于配置模板时预览推送内容
+func (p *provisionWebhook) Preview(data *ProvisionWebhookPreview) (string, error) {
+
+	if !utils.Contains(ProvisionEvents, data.Event) {
+		return "", fmt.Errorf("不支持的事件：%s", data.Event)
+	}
+
+	body, err := p.render(data.Template, sampleProvisionEvent(data.Event))
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// PublishUser 发布用户相关的身份事件
+func (p *provisionWebhook) PublishUser(eventType string, user *model.AuthUser) {
+	item := &ProvisionEventUser{
+		ID:          user.ID,
+		Username:    user.Username,
+		Name:        user.Name,
+		Email:       user.Email,
+		PhoneNumber: user.PhoneNumber,
+		IsActive:    user.IsActive,
+		UserFrom:    user.UserFrom,
+	}
+	if user.ExternalId != nil {
+		item.ExternalId = *user.ExternalId
+	}
+	p.publish(&ProvisionEvent{Type: eventType, User: item})
+}
+
+// PublishGroupMembers 对比更新前后的组内用户，发布分组成员变更事件，成员未变化时不发布
+func (p *provisionWebhook) PublishGroupMembers(group *model.AuthGroup, oldUsernames []string, users []model.AuthUser) {
+
+	item := &ProvisionEventGroup{
+		ID:          group.ID,
+		Name:        group.Name,
+		IsRoleGroup: group.IsRoleGroup,
+		Added:       make([]string, 0),
+		Removed:     make([]string, 0),
+	}
+
+	var usernames []string
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+		if !utils.Contains(oldUsernames, user.Username) {
+			item.Added = append(item.Added, user.Username)
+		}
+	}
+	for _, username := range oldUsernames {
+		if !utils.Contains(usernames, username) {
+			item.Removed = append(item.Removed, username)
+		}
+	}
+	if len(item.Added) == 0 && len(item.Removed) == 0 {
+		return
+	}
+
+	p.publish(&ProvisionEvent{Type: ProvisionEventGroupMembersChanged, Group: item})
+}
+
+// publish 异步推送身份事件至订阅了该事件的下游系统
+func (p *provisionWebhook) publish(event *ProvisionEvent) {
+
+	event.ID = uuid.NewString()
+	event.Time = time.Now()
+
+	go func() {
+		webhooks, err := dao.ProvisionWebhook.GetEnabledProvisionWebhooks()
+		if err != nil {
+			logger.Error("ERROR：获取身份事件推送失败，", err.Error())
+			return
+		}
+		for _, webhook := range webhooks {
+			if webhook.Events != "" && !utils.Contains(strings.Split(webhook.Events, ","), event.Type) {
+				continue
+			}
+			if err := p.deliver(webhook, event); err != nil {
+				logger.Error(fmt.Sprintf("ERROR：身份事件（%s）推送至 %s 失败，%s", event.Type, webhook.Name, err.Error()))
+			}
+		}
+	}()
+}
+
+// deliver 推送身份事件，失败时重试
+func (p *provisionWebhook) deliver(webhook *model.ProvisionWebhook, event *ProvisionEvent) error {
+
+	body, err := p.render(webhook.Template, event)
+	if err != nil {
+		return err
+	}
+
+	for i := 1; ; i++ {
+		err = p.request(webhook, event, body)
+		if err == nil || i >= provisionWebhookRetries {
+			return err
+		}
+		time.Sleep(time.Duration(i) * time.Second)
+	}
+}
+
+// request 发送推送请求
+// 配置了密钥时使用 HMAC-SHA256 对 "时间戳.请求体" 签名，签名及时间戳分别通过请求头 X-Hook-Signature、X-Hook-Timestamp 传递，
+// 事件类型及事件ID通过请求头 X-Hook-Event、X-Hook-Event-Id 传递，接收方可据此去重；返回2xx状态码时视为推送成功
+func (p *provisionWebhook) request(webhook *model.ProvisionWebhook, event *ProvisionEvent, body []byte) error {
+
+	req, err := http.NewRequest(webhook.Method, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", webhook.ContentType)
+	req.Header.Set("X-Hook-Event", event.Type)
+	req.Header.Set("X-Hook-Event-Id", event.ID)
+	signWebhookRequest(req, webhook.Secret, body)
+
+	client := &http.Client{Timeout: time.Duration(webhook.Timeout) * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signWebhookRequest 设置请求头 X-Hook-Timestamp，配置了密钥时使用 HMAC-SHA256 对 "时间戳.请求体" 签名并设置请求头 X-Hook-Signature
+func signWebhookRequest(req *http.Request, secret string, body []byte) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("X-Hook-Timestamp", timestamp)
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(timestamp + "."))
+		mac.Write(body)
+		req.Header.Set("X-Hook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+}
+
+// render 渲染请求体，模板为空时推送原始事件（JSON）
+func (p *provisionWebhook) render(text string, event *ProvisionEvent) ([]byte, error) {
+
+	if strings.TrimSpace(text) == "" {
+		return json.Marshal(event)
+	}
+
+	tmpl, err := template.New("webhook").Funcs(provisionTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("模板解析失败：%s", err.Error())
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("模板渲染失败：%s", err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// validate 校验订阅的事件及模板，模板需使用所有订阅的事件渲染成功，返回去除空格后的事件列表
+func (p *provisionWebhook) validate(events, text string) (string, error) {
+
+	var items []string
+	for _, event := range strings.Split(events, ",") {
+		event = strings.TrimSpace(event)
+		if event == "" {
+			continue
+		}
+		if !utils.Contains(ProvisionEvents, event) {
+			return "", fmt.Errorf("不支持的事件：%s", event)
+		}
+		items = append(items, event)
+	}
+
+	samples := items
+	if len(samples) == 0 {
+		samples = ProvisionEvents
+	}
+	for _, event := range samples {
+		if _, err := p.render(text, sampleProvisionEvent(event)); err != nil {
+			return "", fmt.Errorf("%s（事件：%s）", err.Error(), event)
+		}
+	}
+
+	return strings.Join(items, ","), nil
+}
+
+// sampleProvisionEvent 生成示例事件，用于校验及预览模板
+func sampleProvisionEvent(eventType string) *ProvisionEvent {
+	event := &ProvisionEvent{
+		ID:   "00000000-0000-0000-0000-000000000000",
+		Type: eventType,
+		Time: time.Now(),
+	}
+	if eventType == ProvisionEventGroupMembersChanged {
+		event.Group = &ProvisionEventGroup{
+			ID:      1,
+			Name:    "example",
+			Added:   []string{"zhangsan"},
+			Removed: []string{"lisi"},
+		}
+	} else {
+		event.User = &ProvisionEventUser{
+			ID:          1,
+			Username:    "zhangsan",
+			Name:        "张三",
+			Email:       "zhangsan@example.com",
+			PhoneNumber: "+8613800000000",
+			IsActive:    eventType != ProvisionEventUserDeleted,
+			UserFrom:    "本地",
+		}
+	}
+	return event
+}
@@ -0,0 +1,477 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"html"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/mail"
+	"ops-api/utils/notify"
+	"ops-api/utils/report"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ComplianceReport complianceReport
+
+type complianceReport struct{}
+
+const (
+	// reportURLExpires IM通知中报告下载链接的有效期
+	reportURLExpires = 7 * 24 * time.Hour
+	// defaultDormantAccountDays 未配置沉睡账号天数时的默认值
+	defaultDormantAccountDays = 90
+)
+
+// reportContentTypes 报告文件格式对应的Content-Type
+var reportContentTypes = map[string]string{
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"pdf":  "application/pdf",
+}
+
+// ComplianceReportCreate 生成合规报告结构体
+type ComplianceReportCreate struct {
+	Period  string   `json:"period" binding:"required"` // 报告周期，格式：YYYY-MM
+	Formats []string `json:"formats" binding:"required,min=1,dive,oneof=xlsx pdf"`
+}
+
+// complianceReportSummary 合规报告概览
+type complianceReportSummary struct {
+	Period       string
+	TotalUsers   int
+	Sites        int
+	DormantUsers int
+	DormantDays  int
+	AdminActions int
+	LoginSuccess int64
+	LoginFailed  int64
+}
+
+// reportLink 报告下载链接
+type reportLink struct {
+	Name string
+	URL  string
+}
+
+// generatedReport 已生成的报告文件
+type generatedReport struct {
+	Record *model.ComplianceReport
+	Data   []byte
+}
+
+// GetReportList 获取合规报告列表
+func (r *complianceReport) GetReportList(period string, page, limit int) (*dao.ComplianceReportList, error) {
+	return dao.ComplianceReport.GetReportList(period, page, limit)
+}
+
+// CreateReport 手动生成合规报告，按生成报告的用户的时区计算
+func (r *complianceReport) CreateReport(data *ComplianceReportCreate, userId uint) ([]*model.ComplianceReport, error) {
+	user, err := dao.User.GetUser(map[string]interface{}{"id": userId})
+	if err != nil {
+		return nil, err
+	}
+
+	reports, _, err := r.generate(data.Period, data.Formats, userLocation(user))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*model.ComplianceReport, 0, len(reports))
+	for _, item := range reports {
+		records = append(records, item.Record)
+	}
+	return records, nil
+}
+
+// GetReportURL 获取合规报告下载地址
+func (r *complianceReport) GetReportURL(id uint) (string, error) {
+	data, err := dao.ComplianceReport.GetReport(id)
+	if err != nil {
+		return "", err
+	}
+
+	url, err := utils.GetPresignedURL(data.Object, 10*time.Minute)
+	if err != nil {
+		return "", err
+	}
+	return url.String(), nil
+}
+
+// DeleteReport 删除合规报告及OSS中的文件
+func (r *complianceReport) DeleteReport(id uint) error {
+	data, err := dao.ComplianceReport.GetReport(id)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.RemoveObject(data.Object); err != nil {
+		return err
+	}
+	return dao.ComplianceReport.DeleteReport(data)
+}
+
+// ComplianceReportNotice 定时任务生成上月合规报告，并发送给任务配置的接收人
+func (r *complianceReport) ComplianceReportNotice(task *model.ScheduledTask) error {
+
+	if task.NotifyType == nil || task.Receiver == nil || *task.Receiver == "" {
+		return errors.New("未配置通知方式或接收人")
+	}
+
+	// 定时任务按系统时区计算报告周期
+	now := time.Now().In(config.Location())
+	period := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0).Format("2006-01")
+
+	reports, summary, err := r.generate(period, []string{"xlsx", "pdf"}, now.Location())
+	if err != nil {
+		return err
+	}
+
+	title := fmt.Sprintf("%s 访问审查及登录审计报告", period)
+
+	// 邮件以附件形式发送报告，其它方式发送报告下载链接
+	switch *task.NotifyType {
+	case 1:
+		return r.sendMail(*task.Receiver, title, reports, summary)
+	case 3:
+		links, err := r.reportLinks(reports)
+		if err != nil {
+			return err
+		}
+		jsonBytes, _ := json.Marshal(complianceReportNoticeFeishuPost(title, summary, links))
+		return notify.GetNotifier(*task).SendNotify(string(jsonBytes), title)
+	default:
+		links, err := r.reportLinks(reports)
+		if err != nil {
+			return err
+		}
+		notifier := notify.GetNotifier(*task)
+		if notifier == nil {
+			return errors.New("不支持的通知方式")
+		}
+		return notifier.SendNotify(complianceReportNoticeMarkdown(title, summary, links), title)
+	}
+}
+
+// generate 生成指定月份的合规报告，上传至OSS并保存记录，loc 为报告周期及报告中时间使用的时区
+func (r *complianceReport) generate(period string, formats []string, loc *time.Location) ([]*generatedReport, *complianceReportSummary, error) {
+
+	start, err := time.ParseInLocation("2006-01", period, loc)
+	if err != nil {
+		return nil, nil, errors.New("报告周期格式错误，格式为：YYYY-MM")
+	}
+	if start.After(time.Now()) {
+		return nil, nil, errors.New("报告周期不能晚于当前月份")
+	}
+
+	sheets, summary, err := r.buildSheets(period, start, start.AddDate(0, 1, 0), loc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var reports []*generatedReport
+	for _, format := range formats {
+		contentType, ok := reportContentTypes[format]
+		if !ok {
+			return nil, nil, fmt.Errorf("不支持的报告格式：%s", format)
+		}
+
+		var buf bytes.Buffer
+		if format == "xlsx" {
+			err = report.WriteXLSX(&buf, sheets)
+		} else {
+			err = report.WritePDF(&buf, "访问审查及登录审计报告", sheets)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		record := &model.ComplianceReport{
+			Name:   fmt.Sprintf("合规报告-%s.%s", period, format),
+			Period: period,
+			Format: format,
+			Object: fmt.Sprintf("report/%s/compliance-report-%d.%s", period, time.Now().UnixNano(), format),
+			Size:   int64(buf.Len()),
+		}
+		if err := utils.FileUpload(record.Object, contentType, bytes.NewReader(buf.Bytes()), record.Size); err != nil {
+			return nil, nil, err
+		}
+		if err := dao.ComplianceReport.CreateReport(record); err != nil {
+			return nil, nil, err
+		}
+
+		reports = append(reports, &generatedReport{Record: record, Data: buf.Bytes()})
+	}
+
+	return reports, summary, nil
+}
+
+// userLoginSummary 按用户汇总登录次数，报告时区与系统时区一致时使用登录统计日汇总数据，否则按登录日志统计
+func (r *complianceReport) userLoginSummary(start, end time.Time, loc *time.Location) ([]*dao.UserLoginSummary, error) {
+	if loc.String() != config.Location().String() {
+		return dao.ComplianceReport.GetUserLoginSummary(start, end)
+	}
+	return dao.ComplianceReport.GetUserLoginRollup(start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"))
+}
+
+// buildSheets 汇总报告数据：应用授权用户、沉睡账号、管理员操作、用户登录情况、使用条款接受记录
+func (r *complianceReport) buildSheets(period string, start, end time.Time, loc *time.Location) ([]*report.Sheet, *complianceReportSummary, error) {
+
+	dormantDays := config.GetInt("dormantAccountDays")
+	if dormantDays <= 0 {
+		dormantDays = defaultDormantAccountDays
+	}
+
+	// 沉睡账号以报告周期结束时间为基准计算，当月报告以当前时间为基准
+	reference := end
+	if reference.After(time.Now()) {
+		reference = time.Now()
+	}
+
+	access, err := dao.ComplianceReport.GetSiteUserAccess()
+	if err != nil {
+		return nil, nil, err
+	}
+	dormant, err := dao.ComplianceReport.GetDormantUsers(reference.AddDate(0, 0, -dormantDays))
+	if err != nil {
+		return nil, nil, err
+	}
+	oplogs, err := dao.ComplianceReport.GetOplogRange(start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	logins, err := r.userLoginSummary(start, end, loc)
+	if err != nil {
+		return nil, nil, err
+	}
+	acceptances, err := dao.Terms.GetAcceptanceRange(start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	summary := &complianceReportSummary{
+		Period:       period,
+		DormantUsers: len(dormant),
+		DormantDays:  dormantDays,
+		AdminActions: len(oplogs),
+	}
+
+	// 应用授权用户
+	accessSheet := &report.Sheet{Name: "应用授权用户", Header: []string{"应用", "授权范围", "用户名", "姓名", "账号状态"}}
+	sites := make(map[string]bool)
+	users := make(map[string]bool)
+	for _, item := range access {
+		sites[item.Site] = true
+		switch {
+		case item.AllOpen:
+			accessSheet.Rows = append(accessSheet.Rows, []string{item.Site, "所有用户", "-", "-", "-"})
+		case item.Username == "":
+			accessSheet.Rows = append(accessSheet.Rows, []string{item.Site, "指定用户", "-", "-", "-"})
+		default:
+			users[item.Username] = true
+			accessSheet.Rows = append(accessSheet.Rows, []string{item.Site, "指定用户", item.Username, item.Name, activeText(item.IsActive)})
+		}
+	}
+	summary.Sites = len(sites)
+	summary.TotalUsers = len(users)
+
+	// 沉睡账号
+	dormantSheet := &report.Sheet{Name: "沉睡账号", Header: []string{"用户名", "姓名", "邮箱", "用户来源", "创建时间", "最后登录时间"}}
+	for _, user := range dormant {
+		lastLogin := "从未登录"
+		if user.LastLoginAt != nil {
+			lastLogin = utils.FormatTimeIn(*user.LastLoginAt, loc)
+		}
+		dormantSheet.Rows = append(dormantSheet.Rows, []string{user.Username, user.Name, user.Email, user.UserFrom, utils.FormatTimeIn(user.CreatedAt, loc), lastLogin})
+	}
+
+	// 管理员操作
+	oplogSheet := &report.Sheet{Name: "管理员操作", Header: []string{"时间", "用户名", "请求方法", "接口", "来源IP"}}
+	for _, log := range oplogs {
+		oplogSheet.Rows = append(oplogSheet.Rows, []string{utils.FormatTimeIn(log.CreatedAt, loc), log.Username, log.Method, log.Endpoint, log.ClientIP})
+	}
+
+	// 用户登录情况
+	loginSheet := &report.Sheet{Name: "用户登录情况", Header: []string{"用户名", "成功次数", "失败次数", "最后登录时间"}}
+	for _, item := range logins {
+		summary.LoginSuccess += item.Success
+		summary.LoginFailed += item.Failed
+		loginSheet.Rows = append(loginSheet.Rows, []string{item.Username, fmt.Sprintf("%d", item.Success), fmt.Sprintf("%d", item.Failed), utils.FormatTimeIn(item.LastLoginAt, loc)})
+	}
+
+	// 使用条款接受记录
+	termsSheet := &report.Sheet{Name: "使用条款接受记录", Header: []string{"时间", "用户名", "条款", "版本", "应用", "来源IP"}}
+	for _, item := range acceptances {
+		termsSheet.Rows = append(termsSheet.Rows, []string{utils.FormatTimeIn(item.CreatedAt, loc), item.Username, item.Name, fmt.Sprintf("%d", item.Version), item.Site, item.ClientIP})
+	}
+
+	overviewSheet := &report.Sheet{Name: "概览", Header: []string{"项目", "数值"}, Rows: [][]string{
+		{"报告周期", period},
+		{"生成时间", utils.FormatTimeIn(time.Now(), loc)},
+		{"应用数", fmt.Sprintf("%d", summary.Sites)},
+		{"指定授权用户数", fmt.Sprintf("%d", summary.TotalUsers)},
+		{fmt.Sprintf("沉睡账号数（%d天未登录）", dormantDays), fmt.Sprintf("%d", summary.DormantUsers)},
+		{"管理员操作数", fmt.Sprintf("%d", summary.AdminActions)},
+		{"登录成功次数", fmt.Sprintf("%d", summary.LoginSuccess)},
+		{"登录失败次数", fmt.Sprintf("%d", summary.LoginFailed)},
+		{"使用条款接受次数", fmt.Sprintf("%d", len(acceptances))},
+	}}
+
+	return []*report.Sheet{overviewSheet, accessSheet, dormantSheet, oplogSheet, loginSheet, termsSheet}, summary, nil
+}
+
+// sendMail 以邮件附件形式发送报告
+func (r *complianceReport) sendMail(receiver, title string, reports []*generatedReport, summary *complianceReportSummary) error {
+
+	// 附件文件名取自文件路径，写入临时目录后发送
+	dir, err := os.MkdirTemp("", "compliance-report")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	var files []string
+	for _, item := range reports {
+		file := filepath.Join(dir, item.Record.Name)
+		if err := os.WriteFile(file, item.Data, 0600); err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	return mail.Email.SendMsg(strings.Split(receiver, ","), nil, files, title, complianceReportNoticeHTML(title, summary), "html")
+}
+
+// reportLinks 获取报告下载链接
+func (r *complianceReport) reportLinks(reports []*generatedReport) ([]*reportLink, error) {
+	links := make([]*reportLink, 0, len(reports))
+	for _, item := range reports {
+		url, err := utils.GetObjectURL(item.Record.Object, reportURLExpires)
+		if err != nil {
+			logger.Error("获取报告下载链接失败：", err.Error())
+			return nil, err
+		}
+		links = append(links, &reportLink{Name: item.Record.Name, URL: url})
+	}
+	return links, nil
+}
+
+// lines 报告概览内容
+func (s *complianceReportSummary) lines() []string {
+	return []string{
+		fmt.Sprintf("应用数：%d", s.Sites),
+		fmt.Sprintf("指定授权用户数：%d", s.TotalUsers),
+		fmt.Sprintf("沉睡账号数（%d天未登录）：%d", s.DormantDays, s.DormantUsers),
+		fmt.Sprintf("管理员操作数：%d", s.AdminActions),
+		fmt.Sprintf("登录成功次数：%d", s.LoginSuccess),
+		fmt.Sprintf("登录失败次数：%d", s.LoginFailed),
+	}
+}
+
+// complianceReportNoticeMarkdown 生成合规报告通知 Markdown 文档
+func complianceReportNoticeMarkdown(title string, summary *complianceReportSummary, links []*reportLink) string {
+	var (
+		builder = &strings.Builder{}
+		issuer  = config.GetString("issuer")
+	)
+
+	builder.WriteString(fmt.Sprintf("**%s：**\n\n", title))
+	for _, line := range summary.lines() {
+		builder.WriteString(fmt.Sprintf("- %s\n", line))
+	}
+	builder.WriteString("\n")
+	for _, link := range links {
+		builder.WriteString(fmt.Sprintf("[%s](%s)\n\n", link.Name, link.URL))
+	}
+	builder.WriteString(fmt.Sprintf("下载链接有效期为 %d 天\n\n", int(reportURLExpires.Hours()/24)))
+	builder.WriteString("--------------------------------\n")
+	builder.WriteString(fmt.Sprintf("来源：%s\n", issuer))
+
+	return builder.String()
+}
+
+// complianceReportNoticeFeishuPost 生成合规报告飞书 Post 富文本消息
+func complianceReportNoticeFeishuPost(title string, summary *complianceReportSummary, links []*reportLink) map[string]interface{} {
+	var (
+		issuer  = config.GetString("issuer")
+		content = make([][]map[string]interface{}, 0)
+	)
+
+	for _, line := range summary.lines() {
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": line},
+		})
+	}
+	for _, link := range links {
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": "下载："},
+			{"tag": "a", "text": link.Name, "href": link.URL},
+		})
+	}
+
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": fmt.Sprintf("下载链接有效期为 %d 天", int(reportURLExpires.Hours()/24))},
+	})
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": "--------------------------------\n"},
+	})
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": fmt.Sprintf("来源：%s", issuer)},
+	})
+
+	return map[string]interface{}{
+		"msg_type": "post",
+		"content": map[string]interface{}{
+			"post": map[string]interface{}{
+				"zh_cn": map[string]interface{}{
+					"title":   title,
+					"content": content,
+				},
+			},
+		},
+	}
+}
+
+// complianceReportNoticeHTML 生成合规报告通知邮件 HTML 文档
+func complianceReportNoticeHTML(title string, summary *complianceReportSummary) string {
+	var (
+		issuer = config.GetString("issuer")
+		items  strings.Builder
+	)
+
+	for _, line := range summary.lines() {
+		items.WriteString(fmt.Sprintf("<li>%s</li>", html.EscapeString(line)))
+	}
+
+	return fmt.Sprintf(`
+        <!DOCTYPE html>
+        <html>
+        <head>
+            <meta charset="UTF-8">
+            <title>%s</title>
+        </head>
+        <body>
+            <div style="max-width: 800px; margin: 0 auto; font-family: Arial, sans-serif; line-height: 1.6; color: #333; padding: 20px;">
+                <h1 style="color: #2c3e50; border-bottom: 2px solid #3498db; padding-bottom: 10px;">%s</h1>
+                <ul>%s</ul>
+                <p>报告明细（应用授权用户、沉睡账号、管理员操作、用户登录情况）请查看附件。</p>
+                <p style="color: #999; font-size: 12px;">来源：%s</p>
+            </div>
+        </body>
+        </html>`, html.EscapeString(title), html.EscapeString(title), items.String(), html.EscapeString(issuer))
+}
+
+// activeText 账号状态
+func activeText(active bool) string {
+	if active {
+		return "启用"
+	}
+	return "禁用"
+}
@@ -0,0 +1,390 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/i18n"
+	"ops-api/utils/mail"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var RequiredAction requiredAction
+
+type requiredAction struct{}
+
+// 登录时必须完成的操作
+const (
+	RequiredActionChangePassword = "change_password" // 修改初始密码
+	RequiredActionVerifyEmail    = "verify_email"    // 验证邮箱
+	RequiredActionVerifyPhone    = "verify_phone"    // 验证手机号
+	RequiredActionEnableMFA      = "enable_mfa"      // 绑定MFA，通过MFA_ENABLE页面完成
+	RequiredActionAcceptTerms    = "accept_terms"    // 接受使用条款
+)
+
+// RequiredActionsPage 首次登录操作页面名称，在前端定义
+const RequiredActionsPage = "REQUIRED_ACTIONS"
+
+// requiredActionTokenTTL 首次登录操作临时令牌有效期，每完成一项操作后刷新
+const requiredActionTokenTTL = 10 * time.Minute
+
+// requiredActions 支持的操作，按执行顺序排列
+var requiredActions = []string{
+	RequiredActionChangePassword,
+	RequiredActionVerifyEmail,
+	RequiredActionVerifyPhone,
+	RequiredActionAcceptTerms,
+	RequiredActionEnableMFA,
+}
+
+// requiredActionState 临时令牌对应的登录状态
+type requiredActionState struct {
+	Username string `json:"username"`
+	MFA      bool   `json:"mfa"` // 是否已通过MFA认证
+}
+
+// RequiredActionInfo 用户待完成的操作
+type RequiredActionInfo struct {
+	Actions []string `json:"actions"`
+	Terms   string   `json:"terms"` // 使用条款，包含accept_terms时返回
+	Email   string   `json:"email"` // 接收验证码的邮箱（已脱敏）
+	Phone   string   `json:"phone"` // 接收验证码的手机号（已脱敏）
+}
+
+// RequiredActionCode 获取验证码请求参数
+type RequiredActionCode struct {
+	Token  string `json:"token" binding:"required"`
+	Action string `json:"action" binding:"required"` // verify_email或verify_phone
+}
+
+// RequiredActionSubmit 完成操作请求参数，单点登录相关参数与MFA认证接口一致，所有操作完成后继续处理单点登录请求
+type RequiredActionSubmit struct {
+	Token               string `json:"token" binding:"required"`
+	Action              string `json:"action" binding:"required"`
+	Password            string `json:"password"`              // change_password：新密码
+	RePassword          string `json:"re_password"`           // change_password：确认密码
+	Code                string `json:"code"`                  // verify_email、verify_phone：验证码
+	Accept              bool   `json:"accept"`                // accept_terms：是否接受使用条款
+	ResponseType        string `json:"response_type"`         // OAuth2.0客户端：响应类型，如：code、id_token、code id_token
+	ClientId            string `json:"client_id"`             // OAuth2.0客户端：客户端ID
+	RedirectURI         string `json:"redirect_uri"`          // OAuth2.0客户端：重定向URL
+	State               string `json:"state"`                 // OAuth2.0客户端：客户端状态码
+	Scope               string `json:"scope"`                 // OAuth2.0客户端：申请权限范围
+	Nonce               string `json:"nonce"`                 // OIDC客户端：随机码
+	CodeChallenge       string `json:"code_challenge"`        // OAuth2.0客户端：PKCE code_challenge
+	CodeChallengeMethod string `json:"code_challenge_method"` // OAuth2.0客户端：PKCE code_challenge计算方式
+	Service             string `json:"service"`               // CAS3.0客户端：回调地址
+	SAMLRequest         string `json:"SAMLRequest"`           // SAML2客户端：SAMLRequest
+	RelayState          string `json:"RelayState"`            // SAML2客户端：客户端状态码
+	SigAlg              string `json:"SigAlg"`                // SAML2客户端：签名算法
+	Signature           string `json:"Signature"`             // SAML2客户端：签名
+	NginxRedirectURI    string `json:"nginx_redirect_uri"`    // Nginx代理客户端：回调地址
+	Wtrealm             string `json:"wtrealm"`               // WS-Fed客户端：RP标识
+	Wreply              string `json:"wreply"`                // WS-Fed客户端：RP回调地址
+	Wctx                string `json:"wctx"`                  // WS-Fed客户端：RP状态信息
+}
+
+// RequiredActionResult 完成操作后的登录结果，NextPage不为空时前端需要跳转到对应页面继续登录
+type RequiredActionResult struct {
+	Username    string
+	Token       string
+	RedirectURI string
+	Application string
+	NextPage    *string
+	Actions     []string
+}
+
+// Initial 新用户需要完成的操作
+func (r *requiredAction) Initial() string {
+	var actions []string
+	for _, action := range config.GetList("firstLoginActions") {
+		if utils.Contains(requiredActions, action) {
+			actions = append(actions, action)
+		}
+	}
+	return strings.Join(actions, ",")
+}
+
+// FromKeycloak 将Keycloak用户的requiredActions转换为对应的操作，不支持的操作会被忽略
+func (r *requiredAction) FromKeycloak(kcActions []string) string {
+	mapping := map[string]string{
+		"UPDATE_PASSWORD":      RequiredActionChangePassword,
+		"VERIFY_EMAIL":         RequiredActionVerifyEmail,
+		"CONFIGURE_TOTP":       RequiredActionEnableMFA,
+		"TERMS_AND_CONDITIONS": RequiredActionAcceptTerms,
+	}
+	var actions []string
+	for _, kcAction := range kcActions {
+		if action, ok := mapping[kcAction]; ok && !utils.Contains(actions, action) {
+			actions = append(actions, action)
+		}
+	}
+	return strings.Join(actions, ",")
+}
+
+// Add 为用户添加登录时必须完成的操作，已存在时忽略
+func (r *requiredAction) Add(user *model.AuthUser, action string) error {
+	actions := make([]string, 0)
+	for _, item := range strings.Split(user.RequiredActions, ",") {
+		if item != "" {
+			actions = append(actions, item)
+		}
+	}
+	if utils.Contains(actions, action) {
+		return nil
+	}
+	user.RequiredActions = strings.Join(append(actions, action), ",")
+	return dao.User.UpdateUserRequiredActions(user.ID, user.RequiredActions)
+}
+
+// pending 获取用户待完成的操作，actions为需要在首次登录操作页面完成的操作，mfa表示需要绑定MFA
+// 无法完成的操作（如未绑定邮箱时验证邮箱）会被忽略，避免用户无法登录
+func (r *requiredAction) pending(user *model.AuthUser) (actions []string, mfa bool) {
+
+	if user.RequiredActions == "" {
+		return nil, false
+	}
+
+	configured := strings.Split(user.RequiredActions, ",")
+	for _, action := range requiredActions {
+		if !utils.Contains(configured, action) {
+			continue
+		}
+		switch action {
+		case RequiredActionVerifyEmail:
+			if user.Email == "" {
+				continue
+			}
+		case RequiredActionVerifyPhone:
+			if user.PhoneNumber == "" {
+				continue
+			}
+		case RequiredActionEnableMFA:
+			mfa = user.MFACode == nil
+			continue
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, mfa
+}
+
+// Required 判断用户是否需要在首次登录操作页面完成操作
+func (r *requiredAction) Required(user *model.AuthUser) bool {
+	actions, _ := r.pending(user)
+	return len(actions) > 0
+}
+
+// Begin 生成临时令牌并返回首次登录操作页面，mfa表示用户已通过MFA认证
+func (r *requiredAction) Begin(user *model.AuthUser, mfa bool) (token string, nextPage *string, err error) {
+
+	token = utils.GenerateRandomString(32)
+	state, err := json.Marshal(&requiredActionState{Username: user.Username, MFA: mfa})
+	if err != nil {
+		return "", nil, err
+	}
+	if err := global.Cache.Set(r.tokenKey(token), string(state), requiredActionTokenTTL); err != nil {
+		return "", nil, err
+	}
+
+	page := RequiredActionsPage
+	return token, &page, nil
+}
+
+// GetRequiredActions 获取临时令牌对应用户待完成的操作
+func (r *requiredAction) GetRequiredActions(token string) (*RequiredActionInfo, error) {
+
+	_, user, err := r.load(token)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, _ := r.pending(user)
+	info := &RequiredActionInfo{Actions: actions}
+	if info.Actions == nil {
+		info.Actions = []string{}
+	}
+	if utils.Contains(actions, RequiredActionAcceptTerms) {
+		info.Terms = config.GetString("firstLoginTerms")
+	}
+	if utils.Contains(actions, RequiredActionVerifyEmail) {
+		info.Email = utils.MaskEmail(user.Email)
+	}
+	if utils.Contains(actions, RequiredActionVerifyPhone) {
+		info.Phone = utils.MaskPhone(user.PhoneNumber)
+	}
+
+	return info, nil
+}
+
+// SendCode 发送邮箱或手机号验证码，1分钟内只能发送一次
+func (r *requiredAction) SendCode(data *RequiredActionCode) error {
+
+	_, user, err := r.load(data.Token)
+	if err != nil {
+		return err
+	}
+
+	actions, _ := r.pending(user)
+	if !utils.Contains(actions, data.Action) || (data.Action != RequiredActionVerifyEmail && data.Action != RequiredActionVerifyPhone) {
+		return errors.New("当前操作不需要验证码")
+	}
+
+	keyName := r.codeKey(user.Username, data.Action)
+	if ttl, err := global.Cache.TTL(keyName); err == nil && ttl.Seconds() > 240 {
+		return NewThrottleError(ttl-240*time.Second, fmt.Sprintf("验证码已发送，请%d秒后重试", int(ttl.Seconds()-240)))
+	}
+
+	var code string
+	locale := userLocale(user)
+	if data.Action == RequiredActionVerifyPhone {
+		number, err := SMS.SMSSend(user.PhoneNumber, "手机号验证", locale)
+		if err != nil {
+			return err
+		}
+		code = number
+	} else {
+		code = strconv.Itoa(utils.GenerateRandomNumber())
+		subject := i18n.T(locale, "verify_email.subject")
+		if err := mail.Email.SendMsg([]string{user.Email}, nil, nil, subject, verificationCodeHTML(locale, subject, code), "html"); err != nil {
+			return err
+		}
+	}
+
+	return global.Cache.Set(keyName, code, 5*time.Minute)
+}
+
+// Submit 完成一项操作，所有操作完成后继续登录流程
+func (r *requiredAction) Submit(data *RequiredActionSubmit, clientIP string) (*RequiredActionResult, error) {
+
+	state, user, err := r.load(data.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	actions, _ := r.pending(user)
+	if !utils.Contains(actions, data.Action) {
+		return nil, errors.New("不支持的操作或操作已完成")
+	}
+
+	switch data.Action {
+	case RequiredActionChangePassword:
+		if user.CheckPassword(data.Password) {
+			return nil, errors.New("新密码不能与初始密码相同")
+		}
+		if err := User.UpdateUserPassword(&dao.UserPasswordUpdate{ID: user.ID, Password: data.Password, RePassword: data.RePassword}); err != nil {
+			return nil, err
+		}
+	case RequiredActionVerifyEmail, RequiredActionVerifyPhone:
+		keyName := r.codeKey(user.Username, data.Action)
+		code, err := global.Cache.Get(keyName)
+		if err != nil {
+			return nil, errors.New("验证码已过期，请重新获取")
+		}
+		if data.Code == "" || code != data.Code {
+			return nil, errors.New("验证码错误")
+		}
+		global.Cache.Del(keyName)
+	case RequiredActionAcceptTerms:
+		if !data.Accept {
+			return nil, errors.New("需要接受使用条款后才能继续登录")
+		}
+	}
+
+	// 移除已完成的操作
+	remaining := make([]string, 0)
+	for _, action := range strings.Split(user.RequiredActions, ",") {
+		if action != data.Action {
+			remaining = append(remaining, action)
+		}
+	}
+	user.RequiredActions = strings.Join(remaining, ",")
+	if err := dao.User.UpdateUserRequiredActions(user.ID, user.RequiredActions); err != nil {
+		return nil, err
+	}
+	logger.Info(fmt.Sprintf("用户%s已完成首次登录操作：%s", user.Username, data.Action))
+
+	// 还有未完成的操作时继续使用当前令牌
+	result := &RequiredActionResult{Username: user.Username}
+	if actions, _ := r.pending(user); len(actions) > 0 {
+		global.Cache.Expire(r.tokenKey(data.Token), requiredActionTokenTTL)
+		page := RequiredActionsPage
+		result.Token, result.NextPage, result.Actions = data.Token, &page, actions
+		return result, nil
+	}
+	global.Cache.Del(r.tokenKey(data.Token))
+
+	// 所有操作完成后继续登录流程
+	params := &UserLogin{
+		Username:            user.Username,
+		ResponseType:        data.ResponseType,
+		ClientId:            data.ClientId,
+		RedirectURI:         data.RedirectURI,
+		State:               data.State,
+		Scope:               data.Scope,
+		Nonce:               data.Nonce,
+		CodeChallenge:       data.CodeChallenge,
+		CodeChallengeMethod: data.CodeChallengeMethod,
+		Service:             data.Service,
+		SAMLRequest:         data.SAMLRequest,
+		RelayState:          data.RelayState,
+		SigAlg:              data.SigAlg,
+		Signature:           data.Signature,
+		NginxRedirectURI:    data.NginxRedirectURI,
+		Wtrealm:             data.Wtrealm,
+		Wreply:              data.Wreply,
+		Wctx:                data.Wctx,
+	}
+	mfaRequired, err := LoginPolicy.CheckLoginPolicy(user, clientIP)
+	if err != nil {
+		return nil, err
+	}
+	result.Token, result.RedirectURI, result.Application, result.NextPage, err = User.completeLogin(params, *user, clientIP, mfaRequired, state.MFA)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// load 获取临时令牌对应的登录状态及用户
+func (r *requiredAction) load(token string) (*requiredActionState, *model.AuthUser, error) {
+
+	value, err := global.Cache.Get(r.tokenKey(token))
+	if err != nil {
+		return nil, nil, errors.New("认证已过期，请重新登录")
+	}
+
+	state := &requiredActionState{}
+	if err := json.Unmarshal([]byte(value), state); err != nil {
+		return nil, nil, err
+	}
+
+	user, err := dao.User.GetUser(map[string]interface{}{"username": state.Username})
+	if err != nil {
+		return nil, nil, err
+	}
+	if !user.IsActive {
+		return nil, nil, errors.New("拒绝登录，请联系管理员")
+	}
+
+	return state, user, nil
+}
+
+// tokenKey 临时令牌在Redis中的Key
+func (r *requiredAction) tokenKey(token string) string {
+	return "required_action:" + token
+}
+
+// codeKey 验证码在Redis中的Key
+func (r *requiredAction) codeKey(username, action string) string {
+	return fmt.Sprintf("required_action_code:%s:%s", username, action)
+}
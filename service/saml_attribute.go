@@ -0,0 +1,151 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/LoginRadius/go-saml"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"strings"
+)
+
+// SAML2属性发布策略：站点可配置断言中发布的属性（saml_attributes，JSON数组），每条规则包含属性名、属性名格式，
+// 以及属性值来源（用户属性或属性转换表达式）或固定值，配置后断言中仅包含策略中的属性；
+// 未配置时发布内置的默认属性（兼容阿里云、AWS、华为云及天翼云）及属性映射（claim_mapping）中的属性
+
+// samlAttributeFormats 属性名格式简写
+var samlAttributeFormats = map[string]string{
+	"":            saml.AttributeFormatUnspecified,
+	"unspecified": saml.AttributeFormatUnspecified,
+	"basic":       saml.AttributeFormatBasic,
+	"uri":         saml.AttributeFormatUri,
+}
+
+// SAMLAttributeRule SAML2属性发布规则
+type SAMLAttributeRule struct {
+	Name   string `json:"name"`   // 断言中的属性名
+	Format string `json:"format"` // 属性名格式：unspecified、basic、uri 或完整的URN，为空时为unspecified
+	Source string `json:"source"` // 属性值来源：用户属性或属性转换表达式，与 value 二选一
+	Value  string `json:"value"`  // 固定值，如云厂商的账号ID、IDP名称
+}
+
+// parseSAMLAttributes 解析并校验站点的SAML2属性发布策略，未配置时返回空
+func parseSAMLAttributes(content string) ([]*SAMLAttributeRule, error) {
+
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+
+	var rules []*SAMLAttributeRule
+	if err := json.Unmarshal([]byte(content), &rules); err != nil {
+		return nil, errors.New("SAML2属性发布策略格式错误，格式为JSON数组，如：[{\"name\":\"email\",\"source\":\"email\"},{\"name\":\"accountId\",\"value\":\"123456\"}]")
+	}
+
+	names := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		if rule == nil || strings.TrimSpace(rule.Name) == "" {
+			return nil, errors.New("SAML2属性发布策略中的属性名不能为空")
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("SAML2属性%s重复", rule.Name)
+		}
+		names[rule.Name] = true
+
+		if _, ok := samlAttributeFormats[rule.Format]; !ok && !strings.HasPrefix(rule.Format, "urn:") {
+			return nil, fmt.Errorf("SAML2属性%s的属性名格式错误，可选值为：unspecified、basic、uri 或完整的URN", rule.Name)
+		}
+		if (rule.Source == "") == (rule.Value == "") {
+			return nil, fmt.Errorf("SAML2属性%s必须且只能配置属性值来源（source）或固定值（value）之一", rule.Name)
+		}
+		if rule.Source != "" {
+			if _, err := parseClaimExpression(rule.Source); err != nil {
+				return nil, fmt.Errorf("SAML2属性%s的属性值来源错误：%s", rule.Name, err.Error())
+			}
+		}
+	}
+	return rules, nil
+}
+
+// validateSAMLAttributes 校验站点的SAML2属性发布策略
+func validateSAMLAttributes(content string) error {
+	_, err := parseSAMLAttributes(content)
+	return err
+}
+
+// addSAMLAttributes 按站点的属性发布策略向断言添加用户属性，多值属性以逗号拼接，计算结果为空时不发布该属性
+func addSAMLAttributes(idp *saml.IdentityProvider, site *model.Site, userinfo *dao.UserInfo, userId uint) error {
+
+	rules, err := parseSAMLAttributes(site.SAMLAttributes)
+	if err != nil {
+		return errors.New("应用SAML2属性发布策略配置错误")
+	}
+	if rules == nil {
+		return addDefaultSAMLAttributes(idp, site, userinfo, userId)
+	}
+
+	var user model.AuthUser
+	if err := global.MySQLClient.Preload("Groups").First(&user, userId).Error; err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		format, ok := samlAttributeFormats[rule.Format]
+		if !ok {
+			format = rule.Format
+		}
+		if rule.Value != "" {
+			idp.AddAttribute(rule.Name, rule.Value, format)
+			continue
+		}
+		if values := getUserClaimValues(&user, rule.Source); len(values) > 0 {
+			idp.AddAttribute(rule.Name, strings.Join(values, ","), format)
+		}
+	}
+	return nil
+}
+
+// addDefaultSAMLAttributes 未配置属性发布策略时发布的默认属性
+func addDefaultSAMLAttributes(idp *saml.IdentityProvider, site *model.Site, userinfo *dao.UserInfo, userId uint) error {
+
+	// 添加其它用户属性
+	idp.AddAttribute("name", userinfo.Name, saml.AttributeFormatUnspecified)                // 用户姓名
+	idp.AddAttribute("username", userinfo.Username, saml.AttributeFormatUnspecified)        // 用户名
+	idp.AddAttribute("email", userinfo.Email, saml.AttributeFormatUnspecified)              // 邮箱地址
+	idp.AddAttribute("phone_number", userinfo.PhoneNumber, saml.AttributeFormatUnspecified) // 电话号码
+
+	// AWS专属配置
+	if strings.Contains(site.Address, "awsapps") {
+		idp.AddAttribute("username", userinfo.Email, saml.AttributeFormatUnspecified)
+	}
+
+	// 华为云专属配置
+	idp.AddAttribute("IAM_SAML_Attributes_xUserId", userinfo.Username, saml.AttributeFormatUnspecified)
+	idp.AddAttribute("IAM_SAML_Attributes_redirect_url", site.RedirectUrl, saml.AttributeFormatUnspecified) // 登录后跳转的地址
+	idp.AddAttribute("IAM_SAML_Attributes_domain_id", site.DomainId, saml.AttributeFormatUnspecified)
+	idp.AddAttribute("IAM_SAML_Attributes_idp_id", site.IDPName, saml.AttributeFormatUnspecified)
+
+	// 天翼云专属配置
+	idp.AddAttribute("nickName", userinfo.Name, saml.AttributeFormatUnspecified)  // 用户姓名
+	idp.AddAttribute("accountId", site.DomainId, saml.AttributeFormatUnspecified) //  天翼云账号ID
+	idp.AddAttribute("userId", userinfo.CtyunId, saml.AttributeFormatUnspecified) // 天翼云IAM用户ID
+	idp.AddAttribute("idpId", site.DomainId, saml.AttributeFormatUnspecified)     // 天翼云IDP ID
+
+	// 按站点属性映射添加用户属性
+	if strings.TrimSpace(site.ClaimMapping) != "" {
+		var claimMapping map[string]string
+		if err := json.Unmarshal([]byte(site.ClaimMapping), &claimMapping); err != nil {
+			return errors.New("应用属性映射配置错误")
+		}
+		var user model.AuthUser
+		if err := global.MySQLClient.Preload("Groups").First(&user, userId).Error; err != nil {
+			return err
+		}
+		for attribute, field := range claimMapping {
+			if values := getUserClaimValues(&user, field); len(values) > 0 {
+				idp.AddAttribute(attribute, strings.Join(values, ","), saml.AttributeFormatUnspecified)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"ops-api/model"
+	"strings"
+)
+
+// SAML2断言加密：站点开启断言加密（encrypt_assertion）后，签名后的断言使用随机生成的对称密钥加密（XML Encryption），
+// 对称密钥使用SP加密证书的公钥加密，断言替换为 EncryptedAssertion；SP加密证书未配置时使用SP证书，
+// 密钥加密算法及数据加密算法可按站点配置，未配置时使用 rsa-oaep-mgf1p 及 aes256-cbc
+
+// 密钥加密算法
+const (
+	SAMLKeyEncryptionRSAOAEP = "rsa-oaep-mgf1p"
+	SAMLKeyEncryptionRSA15   = "rsa-1_5"
+)
+
+// 数据加密算法
+const (
+	SAMLDataEncryptionAES128CBC = "aes128-cbc"
+	SAMLDataEncryptionAES256CBC = "aes256-cbc"
+	SAMLDataEncryptionAES128GCM = "aes128-gcm"
+	SAMLDataEncryptionAES256GCM = "aes256-gcm"
+)
+
+// samlKeyEncryptionURIs 密钥加密算法标识
+var samlKeyEncryptionURIs = map[string]string{
+	SAMLKeyEncryptionRSAOAEP: "http://www.w3.org/2001/04/xmlenc#rsa-oaep-mgf1p",
+	SAMLKeyEncryptionRSA15:   "http://www.w3.org/2001/04/xmlenc#rsa-1_5",
+}
+
+// samlDataEncryption 数据加密算法标识及密钥长度
+var samlDataEncryption = map[string]struct {
+	uri     string
+	keySize int
+	gcm     bool
+}{
+	SAMLDataEncryptionAES128CBC: {uri: "http://www.w3.org/2001/04/xmlenc#aes128-cbc", keySize: 16},
+	SAMLDataEncryptionAES256CBC: {uri: "http://www.w3.org/2001/04/xmlenc#aes256-cbc", keySize: 32},
+	SAMLDataEncryptionAES128GCM: {uri: "http://www.w3.org/2009/xmlenc11#aes128-gcm", keySize: 16, gcm: true},
+	SAMLDataEncryptionAES256GCM: {uri: "http://www.w3.org/2009/xmlenc11#aes256-gcm", keySize: 32, gcm: true},
+}
+
+const (
+	samlAssertionStart = "<saml:Assertion"
+	samlAssertionEnd   = "</saml:Assertion>"
+)
+
+// validateSAMLEncryption 校验SAML2断言加密配置
+func validateSAMLEncryption(keyAlgorithm, dataAlgorithm, certificate string) error {
+
+	if _, ok := samlKeyEncryptionURIs[keyAlgorithm]; keyAlgorithm != "" && !ok {
+		return fmt.Errorf("不支持的密钥加密算法：%s，可选值为：%s、%s", keyAlgorithm, SAMLKeyEncryptionRSAOAEP, SAMLKeyEncryptionRSA15)
+	}
+	if _, ok := samlDataEncryption[dataAlgorithm]; dataAlgorithm != "" && !ok {
+		return fmt.Errorf("不支持的数据加密算法：%s，可选值为：%s、%s、%s、%s", dataAlgorithm,
+			SAMLDataEncryptionAES128CBC, SAMLDataEncryptionAES256CBC, SAMLDataEncryptionAES128GCM, SAMLDataEncryptionAES256GCM)
+	}
+	if strings.TrimSpace(certificate) != "" {
+		if _, err := samlEncryptionKey(certificate); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// samlEncryptionKey 解析SP加密证书的RSA公钥
+func samlEncryptionKey(certificate string) (*rsa.PublicKey, error) {
+	crt, err := parseCertificate(normalizeCertificatePEM(certificate))
+	if err != nil {
+		return nil, errors.New("SP加密证书格式错误")
+	}
+	publicKey, ok := crt.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("SP加密证书仅支持RSA公钥")
+	}
+	return publicKey, nil
+}
+
+// encryptSAMLAssertion 加密签名后的SAMLResponse中的断言
+func encryptSAMLAssertion(signedXML string, site *model.Site) (string, error) {
+
+	certificate := site.EncryptionCert
+	if strings.TrimSpace(certificate) == "" {
+		certificate = site.Certificate
+	}
+	publicKey, err := samlEncryptionKey(certificate)
+	if err != nil {
+		return "", err
+	}
+
+	keyAlgorithm, dataAlgorithm := site.KeyEncryption, site.DataEncryption
+	if keyAlgorithm == "" {
+		keyAlgorithm = SAMLKeyEncryptionRSAOAEP
+	}
+	if dataAlgorithm == "" {
+		dataAlgorithm = SAMLDataEncryptionAES256CBC
+	}
+	keyURI, ok := samlKeyEncryptionURIs[keyAlgorithm]
+	if !ok {
+		return "", errors.New("应用密钥加密算法配置错误")
+	}
+	dataMethod, ok := samlDataEncryption[dataAlgorithm]
+	if !ok {
+		return "", errors.New("应用数据加密算法配置错误")
+	}
+
+	// 截取断言，断言中已声明所需的命名空间，可直接作为独立的XML片段加密
+	start := strings.Index(signedXML, samlAssertionStart)
+	end := strings.LastIndex(signedXML, samlAssertionEnd)
+	if start < 0 || end < start {
+		return "", errors.New("SAMLResponse中未找到断言")
+	}
+	end += len(samlAssertionEnd)
+
+	// 使用随机生成的对称密钥加密断言
+	key := make([]byte, dataMethod.keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	cipherData, err := samlEncryptData(key, []byte(signedXML[start:end]), dataMethod.gcm)
+	if err != nil {
+		return "", err
+	}
+
+	// 使用SP加密证书的公钥加密对称密钥
+	var cipherKey []byte
+	if keyAlgorithm == SAMLKeyEncryptionRSA15 {
+		cipherKey, err = rsa.EncryptPKCS1v15(rand.Reader, publicKey, key)
+	} else {
+		cipherKey, err = rsa.EncryptOAEP(sha1.New(), rand.Reader, publicKey, key, nil)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var digestMethod string
+	if keyAlgorithm == SAMLKeyEncryptionRSAOAEP {
+		digestMethod = `<ds:DigestMethod Algorithm="http://www.w3.org/2000/09/xmldsig#sha1"/>`
+	}
+	encryptedAssertion := fmt.Sprintf(`<saml:EncryptedAssertion>`+
+		`<xenc:EncryptedData xmlns:xenc="http://www.w3.org/2001/04/xmlenc#" xmlns:ds="http://www.w3.org/2000/09/xmldsig#" Type="http://www.w3.org/2001/04/xmlenc#Element">`+
+		`<xenc:EncryptionMethod Algorithm="%s"/>`+
+		`<ds:KeyInfo><xenc:EncryptedKey>`+
+		`<xenc:EncryptionMethod Algorithm="%s">%s</xenc:EncryptionMethod>`+
+		`<ds:KeyInfo><ds:X509Data><ds:X509Certificate>%s</ds:X509Certificate></ds:X509Data></ds:KeyInfo>`+
+		`<xenc:CipherData><xenc:CipherValue>%s</xenc:CipherValue></xenc:CipherData>`+
+		`</xenc:EncryptedKey></ds:KeyInfo>`+
+		`<xenc:CipherData><xenc:CipherValue>%s</xenc:CipherValue></xenc:CipherData>`+
+		`</xenc:EncryptedData></saml:EncryptedAssertion>`,
+		dataMethod.uri,
+		keyURI, digestMethod,
+		samlCertificateBody(certificate),
+		base64.StdEncoding.EncodeToString(cipherKey),
+		base64.StdEncoding.EncodeToString(cipherData))
+
+	return signedXML[:start] + encryptedAssertion + signedXML[end:], nil
+}
+
+// samlEncryptData 加密断言，CBC模式的密文为IV+密文（填充方式兼容ISO 10126），GCM模式的密文为IV+密文+认证标签
+func samlEncryptData(key, plaintext []byte, gcm bool) ([]byte, error) {
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if gcm {
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		return aead.Seal(nonce, nonce, plaintext, nil), nil
+	}
+
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := make([]byte, aes.BlockSize+len(plaintext)+padding)
+	iv := data[:aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	copy(data[aes.BlockSize:], plaintext)
+	for i := len(data) - padding; i < len(data); i++ {
+		data[i] = byte(padding)
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data[aes.BlockSize:], data[aes.BlockSize:])
+	return data, nil
+}
+
+// samlCertificateBody 获取证书的Base64内容（去除PEM头尾及换行）
+func samlCertificateBody(certificate string) string {
+	certificate = strings.TrimSpace(certificate)
+	certificate = strings.TrimPrefix(certificate, "-----BEGIN CERTIFICATE-----")
+	certificate = strings.TrimSuffix(certificate, "-----END CERTIFICATE-----")
+	return strings.Join(strings.Fields(certificate), "")
+}
@@ -0,0 +1,509 @@
+package service
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ma314smith/signedxml"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"net/url"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+	"time"
+)
+
+var SAMLLogout samlLogout
+
+type samlLogout struct{}
+
+// SAML2单点注销：登录SP时记录断言的 SessionIndex 及对应的平台会话，
+// SP发起注销时校验 LogoutRequest 签名后注销平台会话并返回签名的 LogoutResponse（HTTP-POST），
+// 平台会话注销时（用户注销、强制下线等）通过后端通道向配置了单点注销地址的SP发送签名的 LogoutRequest
+
+const (
+	samlLogoutPath      = "/api/v1/sso/saml/slo" // 单点注销接口
+	samlLogoutTimeout   = 5 * time.Second        // 注销请求超时时间
+	samlLogoutRetries   = 3                      // 注销请求失败时的最大尝试次数
+	samlLogoutTTL       = 10 * time.Minute       // LogoutRequest 有效期
+	samlLogoutClockSkew = 5 * time.Minute        // 允许的时钟偏差
+	samlTimeFormat      = "2006-01-02T15:04:05Z"
+)
+
+const (
+	samlProtocolNamespace  = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlAssertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+	samlDSigNamespace      = "http://www.w3.org/2000/09/xmldsig#"
+	samlStatusSuccess      = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	samlNameIdUnspecified  = "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified"
+)
+
+// samlRedirectSigAlgs HTTP-Redirect绑定支持的签名算法
+var samlRedirectSigAlgs = map[string]x509.SignatureAlgorithm{
+	"http://www.w3.org/2000/09/xmldsig#rsa-sha1":          x509.SHA1WithRSA,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256":   x509.SHA256WithRSA,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512":   x509.SHA512WithRSA,
+	"http://www.w3.org/2001/04/xmldsig-more#ecdsa-sha256": x509.ECDSAWithSHA256,
+}
+
+// SAMLLogoutRequest SP发起的单点注销请求参数，支持HTTP-Redirect及HTTP-POST绑定
+type SAMLLogoutRequest struct {
+	SAMLRequest string `form:"SAMLRequest" binding:"required"`
+	RelayState  string `form:"RelayState"`
+	SigAlg      string `form:"SigAlg"`    // HTTP-Redirect绑定的签名算法
+	Signature   string `form:"Signature"` // HTTP-Redirect绑定的签名
+}
+
+// samlLogoutRequestData LogoutRequest数据绑定结构体
+type samlLogoutRequestData struct {
+	XMLName        xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:protocol LogoutRequest"`
+	ID             string   `xml:"ID,attr"`
+	Version        string   `xml:"Version,attr"`
+	IssueInstant   string   `xml:"IssueInstant,attr"`
+	NotOnOrAfter   string   `xml:"NotOnOrAfter,attr"`
+	Destination    string   `xml:"Destination,attr"`
+	Issuer         string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion Issuer"`
+	NameID         string   `xml:"urn:oasis:names:tc:SAML:2.0:assertion NameID"`
+	SessionIndexes []string `xml:"urn:oasis:names:tc:SAML:2.0:protocol SessionIndex"`
+}
+
+// samlSignature XML签名模板，DigestValue和SignatureValue由signedxml计算后填充
+type samlSignature struct {
+	Xmlns      string `xml:"xmlns:ds,attr"`
+	SignedInfo struct {
+		CanonicalizationMethod samlAlgorithm `xml:"ds:CanonicalizationMethod"`
+		SignatureMethod        samlAlgorithm `xml:"ds:SignatureMethod"`
+		Reference              struct {
+			URI        string          `xml:"URI,attr"`
+			Transforms []samlAlgorithm `xml:"ds:Transforms>ds:Transform"`
+			Digest     samlAlgorithm   `xml:"ds:DigestMethod"`
+			Value      string          `xml:"ds:DigestValue"`
+		} `xml:"ds:Reference"`
+	} `xml:"ds:SignedInfo"`
+	SignatureValue  string `xml:"ds:SignatureValue"`
+	X509Certificate string `xml:"ds:KeyInfo>ds:X509Data>ds:X509Certificate"`
+}
+type samlAlgorithm struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+// samlLogoutResponseXML IdP返回给SP的 LogoutResponse
+type samlLogoutResponseXML struct {
+	XMLName      xml.Name      `xml:"samlp:LogoutResponse"`
+	XmlnsP       string        `xml:"xmlns:samlp,attr"`
+	XmlnsA       string        `xml:"xmlns:saml,attr"`
+	ID           string        `xml:"ID,attr"`
+	Version      string        `xml:"Version,attr"`
+	IssueInstant string        `xml:"IssueInstant,attr"`
+	Destination  string        `xml:"Destination,attr"`
+	InResponseTo string        `xml:"InResponseTo,attr"`
+	Issuer       string        `xml:"saml:Issuer"`
+	Signature    samlSignature `xml:"ds:Signature"`
+	Status       struct {
+		StatusCode samlStatusCode `xml:"samlp:StatusCode"`
+	} `xml:"samlp:Status"`
+}
+type samlStatusCode struct {
+	Value string `xml:"Value,attr"`
+}
+
+// samlLogoutRequestXML IdP发送给SP的 LogoutRequest
+type samlLogoutRequestXML struct {
+	XMLName      xml.Name      `xml:"samlp:LogoutRequest"`
+	XmlnsP       string        `xml:"xmlns:samlp,attr"`
+	XmlnsA       string        `xml:"xmlns:saml,attr"`
+	ID           string        `xml:"ID,attr"`
+	Version      string        `xml:"Version,attr"`
+	IssueInstant string        `xml:"IssueInstant,attr"`
+	NotOnOrAfter string        `xml:"NotOnOrAfter,attr"`
+	Destination  string        `xml:"Destination,attr"`
+	Issuer       string        `xml:"saml:Issuer"`
+	Signature    samlSignature `xml:"ds:Signature"`
+	NameID       struct {
+		Format string `xml:"Format,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"saml:NameID"`
+	SessionIndex string `xml:"samlp:SessionIndex"`
+}
+
+// validateSloUrl 校验SP单点注销地址
+func validateSloUrl(sloUrl string) error {
+	if sloUrl == "" {
+		return nil
+	}
+	u, err := url.Parse(sloUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("单点注销地址格式错误，仅支持HTTP或HTTPS地址")
+	}
+	return nil
+}
+
+// SAMLSingleLogout SP发起的单点注销：校验 LogoutRequest 签名后注销 SessionIndex 对应的平台会话（平台会话登录过的其它应用一并通知注销），
+// 返回自动提交到SP单点注销地址的 LogoutResponse 表单；会话不存在或已注销时同样返回注销成功
+func (s *sso) SAMLSingleLogout(data *SAMLLogoutRequest, method, rawQuery, locale string) (html string, err error) {
+
+	requestXML, err := decodeSAMLMessage(data.SAMLRequest)
+	if err != nil {
+		return "", errors.New("SAMLRequest格式错误")
+	}
+	var request samlLogoutRequestData
+	if err := xml.Unmarshal(requestXML, &request); err != nil {
+		return "", errors.New("SAMLRequest格式错误")
+	}
+
+	// 获取SP应用
+	site, err := dao.Site.GetSamlSite(request.Issuer)
+	if err != nil {
+		recordSSOError(SSOProtocolSAML, nil, SSOErrorUnregistered, request.Issuer)
+		return "", errors.New("应用未注册或配置错误")
+	}
+	if site.SloUrl == "" {
+		recordSSOError(SSOProtocolSAML, site, SSOErrorInvalidRequest, "应用未配置单点注销地址")
+		return "", errors.New("应用未配置单点注销地址")
+	}
+
+	// 校验签名，HTTP-POST绑定时使用签名覆盖的内容，防止签名包装攻击
+	if method == http.MethodGet {
+		err = verifySAMLRedirectSignature(site, data, rawQuery)
+	} else {
+		requestXML, err = verifySAMLPostSignature(site, requestXML)
+		if err == nil {
+			request = samlLogoutRequestData{}
+			err = xml.Unmarshal(requestXML, &request)
+		}
+	}
+	if err != nil {
+		recordSSOError(SSOProtocolSAML, site, SSOErrorSignatureMismatch, err.Error())
+		return "", err
+	}
+
+	if err := validateSAMLLogoutRequest(&request); err != nil {
+		recordSSOError(SSOProtocolSAML, site, SSOErrorInvalidRequest, err.Error())
+		return "", err
+	}
+
+	// 注销 SessionIndex 对应的平台会话
+	for _, index := range request.SessionIndexes {
+		session, err := dao.SSO.ClaimSAMLSession(site.ID, strings.TrimSpace(index), strings.TrimSpace(request.NameID))
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if session.SessionID == "" {
+			continue
+		}
+		if err := Session.revoke(session.UserID, session.SessionID, SessionRevokeLogout); err != nil {
+			return "", err
+		}
+	}
+
+	// 生成签名的 LogoutResponse
+	responseXML, err := samlLogoutResponse(site.SloUrl, request.ID)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := samlPostFormTemplate.Execute(&b, SAMLResponse{
+		PostFormText: newPostFormText(locale),
+		URL:          site.SloUrl,
+		SAMLResponse: base64.StdEncoding.EncodeToString([]byte(responseXML)),
+		RelayState:   data.RelayState,
+	}); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// decodeSAMLMessage Base64解码，HTTP-Redirect绑定的消息需要解压缩
+func decodeSAMLMessage(message string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(message)
+	if err != nil {
+		return nil, err
+	}
+	if inflated, err := utils.Decompress(data); err == nil {
+		return inflated, nil
+	}
+	return data, nil
+}
+
+// validateSAMLLogoutRequest 校验 LogoutRequest 的版本、有效期及接收地址
+func validateSAMLLogoutRequest(request *samlLogoutRequestData) error {
+
+	if request.ID == "" || request.Version != "2.0" {
+		return errors.New("LogoutRequest格式错误")
+	}
+	if request.NameID == "" || len(request.SessionIndexes) == 0 {
+		return errors.New("LogoutRequest缺少NameID或SessionIndex")
+	}
+
+	now := time.Now()
+	issueInstant, err := time.Parse(time.RFC3339, request.IssueInstant)
+	if err != nil {
+		return errors.New("LogoutRequest签发时间格式错误")
+	}
+	if issueInstant.After(now.Add(samlLogoutClockSkew)) || issueInstant.Add(samlLogoutTTL).Before(now) {
+		return errors.New("LogoutRequest已过期")
+	}
+	if request.NotOnOrAfter != "" {
+		notOnOrAfter, err := time.Parse(time.RFC3339, request.NotOnOrAfter)
+		if err != nil || !now.Before(notOnOrAfter.Add(samlLogoutClockSkew)) {
+			return errors.New("LogoutRequest已过期")
+		}
+	}
+
+	if request.Destination != "" && request.Destination != config.SSO().ExternalUrl+samlLogoutPath {
+		return fmt.Errorf("LogoutRequest接收地址不匹配：%s", request.Destination)
+	}
+	return nil
+}
+
+// samlCertificates 解析站点的SP证书
+func samlCertificates(site *model.Site) []x509.Certificate {
+	var certificates []x509.Certificate
+	for _, item := range spCertificates(site) {
+		block, _ := pem.Decode([]byte(item))
+		if block == nil {
+			continue
+		}
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+			certificates = append(certificates, *cert)
+		}
+	}
+	return certificates
+}
+
+// verifySAMLRedirectSignature 校验HTTP-Redirect绑定的签名，签名内容为原始查询参数中的 SAMLRequest、RelayState 及 SigAlg
+func verifySAMLRedirectSignature(site *model.Site, data *SAMLLogoutRequest, rawQuery string) error {
+
+	if data.SigAlg == "" || data.Signature == "" {
+		return errors.New("LogoutRequest未签名")
+	}
+	algorithm, ok := samlRedirectSigAlgs[data.SigAlg]
+	if !ok {
+		return fmt.Errorf("不支持的签名算法：%s", data.SigAlg)
+	}
+	signature, err := base64.StdEncoding.DecodeString(data.Signature)
+	if err != nil {
+		return errors.New("签名格式错误")
+	}
+
+	params := make(map[string]string)
+	for _, item := range strings.Split(rawQuery, "&") {
+		if key, _, found := strings.Cut(item, "="); found {
+			if _, exists := params[key]; !exists {
+				params[key] = item
+			}
+		}
+	}
+	signed := []string{params["SAMLRequest"]}
+	if relayState, ok := params["RelayState"]; ok {
+		signed = append(signed, relayState)
+	}
+	signed = append(signed, params["SigAlg"])
+	octets := []byte(strings.Join(signed, "&"))
+
+	for _, cert := range samlCertificates(site) {
+		if cert.CheckSignature(algorithm, octets, signature) == nil {
+			return nil
+		}
+	}
+	return errors.New("LogoutRequest签名校验失败")
+}
+
+// verifySAMLPostSignature 校验HTTP-POST绑定的XML签名，仅接受SP证书的签名，返回签名覆盖的XML
+func verifySAMLPostSignature(site *model.Site, requestXML []byte) ([]byte, error) {
+
+	if !bytes.Contains(requestXML, []byte("SignatureValue")) {
+		return nil, errors.New("LogoutRequest未签名")
+	}
+	certificates := samlCertificates(site)
+	if len(certificates) == 0 {
+		return nil, errors.New("应用未配置SP证书")
+	}
+
+	validator, err := signedxml.NewValidator(string(requestXML))
+	if err != nil {
+		return nil, err
+	}
+	validator.Certificates = certificates
+	referenced, err := validator.ValidateReferences()
+	if err != nil {
+		return nil, errors.New("LogoutRequest签名校验失败")
+	}
+	if len(referenced) != 1 {
+		return nil, errors.New("LogoutRequest签名校验失败")
+	}
+	return []byte(referenced[0]), nil
+}
+
+// newSAMLSignature 生成签名模板，使用RSA-SHA256签名
+func newSAMLSignature(referenceId string, cert *x509.Certificate) samlSignature {
+	var signature samlSignature
+	signature.Xmlns = samlDSigNamespace
+	signature.SignedInfo.CanonicalizationMethod.Algorithm = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	signature.SignedInfo.SignatureMethod.Algorithm = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	signature.SignedInfo.Reference.URI = "#" + referenceId
+	signature.SignedInfo.Reference.Transforms = []samlAlgorithm{
+		{Algorithm: "http://www.w3.org/2000/09/xmldsig#enveloped-signature"},
+		{Algorithm: "http://www.w3.org/2001/10/xml-exc-c14n#"},
+	}
+	signature.SignedInfo.Reference.Digest.Algorithm = "http://www.w3.org/2001/04/xmlenc#sha256"
+	signature.X509Certificate = base64.StdEncoding.EncodeToString(cert.Raw)
+	return signature
+}
+
+// signSAMLMessage 使用IdP私钥对消息签名
+func signSAMLMessage(message interface{}) (string, error) {
+
+	privateKey, err := utils.LoadIdpPrivateKey()
+	if err != nil {
+		return "", err
+	}
+	data, err := xml.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	signer, err := signedxml.NewSigner(string(data))
+	if err != nil {
+		return "", err
+	}
+	return signer.Sign(privateKey)
+}
+
+// samlLogoutResponse 生成签名的 LogoutResponse
+func samlLogoutResponse(destination, inResponseTo string) (string, error) {
+
+	cert, err := utils.LoadIdpCertificate()
+	if err != nil {
+		return "", err
+	}
+
+	id := "_" + uuid.NewString()
+	response := samlLogoutResponseXML{
+		XmlnsP:       samlProtocolNamespace,
+		XmlnsA:       samlAssertionNamespace,
+		ID:           id,
+		Version:      "2.0",
+		IssueInstant: time.Now().UTC().Format(samlTimeFormat),
+		Destination:  destination,
+		InResponseTo: inResponseTo,
+		Issuer:       config.SSO().ExternalUrl,
+		Signature:    newSAMLSignature(id, cert),
+	}
+	response.Status.StatusCode.Value = samlStatusSuccess
+
+	return signSAMLMessage(response)
+}
+
+// samlLogoutRequest 生成签名的 LogoutRequest
+func samlLogoutRequest(destination string, session *model.SsoSAMLSession) (string, error) {
+
+	cert, err := utils.LoadIdpCertificate()
+	if err != nil {
+		return "", err
+	}
+
+	id := "_" + uuid.NewString()
+	now := time.Now().UTC()
+	request := samlLogoutRequestXML{
+		XmlnsP:       samlProtocolNamespace,
+		XmlnsA:       samlAssertionNamespace,
+		ID:           id,
+		Version:      "2.0",
+		IssueInstant: now.Format(samlTimeFormat),
+		NotOnOrAfter: now.Add(samlLogoutTTL).Format(samlTimeFormat),
+		Destination:  destination,
+		Issuer:       config.SSO().ExternalUrl,
+		Signature:    newSAMLSignature(id, cert),
+		SessionIndex: session.SessionIndex,
+	}
+	request.NameID.Format = samlNameIdUnspecified
+	request.NameID.Value = session.NameID
+
+	return signSAMLMessage(request)
+}
+
+// Notify IdP发起的单点注销：平台会话注销时，通过后端通道向会话登录过且配置了单点注销地址的SP发送签名的 LogoutRequest（HTTP-POST），
+// 通知失败不影响会话注销
+func (l *samlLogout) Notify(sessionId string) {
+
+	if sessionId == "" {
+		return
+	}
+
+	sessions, err := dao.SSO.ClaimSAMLLogoutSessions(sessionId)
+	if err != nil {
+		logger.Error("ERROR：获取SAML2单点注销会话失败，", err.Error())
+	}
+
+	for _, session := range sessions {
+		site := &model.Site{}
+		if err := global.MySQLClient.First(site, session.SiteID).Error; err != nil {
+			logger.Error(fmt.Sprintf("ERROR：获取SAML2单点注销应用（%d）失败，%s", session.SiteID, err.Error()))
+			continue
+		}
+		go l.send(site.SloUrl, session)
+	}
+}
+
+// send 发送注销请求，失败时重试
+func (l *samlLogout) send(sloUrl string, session *model.SsoSAMLSession) {
+	for i := 1; ; i++ {
+		err := l.request(sloUrl, session)
+		if err == nil {
+			return
+		}
+		if i >= samlLogoutRetries {
+			logger.Error(fmt.Sprintf("ERROR：SAML2单点注销请求（%s）发送失败，%s", sloUrl, err.Error()))
+			return
+		}
+		time.Sleep(time.Duration(i) * time.Second)
+	}
+}
+
+// request 以表单方式发送注销请求，返回2xx或3xx状态码时视为发送成功
+func (l *samlLogout) request(sloUrl string, session *model.SsoSAMLSession) error {
+
+	message, err := samlLogoutRequest(sloUrl, session)
+	if err != nil {
+		return err
+	}
+
+	form := url.Values{"SAMLRequest": {base64.StdEncoding.EncodeToString([]byte(message))}}
+	req, err := http.NewRequest(http.MethodPost, sloUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := &http.Client{
+		Timeout: samlLogoutTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
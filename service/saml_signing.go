@@ -0,0 +1,130 @@
+package service
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/LoginRadius/go-saml"
+	"github.com/ma314smith/signedxml"
+	"ops-api/model"
+	"ops-api/utils"
+	"regexp"
+	"strings"
+)
+
+// SAML2签名配置：站点可配置登录响应的签名算法、摘要算法及签名位置（断言、响应或两者），用于兼容仅支持SHA-1的旧SP
+// 及要求SHA-256以上算法的新SP；未配置时使用 rsa-sha256、sha256 并仅签名断言。同时开启断言加密时先加密断言再签名响应
+
+// 签名位置
+const (
+	SAMLSigningAssertion = "assertion" // 仅签名断言
+	SAMLSigningResponse  = "response"  // 仅签名响应
+	SAMLSigningBoth      = "both"      // 同时签名断言及响应
+)
+
+// samlSignatureAlgorithms 签名算法标识
+var samlSignatureAlgorithms = map[string]string{
+	"rsa-sha1":   saml.SignatureAlgorithmRSASHA1,
+	"rsa-sha256": saml.SignatureAlgorithmRSASHA256,
+	"rsa-sha384": "http://www.w3.org/2001/04/xmldsig-more#rsa-sha384",
+	"rsa-sha512": "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512",
+}
+
+// samlDigestAlgorithms 摘要算法标识
+var samlDigestAlgorithms = map[string]string{
+	"sha1":   saml.DigestAlgorithmSHA1,
+	"sha256": saml.DigestAlgorithmSHA256,
+	"sha384": "http://www.w3.org/2001/04/xmldsig-more#sha384",
+	"sha512": "http://www.w3.org/2001/04/xmlenc#sha512",
+}
+
+var (
+	samlResponseIdPattern         = regexp.MustCompile(`<samlp:Response[^>]*\sID="([^"]+)"`)
+	samlAssertionSignaturePattern = regexp.MustCompile(`(?s)<ds:Signature[\s>].*?</ds:Signature>`)
+)
+
+const samlIssuerEnd = "</saml:Issuer>"
+
+// validateSAMLSigning 校验SAML2签名配置
+func validateSAMLSigning(signatureAlgorithm, digestAlgorithm, level string) error {
+
+	if _, ok := samlSignatureAlgorithms[signatureAlgorithm]; signatureAlgorithm != "" && !ok {
+		return fmt.Errorf("不支持的签名算法：%s，可选值为：rsa-sha1、rsa-sha256、rsa-sha384、rsa-sha512", signatureAlgorithm)
+	}
+	if _, ok := samlDigestAlgorithms[digestAlgorithm]; digestAlgorithm != "" && !ok {
+		return fmt.Errorf("不支持的摘要算法：%s，可选值为：sha1、sha256、sha384、sha512", digestAlgorithm)
+	}
+	switch level {
+	case "", SAMLSigningAssertion, SAMLSigningResponse, SAMLSigningBoth:
+	default:
+		return fmt.Errorf("不支持的签名位置：%s，可选值为：%s、%s、%s", level, SAMLSigningAssertion, SAMLSigningResponse, SAMLSigningBoth)
+	}
+	return nil
+}
+
+// samlSigningAlgorithms 获取站点的签名算法及摘要算法标识，未配置时为RSA-SHA256及SHA256
+func samlSigningAlgorithms(site *model.Site) (signatureAlgorithm, digestAlgorithm string) {
+	signatureAlgorithm, ok := samlSignatureAlgorithms[site.SignatureAlg]
+	if !ok {
+		signatureAlgorithm = saml.SignatureAlgorithmRSASHA256
+	}
+	digestAlgorithm, ok = samlDigestAlgorithms[site.DigestAlg]
+	if !ok {
+		digestAlgorithm = saml.DigestAlgorithmSHA256
+	}
+	return signatureAlgorithm, digestAlgorithm
+}
+
+// samlSignAssertion 站点是否签名断言
+func samlSignAssertion(site *model.Site) bool {
+	return site.SigningLevel != SAMLSigningResponse
+}
+
+// samlSignResponse 站点是否签名响应
+func samlSignResponse(site *model.Site) bool {
+	return site.SigningLevel == SAMLSigningResponse || site.SigningLevel == SAMLSigningBoth
+}
+
+// removeSAMLAssertionSignature 移除断言签名（仅签名响应时使用），断言签名为封装签名，移除后断言内容不变
+func removeSAMLAssertionSignature(responseXML string) string {
+	return samlAssertionSignaturePattern.ReplaceAllLiteralString(responseXML, "")
+}
+
+// signSAMLResponse 签名SAMLResponse，签名位于响应的Issuer之后
+func signSAMLResponse(responseXML string, site *model.Site) (string, error) {
+
+	match := samlResponseIdPattern.FindStringSubmatch(responseXML)
+	if match == nil {
+		return "", errors.New("SAMLResponse中未找到响应ID")
+	}
+	index := strings.Index(responseXML, samlIssuerEnd)
+	if index < 0 {
+		return "", errors.New("SAMLResponse中未找到Issuer")
+	}
+	index += len(samlIssuerEnd)
+
+	cert, err := utils.LoadIdpCertificate()
+	if err != nil {
+		return "", err
+	}
+	privateKey, err := utils.LoadIdpPrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	signature := newSAMLSignature(match[1], cert)
+	signature.SignedInfo.SignatureMethod.Algorithm, signature.SignedInfo.Reference.Digest.Algorithm = samlSigningAlgorithms(site)
+	signatureXML, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"ds:Signature"`
+		samlSignature
+	}{samlSignature: signature})
+	if err != nil {
+		return "", err
+	}
+
+	signer, err := signedxml.NewSigner(responseXML[:index] + string(signatureXML) + responseXML[index:])
+	if err != nil {
+		return "", err
+	}
+	return signer.Sign(privateKey)
+}
@@ -0,0 +1,1149 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"net/http"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/check"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	ScimSchemaUser         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ScimSchemaGroup        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ScimSchemaListResponse = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	ScimSchemaPatchOp      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ScimSchemaError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ScimSchemaSPConfig     = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ScimSchemaEnterprise   = "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"
+
+	scimDefaultCount = 100 // 默认分页大小
+	scimMaxCount     = 200 // 最大分页大小
+)
+
+var SCIM scim
+
+type scim struct{}
+
+// ScimError SCIM协议错误信息
+type ScimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+	code     int
+}
+
+func (e *ScimError) Error() string { return e.Detail }
+
+// StatusCode 返回HTTP状态码
+func (e *ScimError) StatusCode() int { return e.code }
+
+// NewScimError 创建SCIM协议错误
+func NewScimError(code int, scimType, detail string) *ScimError {
+	return &ScimError{
+		Schemas:  []string{ScimSchemaError},
+		Status:   strconv.Itoa(code),
+		ScimType: scimType,
+		Detail:   detail,
+		code:     code,
+	}
+}
+
+// ScimClient SCIM请求来源，用于记录同步日志
+type ScimClient struct {
+	ClientIP  string
+	UserAgent string
+}
+
+type ScimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Created      string `json:"created"`
+	LastModified string `json:"lastModified"`
+	Location     string `json:"location"`
+}
+
+type ScimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type ScimMultiValue struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type ScimMember struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// ScimUser SCIM用户资源
+type ScimUser struct {
+	Schemas      []string         `json:"schemas"`
+	Id           string           `json:"id,omitempty"`
+	UserName     string           `json:"userName"`
+	Name         *ScimName        `json:"name,omitempty"`
+	DisplayName  string           `json:"displayName,omitempty"`
+	Emails       []ScimMultiValue `json:"emails,omitempty"`
+	PhoneNumbers []ScimMultiValue `json:"phoneNumbers,omitempty"`
+	Title        string           `json:"title,omitempty"`
+	Active       *bool            `json:"active,omitempty"`
+	Language     string           `json:"preferredLanguage,omitempty"`
+	Password     string           `json:"password,omitempty"`
+	Groups       []ScimMember     `json:"groups,omitempty"`
+	Enterprise   *ScimEnterprise  `json:"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User,omitempty"`
+	Meta         *ScimMeta        `json:"meta,omitempty"`
+}
+
+// ScimEnterprise SCIM企业用户扩展属性
+type ScimEnterprise struct {
+	Department string `json:"department,omitempty"`
+}
+
+// ScimGroup SCIM分组资源
+type ScimGroup struct {
+	Schemas     []string     `json:"schemas"`
+	Id          string       `json:"id,omitempty"`
+	DisplayName string       `json:"displayName"`
+	Members     []ScimMember `json:"members"`
+	Meta        *ScimMeta    `json:"meta,omitempty"`
+}
+
+// ScimListResponse SCIM列表响应
+type ScimListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// ScimPatchOp SCIM PATCH请求
+type ScimPatchOp struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []ScimPatchOperation `json:"Operations"`
+}
+
+type ScimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+// scimUserAttributes SCIM用户属性（小写）与数据库字段映射
+var scimUserAttributes = map[string]string{
+	"username":           "username",
+	"displayname":        "name",
+	"name.formatted":     "name",
+	"emails":             "email",
+	"emails.value":       "email",
+	"phonenumbers":       "phone_number",
+	"phonenumbers.value": "phone_number",
+	"active":             "is_active",
+	"password":           "password",
+	"preferredlanguage":  "language",
+	"title":              "title",
+	"department":         "department",
+}
+
+// scimGroupAttributes SCIM分组属性（小写）与数据库字段映射
+var scimGroupAttributes = map[string]string{
+	"displayname": "name",
+}
+
+var (
+	// 仅支持 attr eq "value" 形式的过滤条件
+	scimFilterRegexp = regexp.MustCompile(`^\s*([A-Za-z][\w.]*)\s+(?i:eq)\s+(?:"((?:[^"\\]|\\.)*)"|(true|false))\s*$`)
+	// 匹配PATCH路径中的值过滤，例如 emails[type eq "work"].value
+	scimValuePathRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+	// 匹配成员过滤，例如 members[value eq "1"]
+	scimMemberPathRegexp = regexp.MustCompile(`^(?i:members)\[\s*(?i:value)\s+(?i:eq)\s+"([^"]*)"\s*\]$`)
+)
+
+// ServiceProviderConfig 返回SCIM服务能力说明
+func (s *scim) ServiceProviderConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":        []string{ScimSchemaSPConfig},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimMaxCount},
+		"changePassword": map[string]bool{"supported": true},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{
+			{
+				"type":        "oauthbearertoken",
+				"name":        "Bearer Token",
+				"description": "使用系统配置中的SCIM令牌进行认证",
+				"primary":     true,
+			},
+		},
+	}
+}
+
+// ListUsers 获取用户列表
+func (s *scim) ListUsers(filter string, startIndex, count int) (*ScimListResponse, error) {
+
+	f, err := parseScimFilter(filter, scimUserAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex, count = scimPagination(startIndex, count)
+	users, total, err := dao.SCIM.GetUserList(f, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*ScimUser, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, toScimUser(user))
+	}
+
+	return &ScimListResponse{
+		Schemas:      []string{ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+// GetUser 获取单个用户
+func (s *scim) GetUser(id string) (*ScimUser, error) {
+	user, err := s.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	return toScimUser(user), nil
+}
+
+// CreateUser 创建用户
+func (s *scim) CreateUser(client *ScimClient, data *ScimUser) (result *ScimUser, err error) {
+
+	record := &model.LogSCIM{ResourceType: "User", Operation: "create", ResourceName: data.UserName}
+	defer func() { s.record(client, record, err) }()
+
+	if strings.TrimSpace(data.UserName) == "" {
+		return nil, NewScimError(http.StatusBadRequest, "invalidValue", "userName不能为空")
+	}
+
+	// 判断用户名是否已存在
+	exist, err := dao.SCIM.IsUsernameExist(data.UserName, 0)
+	if err != nil {
+		return nil, err
+	}
+	if exist {
+		return nil, NewScimError(http.StatusConflict, "uniqueness", "用户名已存在")
+	}
+
+	// 未同步密码时生成随机密码，用户需通过密码重置功能设置密码
+	password := data.Password
+	if password != "" {
+		if err := check.PasswordCheck(password); err != nil {
+			return nil, NewScimError(http.StatusBadRequest, "invalidValue", err.Error())
+		}
+	} else {
+		password = utils.GenerateRandomString(32)
+	}
+
+	// 标准化手机号
+	phoneNumber := primaryValue(data.PhoneNumbers)
+	var phoneNational, phoneDisplay string
+	if err := normalizeUserPhone(&phoneNumber, &phoneNational, &phoneDisplay); err != nil {
+		return nil, NewScimError(http.StatusBadRequest, "invalidValue", err.Error())
+	}
+
+	// 获取密码有效期
+	passwordExpiredAtDays := config.GetInt("passwordExpireDays")
+	passwordExpiredAt := time.Now().AddDate(0, 0, passwordExpiredAtDays)
+
+	active := true
+	if data.Active != nil {
+		active = *data.Active
+	}
+
+	user, err := dao.User.AddUser(&model.AuthUser{
+		Name:              scimDisplayName(data),
+		Username:          data.UserName,
+		Password:          password,
+		PhoneNumber:       phoneNumber,
+		PhoneNational:     phoneNational,
+		PhoneDisplay:      phoneDisplay,
+		IsActive:          active,
+		Email:             primaryValue(data.Emails),
+		UserFrom:          "SCIM",
+		Language:          data.Language,
+		Department:        scimDepartment(data),
+		Title:             data.Title,
+		RequiredActions:   RequiredAction.Initial(),
+		PasswordExpiredAt: &passwordExpiredAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	record.ResourceId = strconv.Itoa(int(user.ID))
+
+	// 根据自动分配规则分配分组及站点
+	ProvisionRule.ApplyToUser(user)
+
+	// 根据用户属性计算动态分组
+	DynamicGroup.SyncUser(user)
+
+	return toScimUser(user), nil
+}
+
+// ReplaceUser 全量更新用户（PUT）
+func (s *scim) ReplaceUser(client *ScimClient, id string, data *ScimUser) (result *ScimUser, err error) {
+
+	record := &model.LogSCIM{ResourceType: "User", Operation: "update", ResourceId: id, ResourceName: data.UserName}
+	defer func() { s.record(client, record, err) }()
+
+	user, err := s.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{
+		"username":     data.UserName,
+		"name":         scimDisplayName(data),
+		"email":        primaryValue(data.Emails),
+		"phone_number": primaryValue(data.PhoneNumbers),
+		"language":     data.Language,
+		"department":   scimDepartment(data),
+		"title":        data.Title,
+	}
+	if data.Active != nil {
+		fields["is_active"] = *data.Active
+	}
+	if data.Password != "" {
+		fields["password"] = data.Password
+	}
+
+	if err := s.saveUser(user, fields); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(id)
+}
+
+// PatchUser 部分更新用户（PATCH）
+func (s *scim) PatchUser(client *ScimClient, id string, data *ScimPatchOp) (result *ScimUser, err error) {
+
+	record := &model.LogSCIM{ResourceType: "User", Operation: "update", ResourceId: id}
+	defer func() { s.record(client, record, err) }()
+
+	user, err := s.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	record.ResourceName = user.Username
+
+	fields := make(map[string]interface{})
+	for _, operation := range data.Operations {
+		op := strings.ToLower(operation.Op)
+		switch op {
+		case "add", "replace":
+			if operation.Path == "" {
+				// 未指定路径时，value为包含多个属性的对象
+				var values map[string]json.RawMessage
+				if err := json.Unmarshal(operation.Value, &values); err != nil {
+					return nil, NewScimError(http.StatusBadRequest, "invalidValue", "value格式错误")
+				}
+				for key, value := range values {
+					if strings.EqualFold(key, "name") {
+						var name ScimName
+						if err := json.Unmarshal(value, &name); err == nil && name.Formatted != "" {
+							fields["name"] = name.Formatted
+						}
+						continue
+					}
+					// 企业用户扩展属性，value为包含扩展属性的对象
+					if strings.EqualFold(key, ScimSchemaEnterprise) {
+						var extension map[string]json.RawMessage
+						if err := json.Unmarshal(value, &extension); err != nil {
+							return nil, NewScimError(http.StatusBadRequest, "invalidValue", "企业用户扩展属性格式错误")
+						}
+						for name, item := range extension {
+							if err := setScimUserField(fields, name, item); err != nil {
+								return nil, err
+							}
+						}
+						continue
+					}
+					if err := setScimUserField(fields, key, value); err != nil {
+						return nil, err
+					}
+				}
+			} else if err := setScimUserField(fields, operation.Path, operation.Value); err != nil {
+				return nil, err
+			}
+		case "remove":
+			column, ok := scimUserAttributes[normalizeScimPath(operation.Path)]
+			if !ok {
+				continue
+			}
+			switch column {
+			case "username", "password":
+				return nil, NewScimError(http.StatusBadRequest, "mutability", fmt.Sprintf("%s不允许删除", operation.Path))
+			case "is_active":
+				fields[column] = false
+			default:
+				fields[column] = ""
+			}
+		default:
+			return nil, NewScimError(http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("不支持的操作：%s", operation.Op))
+		}
+	}
+
+	if err := s.saveUser(user, fields); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(id)
+}
+
+// DeleteUser 删除用户
+func (s *scim) DeleteUser(client *ScimClient, id string) (err error) {
+
+	record := &model.LogSCIM{ResourceType: "User", Operation: "delete", ResourceId: id}
+	defer func() { s.record(client, record, err) }()
+
+	user, err := s.getUser(id)
+	if err != nil {
+		return err
+	}
+	record.ResourceName = user.Username
+
+	if scimPrivileged(user) {
+		return NewScimError(http.StatusBadRequest, "mutability", "超级管理员、应急账号及角色分组成员不允许通过SCIM删除")
+	}
+
+	return User.DeleteUser(int(user.ID))
+}
+
+// ListGroups 获取分组列表
+func (s *scim) ListGroups(filter string, startIndex, count int) (*ScimListResponse, error) {
+
+	f, err := parseScimFilter(filter, scimGroupAttributes)
+	if err != nil {
+		return nil, err
+	}
+
+	startIndex, count = scimPagination(startIndex, count)
+	groups, total, err := dao.SCIM.GetGroupList(f, startIndex-1, count)
+	if err != nil {
+		return nil, err
+	}
+
+	resources := make([]*ScimGroup, 0, len(groups))
+	for _, group := range groups {
+		resources = append(resources, toScimGroup(group))
+	}
+
+	return &ScimListResponse{
+		Schemas:      []string{ScimSchemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+// GetGroup 获取单个分组
+func (s *scim) GetGroup(id string) (*ScimGroup, error) {
+	group, err := s.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	return toScimGroup(group), nil
+}
+
+// CreateGroup 创建分组，通过SCIM创建的均为普通分组
+func (s *scim) CreateGroup(client *ScimClient, data *ScimGroup) (result *ScimGroup, err error) {
+
+	record := &model.LogSCIM{ResourceType: "Group", Operation: "create", ResourceName: data.DisplayName}
+	defer func() { s.record(client, record, err) }()
+
+	if strings.TrimSpace(data.DisplayName) == "" {
+		return nil, NewScimError(http.StatusBadRequest, "invalidValue", "displayName不能为空")
+	}
+
+	exist, err := dao.SCIM.IsGroupNameExist(data.DisplayName, 0)
+	if err != nil {
+		return nil, err
+	}
+	if exist {
+		return nil, NewScimError(http.StatusConflict, "uniqueness", "分组名称已存在")
+	}
+
+	group, err := dao.Group.AddGroup(&model.AuthGroup{Name: data.DisplayName})
+	if err != nil {
+		return nil, err
+	}
+	record.ResourceId = strconv.Itoa(int(group.ID))
+
+	if len(data.Members) > 0 {
+		ids, err := scimMemberIds(data.Members)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.saveGroupMembers(group, ids); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetGroup(record.ResourceId)
+}
+
+// ReplaceGroup 全量更新分组（PUT）
+func (s *scim) ReplaceGroup(client *ScimClient, id string, data *ScimGroup) (result *ScimGroup, err error) {
+
+	record := &model.LogSCIM{ResourceType: "Group", Operation: "update", ResourceId: id, ResourceName: data.DisplayName}
+	defer func() { s.record(client, record, err) }()
+
+	group, err := s.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	if group.IsRoleGroup {
+		return nil, NewScimError(http.StatusBadRequest, "mutability", "角色分组不允许通过SCIM修改")
+	}
+
+	if err := s.renameGroup(group, data.DisplayName); err != nil {
+		return nil, err
+	}
+
+	ids, err := scimMemberIds(data.Members)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveGroupMembers(group, ids); err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(id)
+}
+
+// PatchGroup 部分更新分组（PATCH），主要用于成员的增加与移除
+func (s *scim) PatchGroup(client *ScimClient, id string, data *ScimPatchOp) (result *ScimGroup, err error) {
+
+	record := &model.LogSCIM{ResourceType: "Group", Operation: "update", ResourceId: id}
+	defer func() { s.record(client, record, err) }()
+
+	group, err := s.getGroup(id)
+	if err != nil {
+		return nil, err
+	}
+	record.ResourceName = group.Name
+	if group.IsRoleGroup {
+		return nil, NewScimError(http.StatusBadRequest, "mutability", "角色分组不允许通过SCIM修改")
+	}
+
+	// 当前成员
+	members := make([]uint, 0, len(group.Users))
+	for _, user := range group.Users {
+		members = append(members, user.ID)
+	}
+	displayName := group.Name
+
+	for _, operation := range data.Operations {
+		op := strings.ToLower(operation.Op)
+		path := strings.TrimSpace(operation.Path)
+
+		// 移除指定成员，例如 members[value eq "1"]
+		if match := scimMemberPathRegexp.FindStringSubmatch(path); match != nil {
+			if op != "remove" {
+				return nil, NewScimError(http.StatusBadRequest, "invalidPath", "不支持的路径："+path)
+			}
+			ids, err := scimMemberIds([]ScimMember{{Value: match[1]}})
+			if err != nil {
+				return nil, err
+			}
+			members = removeIds(members, ids)
+			continue
+		}
+
+		switch {
+		case path == "":
+			if op == "remove" {
+				return nil, NewScimError(http.StatusBadRequest, "noTarget", "remove操作需要指定path")
+			}
+			var value ScimGroup
+			if err := json.Unmarshal(operation.Value, &value); err != nil {
+				return nil, NewScimError(http.StatusBadRequest, "invalidValue", "value格式错误")
+			}
+			if value.DisplayName != "" {
+				displayName = value.DisplayName
+			}
+			if value.Members != nil {
+				ids, err := scimMemberIds(value.Members)
+				if err != nil {
+					return nil, err
+				}
+				if op == "add" {
+					members = appendIds(members, ids)
+				} else {
+					members = ids
+				}
+			}
+		case strings.EqualFold(path, "displayName"):
+			if op == "remove" {
+				return nil, NewScimError(http.StatusBadRequest, "mutability", "displayName不允许删除")
+			}
+			if err := json.Unmarshal(operation.Value, &displayName); err != nil {
+				return nil, NewScimError(http.StatusBadRequest, "invalidValue", "displayName格式错误")
+			}
+		case strings.EqualFold(path, "members"):
+			var value []ScimMember
+			if len(operation.Value) > 0 {
+				if err := json.Unmarshal(operation.Value, &value); err != nil {
+					return nil, NewScimError(http.StatusBadRequest, "invalidValue", "members格式错误")
+				}
+			}
+			ids, err := scimMemberIds(value)
+			if err != nil {
+				return nil, err
+			}
+			switch op {
+			case "add":
+				members = appendIds(members, ids)
+			case "replace":
+				members = ids
+			case "remove":
+				// 未指定成员时清空所有成员
+				if len(value) == 0 {
+					members = []uint{}
+				} else {
+					members = removeIds(members, ids)
+				}
+			default:
+				return nil, NewScimError(http.StatusBadRequest, "invalidSyntax", fmt.Sprintf("不支持的操作：%s", operation.Op))
+			}
+		default:
+			return nil, NewScimError(http.StatusBadRequest, "invalidPath", "不支持的路径："+path)
+		}
+	}
+
+	if err := s.renameGroup(group, displayName); err != nil {
+		return nil, err
+	}
+	if err := s.saveGroupMembers(group, members); err != nil {
+		return nil, err
+	}
+
+	return s.GetGroup(id)
+}
+
+// DeleteGroup 删除分组，角色分组关联系统权限，不允许通过SCIM删除
+func (s *scim) DeleteGroup(client *ScimClient, id string) (err error) {
+
+	record := &model.LogSCIM{ResourceType: "Group", Operation: "delete", ResourceId: id}
+	defer func() { s.record(client, record, err) }()
+
+	group, err := s.getGroup(id)
+	if err != nil {
+		return err
+	}
+	record.ResourceName = group.Name
+
+	if group.IsRoleGroup {
+		return NewScimError(http.StatusBadRequest, "mutability", "角色分组不允许通过SCIM删除")
+	}
+
+	return Group.DeleteGroup(int(group.ID))
+}
+
+// getUser 根据SCIM资源ID获取用户
+func (s *scim) getUser(id string) (*model.AuthUser, error) {
+	userId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, NewScimError(http.StatusNotFound, "", "用户不存在")
+	}
+	user, err := dao.SCIM.GetUser(uint(userId))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, NewScimError(http.StatusNotFound, "", "用户不存在")
+	}
+	return user, err
+}
+
+// getGroup 根据SCIM资源ID获取分组
+func (s *scim) getGroup(id string) (*model.AuthGroup, error) {
+	groupId, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return nil, NewScimError(http.StatusNotFound, "", "分组不存在")
+	}
+	group, err := dao.SCIM.GetGroup(uint(groupId))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, NewScimError(http.StatusNotFound, "", "分组不存在")
+	}
+	return group, err
+}
+
+// saveUser 保存用户字段，用户名变更时同步更新CasBin策略表
+func (s *scim) saveUser(user *model.AuthUser, fields map[string]interface{}) error {
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	if scimPrivileged(user) {
+		return NewScimError(http.StatusBadRequest, "mutability", "超级管理员、应急账号及角色分组成员不允许通过SCIM修改")
+	}
+
+	// 用户名校验
+	username, renamed := fields["username"].(string)
+	if renamed {
+		if strings.TrimSpace(username) == "" {
+			return NewScimError(http.StatusBadRequest, "invalidValue", "userName不能为空")
+		}
+		renamed = username != user.Username
+	}
+	if renamed {
+		exist, err := dao.SCIM.IsUsernameExist(username, user.ID)
+		if err != nil {
+			return err
+		}
+		if exist {
+			return NewScimError(http.StatusConflict, "uniqueness", "用户名已存在")
+		}
+	}
+
+	// 标准化手机号
+	if _, ok := fields["phone_number"]; ok {
+		phoneNumber, _ := fields["phone_number"].(string)
+		var phoneNational, phoneDisplay string
+		if err := normalizeUserPhone(&phoneNumber, &phoneNational, &phoneDisplay); err != nil {
+			return NewScimError(http.StatusBadRequest, "invalidValue", err.Error())
+		}
+		fields["phone_number"] = phoneNumber
+		fields["phone_national"] = phoneNational
+		fields["phone_display"] = phoneDisplay
+	}
+
+	// 密码校验并加密，同时刷新密码有效期
+	if password, ok := fields["password"].(string); ok {
+		if err := check.PasswordCheck(password); err != nil {
+			return NewScimError(http.StatusBadRequest, "invalidValue", err.Error())
+		}
+		cipherText, err := utils.Encrypt(password)
+		if err != nil {
+			return err
+		}
+		passwordExpiredAtDays := config.GetInt("passwordExpireDays")
+		fields["password"] = cipherText
+		fields["password_expired_at"] = time.Now().AddDate(0, 0, passwordExpiredAtDays)
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	oldUsername := user.Username
+	if err := dao.SCIM.UpdateUser(tx, user, fields); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if renamed {
+		if err := dao.CasBin.UpdateUserName(tx, oldUsername, username); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 清除用户信息缓存
+	dao.User.ClearUserInfoCache(user.ID)
+
+	// 重新加载策略
+	if renamed {
+		if err := global.CasBinServer.LoadPolicy(); err != nil {
+			return err
+		}
+	}
+
+	// 根据用户属性重新计算动态分组
+	DynamicGroup.SyncUser(user)
+
+	return nil
+}
+
+// scimPrivileged 判断是否为特权用户（超级管理员、应急账号或角色分组成员），特权用户关联系统权限，不允许通过SCIM修改或删除
+func scimPrivileged(user *model.AuthUser) bool {
+	if user.ID == 1 || user.Username == "admin" || user.BreakGlass {
+		return true
+	}
+	for _, group := range user.Groups {
+		if group.IsRoleGroup {
+			return true
+		}
+	}
+	return false
+}
+
+// renameGroup 修改分组名称
+func (s *scim) renameGroup(group *model.AuthGroup, name string) error {
+
+	if name == group.Name {
+		return nil
+	}
+	if strings.TrimSpace(name) == "" {
+		return NewScimError(http.StatusBadRequest, "invalidValue", "displayName不能为空")
+	}
+
+	exist, err := dao.SCIM.IsGroupNameExist(name, group.ID)
+	if err != nil {
+		return err
+	}
+	if exist {
+		return NewScimError(http.StatusConflict, "uniqueness", "分组名称已存在")
+	}
+
+	if _, err := Group.UpdateGroup(&GroupUpdate{ID: group.ID, Name: name}); err != nil {
+		return err
+	}
+	group.Name = name
+
+	return nil
+}
+
+// saveGroupMembers 保存分组成员，角色分组关联系统权限，不允许通过SCIM修改成员
+func (s *scim) saveGroupMembers(group *model.AuthGroup, ids []uint) error {
+
+	if group.IsRoleGroup {
+		return NewScimError(http.StatusBadRequest, "mutability", "角色分组不允许通过SCIM修改")
+	}
+	if group.DynamicRule != "" {
+		return NewScimError(http.StatusBadRequest, "mutability", "动态分组的成员由规则自动计算，不允许修改")
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	var users []model.AuthUser
+	if len(ids) > 0 {
+		if err := tx.Find(&users, ids).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+		if len(users) != len(ids) {
+			tx.Rollback()
+			return NewScimError(http.StatusBadRequest, "invalidValue", "分组成员中存在不存在的用户")
+		}
+	}
+
+	if len(users) == 0 {
+		if err := dao.Group.ClearGroupUser(tx, group); err != nil {
+			tx.Rollback()
+			return err
+		}
+	} else if _, err := dao.Group.UpdateGroupUser(tx, group, users); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// record 记录SCIM同步日志
+func (s *scim) record(client *ScimClient, record *model.LogSCIM, err error) {
+
+	record.ClientIP = client.ClientIP
+	record.UserAgent = client.UserAgent
+	record.Status = 1
+	if err != nil {
+		record.Status = 2
+		record.FailedReason = err.Error()
+	}
+
+	if err := dao.Audit.AddSCIMRecord(record); err != nil {
+		logger.Warn("保存SCIM同步日志失败: %v", err)
+	}
+}
+
+// setScimUserField 将SCIM属性值写入待更新字段，忽略不支持的属性
+func setScimUserField(fields map[string]interface{}, path string, raw json.RawMessage) error {
+
+	column, ok := scimUserAttributes[normalizeScimPath(path)]
+	if !ok {
+		return nil
+	}
+
+	switch column {
+	case "is_active":
+		// 部分客户端（如Azure AD）会以字符串形式传递布尔值
+		var active bool
+		if err := json.Unmarshal(raw, &active); err != nil {
+			var str string
+			if err := json.Unmarshal(raw, &str); err != nil {
+				return NewScimError(http.StatusBadRequest, "invalidValue", "active格式错误")
+			}
+			active = strings.EqualFold(str, "true")
+		}
+		fields[column] = active
+	case "email", "phone_number":
+		value, err := scimMultiValue(raw)
+		if err != nil {
+			return NewScimError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("%s格式错误", path))
+		}
+		fields[column] = value
+	default:
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return NewScimError(http.StatusBadRequest, "invalidValue", fmt.Sprintf("%s格式错误", path))
+		}
+		fields[column] = value
+	}
+
+	return nil
+}
+
+// scimMultiValue 解析多值属性，支持字符串、对象以及对象数组
+func scimMultiValue(raw json.RawMessage) (string, error) {
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str, nil
+	}
+	var values []ScimMultiValue
+	if err := json.Unmarshal(raw, &values); err == nil {
+		return primaryValue(values), nil
+	}
+	var value ScimMultiValue
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", err
+	}
+	return value.Value, nil
+}
+
+// normalizeScimPath 去除路径中的值过滤与Schema前缀，并转换为小写
+func normalizeScimPath(path string) string {
+	path = scimValuePathRegexp.ReplaceAllString(path, "")
+	if strings.HasPrefix(path, ScimSchemaUser+":") {
+		path = strings.TrimPrefix(path, ScimSchemaUser+":")
+	}
+	if strings.HasPrefix(path, ScimSchemaEnterprise+":") {
+		path = strings.TrimPrefix(path, ScimSchemaEnterprise+":")
+	}
+	return strings.ToLower(strings.TrimSpace(path))
+}
+
+// parseScimFilter 解析过滤条件
+func parseScimFilter(filter string, attributes map[string]string) (*dao.ScimFilter, error) {
+
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+
+	match := scimFilterRegexp.FindStringSubmatch(filter)
+	if match == nil {
+		return nil, NewScimError(http.StatusBadRequest, "invalidFilter", "仅支持 attribute eq \"value\" 形式的过滤条件")
+	}
+
+	column, ok := attributes[normalizeScimPath(match[1])]
+	if !ok || column == "password" {
+		return nil, NewScimError(http.StatusBadRequest, "invalidFilter", "不支持的过滤属性："+match[1])
+	}
+
+	if column == "is_active" {
+		value := match[3]
+		if value == "" {
+			value = match[2]
+		}
+		return &dao.ScimFilter{Column: column, Value: strings.EqualFold(value, "true")}, nil
+	}
+
+	return &dao.ScimFilter{Column: column, Value: strings.ReplaceAll(match[2], `\"`, `"`)}, nil
+}
+
+// scimPagination 处理分页参数，startIndex从1开始
+func scimPagination(startIndex, count int) (int, int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = scimDefaultCount
+	}
+	if count > scimMaxCount {
+		count = scimMaxCount
+	}
+	return startIndex, count
+}
+
+// scimMemberIds 将成员列表转换为用户ID列表
+func scimMemberIds(members []ScimMember) ([]uint, error) {
+	ids := make([]uint, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member.Value, 10, 64)
+		if err != nil {
+			return nil, NewScimError(http.StatusBadRequest, "invalidValue", "成员ID格式错误："+member.Value)
+		}
+		ids = appendIds(ids, []uint{uint(id)})
+	}
+	return ids, nil
+}
+
+// appendIds 合并ID列表并去重
+func appendIds(ids, values []uint) []uint {
+	for _, value := range values {
+		if !containsId(ids, value) {
+			ids = append(ids, value)
+		}
+	}
+	return ids
+}
+
+// removeIds 从ID列表中移除指定ID
+func removeIds(ids, values []uint) []uint {
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !containsId(values, id) {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func containsId(ids []uint, id uint) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
+
+// scimDepartment 获取企业用户扩展属性中的部门
+func scimDepartment(data *ScimUser) string {
+	if data.Enterprise == nil {
+		return ""
+	}
+	return data.Enterprise.Department
+}
+
+// primaryValue 获取多值属性中的主值，未指定主值时取第一个
+func primaryValue(values []ScimMultiValue) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// scimDisplayName 获取用户显示名称
+func scimDisplayName(data *ScimUser) string {
+	if data.DisplayName != "" {
+		return data.DisplayName
+	}
+	if data.Name != nil {
+		if data.Name.Formatted != "" {
+			return data.Name.Formatted
+		}
+		if name := strings.TrimSpace(data.Name.FamilyName + data.Name.GivenName); name != "" {
+			return name
+		}
+	}
+	return data.UserName
+}
+
+// scimLocation 获取资源地址
+func scimLocation(resourceType string, id uint) string {
+	externalUrl := config.GetString("externalUrl")
+	return fmt.Sprintf("%s/scim/v2/%ss/%d", strings.TrimRight(externalUrl, "/"), resourceType, id)
+}
+
+// toScimUser 将用户信息转换为SCIM资源
+func toScimUser(user *model.AuthUser) *ScimUser {
+
+	active := user.IsActive
+	result := &ScimUser{
+		Schemas:     []string{ScimSchemaUser},
+		Id:          strconv.Itoa(int(user.ID)),
+		UserName:    user.Username,
+		Name:        &ScimName{Formatted: user.Name},
+		DisplayName: user.Name,
+		Active:      &active,
+		Language:    user.Language,
+		Meta: &ScimMeta{
+			ResourceType: "User",
+			Created:      user.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: user.UpdatedAt.UTC().Format(time.RFC3339),
+			Location:     scimLocation("User", user.ID),
+		},
+	}
+	if user.Email != "" {
+		result.Emails = []ScimMultiValue{{Value: user.Email, Type: "work", Primary: true}}
+	}
+	if user.PhoneNumber != "" {
+		result.PhoneNumbers = []ScimMultiValue{{Value: user.PhoneNumber, Type: "mobile", Primary: true}}
+	}
+	result.Title = user.Title
+	if user.Department != "" {
+		result.Schemas = append(result.Schemas, ScimSchemaEnterprise)
+		result.Enterprise = &ScimEnterprise{Department: user.Department}
+	}
+	for _, group := range user.Groups {
+		result.Groups = append(result.Groups, ScimMember{
+			Value:   strconv.Itoa(int(group.ID)),
+			Display: group.Name,
+			Ref:     scimLocation("Group", group.ID),
+		})
+	}
+
+	return result
+}
+
+// toScimGroup 将分组信息转换为SCIM资源
+func toScimGroup(group *model.AuthGroup) *ScimGroup {
+
+	result := &ScimGroup{
+		Schemas:     []string{ScimSchemaGroup},
+		Id:          strconv.Itoa(int(group.ID)),
+		DisplayName: group.Name,
+		Members:     make([]ScimMember, 0, len(group.Users)),
+		Meta: &ScimMeta{
+			ResourceType: "Group",
+			Created:      group.CreatedAt.UTC().Format(time.RFC3339),
+			LastModified: group.UpdatedAt.UTC().Format(time.RFC3339),
+			Location:     scimLocation("Group", group.ID),
+		},
+	}
+	for _, user := range group.Users {
+		result.Members = append(result.Members, ScimMember{
+			Value:   strconv.Itoa(int(user.ID)),
+			Display: user.Username,
+			Ref:     scimLocation("User", user.ID),
+		})
+	}
+
+	return result
+}
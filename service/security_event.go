@@ -0,0 +1,271 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"html"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/notify"
+	"strings"
+	"time"
+)
+
+var SecurityEvent securityEvent
+
+type securityEvent struct{}
+
+// 安全事件类型
+const (
+	SecurityEventRoleGranted = "role_granted" // 授予角色
+	SecurityEventMFAReset    = "mfa_reset"    // 重置MFA
+
+	SecurityEventBreakGlassChanged  = "break_glass_changed"  // 设置或取消应急账号
+	SecurityEventBreakGlassLogin    = "break_glass_login"    // 应急账号登录
+	SecurityEventBreakGlassDenied   = "break_glass_denied"   // 应急账号登录被拒绝
+	SecurityEventBreakGlassDisabled = "break_glass_disabled" // 应急账号超过使用时间窗口被禁用
+
+	SecurityEventMaintenanceChanged = "maintenance_changed" // 开启或关闭维护模式
+
+	SecurityEventUserErased = "user_erased" // 擦除用户个人数据
+
+	SecurityEventSigningKeyChanged = "signing_key_changed" // 启用或停用JWT签名密钥
+
+	SecurityEventAuditorChanged = "auditor_changed" // 授予或撤销审计员
+
+	SecurityEventAccountSecured = "account_secured" // 用户通过登录提醒邮件保护账号
+)
+
+// securityEventNames 安全事件名称
+var securityEventNames = map[string]string{
+	SecurityEventRoleGranted:        "授予角色",
+	SecurityEventMFAReset:           "重置MFA",
+	SecurityEventBreakGlassChanged:  "应急账号变更",
+	SecurityEventBreakGlassLogin:    "应急账号登录",
+	SecurityEventBreakGlassDenied:   "应急账号登录被拒绝",
+	SecurityEventBreakGlassDisabled: "应急账号已自动禁用",
+	SecurityEventMaintenanceChanged: "维护模式变更",
+	SecurityEventUserErased:         "用户个人数据已擦除",
+	SecurityEventSigningKeyChanged:  "签名密钥变更",
+	SecurityEventAuditorChanged:     "审计员变更",
+	SecurityEventAccountSecured:     "用户保护账号",
+}
+
+// securityEventUrgent 需要立即通知的安全事件，开启汇总模式时也不写入队列
+var securityEventUrgent = map[string]bool{
+	SecurityEventBreakGlassLogin:    true,
+	SecurityEventBreakGlassDenied:   true,
+	SecurityEventBreakGlassDisabled: true,
+}
+
+// securityEventQueue 汇总模式下待发送的安全事件
+const securityEventQueue = "security_event_queue"
+
+// SecurityEventItem 安全事件
+type SecurityEventItem struct {
+	Type     string    `json:"type"`
+	Operator string    `json:"operator"` // 操作人
+	Target   string    `json:"target"`   // 受影响的用户
+	Detail   string    `json:"detail"`
+	Time     time.Time `json:"time"`
+}
+
+// Publish 发布安全事件，通知方式及接收人使用“安全事件通知”任务的配置，任务未启用时不通知；
+// 开启汇总模式（securityNotifyDigest）时事件写入队列，由定时任务按周期汇总发送，否则立即发送（应急账号相关事件始终立即发送）
+func (s *securityEvent) Publish(eventType, operator, target, detail string) {
+
+	event := &SecurityEventItem{
+		Type:     eventType,
+		Operator: operator,
+		Target:   target,
+		Detail:   detail,
+		Time:     time.Now(),
+	}
+	logger.Info(fmt.Sprintf("安全事件：%s，操作人：%s，用户：%s，%s", securityEventNames[eventType], operator, target, detail))
+
+	task, ok := s.notifyTask()
+	if !ok {
+		return
+	}
+
+	if digest := config.GetBool("securityNotifyDigest"); digest && !securityEventUrgent[eventType] {
+		data, _ := json.Marshal(event)
+		if err := global.Cache.RPush(securityEventQueue, string(data)); err != nil {
+			logger.Error("ERROR：安全事件写入队列失败，", err.Error())
+		}
+		return
+	}
+
+	go func() {
+		if err := s.send(task, []*SecurityEventItem{event}); err != nil {
+			logger.Error("ERROR：安全事件通知发送失败，", err.Error())
+		}
+	}()
+}
+
+// SecurityEventNotice 汇总发送队列中的安全事件（定时任务调用）
+func (s *securityEvent) SecurityEventNotice(task *model.ScheduledTask) error {
+
+	if task.NotifyType == nil || task.Receiver == nil || *task.Receiver == "" {
+		return fmt.Errorf("未配置通知方式及接收人")
+	}
+
+	// 取出队列中的所有事件并清空队列
+	values, err := global.Cache.PopAll(securityEventQueue)
+	if err != nil {
+		return err
+	}
+
+	var events []*SecurityEventItem
+	for _, value := range values {
+		var event SecurityEventItem
+		if err := json.Unmarshal([]byte(value), &event); err != nil {
+			continue
+		}
+		events = append(events, &event)
+	}
+
+	if len(events) == 0 {
+		logger.Info("无待发送的安全事件.")
+		return nil
+	}
+
+	return s.send(task, events)
+}
+
+// notifyTask 获取已启用并配置了通知方式的安全事件通知任务
+func (s *securityEvent) notifyTask() (*model.ScheduledTask, bool) {
+	var task model.ScheduledTask
+	if err := global.MySQLClient.Where("built_in_method = ?", "security_event_notify").First(&task).Error; err != nil {
+		return nil, false
+	}
+	if !task.Enabled || task.NotifyType == nil || task.Receiver == nil || *task.Receiver == "" {
+		return nil, false
+	}
+	return &task, true
+}
+
+// send 按任务配置的通知方式发送安全事件（1：邮件 HTML，3：飞书富文本，其它： Markdown 文档）
+func (s *securityEvent) send(task *model.ScheduledTask, events []*SecurityEventItem) error {
+
+	var message string
+	switch *task.NotifyType {
+	case 1:
+		message = securityEventNoticeHTML(events)
+	case 3:
+		jsonBytes, _ := json.Marshal(securityEventNoticePost(events))
+		message = string(jsonBytes)
+	default:
+		message = securityEventNoticeMarkdown(events)
+	}
+
+	notifier := notify.GetNotifier(*task)
+	if notifier == nil {
+		return fmt.Errorf("不支持的通知方式")
+	}
+	return notifier.SendNotify(message, "安全事件提醒")
+}
+
+// securityEventNoticePost 生成飞书 Post 格式的富文本内容
+func securityEventNoticePost(events []*SecurityEventItem) map[string]interface{} {
+	var (
+		issuer  = config.GetString("issuer")
+		content = make([][]map[string]interface{}, 0)
+	)
+
+	for i, event := range events {
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("%d. 事件：", i+1)},
+			{"tag": "text", "text": securityEventNames[event.Type], "text_color": "red"},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("   用户：%s，操作人：%s", event.Target, event.Operator)},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("   详情：%s", event.Detail)},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("   时间：%s", utils.FormatTime(event.Time))},
+		})
+	}
+
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": "--------------------------------\n"},
+	})
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": fmt.Sprintf("来源：%s", issuer)},
+	})
+
+	return map[string]interface{}{
+		"msg_type": "post",
+		"content": map[string]interface{}{
+			"post": map[string]interface{}{
+				"zh_cn": map[string]interface{}{
+					"title":   "安全事件提醒：",
+					"content": content,
+				},
+			},
+		},
+	}
+}
+
+// securityEventNoticeMarkdown 生成安全事件通知 Markdown 文档
+func securityEventNoticeMarkdown(events []*SecurityEventItem) string {
+	var (
+		builder = &strings.Builder{}
+		issuer  = config.GetString("issuer")
+	)
+
+	builder.WriteString("**安全事件提醒：**\n\n")
+
+	for i, event := range events {
+		builder.WriteString(fmt.Sprintf("%d. 事件：<font color=\"warning\">%s</font>\n\n", i+1, securityEventNames[event.Type]))
+		builder.WriteString(fmt.Sprintf("   用户：%s，操作人：%s\n\n", event.Target, event.Operator))
+		builder.WriteString(fmt.Sprintf("   详情：%s\n\n", event.Detail))
+		builder.WriteString(fmt.Sprintf("   时间：%s\n\n", utils.FormatTime(event.Time)))
+	}
+
+	builder.WriteString("--------------------------------\n")
+	builder.WriteString(fmt.Sprintf("来源：%s\n", issuer))
+
+	return builder.String()
+}
+
+// securityEventNoticeHTML 安全事件通知 HTML
+func securityEventNoticeHTML(events []*SecurityEventItem) string {
+
+	issuer := config.GetString("issuer")
+
+	var rows strings.Builder
+	for _, event := range events {
+		rows.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>",
+			utils.FormatTime(event.Time),
+			securityEventNames[event.Type],
+			html.EscapeString(event.Target),
+			html.EscapeString(event.Operator),
+			html.EscapeString(event.Detail)))
+	}
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="UTF-8">
+			<title>安全事件提醒</title>
+		</head>
+		<body>
+			<p>以下特权变更需要关注，如非预期操作请及时处理：</p>
+			<table border="1" cellspacing="0" cellpadding="6">
+				<tr><th>时间</th><th>事件</th><th>用户</th><th>操作人</th><th>详情</th></tr>
+				%s
+			</table>
+			<br>
+			<p>来源：%s</p>
+			<p style="color: red">此邮件为系统自动发送，请勿回复此邮件。</p>
+		</body>
+		</html>
+	`, rows.String(), html.EscapeString(issuer))
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/middleware"
+	"ops-api/model"
+	"ops-api/utils"
+	"strings"
+	"time"
+)
+
+var ServiceClient serviceClient
+
+type serviceClient struct{}
+
+const (
+	// OAuthClientCredentialsGrantType 内部服务使用服务客户端获取服务令牌的授权类型
+	OAuthClientCredentialsGrantType = "client_credentials"
+
+	// serviceClientPath 服务客户端管理接口，不允许授予服务客户端，避免服务客户端为自己扩大权限
+	serviceClientPath = "/api/v1/service_client"
+)
+
+// ServiceClientCreate 创建服务客户端结构体
+type ServiceClientCreate struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Scopes      string `json:"scopes" binding:"required"` // 允许调用的接口名称（system_path），多个以空格分隔
+	Enabled     *bool  `json:"enabled" binding:"required"`
+}
+
+// ServiceClientSecret 创建服务客户端或重置密钥时返回的客户端信息，密钥仅返回一次
+type ServiceClientSecret struct {
+	*model.ServiceClient
+	ClientSecret string `json:"client_secret"`
+}
+
+// GetServiceClientList 获取服务客户端列表（表格）
+func (s *serviceClient) GetServiceClientList(name string, page, limit int) (*dao.ServiceClientList, error) {
+	return dao.ServiceClient.GetServiceClientList(name, page, limit)
+}
+
+// AddServiceClient 创建服务客户端，生成ClientId及ClientSecret
+func (s *serviceClient) AddServiceClient(data *ServiceClientCreate) (*ServiceClientSecret, error) {
+
+	scopes, err := s.validateScopes(data.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := utils.GenerateRandomString(32)
+	cipherText, err := utils.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := dao.ServiceClient.AddServiceClient(&model.ServiceClient{
+		Name:         data.Name,
+		Description:  data.Description,
+		ClientId:     utils.GenerateRandomString(16),
+		ClientSecret: cipherText,
+		Scopes:       scopes,
+		Enabled:      *data.Enabled,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceClientSecret{ServiceClient: client, ClientSecret: secret}, nil
+}
+
+// UpdateServiceClient 更新服务客户端，此前签发的服务令牌立即失效
+func (s *serviceClient) UpdateServiceClient(data *dao.ServiceClientUpdate) (*model.ServiceClient, error) {
+
+	scopes, err := s.validateScopes(data.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	data.Scopes = scopes
+
+	client, err := dao.ServiceClient.GetServiceClient(map[string]interface{}{"id": data.ID})
+	if err != nil {
+		return nil, err
+	}
+	if client, err = dao.ServiceClient.UpdateServiceClient(client, data); err != nil {
+		return nil, err
+	}
+
+	if err := middleware.RevokeServiceClient(client.ClientId); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// ResetServiceClientSecret 重置服务客户端密钥，此前签发的服务令牌立即失效
+func (s *serviceClient) ResetServiceClientSecret(id int) (*ServiceClientSecret, error) {
+
+	client, err := dao.ServiceClient.GetServiceClient(map[string]interface{}{"id": id})
+	if err != nil {
+		return nil, err
+	}
+
+	secret := utils.GenerateRandomString(32)
+	cipherText, err := utils.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+	if err := dao.ServiceClient.UpdateServiceClientSecret(client, cipherText); err != nil {
+		return nil, err
+	}
+
+	if err := middleware.RevokeServiceClient(client.ClientId); err != nil {
+		return nil, err
+	}
+
+	return &ServiceClientSecret{ServiceClient: client, ClientSecret: secret}, nil
+}
+
+// DeleteServiceClient 删除服务客户端，此前签发的服务令牌立即失效
+func (s *serviceClient) DeleteServiceClient(id int) error {
+
+	client, err := dao.ServiceClient.GetServiceClient(map[string]interface{}{"id": id})
+	if err != nil {
+		return err
+	}
+	if err := dao.ServiceClient.DeleteServiceClient(client); err != nil {
+		return err
+	}
+
+	return middleware.RevokeServiceClient(client.ClientId)
+}
+
+// IssueToken 使用client_credentials为服务客户端签发服务令牌，失败时返回 *OAuthError；
+// 未申请Scope时授予服务客户端的所有Scope，申请的Scope不能超出服务客户端允许的范围
+func (s *serviceClient) IssueToken(param *Token) (*ResponseToken, error) {
+
+	invalidClient := NewOAuthError(http.StatusUnauthorized, OAuthInvalidClient, "Client authentication failed")
+
+	client, err := dao.ServiceClient.GetServiceClient(map[string]interface{}{"client_id": param.ClientId})
+	if err != nil || !client.Enabled || param.ClientSecret == "" {
+		return nil, invalidClient
+	}
+	secret, err := utils.Decrypt(client.ClientSecret)
+	if err != nil {
+		logger.Error("ERROR：服务客户端密钥解密失败，", err.Error())
+		return nil, NewOAuthServerError()
+	}
+	// 使用常量时间比较，避免时序攻击
+	if subtle.ConstantTimeCompare([]byte(param.ClientSecret), []byte(secret)) != 1 {
+		logger.Warn(fmt.Sprintf("服务客户端%s（%s）认证失败", client.Name, client.ClientId))
+		return nil, invalidClient
+	}
+
+	allowed := strings.Fields(client.Scopes)
+	scope := strings.Join(allowed, " ")
+	if requested := strings.Fields(param.Scope); len(requested) > 0 {
+		for _, item := range requested {
+			if !utils.Contains(allowed, item) {
+				return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidScope, fmt.Sprintf("scope %s is not allowed for this client", item))
+			}
+		}
+		scope = strings.Join(requested, " ")
+	}
+
+	ttl := time.Duration(config.GetInt("serviceTokenTTL")) * time.Second
+	if ttl <= 0 || ttl > middleware.ServiceTokenMaxTTL {
+		ttl = middleware.ServiceTokenMaxTTL
+	}
+
+	token, err := middleware.GenerateServiceToken(client.ClientId, client.Name, scope, ttl)
+	if err != nil {
+		logger.Error("生成服务令牌失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	if err := dao.ServiceClient.UpdateLastIssuedAt(client.ID, time.Now()); err != nil {
+		logger.Error("ERROR：", err.Error())
+	}
+
+	return &ResponseToken{
+		AccessToken: token,
+		TokenType:   "bearer",
+		ExpiresIn:   int(ttl.Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// validateScopes 校验服务客户端的Scope，Scope必须为已存在的系统接口名称，返回去重后的Scope
+func (s *serviceClient) validateScopes(scopes string) (string, error) {
+
+	var names []string
+	for _, name := range strings.Fields(scopes) {
+		if !utils.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", errors.New("至少需要授予一个接口")
+	}
+
+	paths, err := dao.ServiceClient.GetSystemPaths(names)
+	if err != nil {
+		return "", err
+	}
+	found := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if strings.HasPrefix(path.Path, serviceClientPath) {
+			return "", fmt.Errorf("不允许授予服务客户端管理接口：%s", path.Name)
+		}
+		found[path.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			return "", fmt.Errorf("接口不存在：%s", name)
+		}
+	}
+
+	return strings.Join(names, " "), nil
+}
@@ -0,0 +1,383 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/middleware"
+	"ops-api/model"
+	"strings"
+	"time"
+)
+
+var Session session
+
+type session struct{}
+
+// 会话并发策略超出限制时的动作
+const (
+	SessionPolicyDeny  = 1 // 拒绝新的登录
+	SessionPolicyEvict = 2 // 注销最早的会话
+)
+
+// 会话注销原因
+const (
+	SessionRevokeLogout  = "logout"  // 用户注销
+	SessionRevokeAdmin   = "revoked" // 强制下线
+	SessionRevokeEvicted = "evicted" // 超出会话数量被挤下线
+	SessionRevokeDenied  = "denied"  // 超出会话数量拒绝登录
+	SessionRevokeWiped   = "wiped"   // 设备已擦除
+)
+
+// 会话设备类型
+const (
+	SessionDeviceDesktop = "desktop"
+	SessionDeviceMobile  = "mobile"
+)
+
+// sessionRetention 过期会话记录的保留时间
+const sessionRetention = 30 * 24 * time.Hour
+
+// SessionPolicyCreate 创建会话并发策略结构体
+type SessionPolicyCreate struct {
+	Name          string `json:"name" binding:"required"`
+	Description   string `json:"description"`
+	MaxSessions   *uint  `json:"max_sessions" binding:"required"`
+	PerDeviceType *bool  `json:"per_device_type" binding:"required"`
+	Action        uint   `json:"action" binding:"required,oneof=1 2"`
+	Enabled       *bool  `json:"enabled" binding:"required"`
+	Groups        []uint `json:"groups"`
+}
+
+// SessionPolicyUpdate 更新会话并发策略结构体
+type SessionPolicyUpdate struct {
+	dao.SessionPolicyUpdate
+	Groups []uint `json:"groups"`
+}
+
+// Logout 用户注销，注销当前Token及其所属会话
+func (s *session) Logout(token string) error {
+
+	parts := strings.SplitN(token, " ", 2)
+	if len(parts) != 2 {
+		return errors.New("token无效")
+	}
+
+	// 将Token加入黑名单，并通知其它实例
+	if err := middleware.RevokeToken(parts[1]); err != nil {
+		return err
+	}
+
+	// 未携带会话ID的Token（升级前签发）仅注销Token本身
+	mc, err := middleware.ParseToken(parts[1])
+	if err != nil || mc.SessionID == "" {
+		return nil
+	}
+
+	return s.revoke(mc.ID, mc.SessionID, SessionRevokeLogout)
+}
+
+// Revoke 注销会话，会话签发的用户Token、OAuth2.0 Token及未使用的单点登录票据一并失效
+func (s *session) Revoke(userId uint, sessionId string) error {
+	return s.revoke(userId, sessionId, SessionRevokeAdmin)
+}
+
+// revoke 注销会话并记录注销原因，通知会话登录过的CAS及SAML2应用注销本地会话
+func (s *session) revoke(userId uint, sessionId, reason string) error {
+	if err := middleware.RevokeSession(userId, sessionId); err != nil {
+		return err
+	}
+	CASLogout.Notify(sessionId)
+	SAMLLogout.Notify(sessionId)
+	if err := dao.SSO.RevokeSessionTickets(sessionId); err != nil {
+		return err
+	}
+	return dao.Session.RevokeUserSession(sessionId, reason)
+}
+
+// RevokeUser 强制用户下线，注销用户的所有会话
+func (s *session) RevokeUser(userId uint) error {
+
+	sessionIds, err := middleware.GetUserSessions(userId)
+	if err != nil {
+		return err
+	}
+
+	for _, sessionId := range sessionIds {
+		if err := s.Revoke(userId, sessionId); err != nil {
+			return err
+		}
+	}
+
+	// 注销离线访问的刷新令牌
+	refreshSessionIds, err := dao.SSO.GetUserRefreshSessions(userId)
+	if err != nil {
+		return err
+	}
+	for _, sessionId := range refreshSessionIds {
+		if err := s.revokeOffline(userId, sessionId); err != nil {
+			return err
+		}
+	}
+
+	logger.Info(fmt.Sprintf("用户（ID：%d）已强制下线，共注销 %d 个会话", userId, len(sessionIds)))
+	return nil
+}
+
+// revokeOffline 注销离线访问会话，会话的刷新令牌及刷新后签发的Token一并失效
+func (s *session) revokeOffline(userId uint, sessionId string) error {
+	if err := dao.SSO.RevokeRefreshTokens(sessionId); err != nil {
+		return err
+	}
+	return middleware.RevokeSession(userId, sessionId)
+}
+
+// Admit 登录成功后记录会话，并按用户所属分组的会话并发策略检查会话数量，
+// 超出限制时根据策略拒绝新的登录（注销新签发的会话）或注销最早的会话
+func (s *session) Admit(token string, info *DeviceInfo, clientIP string) error {
+
+	mc, err := middleware.ParseToken(token)
+	if err != nil {
+		return err
+	}
+	if mc.SessionID == "" {
+		return nil
+	}
+
+	now := time.Now()
+	record := &model.UserSession{
+		SessionID:   mc.SessionID,
+		UserID:      mc.ID,
+		Username:    mc.Username,
+		DeviceType:  info.DeviceType(),
+		Device:      info.Name(),
+		Fingerprint: info.Fingerprint(),
+		ClientIP:    clientIP,
+		UserAgent:   info.UserAgent,
+		CreatedAt:   now,
+		ExpiresAt:   mc.ExpiresAt.Time,
+	}
+	if len(record.UserAgent) > 512 {
+		record.UserAgent = record.UserAgent[:512]
+	}
+
+	policy, conflicts, err := s.check(mc.ID, record.DeviceType)
+	if err != nil {
+		return err
+	}
+
+	// 拒绝新的登录，保留会话记录便于管理员查看
+	if len(conflicts) > 0 && policy.Action == SessionPolicyDeny {
+		record.RevokedAt = &now
+		record.RevokeReason = SessionRevokeDenied
+		if err := dao.Session.AddUserSession(record); err != nil {
+			return err
+		}
+		if err := s.revoke(mc.ID, mc.SessionID, SessionRevokeDenied); err != nil {
+			return err
+		}
+		return errors.New("该账号的在线会话数量已达到上限，请先退出其它设备后再登录")
+	}
+
+	if err := dao.Session.AddUserSession(record); err != nil {
+		return err
+	}
+
+	// 注销最早的会话
+	for _, item := range conflicts {
+		if err := s.revoke(item.UserID, item.SessionID, SessionRevokeEvicted); err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("用户%s的会话（%s，%s）超出会话并发策略“%s”的限制，已被注销", mc.Username, item.Device, item.ClientIP, policy.Name))
+	}
+
+	// 清理过期的会话记录
+	if err := dao.Session.DeleteExpiredUserSessions(mc.ID, now.Add(-sessionRetention)); err != nil {
+		logger.Error("ERROR：清理过期会话记录失败，", err.Error())
+	}
+
+	return nil
+}
+
+// check 获取适用于用户的会话并发策略，以及新会话登录后超出限制的在线会话（按登录时间排序）
+func (s *session) check(userId uint, deviceType string) (*model.SessionPolicy, []*model.UserSession, error) {
+
+	groupIds, err := dao.LoginPolicy.GetUserGroupIds(userId)
+	if err != nil {
+		return nil, nil, err
+	}
+	policy, err := dao.Session.GetUserSessionPolicy(groupIds)
+	if err != nil || policy == nil {
+		return nil, nil, err
+	}
+
+	sessions, err := dao.Session.GetActiveUserSessions(userId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		conflicts []*model.UserSession
+		remaining []*model.UserSession
+	)
+
+	// 每种设备类型仅允许一个会话
+	for _, item := range sessions {
+		if policy.PerDeviceType && item.DeviceType == deviceType {
+			conflicts = append(conflicts, item)
+		} else {
+			remaining = append(remaining, item)
+		}
+	}
+
+	// 加上新会话后超出最大会话数量的部分
+	if policy.MaxSessions > 0 && uint(len(remaining)) >= policy.MaxSessions {
+		conflicts = append(conflicts, remaining[:uint(len(remaining))-policy.MaxSessions+1]...)
+	}
+
+	return policy, conflicts, nil
+}
+
+// GetUserSessionList 获取用户会话列表（表格）
+func (s *session) GetUserSessionList(username string, active bool, page, limit int) (data *dao.UserSessionList, err error) {
+	return dao.Session.GetUserSessionList(username, active, page, limit)
+}
+
+// GetUserSessions 获取用户最近的会话，包括已注销的会话
+func (s *session) GetUserSessions(userId uint) ([]*model.UserSession, error) {
+	return dao.Session.GetUserSessions(userId, 50)
+}
+
+// Renew 升级认证后会话有效期重新计算，同步更新会话记录的过期时间
+func (s *session) Renew(token string) {
+	mc, err := middleware.ParseToken(token)
+	if err != nil || mc.SessionID == "" {
+		return
+	}
+	if err := dao.Session.RenewUserSession(mc.SessionID, mc.ExpiresAt.Time); err != nil {
+		logger.Error("ERROR：更新会话过期时间失败，", err.Error())
+	}
+}
+
+// GetSessionPolicyList 获取会话并发策略列表（表格）
+func (s *session) GetSessionPolicyList(name string, page, limit int) (data *dao.SessionPolicyList, err error) {
+	return dao.Session.GetSessionPolicyList(name, page, limit)
+}
+
+// AddSessionPolicy 创建会话并发策略
+func (s *session) AddSessionPolicy(data *SessionPolicyCreate) (*model.SessionPolicy, error) {
+
+	if *data.MaxSessions == 0 && !*data.PerDeviceType {
+		return nil, errors.New("最大会话数量和每种设备类型仅允许一个会话不能同时为空")
+	}
+
+	policy := &model.SessionPolicy{
+		Name:          data.Name,
+		Description:   data.Description,
+		MaxSessions:   *data.MaxSessions,
+		PerDeviceType: *data.PerDeviceType,
+		Action:        data.Action,
+		Enabled:       *data.Enabled,
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.Session.AddSessionPolicy(tx, policy)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 设置策略适用的分组
+	if err := s.updatePolicyGroups(tx, result, data.Groups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateSessionPolicy 更新会话并发策略
+func (s *session) UpdateSessionPolicy(data *SessionPolicyUpdate) (*model.SessionPolicy, error) {
+
+	if *data.MaxSessions == 0 && !*data.PerDeviceType {
+		return nil, errors.New("最大会话数量和每种设备类型仅允许一个会话不能同时为空")
+	}
+
+	// 查询要修改的策略
+	policy, err := dao.Session.GetSessionPolicy(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.Session.UpdateSessionPolicy(tx, policy, &data.SessionPolicyUpdate)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 更新策略适用的分组
+	if err := s.updatePolicyGroups(tx, result, data.Groups); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteSessionPolicy 删除会话并发策略
+func (s *session) DeleteSessionPolicy(id int) error {
+
+	policy, err := dao.Session.GetSessionPolicy(uint(id))
+	if err != nil {
+		return err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.Session.DeleteSessionPolicy(tx, policy); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// updatePolicyGroups 更新会话并发策略适用的分组
+func (s *session) updatePolicyGroups(tx *gorm.DB, policy *model.SessionPolicy, groupIds []uint) error {
+
+	var groups []model.AuthGroup
+	if len(groupIds) > 0 {
+		if err := tx.Where("id IN ?", groupIds).Find(&groups).Error; err != nil {
+			return err
+		}
+	}
+
+	return dao.Session.UpdateSessionPolicyGroups(tx, policy, groups)
+}
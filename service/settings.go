@@ -2,19 +2,30 @@ package service
 
 import (
 	"crypto/rsa"
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"github.com/wonderivan/logger"
 	"mime/multipart"
+	"net"
+	"net/url"
 	"ops-api/config"
 	"ops-api/dao"
 	"ops-api/db"
 	"ops-api/global"
+	"ops-api/middleware"
 	"ops-api/model"
 	"ops-api/utils"
+	"ops-api/utils/check"
+	"ops-api/utils/i18n"
 	"ops-api/utils/mail"
+	"ops-api/utils/scan"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -50,6 +61,9 @@ type SettingsUpdate struct {
 	SmsSender                  string `json:"smsSender"`
 	SmsCallbackUrl             string `json:"smsCallbackUrl"`
 	SmsTemplateId              string `json:"smsTemplateId"`
+	SmsTemplateIdEn            string `json:"smsTemplateIdEn"`
+	SmsRegion                  string `json:"smsRegion"`
+	SmsTemplates               string `json:"smsTemplates"`
 	DingdingAppKey             string `json:"dingdingAppKey"`
 	DingdingAppSecret          string `json:"dingdingAppSecret"`
 	FeishuAppId                string `json:"feishuAppId"`
@@ -60,6 +74,72 @@ type SettingsUpdate struct {
 	TokenExpiresTime           string `json:"tokenExpiresTime"`
 	Swagger                    string `json:"swagger"`
 	PasswordMailResetOff       string `json:"passwordMailResetOff"`
+	PasswordBreachCheck        string `json:"passwordBreachCheck"`
+	PasswordBreachMode         string `json:"passwordBreachMode"`
+	PasswordBreachCorpus       string `json:"passwordBreachCorpus"`
+	ScimToken                  string `json:"scimToken"`
+	LdapServer                 string `json:"ldapServer"`
+	LdapServerAddress          string `json:"ldapServerAddress"`
+	LdapServerBaseDn           string `json:"ldapServerBaseDn"`
+	LdapServerBindDn           string `json:"ldapServerBindDn"`
+	LdapServerBindPassword     string `json:"ldapServerBindPassword"`
+	LdapServerLockoutThreshold string `json:"ldapServerLockoutThreshold"`
+	LdapServerLockoutMinutes   string `json:"ldapServerLockoutMinutes"`
+	LdapServerTlsAddress       string `json:"ldapServerTlsAddress"`
+	LdapServerCertificate      string `json:"ldapServerCertificate"`
+	LdapServerPrivateKey       string `json:"ldapServerPrivateKey"`
+	DefaultLanguage            string `json:"defaultLanguage"`
+	EndpointAllowlist          string `json:"endpointAllowlist"`
+	CorsPolicies               string `json:"corsPolicies"`
+	LegacyStatusCode           string `json:"legacyStatusCode"`
+	PublicRateLimit            string `json:"publicRateLimit"`
+	SecurityNotifyDigest       string `json:"securityNotifyDigest"`
+	TokenRevocationFailOpen    string `json:"tokenRevocationFailOpen"`
+	OidcIssuer                 string `json:"oidcIssuer"`
+	OidcAuthorizationEndpoint  string `json:"oidcAuthorizationEndpoint"`
+	OidcTokenEndpoint          string `json:"oidcTokenEndpoint"`
+	OidcUserinfoEndpoint       string `json:"oidcUserinfoEndpoint"`
+	OidcJwksUri                string `json:"oidcJwksUri"`
+	OidcDeviceEndpoint         string `json:"oidcDeviceEndpoint"`
+	OidcVerificationUri        string `json:"oidcVerificationUri"`
+	TrustedNetworks            string `json:"trustedNetworks"`
+	TrustedProxies             string `json:"trustedProxies"`
+	TrustedRateLimit           string `json:"trustedRateLimit"`
+	PublicDirectory            string `json:"publicDirectory"`
+	BreakGlassNetworks         string `json:"breakGlassNetworks"`
+	BreakGlassWindow           string `json:"breakGlassWindow"`
+	RedirectAllowlist          string `json:"redirectAllowlist"`
+	UploadScanner              string `json:"uploadScanner"`
+	UploadScanAddress          string `json:"uploadScanAddress"`
+	UploadMaxSize              string `json:"uploadMaxSize"`
+	UploadMaxDimension         string `json:"uploadMaxDimension"`
+	AlertWindow                string `json:"alertWindow"`
+	AlertLoginFailureThreshold string `json:"alertLoginFailureThreshold"`
+	AlertSmsErrorRate          string `json:"alertSmsErrorRate"`
+	AlertCertificateDays       string `json:"alertCertificateDays"`
+	FirstLoginActions          string `json:"firstLoginActions"`
+	FirstLoginTerms            string `json:"firstLoginTerms"`
+	OidcRegistration           string `json:"oidcRegistration"`
+	OidcRegistrationToken      string `json:"oidcRegistrationToken"`
+	Timezone                   string `json:"timezone"`
+	ServiceTokenTTL            string `json:"serviceTokenTTL"`
+	LdapStartTLS               string `json:"ldapStartTLS"`
+	LdapSkipVerify             string `json:"ldapSkipVerify"`
+	LdapFollowReferrals        string `json:"ldapFollowReferrals"`
+	LdapTimeout                string `json:"ldapTimeout"`
+	LdapPoolSize               string `json:"ldapPoolSize"`
+	LdapRateLimit              string `json:"ldapRateLimit"`
+	ProtocolSunset             string `json:"protocolSunset"`
+	ItsmProvider               string `json:"itsmProvider"`
+	ItsmJiraAddress            string `json:"itsmJiraAddress"`
+	ItsmJiraUser               string `json:"itsmJiraUser"`
+	ItsmJiraToken              string `json:"itsmJiraToken"`
+	ItsmJiraServiceDesk        string `json:"itsmJiraServiceDesk"`
+	ItsmJiraRequestType        string `json:"itsmJiraRequestType"`
+	ItsmFeishuApprovalCode     string `json:"itsmFeishuApprovalCode"`
+	ItsmFeishuWidget           string `json:"itsmFeishuWidget"`
+	ItsmWebhookToken           string `json:"itsmWebhookToken"`
+	AuditorMaxDays             string `json:"auditorMaxDays"`
 }
 
 type MailTest struct {
@@ -122,9 +202,7 @@ func (s *settings) UploadLogo(path string, logo *multipart.FileHeader) (url stri
 	}
 
 	// 获取预览URL
-	logoPreview, err := utils.GetPresignedURL(path, 6*time.Hour)
-
-	return logoPreview.String(), nil
+	return utils.GetObjectURL(path, 6*time.Hour)
 }
 
 // GetLogo 获取 Logo
@@ -139,9 +217,7 @@ func (s *settings) GetLogo() (string, error) {
 	}
 
 	// 获取预览URL
-	logoPreview, err := utils.GetPresignedURL(*logo.Value, 6*time.Hour)
-
-	return logoPreview.String(), nil
+	return utils.GetObjectURL(*logo.Value, 6*time.Hour)
 }
 
 // GetSettingByKeyWithParsedValue 获取单个配置
@@ -163,8 +239,8 @@ func (s *settings) UpdateSettingValue(key, value string) error {
 	return dao.Settings.UpdateSetting(key, value)
 }
 
-// UpdateSettingValues 更新多个配置
-func (s *settings) UpdateSettingValues(data *SettingsUpdate) (map[string]interface{}, error) {
+// collect 校验并整理需要修改的配置，敏感信息加密保存
+func (s *settings) collect(data *SettingsUpdate) (map[string]interface{}, error) {
 
 	settingsToUpdate := map[string]interface{}{}
 
@@ -173,7 +249,7 @@ func (s *settings) UpdateSettingValues(data *SettingsUpdate) (map[string]interfa
 		settingsToUpdate["externalUrl"] = data.ExternalUrl
 	}
 	if data.Swagger != "" {
-		settingsToUpdate["Swagger"] = data.Swagger
+		settingsToUpdate["swagger"] = data.Swagger
 	}
 
 	// 安全设置
@@ -187,7 +263,7 @@ func (s *settings) UpdateSettingValues(data *SettingsUpdate) (map[string]interfa
 		settingsToUpdate["secret"] = data.Secret
 	}
 	if data.TokenExpiresTime != "" {
-		settingsToUpdate["TokenExpiresTime"] = data.TokenExpiresTime
+		settingsToUpdate["tokenExpiresTime"] = data.TokenExpiresTime
 	}
 
 	// LDAP 设置
@@ -210,6 +286,45 @@ func (s *settings) UpdateSettingValues(data *SettingsUpdate) (map[string]interfa
 	if data.LdapUserPasswordExpireDays != "" {
 		settingsToUpdate["ldapUserPasswordExpireDays"] = data.LdapUserPasswordExpireDays
 	}
+	if data.LdapStartTLS != "" {
+		settingsToUpdate["ldapStartTLS"] = data.LdapStartTLS
+	}
+	if data.LdapSkipVerify != "" {
+		settingsToUpdate["ldapSkipVerify"] = data.LdapSkipVerify
+	}
+	if data.LdapFollowReferrals != "" {
+		settingsToUpdate["ldapFollowReferrals"] = data.LdapFollowReferrals
+	}
+	if data.LdapTimeout != "" {
+		if timeout, err := strconv.Atoi(data.LdapTimeout); err != nil || timeout <= 0 || timeout > 300 {
+			return nil, errors.New("LDAP超时时间必须为1到300之间的整数")
+		}
+		settingsToUpdate["ldapTimeout"] = data.LdapTimeout
+	}
+	if data.LdapPoolSize != "" {
+		if size, err := strconv.Atoi(data.LdapPoolSize); err != nil || size <= 0 || size > 100 {
+			return nil, errors.New("LDAP连接池大小必须为1到100之间的整数")
+		}
+		settingsToUpdate["ldapPoolSize"] = data.LdapPoolSize
+	}
+	if data.LdapRateLimit != "" {
+		if limit, err := strconv.Atoi(data.LdapRateLimit); err != nil || limit < 0 {
+			return nil, errors.New("LDAP操作频率限制必须为大于等于0的整数")
+		}
+		settingsToUpdate["ldapRateLimit"] = data.LdapRateLimit
+	}
+
+	// 单点登录协议停用日期，为JSON数组，每条规则格式为：协议=YYYY-MM-DD
+	if data.ProtocolSunset != "" {
+		var rules []string
+		if err := json.Unmarshal([]byte(data.ProtocolSunset), &rules); err != nil {
+			return nil, errors.New("单点登录协议停用日期格式错误")
+		}
+		if err := validateProtocolSunset(rules); err != nil {
+			return nil, err
+		}
+		settingsToUpdate["protocolSunset"] = data.ProtocolSunset
+	}
 
 	// 用户密码策略
 	if data.PasswordExpireDays != "" {
@@ -227,6 +342,15 @@ func (s *settings) UpdateSettingValues(data *SettingsUpdate) (map[string]interfa
 	if data.PasswordMailResetOff != "" {
 		settingsToUpdate["passwordMailResetOff"] = data.PasswordMailResetOff
 	}
+	if data.PasswordBreachCheck != "" {
+		settingsToUpdate["passwordBreachCheck"] = data.PasswordBreachCheck
+	}
+	if data.PasswordBreachMode != "" {
+		settingsToUpdate["passwordBreachMode"] = data.PasswordBreachMode
+	}
+	if data.PasswordBreachCorpus != "" {
+		settingsToUpdate["passwordBreachCorpus"] = data.PasswordBreachCorpus
+	}
 
 	// 邮件配置
 	if data.MailAddress != "" {
@@ -277,6 +401,49 @@ func (s *settings) UpdateSettingValues(data *SettingsUpdate) (map[string]interfa
 	if data.SmsTemplateId != "" {
 		settingsToUpdate["smsTemplateId"] = data.SmsTemplateId
 	}
+	if data.SmsTemplateIdEn != "" {
+		settingsToUpdate["smsTemplateIdEn"] = data.SmsTemplateIdEn
+	}
+	// 短信模板映射，为JSON对象，按服务商配置模板ID及模板变量；切换服务商时校验新服务商是否配置了模板
+	if data.SmsTemplates != "" || data.SmsProvider != "" {
+		provider, templates := config.GetString("smsProvider"), config.GetString("smsTemplates")
+		if data.SmsProvider != "" {
+			provider = data.SmsProvider
+		}
+		if data.SmsTemplates != "" {
+			templates = data.SmsTemplates
+		}
+		if err := checkSMSProviderTemplate(provider, templates); err != nil {
+			return nil, err
+		}
+		if data.SmsTemplates != "" {
+			settingsToUpdate["smsTemplates"] = data.SmsTemplates
+		}
+	}
+	// 手机号默认地区，修改后仅影响之后录入的未携带国际区号的手机号
+	if data.SmsRegion != "" {
+		if !check.IsPhoneRegion(data.SmsRegion) {
+			return nil, fmt.Errorf("无效的手机号地区代码：%s", data.SmsRegion)
+		}
+		settingsToUpdate["smsRegion"] = strings.ToUpper(data.SmsRegion)
+	}
+
+	// 系统默认语言，用户未设置首选语言时使用
+	if data.DefaultLanguage != "" {
+		locale := i18n.Normalize(data.DefaultLanguage)
+		if locale == "" {
+			return nil, fmt.Errorf("不支持的语言，可选值：%s", strings.Join(i18n.Locales, "、"))
+		}
+		settingsToUpdate["defaultLanguage"] = locale
+	}
+
+	// 系统时区，定时任务调度的时区修改后需重启服务生效
+	if data.Timezone != "" {
+		if _, err := config.LoadLocation(data.Timezone); err != nil {
+			return nil, fmt.Errorf("无效的时区：%s", data.Timezone)
+		}
+		settingsToUpdate["timezone"] = data.Timezone
+	}
 
 	// 钉钉配置
 	if data.DingdingAppKey != "" {
@@ -308,16 +475,617 @@ func (s *settings) UpdateSettingValues(data *SettingsUpdate) (map[string]interfa
 		settingsToUpdate["wechatCorpId"] = data.WechatCorpId
 	}
 
+	// LDAP目录服务配置，启用状态及监听地址修改后需重启服务生效
+	if data.LdapServer != "" {
+		settingsToUpdate["ldapServer"] = data.LdapServer
+	}
+	if data.LdapServerAddress != "" {
+		settingsToUpdate["ldapServerAddress"] = data.LdapServerAddress
+	}
+	if data.LdapServerBaseDn != "" {
+		settingsToUpdate["ldapServerBaseDn"] = data.LdapServerBaseDn
+	}
+	if data.LdapServerBindDn != "" {
+		settingsToUpdate["ldapServerBindDn"] = data.LdapServerBindDn
+	}
+	if data.LdapServerBindPassword != "" {
+		cipherText, _ := utils.Encrypt(data.LdapServerBindPassword)
+		settingsToUpdate["ldapServerBindPassword"] = cipherText
+	}
+	if data.LdapServerLockoutThreshold != "" {
+		settingsToUpdate["ldapServerLockoutThreshold"] = data.LdapServerLockoutThreshold
+	}
+	if data.LdapServerLockoutMinutes != "" {
+		settingsToUpdate["ldapServerLockoutMinutes"] = data.LdapServerLockoutMinutes
+	}
+	if data.LdapServerTlsAddress != "" {
+		settingsToUpdate["ldapServerTlsAddress"] = data.LdapServerTlsAddress
+	}
+	if data.LdapServerCertificate != "" || data.LdapServerPrivateKey != "" {
+		certificate, privateKey := data.LdapServerCertificate, data.LdapServerPrivateKey
+		if certificate == "" {
+			certificate = config.GetString("ldapServerCertificate")
+		}
+		if privateKey == "" {
+			privateKey = config.GetString("ldapServerPrivateKey")
+		}
+		if certificate != "" && privateKey != "" {
+			if _, err := tls.X509KeyPair([]byte(certificate), []byte(privateKey)); err != nil {
+				return nil, fmt.Errorf("LDAP目录服务TLS证书或私钥无效：%v", err)
+			}
+		}
+		if data.LdapServerCertificate != "" {
+			settingsToUpdate["ldapServerCertificate"] = data.LdapServerCertificate
+		}
+		if data.LdapServerPrivateKey != "" {
+			settingsToUpdate["ldapServerPrivateKey"] = data.LdapServerPrivateKey
+		}
+	}
+
+	// SCIM配置
+	if data.ScimToken != "" {
+		cipherText, _ := utils.Encrypt(data.ScimToken)
+		settingsToUpdate["scimToken"] = cipherText
+	}
+
+	// 外部审批（ITSM）配置
+	if data.ItsmProvider != "" {
+		if data.ItsmProvider != "none" && data.ItsmProvider != ITSMJira && data.ItsmProvider != ITSMFeishu {
+			return nil, fmt.Errorf("不支持的外部审批：%s，可选值为：none、%s、%s", data.ItsmProvider, ITSMJira, ITSMFeishu)
+		}
+		if data.ItsmProvider == "none" {
+			data.ItsmProvider = ""
+		}
+		settingsToUpdate["itsmProvider"] = data.ItsmProvider
+	}
+	if data.ItsmJiraAddress != "" {
+		if u, err := url.Parse(data.ItsmJiraAddress); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, errors.New("Jira Service Management地址格式错误")
+		}
+		settingsToUpdate["itsmJiraAddress"] = data.ItsmJiraAddress
+	}
+	if data.ItsmJiraUser != "" {
+		settingsToUpdate["itsmJiraUser"] = data.ItsmJiraUser
+	}
+	if data.ItsmJiraToken != "" {
+		cipherText, _ := utils.Encrypt(data.ItsmJiraToken)
+		settingsToUpdate["itsmJiraToken"] = cipherText
+	}
+	if data.ItsmJiraServiceDesk != "" {
+		settingsToUpdate["itsmJiraServiceDesk"] = data.ItsmJiraServiceDesk
+	}
+	if data.ItsmJiraRequestType != "" {
+		settingsToUpdate["itsmJiraRequestType"] = data.ItsmJiraRequestType
+	}
+	if data.ItsmFeishuApprovalCode != "" {
+		settingsToUpdate["itsmFeishuApprovalCode"] = data.ItsmFeishuApprovalCode
+	}
+	if data.ItsmFeishuWidget != "" {
+		settingsToUpdate["itsmFeishuWidget"] = data.ItsmFeishuWidget
+	}
+	if data.ItsmWebhookToken != "" {
+		cipherText, _ := utils.Encrypt(data.ItsmWebhookToken)
+		settingsToUpdate["itsmWebhookToken"] = cipherText
+	}
+
+	// 内部服务令牌有效期（秒）
+	if data.ServiceTokenTTL != "" {
+		if ttl, err := strconv.Atoi(data.ServiceTokenTTL); err != nil || ttl <= 0 || ttl > int(middleware.ServiceTokenMaxTTL.Seconds()) {
+			return nil, fmt.Errorf("服务令牌有效期必须为1到%d之间的整数", int(middleware.ServiceTokenMaxTTL.Seconds()))
+		}
+		settingsToUpdate["serviceTokenTTL"] = data.ServiceTokenTTL
+	}
+
+	// 公开接口防护，IP白名单为JSON数组，每条规则格式为：接口路径=IP或网段,IP或网段
+	if data.EndpointAllowlist != "" {
+		var rules []string
+		if err := json.Unmarshal([]byte(data.EndpointAllowlist), &rules); err != nil {
+			return nil, errors.New("接口IP白名单格式错误")
+		}
+		for _, rule := range rules {
+			parts := strings.SplitN(rule, "=", 2)
+			if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+				return nil, fmt.Errorf("接口IP白名单规则格式错误：%s", rule)
+			}
+			for _, cidr := range strings.Split(parts[1], ",") {
+				cidr = strings.TrimSpace(cidr)
+				if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+					return nil, fmt.Errorf("无效的IP或网段：%s", cidr)
+				}
+			}
+		}
+		settingsToUpdate["endpointAllowlist"] = data.EndpointAllowlist
+	}
+
+	// 跨域策略，JSON数组，按接口路径前缀配置允许的来源、方法、请求头及预检请求缓存时间
+	if data.CorsPolicies != "" {
+		if _, err := middleware.ParseCorsPolicies(data.CorsPolicies); err != nil {
+			return nil, err
+		}
+		settingsToUpdate["corsPolicies"] = data.CorsPolicies
+	}
+	if data.PublicRateLimit != "" {
+		settingsToUpdate["publicRateLimit"] = data.PublicRateLimit
+	}
+
+	// 可信网络，JSON数组，每项为IP或网段，访问频率限制为0时不限制
+	if data.TrustedNetworks != "" {
+		var networks []string
+		if err := json.Unmarshal([]byte(data.TrustedNetworks), &networks); err != nil {
+			return nil, errors.New("可信网络格式错误")
+		}
+		for _, cidr := range networks {
+			cidr = strings.TrimSpace(cidr)
+			if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+				return nil, fmt.Errorf("无效的IP或网段：%s", cidr)
+			}
+		}
+		settingsToUpdate["trustedNetworks"] = data.TrustedNetworks
+	}
+	if data.TrustedRateLimit != "" {
+		settingsToUpdate["trustedRateLimit"] = data.TrustedRateLimit
+	}
+
+	// 可信代理，JSON数组，每项为IP或网段，仅信任可信代理转发的客户端IP（X-Forwarded-For），修改后需重启服务生效
+	if data.TrustedProxies != "" {
+		var proxies []string
+		if err := json.Unmarshal([]byte(data.TrustedProxies), &proxies); err != nil {
+			return nil, errors.New("可信代理格式错误")
+		}
+		for _, cidr := range proxies {
+			cidr = strings.TrimSpace(cidr)
+			if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+				return nil, fmt.Errorf("无效的IP或网段：%s", cidr)
+			}
+		}
+		settingsToUpdate["trustedProxies"] = data.TrustedProxies
+	}
+
+	// 应急账号允许登录的网络，JSON数组，每项为IP或网段，为空时应急账号无法登录
+	if data.BreakGlassNetworks != "" {
+		var networks []string
+		if err := json.Unmarshal([]byte(data.BreakGlassNetworks), &networks); err != nil {
+			return nil, errors.New("应急账号允许登录的网络格式错误")
+		}
+		for _, cidr := range networks {
+			cidr = strings.TrimSpace(cidr)
+			if _, _, err := net.ParseCIDR(cidr); err != nil && net.ParseIP(cidr) == nil {
+				return nil, fmt.Errorf("无效的IP或网段：%s", cidr)
+			}
+		}
+		settingsToUpdate["breakGlassNetworks"] = data.BreakGlassNetworks
+	}
+	// 应急账号使用时间窗口（分钟），首次登录后超过该时间自动禁用
+	if data.BreakGlassWindow != "" {
+		if window, err := strconv.Atoi(data.BreakGlassWindow); err != nil || window <= 0 {
+			return nil, errors.New("应急账号使用时间窗口必须为大于0的整数")
+		}
+		settingsToUpdate["breakGlassWindow"] = data.BreakGlassWindow
+	}
+	// 审计员最长有效期（天）
+	if data.AuditorMaxDays != "" {
+		if days, err := strconv.Atoi(data.AuditorMaxDays); err != nil || days <= 0 || days > 365 {
+			return nil, errors.New("审计员最长有效期必须为1到365之间的整数")
+		}
+		settingsToUpdate["auditorMaxDays"] = data.AuditorMaxDays
+	}
+
+	// 登录后允许跳转的域名，JSON数组，每项为域名，*.example.com 匹配所有子域名
+	if data.RedirectAllowlist != "" {
+		var hosts []string
+		if err := json.Unmarshal([]byte(data.RedirectAllowlist), &hosts); err != nil {
+			return nil, errors.New("允许跳转的域名格式错误")
+		}
+		for _, host := range hosts {
+			host = strings.TrimSpace(host)
+			if host == "" || host == "*." || strings.ContainsAny(host, "/:?#@\\ ") || strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+				return nil, fmt.Errorf("无效的域名：%s", host)
+			}
+		}
+		settingsToUpdate["redirectAllowlist"] = data.RedirectAllowlist
+	}
+
+	// 上传文件扫描器（clamav、http或自定义扫描器名称），设置为none时关闭扫描
+	if data.UploadScanner != "" {
+		if data.UploadScanner != "none" {
+			if _, ok := scan.Get(data.UploadScanner); !ok {
+				return nil, fmt.Errorf("未注册的文件扫描器：%s", data.UploadScanner)
+			}
+			settingsToUpdate["uploadScanner"] = data.UploadScanner
+		} else {
+			settingsToUpdate["uploadScanner"] = ""
+		}
+	}
+	if data.UploadScanAddress != "" {
+		settingsToUpdate["uploadScanAddress"] = data.UploadScanAddress
+	}
+	// 上传文件大小限制（KB）及图片宽度、高度限制（像素）
+	if data.UploadMaxSize != "" {
+		if size, err := strconv.Atoi(data.UploadMaxSize); err != nil || size <= 0 {
+			return nil, errors.New("上传文件大小限制必须为大于0的整数")
+		}
+		settingsToUpdate["uploadMaxSize"] = data.UploadMaxSize
+	}
+	if data.UploadMaxDimension != "" {
+		if dimension, err := strconv.Atoi(data.UploadMaxDimension); err != nil || dimension <= 0 {
+			return nil, errors.New("图片宽度及高度限制必须为大于0的整数")
+		}
+		settingsToUpdate["uploadMaxDimension"] = data.UploadMaxDimension
+	}
+
+	// 内置告警统计窗口（分钟）及阈值，阈值设置为0时不检查对应的告警规则
+	if data.AlertWindow != "" {
+		if window, err := strconv.Atoi(data.AlertWindow); err != nil || window <= 0 {
+			return nil, errors.New("告警统计窗口必须为大于0的整数")
+		}
+		settingsToUpdate["alertWindow"] = data.AlertWindow
+	}
+	if data.AlertLoginFailureThreshold != "" {
+		if threshold, err := strconv.Atoi(data.AlertLoginFailureThreshold); err != nil || threshold < 0 {
+			return nil, errors.New("登录失败告警阈值必须为大于等于0的整数")
+		}
+		settingsToUpdate["alertLoginFailureThreshold"] = data.AlertLoginFailureThreshold
+	}
+	if data.AlertSmsErrorRate != "" {
+		if rate, err := strconv.Atoi(data.AlertSmsErrorRate); err != nil || rate < 0 || rate > 100 {
+			return nil, errors.New("短信发送失败率告警阈值必须为0到100之间的整数")
+		}
+		settingsToUpdate["alertSmsErrorRate"] = data.AlertSmsErrorRate
+	}
+	if data.AlertCertificateDays != "" {
+		if days, err := strconv.Atoi(data.AlertCertificateDays); err != nil || days < 0 {
+			return nil, errors.New("证书过期告警天数必须为大于等于0的整数")
+		}
+		settingsToUpdate["alertCertificateDays"] = data.AlertCertificateDays
+	}
+
+	// 新用户首次登录时必须完成的操作，JSON数组，每项为操作名称
+	if data.FirstLoginActions != "" {
+		var actions []string
+		if err := json.Unmarshal([]byte(data.FirstLoginActions), &actions); err != nil {
+			return nil, errors.New("首次登录操作格式错误")
+		}
+		for _, action := range actions {
+			if !utils.Contains(requiredActions, action) {
+				return nil, fmt.Errorf("不支持的首次登录操作：%s", action)
+			}
+		}
+		settingsToUpdate["firstLoginActions"] = data.FirstLoginActions
+	}
+	if data.FirstLoginTerms != "" {
+		settingsToUpdate["firstLoginTerms"] = data.FirstLoginTerms
+	}
+
+	// 公开应用目录
+	if data.PublicDirectory != "" {
+		settingsToUpdate["publicDirectory"] = data.PublicDirectory
+	}
+
+	// 安全事件通知汇总模式
+	if data.SecurityNotifyDigest != "" {
+		settingsToUpdate["securityNotifyDigest"] = data.SecurityNotifyDigest
+	}
+
+	// Redis不可用时令牌吊销检查的降级策略
+	if data.TokenRevocationFailOpen != "" {
+		settingsToUpdate["tokenRevocationFailOpen"] = data.TokenRevocationFailOpen
+	}
+
+	// 接口错误响应的HTTP状态码兼容模式
+	if data.LegacyStatusCode != "" {
+		settingsToUpdate["legacyStatusCode"] = data.LegacyStatusCode
+	}
+
+	// OIDC签发者及端点，修改签发者后已签发的Token将失效
+	if data.OidcIssuer != "" {
+		issuer := strings.TrimRight(strings.TrimSpace(data.OidcIssuer), "/")
+		if err := middleware.ValidateOIDCIssuer(issuer); err != nil {
+			return nil, err
+		}
+		settingsToUpdate["oidcIssuer"] = issuer
+	}
+	// OIDC动态客户端注册
+	if data.OidcRegistration != "" {
+		settingsToUpdate["oidcRegistration"] = data.OidcRegistration
+	}
+	if data.OidcRegistrationToken != "" {
+		cipherText, _ := utils.Encrypt(data.OidcRegistrationToken)
+		settingsToUpdate["oidcRegistrationToken"] = cipherText
+	}
+	for key, value := range map[string]string{
+		"oidcAuthorizationEndpoint": data.OidcAuthorizationEndpoint,
+		"oidcTokenEndpoint":         data.OidcTokenEndpoint,
+		"oidcUserinfoEndpoint":      data.OidcUserinfoEndpoint,
+		"oidcJwksUri":               data.OidcJwksUri,
+		"oidcDeviceEndpoint":        data.OidcDeviceEndpoint,
+		"oidcVerificationUri":       data.OidcVerificationUri,
+	} {
+		if value == "" {
+			continue
+		}
+		if err := middleware.ValidateOIDCEndpoint(key, value); err != nil {
+			return nil, err
+		}
+		settingsToUpdate[key] = value
+	}
+
+	return settingsToUpdate, nil
+}
+
+// SettingsChange 配置项修改前后的值，敏感信息不返回原值
+type SettingsChange struct {
+	Key string  `json:"key"`
+	Old *string `json:"old"`
+	New *string `json:"new"`
+}
+
+// SettingsPreview 配置修改预览，Problems 不为空时无法应用
+type SettingsPreview struct {
+	Changes  []*SettingsChange `json:"changes"`
+	Problems []string          `json:"problems"`
+}
+
+// SettingsRevision 配置修改记录
+type SettingsRevision struct {
+	ID         uint              `json:"id"`
+	Operator   string            `json:"operator"`
+	Changes    []*SettingsChange `json:"changes"`
+	RollbackOf *uint             `json:"rollback_of"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// SettingsRevisionList 配置修改记录列表
+type SettingsRevisionList struct {
+	Items []*SettingsRevision `json:"items"`
+	Total int64               `json:"total"`
+}
+
+// Preview 校验配置并返回与当前配置的差异，不修改配置
+func (s *settings) Preview(data *SettingsUpdate) (*SettingsPreview, error) {
+
+	preview := &SettingsPreview{Changes: []*SettingsChange{}, Problems: []string{}}
+
+	values, err := s.collect(data)
+	if err != nil {
+		preview.Problems = append(preview.Problems, err.Error())
+		return preview, nil
+	}
+
+	current, err := s.current(values)
+	if err != nil {
+		return nil, err
+	}
+
+	preview.Problems = append(preview.Problems, s.lint(values, current)...)
+	preview.Changes = maskSettingsChanges(s.diff(values, current))
+
+	return preview, nil
+}
+
+// UpdateSettingValues 更新多个配置，校验通过后仅更新有变化的配置并记录修改历史
+func (s *settings) UpdateSettingValues(data *SettingsUpdate, operator string) (map[string]interface{}, error) {
+
+	values, err := s.collect(data)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := s.current(values)
+	if err != nil {
+		return nil, err
+	}
+
+	if problems := s.lint(values, current); len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "；"))
+	}
+
+	changes := s.diff(values, current)
+	if len(changes) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	return s.apply(changes, operator, nil)
+}
+
+// GetSettingsRevisionList 获取配置修改记录列表，敏感信息不返回原值
+func (s *settings) GetSettingsRevisionList(page, limit int) (*SettingsRevisionList, error) {
+
+	data, err := dao.Settings.GetSettingsRevisionList(page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	list := &SettingsRevisionList{Items: make([]*SettingsRevision, 0, len(data.Items)), Total: data.Total}
+	for _, item := range data.Items {
+		var changes []*SettingsChange
+		if err := json.Unmarshal([]byte(item.Changes), &changes); err != nil {
+			return nil, err
+		}
+		list.Items = append(list.Items, &SettingsRevision{
+			ID:         item.ID,
+			Operator:   item.Operator,
+			Changes:    maskSettingsChanges(changes),
+			RollbackOf: item.RollbackOf,
+			CreatedAt:  item.CreatedAt,
+		})
+	}
+
+	return list, nil
+}
+
+// Rollback 将配置恢复为指定修改记录之前的值，修改记录之后又被修改过的配置不允许回滚
+func (s *settings) Rollback(id uint, operator string) (map[string]interface{}, error) {
+
+	revision, err := dao.Settings.GetSettingsRevision(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*SettingsChange
+	if err := json.Unmarshal([]byte(revision.Changes), &changes); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{}, len(changes))
+	for _, change := range changes {
+		values[change.Key] = change.New
+	}
+	current, err := s.current(values)
+	if err != nil {
+		return nil, err
+	}
+
+	rollback := make([]*SettingsChange, 0, len(changes))
+	for _, change := range changes {
+		setting, ok := current[change.Key]
+		if !ok {
+			return nil, fmt.Errorf("配置项%s不存在", change.Key)
+		}
+		if !equalSettingValue(setting.Value, change.New) {
+			return nil, fmt.Errorf("配置项%s在该修改之后已被修改，无法回滚", change.Key)
+		}
+		rollback = append(rollback, &SettingsChange{Key: change.Key, Old: change.New, New: change.Old})
+	}
+
+	return s.apply(rollback, operator, &revision.ID)
+}
+
+// current 获取需要修改的配置项当前的值
+func (s *settings) current(values map[string]interface{}) (map[string]*model.Settings, error) {
+
+	settings, err := dao.Settings.GetSettingsByKeys(sortedKeys(values))
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]*model.Settings, len(settings))
+	for i := range settings {
+		current[settings[i].Key] = &settings[i]
+	}
+	return current, nil
+}
+
+// lint 校验配置项是否存在、值是否符合配置类型及取值范围，返回所有问题
+func (s *settings) lint(values map[string]interface{}, current map[string]*model.Settings) []string {
+
+	problems := make([]string, 0)
+	for _, key := range sortedKeys(values) {
+		setting, ok := current[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("配置项%s不存在", key))
+			continue
+		}
+		value := fmt.Sprint(values[key])
+
+		// 类型校验
+		switch setting.ValueType {
+		case "int":
+			if _, err := strconv.Atoi(value); err != nil {
+				problems = append(problems, fmt.Sprintf("配置项%s必须为整数", key))
+				continue
+			}
+		case "boolean":
+			if value != "true" && value != "false" {
+				problems = append(problems, fmt.Sprintf("配置项%s必须为true或false", key))
+				continue
+			}
+		case "list":
+			var list []string
+			if err := json.Unmarshal([]byte(value), &list); err != nil {
+				problems = append(problems, fmt.Sprintf("配置项%s必须为字符串数组（JSON）", key))
+				continue
+			}
+		}
+
+		// 取值范围校验
+		switch key {
+		case "externalUrl":
+			if u, err := url.Parse(value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				problems = append(problems, "外部访问地址必须为http或https开头的完整地址")
+			} else if strings.HasSuffix(value, "/") {
+				problems = append(problems, "外部访问地址不能以/结尾")
+			}
+		case "tokenExpiresTime":
+			if n, _ := strconv.Atoi(value); n <= 0 {
+				problems = append(problems, "Token过期时间必须大于0")
+			}
+		case "mailPort":
+			if n, _ := strconv.Atoi(value); n <= 0 || n > 65535 {
+				problems = append(problems, "邮件服务端口必须在1-65535之间")
+			}
+		case "smsProvider":
+			if value != "huawei" && value != "aliyun" {
+				problems = append(problems, "不支持的短信服务商："+value)
+			}
+		case "smsTemplates":
+			if _, err := parseSMSTemplates(value); err != nil {
+				problems = append(problems, err.Error())
+			}
+		case "corsPolicies":
+			if _, err := middleware.ParseCorsPolicies(value); err != nil {
+				problems = append(problems, err.Error())
+			}
+		case "publicRateLimit", "trustedRateLimit":
+			if n, _ := strconv.Atoi(value); n < 0 {
+				problems = append(problems, fmt.Sprintf("配置项%s不能小于0", key))
+			}
+		}
+	}
+
+	return problems
+}
+
+// diff 对比配置项修改前后的值，返回有变化的配置项（按配置项名称排序）
+func (s *settings) diff(values map[string]interface{}, current map[string]*model.Settings) []*SettingsChange {
+
+	changes := make([]*SettingsChange, 0, len(values))
+	for _, key := range sortedKeys(values) {
+		setting, ok := current[key]
+		if !ok {
+			continue
+		}
+		value := fmt.Sprint(values[key])
+		if equalSettingValue(setting.Value, &value) {
+			continue
+		}
+		changes = append(changes, &SettingsChange{Key: key, Old: setting.Value, New: &value})
+	}
+	return changes
+}
+
+// apply 应用配置修改、记录修改历史并重新加载配置
+func (s *settings) apply(changes []*SettingsChange, operator string, rollbackOf *uint) (map[string]interface{}, error) {
+
+	values := make(map[string]interface{}, len(changes))
+	for _, change := range changes {
+		values[change.Key] = change.New
+	}
+
+	record, err := json.Marshal(changes)
+	if err != nil {
+		return nil, err
+	}
+
 	// 开启事务
 	tx := global.MySQLClient.Begin()
 
 	// 批量更新
-	result, err := dao.Settings.UpdateSettings(tx, settingsToUpdate)
+	result, err := dao.Settings.UpdateSettings(tx, values)
 	if err != nil {
 		tx.Rollback()
 		return nil, err
 	}
 
+	// 记录修改历史
+	if err := dao.Settings.AddSettingsRevision(tx, &model.SettingsRevision{
+		Operator:   operator,
+		Changes:    string(record),
+		RollbackOf: rollbackOf,
+	}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// 提交事务
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
@@ -332,6 +1100,35 @@ func (s *settings) UpdateSettingValues(data *SettingsUpdate) (map[string]interfa
 	return result, nil
 }
 
+// maskSettingsChanges 隐藏敏感配置项修改前后的值
+func maskSettingsChanges(changes []*SettingsChange) []*SettingsChange {
+	masked := make([]*SettingsChange, 0, len(changes))
+	for _, change := range changes {
+		if utils.Contains(dao.SensitiveSettings, change.Key) {
+			mask := "******"
+			change = &SettingsChange{Key: change.Key, Old: &mask, New: &mask}
+		}
+		masked = append(masked, change)
+	}
+	return masked
+}
+
+func equalSettingValue(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // MailTest 发送邮件测试
 func (s *settings) MailTest(receiver string) error {
 
@@ -361,7 +1158,7 @@ func (s *settings) SmsTest(username string) error {
 	}
 
 	// 发送短信
-	if _, err := SMS.SMSSend(user.PhoneNumber, "测试"); err != nil {
+	if _, err := SMS.SMSSend(user.PhoneNumber, "测试", userLocale(user)); err != nil {
 		return err
 	}
 
@@ -459,7 +1256,7 @@ func (s *settings) CertUpdate(certificate, privateKey, publicKey string) (map[st
 		accounts       []model.Account
 		cfgs           []model.Settings
 		domainProvider []model.DomainServiceProvider
-		keys           = []string{"ldapBindPassword", "mailPassword", "smsAppSecret", "dingdingAppSecret", "feishuAppSecret", "wechatSecret"}
+		keys           = []string{"ldapBindPassword", "mailPassword", "smsAppSecret", "dingdingAppSecret", "feishuAppSecret", "wechatSecret", "itsmJiraToken", "itsmWebhookToken"}
 	)
 
 	// 开启事务
@@ -648,12 +1445,20 @@ func (s *settings) CertUpdate(certificate, privateKey, publicKey string) (map[st
 		logger.Warn("配置加载失败：" + err.Error())
 	}
 
+	// 重新加载密钥及证书
+	if err := utils.LoadKeyStore(); err != nil {
+		logger.Warn("密钥加载失败：" + err.Error())
+	}
+	if err := middleware.ReloadSigningKeys(); err != nil {
+		logger.Warn("签名密钥加载失败：" + err.Error())
+	}
+
 	return result, nil
 }
 
 func TestHTML() string {
 
-	issuer := config.Conf.Settings["issuer"].(string)
+	issuer := config.GetString("issuer")
 
 	return fmt.Sprintf(`
 		<!DOCTYPE html>
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/cache"
+	"ops-api/utils/check"
+	"time"
+)
+
+var Setup setup
+
+type setup struct{}
+
+const (
+	setupAdminUsername = "admin"        // 超级管理员用户名，CasBin规则中该用户拥有所有权限
+	setupTokenKey      = "setup_token"  // 初始化令牌在缓存中的Key
+	setupTokenTTL      = 24 * time.Hour // 初始化令牌有效期，过期后重启服务重新生成
+)
+
+// setupRoles 初始化时创建的默认角色，readOnly 为true时仅授予所有菜单及GET接口的权限
+var setupRoles = []struct {
+	name     string
+	readOnly bool
+}{
+	{name: "系统管理员"},
+	{name: "只读用户", readOnly: true},
+}
+
+// SetupCreate 系统初始化结构体
+type SetupCreate struct {
+	Token      string `json:"token" binding:"required"`       // 初始化令牌，系统未初始化时启动日志中输出
+	Password   string `json:"password" binding:"required"`    // 管理员密码
+	RePassword string `json:"re_password" binding:"required"` // 确认密码
+	Email      string `json:"email" binding:"omitempty,email"`
+}
+
+// SetupStatus 系统初始化状态
+type SetupStatus struct {
+	Initialized bool `json:"initialized"`
+}
+
+// SetupInit 首次启动时初始化系统：配置文件或环境变量中指定了初始管理员密码时直接创建管理员及默认角色，
+// 否则生成初始化令牌并输出到日志，通过初始化接口使用令牌创建管理员；已初始化时不做任何处理
+func SetupInit() error {
+
+	initialized, err := dao.Setup.IsInitialized()
+	if err != nil || initialized {
+		return err
+	}
+
+	if password := config.Conf.Admin.Password; password != "" {
+		if err := Setup.initialize(password, config.Conf.Admin.Email); err != nil {
+			return err
+		}
+		logger.Info("已使用配置的初始管理员密码完成系统初始化.")
+		return nil
+	}
+
+	token, err := Setup.token()
+	if err != nil {
+		return err
+	}
+	logger.Warn(fmt.Sprintf("系统尚未初始化，请调用 POST /api/v1/setup 创建管理员，初始化令牌：%s", token))
+	return nil
+}
+
+// token 获取初始化令牌，使用Redis等共享缓存时多个实例使用同一个令牌；
+// 使用内存缓存时每个实例的令牌不同，请在单实例运行时完成初始化
+func (s *setup) token() (string, error) {
+	if _, err := global.Cache.SetNX(setupTokenKey, utils.GenerateRandomString(32), setupTokenTTL); err != nil {
+		return "", err
+	}
+	return global.Cache.Get(setupTokenKey)
+}
+
+// GetStatus 获取系统初始化状态
+func (s *setup) GetStatus() (*SetupStatus, error) {
+	initialized, err := dao.Setup.IsInitialized()
+	if err != nil {
+		return nil, err
+	}
+	return &SetupStatus{Initialized: initialized}, nil
+}
+
+// Create 使用初始化令牌创建管理员及默认角色，仅能在系统未初始化时调用，初始化成功后令牌失效
+func (s *setup) Create(data *SetupCreate) error {
+
+	initialized, err := dao.Setup.IsInitialized()
+	if err != nil {
+		return err
+	}
+	if initialized {
+		return errors.New("系统已初始化")
+	}
+
+	token, err := global.Cache.Get(setupTokenKey)
+	if errors.Is(err, cache.ErrNotFound) {
+		return errors.New("初始化令牌不存在或已过期，请重启服务后从日志中获取")
+	}
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(data.Token), []byte(token)) != 1 {
+		return errors.New("初始化令牌错误")
+	}
+
+	if data.Password != data.RePassword {
+		return errors.New("两次输入的密码不匹配")
+	}
+	if err := check.PasswordCheck(data.Password); err != nil {
+		return err
+	}
+
+	if err := s.initialize(data.Password, data.Email); err != nil {
+		return err
+	}
+
+	global.Cache.Del(setupTokenKey)
+	return nil
+}
+
+// initialize 创建默认角色及权限，然后创建管理员；管理员创建后视为已初始化，失败时可重复执行
+func (s *setup) initialize(password, email string) error {
+
+	menus, err := dao.Setup.GetMenuNames()
+	if err != nil {
+		return err
+	}
+	paths, err := dao.Setup.GetPaths()
+	if err != nil {
+		return err
+	}
+
+	var adminRole *model.AuthGroup
+	for _, item := range setupRoles {
+		role, err := dao.Setup.GetOrCreateRole(item.name)
+		if err != nil {
+			return err
+		}
+		if !role.IsRoleGroup {
+			logger.Warn(fmt.Sprintf("分组（%s）已存在且不是角色，跳过默认权限初始化", role.Name))
+			continue
+		}
+		if adminRole == nil {
+			adminRole = role
+		}
+		if err := s.grant(role.Name, menus, paths, item.readOnly); err != nil {
+			return err
+		}
+	}
+
+	admin := &model.AuthUser{
+		Name:     "管理员",
+		Username: setupAdminUsername,
+		Password: password,
+		Email:    email,
+		IsActive: true,
+	}
+	if err := dao.Setup.CreateAdmin(admin, adminRole); err != nil {
+		return err
+	}
+
+	// CreateAdmin 直接写入了角色成员规则，需要重新加载策略
+	if err := global.CasBinServer.LoadPolicy(); err != nil {
+		return err
+	}
+
+	logger.Info("系统初始化成功，管理员账号：", setupAdminUsername)
+	return nil
+}
+
+// grant 为角色授予所有菜单及接口权限，已存在的权限不重复添加
+func (s *setup) grant(role string, menus []string, paths []*model.SystemPath, readOnly bool) error {
+	for _, menu := range menus {
+		if _, err := global.CasBinServer.AddNamedPolicy("p", role, menu, "read"); err != nil {
+			return err
+		}
+	}
+	for _, path := range paths {
+		if readOnly && path.Method != http.MethodGet {
+			continue
+		}
+		if _, err := global.CasBinServer.AddNamedPolicy("p", role, path.Path, path.Method); err != nil {
+			return err
+		}
+	}
+	return nil
+}
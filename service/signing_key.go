@@ -0,0 +1,146 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"gorm.io/gorm"
+	"ops-api/dao"
+	"ops-api/middleware"
+	"ops-api/model"
+	"ops-api/utils"
+	"time"
+)
+
+var SigningKey signingKey
+
+type signingKey struct{}
+
+// SigningKeyCreate 生成签名密钥结构体
+type SigningKeyCreate struct {
+	KeySize int `json:"key_size" binding:"omitempty,oneof=2048 3072 4096"` // 密钥长度，为空时为2048
+}
+
+// SigningKeyItem 签名密钥（列表）
+type SigningKeyItem struct {
+	*model.SigningKey
+	Published   bool       `json:"published"`    // 是否发布在JWKS中
+	UnpublishAt *time.Time `json:"unpublish_at"` // 停用的密钥从JWKS中移除的时间
+}
+
+// GetSigningKeyList 获取签名密钥列表
+func (s *signingKey) GetSigningKeyList() ([]*SigningKeyItem, error) {
+
+	keys, err := dao.SigningKey.GetSigningKeyList()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*SigningKeyItem, 0, len(keys))
+	for _, key := range keys {
+		item := &SigningKeyItem{SigningKey: key, Published: true}
+		if key.Status == middleware.SigningKeyRetired && key.RetiredAt != nil {
+			unpublishAt := key.RetiredAt.Add(middleware.SigningKeyRetention())
+			item.UnpublishAt = &unpublishAt
+			item.Published = time.Now().Before(unpublishAt)
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// AddSigningKey 生成签名密钥，新密钥立即发布在JWKS中，客户端刷新JWKS后再启用
+func (s *signingKey) AddSigningKey(data *SigningKeyCreate) (*model.SigningKey, error) {
+
+	keySize := data.KeySize
+	if keySize == 0 {
+		keySize = 2048
+	}
+
+	privateKey, publicKey, err := utils.GenerateRSAKeyPair(keySize)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := utils.ParseRSAPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	kid, err := utils.RSAKeyId(parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := dao.SigningKey.AddSigningKey(&model.SigningKey{
+		Kid:        kid,
+		KeySize:    keySize,
+		PrivateKey: privateKey,
+		PublicKey:  publicKey,
+		Status:     middleware.SigningKeyPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.reload()
+	return key, nil
+}
+
+// ActivateSigningKey 启用签名密钥（轮换），此后签发的Token使用该密钥签名，原签名密钥停用并在其签发的Token过期前继续发布
+func (s *signingKey) ActivateSigningKey(id uint, operator string) (*model.SigningKey, error) {
+
+	key, err := s.getSigningKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Status != middleware.SigningKeyPending {
+		return nil, errors.New("只能启用待启用的签名密钥")
+	}
+
+	if err := dao.SigningKey.ActivateSigningKey(key, middleware.SigningKeyActive, middleware.SigningKeyRetired); err != nil {
+		return nil, err
+	}
+
+	s.reload()
+	SecurityEvent.Publish(SecurityEventSigningKeyChanged, operator, key.Kid, "启用签名密钥")
+	return dao.SigningKey.GetSigningKey(id)
+}
+
+// RetireSigningKey 停用签名密钥，停用当前启用的签名密钥后使用系统配置的密钥签名
+func (s *signingKey) RetireSigningKey(id uint, operator string) (*model.SigningKey, error) {
+
+	key, err := s.getSigningKey(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.Status == middleware.SigningKeyRetired {
+		return nil, errors.New("签名密钥已停用")
+	}
+
+	if err := dao.SigningKey.RetireSigningKey(key, middleware.SigningKeyRetired); err != nil {
+		return nil, err
+	}
+
+	s.reload()
+	SecurityEvent.Publish(SecurityEventSigningKeyChanged, operator, key.Kid, fmt.Sprintf("停用签名密钥，%s后从JWKS中移除", middleware.SigningKeyRetention()))
+	return dao.SigningKey.GetSigningKey(id)
+}
+
+// getSigningKey 获取签名密钥
+func (s *signingKey) getSigningKey(id uint) (*model.SigningKey, error) {
+	key, err := dao.SigningKey.GetSigningKey(id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("签名密钥不存在")
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// reload 重新加载当前实例的签名密钥，其它实例在本地缓存过期后加载
+func (s *signingKey) reload() {
+	if err := middleware.ReloadSigningKeys(); err != nil {
+		logger.Error("签名密钥加载失败：" + err.Error())
+	}
+}
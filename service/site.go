@@ -1,6 +1,9 @@
 package service
 
 import (
+	"encoding/json"
+	"errors"
+	"ops-api/config"
 	"ops-api/dao"
 	"ops-api/global"
 	"ops-api/model"
@@ -17,20 +20,59 @@ type SiteGroupCreate struct {
 
 // SiteCreate 创建站点结构体，定义新增时的字段信息
 type SiteCreate struct {
-	Name        string `json:"name" binding:"required"`
-	Address     string `json:"address" binding:"required"`
-	SSO         *bool  `json:"sso" binding:"required"`
-	SSOType     uint   `json:"sso_type"`
-	Icon        string `json:"icon"`
-	CallbackUrl string `json:"callback_url"`
-	EntityId    string `json:"entity_id"`
-	Certificate string `json:"certificate"`
-	Description string `json:"description" binding:"required"`
-	SiteGroupID uint   `json:"site_group_id" binding:"required"`
-	DomainId    string `json:"domain_id"`
-	RedirectUrl string `json:"redirect_url"`
-	IDPName     string `json:"idp_name"`
-	HelperUrl   string `json:"helper_url"`
+	Name             string `json:"name" binding:"required"`
+	Address          string `json:"address" binding:"required"`
+	SSO              *bool  `json:"sso" binding:"required"`
+	SSOType          uint   `json:"sso_type"`
+	Icon             string `json:"icon"`
+	CallbackUrl      string `json:"callback_url"`
+	EntityId         string `json:"entity_id"`
+	Certificate      string `json:"certificate"`
+	MetadataUrl      string `json:"metadata_url"`
+	MetadataXML      string `json:"metadata_xml"` // SAML2.0 SP Metadata XML，SP无法从平台访问或未提供Metadata地址时使用，优先于Metadata地址
+	AcsUrls          string `json:"acs_urls"`     // SAML2.0 SP ACS地址（JSON数组），可从SP Metadata中获取或手动维护
+	SloUrl           string `json:"slo_url"`      // SAML2.0 SP 单点注销地址，可从SP Metadata中获取或手动维护
+	Description      string `json:"description" binding:"required"`
+	SiteGroupID      uint   `json:"site_group_id" binding:"required"`
+	DomainId         string `json:"domain_id"`
+	RedirectUrl      string `json:"redirect_url"`
+	IDPName          string `json:"idp_name"`
+	HelperUrl        string `json:"helper_url"`
+	ClaimMapping     string `json:"claim_mapping"`
+	SAMLAttributes   string `json:"saml_attributes"`                                        // SAML2.0 属性发布策略（JSON数组），为空时发布默认属性
+	EncryptAssertion bool   `json:"encrypt_assertion"`                                      // SAML2.0 是否加密断言
+	EncryptionCert   string `json:"encryption_certificate"`                                 // SAML2.0 SP 加密证书，为空时从SP Metadata中获取，仍为空时使用SP证书
+	KeyEncryption    string `json:"key_encryption_algorithm"`                               // SAML2.0 密钥加密算法，为空时为rsa-oaep-mgf1p
+	DataEncryption   string `json:"data_encryption_algorithm"`                              // SAML2.0 数据加密算法，为空时为aes256-cbc
+	SignatureAlg     string `json:"signature_algorithm"`                                    // SAML2.0 签名算法，为空时为rsa-sha256
+	DigestAlg        string `json:"digest_algorithm"`                                       // SAML2.0 摘要算法，为空时为sha256
+	SigningLevel     string `json:"signing_level"`                                          // SAML2.0 签名位置：assertion、response、both，为空时仅签名断言
+	SubjectType      string `json:"subject_type" binding:"omitempty,oneof=public pairwise"` // OIDC sub类型，为空时为public
+	SectorId         string `json:"sector_identifier"`
+	NginxTTL         uint   `json:"nginx_ttl"`                  // Nginx 票据有效期（小时），为空时为12小时
+	GrantTypes       string `json:"grant_types"`                // OAuth2.0 允许使用的授权类型，多个以空格分隔，为空时不限制
+	RespTypes        string `json:"response_types"`             // OAuth2.0 允许使用的响应类型，多个以逗号分隔（如：code,code id_token），为空时仅允许code
+	Scopes           string `json:"scopes"`                     // OAuth2.0 允许申请的Scope，多个以空格分隔，为空时不限制
+	Resources        string `json:"resources"`                  // OAuth2.0 允许申请的资源（RFC 8707），多个以空格分隔，为空时不允许申请资源
+	PKCE             string `json:"pkce"`                       // OAuth2.0 PKCE：为空时可选，required 必须使用，public 为公共客户端
+	RefreshTTL       uint   `json:"refresh_token_ttl"`          // OAuth2.0 刷新令牌有效期（天），为空时为30天
+	RefreshMaxTTL    uint   `json:"refresh_token_max_ttl"`      // OAuth2.0 刷新令牌自授权起的最长有效期（天），为空时为365天
+	CodeTTL          uint   `json:"code_ttl"`                   // OAuth2.0 授权码有效期（秒），为空时为10秒
+	AccessTTL        uint   `json:"access_token_ttl"`           // OAuth2.0 Access Token有效期（秒），为空时与平台登录Token的有效期一致
+	TicketTTL        uint   `json:"ticket_ttl"`                 // CAS3.0 票据有效期（秒），为空时为10秒
+	TokenAuthMethod  string `json:"token_endpoint_auth_method"` // OAuth2.0 Token接口客户端认证方式，为空时允许client_secret_basic及client_secret_post
+	JwtPublicKey     string `json:"jwt_public_key"`             // OAuth2.0 private_key_jwt 客户端公钥（PEM格式的公钥或证书）
+	NginxRenewal     bool   `json:"nginx_renewal"`              // Nginx 票据自动续期
+	NginxGrace       uint   `json:"nginx_grace"`                // Nginx 票据续期后旧票据的宽限时间（秒），为空时为60秒
+	Template         string `json:"template"`                   // 集成模板标识，为空时不使用模板
+	CASProfile       string `json:"cas_profile"`                // CAS3.0 票据校验响应格式，为空时使用默认格式
+	CASLogout        bool   `json:"cas_logout"`                 // CAS3.0 单点注销
+	PublicDir        bool   `json:"public_directory"`           // 是否在公开应用目录中展示
+	LaunchHook       string `json:"launch_hook"`                // 单点登录成功后异步通知的Webhook地址，为空时不通知
+	LaunchSecret     string `json:"launch_secret"`              // Webhook签名密钥
+	Consent          bool   `json:"consent"`                    // OAuth2.0 授权前需要用户确认授权
+	Sunset           string `json:"sunset"`                     // 单点登录停用日期（YYYY-MM-DD），为空时不停用
+	OwnerEmail       string `json:"owner_email"`                // 应用负责人邮箱，多个以逗号分隔
 }
 
 // SiteGroupUpdate 更新分组名称构体
@@ -69,6 +111,14 @@ func (s *site) GetSiteGuideList(name string) (data *dao.SiteGuideList, err error
 	return data, nil
 }
 
+// GetPublicDirectory 获取公开应用目录，未开启公开应用目录（publicDirectory）时返回错误
+func (s *site) GetPublicDirectory() ([]*dao.PublicDirectoryItem, error) {
+	if enabled := config.GetBool("publicDirectory"); !enabled {
+		return nil, errors.New("公开应用目录未开启")
+	}
+	return dao.Site.GetPublicDirectory()
+}
+
 // AddGroup 创建站点分组
 func (s *site) AddGroup(data *SiteGroupCreate) (siteGroup *model.SiteGroup, err error) {
 
@@ -81,24 +131,163 @@ func (s *site) AddGroup(data *SiteGroupCreate) (siteGroup *model.SiteGroup, err
 
 // AddSite 创建站点
 func (s *site) AddSite(data *SiteCreate) (site *model.Site, err error) {
+	// 使用集成模板填充站点配置
+	if data.Template != "" {
+		if err := s.applySiteTemplate(data); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验OAuth2.0授权类型、响应类型及Scope
+	if err := validateOAuthPolicy(data.GrantTypes, data.RespTypes, data.Scopes); err != nil {
+		return nil, err
+	}
+	if err := validatePKCEMode(data.PKCE); err != nil {
+		return nil, err
+	}
+	if err := validateResources(data.Resources); err != nil {
+		return nil, err
+	}
+	if err := validateTokenAuthMethod(data.TokenAuthMethod, data.JwtPublicKey); err != nil {
+		return nil, err
+	}
+	if err := validateRefreshTokenTTL(data.RefreshTTL); err != nil {
+		return nil, err
+	}
+	if err := validateRefreshTokenTTL(data.RefreshMaxTTL); err != nil {
+		return nil, err
+	}
+	if err := validateTokenTTL(data.CodeTTL, data.AccessTTL, data.TicketTTL); err != nil {
+		return nil, err
+	}
+
+	// 校验CAS3.0响应格式
+	if err := validateCASProfile(data.CASProfile); err != nil {
+		return nil, err
+	}
+
+	// 校验属性映射
+	if err := validateClaimMapping(data.ClaimMapping); err != nil {
+		return nil, err
+	}
+
+	// 校验SAML2属性发布策略
+	if err := validateSAMLAttributes(data.SAMLAttributes); err != nil {
+		return nil, err
+	}
+
+	// 校验SAML2断言加密及签名配置
+	if err := validateSAMLEncryption(data.KeyEncryption, data.DataEncryption, data.EncryptionCert); err != nil {
+		return nil, err
+	}
+	if err := validateSAMLSigning(data.SignatureAlg, data.DigestAlg, data.SigningLevel); err != nil {
+		return nil, err
+	}
+
+	// 校验单点登录通知地址
+	if err := validateLaunchHook(data.LaunchHook); err != nil {
+		return nil, err
+	}
+
+	// 校验单点登录停用日期及应用负责人邮箱
+	if err := validateSunset(data.Sunset, data.OwnerEmail); err != nil {
+		return nil, err
+	}
+
+	// 校验ACS地址及单点注销地址，未手动配置时从SP Metadata中获取（开启断言加密时同时获取SP加密证书），
+	// 上传了SP Metadata XML时同时获取未配置的EntityID及SP证书
+	if _, err := parseAcsUrls(data.AcsUrls); err != nil {
+		return nil, err
+	}
+	if err := validateSloUrl(data.SloUrl); err != nil {
+		return nil, err
+	}
+	var metadata *SPMetadata
+	if data.MetadataXML != "" {
+		metadata, err = SSO.ParseSPMetadataXML([]byte(data.MetadataXML))
+		if err != nil {
+			return nil, err
+		}
+		if data.EntityId == "" {
+			data.EntityId = metadata.EntityID
+		}
+		if data.Certificate == "" {
+			data.Certificate = metadata.Certificate
+		}
+	} else if (data.AcsUrls == "" || data.SloUrl == "" || (data.EncryptAssertion && data.EncryptionCert == "")) && data.MetadataUrl != "" {
+		metadata, err = SSO.ParseSPMetadata(data.MetadataUrl)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if metadata != nil {
+		if data.AcsUrls == "" && len(metadata.AcsUrls) > 0 {
+			acsUrls, _ := json.Marshal(metadata.AcsUrls)
+			data.AcsUrls = string(acsUrls)
+		}
+		if data.SloUrl == "" {
+			data.SloUrl = metadata.SloUrl
+		}
+		if data.EncryptAssertion && data.EncryptionCert == "" {
+			data.EncryptionCert = metadata.EncryptionCertificate
+		}
+	}
+
 	// 开启事务
 	tx := global.MySQLClient.Begin()
 
 	group := &model.Site{
-		Name:        data.Name,
-		Address:     data.Address,
-		SSO:         *data.SSO,
-		SSOType:     data.SSOType,
-		Icon:        &data.Icon,
-		CallbackUrl: data.CallbackUrl,
-		Description: data.Description,
-		SiteGroupID: data.SiteGroupID,
-		EntityId:    data.EntityId,
-		Certificate: data.Certificate,
-		DomainId:    data.DomainId,
-		RedirectUrl: data.RedirectUrl,
-		IDPName:     data.IDPName,
-		HelperUrl:   data.HelperUrl,
+		Name:             data.Name,
+		Address:          data.Address,
+		SSO:              *data.SSO,
+		SSOType:          data.SSOType,
+		Icon:             &data.Icon,
+		CallbackUrl:      data.CallbackUrl,
+		Description:      data.Description,
+		SiteGroupID:      data.SiteGroupID,
+		EntityId:         data.EntityId,
+		Certificate:      data.Certificate,
+		MetadataUrl:      data.MetadataUrl,
+		AcsUrls:          data.AcsUrls,
+		SloUrl:           data.SloUrl,
+		DomainId:         data.DomainId,
+		RedirectUrl:      data.RedirectUrl,
+		IDPName:          data.IDPName,
+		HelperUrl:        data.HelperUrl,
+		ClaimMapping:     data.ClaimMapping,
+		SAMLAttributes:   data.SAMLAttributes,
+		EncryptAssertion: data.EncryptAssertion,
+		EncryptionCert:   data.EncryptionCert,
+		KeyEncryption:    data.KeyEncryption,
+		DataEncryption:   data.DataEncryption,
+		SignatureAlg:     data.SignatureAlg,
+		DigestAlg:        data.DigestAlg,
+		SigningLevel:     data.SigningLevel,
+		SubjectType:      data.SubjectType,
+		SectorId:         data.SectorId,
+		NginxTTL:         data.NginxTTL,
+		GrantTypes:       data.GrantTypes,
+		RespTypes:        data.RespTypes,
+		Scopes:           data.Scopes,
+		Resources:        data.Resources,
+		PKCE:             data.PKCE,
+		RefreshTTL:       data.RefreshTTL,
+		RefreshMaxTTL:    data.RefreshMaxTTL,
+		CodeTTL:          data.CodeTTL,
+		AccessTTL:        data.AccessTTL,
+		TicketTTL:        data.TicketTTL,
+		TokenAuthMethod:  data.TokenAuthMethod,
+		JwtPublicKey:     data.JwtPublicKey,
+		NginxRenewal:     data.NginxRenewal,
+		NginxGrace:       data.NginxGrace,
+		CASProfile:       data.CASProfile,
+		CASLogout:        data.CASLogout,
+		PublicDir:        data.PublicDir,
+		LaunchHook:       data.LaunchHook,
+		LaunchSecret:     data.LaunchSecret,
+		Consent:          data.Consent,
+		Sunset:           data.Sunset,
+		OwnerEmail:       data.OwnerEmail,
 	}
 
 	// 创建数据库数据
@@ -134,6 +323,179 @@ func (s *site) UpdateGroup(data *SiteGroupUpdate) (*model.SiteGroup, error) {
 // UpdateSite 更新站点
 func (s *site) UpdateSite(data *dao.UpdateSite) (*model.Site, error) {
 
+	// 校验OAuth2.0授权类型、响应类型及Scope
+	var grantTypes, respTypes, scopes string
+	if data.GrantTypes != nil {
+		grantTypes = *data.GrantTypes
+	}
+	if data.RespTypes != nil {
+		respTypes = *data.RespTypes
+	}
+	if data.Scopes != nil {
+		scopes = *data.Scopes
+	}
+	if err := validateOAuthPolicy(grantTypes, respTypes, scopes); err != nil {
+		return nil, err
+	}
+	if data.PKCE != nil {
+		if err := validatePKCEMode(*data.PKCE); err != nil {
+			return nil, err
+		}
+	}
+	if data.Resources != nil {
+		if err := validateResources(*data.Resources); err != nil {
+			return nil, err
+		}
+	}
+	if data.TokenAuthMethod != nil || data.JwtPublicKey != nil {
+		var method, publicKey string
+		if data.TokenAuthMethod != nil {
+			method = *data.TokenAuthMethod
+		}
+		if data.JwtPublicKey != nil {
+			publicKey = *data.JwtPublicKey
+		}
+		if err := validateTokenAuthMethod(method, publicKey); err != nil {
+			return nil, err
+		}
+	}
+	if data.RefreshTTL != nil {
+		if err := validateRefreshTokenTTL(*data.RefreshTTL); err != nil {
+			return nil, err
+		}
+	}
+	if data.RefreshMaxTTL != nil {
+		if err := validateRefreshTokenTTL(*data.RefreshMaxTTL); err != nil {
+			return nil, err
+		}
+	}
+	if data.CodeTTL != nil || data.AccessTTL != nil || data.TicketTTL != nil {
+		var codeTTL, accessTTL, ticketTTL uint
+		if data.CodeTTL != nil {
+			codeTTL = *data.CodeTTL
+		}
+		if data.AccessTTL != nil {
+			accessTTL = *data.AccessTTL
+		}
+		if data.TicketTTL != nil {
+			ticketTTL = *data.TicketTTL
+		}
+		if err := validateTokenTTL(codeTTL, accessTTL, ticketTTL); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验CAS3.0响应格式
+	if data.CASProfile != nil {
+		if err := validateCASProfile(*data.CASProfile); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验属性映射
+	if data.ClaimMapping != nil {
+		if err := validateClaimMapping(*data.ClaimMapping); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验SAML2属性发布策略
+	if data.SAMLAttributes != nil {
+		if err := validateSAMLAttributes(*data.SAMLAttributes); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验SAML2断言加密配置
+	if data.KeyEncryption != nil || data.DataEncryption != nil || data.EncryptionCert != nil {
+		var keyAlgorithm, dataAlgorithm, certificate string
+		if data.KeyEncryption != nil {
+			keyAlgorithm = *data.KeyEncryption
+		}
+		if data.DataEncryption != nil {
+			dataAlgorithm = *data.DataEncryption
+		}
+		if data.EncryptionCert != nil {
+			certificate = *data.EncryptionCert
+		}
+		if err := validateSAMLEncryption(keyAlgorithm, dataAlgorithm, certificate); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验SAML2签名配置
+	if data.SignatureAlg != nil || data.DigestAlg != nil || data.SigningLevel != nil {
+		var signatureAlgorithm, digestAlgorithm, level string
+		if data.SignatureAlg != nil {
+			signatureAlgorithm = *data.SignatureAlg
+		}
+		if data.DigestAlg != nil {
+			digestAlgorithm = *data.DigestAlg
+		}
+		if data.SigningLevel != nil {
+			level = *data.SigningLevel
+		}
+		if err := validateSAMLSigning(signatureAlgorithm, digestAlgorithm, level); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验单点登录通知地址
+	if data.LaunchHook != nil {
+		if err := validateLaunchHook(*data.LaunchHook); err != nil {
+			return nil, err
+		}
+	}
+
+	// 校验单点登录停用日期及应用负责人邮箱
+	if data.Sunset != nil || data.OwnerEmail != nil {
+		var sunset, ownerEmail string
+		if data.Sunset != nil {
+			sunset = *data.Sunset
+		}
+		if data.OwnerEmail != nil {
+			ownerEmail = *data.OwnerEmail
+		}
+		if err := validateSunset(sunset, ownerEmail); err != nil {
+			return nil, err
+		}
+	}
+
+	// 上传了SP Metadata XML时，从中获取未配置的EntityID、SP证书、ACS地址及单点注销地址（开启断言加密时同时获取SP加密证书）
+	if data.MetadataXML != "" {
+		metadata, err := SSO.ParseSPMetadataXML([]byte(data.MetadataXML))
+		if err != nil {
+			return nil, err
+		}
+		fill := func(field **string, value string) {
+			if (*field == nil || **field == "") && value != "" {
+				*field = &value
+			}
+		}
+		fill(&data.EntityId, metadata.EntityID)
+		fill(&data.Certificate, metadata.Certificate)
+		fill(&data.SloUrl, metadata.SloUrl)
+		if len(metadata.AcsUrls) > 0 {
+			acsUrls, _ := json.Marshal(metadata.AcsUrls)
+			fill(&data.AcsUrls, string(acsUrls))
+		}
+		if data.EncryptAssertion != nil && *data.EncryptAssertion {
+			fill(&data.EncryptionCert, metadata.EncryptionCertificate)
+		}
+	}
+
+	// 校验ACS地址及单点注销地址
+	if data.AcsUrls != nil {
+		if _, err := parseAcsUrls(*data.AcsUrls); err != nil {
+			return nil, err
+		}
+	}
+	if data.SloUrl != nil {
+		if err := validateSloUrl(*data.SloUrl); err != nil {
+			return nil, err
+		}
+	}
+
 	// 开启事务
 	tx := global.MySQLClient.Begin()
 
@@ -0,0 +1,317 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"html"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/notify"
+	"strings"
+	"time"
+)
+
+// spCertificateExpireDays SP证书过期提醒的提前天数
+const spCertificateExpireDays = 30
+
+// SiteCertificateCreate 新增SP证书结构体
+type SiteCertificateCreate struct {
+	SiteID      uint   `json:"site_id" binding:"required"`
+	Name        string `json:"name" binding:"required"`
+	Certificate string `json:"certificate" binding:"required"`
+}
+
+// SiteCertificateUpdate 修改SP证书结构体
+type SiteCertificateUpdate struct {
+	ID      uint   `json:"id" binding:"required"`
+	Name    string `json:"name" binding:"required"`
+	Enabled *bool  `json:"enabled" binding:"required"`
+}
+
+// spCertificateExpired SP证书过期信息
+type spCertificateExpired struct {
+	SiteName  string
+	Name      string
+	NotAfter  time.Time
+	IsExpired bool
+}
+
+// normalizeCertificatePEM 为证书加上头尾，SP元数据中的证书通常不包含头尾
+func normalizeCertificatePEM(certificate string) string {
+	certificate = strings.TrimSpace(certificate)
+	if !strings.HasPrefix(certificate, "-----BEGIN CERTIFICATE-----") && !strings.HasSuffix(certificate, "-----END CERTIFICATE-----") {
+		certificate = fmt.Sprintf("-----BEGIN CERTIFICATE-----\n%s\n-----END CERTIFICATE-----\n", certificate)
+	}
+	return certificate
+}
+
+// GetSiteCertificates 获取站点的SP证书
+func (s *site) GetSiteCertificates(siteId uint) ([]*model.SiteCertificate, error) {
+	return dao.Site.GetSiteCertificates(siteId)
+}
+
+// AddSiteCertificate 新增SP证书，证书有效期从证书中读取
+func (s *site) AddSiteCertificate(data *SiteCertificateCreate) (*model.SiteCertificate, error) {
+
+	var site model.Site
+	if err := global.MySQLClient.First(&site, data.SiteID).Error; err != nil {
+		return nil, err
+	}
+	if site.SSOType != 3 {
+		return nil, errors.New("仅SAML2.0站点支持配置SP证书")
+	}
+
+	certificate := normalizeCertificatePEM(data.Certificate)
+	crt, err := parseCertificate(certificate)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(crt.NotAfter) {
+		return nil, errors.New("证书已过期")
+	}
+
+	sum := sha256.Sum256(crt.Raw)
+	fingerprint := hex.EncodeToString(sum[:])
+
+	// 同一站点不允许重复添加相同的证书
+	certificates, err := dao.Site.GetSiteCertificates(site.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range certificates {
+		if item.Fingerprint == fingerprint {
+			return nil, errors.New("证书已存在")
+		}
+	}
+
+	return dao.Site.AddSiteCertificate(&model.SiteCertificate{
+		SiteID:      site.ID,
+		Name:        data.Name,
+		Certificate: certificate,
+		Fingerprint: fingerprint,
+		NotBefore:   crt.NotBefore,
+		NotAfter:    crt.NotAfter,
+		Enabled:     true,
+	})
+}
+
+// UpdateSiteCertificate 修改SP证书，停用的证书不再用于校验SP请求签名
+func (s *site) UpdateSiteCertificate(data *SiteCertificateUpdate) error {
+	if _, err := dao.Site.GetSiteCertificate(data.ID); err != nil {
+		return err
+	}
+	return dao.Site.UpdateSiteCertificate(data.ID, data.Name, *data.Enabled)
+}
+
+// DeleteSiteCertificate 删除SP证书
+func (s *site) DeleteSiteCertificate(id uint) error {
+	return dao.Site.DeleteSiteCertificate(id)
+}
+
+// spCertificates 获取用于校验SP请求签名的证书，站点证书字段中的证书优先，其次为在有效期内的SP证书
+func spCertificates(site *model.Site) []string {
+
+	var certificates []string
+	if strings.TrimSpace(site.Certificate) != "" {
+		certificates = append(certificates, normalizeCertificatePEM(site.Certificate))
+	}
+
+	items, err := dao.Site.GetActiveSiteCertificates(site.ID, time.Now())
+	if err != nil {
+		logger.Error("ERROR：获取SP证书失败，", err.Error())
+		return certificates
+	}
+	for _, item := range items {
+		certificates = append(certificates, item.Certificate)
+	}
+
+	return certificates
+}
+
+// SPCertificateExpiredNotice SP证书过期提醒（定时任务调用），包括站点证书字段中的证书及已启用的SP证书
+func (s *site) SPCertificateExpiredNotice(task *model.ScheduledTask) error {
+
+	if task.NotifyType == nil || task.Receiver == nil || *task.Receiver == "" {
+		return errors.New("未配置通知方式及接收人")
+	}
+
+	var (
+		now      = time.Now()
+		deadline = now.AddDate(0, 0, spCertificateExpireDays)
+		expired  []*spCertificateExpired
+	)
+
+	sites, err := dao.Site.GetSamlSites()
+	if err != nil {
+		return err
+	}
+	siteNames := make(map[uint]string, len(sites))
+	for _, site := range sites {
+		siteNames[site.ID] = site.Name
+		if strings.TrimSpace(site.Certificate) == "" {
+			continue
+		}
+		crt, err := parseCertificate(normalizeCertificatePEM(site.Certificate))
+		if err != nil || crt.NotAfter.After(deadline) {
+			continue
+		}
+		expired = append(expired, &spCertificateExpired{SiteName: site.Name, Name: "默认证书", NotAfter: crt.NotAfter, IsExpired: crt.NotAfter.Before(now)})
+	}
+
+	certificates, err := dao.Site.GetExpiringSiteCertificates(deadline)
+	if err != nil {
+		return err
+	}
+	for _, item := range certificates {
+		siteName, ok := siteNames[item.SiteID]
+		if !ok {
+			continue
+		}
+		expired = append(expired, &spCertificateExpired{SiteName: siteName, Name: item.Name, NotAfter: item.NotAfter, IsExpired: item.NotAfter.Before(now)})
+	}
+
+	if len(expired) == 0 {
+		logger.Info("检查SP证书状态正常.")
+		return nil
+	}
+
+	// 生成通知内容（1：邮件 HTML，3：富文本，其它： Markdown 文档）
+	var message string
+	switch *task.NotifyType {
+	case 1:
+		message = spCertificateExpiredNoticeHTML(expired)
+	case 3:
+		jsonBytes, _ := json.Marshal(spCertificateExpiredNoticePost(expired))
+		message = string(jsonBytes)
+	default:
+		message = spCertificateExpiredNoticeMarkdown(expired)
+	}
+
+	// 发送告警
+	notifier := notify.GetNotifier(*task)
+	if notifier == nil {
+		return errors.New("不支持的通知方式")
+	}
+	return notifier.SendNotify(message, "SP证书过期提醒")
+}
+
+// statusText 证书状态
+func (e *spCertificateExpired) statusText() string {
+	if e.IsExpired {
+		return "已过期"
+	}
+	return "即将过期"
+}
+
+// spCertificateExpiredNoticePost 生成飞书 Post 格式的富文本内容
+func spCertificateExpiredNoticePost(items []*spCertificateExpired) map[string]interface{} {
+	var (
+		issuer  = config.GetString("issuer")
+		content = make([][]map[string]interface{}, 0)
+	)
+
+	for i, item := range items {
+		statusColor := "orange"
+		if item.IsExpired {
+			statusColor = "red"
+		}
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("%d. 站点：", i+1)},
+			{"tag": "text", "text": item.SiteName, "bold": true},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("   证书：%s", item.Name)},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": "   到期时间："},
+			{"tag": "text", "text": utils.FormatTime(item.NotAfter)},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": "   状态："},
+			{"tag": "text", "text": item.statusText(), "text_color": statusColor},
+		})
+	}
+
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": "--------------------------------\n"},
+	})
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": fmt.Sprintf("来源：%s", issuer)},
+	})
+
+	return map[string]interface{}{
+		"msg_type": "post",
+		"content": map[string]interface{}{
+			"post": map[string]interface{}{
+				"zh_cn": map[string]interface{}{
+					"title":   "SP证书过期提醒：",
+					"content": content,
+				},
+			},
+		},
+	}
+}
+
+// spCertificateExpiredNoticeMarkdown 生成SP证书过期通知 Markdown 文档
+func spCertificateExpiredNoticeMarkdown(items []*spCertificateExpired) string {
+	var (
+		builder = &strings.Builder{}
+		issuer  = config.GetString("issuer")
+	)
+
+	builder.WriteString("**SP证书过期提醒：**\n\n")
+
+	for i, item := range items {
+		builder.WriteString(fmt.Sprintf("%d. 站点：%s\n\n", i+1, item.SiteName))
+		builder.WriteString(fmt.Sprintf("   证书：%s\n\n", item.Name))
+		builder.WriteString(fmt.Sprintf("   到期时间：%s\n\n", utils.FormatTime(item.NotAfter)))
+		builder.WriteString(fmt.Sprintf("   状态：<font color=\"warning\">%s</font>\n\n", item.statusText()))
+	}
+
+	builder.WriteString("--------------------------------\n")
+	builder.WriteString(fmt.Sprintf("来源：%s\n", issuer))
+
+	return builder.String()
+}
+
+// spCertificateExpiredNoticeHTML SP证书过期通知 HTML
+func spCertificateExpiredNoticeHTML(items []*spCertificateExpired) string {
+
+	issuer := config.GetString("issuer")
+
+	var rows strings.Builder
+	for _, item := range items {
+		rows.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>",
+			html.EscapeString(item.SiteName),
+			html.EscapeString(item.Name),
+			utils.FormatTime(item.NotAfter),
+			item.statusText()))
+	}
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="UTF-8">
+			<title>SP证书过期提醒</title>
+		</head>
+		<body>
+			<p>以下SAML应用的SP证书已过期或将在%d天内过期，请联系应用管理员及时上传新证书：</p>
+			<table border="1" cellspacing="0" cellpadding="6">
+				<tr><th>站点</th><th>证书</th><th>到期时间</th><th>状态</th></tr>
+				%s
+			</table>
+			<br>
+			<p>来源：%s</p>
+			<p style="color: red">此邮件为系统自动发送，请勿回复此邮件。</p>
+		</body>
+		</html>
+	`, spCertificateExpireDays, rows.String(), html.EscapeString(issuer))
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"html"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	mailer "ops-api/utils/mail"
+	"strings"
+	"time"
+)
+
+var SiteGrant siteGrant
+
+type siteGrant struct{}
+
+// 授权有效期：可为用户访问站点的授权设置到期时间，“站点授权到期撤销”任务撤销已到期的授权、记录操作日志并通知应用负责人，
+// 即将到期的授权每天提醒一次应用负责人，可通过复核接口列出即将到期的授权并延长有效期（重新认证）
+
+const (
+	siteGrantReviewDays     = 30                   // 复核接口默认列出的到期天数
+	siteGrantNoticeDays     = 7                    // 到期前提醒应用负责人的天数
+	siteGrantNoticeKey      = "site_grant_notice:" // 即将到期提醒发送记录Key前缀，同一应用每天只提醒一次
+	siteGrantNoticeInterval = 24 * time.Hour
+	siteGrantAuditUser      = "系统" // 撤销到期授权时操作日志中的操作人
+)
+
+// SiteGrantExpiryUpdate 设置授权有效期结构体
+type SiteGrantExpiryUpdate struct {
+	SiteID    uint       `json:"site_id" binding:"required"`
+	Users     []uint     `json:"users" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expires_at"` // 到期时间，为空时永久有效
+}
+
+// siteGrantNotice 单个应用的授权到期通知
+type siteGrantNotice struct {
+	site     *dao.SiteGrantItem
+	revoked  []*dao.SiteGrantItem
+	expiring []*dao.SiteGrantItem
+}
+
+// UpdateExpiry 设置用户访问站点授权的有效期，用户必须已被授权访问站点
+func (g *siteGrant) UpdateExpiry(data *SiteGrantExpiryUpdate, operator string) error {
+
+	site := &model.Site{}
+	if err := global.MySQLClient.First(site, data.SiteID).Error; err != nil {
+		return err
+	}
+	if site.AllOpen {
+		return errors.New("对所有人开放的站点无需设置授权有效期")
+	}
+	if data.ExpiresAt != nil && !data.ExpiresAt.After(time.Now()) {
+		return errors.New("到期时间必须晚于当前时间")
+	}
+
+	var (
+		users []uint
+		seen  = make(map[uint]bool)
+	)
+	for _, id := range data.Users {
+		if !seen[id] {
+			seen[id] = true
+			users = append(users, id)
+		}
+	}
+	granted, err := dao.SiteGrant.GetGrantedUserIds(site.ID, users)
+	if err != nil {
+		return err
+	}
+	if len(granted) != len(users) {
+		return errors.New("部分用户未被授权访问该站点")
+	}
+
+	return dao.SiteGrant.SaveSiteGrants(site.ID, users, data.ExpiresAt, operator)
+}
+
+// GetExpiringList 获取指定天数内到期的授权（包含已到期尚未撤销的授权），用于授权复核，days 为0时为30天
+func (g *siteGrant) GetExpiringList(days int) ([]*dao.SiteGrantItem, error) {
+	if days <= 0 {
+		days = siteGrantReviewDays
+	}
+	return dao.SiteGrant.GetExpiringSiteGrants(time.Now().AddDate(0, 0, days))
+}
+
+// SiteGrantExpireNotice 撤销已到期的授权并通知应用负责人（定时任务调用），即将到期的授权每天提醒一次
+func (g *siteGrant) SiteGrantExpireNotice() error {
+
+	if err := dao.SiteGrant.DeleteOrphanSiteGrants(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	items, err := dao.SiteGrant.GetExpiringSiteGrants(now.AddDate(0, 0, siteGrantNoticeDays))
+	if err != nil {
+		return err
+	}
+
+	var (
+		notices []*siteGrantNotice
+		bySite  = make(map[uint]*siteGrantNotice)
+		failed  int
+	)
+	for _, item := range items {
+		notice, ok := bySite[item.SiteID]
+		if !ok {
+			notice = &siteGrantNotice{site: item}
+			bySite[item.SiteID] = notice
+			notices = append(notices, notice)
+		}
+
+		if item.ExpiresAt.After(now) {
+			notice.expiring = append(notice.expiring, item)
+			continue
+		}
+
+		revoked, err := dao.SiteGrant.RevokeExpiredSiteGrant(item.SiteID, item.UserID, now)
+		if err != nil {
+			logger.Error(fmt.Sprintf("撤销用户%s访问应用%s的授权失败：%s", item.Username, item.SiteName, err.Error()))
+			failed++
+			continue
+		}
+		if revoked {
+			g.audit(item)
+			notice.revoked = append(notice.revoked, item)
+		}
+	}
+
+	for _, notice := range notices {
+		g.notify(notice, now)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d个到期授权撤销失败", failed)
+	}
+	return nil
+}
+
+// audit 在操作日志中记录到期撤销的授权
+func (g *siteGrant) audit(item *dao.SiteGrantItem) {
+
+	params, _ := json.Marshal(map[string]interface{}{
+		"site_id":    item.SiteID,
+		"site_name":  item.SiteName,
+		"user_id":    item.UserID,
+		"username":   item.Username,
+		"expires_at": item.ExpiresAt,
+		"operator":   item.Operator,
+	})
+
+	if err := global.MySQLClient.Create(&model.LogOplog{
+		Username:      siteGrantAuditUser,
+		Endpoint:      "/api/v1/site/users",
+		Method:        "EXPIRE",
+		RequestParams: string(params),
+		ResponseData:  "授权已到期，自动撤销",
+	}).Error; err != nil {
+		logger.Error(fmt.Sprintf("记录用户%s访问应用%s的授权撤销日志失败：%s", item.Username, item.SiteName, err.Error()))
+	}
+}
+
+// notify 通知应用负责人已撤销及即将到期的授权，未配置应用负责人邮箱时不通知；仅有即将到期的授权时每天提醒一次
+func (g *siteGrant) notify(notice *siteGrantNotice, now time.Time) {
+
+	if notice.site.OwnerEmail == "" {
+		return
+	}
+	if len(notice.revoked) == 0 {
+		ok, err := global.Cache.SetNX(fmt.Sprintf("%s%d", siteGrantNoticeKey, notice.site.SiteID), now.Format("2006-01-02"), siteGrantNoticeInterval)
+		if err != nil || !ok {
+			return
+		}
+	}
+
+	var receivers []string
+	for _, item := range strings.Split(notice.site.OwnerEmail, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			receivers = append(receivers, item)
+		}
+	}
+
+	if err := mailer.Email.SendMsg(receivers, nil, nil, "应用访问授权到期提醒", siteGrantNoticeHTML(notice), "html"); err != nil {
+		logger.Error(fmt.Sprintf("应用%s授权到期提醒发送失败：%s", notice.site.SiteName, err.Error()))
+	}
+}
+
+// siteGrantNoticeHTML 授权到期提醒正文
+func siteGrantNoticeHTML(notice *siteGrantNotice) string {
+
+	rows := func(items []*dao.SiteGrantItem) string {
+		var builder strings.Builder
+		for _, item := range items {
+			builder.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td></tr>",
+				html.EscapeString(item.Name), html.EscapeString(item.Username), item.ExpiresAt.Format("2006-01-02 15:04")))
+		}
+		return builder.String()
+	}
+
+	var body strings.Builder
+	if len(notice.revoked) > 0 {
+		body.WriteString("<p>以下用户的访问授权已到期，已自动撤销：</p>")
+		body.WriteString(`<table border="1" cellspacing="0" cellpadding="4"><tr><th>姓名</th><th>用户名</th><th>到期时间</th></tr>`)
+		body.WriteString(rows(notice.revoked))
+		body.WriteString("</table>")
+	}
+	if len(notice.expiring) > 0 {
+		body.WriteString(fmt.Sprintf("<p>以下用户的访问授权将在%d天内到期，如需继续访问请在到期前延长授权有效期：</p>", siteGrantNoticeDays))
+		body.WriteString(`<table border="1" cellspacing="0" cellpadding="4"><tr><th>姓名</th><th>用户名</th><th>到期时间</th></tr>`)
+		body.WriteString(rows(notice.expiring))
+		body.WriteString("</table>")
+	}
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="UTF-8">
+			<title>应用访问授权到期提醒</title>
+		</head>
+		<body>
+			<p>您负责的应用 <b>%s</b> 存在已到期或即将到期的访问授权。</p>
+			%s
+			<br>
+			<p>来源：%s</p>
+			<p style="color: red">此邮件为系统自动发送，请勿回复此邮件。</p>
+		</body>
+		</html>
+	`, html.EscapeString(notice.site.SiteName), body.String(), html.EscapeString(config.GetString("issuer")))
+}
@@ -0,0 +1,422 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/middleware"
+	"ops-api/model"
+	"ops-api/utils"
+	"sort"
+	"strings"
+)
+
+// 站点集成描述：根据站点配置及当前环境的地址生成应用侧可直接导入的配置，包括环境变量、OIDC客户端JSON、
+// SAML2 IdP Metadata及SP Metadata、Nginx及Traefik代理配置示例，对接时无需手动拼接各个端点地址
+
+// SiteIntegration 站点集成描述
+type SiteIntegration struct {
+	SiteID      uint                       `json:"site_id"`
+	SiteName    string                     `json:"site_name"`
+	SSOType     uint                       `json:"sso_type"`
+	Environment []*SiteIntegrationVariable `json:"environment"` // 环境变量，与 .env 文件的内容一致
+	Files       []*SiteIntegrationFile     `json:"files"`       // 可下载的配置文件
+	Hints       []string                   `json:"hints"`       // 应用侧配置提示
+}
+
+// SiteIntegrationVariable 环境变量
+type SiteIntegrationVariable struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Description string `json:"description"`
+}
+
+// SiteIntegrationFile 配置文件
+type SiteIntegrationFile struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Description string `json:"description"`
+	Content     string `json:"content"`
+}
+
+// siteIntegrationBuilder 生成站点集成描述
+type siteIntegrationBuilder struct {
+	site        *model.Site
+	externalUrl string
+	data        *SiteIntegration
+}
+
+// GetSiteIntegration 获取站点集成描述，仅支持已开启单点登录的站点
+func (s *site) GetSiteIntegration(id uint) (*SiteIntegration, error) {
+
+	site := &model.Site{}
+	if err := global.MySQLClient.First(site, id).Error; err != nil {
+		return nil, err
+	}
+	if !site.SSO || site.SSOType == 0 {
+		return nil, errors.New("站点未开启单点登录")
+	}
+
+	b := &siteIntegrationBuilder{
+		site:        site,
+		externalUrl: strings.TrimRight(config.SSO().ExternalUrl, "/"),
+		data: &SiteIntegration{
+			SiteID:   site.ID,
+			SiteName: site.Name,
+			SSOType:  site.SSOType,
+		},
+	}
+
+	b.env("IDSPHERE_URL", b.externalUrl, "IDSphere访问地址")
+
+	var err error
+	switch site.SSOType {
+	case 1:
+		b.cas()
+	case 2:
+		err = b.oidc()
+	case 3:
+		err = b.saml()
+	case 4:
+		err = b.nginx()
+	case 5:
+		b.wsfed()
+	default:
+		return nil, errors.New("不支持的单点登录类型")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b.file(".env", "text/plain", "环境变量", b.dotenv())
+
+	return b.data, nil
+}
+
+// GetSiteIntegrationFile 获取站点集成描述中的单个配置文件
+func (s *site) GetSiteIntegrationFile(id uint, name string) (*SiteIntegrationFile, error) {
+	data, err := s.GetSiteIntegration(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range data.Files {
+		if file.Name == name {
+			return file, nil
+		}
+	}
+	return nil, errors.New("配置文件不存在")
+}
+
+func (b *siteIntegrationBuilder) env(name, value, description string) {
+	b.data.Environment = append(b.data.Environment, &SiteIntegrationVariable{Name: name, Value: value, Description: description})
+}
+
+func (b *siteIntegrationBuilder) file(name, contentType, description, content string) {
+	b.data.Files = append(b.data.Files, &SiteIntegrationFile{Name: name, ContentType: contentType, Description: description, Content: content})
+}
+
+func (b *siteIntegrationBuilder) hint(format string, args ...interface{}) {
+	b.data.Hints = append(b.data.Hints, fmt.Sprintf(format, args...))
+}
+
+// dotenv 生成 .env 文件，值中包含空白或特殊字符时使用双引号
+func (b *siteIntegrationBuilder) dotenv() string {
+	var builder strings.Builder
+	for _, item := range b.data.Environment {
+		value := item.Value
+		if strings.ContainsAny(value, " \t\"'#$\\\n") {
+			value = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "\n", `\n`).Replace(value) + `"`
+		}
+		builder.WriteString(fmt.Sprintf("# %s\n%s=%s\n", item.Description, item.Name, value))
+	}
+	return builder.String()
+}
+
+// cas CAS3.0，服务端地址前缀兼容CAS1.0、CAS2.0及CAS3.0客户端
+func (b *siteIntegrationBuilder) cas() {
+	b.env("CAS_SERVER_URL_PREFIX", b.externalUrl, "CAS服务端地址前缀")
+	b.env("CAS_LOGIN_URL", b.externalUrl+"/login", "CAS登录地址")
+	b.env("CAS_VALIDATE_URL", b.externalUrl+"/p3/serviceValidate", "CAS3.0票据校验地址")
+	b.env("CAS_SERVICE_URL", b.site.CallbackUrl, "应用回调地址（service）")
+
+	b.hint("CAS协议版本选择 CAS 3.0，服务端地址前缀配置为 %s", b.externalUrl)
+	b.hint("仅支持CAS2.0的客户端使用 %s/serviceValidate，仅支持CAS1.0的客户端使用 %s/validate", b.externalUrl, b.externalUrl)
+	if b.site.CASLogout {
+		b.hint("已开启单点注销，应用需要接收发送到回调地址的 logoutRequest 请求")
+	}
+}
+
+// oidc OAuth2.0/OIDC，生成RFC 7591格式的客户端元数据及服务端端点
+func (b *siteIntegrationBuilder) oidc() error {
+
+	issuer := middleware.OIDCIssuer()
+	public := b.site.PKCE == "public"
+
+	authMethod := b.site.TokenAuthMethod
+	switch {
+	case authMethod != "":
+	case public:
+		authMethod = tokenAuthNone
+	default:
+		authMethod = tokenAuthClientSecretBasic
+	}
+
+	grantTypes := strings.Fields(b.site.GrantTypes)
+	if len(grantTypes) == 0 {
+		grantTypes = []string{"authorization_code", "refresh_token"}
+	}
+	responseTypes := []string{"code"}
+	if b.site.RespTypes != "" {
+		responseTypes = strings.Split(b.site.RespTypes, ",")
+	}
+	scope := b.site.Scopes
+	if scope == "" {
+		scope = "openid profile email"
+	}
+
+	client := map[string]interface{}{
+		"client_name":                  b.site.Name,
+		"client_id":                    b.site.ClientId,
+		"redirect_uris":                []string{b.site.CallbackUrl},
+		"grant_types":                  grantTypes,
+		"response_types":               responseTypes,
+		"scope":                        scope,
+		"token_endpoint_auth_method":   authMethod,
+		"issuer":                       issuer,
+		"discovery_url":                issuer + "/.well-known/openid-configuration",
+		"authorization_endpoint":       middleware.OIDCEndpoint("oidcAuthorizationEndpoint"),
+		"token_endpoint":               middleware.OIDCEndpoint("oidcTokenEndpoint"),
+		"userinfo_endpoint":            middleware.OIDCEndpoint("oidcUserinfoEndpoint"),
+		"jwks_uri":                     middleware.OIDCEndpoint("oidcJwksUri"),
+		"id_token_signed_response_alg": "RS256",
+	}
+	if !public && authMethod != tokenAuthPrivateKeyJWT {
+		client["client_secret"] = b.site.ClientSecret
+	}
+	content, err := json.MarshalIndent(client, "", "  ")
+	if err != nil {
+		return err
+	}
+	b.file("oidc-client.json", "application/json", "OIDC客户端配置", string(content))
+
+	b.env("OIDC_ISSUER", issuer, "OIDC签发者")
+	b.env("OIDC_DISCOVERY_URL", client["discovery_url"].(string), "OIDC发现地址")
+	b.env("OIDC_CLIENT_ID", b.site.ClientId, "客户端ID")
+	if secret, ok := client["client_secret"]; ok {
+		b.env("OIDC_CLIENT_SECRET", secret.(string), "客户端密钥")
+	}
+	b.env("OIDC_REDIRECT_URI", b.site.CallbackUrl, "回调地址")
+	b.env("OIDC_SCOPES", scope, "申请的Scope")
+
+	b.hint("Token接口客户端认证方式配置为 %s", authMethod)
+	if b.site.PKCE != "" {
+		b.hint("客户端必须使用PKCE（S256）")
+	}
+	if b.site.SubjectType == "pairwise" {
+		b.hint("sub 为按扇区生成的匿名标识，关联用户时请使用 preferred_username 或 email")
+	}
+	return nil
+}
+
+// saml SAML2.0，生成IdP Metadata及根据站点配置生成的SP Metadata
+func (b *siteIntegrationBuilder) saml() error {
+
+	metadata, err := SSO.GetIdPMetadata()
+	if err != nil {
+		return err
+	}
+	cert, err := utils.LoadIdpCertificate()
+	if err != nil {
+		return err
+	}
+	acsUrls, err := parseAcsUrls(b.site.AcsUrls)
+	if err != nil {
+		return err
+	}
+	if len(acsUrls) == 0 && b.site.CallbackUrl != "" {
+		acsUrls = []string{b.site.CallbackUrl}
+	}
+
+	b.file("idp-metadata.xml", "application/xml", "IdP Metadata，导入到应用（SP）中", metadata)
+	if b.site.EntityId != "" && len(acsUrls) > 0 {
+		spMetadata, err := b.spMetadata(acsUrls)
+		if err != nil {
+			return err
+		}
+		b.file("sp-metadata.xml", "application/xml", "根据站点配置生成的SP Metadata，用于核对应用侧配置", spMetadata)
+	}
+	b.file("idp.crt", "application/x-pem-file", "IdP签名证书", string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+
+	b.env("SAML_IDP_METADATA_URL", b.externalUrl+"/api/v1/sso/saml/metadata", "IdP Metadata地址")
+	b.env("SAML_IDP_ENTITY_ID", b.externalUrl, "IdP EntityID")
+	b.env("SAML_IDP_SSO_URL", b.externalUrl+"/login", "IdP单点登录地址（HTTP-Redirect）")
+	b.env("SAML_IDP_SLO_URL", b.externalUrl+samlLogoutPath, "IdP单点注销地址（HTTP-Redirect及HTTP-POST）")
+	b.env("SAML_SP_ENTITY_ID", b.site.EntityId, "SP EntityID")
+	if len(acsUrls) > 0 {
+		b.env("SAML_SP_ACS_URL", acsUrls[0], "SP默认ACS地址")
+	}
+	if b.site.SloUrl != "" {
+		b.env("SAML_SP_SLO_URL", b.site.SloUrl, "SP单点注销地址")
+		b.hint("已配置单点注销地址，SP发起注销时 LogoutRequest 必须使用SP证书签名，平台会话注销时通过后端通道向 %s 发送 LogoutRequest", b.site.SloUrl)
+	}
+
+	if attributes := b.samlAttributeNames(); len(attributes) > 0 {
+		b.env("SAML_ATTRIBUTES", strings.Join(attributes, ","), "断言中包含的属性名")
+		b.hint("断言中包含的属性：%s", strings.Join(attributes, "、"))
+	}
+	b.hint("应用支持Metadata地址时优先使用 %s/api/v1/sso/saml/metadata，IdP证书轮换后无需重新导入", b.externalUrl)
+	return nil
+}
+
+// spMetadata 根据站点的EntityID、ACS地址及SP证书生成SP Metadata
+func (b *siteIntegrationBuilder) spMetadata(acsUrls []string) (string, error) {
+
+	escape := func(value string) (string, error) {
+		var buf bytes.Buffer
+		err := xml.EscapeText(&buf, []byte(value))
+		return buf.String(), err
+	}
+
+	entityId, err := escape(b.site.EntityId)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	builder.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	builder.WriteString(fmt.Sprintf(`<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="%s">`, entityId))
+	builder.WriteString(`<SPSSODescriptor AuthnRequestsSigned="false" WantAssertionsSigned="true" protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">`)
+	if block, _ := pem.Decode([]byte(normalizeCertificatePEM(b.site.Certificate))); block != nil && b.site.Certificate != "" {
+		builder.WriteString(fmt.Sprintf(`<KeyDescriptor use="signing"><KeyInfo xmlns="http://www.w3.org/2000/09/xmldsig#"><X509Data><X509Certificate>%s</X509Certificate></X509Data></KeyInfo></KeyDescriptor>`,
+			base64.StdEncoding.EncodeToString(block.Bytes)))
+	}
+	if b.site.SloUrl != "" {
+		location, err := escape(b.site.SloUrl)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(fmt.Sprintf(`<SingleLogoutService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="%s"/>`, location))
+	}
+	builder.WriteString(`<NameIDFormat>urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified</NameIDFormat>`)
+	for i, item := range acsUrls {
+		location, err := escape(item)
+		if err != nil {
+			return "", err
+		}
+		builder.WriteString(fmt.Sprintf(`<AssertionConsumerService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST" Location="%s" index="%d" isDefault="%t"/>`,
+			location, i, i == 0))
+	}
+	builder.WriteString(`</SPSSODescriptor></EntityDescriptor>`)
+
+	return builder.String(), nil
+}
+
+// claimNames 获取属性映射中应用侧的属性名
+func (b *siteIntegrationBuilder) claimNames() []string {
+	if b.site.ClaimMapping == "" {
+		return nil
+	}
+	var mapping map[string]string
+	if err := json.Unmarshal([]byte(b.site.ClaimMapping), &mapping); err != nil {
+		return nil
+	}
+	var names []string
+	for name := range mapping {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// samlAttributeNames 获取断言中发布的属性名，未配置属性发布策略时为属性映射中的属性名
+func (b *siteIntegrationBuilder) samlAttributeNames() []string {
+	rules, err := parseSAMLAttributes(b.site.SAMLAttributes)
+	if err != nil || rules == nil {
+		return b.claimNames()
+	}
+	names := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		names = append(names, rule.Name)
+	}
+	return names
+}
+
+// nginx Nginx代理模式，生成Nginx auth_request及Traefik forwardAuth配置示例
+func (b *siteIntegrationBuilder) nginx() error {
+
+	callback, err := url.Parse(b.site.CallbackUrl)
+	if err != nil || callback.Path == "" {
+		return errors.New("站点回调地址格式错误")
+	}
+	authUrl := b.externalUrl + "/api/v1/sso/cookie/auth"
+	loginUrl := b.externalUrl + "/login?nginx_redirect_uri=" + url.QueryEscape(b.site.CallbackUrl)
+
+	b.env("IDSPHERE_AUTH_URL", authUrl, "Cookie认证地址")
+	b.env("IDSPHERE_LOGIN_URL", loginUrl, "未认证时跳转的登录地址")
+	b.env("NGINX_CALLBACK_URL", b.site.CallbackUrl, "登录成功后的回调地址，携带 token 参数")
+
+	b.file("nginx.conf", "text/plain", "Nginx auth_request 配置示例", fmt.Sprintf(`# %[1]s：IDSphere 单点登录（auth_request）
+location = /_idsphere_auth {
+    internal;
+    proxy_pass %[2]s;
+    proxy_pass_request_body off;
+    proxy_set_header Content-Length "";
+    proxy_set_header Cookie $http_cookie;
+}
+
+location @idsphere_login {
+    return 302 %[3]s;
+}
+
+# 登录成功后IDSphere携带 token 参数跳转到回调地址，保存到Cookie后返回首页
+location = %[4]s {
+    add_header Set-Cookie "idsphere_token=$arg_token; Path=/; HttpOnly; Secure; SameSite=Lax";
+    return 302 /;
+}
+
+location / {
+    auth_request /_idsphere_auth;
+    # 票据续期时返回新的Cookie
+    auth_request_set $sso_cookie $upstream_http_set_cookie;
+    add_header Set-Cookie $sso_cookie;
+    error_page 401 = @idsphere_login;
+
+    proxy_pass http://127.0.0.1:8080;
+}
+`, b.site.Name, authUrl, loginUrl, callback.Path))
+
+	b.file("traefik.yml", "application/yaml", "Traefik forwardAuth 动态配置示例", fmt.Sprintf(`# %[1]s：IDSphere 单点登录（forwardAuth）
+http:
+  middlewares:
+    idsphere-auth:
+      forwardAuth:
+        address: "%[2]s"
+        # 票据续期时返回新的Cookie
+        authResponseHeaders:
+          - "Set-Cookie"
+          - "X-Nginx-Token"
+`, b.site.Name, authUrl))
+
+	b.hint("Cookie名称可自定义，Cookie认证接口会校验请求中的所有Cookie")
+	b.hint("Traefik forwardAuth 认证失败时直接返回401，需要应用（或 errors 中间件）跳转到 %s，并在回调地址 %s 中将 token 参数保存到Cookie", loginUrl, callback.Path)
+	if b.site.NginxRenewal {
+		b.hint("已开启票据自动续期，代理需要将认证接口返回的 Set-Cookie 返回给浏览器")
+	}
+	return nil
+}
+
+// wsfed WS-Federation
+func (b *siteIntegrationBuilder) wsfed() {
+	b.env("WSFED_METADATA_URL", b.externalUrl+"/FederationMetadata/2007-06/FederationMetadata.xml", "联合元数据地址")
+	b.env("WSFED_REALM", b.site.EntityId, "RP标识（wtrealm）")
+	b.env("WSFED_REPLY_URL", b.site.CallbackUrl, "RP回调地址（wreply）")
+
+	b.hint("应用中导入联合元数据地址，wtrealm 配置为 %s", b.site.EntityId)
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/wonderivan/logger"
+	"net/http"
+	"net/url"
+	"ops-api/dao"
+	"ops-api/model"
+	"time"
+)
+
+var SiteLaunchHook siteLaunchHook
+
+type siteLaunchHook struct{}
+
+const (
+	SiteLaunchEventType = "sso.launch" // 单点登录成功事件
+
+	siteLaunchHookTimeout = 5 * time.Second // 通知请求超时时间
+	siteLaunchHookRetries = 3               // 通知失败时的最大尝试次数
+)
+
+// SiteLaunchEvent 用户单点登录应用成功后推送至应用的事件，可用于应用统计授权用户数或预热会话
+type SiteLaunchEvent struct {
+	ID       string               `json:"id"`
+	Type     string               `json:"type"`
+	Time     time.Time            `json:"time"`
+	Protocol string               `json:"protocol"` // 认证协议：oauth2、cas3、saml2、nginx、wsfed
+	Site     *SiteLaunchEventSite `json:"site"`
+	User     *ProvisionEventUser  `json:"user"`
+}
+
+// SiteLaunchEventSite 事件中的应用信息
+type SiteLaunchEventSite struct {
+	ID       uint   `json:"id"`
+	Name     string `json:"name"`
+	ClientId string `json:"client_id,omitempty"`
+}
+
+// validateLaunchHook 校验站点配置的单点登录通知地址，仅支持HTTP及HTTPS
+func validateLaunchHook(hook string) error {
+	if hook == "" {
+		return nil
+	}
+	u, err := url.Parse(hook)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("单点登录通知地址格式错误，仅支持HTTP或HTTPS地址")
+	}
+	return nil
+}
+
+// Publish 用户单点登录应用成功后异步通知应用，站点未配置通知地址时不通知，通知失败不影响用户登录
+func (s *siteLaunchHook) Publish(site *model.Site, protocol string, userId uint) {
+
+	if site == nil || site.LaunchHook == "" {
+		return
+	}
+
+	event := &SiteLaunchEvent{
+		ID:       uuid.NewString(),
+		Type:     SiteLaunchEventType,
+		Time:     time.Now(),
+		Protocol: protocol,
+		Site:     &SiteLaunchEventSite{ID: site.ID, Name: site.Name, ClientId: site.ClientId},
+	}
+	hook, secret := site.LaunchHook, site.LaunchSecret
+
+	go func() {
+		user, err := dao.User.GetUser(map[string]interface{}{"id": userId})
+		if err != nil {
+			logger.Error(fmt.Sprintf("ERROR：应用（%s）单点登录通知失败，%s", event.Site.Name, err.Error()))
+			return
+		}
+		event.User = &ProvisionEventUser{
+			ID:          user.ID,
+			Username:    user.Username,
+			Name:        user.Name,
+			Email:       user.Email,
+			PhoneNumber: user.PhoneNumber,
+			IsActive:    user.IsActive,
+			UserFrom:    user.UserFrom,
+		}
+
+		body, err := json.Marshal(event)
+		if err != nil {
+			logger.Error(fmt.Sprintf("ERROR：应用（%s）单点登录通知失败，%s", event.Site.Name, err.Error()))
+			return
+		}
+
+		for i := 1; ; i++ {
+			err = s.request(hook, secret, event, body)
+			if err == nil {
+				return
+			}
+			if i >= siteLaunchHookRetries {
+				logger.Error(fmt.Sprintf("ERROR：应用（%s）单点登录通知失败，%s", event.Site.Name, err.Error()))
+				return
+			}
+			time.Sleep(time.Duration(i) * time.Second)
+		}
+	}()
+}
+
+// request 发送通知请求，签名方式与身份事件推送相同，返回2xx状态码时视为通知成功
+func (s *siteLaunchHook) request(hook, secret string, event *SiteLaunchEvent, body []byte) error {
+
+	req, err := http.NewRequest(http.MethodPost, hook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Hook-Event", event.Type)
+	req.Header.Set("X-Hook-Event-Id", event.ID)
+	signWebhookRequest(req, secret, body)
+
+	client := &http.Client{Timeout: siteLaunchHookTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"html"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils/notify"
+	"strings"
+	"time"
+)
+
+// SP Metadata自动刷新：“SP Metadata自动刷新”任务定时重新获取配置了Metadata地址的SAML2站点的SP Metadata，
+// EntityID变化时更新站点EntityID，SP默认证书变化时更新站点证书，Metadata中新增的签名证书添加为站点的SP证书（SP轮换证书期间新旧证书同时有效）；
+// 开启断言加密的站点同时更新SP加密证书；站点配置有变化或Metadata首次获取失败时按任务配置的通知方式通知管理员，未配置通知方式时仅记录日志
+
+const spMetadataCertificateName = "Metadata自动同步" // 从Metadata中添加的SP证书名称
+
+// spMetadataChange 站点SP Metadata刷新结果
+type spMetadataChange struct {
+	SiteName string
+	Details  []string
+	Failed   bool
+}
+
+// SPMetadataRefresh 刷新所有配置了Metadata地址的SAML2站点（定时任务调用）
+func (s *site) SPMetadataRefresh(task *model.ScheduledTask) error {
+
+	sites, err := dao.Site.GetSamlSites()
+	if err != nil {
+		return err
+	}
+
+	var (
+		changes []*spMetadataChange
+		failed  int
+	)
+	for _, site := range sites {
+		if strings.TrimSpace(site.MetadataUrl) == "" {
+			continue
+		}
+
+		details, err := s.refreshSPMetadata(site)
+		if err != nil {
+			failed++
+			logger.Error(fmt.Sprintf("站点%s的SP Metadata刷新失败：%s", site.Name, err.Error()))
+
+			// 仅在首次失败时通知，避免每次执行都重复通知
+			if site.MetadataError == "" {
+				changes = append(changes, &spMetadataChange{SiteName: site.Name, Details: []string{err.Error()}, Failed: true})
+			}
+			global.MySQLClient.Model(site).Update("metadata_error", err.Error())
+			continue
+		}
+
+		if len(details) > 0 {
+			logger.Info(fmt.Sprintf("站点%s的SP Metadata已更新：%s", site.Name, strings.Join(details, "；")))
+			changes = append(changes, &spMetadataChange{SiteName: site.Name, Details: details})
+		}
+	}
+
+	if len(changes) > 0 {
+		if err := s.spMetadataNotify(task, changes); err != nil {
+			logger.Error("ERROR：SP Metadata变更通知发送失败，", err.Error())
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d个站点的SP Metadata刷新失败", failed)
+	}
+	return nil
+}
+
+// refreshSPMetadata 刷新单个站点的SP Metadata，返回站点配置的变更内容
+func (s *site) refreshSPMetadata(site *model.Site) ([]string, error) {
+
+	metadata, err := SSO.ParseSPMetadata(site.MetadataUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		details []string
+		updates = map[string]interface{}{"metadata_error": nil}
+	)
+
+	// EntityID变化时，新的EntityID不能已被其它站点使用
+	if metadata.EntityID != "" && metadata.EntityID != site.EntityId {
+		if other, err := dao.Site.GetSamlSite(metadata.EntityID); err == nil && other.ID != site.ID {
+			return nil, fmt.Errorf("EntityID（%s）已被站点%s使用", metadata.EntityID, other.Name)
+		}
+		details = append(details, fmt.Sprintf("EntityID由%s变更为%s", site.EntityId, metadata.EntityID))
+		updates["entity_id"] = metadata.EntityID
+	}
+
+	// 校验Metadata中的证书
+	var (
+		certificates = make([]string, 0, len(metadata.Certificates))
+		fingerprints = make([]string, 0, len(metadata.Certificates))
+	)
+	for _, item := range metadata.Certificates {
+		certificate := normalizeCertificatePEM(item)
+		fingerprint, notAfter, err := spCertificateFingerprint(certificate)
+		if err != nil {
+			return nil, fmt.Errorf("Metadata中的证书格式错误：%s", err.Error())
+		}
+		if time.Now().After(notAfter) {
+			continue
+		}
+		certificates = append(certificates, certificate)
+		fingerprints = append(fingerprints, fingerprint)
+	}
+	if len(certificates) == 0 {
+		return nil, errors.New("Metadata中的签名证书均已过期")
+	}
+
+	// 默认证书变化时更新站点证书
+	current, _, _ := spCertificateFingerprint(normalizeCertificatePEM(site.Certificate))
+	if current != fingerprints[0] {
+		details = append(details, fmt.Sprintf("SP证书变更，新证书指纹：%s", fingerprints[0]))
+		updates["certificate"] = certificates[0]
+	}
+
+	// Metadata中其它未注册的签名证书（包括被替换的原默认证书）添加为SP证书
+	registered, err := dao.Site.GetSiteCertificates(site.ID)
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{fingerprints[0]: true}
+	for _, item := range registered {
+		known[item.Fingerprint] = true
+	}
+	var added []*model.SiteCertificate
+	for i := 1; i < len(certificates); i++ {
+		if known[fingerprints[i]] {
+			continue
+		}
+		known[fingerprints[i]] = true
+		crt, _ := parseCertificate(certificates[i])
+		added = append(added, &model.SiteCertificate{
+			SiteID:      site.ID,
+			Name:        spMetadataCertificateName,
+			Certificate: certificates[i],
+			Fingerprint: fingerprints[i],
+			NotBefore:   crt.NotBefore,
+			NotAfter:    crt.NotAfter,
+			Enabled:     true,
+		})
+		details = append(details, fmt.Sprintf("新增SP证书，指纹：%s", fingerprints[i]))
+	}
+
+	// 开启断言加密的站点，SP加密证书变化时更新
+	if site.EncryptAssertion && metadata.EncryptionCertificate != "" {
+		certificate := normalizeCertificatePEM(metadata.EncryptionCertificate)
+		fingerprint, _, err := spCertificateFingerprint(certificate)
+		if err != nil {
+			return nil, fmt.Errorf("Metadata中的加密证书格式错误：%s", err.Error())
+		}
+		if current, _, _ := spCertificateFingerprint(normalizeCertificatePEM(site.EncryptionCert)); current != fingerprint {
+			details = append(details, fmt.Sprintf("SP加密证书变更，新证书指纹：%s", fingerprint))
+			updates["encryption_cert"] = certificate
+		}
+	}
+
+	now := time.Now()
+	updates["metadata_sync_at"] = &now
+
+	tx := global.MySQLClient.Begin()
+	if err := tx.Model(site).Updates(updates).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	for _, item := range added {
+		if err := tx.Create(item).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return details, nil
+}
+
+// spCertificateFingerprint 获取证书的SHA256指纹及过期时间
+func spCertificateFingerprint(certificate string) (string, time.Time, error) {
+	crt, err := parseCertificate(certificate)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	sum := sha256.Sum256(crt.Raw)
+	return hex.EncodeToString(sum[:]), crt.NotAfter, nil
+}
+
+// spMetadataNotify 按任务配置的通知方式发送SP Metadata变更及刷新失败通知，未配置通知方式时不通知
+func (s *site) spMetadataNotify(task *model.ScheduledTask, changes []*spMetadataChange) error {
+
+	if task.NotifyType == nil || task.Receiver == nil || *task.Receiver == "" {
+		return nil
+	}
+
+	// 生成通知内容（1：邮件 HTML，3：富文本，其它： Markdown 文档）
+	var message string
+	switch *task.NotifyType {
+	case 1:
+		message = spMetadataNoticeHTML(changes)
+	case 3:
+		jsonBytes, _ := json.Marshal(spMetadataNoticePost(changes))
+		message = string(jsonBytes)
+	default:
+		message = spMetadataNoticeMarkdown(changes)
+	}
+
+	notifier := notify.GetNotifier(*task)
+	if notifier == nil {
+		return errors.New("不支持的通知方式")
+	}
+	return notifier.SendNotify(message, "SP Metadata变更通知")
+}
+
+// statusText 刷新结果
+func (c *spMetadataChange) statusText() string {
+	if c.Failed {
+		return "刷新失败"
+	}
+	return "已更新"
+}
+
+// spMetadataNoticePost 生成飞书 Post 格式的富文本内容
+func spMetadataNoticePost(changes []*spMetadataChange) map[string]interface{} {
+
+	content := make([][]map[string]interface{}, 0)
+	for i, change := range changes {
+		statusColor := "green"
+		if change.Failed {
+			statusColor = "red"
+		}
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("%d. 站点：", i+1)},
+			{"tag": "text", "text": change.SiteName, "bold": true},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": "   状态："},
+			{"tag": "text", "text": change.statusText(), "text_color": statusColor},
+		})
+		content = append(content, []map[string]interface{}{
+			{"tag": "text", "text": fmt.Sprintf("   详情：%s", strings.Join(change.Details, "；"))},
+		})
+	}
+
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": "--------------------------------\n"},
+	})
+	content = append(content, []map[string]interface{}{
+		{"tag": "text", "text": fmt.Sprintf("来源：%s", config.GetString("issuer"))},
+	})
+
+	return map[string]interface{}{
+		"msg_type": "post",
+		"content": map[string]interface{}{
+			"post": map[string]interface{}{
+				"zh_cn": map[string]interface{}{
+					"title":   "SP Metadata变更通知：",
+					"content": content,
+				},
+			},
+		},
+	}
+}
+
+// spMetadataNoticeMarkdown 生成SP Metadata变更通知 Markdown 文档
+func spMetadataNoticeMarkdown(changes []*spMetadataChange) string {
+
+	builder := &strings.Builder{}
+	builder.WriteString("**SP Metadata变更通知：**\n\n")
+
+	for i, change := range changes {
+		builder.WriteString(fmt.Sprintf("%d. 站点：%s\n\n", i+1, change.SiteName))
+		builder.WriteString(fmt.Sprintf("   状态：<font color=\"warning\">%s</font>\n\n", change.statusText()))
+		builder.WriteString(fmt.Sprintf("   详情：%s\n\n", strings.Join(change.Details, "；")))
+	}
+
+	builder.WriteString("--------------------------------\n")
+	builder.WriteString(fmt.Sprintf("来源：%s\n", config.GetString("issuer")))
+
+	return builder.String()
+}
+
+// spMetadataNoticeHTML 生成SP Metadata变更通知 HTML
+func spMetadataNoticeHTML(changes []*spMetadataChange) string {
+
+	var rows strings.Builder
+	for _, change := range changes {
+		rows.WriteString(fmt.Sprintf("<tr><td>%s</td><td>%s</td><td>%s</td></tr>",
+			html.EscapeString(change.SiteName),
+			change.statusText(),
+			html.EscapeString(strings.Join(change.Details, "；"))))
+	}
+
+	return fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<meta charset="UTF-8">
+			<title>SP Metadata变更通知</title>
+		</head>
+		<body>
+			<p>以下SAML2站点的SP Metadata已变更或刷新失败，请确认站点配置：</p>
+			<table border="1" cellspacing="0" cellpadding="4"><tr><th>站点</th><th>状态</th><th>详情</th></tr>%s</table>
+			<br>
+			<p>来源：%s</p>
+			<p style="color: red">此邮件为系统自动发送，请勿回复此邮件。</p>
+		</body>
+		</html>
+	`, rows.String(), html.EscapeString(config.GetString("issuer")))
+}
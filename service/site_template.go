@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// SiteTemplate 站点集成模板，预置常见运维工具的单点登录配置
+type SiteTemplate struct {
+	Key          string            `json:"key"`            // 模板标识
+	Name         string            `json:"name"`           // 模板名称
+	Description  string            `json:"description"`    // 模板描述
+	SSOType      uint              `json:"sso_type"`       // 单点登录类型：1：CAS3.0，2：OAuth2.0/OIDC，3：SAML2，4：Nginx，5：WS-Fed
+	CallbackPath string            `json:"callback_path"`  // 回调路径，创建站点时拼接在站点地址后
+	EntityIdPath string            `json:"entity_id_path"` // SAML2 SP EntityID路径，创建站点时拼接在站点地址后
+	NameId       string            `json:"name_id"`        // NameID/用户唯一标识使用的用户属性
+	ClaimMapping map[string]string `json:"claim_mapping"`  // 属性映射：应用侧属性名 -> 用户属性（username/name/email/phone_number/groups）
+	HelperUrl    string            `json:"helper_url"`     // 应用官方集成文档
+	Hints        []string          `json:"hints"`          // 应用侧配置提示
+}
+
+// siteTemplates 内置站点集成模板
+var siteTemplates = []*SiteTemplate{
+	{
+		Key:          "jumpserver",
+		Name:         "JumpServer",
+		Description:  "JumpServer 堡垒机，使用 OIDC 认证",
+		SSOType:      2,
+		CallbackPath: "/core/auth/openid/callback/",
+		NameId:       "username",
+		ClaimMapping: map[string]string{"preferred_username": "username", "name": "name", "email": "email"},
+		HelperUrl:    "https://docs.jumpserver.org/zh/v3/admin-guide/authentication/openid/",
+		Hints: []string{
+			"认证方式选择 OIDC，开启“使用 Keycloak”为否",
+			"AUTH_OPENID_USER_ATTR_MAP 配置为 {\"name\": \"name\", \"username\": \"preferred_username\", \"email\": \"email\"}",
+			"开启“首次登录自动创建用户”，用户组及授权需在 JumpServer 中手动维护",
+		},
+	},
+	{
+		Key:          "grafana",
+		Name:         "Grafana",
+		Description:  "Grafana 监控面板，使用 Generic OAuth 认证",
+		SSOType:      2,
+		CallbackPath: "/login/generic_oauth",
+		NameId:       "username",
+		ClaimMapping: map[string]string{"username": "username", "name": "name", "email": "email"},
+		HelperUrl:    "https://grafana.com/docs/grafana/latest/setup-grafana/configure-security/configure-authentication/generic-oauth/",
+		Hints: []string{
+			"[auth.generic_oauth] 中配置 login_attribute_path = username，name_attribute_path = name，email_attribute_path = email",
+			"scopes 配置为 openid profile email",
+			"开启 allow_sign_up 后首次登录自动创建用户，默认角色由 auto_assign_org_role 决定",
+		},
+	},
+	{
+		Key:          "zabbix",
+		Name:         "Zabbix",
+		Description:  "Zabbix 监控系统，使用 SAML2 认证",
+		SSOType:      3,
+		CallbackPath: "/index_sso.php?acs",
+		EntityIdPath: "/zabbix",
+		NameId:       "username",
+		ClaimMapping: map[string]string{"username": "username", "name": "name", "email": "email"},
+		HelperUrl:    "https://www.zabbix.com/documentation/current/en/manual/web_interface/frontend_sections/users/authentication/saml",
+		Hints: []string{
+			"Username attribute 配置为 username，SP entity ID 与站点的 EntityID 保持一致",
+			"IdP 证书从 IDP 元数据中获取，需保存为 conf/certs/idp.crt",
+			"开启 JIT provisioning 时需配置用户组及媒介映射，否则需提前在 Zabbix 中创建同名用户",
+		},
+	},
+	{
+		Key:          "gitlab",
+		Name:         "GitLab",
+		Description:  "GitLab 代码仓库，使用 OIDC 认证",
+		SSOType:      2,
+		CallbackPath: "/users/auth/openid_connect/callback",
+		NameId:       "username",
+		ClaimMapping: map[string]string{"preferred_username": "username", "name": "name", "email": "email"},
+		HelperUrl:    "https://docs.gitlab.com/ee/administration/auth/oidc.html",
+		Hints: []string{
+			"omniauth_providers 中 name 配置为 openid_connect，uid_field 配置为 preferred_username",
+			"discovery 配置为 true，issuer 为 IDP 外部访问地址",
+			"omniauth_allow_single_sign_on 包含 openid_connect 时首次登录自动创建用户",
+		},
+	},
+	{
+		Key:          "harbor",
+		Name:         "Harbor",
+		Description:  "Harbor 镜像仓库，使用 OIDC 认证",
+		SSOType:      2,
+		CallbackPath: "/c/oidc/callback",
+		NameId:       "username",
+		ClaimMapping: map[string]string{"preferred_username": "username", "email": "email"},
+		HelperUrl:    "https://goharbor.io/docs/main/administration/configure-authentication/oidc-auth/",
+		Hints: []string{
+			"认证模式选择 OIDC，OIDC Scope 配置为 openid,profile,email",
+			"Username Claim 配置为 preferred_username",
+			"认证模式仅能在 Harbor 中不存在本地用户时切换",
+		},
+	},
+	{
+		Key:          "jenkins",
+		Name:         "Jenkins",
+		Description:  "Jenkins 持续集成，使用 CAS3.0 认证（CAS Plugin）",
+		SSOType:      1,
+		CallbackPath: "/securityRealm/finishLogin",
+		NameId:       "username",
+		ClaimMapping: map[string]string{"name": "name", "email": "email"},
+		HelperUrl:    "https://plugins.jenkins.io/cas-plugin/",
+		Hints: []string{
+			"安全域选择 CAS，CAS Protocol 选择 CAS 3.0",
+			"Full Name Attribute 配置为 name，Email Attribute 配置为 email",
+			"用户授权需在 Jenkins 授权策略中按用户名配置",
+		},
+	},
+}
+
+// GetSiteTemplateList 获取站点集成模板列表
+func (s *site) GetSiteTemplateList() []*SiteTemplate {
+	return siteTemplates
+}
+
+// GetSiteTemplate 获取单个站点集成模板
+func (s *site) GetSiteTemplate(key string) (*SiteTemplate, error) {
+	for _, template := range siteTemplates {
+		if template.Key == key {
+			return template, nil
+		}
+	}
+	return nil, errors.New("集成模板不存在")
+}
+
+// applySiteTemplate 使用集成模板填充站点未配置的字段
+func (s *site) applySiteTemplate(data *SiteCreate) error {
+	template, err := s.GetSiteTemplate(data.Template)
+	if err != nil {
+		return err
+	}
+
+	address := strings.TrimRight(data.Address, "/")
+
+	if data.SSOType == 0 {
+		data.SSOType = template.SSOType
+	}
+	if data.CallbackUrl == "" && template.CallbackPath != "" {
+		data.CallbackUrl = address + template.CallbackPath
+	}
+	if data.EntityId == "" && template.EntityIdPath != "" {
+		data.EntityId = address + template.EntityIdPath
+	}
+	if data.HelperUrl == "" {
+		data.HelperUrl = template.HelperUrl
+	}
+	if data.ClaimMapping == "" && len(template.ClaimMapping) > 0 {
+		claimMapping, err := json.Marshal(template.ClaimMapping)
+		if err != nil {
+			return err
+		}
+		data.ClaimMapping = string(claimMapping)
+	}
+
+	return nil
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"ops-api/dao"
+	"strings"
+	"time"
+)
+
+// 站点配置预检：创建或修改站点前校验站点配置，获取并解析SP Metadata、探测回调地址是否可访问、校验证书，
+// 每项检查返回检查结果及修改建议，用于在保存前发现导致“应用未注册或配置错误”的配置问题
+
+const (
+	SiteCheckPass    = "pass"    // 检查通过
+	SiteCheckWarning = "warning" // 存在风险，不影响保存
+	SiteCheckError   = "error"   // 配置错误，保存后无法单点登录
+
+	siteProbeTimeout = 5 * time.Second
+)
+
+// siteProbeClient 探测站点地址使用的HTTP客户端，不跟随重定向
+var siteProbeClient = &http.Client{
+	Timeout: siteProbeTimeout,
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// SiteValidate 站点配置预检请求参数，字段与新增站点一致，ID不为空时为修改站点
+type SiteValidate struct {
+	ID               uint   `json:"id"`
+	SSOType          uint   `json:"sso_type" binding:"required,oneof=1 2 3 4 5"`
+	Address          string `json:"address"`
+	CallbackUrl      string `json:"callback_url"`
+	EntityId         string `json:"entity_id"`
+	Certificate      string `json:"certificate"`
+	MetadataUrl      string `json:"metadata_url"`
+	MetadataXML      string `json:"metadata_xml"`
+	AcsUrls          string `json:"acs_urls"`
+	GrantTypes       string `json:"grant_types"`
+	RespTypes        string `json:"response_types"`
+	Scopes           string `json:"scopes"`
+	Resources        string `json:"resources"`
+	PKCE             string `json:"pkce"`
+	TokenAuthMethod  string `json:"token_endpoint_auth_method"`
+	JwtPublicKey     string `json:"jwt_public_key"`
+	CASProfile       string `json:"cas_profile"`
+	LaunchHook       string `json:"launch_hook"`
+	Sunset           string `json:"sunset"`
+	OwnerEmail       string `json:"owner_email"`
+	ClaimMapping     string `json:"claim_mapping"`
+	SAMLAttributes   string `json:"saml_attributes"`
+	EncryptAssertion bool   `json:"encrypt_assertion"`
+	EncryptionCert   string `json:"encryption_certificate"`
+	KeyEncryption    string `json:"key_encryption_algorithm"`
+	DataEncryption   string `json:"data_encryption_algorithm"`
+	SignatureAlg     string `json:"signature_algorithm"`
+	DigestAlg        string `json:"digest_algorithm"`
+	SigningLevel     string `json:"signing_level"`
+}
+
+// SiteCheck 站点配置检查项
+type SiteCheck struct {
+	Field   string `json:"field"`            // 对应的站点配置字段
+	Name    string `json:"name"`             // 检查项名称
+	Status  string `json:"status"`           // 检查结果：pass、warning、error
+	Message string `json:"message"`          // 检查结果说明
+	Advice  string `json:"advice,omitempty"` // 修改建议
+}
+
+// SiteValidateResult 站点配置预检结果
+type SiteValidateResult struct {
+	Valid    bool         `json:"valid"`              // 是否不存在 error 级别的问题
+	Checks   []*SiteCheck `json:"checks"`             // 检查项
+	Metadata *SPMetadata  `json:"metadata,omitempty"` // 从SP Metadata中获取的配置，可用于填充表单
+}
+
+// siteValidator 站点配置预检过程
+type siteValidator struct {
+	data   *SiteValidate
+	result *SiteValidateResult
+}
+
+func (v *siteValidator) add(field, name, status, message, advice string) {
+	v.result.Checks = append(v.result.Checks, &SiteCheck{Field: field, Name: name, Status: status, Message: message, Advice: advice})
+	if status == SiteCheckError {
+		v.result.Valid = false
+	}
+}
+
+func (v *siteValidator) pass(field, name, message string) {
+	v.add(field, name, SiteCheckPass, message, "")
+}
+
+func (v *siteValidator) warn(field, name, message, advice string) {
+	v.add(field, name, SiteCheckWarning, message, advice)
+}
+
+func (v *siteValidator) fail(field, name, message, advice string) {
+	v.add(field, name, SiteCheckError, message, advice)
+}
+
+// rule 将已有的配置校验结果记录为检查项
+func (v *siteValidator) rule(field, name string, err error) {
+	if err != nil {
+		v.fail(field, name, err.Error(), "")
+		return
+	}
+	v.pass(field, name, "配置正确")
+}
+
+// ValidateSite 站点配置预检，不保存站点
+func (s *site) ValidateSite(data *SiteValidate) *SiteValidateResult {
+
+	v := &siteValidator{data: data, result: &SiteValidateResult{Valid: true, Checks: []*SiteCheck{}}}
+
+	// 站点地址
+	if data.Address != "" {
+		v.checkURL("address", "站点地址", data.Address, false)
+	}
+
+	switch data.SSOType {
+	case 1: // CAS3.0
+		v.checkCallback(true, "回调地址（service）与应用发起登录时携带的service参数完全一致时才能匹配到应用")
+		v.rule("cas_profile", "票据校验响应格式", validateCASProfile(data.CASProfile))
+	case 2: // OAuth2.0
+		v.checkCallback(false, "回调地址（redirect_uri）需与应用中配置的回调地址一致")
+		v.rule("grant_types", "授权类型、响应类型及Scope", validateOAuthPolicy(data.GrantTypes, data.RespTypes, data.Scopes))
+		v.rule("pkce", "PKCE模式", validatePKCEMode(data.PKCE))
+		v.rule("resources", "资源", validateResources(data.Resources))
+		v.rule("token_endpoint_auth_method", "客户端认证方式", validateTokenAuthMethod(data.TokenAuthMethod, data.JwtPublicKey))
+	case 3: // SAML2
+		v.checkSAML()
+		v.rule("saml_attributes", "属性发布策略", validateSAMLAttributes(data.SAMLAttributes))
+		v.checkSAMLEncryption()
+		v.rule("signature_algorithm", "签名算法", validateSAMLSigning(data.SignatureAlg, data.DigestAlg, data.SigningLevel))
+	case 4: // Nginx
+		v.checkCallback(true, "回调地址需与Nginx配置中传递的回调地址完全一致时才能匹配到应用")
+	case 5: // WS-Fed
+		v.checkEntityId("wtrealm", "Realm（entity_id）需与应用登录请求中的wtrealm参数完全一致")
+		if data.CallbackUrl == "" {
+			v.warn("callback_url", "回调地址", "未配置回调地址，应用登录请求未携带wreply参数时无法登录", "填写应用接收登录响应的地址")
+		} else {
+			v.checkURL("callback_url", "回调地址", data.CallbackUrl, true)
+		}
+	}
+
+	v.rule("claim_mapping", "属性映射", validateClaimMapping(data.ClaimMapping))
+	v.rule("launch_hook", "单点登录通知地址", validateLaunchHook(data.LaunchHook))
+	v.rule("sunset", "停用日期及应用负责人邮箱", validateSunset(data.Sunset, data.OwnerEmail))
+
+	return v.result
+}
+
+// checkCallback 校验回调地址：必须为完整的HTTP(S)地址，unique为true时（单点登录时按回调地址匹配站点）不能与其它同类型站点重复
+func (v *siteValidator) checkCallback(unique bool, advice string) {
+
+	if v.data.CallbackUrl == "" {
+		v.fail("callback_url", "回调地址", "回调地址不能为空", advice)
+		return
+	}
+	if !v.checkURL("callback_url", "回调地址", v.data.CallbackUrl, true) {
+		return
+	}
+
+	if unique {
+		v.checkConflict("callback_url", "回调地址", v.data.CallbackUrl)
+	}
+}
+
+// checkEntityId 校验EntityID（SAML2）或Realm（WS-Fed）不能为空且不能与其它同类型站点重复
+func (v *siteValidator) checkEntityId(param, advice string) {
+	if v.data.EntityId == "" {
+		v.fail("entity_id", "EntityID", "EntityID不能为空", advice)
+		return
+	}
+	if v.checkConflict("entity_id", "EntityID", v.data.EntityId) {
+		v.pass("entity_id", "EntityID", "EntityID可用，应用请求中的"+param+"需与其完全一致")
+	}
+}
+
+// checkConflict 判断字段值是否已被其它同类型站点使用，未使用时返回true
+func (v *siteValidator) checkConflict(column, name, value string) bool {
+	site, err := dao.Site.GetConflictSite(v.data.SSOType, column, value, v.data.ID)
+	if err != nil {
+		v.warn(column, name+"唯一性", "检查失败："+err.Error(), "")
+		return false
+	}
+	if site != nil {
+		v.fail(column, name+"唯一性", fmt.Sprintf("%s已被站点%s使用", name, site.Name), "同一单点登录类型的站点"+name+"不能重复，请修改或删除重复的站点")
+		return false
+	}
+	return true
+}
+
+// checkURL 校验地址格式并探测地址是否可访问，redirect为true时为浏览器回调地址，地址格式正确时返回true
+func (v *siteValidator) checkURL(field, name, rawUrl string, redirect bool) bool {
+
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.fail(field, name, "地址格式错误："+rawUrl, "填写以 http:// 或 https:// 开头的完整地址")
+		return false
+	}
+	if redirect && u.Fragment != "" {
+		v.fail(field, name, "回调地址不能包含“#”片段", "删除地址中“#”及之后的内容")
+		return false
+	}
+	if u.Scheme == "http" && !isLoopbackHost(u.Hostname()) {
+		v.warn(field, name+"安全性", "地址未使用HTTPS，票据或授权码可能被窃取", "为应用配置HTTPS证书并使用 https:// 地址")
+	}
+
+	if err := probeURL(rawUrl); err != nil {
+		// 回调地址由用户浏览器访问，平台所在网络无法访问时不影响单点登录
+		v.warn(field, name+"可访问性", "地址无法访问："+err.Error(), "确认地址填写正确且应用已启动，内网应用请在用户网络中确认")
+		return true
+	}
+	v.pass(field, name+"可访问性", "地址可以访问")
+	return true
+}
+
+// checkSAML 校验SAML2配置：获取并解析SP Metadata，校验EntityID、SP证书及ACS地址
+func (v *siteValidator) checkSAML() {
+
+	data := v.data
+	if data.MetadataXML != "" || data.MetadataUrl != "" {
+		var (
+			metadata *SPMetadata
+			err      error
+		)
+		// 上传了SP Metadata XML时优先使用
+		if data.MetadataXML != "" {
+			metadata, err = SSO.ParseSPMetadataXML([]byte(data.MetadataXML))
+			if err != nil {
+				v.fail("metadata_xml", "SP Metadata", "解析SP Metadata失败："+err.Error(), "确认上传的内容为SP的EntityDescriptor")
+			}
+		} else {
+			metadata, err = SSO.ParseSPMetadata(data.MetadataUrl)
+			if err != nil {
+				v.fail("metadata_url", "SP Metadata", "获取或解析SP Metadata失败："+err.Error(), "确认地址可以在平台所在网络访问且返回SP的EntityDescriptor，或者上传SP Metadata文件，或者不填写Metadata地址并手动配置EntityID、证书及ACS地址")
+			}
+		}
+		if err == nil {
+			v.pass("metadata_url", "SP Metadata", "解析成功")
+			v.result.Metadata = metadata
+			if data.EntityId == "" {
+				data.EntityId = metadata.EntityID
+			} else if data.EntityId != metadata.EntityID {
+				v.fail("entity_id", "EntityID", fmt.Sprintf("EntityID与SP Metadata中的不一致：%s", metadata.EntityID), "使用SP Metadata中的EntityID，否则SP发起登录时将提示应用未注册")
+			}
+			if data.Certificate == "" {
+				data.Certificate = metadata.Certificate
+			}
+			if data.AcsUrls == "" && len(metadata.AcsUrls) == 0 {
+				v.fail("acs_urls", "ACS地址", "SP Metadata中未声明HTTP-POST绑定的ACS地址", "手动配置ACS地址")
+			}
+		}
+	}
+
+	v.checkEntityId("Issuer", "EntityID需与SP登录请求中的Issuer完全一致，可从SP Metadata中获取")
+
+	// SP证书
+	if data.Certificate == "" {
+		v.warn("certificate", "SP证书", "未配置SP证书，需在SP证书中添加后才能单点登录", "从SP Metadata中获取或手动填写SP签名证书")
+	} else {
+		v.checkCertificate(data.Certificate)
+	}
+
+	// ACS地址
+	acsUrls, err := parseAcsUrls(data.AcsUrls)
+	if err != nil {
+		v.fail("acs_urls", "ACS地址", err.Error(), "")
+		return
+	}
+	if v.result.Metadata != nil && len(acsUrls) == 0 {
+		acsUrls = v.result.Metadata.AcsUrls
+	}
+	for _, acsUrl := range acsUrls {
+		v.checkURL("acs_urls", "ACS地址", acsUrl, true)
+	}
+}
+
+// checkSAMLEncryption 校验断言加密配置，未配置SP加密证书时依次使用SP Metadata中的加密证书及SP证书
+func (v *siteValidator) checkSAMLEncryption() {
+
+	data := v.data
+	if !data.EncryptAssertion {
+		return
+	}
+
+	certificate := data.EncryptionCert
+	if certificate == "" && v.result.Metadata != nil {
+		certificate = v.result.Metadata.EncryptionCertificate
+	}
+	if certificate == "" {
+		certificate = data.Certificate
+	}
+	if certificate == "" {
+		v.fail("encryption_certificate", "SP加密证书", "开启断言加密时需配置SP加密证书或SP证书", "从SP Metadata中获取或手动填写SP加密证书")
+		return
+	}
+	v.rule("encryption_certificate", "断言加密", validateSAMLEncryption(data.KeyEncryption, data.DataEncryption, certificate))
+}
+
+// checkCertificate 校验证书能否解析及有效期
+func (v *siteValidator) checkCertificate(certificate string) {
+
+	crt, err := parseCertificate(normalizeCertificatePEM(certificate))
+	if err != nil {
+		v.fail("certificate", "SP证书", "证书解析失败："+err.Error(), "填写PEM格式的X.509证书，可不包含证书头尾")
+		return
+	}
+
+	now := time.Now()
+	switch {
+	case now.Before(crt.NotBefore):
+		v.fail("certificate", "SP证书", "证书尚未生效，生效时间："+crt.NotBefore.Local().Format("2006-01-02 15:04:05"), "确认SP使用的证书")
+	case now.After(crt.NotAfter):
+		v.fail("certificate", "SP证书", "证书已过期，过期时间："+crt.NotAfter.Local().Format("2006-01-02 15:04:05"), "更新SP证书后重新获取")
+	case now.AddDate(0, 0, spCertificateExpireDays).After(crt.NotAfter):
+		v.warn("certificate", "SP证书", "证书即将过期，过期时间："+crt.NotAfter.Local().Format("2006-01-02 15:04:05"), "提前更新SP证书，轮换期间可添加多个SP证书")
+	default:
+		v.pass("certificate", "SP证书", fmt.Sprintf("证书有效（%s），过期时间：%s", crt.Subject.CommonName, crt.NotAfter.Local().Format("2006-01-02 15:04:05")))
+	}
+}
+
+// probeURL 探测地址是否可访问，返回任意HTTP响应即为可访问，无法访问时返回原因
+func probeURL(rawUrl string) error {
+
+	resp, err := siteProbeClient.Get(rawUrl)
+	if err != nil {
+		var dnsErr *net.DNSError
+		var certErr *tls.CertificateVerificationError
+		var unknownAuthority x509.UnknownAuthorityError
+		var hostnameErr x509.HostnameError
+		var netErr net.Error
+		switch {
+		case errors.As(err, &dnsErr):
+			return errors.New("域名无法解析")
+		case errors.As(err, &unknownAuthority), errors.As(err, &hostnameErr), errors.As(err, &certErr):
+			return errors.New("HTTPS证书校验失败，请确认证书由受信任的CA签发且与域名匹配")
+		case errors.As(err, &netErr) && netErr.Timeout():
+			return errors.New("连接超时")
+		}
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("HTTP状态码：%d", resp.StatusCode)
+	}
+	return nil
+}
+
+// isLoopbackHost 判断是否为本机地址，本机地址（如桌面应用）可以使用HTTP
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
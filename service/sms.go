@@ -2,27 +2,32 @@ package service
 
 import (
 	"errors"
+	"fmt"
 	"net/url"
 	"ops-api/config"
 	"ops-api/dao"
+	"ops-api/global"
 	"ops-api/model"
 	"ops-api/utils"
 	message "ops-api/utils/sms"
 	"strconv"
-	"strings"
 )
 
 var SMS sms
 
 type sms struct{}
 
-// SMSSend 发送短信
-func (s *sms) SMSSend(phoneNumber, note string) (string, error) {
+// SMSSend 发送短信，locale 为接收人的语言，用于选择短信模板
+func (s *sms) SMSSend(phoneNumber, note, locale string) (string, error) {
 
-	var (
-		smsSignature  = config.Conf.Settings["smsSignature"].(string)
-		smsTemplateId = config.Conf.Settings["smsTemplateId"].(string)
-	)
+	conf := config.SMS()
+
+	// 使用标准化后的手机号发送短信
+	phone, err := normalizePhone(phoneNumber)
+	if err != nil {
+		return "", err
+	}
+	phoneNumber = phone.E164
 
 	// 定义验证码
 	var code = strconv.Itoa(utils.GenerateRandomNumber())
@@ -33,8 +38,19 @@ func (s *sms) SMSSend(phoneNumber, note string) (string, error) {
 		return "", errors.New("不支持的短信服务提供商")
 	}
 
+	// 获取短信服务商的模板并生成模板参数
+	template, err := smsTemplate(conf.Provider)
+	if err != nil {
+		return "", err
+	}
+	params, err := template.params(code)
+	if err != nil {
+		return "", err
+	}
+	smsTemplateId := template.templateId(locale)
+
 	// 发送短信
-	resp, err := smsSender.SendSMS(phoneNumber, code)
+	resp, err := smsSender.SendSMS(phoneNumber, smsTemplateId, params)
 	if err != nil {
 		return "", err
 	}
@@ -48,7 +64,7 @@ func (s *sms) SMSSend(phoneNumber, note string) (string, error) {
 	// 记录短信发送日志
 	smsLog := &model.LogSMS{
 		Note:       note,
-		Signature:  smsSignature,
+		Signature:  conf.Signature,
 		TemplateId: smsTemplateId,
 		Receiver:   phoneNumber,
 		Status:     "API请求成功",
@@ -62,27 +78,52 @@ func (s *sms) SMSSend(phoneNumber, note string) (string, error) {
 	return code, nil
 }
 
-// SMSCallback 短信回调
-func (s *sms) SMSCallback(data string) error {
+// SMSCallback 短信回调，ts、sign为发送短信时写入回调地址的时间戳及签名
+func (s *sms) SMSCallback(data, ts, sign string) error {
+
+	// 校验回调签名，防止伪造短信回执
+	if err := message.VerifyCallback(ts, sign); err != nil {
+		return err
+	}
 
 	// 对回调返回的数据进行处理
-	ss, _ := url.QueryUnescape(data)
-	params := strings.Split(ss, "&")
-	keyValues := make(map[string]string)
-	for i := range params {
-		temp := strings.Split(params[i], "=")
-		keyValues[temp[0]] = temp[1]
+	keyValues, err := url.ParseQuery(data)
+	if err != nil {
+		return err
+	}
+	smsMsgId := keyValues.Get("smsMsgId")
+	if smsMsgId == "" {
+		return errors.New("短信唯一标识为空")
+	}
+
+	// 仅处理本系统发送的短信
+	exist, err := dao.Audit.SMSRecordExists(smsMsgId)
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return errors.New("短信不存在：" + smsMsgId)
+	}
+
+	// 同一条短信的同一状态只处理一次，防止重放
+	replayKey := fmt.Sprintf("sms_callback:%s:%s", smsMsgId, keyValues.Get("status"))
+	ok, err := global.Cache.SetNX(replayKey, "1", message.CallbackMaxAge)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
 	}
 
 	// 将数据与结构体进行绑定
 	callback := &dao.Callback{
 		Status:    "接收成功",
-		SmsMsgId:  keyValues["smsMsgId"],
+		SmsMsgId:  smsMsgId,
 		ErrorCode: "",
 	}
-	if keyValues["status"] != "DELIVRD" {
+	if keyValues.Get("status") != "DELIVRD" {
 		callback.Status = "发送失败"
-		callback.ErrorCode = keyValues["status"]
+		callback.ErrorCode = keyValues.Get("status")
 	}
 
 	// 将回调数据写入数据库
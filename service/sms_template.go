@@ -0,0 +1,165 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"ops-api/config"
+	"ops-api/utils"
+	"ops-api/utils/i18n"
+	message "ops-api/utils/sms"
+	"regexp"
+	"strconv"
+)
+
+// 短信模板映射：短信服务商需要预先报备模板，模板ID及变量的个数、顺序、名称固定，系统配置 smsTemplates 按服务商分别配置模板，
+// 切换服务商时使用对应服务商的模板，配置时及发送时均会校验；未配置时使用 smsTemplateId、smsTemplateIdEn，模板中只有一个验证码变量
+
+// 模板变量可使用的值
+const (
+	SMSVarCode    = "code"    // 验证码
+	SMSVarMinutes = "minutes" // 验证码有效期（分钟）
+	SMSVarIssuer  = "issuer"  // 站点名称
+
+	smsCodeMinutes = 5 // 短信验证码有效期（分钟）
+)
+
+// smsProviders 支持的短信服务商
+var smsProviders = []string{"huawei", "aliyun"}
+
+// smsVariableName 阿里云模板变量名的格式
+var smsVariableName = regexp.MustCompile(`^[A-Za-z0-9_]{1,32}$`)
+
+// SMSTemplate 短信服务商的模板
+type SMSTemplate struct {
+	TemplateId   string                `json:"template_id"`
+	TemplateIdEn string                `json:"template_id_en"` // 英文模板ID，为空时使用 template_id
+	Variables    []SMSTemplateVariable `json:"variables"`      // 模板变量，按模板中变量的顺序排列
+}
+
+// SMSTemplateVariable 短信模板变量
+type SMSTemplateVariable struct {
+	Name  string `json:"name"`  // 模板中的变量名，阿里云按变量名传参，华为云按顺序传参（变量名仅用于说明）
+	Value string `json:"value"` // 变量的值：code、minutes、issuer
+}
+
+// parseSMSTemplates 解析并校验短信模板映射，格式为：{"服务商": SMSTemplate}
+func parseSMSTemplates(value string) (map[string]*SMSTemplate, error) {
+
+	templates := make(map[string]*SMSTemplate)
+	if value == "" {
+		return templates, nil
+	}
+	if err := json.Unmarshal([]byte(value), &templates); err != nil {
+		return nil, errors.New("短信模板映射格式错误，格式为：{\"服务商\": {\"template_id\": \"\", \"variables\": [{\"name\": \"\", \"value\": \"code\"}]}}")
+	}
+
+	for provider, template := range templates {
+		if err := validateSMSTemplate(provider, template); err != nil {
+			return nil, err
+		}
+	}
+	return templates, nil
+}
+
+// validateSMSTemplate 校验服务商的短信模板：模板ID不能为空，必须包含验证码变量，阿里云的变量名不能为空且不能重复
+func validateSMSTemplate(provider string, template *SMSTemplate) error {
+
+	if !utils.Contains(smsProviders, provider) {
+		return fmt.Errorf("不支持的短信服务商：%s", provider)
+	}
+	if template == nil || template.TemplateId == "" {
+		return fmt.Errorf("短信服务商%s未配置模板ID", provider)
+	}
+
+	var hasCode bool
+	names := make(map[string]bool)
+	for _, variable := range template.Variables {
+		switch variable.Value {
+		case SMSVarCode:
+			hasCode = true
+		case SMSVarMinutes, SMSVarIssuer:
+		default:
+			return fmt.Errorf("短信服务商%s的模板变量值不支持：%s，可选值为：%s、%s、%s", provider, variable.Value, SMSVarCode, SMSVarMinutes, SMSVarIssuer)
+		}
+		if provider == "aliyun" {
+			if !smsVariableName.MatchString(variable.Name) {
+				return fmt.Errorf("短信服务商%s的模板变量名格式错误：%s", provider, variable.Name)
+			}
+			if names[variable.Name] {
+				return fmt.Errorf("短信服务商%s的模板变量名重复：%s", provider, variable.Name)
+			}
+			names[variable.Name] = true
+		}
+	}
+	if !hasCode {
+		return fmt.Errorf("短信服务商%s的模板中必须包含验证码变量（code）", provider)
+	}
+	return nil
+}
+
+// checkSMSProviderTemplate 校验短信服务商是否配置了模板，配置了模板映射但缺少该服务商的模板时，切换服务商会导致短信无法发送
+func checkSMSProviderTemplate(provider, templates string) error {
+	mapping, err := parseSMSTemplates(templates)
+	if err != nil {
+		return err
+	}
+	if len(mapping) > 0 && mapping[provider] == nil {
+		return fmt.Errorf("短信模板映射中未配置短信服务商%s的模板", provider)
+	}
+	return nil
+}
+
+// smsTemplate 获取当前短信服务商的模板，未配置模板映射时使用 smsTemplateId、smsTemplateIdEn
+func smsTemplate(provider string) (*SMSTemplate, error) {
+
+	templates, err := parseSMSTemplates(config.GetString("smsTemplates"))
+	if err != nil {
+		return nil, err
+	}
+	if len(templates) > 0 {
+		template := templates[provider]
+		if template == nil {
+			return nil, fmt.Errorf("未配置短信服务商%s的短信模板", provider)
+		}
+		return template, nil
+	}
+
+	conf := config.SMS()
+	if conf.TemplateId == "" {
+		return nil, errors.New("未配置短信模板")
+	}
+	return &SMSTemplate{
+		TemplateId:   conf.TemplateId,
+		TemplateIdEn: conf.TemplateIdEn,
+		Variables:    []SMSTemplateVariable{{Name: SMSVarCode, Value: SMSVarCode}},
+	}, nil
+}
+
+// templateId 获取指定语言的模板ID，未配置英文模板时使用默认模板
+func (t *SMSTemplate) templateId(locale string) string {
+	if locale == i18n.EnUS && t.TemplateIdEn != "" {
+		return t.TemplateIdEn
+	}
+	return t.TemplateId
+}
+
+// params 按模板变量生成短信参数，变量的值为空时返回错误，避免发送内容不完整的短信
+func (t *SMSTemplate) params(code string) ([]message.Param, error) {
+
+	values := map[string]string{
+		SMSVarCode:    code,
+		SMSVarMinutes: strconv.Itoa(smsCodeMinutes),
+		SMSVarIssuer:  config.GetString("issuer"),
+	}
+
+	params := make([]message.Param, 0, len(t.Variables))
+	for _, variable := range t.Variables {
+		value := values[variable.Value]
+		if value == "" {
+			return nil, fmt.Errorf("短信模板变量%s的值为空", variable.Value)
+		}
+		params = append(params, message.Param{Name: variable.Name, Value: value})
+	}
+	return params, nil
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
-	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
@@ -13,10 +12,12 @@ import (
 	"fmt"
 	"github.com/LoginRadius/go-saml"
 	"github.com/google/uuid"
-	"github.com/lestrrat-go/jwx/jwk"
+	"github.com/wonderivan/logger"
+	"net/http"
 	"net/url"
 	"ops-api/config"
 	"ops-api/dao"
+	"ops-api/global"
 	"ops-api/middleware"
 	"ops-api/model"
 	"ops-api/utils"
@@ -38,6 +39,15 @@ type OAuthAuthorize struct {
 	State        string `json:"state"`
 	Scope        string `json:"scope"`
 	Nonce        string `json:"nonce"`
+	AcrValues    string `json:"acr_values"` // 要求的认证等级，多个以空格分隔，如：2
+	// 资源指示器（RFC 8707）：申请访问的资源，多个以空格分隔；audience 与 resource 作用相同，用于兼容使用 audience 参数的客户端
+	Resource string `json:"resource"`
+	Audience string `json:"audience"`
+	// PKCE：code_challenge 为客户端生成的code_verifier计算后的值，code_challenge_method 为计算方式（S256、plain），为空时为plain
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+	// 用户在授权确认页面的选择：true 同意授权，false 拒绝授权，为空时未确认（应用要求确认授权时返回需要确认的Scope）
+	Consent *bool `json:"consent"`
 }
 
 // CASAuthorize CAS3.0客户端获取授权请求参数
@@ -52,11 +62,17 @@ type NginxAuthorize struct {
 
 // Token OAuth2.0客户端获取token请求参数
 type Token struct {
+	OAuthClientAuth
 	GrantType    string `form:"grant_type"`
 	Code         string `form:"code"`
-	ClientId     string `form:"client_id"`
 	RedirectURI  string `form:"redirect_uri"`
-	ClientSecret string `form:"client_secret"`
+	RefreshToken string `form:"refresh_token"` // 刷新令牌，grant_type=refresh_token时使用
+	CodeVerifier string `form:"code_verifier"` // PKCE：授权请求携带code_challenge时必须提供
+	DeviceCode   string `form:"device_code"`   // 设备授权码，grant_type=urn:ietf:params:oauth:grant-type:device_code时使用
+	Scope        string `form:"scope"`         // 申请的Scope，grant_type=client_credentials时使用
+	// 资源指示器（RFC 8707）：申请访问的资源，可以传递多次，只能在授权时授予的资源范围内缩小
+	Resource []string `form:"resource"`
+	Audience string   `form:"audience"`
 }
 
 // CASServiceValidate CAS3.0客户端票据校验请求参数
@@ -73,19 +89,29 @@ type SAMLRequest struct {
 	Signature   string `form:"Signature"`                      // 签名，用于验证SP的身份，但需要配置SP的公钥
 }
 
-// ParseSPMetadata 获取SP Metadata信息请求参数
+// ParseSPMetadata 获取SP Metadata信息请求参数，Metadata地址及Metadata XML二选一
 type ParseSPMetadata struct {
-	SPMetadataURL string `json:"sp_metadata_url" binding:"required"`
+	SPMetadataURL string `json:"sp_metadata_url"`
+	SPMetadataXML string `json:"sp_metadata_xml"` // SP无法从平台访问或未提供Metadata地址时，粘贴的Metadata XML
 }
 
+// SPMetadataMaxSize 上传的SP Metadata大小限制
+const SPMetadataMaxSize = 1 << 20
+
 // SPMetadata 返回给前端的SP Metadata数据
 type SPMetadata struct {
-	EntityID    string `json:"entity_id"`
-	Certificate string `json:"certificate"`
+	EntityID     string   `json:"entity_id"`
+	Certificate  string   `json:"certificate"`
+	Certificates []string `json:"certificates"` // SP声明的所有签名证书，SP轮换证书期间可能存在多个
+	AcsUrls      []string `json:"acs_urls"`     // SP声明的ACS地址，默认地址排在第一个
+	SloUrl       string   `json:"slo_url"`      // SP声明的单点注销地址（HTTP-POST）
+
+	EncryptionCertificate string `json:"encryption_certificate"` // SP声明的加密证书，用于加密断言
 }
 
 // SAMLResponse IDP返回给浏览器的SAMLResponse数据
 type SAMLResponse struct {
+	PostFormText
 	URL          string
 	SAMLResponse string
 	RelayState   string
@@ -93,11 +119,11 @@ type SAMLResponse struct {
 
 // ResponseToken 返回给OAuth2.0客户端客户端的Token信息
 type ResponseToken struct {
-	IdToken      string `json:"id_token"`
+	IdToken      string `json:"id_token,omitempty"`
 	AccessToken  string `json:"access_token"`
 	TokenType    string `json:"token_type"`
 	ExpiresIn    int    `json:"expires_in"`
-	RefreshToken string `json:"refresh_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	Scope        string `json:"scope"`
 }
 
@@ -106,10 +132,15 @@ type CASServiceResponse struct {
 	XMLName               xml.Name               `xml:"cas:serviceResponse"`
 	Xmlns                 string                 `xml:"xmlns:cas,attr"`
 	AuthenticationSuccess *AuthenticationSuccess `xml:"cas:authenticationSuccess"`
+	AuthenticationFailure *AuthenticationFailure `xml:"cas:authenticationFailure"` // 仅CAS2.0票据校验失败时返回
 }
 type AuthenticationSuccess struct {
-	User       string     `xml:"cas:user"`
-	Attributes Attributes `xml:"cas:attributes"`
+	User       string      `xml:"cas:user"`
+	Attributes *Attributes `xml:"cas:attributes"` // CAS2.0不返回用户属性
+}
+type AuthenticationFailure struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
 }
 type Attributes struct {
 	Id          uint   `xml:"id"`
@@ -117,16 +148,19 @@ type Attributes struct {
 	Username    string `xml:"username"`
 	Email       string `xml:"email"`
 	PhoneNumber string `xml:"phone_number"`
+
+	profile string         // 站点配置的响应格式
+	compat  []casAttribute // 兼容格式返回的属性
 }
 
 // ResponseUserinfo 返回给客户端的用户信息
 type ResponseUserinfo struct {
 	Id                uint   `json:"id"`
-	Name              string `json:"name"`               // 用户姓名
-	Username          string `json:"username"`           // 用户名
-	PreferredUsername string `json:"preferred_username"` // 首选用户名
-	Email             string `json:"email"`              // 邮箱地址
-	PhoneNumber       string `json:"phone_number"`       // 电话号码
+	Name              string `json:"name"`                   // 用户姓名
+	Username          string `json:"username"`               // 用户名
+	PreferredUsername string `json:"preferred_username"`     // 首选用户名
+	Email             string `json:"email,omitempty"`        // 邮箱地址，仅授予email时返回
+	PhoneNumber       string `json:"phone_number,omitempty"` // 电话号码，仅授予phone时返回
 	Sub               string `json:"sub"`
 }
 
@@ -144,50 +178,73 @@ type OIDCConfig struct {
 	JwksURI                           string   `json:"jwks_uri"`
 	ScopesSupported                   []string `json:"scopes_supported"`
 	ResponseTypesSupported            []string `json:"response_types_supported"`
+	ResponseModesSupported            []string `json:"response_modes_supported"`
 	GrantTypesSupported               []string `json:"grant_types_supported"`
 	SubjectTypesSupported             []string `json:"subject_types_supported"`
 	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
 	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	TokenEndpointAuthSigningAlgs      []string `json:"token_endpoint_auth_signing_alg_values_supported"`
 	ClaimsSupported                   []string `json:"claims_supported"`
+	AcrValuesSupported                []string `json:"acr_values_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint,omitempty"`
 }
 
 // GetOIDCConfig 获取OIDC配置信息
 func (s *sso) GetOIDCConfig() (configuration *OIDCConfig, err error) {
-	externalUrl := config.Conf.Settings["externalUrl"].(string)
 	var cfg = &OIDCConfig{
-		Issuer:                            externalUrl,
-		AuthorizationEndpoint:             externalUrl + "/login",
-		TokenEndpoint:                     externalUrl + "/api/v1/sso/oauth/token",
-		UserInfoEndpoint:                  externalUrl + "/api/v1/sso/oauth/userinfo",
-		JwksURI:                           externalUrl + "/api/v1/sso/oidc/jwks",
-		ScopesSupported:                   []string{"openid"},
-		ResponseTypesSupported:            []string{"code"},
-		GrantTypesSupported:               []string{"authorization_code"},
-		SubjectTypesSupported:             []string{"public"},
+		Issuer:                            middleware.OIDCIssuer(),
+		AuthorizationEndpoint:             middleware.OIDCEndpoint("oidcAuthorizationEndpoint"),
+		TokenEndpoint:                     middleware.OIDCEndpoint("oidcTokenEndpoint"),
+		UserInfoEndpoint:                  middleware.OIDCEndpoint("oidcUserinfoEndpoint"),
+		JwksURI:                           middleware.OIDCEndpoint("oidcJwksUri"),
+		ScopesSupported:                   oauthSupportedScopes,
+		ResponseTypesSupported:            oauthSupportedResponseTypes,
+		ResponseModesSupported:            []string{"query", "fragment"},
+		GrantTypesSupported:               oauthSupportedGrantTypes,
+		SubjectTypesSupported:             []string{"public", "pairwise"},
 		IDTokenSigningAlgValuesSupported:  []string{"RS256"},
-		TokenEndpointAuthMethodsSupported: []string{"client_secret_post"},
-		ClaimsSupported:                   []string{"id", "name", "username", "preferred_username", "sub"},
+		TokenEndpointAuthMethodsSupported: []string{tokenAuthClientSecretBasic, tokenAuthClientSecretPost, tokenAuthPrivateKeyJWT, tokenAuthNone},
+		TokenEndpointAuthSigningAlgs:      clientAssertionAlgs,
+		ClaimsSupported:                   []string{"id", "name", "username", "preferred_username", "email", "phone_number", "sub", "acr", "amr", "auth_time"},
+		AcrValuesSupported:                []string{middleware.ACRSingleFactor, middleware.ACRMultiFactor},
+		CodeChallengeMethodsSupported:     oauthSupportedCodeChallengeMethods,
+		DeviceAuthorizationEndpoint:       middleware.OIDCEndpoint("oidcDeviceEndpoint"),
+		RegistrationEndpoint:              OIDCRegistrationEndpoint(),
 	}
 
 	return cfg, nil
 }
 
 // GetNginxAuthorize Nginx授权
-func (s *sso) GetNginxAuthorize(data *NginxAuthorize, userId uint) (callbackUrl, siteName string, err error) {
+func (s *sso) GetNginxAuthorize(data *NginxAuthorize, userId uint, sessionId string) (callbackUrl, siteName string, err error) {
 
 	// 获取客户端应用
 	site, err := dao.Site.GetNginxSite(data.CallbackURL)
 	if err != nil {
+		recordSSOError(SSOProtocolNginx, nil, SSOErrorUnregistered, data.CallbackURL)
 		return "", "", errors.New("应用未注册或配置错误")
 	}
 
+	// 判断应用使用的单点登录协议是否已停用
+	if err := checkProtocolSunset(SSOProtocolNginx, site); err != nil {
+		return "", site.Name, err
+	}
+
 	// 判断用户是否有权限访问
 	if !site.AllOpen {
 		if !dao.Site.IsUserInSite(userId, site) {
+			recordSSOError(SSOProtocolNginx, site, SSOErrorAccessDenied, fmt.Sprintf("用户ID：%d", userId))
 			return "", site.Name, errors.New("您无权访问该应用")
 		}
 	}
 
+	// 判断用户是否已接受使用条款
+	if err := Terms.Check(site, userId); err != nil {
+		return "", site.Name, err
+	}
+
 	// 生成token
 	str := utils.GenerateRandomString(32)
 	// 字符串加密，用于返回给客户端授权码
@@ -195,39 +252,130 @@ func (s *sso) GetNginxAuthorize(data *NginxAuthorize, userId uint) (callbackUrl,
 
 	// 将Token写入数据库
 	ticket := &model.SsoNginxTicket{
-		Token:     str,                            // 数据库中存放未加密的code，客户端来认证的时候使用的是加密后的code，这样在验证code的时候将前端加密的进行解密判断是否与数据库中的相等即可
-		UserID:    userId,                         // 用户ID
-		ExpiresAt: time.Now().Add(12 * time.Hour), // Token的有效期为12小时
+		Token:     str,                                  // 数据库中存放未加密的code，客户端来认证的时候使用的是加密后的code，这样在验证code的时候将前端加密的进行解密判断是否与数据库中的相等即可
+		UserID:    userId,                               // 用户ID
+		SiteID:    site.ID,                              // 站点ID
+		SessionID: sessionId,                            // 用户会话ID
+		ExpiresAt: time.Now().Add(nginxTicketTTL(site)), // Token的有效期，默认为12小时
 	}
 	if err = dao.SSO.CreateAuthorizeToken(ticket); err != nil {
 		return "", "", err
 	}
 
+	// 通知应用用户已登录
+	SiteLaunchHook.Publish(site, SSOProtocolNginx, userId)
+
 	redirectURI := fmt.Sprintf("%s?token=%s", site.CallbackUrl, code)
 	return redirectURI, site.Name, nil
 }
 
+// nginxTicketTTL 获取站点Nginx票据有效期，未配置时为12小时
+func nginxTicketTTL(site *model.Site) time.Duration {
+	if site.NginxTTL == 0 {
+		return 12 * time.Hour
+	}
+	return time.Duration(site.NginxTTL) * time.Hour
+}
+
+// NginxCookieAuth Nginx Cookie认证，站点开启自动续期且票据已超过一半有效期时签发新票据并返回加密后的Token，
+// 旧票据在宽限时间内仍然有效，避免并发请求因Cookie尚未更新而认证失败；续期失败不影响本次认证
+func (s *sso) NginxCookieAuth(cookieValue string) (renewed string, err error) {
+
+	token, err := utils.Decrypt(cookieValue)
+	if err != nil {
+		return "", err
+	}
+
+	// 获取Token（如果有数据则表明：1、Code存在，2、在有效期内）
+	ticket, err := dao.SSO.GetAuthorizeToken(token)
+	if err != nil || ticket.Token != token {
+		return "", errors.New("认证失败")
+	}
+
+	// 已续期的票据及历史票据不再续期
+	if ticket.RotatedAt != nil || ticket.SiteID == 0 {
+		return "", nil
+	}
+
+	var site model.Site
+	if err := global.MySQLClient.First(&site, ticket.SiteID).Error; err != nil || !site.NginxRenewal {
+		return "", nil
+	}
+
+	// 未超过一半有效期时不续期
+	now := time.Now()
+	if now.Sub(ticket.CreatedAt) < ticket.ExpiresAt.Sub(ticket.CreatedAt)/2 {
+		return "", nil
+	}
+
+	// 用户已无权访问或会话已注销时不续期，票据到期后失效
+	if !site.AllOpen && !dao.Site.IsUserInSite(ticket.UserID, &site) {
+		return "", nil
+	}
+	if revoked, err := middleware.IsSessionRevoked(ticket.SessionID); err != nil || revoked {
+		return "", nil
+	}
+
+	str := utils.GenerateRandomString(32)
+	code, err := utils.Encrypt(str)
+	if err != nil {
+		logger.Error("ERROR：Nginx票据续期失败，", err.Error())
+		return "", nil
+	}
+
+	newTicket := &model.SsoNginxTicket{
+		Token:     str,
+		UserID:    ticket.UserID,
+		SiteID:    ticket.SiteID,
+		SessionID: ticket.SessionID,
+		ExpiresAt: now.Add(nginxTicketTTL(&site)),
+	}
+	grace := time.Duration(site.NginxGrace) * time.Second
+	rotated, err := dao.SSO.RotateAuthorizeToken(ticket, now.Add(grace), newTicket)
+	if err != nil {
+		logger.Error("ERROR：Nginx票据续期失败，", err.Error())
+		return "", nil
+	}
+	if !rotated {
+		return "", nil
+	}
+
+	return code, nil
+}
+
 // GetCASAuthorize CAS3.0客户端授权
-func (s *sso) GetCASAuthorize(data *CASAuthorize, userId uint, username string) (callbackUrl, siteName string, err error) {
+func (s *sso) GetCASAuthorize(data *CASAuthorize, userId uint, username, sessionId string) (callbackUrl, siteName string, err error) {
 
 	// 获取客户端应用
 	site, err := dao.Site.GetCASSite(data.Service)
 	if err != nil {
+		recordSSOError(SSOProtocolCAS, nil, SSOErrorUnregistered, data.Service)
 		return "", "", errors.New("应用未注册或配置错误")
 	}
 
+	// 判断应用使用的单点登录协议是否已停用
+	if err := checkProtocolSunset(SSOProtocolCAS, site); err != nil {
+		return "", site.Name, err
+	}
+
 	// 判断用户是否有权限访问
 	if !site.AllOpen {
 		if !dao.Site.IsUserInSite(userId, site) {
+			recordSSOError(SSOProtocolCAS, site, SSOErrorAccessDenied, fmt.Sprintf("用户ID：%d", userId))
 			return "", site.Name, errors.New("您无权访问该应用")
 		}
 	}
 
+	// 判断用户是否已接受使用条款
+	if err := Terms.Check(site, userId); err != nil {
+		return "", site.Name, err
+	}
+
 	// 生成票据（固定格式）
 	st := fmt.Sprintf("ST-%d-%s", time.Now().Unix(), username)
 
 	// 使用HMAC SHA-256对票据进行签名
-	secret := config.Conf.Settings["secret"].(string)
+	secret := config.SSO().Secret
 	mac := hmac.New(sha256.New, []byte(secret))
 	mac.Write([]byte(st))
 	signature := hex.EncodeToString(mac.Sum(nil))
@@ -235,10 +383,12 @@ func (s *sso) GetCASAuthorize(data *CASAuthorize, userId uint, username string)
 	// 将授权票据写入数据库
 	st = fmt.Sprintf("%s-%s", st, signature)
 	ticket := &model.SsoCASTicket{
-		Ticket:    st,                               // 票据信息
-		Service:   site.CallbackUrl,                 // 回调地址
-		UserID:    userId,                           // 用户ID
-		ExpiresAt: time.Now().Add(10 * time.Second), // 票据的有效期为10秒
+		Ticket:    st,                                 // 票据信息
+		Service:   site.CallbackUrl,                   // 回调地址
+		UserID:    userId,                             // 用户ID
+		SessionID: sessionId,                          // 用户会话ID
+		SiteID:    site.ID,                            // 站点ID
+		ExpiresAt: time.Now().Add(casTicketTTL(site)), // 票据的有效期，默认为10秒
 	}
 	if err = dao.SSO.CreateAuthorizeTicket(ticket); err != nil {
 		return "", site.Name, err
@@ -250,21 +400,27 @@ func (s *sso) GetCASAuthorize(data *CASAuthorize, userId uint, username string)
 		separator = "&"
 	}
 	redirectURI := fmt.Sprintf("%s%sticket=%s", site.CallbackUrl, separator, st)
+
+	// 通知应用用户已登录
+	SiteLaunchHook.Publish(site, SSOProtocolCAS, userId)
+
 	return redirectURI, site.Name, nil
 }
 
 // ServiceValidate CAS3.0客户端票据校验
 func (s *sso) ServiceValidate(param *CASServiceValidate) (data *CASServiceResponse, err error) {
 	// 客户端验证
-	_, err = dao.Site.GetCASSite(param.Service)
+	site, err := dao.Site.GetCASSite(param.Service)
 	if err != nil {
-		return nil, errors.New("service string is invalid")
+		recordSSOError(SSOProtocolCAS, nil, SSOErrorUnregistered, param.Service)
+		return nil, ErrCASInvalidService
 	}
 
 	// 获取票据（如果有数据则表明：1、Code存在，2、在有效期内，3、未使用）
 	ticketInfo, err := dao.SSO.GetAuthorizeTicket(param.Ticket)
 	if err != nil {
-		return nil, errors.New("ticket string is invalid")
+		recordSSOError(SSOProtocolCAS, site, SSOErrorInvalidTicket, "票据不存在、已过期或已使用")
+		return nil, ErrCASInvalidTicket
 	}
 
 	// 分离票据
@@ -272,7 +428,8 @@ func (s *sso) ServiceValidate(param *CASServiceValidate) (data *CASServiceRespon
 
 	// 票据验证：结构验证
 	if len(parts) != 4 {
-		return nil, errors.New("ticket string is invalid")
+		recordSSOError(SSOProtocolCAS, site, SSOErrorInvalidTicket, "票据格式错误")
+		return nil, ErrCASInvalidTicket
 	}
 
 	// 获取票据本体
@@ -281,14 +438,15 @@ func (s *sso) ServiceValidate(param *CASServiceValidate) (data *CASServiceRespon
 	signature := parts[3]
 
 	// 生成新的签名
-	secret := config.Conf.Settings["secret"].(string)
+	secret := config.SSO().Secret
 	mac := hmac.New(sha256.New, []byte(secret))
 	mac.Write([]byte(ticket))
 	newSignature := hex.EncodeToString(mac.Sum(nil))
 
 	// 票据验证：比较签名
 	if !hmac.Equal([]byte(newSignature), []byte(signature)) {
-		return nil, errors.New("ticket string is invalid")
+		recordSSOError(SSOProtocolCAS, site, SSOErrorSignatureMismatch, "票据签名校验失败")
+		return nil, ErrCASInvalidTicket
 	}
 
 	// 获取用户信息
@@ -297,176 +455,433 @@ func (s *sso) ServiceValidate(param *CASServiceValidate) (data *CASServiceRespon
 		return nil, err
 	}
 
+	attributes := Attributes{
+		Id:          uint(user.ID),
+		Email:       user.Email,
+		Name:        user.Name,
+		PhoneNumber: user.PhoneNumber,
+		Username:    user.Username,
+		profile:     site.CASProfile,
+	}
+
+	// 兼容旧CAS服务端的响应格式
+	if site.CASProfile != CASProfileDefault {
+		var authUser model.AuthUser
+		if err := global.MySQLClient.Preload("Groups").First(&authUser, ticketInfo.UserID).Error; err != nil {
+			return nil, err
+		}
+		if attributes.compat, err = casCompatAttributes(site, &authUser); err != nil {
+			return nil, err
+		}
+	}
+
 	return &CASServiceResponse{
 		Xmlns: "http://www.yale.edu/tp/cas",
 		AuthenticationSuccess: &AuthenticationSuccess{
-			User: user.Username,
-			Attributes: Attributes{
-				Id:          uint(user.ID),
-				Email:       user.Email,
-				Name:        user.Name,
-				PhoneNumber: user.PhoneNumber,
-				Username:    user.Username,
-			},
+			User:       user.Username,
+			Attributes: &attributes,
 		},
 	}, nil
 }
 
-// GetOAuthAuthorize OAuth2.0客户端授权
-func (s *sso) GetOAuthAuthorize(data *OAuthAuthorize, userId uint) (callbackUrl, siteName string, err error) {
+// GetOAuthAuthorize OAuth2.0客户端授权，失败时返回 *OAuthError；支持授权码（code）、隐式（id_token、id_token token）及混合（code id_token）流程
+func (s *sso) GetOAuthAuthorize(data *OAuthAuthorize, userId uint, sessionId string) (callbackUrl, siteName string, err error) {
 
-	// 获取客户端应用
+	// 除授权码流程外，授权结果及错误信息均通过回调地址的fragment返回
+	responseType := normalizeResponseType(data.ResponseType)
+	defer func() {
+		var oauthErr *OAuthError
+		if errors.As(err, &oauthErr) {
+			oauthErr.WithFragment(oauthFragmentResponse(responseType))
+		}
+	}()
+
+	// 获取客户端应用，客户端未注册时不能重定向到客户端
 	site, err := dao.Site.GetOAuthSite(data.ClientId)
 	if err != nil {
-		return "", "", errors.New("应用未注册或配置错误")
+		recordSSOError(SSOProtocolOAuth, nil, SSOErrorUnregistered, data.ClientId)
+		return "", "", NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Unknown client_id").WithState(data.State)
+	}
+
+	// 判断应用使用的单点登录协议是否已停用
+	if err := checkProtocolSunset(SSOProtocolOAuth, site); err != nil {
+		return "", site.Name, err
+	}
+
+	// 判断授权类型
+	if !utils.Contains(oauthSupportedResponseTypes, responseType) {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "不支持的response_type："+data.ResponseType)
+		return "", site.Name, NewOAuthError(http.StatusBadRequest, OAuthUnsupportedResponseType, "Unsupported response_type").
+			WithState(data.State).WithRedirect(site.CallbackUrl)
+	}
+	if !siteAllowsResponseType(site, responseType) {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "应用不允许使用的response_type："+data.ResponseType)
+		return "", site.Name, NewOAuthError(http.StatusBadRequest, OAuthUnauthorizedClient, "The client is not allowed to use this response_type").
+			WithState(data.State).WithRedirect(site.CallbackUrl)
+	}
+
+	// 授权端点直接返回ID Token时必须携带nonce，用于防止重放
+	if responseTypeIncludes(responseType, "id_token") && data.Nonce == "" {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "隐式及混合流程缺少nonce")
+		return "", site.Name, NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Missing required parameter: nonce").
+			WithState(data.State).WithRedirect(site.CallbackUrl)
+	}
+
+	// 校验PKCE参数，公共客户端必须使用PKCE（仅签发授权码时）
+	var challengeMethod string
+	if responseTypeIncludes(responseType, "code") {
+		var oauthErr *OAuthError
+		if challengeMethod, oauthErr = checkCodeChallenge(site, data.CodeChallenge, data.CodeChallengeMethod); oauthErr != nil {
+			recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "PKCE参数错误："+oauthErr.Description)
+			return "", site.Name, oauthErr.WithState(data.State).WithRedirect(site.CallbackUrl)
+		}
+	}
+
+	// 判断申请的Scope
+	scope, err := grantedScope(site, data.Scope)
+	if err != nil {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "应用不允许申请的scope："+data.Scope)
+		return "", site.Name, NewOAuthError(http.StatusBadRequest, OAuthInvalidScope, err.Error()).
+			WithState(data.State).WithRedirect(site.CallbackUrl)
+	}
+
+	// 判断申请的资源
+	resources, oauthErr := grantedResources(site, requestedResources([]string{data.Resource}, data.Audience))
+	if oauthErr != nil {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "应用不允许申请的资源："+strings.TrimSpace(data.Resource+" "+data.Audience))
+		return "", site.Name, oauthErr.WithState(data.State).WithRedirect(site.CallbackUrl)
 	}
 
 	// 判断用户是否有权限访问
 	if !site.AllOpen {
 		if !dao.Site.IsUserInSite(userId, site) {
-			return "", site.Name, errors.New("您无权访问该应用")
+			recordSSOError(SSOProtocolOAuth, site, SSOErrorAccessDenied, fmt.Sprintf("用户ID：%d", userId))
+			return "", site.Name, NewOAuthError(http.StatusForbidden, OAuthAccessDenied, "The user is not allowed to access this application").
+				WithState(data.State).WithRedirect(site.CallbackUrl)
 		}
 	}
 
-	// 创建随机字符串（长度建议>16）
-	str := utils.GenerateRandomString(32)
-	// 字符串加密，用于返回给客户端授权码
-	code, err := utils.Encrypt(str)
-	if err != nil {
+	// 判断用户是否已接受使用条款
+	if err := Terms.Check(site, userId); err != nil {
 		return "", site.Name, err
 	}
 
-	// 将授权票据写入数据库
-	ticket := &model.SsoOAuthTicket{
-		Code:        str,                              // 数据库中存放未加密的code，客户端来认证的时候使用的是加密后的code，这样在验证code的时候将前端加密的进行解密判断是否与数据库中的相等即可
-		RedirectURI: site.CallbackUrl,                 // 回调地址
-		UserID:      userId,                           // 用户ID
-		ExpiresAt:   time.Now().Add(10 * time.Second), // 票据的有效期为10秒
-		Nonce:       &data.Nonce,
-	}
-	if err = dao.SSO.CreateAuthorizeCode(ticket); err != nil {
+	// 客户端要求多因子认证时，会话未完成多因子认证则需要先进行升级认证
+	if err := s.checkAcrValues(data, site.CallbackUrl, userId, sessionId); err != nil {
 		return "", site.Name, err
 	}
 
-	// 返回授权码
-	separator := "?"
-	if strings.Contains(site.CallbackUrl, "?") {
-		separator = "&"
+	// 应用要求用户确认授权时，用户未授权过申请的Scope需要先确认
+	if site.Consent {
+		if data.Consent != nil && !*data.Consent {
+			recordSSOError(SSOProtocolOAuth, site, SSOErrorAccessDenied, fmt.Sprintf("用户拒绝授权，用户ID：%d", userId))
+			return "", site.Name, NewOAuthError(http.StatusForbidden, OAuthAccessDenied, "The user denied the authorization request").
+				WithState(data.State).WithRedirect(site.CallbackUrl)
+		}
+		if err := Consent.Check(site, userId, scope, data.Consent != nil); err != nil {
+			if consentPending(err) {
+				return "", site.Name, err
+			}
+			logger.Error("校验用户授权失败：" + err.Error())
+			return "", site.Name, NewOAuthServerError().WithState(data.State).WithRedirect(site.CallbackUrl)
+		}
 	}
-	redirectURI := fmt.Sprintf("%s%scode=%s&state=%s", site.CallbackUrl, separator, code, data.State)
+
+	// 签发授权码（授权码及混合流程）
+	var code string
+	if responseTypeIncludes(responseType, "code") {
+		// 创建随机字符串（长度建议>16）
+		str := utils.GenerateRandomString(32)
+		// 字符串加密，用于返回给客户端授权码
+		code, err = utils.Encrypt(str)
+		if err != nil {
+			logger.Error("生成授权码失败：" + err.Error())
+			return "", site.Name, NewOAuthServerError().WithState(data.State).WithRedirect(site.CallbackUrl)
+		}
+
+		// 将授权票据写入数据库
+		ticket := &model.SsoOAuthTicket{
+			Code:        str,                                        // 数据库中存放未加密的code，客户端来认证的时候使用的是加密后的code，这样在验证code的时候将前端加密的进行解密判断是否与数据库中的相等即可
+			RedirectURI: data.RedirectURI,                           // 授权请求中的回调地址，获取Token时需要一致
+			ClientId:    site.ClientId,                              // 签发授权码的客户端
+			UserID:      userId,                                     // 用户ID
+			SessionID:   sessionId,                                  // 用户会话ID
+			ExpiresAt:   time.Now().Add(authorizationCodeTTL(site)), // 授权码的有效期，默认为10秒
+			Nonce:       &data.Nonce,
+			Scope:       scope,     // 授予的Scope
+			Resource:    resources, // 授予的资源
+
+			CodeChallenge:       data.CodeChallenge,
+			CodeChallengeMethod: challengeMethod,
+		}
+		if err = dao.SSO.CreateAuthorizeCode(ticket); err != nil {
+			logger.Error("保存授权码失败：" + err.Error())
+			return "", site.Name, NewOAuthServerError().WithState(data.State).WithRedirect(site.CallbackUrl)
+		}
+	}
+
+	var redirectURI string
+	if oauthFragmentResponse(responseType) {
+		// 隐式及混合流程通过fragment返回Token
+		if redirectURI, err = s.fragmentRedirect(site, data, responseType, scope, resources, code, userId, sessionId); err != nil {
+			logger.Error("生成Token失败：" + err.Error())
+			return "", site.Name, NewOAuthServerError().WithState(data.State).WithRedirect(site.CallbackUrl)
+		}
+	} else {
+		// 返回授权码
+		separator := "?"
+		if strings.Contains(site.CallbackUrl, "?") {
+			separator = "&"
+		}
+		redirectURI = fmt.Sprintf("%s%scode=%s&state=%s", site.CallbackUrl, separator, code, data.State)
+	}
+
+	// 通知应用用户已登录
+	SiteLaunchHook.Publish(site, SSOProtocolOAuth, userId)
+
 	return redirectURI, site.Name, nil
 }
 
-// GetToken OAuth2.0客户端Token获取
+// oidcSubject 获取用户在应用中的sub标识
+// public：所有应用相同，为 user-{用户ID}；
+// pairwise：HMAC-SHA256(系统密钥, 扇区标识:用户ID)，同一扇区（默认为回调地址的主机名）内的应用相同，不同扇区的应用无法通过sub关联同一用户
+func oidcSubject(site *model.Site, userId uint) string {
+
+	if site.SubjectType != "pairwise" {
+		return fmt.Sprintf("user-%d", userId)
+	}
+
+	sector := site.SectorId
+	if sector == "" {
+		if u, err := url.Parse(site.CallbackUrl); err == nil {
+			sector = u.Host
+		}
+	}
+
+	secret := config.SSO().Secret
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%s:%d", sector, userId)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// checkAcrValues 校验会话认证等级是否满足客户端要求
+// 用户已绑定MFA时返回 interaction_required（不重定向到客户端），由前端完成升级认证后重新授权；
+// 用户未绑定MFA时无法升级认证，返回 unmet_authentication_requirements 并重定向到客户端
+func (s *sso) checkAcrValues(data *OAuthAuthorize, callbackUrl string, userId uint, sessionId string) *OAuthError {
+
+	if !utils.Contains(strings.Fields(data.AcrValues), middleware.ACRMultiFactor) {
+		return nil
+	}
+	if middleware.ACR(middleware.GetSessionAMR(sessionId)) == middleware.ACRMultiFactor {
+		return nil
+	}
+
+	user, err := dao.User.GetUser(map[string]interface{}{"id": userId})
+	if err != nil {
+		logger.Error("获取用户信息失败：" + err.Error())
+		return NewOAuthServerError().WithState(data.State).WithRedirect(callbackUrl)
+	}
+	if user.MFACode == nil {
+		return NewOAuthError(http.StatusForbidden, OAuthUnmetAuthentication, "Multi-factor authentication is required but not configured for the user").
+			WithState(data.State).WithRedirect(callbackUrl)
+	}
+
+	return NewOAuthError(http.StatusUnauthorized, OAuthInteractionRequired, "Multi-factor authentication is required").WithState(data.State)
+}
+
+// GetToken OAuth2.0客户端Token获取，失败时返回 *OAuthError
 func (s *sso) GetToken(param *Token) (token *ResponseToken, err error) {
 
 	var user *dao.UserInfoWithMenu
 
+	if param.GrantType == "" {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Missing required parameter: grant_type")
+	}
+
+	// 内部服务使用服务客户端获取服务令牌
+	if param.GrantType == OAuthClientCredentialsGrantType {
+		return ServiceClient.IssueToken(param)
+	}
+
 	// 客户端验证
-	site, err := dao.Site.GetOAuthSite(param.ClientId)
-	if err != nil {
-		return nil, errors.New("client_id string is invalid")
+	site, authErr := authenticateClient(&param.OAuthClientAuth)
+	if authErr != nil {
+		return nil, authErr
+	}
+
+	// 应用使用的单点登录协议已停用时不再签发Token
+	if err := checkProtocolSunset(SSOProtocolOAuth, site); err != nil {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthUnauthorizedClient, "The OAuth2.0 protocol has been retired for this client")
+	}
+
+	// 判断授权类型
+	if !utils.Contains(oauthSupportedGrantTypes, param.GrantType) {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "不支持的grant_type："+param.GrantType)
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthUnsupportedGrantType, "Unsupported grant_type")
 	}
-	if site.ClientSecret != param.ClientSecret {
-		return nil, errors.New("client_secret string is invalid")
+	if !siteAllowsGrantType(site, param.GrantType) {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "应用不允许使用的grant_type："+param.GrantType)
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthUnauthorizedClient, "The client is not allowed to use this grant_type")
+	}
+
+	// 使用刷新令牌获取Token
+	if param.GrantType == "refresh_token" {
+		return s.refreshToken(site, param)
+	}
+
+	// 设备轮询获取Token
+	if param.GrantType == OAuthDeviceCodeGrantType {
+		return s.deviceCodeToken(site, param)
+	}
+
+	if param.Code == "" {
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidRequest, "Missing required parameter: code")
 	}
 
 	// 获取Code（如果有数据则表明：1、Code存在，2、在有效期内，3、未使用）
 	code, _ := utils.Decrypt(param.Code)
 	ticket, err := dao.SSO.GetAuthorizeCode(code)
 	if err != nil {
-		return nil, errors.New("code string is invalid")
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorExpiredCode, "授权码无效、已过期或已使用")
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidGrant, "The authorization code is invalid, expired or already used")
+	}
+
+	// 授权码只能由签发授权码的客户端使用
+	if ticket.ClientId != site.ClientId {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "授权码不是签发给该客户端的")
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidGrant, "The authorization code was not issued to this client")
+	}
+
+	// 授权请求携带了redirect_uri时，本次请求必须携带且与授权请求一致（RFC 6749 4.1.3），未携带时本次请求携带的redirect_uri需与应用回调地址一致
+	expectedURI := ticket.RedirectURI
+	if expectedURI == "" && param.RedirectURI != "" {
+		expectedURI = site.CallbackUrl
+	}
+	if param.RedirectURI != expectedURI {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "redirect_uri与授权请求不一致："+param.RedirectURI)
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+
+	// 授权请求携带code_challenge时校验code_verifier
+	if !verifyCodeVerifier(ticket, param.CodeVerifier) {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "PKCE code_verifier校验失败")
+		return nil, NewOAuthError(http.StatusBadRequest, OAuthInvalidGrant, "PKCE verification failed")
+	}
+
+	// 兼容未记录Scope的授权码
+	scope := ticket.Scope
+	if scope == "" {
+		scope = oauthDefaultScope
+	}
+
+	// Access Token的资源只能在授权时授予的资源范围内缩小
+	resources, oauthErr := narrowResources(site, ticket.Resource, requestedResources(param.Resource, param.Audience))
+	if oauthErr != nil {
+		recordSSOError(SSOProtocolOAuth, site, SSOErrorInvalidRequest, "申请的资源超出授权范围")
+		return nil, oauthErr
 	}
 
-	// 生成token供access_token和id_token使用（OIDC认证使用的id_token，OAuth认证使用的access_token）
+	// 分别签发id_token（OIDC认证）及access_token（访问userinfo等资源）
 	user, err = dao.User.GetUserInfo(ticket.UserID)
-	idToken, err := middleware.GenerateOAuthToken(uint(user.ID), user.Name, user.Username, site.ClientId, "readwrite", *ticket.Nonce)
 	if err != nil {
-		return nil, err
+		logger.Error("获取用户信息失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+	idToken, accessToken, err := s.signOAuthTokens(site, user, ticket.UserID, scope, resources, *ticket.Nonce, ticket.SessionID)
+	if err != nil {
+		logger.Error("生成Token失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
+
+	// 应用允许使用refresh_token时签发刷新令牌，授予了offline_access时绑定登录设备
+	refreshToken, err := s.issueRefreshToken(site, ticket.UserID, ticket.SessionID, scope, ticket.Resource)
+	if err != nil {
+		logger.Error("生成刷新令牌失败：" + err.Error())
+		return nil, NewOAuthServerError()
 	}
 
 	token = &ResponseToken{
-		IdToken:     idToken,
-		AccessToken: idToken,
-		TokenType:   "bearer", // 固定值
-		ExpiresIn:   3600,     // Token过期时间，这里和配置文件中的JWT过期时间保持一致，也可以独立配置
-		Scope:       "openid", // 固定值
+		IdToken:      idToken,
+		AccessToken:  accessToken,
+		TokenType:    "bearer",                            // 固定值
+		ExpiresIn:    int(accessTokenTTL(site).Seconds()), // Token过期时间，与Access Token的有效期一致
+		RefreshToken: refreshToken,                        // 刷新令牌，应用允许使用refresh_token时返回
+		Scope:        scope,                               // 授权时授予的Scope
 	}
 
-	return token, err
+	return token, nil
 }
 
-// GetUserinfo 客户端获取用户信息
+// signOAuthTokens 签发OAuth2.0客户端使用的ID Token及Access Token，resources 为授予的资源（Access Token的受众），nonce 为空时ID Token不包含nonce声明
+func (s *sso) signOAuthTokens(site *model.Site, user *dao.UserInfoWithMenu, userId uint, scope, resources, nonce, sessionId string) (idToken, accessToken string, err error) {
+
+	subject := oidcSubject(site, userId)
+
+	accessToken, err = middleware.GenerateOAuthAccessToken(uint(user.ID), subject, site.ClientId, scope, sessionId, accessTokenAudience(site, resources), accessTokenTTL(site))
+	if err != nil {
+		return "", "", err
+	}
+
+	// ID Token中包含at_hash，客户端可据此校验Access Token与ID Token为同时签发
+	idToken, err = middleware.GenerateOAuthToken(uint(user.ID), user.Name, user.Username, subject, site.ClientId, "readwrite", nonce, sessionId, accessToken, "", accessTokenTTL(site))
+	if err != nil {
+		return "", "", err
+	}
+
+	return idToken, accessToken, nil
+}
+
+// GetUserinfo 客户端获取用户信息，失败时返回 *OAuthError
 func (s *sso) GetUserinfo(token string) (user *ResponseUserinfo, err error) {
 	// 验证Token
-	mc, err := middleware.ValidateJWT(token)
+	if token == "" {
+		return nil, NewOAuthError(http.StatusUnauthorized, OAuthInvalidRequest, "Missing access token")
+	}
+	// 仅接受Access Token，ID Token及平台登录Token均视为无效
+	mc, err := middleware.ValidateAccessToken(token)
 	if err != nil {
-		return nil, err
+		return nil, NewOAuthError(http.StatusUnauthorized, OAuthInvalidToken, "The access token is invalid or expired")
 	}
 
 	// 获取用户信息
 	userinfo, err := dao.User.GetUserInfo(mc.ID)
+	if err != nil {
+		logger.Error("获取用户信息失败：" + err.Error())
+		return nil, NewOAuthServerError()
+	}
 
 	user = &ResponseUserinfo{
 		Id:                uint(userinfo.ID),
 		Name:              userinfo.Name,
 		Username:          userinfo.Username,
 		PreferredUsername: userinfo.Username,
-		Email:             userinfo.Email,
-		PhoneNumber:       userinfo.PhoneNumber,
-		Sub:               fmt.Sprintf("user-%d", mc.ID),
+		Sub:               mc.Subject,
 	}
-
-	return user, err
-}
-
-// GetJwks OIDC客户端获取Jwks
-func (s *sso) GetJwks() ([]byte, error) {
-
-	// 读取公钥文件
-	pubKey, err := utils.LoadPublicKey()
-	if err != nil {
-		return nil, err
+	// 按Access Token授予的Scope返回用户的联系方式
+	if scopeGranted(mc.Scope, oauthEmailScope) {
+		user.Email = userinfo.Email
 	}
-
-	// 转换公钥为JWK
-	jwkKey, err := jwk.New(pubKey)
-	if err != nil {
-		return nil, err
+	if scopeGranted(mc.Scope, oauthPhoneScope) {
+		user.PhoneNumber = userinfo.PhoneNumber
 	}
-
-	// 将公钥转换为PKIX格式的字节
-	pubKeyBytes, err := x509.MarshalPKIXPublicKey(pubKey)
-	if err != nil {
-		return nil, err
+	// 兼容未携带sub声明的Token
+	if user.Sub == "" {
+		user.Sub = fmt.Sprintf("user-%d", mc.ID)
 	}
 
-	// 基于公钥内容生成kid
-	hash := sha256.Sum256(pubKeyBytes)
-	kid := base64.URLEncoding.EncodeToString(hash[:])
-
-	// 设置其它参数
-	_ = jwkKey.Set(jwk.KeyIDKey, kid)
-	_ = jwkKey.Set(jwk.AlgorithmKey, "RS256")
-	_ = jwkKey.Set("use", "sig")
-
-	// 创建JWK Set
-	jwkSet := jwk.NewSet()
-	jwkSet.Add(jwkKey)
-
-	// 将JWK Set序列化为JSON
-	jwksJSON, err := json.Marshal(jwkSet)
-	if err != nil {
-		return nil, err
-	}
+	return user, nil
+}
 
-	return jwksJSON, nil
+// GetJwks OIDC客户端获取Jwks
+func (s *sso) GetJwks() ([]byte, error) {
+	// 包括当前签名使用、待启用及停用后仍在保留期内的签名密钥
+	return middleware.JWKS()
 }
 
 // GetIdPMetadata 获取SAML2 IDP Metadata
 func (s *sso) GetIdPMetadata() (metadata string, err error) {
 
-	externalUrl := config.Conf.Settings["externalUrl"].(string)
+	externalUrl := config.SSO().ExternalUrl
 
 	// 获取证书
 	cert, err := utils.LoadIdpCertificate()
@@ -499,10 +914,14 @@ func (s *sso) GetIdPMetadata() (metadata string, err error) {
 		OrganizationURL:         externalUrl,
 	})
 
-	// 添加单点登录接口信息（实际不支持单点登出）
+	// 添加单点注销接口信息
+	idp.AddSingleSignOutService(saml.MetadataBinding{
+		Binding:  saml.HTTPRedirectBinding,
+		Location: externalUrl + samlLogoutPath,
+	})
 	idp.AddSingleSignOutService(saml.MetadataBinding{
 		Binding:  saml.HTTPPostBinding,
-		Location: externalUrl + "/api/auth/logout",
+		Location: externalUrl + samlLogoutPath,
 	})
 
 	// 生成metadata元数据
@@ -522,24 +941,137 @@ func (s *sso) ParseSPMetadata(metadataUrl string) (data *SPMetadata, err error)
 		return nil, err
 	}
 
-	// 提取IDP的签名证书
-	var signingCertData string
+	return spMetadata(metadata)
+}
+
+// ParseSPMetadataXML 解析上传或粘贴的SP Metadata XML，用于SP无法从平台访问或未提供Metadata地址的场景
+func (s *sso) ParseSPMetadataXML(data []byte) (*SPMetadata, error) {
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errors.New("SP Metadata不能为空")
+	}
+	if len(data) > SPMetadataMaxSize {
+		return nil, fmt.Errorf("SP Metadata不能超过%dKB", SPMetadataMaxSize>>10)
+	}
+
+	metadata, err := utils.ParseSPMetadataXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("SP Metadata格式错误：%s", err.Error())
+	}
+	if metadata.EntityID == "" {
+		return nil, errors.New("SP Metadata中未找到EntityID")
+	}
+
+	return spMetadata(metadata)
+}
+
+// spMetadata 从SP Metadata中提取EntityID、证书、ACS地址及单点注销地址
+func spMetadata(metadata *utils.EntityDescriptor) (*SPMetadata, error) {
+
+	// 提取SP的签名证书，第一个为默认证书
+	var signingCerts []string
 	for _, keyDescriptor := range metadata.SPSSODescriptor.KeyDescriptors {
-		if keyDescriptor.Use == "signing" {
-			signingCertData = keyDescriptor.KeyInfo.X509Data.X509Certificate
-			break
+		if keyDescriptor.Use == "signing" && strings.TrimSpace(keyDescriptor.KeyInfo.X509Data.X509Certificate) != "" {
+			signingCerts = append(signingCerts, strings.TrimSpace(keyDescriptor.KeyInfo.X509Data.X509Certificate))
 		}
 	}
-	if signingCertData == "" {
+	if len(signingCerts) == 0 {
 		return nil, errors.New("未找到签名证书")
 	}
 
+	// 提取SP的加密证书，未指定用途的证书同时用于签名及加密
+	var encryptionCert string
+	for _, keyDescriptor := range metadata.SPSSODescriptor.KeyDescriptors {
+		if (keyDescriptor.Use == "encryption" || keyDescriptor.Use == "") && strings.TrimSpace(keyDescriptor.KeyInfo.X509Data.X509Certificate) != "" {
+			encryptionCert = strings.TrimSpace(keyDescriptor.KeyInfo.X509Data.X509Certificate)
+			break
+		}
+	}
+
+	// 提取SP的ACS地址，仅支持HTTP-POST绑定，默认地址排在第一个
+	var acsUrls []string
+	for _, acs := range metadata.SPSSODescriptor.AssertionConsumerServices {
+		if acs.Location == "" || acs.Binding != saml.HTTPPostBinding {
+			continue
+		}
+		if acs.IsDefault == "true" {
+			acsUrls = append([]string{acs.Location}, acsUrls...)
+		} else {
+			acsUrls = append(acsUrls, acs.Location)
+		}
+	}
+
+	// 提取SP的单点注销地址，仅支持HTTP-POST绑定
+	var sloUrl string
+	for _, slo := range metadata.SPSSODescriptor.SingleLogoutServices {
+		if slo.Location != "" && slo.Binding == saml.HTTPPostBinding {
+			sloUrl = slo.Location
+			break
+		}
+	}
+
 	return &SPMetadata{
-		Certificate: signingCertData,
-		EntityID:    metadata.EntityID,
+		Certificate:  signingCerts[0],
+		Certificates: signingCerts,
+		EntityID:     metadata.EntityID,
+		AcsUrls:      acsUrls,
+		SloUrl:       sloUrl,
+
+		EncryptionCertificate: encryptionCert,
 	}, nil
 }
 
+// parseAcsUrls 解析站点已注册的ACS地址（JSON数组），地址必须为完整的http(s)地址
+func parseAcsUrls(data string) ([]string, error) {
+
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var acsUrls []string
+	if err := json.Unmarshal([]byte(data), &acsUrls); err != nil {
+		return nil, errors.New("ACS地址格式错误，应为JSON数组")
+	}
+	for _, item := range acsUrls {
+		u, err := url.Parse(item)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("ACS地址（%s）格式错误", item)
+		}
+	}
+
+	return acsUrls, nil
+}
+
+// resolveACS 确定SAMLResponse的发送地址，SAMLRequest中指定的ACS地址必须已在站点中注册，未指定时使用默认ACS地址，
+// 防止将断言发送到攻击者控制的地址；未注册ACS地址的历史站点仍信任SAMLRequest中的地址
+func resolveACS(site *model.Site, requested string) (string, error) {
+
+	acsUrls, err := parseAcsUrls(site.AcsUrls)
+	if err != nil {
+		return "", errors.New("应用ACS地址配置错误")
+	}
+
+	if len(acsUrls) == 0 {
+		if requested == "" {
+			return "", errors.New("应用未配置ACS地址")
+		}
+		logger.Warn(fmt.Sprintf("应用（%s）未注册ACS地址，使用SAMLRequest中的地址：%s", site.Name, requested))
+		return requested, nil
+	}
+
+	if requested == "" {
+		return acsUrls[0], nil
+	}
+	for _, item := range acsUrls {
+		if item == requested {
+			return item, nil
+		}
+	}
+
+	logger.Warn(fmt.Sprintf("应用（%s）的SAMLRequest中的ACS地址未注册：%s", site.Name, requested))
+	return "", errors.New("ACS地址未在应用中注册")
+}
+
 // GetSampleAuthnRequest 获取简单SAMLRequest数据
 func (s *sso) GetSampleAuthnRequest(samlRequest *SAMLRequest) url.Values {
 	payload := url.Values{}
@@ -550,11 +1082,22 @@ func (s *sso) GetSampleAuthnRequest(samlRequest *SAMLRequest) url.Values {
 	return payload
 }
 
+// validateAuthnRequest SAMLRequest请求校验，首先尝试使用GET方法校验，适用于HTTP-Redirect，失败后使用POST方法校验，适用于HTTP-POST
+func (s *sso) validateAuthnRequest(idp *saml.IdentityProvider, samlRequest *SAMLRequest) error {
+	if _, validationError := idp.ValidateAuthnRequest("GET", s.GetSampleAuthnRequest(samlRequest), url.Values{}); validationError == nil {
+		return nil
+	}
+	if _, validationError := idp.ValidateAuthnRequest("POST", url.Values{}, s.GetSampleAuthnRequest(samlRequest)); validationError != nil {
+		return validationError.Error
+	}
+	return nil
+}
+
 // GetSPAuthorize SP授权
-func (s *sso) GetSPAuthorize(samlRequest *SAMLRequest, userId uint) (html, siteName string, err error) {
+func (s *sso) GetSPAuthorize(samlRequest *SAMLRequest, userId uint, sessionId string) (html, siteName string, err error) {
 
 	var b bytes.Buffer
-	externalUrl := config.Conf.Settings["externalUrl"].(string)
+	externalUrl := config.SSO().ExternalUrl
 
 	// 获取SAMLRequest数据
 	requestData, err := utils.ParseSAMLRequest(samlRequest.SAMLRequest)
@@ -565,26 +1108,46 @@ func (s *sso) GetSPAuthorize(samlRequest *SAMLRequest, userId uint) (html, siteN
 	// 获取SP应用
 	site, err := dao.Site.GetSamlSite(requestData.Issuer.Value)
 	if err != nil {
+		recordSSOError(SSOProtocolSAML, nil, SSOErrorUnregistered, requestData.Issuer.Value)
 		return "", "", errors.New("应用未注册或配置错误")
 	}
 
+	// 判断应用使用的单点登录协议是否已停用
+	if err := checkProtocolSunset(SSOProtocolSAML, site); err != nil {
+		return "", site.Name, err
+	}
+
 	// 判断用户是否有权限访问
 	if !site.AllOpen {
 		if !dao.Site.IsUserInSite(userId, site) {
+			recordSSOError(SSOProtocolSAML, site, SSOErrorAccessDenied, fmt.Sprintf("用户ID：%d", userId))
 			return "", site.Name, errors.New("您无权访问该应用")
 		}
 	}
 
+	// 判断用户是否已接受使用条款
+	if err := Terms.Check(site, userId); err != nil {
+		return "", site.Name, err
+	}
+
 	// 获取IDP私钥
-	privateKeySrt := config.Conf.Settings["privateKey"].(string)
+	privateKeySrt := config.SSO().PrivateKey
 
 	// 获取IDP证书
-	certificate := config.Conf.Settings["certificate"].(string)
+	certificate := config.SSO().Certificate
+
+	// 获取SP证书，SP轮换证书期间可能存在多个有效证书
+	spCerts := spCertificates(site)
+	if len(spCerts) == 0 {
+		recordSSOError(SSOProtocolSAML, site, SSOErrorUnregistered, "应用未配置SP证书")
+		return "", site.Name, errors.New("应用未配置SP证书")
+	}
 
-	// 获取SP证书（给证书加上头尾）
-	SPCert := site.Certificate
-	if !strings.HasPrefix(SPCert, "-----BEGIN CERTIFICATE-----") && !strings.HasSuffix(SPCert, "-----END CERTIFICATE-----") {
-		SPCert = fmt.Sprintf("-----BEGIN CERTIFICATE-----\n%s\n-----END CERTIFICATE-----\n", site.Certificate)
+	// 校验SP回调地址
+	acsLocation, err := resolveACS(site, requestData.AssertionConsumerServiceURL)
+	if err != nil {
+		recordSSOError(SSOProtocolSAML, site, SSOErrorInvalidRequest, fmt.Sprintf("%s：%s", err.Error(), requestData.AssertionConsumerServiceURL))
+		return "", site.Name, err
 	}
 
 	// 获取用户信息
@@ -595,17 +1158,17 @@ func (s *sso) GetSPAuthorize(samlRequest *SAMLRequest, userId uint) (html, siteN
 
 	// 初始化IDP实（注：也可以在结构体中使用IDPCertFilePath和IDPKeyFilePath从指定路径中读取IDP的证书和私钥，但经测试有Bug）
 	idp := saml.IdentityProvider{
-		IsIdpInitiated:       false,                                   // 是否为IDP发起认证
-		Issuer:               externalUrl,                             // IDP实体
-		Audiences:            []string{requestData.Issuer.Value},      // SP实体
-		IDPKey:               privateKeySrt,                           // IDP私钥
-		IDPCert:              certificate,                             // IDP证书
-		SPCert:               SPCert,                                  // SP证书
-		NameIdentifier:       userinfo.Username,                       // 用户的唯一标识符
-		NameIdentifierFormat: saml.NameIdFormatUnspecified,            // 用户唯一标识符格式
-		ACSLocation:          requestData.AssertionConsumerServiceURL, // SP回调地址
-		ACSBinging:           saml.HTTPPostBinding,                    // 将SAMLResponse发送到SP的方法
-		SessionIndex:         uuid.New().String(),                     // 会话唯一标识符,常用用于会议跟踪
+		IsIdpInitiated:       false,                              // 是否为IDP发起认证
+		Issuer:               externalUrl,                        // IDP实体
+		Audiences:            []string{requestData.Issuer.Value}, // SP实体
+		IDPKey:               privateKeySrt,                      // IDP私钥
+		IDPCert:              certificate,                        // IDP证书
+		SPCert:               spCerts[0],                         // SP证书
+		NameIdentifier:       userinfo.Username,                  // 用户的唯一标识符
+		NameIdentifierFormat: saml.NameIdFormatUnspecified,       // 用户唯一标识符格式
+		ACSLocation:          acsLocation,                        // SP回调地址
+		ACSBinging:           saml.HTTPPostBinding,               // 将SAMLResponse发送到SP的方法
+		SessionIndex:         uuid.New().String(),                // 会话唯一标识符,常用用于会议跟踪
 	}
 
 	// 阿里云相关配置（需要给NameID加上域名）
@@ -613,53 +1176,72 @@ func (s *sso) GetSPAuthorize(samlRequest *SAMLRequest, userId uint) (html, siteN
 		idp.NameIdentifier = fmt.Sprintf("%s@%s", userinfo.Username, site.DomainId)
 	}
 
-	// 添加其它用户属性
-	idp.AddAttribute("name", userinfo.Name, saml.AttributeFormatUnspecified)                // 用户姓名
-	idp.AddAttribute("username", userinfo.Username, saml.AttributeFormatUnspecified)        // 用户名
-	idp.AddAttribute("email", userinfo.Email, saml.AttributeFormatUnspecified)              // 邮箱地址
-	idp.AddAttribute("phone_number", userinfo.PhoneNumber, saml.AttributeFormatUnspecified) // 电话号码\
-
 	// AWS专属配置
 	if strings.Contains(site.Address, "awsapps") {
 		idp.NameIdentifierFormat = saml.NameIdFormatEmailAddress
 		idp.NameIdentifier = userinfo.Email
-		idp.AddAttribute("username", userinfo.Email, saml.AttributeFormatUnspecified)
 	}
 
-	// 华为云专属配置
-	idp.AddAttribute("IAM_SAML_Attributes_xUserId", userinfo.Username, saml.AttributeFormatUnspecified)
-	idp.AddAttribute("IAM_SAML_Attributes_redirect_url", site.RedirectUrl, saml.AttributeFormatUnspecified) // 登录后跳转的地址
-	idp.AddAttribute("IAM_SAML_Attributes_domain_id", site.DomainId, saml.AttributeFormatUnspecified)
-	idp.AddAttribute("IAM_SAML_Attributes_idp_id", site.IDPName, saml.AttributeFormatUnspecified)
-
-	// 天翼云专属配置
-	idp.AddAttribute("nickName", userinfo.Name, saml.AttributeFormatUnspecified)  // 用户姓名
-	idp.AddAttribute("accountId", site.DomainId, saml.AttributeFormatUnspecified) //  天翼云账号ID
-	idp.AddAttribute("userId", userinfo.CtyunId, saml.AttributeFormatUnspecified) // 天翼云IAM用户ID
-	idp.AddAttribute("idpId", site.DomainId, saml.AttributeFormatUnspecified)     // 天翼云IDP ID
+	// 按站点的属性发布策略添加用户属性
+	if err := addSAMLAttributes(&idp, site, &userinfo.UserInfo, userId); err != nil {
+		return "", site.Name, err
+	}
 
 	// 设置认证请求有效期
 	idp.AuthnRequestTTL(time.Minute * 10)
 
-	// SAMLRequest请求校验，首先尝试使用GET方法校验，试用于HTTP-Redirect
-	_, validationError := idp.ValidateAuthnRequest("GET", s.GetSampleAuthnRequest(samlRequest), url.Values{})
-	if validationError != nil {
-		// POST方法校验，试用于HTTP-POST
-		_, validationError := idp.ValidateAuthnRequest("POST", url.Values{}, s.GetSampleAuthnRequest(samlRequest))
-		if validationError != nil {
-			// 如果全部出错，则返回错误信息
-			return "", site.Name, validationError.Error
+	// SAMLRequest请求校验，依次使用每个SP证书校验，任一证书校验通过即可
+	var validationErr error
+	for _, spCert := range spCerts {
+		idp.SPCert = spCert
+		if validationErr = s.validateAuthnRequest(&idp, samlRequest); validationErr == nil {
+			break
 		}
 	}
+	if validationErr != nil {
+		recordSSOError(SSOProtocolSAML, site, SSOErrorSignatureMismatch, validationErr.Error())
+		return "", site.Name, validationErr
+	}
 
-	// 生成签名后XML数据
+	// 生成签名后XML数据，签名算法及摘要算法按站点配置
+	idp.SignatureAlgorithm, idp.DigestAlgorithm = samlSigningAlgorithms(site)
 	signedXML, signedXMLErr := idp.NewSignedLoginResponse()
 	if signedXMLErr != nil {
 		return "", site.Name, signedXMLErr.Error
 	}
+	if !samlSignAssertion(site) {
+		signedXML = removeSAMLAssertionSignature(signedXML)
+	}
+
+	// 加密断言
+	if site.EncryptAssertion {
+		if signedXML, err = encryptSAMLAssertion(signedXML, site); err != nil {
+			recordSSOError(SSOProtocolSAML, site, SSOErrorUnregistered, "断言加密失败："+err.Error())
+			return "", site.Name, err
+		}
+	}
+
+	// 签名响应
+	if samlSignResponse(site) {
+		if signedXML, err = signSAMLResponse(signedXML, site); err != nil {
+			return "", site.Name, err
+		}
+	}
+
+	// 记录断言对应的会话，用于单点注销
+	if err := dao.SSO.CreateSAMLSession(&model.SsoSAMLSession{
+		SessionIndex: idp.SessionIndex,
+		SessionID:    sessionId,
+		SiteID:       site.ID,
+		UserID:       userId,
+		NameID:       idp.NameIdentifier,
+	}); err != nil {
+		return "", site.Name, err
+	}
 
 	// 生成HTML响应
 	var htmlData = SAMLResponse{
+		PostFormText: newPostFormText(languageLocale(userinfo.Language)),
 		URL:          idp.ACSLocation,
 		SAMLResponse: base64.StdEncoding.EncodeToString([]byte(signedXML)),
 		RelayState:   idp.RelayState,
@@ -668,11 +1250,14 @@ func (s *sso) GetSPAuthorize(samlRequest *SAMLRequest, userId uint) (html, siteN
 		return "", site.Name, err
 	}
 
+	// 通知应用用户已登录
+	SiteLaunchHook.Publish(site, SSOProtocolSAML, userId)
+
 	return b.String(), site.Name, nil
 }
 
 // Login 单点登录
-func (s *sso) Login(queryParams AuthorizeParam, user model.AuthUser) (callbackData, appName string, err error) {
+func (s *sso) Login(queryParams AuthorizeParam, user model.AuthUser, sessionId string) (callbackData, appName string, err error) {
 
 	var (
 		data        string
@@ -688,8 +1273,14 @@ func (s *sso) Login(queryParams AuthorizeParam, user model.AuthUser) (callbackDa
 			Scope:        queryParams.GetScope(),
 			State:        queryParams.GetState(),
 			Nonce:        queryParams.GetNonce(),
+			AcrValues:    queryParams.GetAcrValues(),
+			Resource:     queryParams.GetResource(),
+			Audience:     queryParams.GetAudience(),
+
+			CodeChallenge:       queryParams.GetCodeChallenge(),
+			CodeChallengeMethod: queryParams.GetCodeChallengeMethod(),
 		}
-		callbackUrl, siteName, err := s.GetOAuthAuthorize(params, user.ID)
+		callbackUrl, siteName, err := s.GetOAuthAuthorize(params, user.ID, sessionId)
 		if err != nil {
 			return "", siteName, err
 		}
@@ -700,7 +1291,7 @@ func (s *sso) Login(queryParams AuthorizeParam, user model.AuthUser) (callbackDa
 		params := &CASAuthorize{
 			Service: queryParams.GetService(),
 		}
-		callbackUrl, siteName, err := s.GetCASAuthorize(params, user.ID, user.Username)
+		callbackUrl, siteName, err := s.GetCASAuthorize(params, user.ID, user.Username, sessionId)
 		if err != nil {
 			return "", siteName, err
 		}
@@ -714,7 +1305,20 @@ func (s *sso) Login(queryParams AuthorizeParam, user model.AuthUser) (callbackDa
 			SigAlg:      queryParams.GetSigAlg(),
 			Signature:   queryParams.GetSignature(),
 		}
-		html, siteName, err := s.GetSPAuthorize(params, user.ID)
+		html, siteName, err := s.GetSPAuthorize(params, user.ID, sessionId)
+		if err != nil {
+			return "", siteName, err
+		}
+		data = html
+		application = siteName
+	} else if queryParams.GetWtrealm() != "" {
+		// WS-Fed认证返回
+		params := &WsFedSignIn{
+			Wtrealm: queryParams.GetWtrealm(),
+			Wreply:  queryParams.GetWreply(),
+			Wctx:    queryParams.GetWctx(),
+		}
+		html, siteName, err := s.GetWsFedAuthorize(params, user.ID)
 		if err != nil {
 			return "", siteName, err
 		}
@@ -725,7 +1329,7 @@ func (s *sso) Login(queryParams AuthorizeParam, user model.AuthUser) (callbackDa
 		params := &NginxAuthorize{
 			CallbackURL: queryParams.GetNginxRedirectURI(),
 		}
-		callbackUrl, siteName, err := s.GetNginxAuthorize(params, user.ID)
+		callbackUrl, siteName, err := s.GetNginxAuthorize(params, user.ID, sessionId)
 		if err != nil {
 			return "", siteName, err
 		}
@@ -0,0 +1,346 @@
+package service
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"github.com/casbin/casbin/v2"
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"math/big"
+	"net/url"
+	"ops-api/config"
+	"ops-api/global"
+	"ops-api/middleware"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/cache"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// 单点登录核心路径的基准测试，使用SQLite及内存缓存代替MySQL及Redis，用于对比代码变更前后的耗时及内存分配：
+// go test ./service -run '^$' -bench . -benchmem
+
+const (
+	benchUsername    = "bench"
+	benchPassword    = "Bench@123456"
+	benchCASService  = "https://cas.example.com/login"
+	benchOAuthClient = "bench-client"
+	benchOAuthSecret = "bench-client-secret"
+	benchOAuthURI    = "https://oauth.example.com/callback"
+	benchSAMLEntity  = "https://saml.example.com/metadata"
+	benchSAMLACS     = "https://saml.example.com/acs"
+)
+
+// benchKeys IDP密钥及证书，生成RSA密钥较慢，所有基准测试共用
+var benchKeys struct {
+	once        sync.Once
+	privateKey  string
+	publicKey   string
+	certificate string
+	err         error
+}
+
+// loadBenchKeys 生成IDP密钥对及自签名证书
+func loadBenchKeys(b *testing.B) {
+	b.Helper()
+
+	benchKeys.once.Do(func() {
+		benchKeys.privateKey, benchKeys.publicKey, benchKeys.err = utils.GenerateRSAKeyPair(2048)
+		if benchKeys.err != nil {
+			return
+		}
+		privateKey, err := utils.ParseRSAPrivateKey(benchKeys.privateKey)
+		if err != nil {
+			benchKeys.err = err
+			return
+		}
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "IDSphere Benchmark"},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(24 * time.Hour),
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+		if err != nil {
+			benchKeys.err = err
+			return
+		}
+		benchKeys.certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	})
+	if benchKeys.err != nil {
+		b.Fatal(benchKeys.err)
+	}
+}
+
+// setupBenchmark 初始化基准测试环境：临时SQLite数据库、内存缓存及系统配置，并创建测试用户及CAS、OAuth2.0、SAML2应用
+func setupBenchmark(b *testing.B) *model.AuthUser {
+	b.Helper()
+	loadBenchKeys(b)
+
+	// 系统配置
+	oldConf := config.Conf
+	config.Conf = &config.Config{Settings: map[string]interface{}{
+		"externalUrl":      "https://idp.example.com",
+		"issuer":           "https://idp.example.com",
+		"secret":           "benchmark-secret",
+		"privateKey":       benchKeys.privateKey,
+		"publicKey":        benchKeys.publicKey,
+		"certificate":      benchKeys.certificate,
+		"tokenExpiresTime": 2,
+	}}
+
+	// 缓存
+	oldCache := global.Cache
+	global.Cache = cache.NewMemory()
+
+	// 数据库
+	dsn := "file:" + filepath.Join(b.TempDir(), "bench.db") + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := db.AutoMigrate(
+		&model.AuthUser{},
+		&model.AuthGroup{},
+		&model.Site{},
+		&model.SiteCertificate{},
+		&model.SiteGrant{},
+		&model.Menu{},
+		&model.SubMenu{},
+		&model.SystemPath{},
+		&model.SsoOAuthTicket{},
+		&model.SsoOAuthRefreshToken{},
+		&model.SsoCASTicket{},
+		&model.SsoSAMLSession{},
+		&model.LoginPolicy{},
+		&model.LoginTimePolicy{},
+		&model.LoginHook{},
+		&model.UserSession{},
+		&model.UserDevice{},
+		&model.UserConsent{},
+		&model.Terms{},
+		&model.TermsVersion{},
+		&model.TermsAcceptance{},
+		&model.SigningKey{},
+		&model.FeatureFlag{},
+		&model.LogSSOError{},
+	); err != nil {
+		b.Fatal(err)
+	}
+	oldDB := global.MySQLClient
+	global.MySQLClient = db
+
+	// 权限（不加载规则）
+	enforcer, err := casbin.NewEnforcer("../config/rbac_model.conf")
+	if err != nil {
+		b.Fatal(err)
+	}
+	oldEnforcer := global.CasBinServer
+	global.CasBinServer = enforcer
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		global.MySQLClient = oldDB
+		global.CasBinServer = oldEnforcer
+		global.Cache = oldCache
+		config.Conf = oldConf
+		_ = sqlDB.Close()
+		_ = utils.LoadKeyStore()
+		_ = middleware.ReloadSigningKeys()
+	})
+	if err := utils.LoadKeyStore(); err != nil {
+		b.Fatal(err)
+	}
+	if err := middleware.ReloadSigningKeys(); err != nil {
+		b.Fatal(err)
+	}
+
+	// 测试用户
+	user := &model.AuthUser{
+		Name:     "Benchmark",
+		Username: benchUsername,
+		Password: benchPassword,
+		IsActive: true,
+		Email:    "bench@example.com",
+	}
+	if err := db.Create(user).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	// 测试应用，ClientId及ClientSecret在创建时随机生成，创建后更新为固定值
+	sites := []*model.Site{
+		{Name: "CAS", SSO: true, SSOType: 1, AllOpen: true, CallbackUrl: benchCASService},
+		{Name: "OAuth", SSO: true, SSOType: 2, AllOpen: true, CallbackUrl: benchOAuthURI},
+		{Name: "SAML", SSO: true, SSOType: 3, AllOpen: true, EntityId: benchSAMLEntity, AcsUrls: `["` + benchSAMLACS + `"]`, Certificate: benchKeys.certificate},
+	}
+	for _, site := range sites {
+		if err := db.Create(site).Error; err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := db.Model(sites[1]).Updates(map[string]interface{}{"client_id": benchOAuthClient, "client_secret": benchOAuthSecret}).Error; err != nil {
+		b.Fatal(err)
+	}
+
+	return user
+}
+
+// benchSession 为测试用户创建登录会话，并记录会话（签发刷新令牌时使用）
+func benchSession(b *testing.B, user *model.AuthUser) string {
+	b.Helper()
+
+	_, sessionId, err := middleware.GenerateJWT(user.ID, user.Name, user.Username, []string{middleware.AMRPassword})
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := global.MySQLClient.Create(&model.UserSession{
+		SessionID: sessionId,
+		UserID:    user.ID,
+		Username:  user.Username,
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}).Error; err != nil {
+		b.Fatal(err)
+	}
+	return sessionId
+}
+
+// benchSAMLRequest 生成HTTP-Redirect绑定的SAMLRequest（未签名）
+func benchSAMLRequest(b *testing.B) *SAMLRequest {
+	b.Helper()
+
+	authnRequest := fmt.Sprintf(`<samlp:AuthnRequest xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="_%s" Version="2.0" IssueInstant="%s" Destination="https://idp.example.com/api/v1/sso/saml/authorize" AssertionConsumerServiceURL="%s" ProtocolBinding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"><saml:Issuer>%s</saml:Issuer><samlp:NameIDPolicy Format="urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified" AllowCreate="true"/></samlp:AuthnRequest>`,
+		uuid.NewString(), time.Now().UTC().Format(time.RFC3339), benchSAMLACS, benchSAMLEntity)
+
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := writer.Write([]byte(authnRequest)); err != nil {
+		b.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		b.Fatal(err)
+	}
+
+	return &SAMLRequest{SAMLRequest: base64.StdEncoding.EncodeToString(buf.Bytes()), RelayState: "bench"}
+}
+
+// BenchmarkLogin 账号密码登录：用户认证、登录策略检查及签发用户Token
+func BenchmarkLogin(b *testing.B) {
+	setupBenchmark(b)
+	params := &UserLogin{Username: benchUsername, Password: benchPassword}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		token, _, _, _, err := User.Login(params, "127.0.0.1")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if token == "" {
+			b.Fatal("未签发用户Token")
+		}
+	}
+}
+
+// BenchmarkGetToken OAuth2.0授权码换取Token，每次迭代前签发新的授权码（不计入耗时）
+func BenchmarkGetToken(b *testing.B) {
+	user := setupBenchmark(b)
+	sessionId := benchSession(b, user)
+	authorize := &OAuthAuthorize{ResponseType: "code", ClientId: benchOAuthClient, RedirectURI: benchOAuthURI, Scope: "openid", State: "bench"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		callbackUrl, _, err := SSO.GetOAuthAuthorize(authorize, user.ID, sessionId)
+		if err != nil {
+			b.Fatal(err)
+		}
+		callback, err := url.Parse(callbackUrl)
+		if err != nil {
+			b.Fatal(err)
+		}
+		param := &Token{
+			OAuthClientAuth: OAuthClientAuth{ClientId: benchOAuthClient, ClientSecret: benchOAuthSecret},
+			GrantType:       "authorization_code",
+			Code:            callback.Query().Get("code"),
+			RedirectURI:     benchOAuthURI,
+		}
+		b.StartTimer()
+
+		token, err := SSO.GetToken(param)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if token.IdToken == "" || token.AccessToken == "" {
+			b.Fatal("未签发Token")
+		}
+	}
+}
+
+// BenchmarkServiceValidate CAS3.0票据校验，每次迭代前签发新的票据（不计入耗时）
+func BenchmarkServiceValidate(b *testing.B) {
+	user := setupBenchmark(b)
+	sessionId := benchSession(b, user)
+	authorize := &CASAuthorize{Service: benchCASService}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		callbackUrl, _, err := SSO.GetCASAuthorize(authorize, user.ID, user.Username, sessionId)
+		if err != nil {
+			b.Fatal(err)
+		}
+		callback, err := url.Parse(callbackUrl)
+		if err != nil {
+			b.Fatal(err)
+		}
+		param := &CASServiceValidate{Service: benchCASService, Ticket: callback.Query().Get("ticket")}
+		b.StartTimer()
+
+		data, err := SSO.ServiceValidate(param)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if data.AuthenticationSuccess == nil {
+			b.Fatal("票据校验失败")
+		}
+	}
+}
+
+// BenchmarkGetSPAuthorize SAML2 SP授权：校验SAMLRequest、生成并签名SAMLResponse
+func BenchmarkGetSPAuthorize(b *testing.B) {
+	user := setupBenchmark(b)
+	sessionId := benchSession(b, user)
+	request := benchSAMLRequest(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		html, _, err := SSO.GetSPAuthorize(request, user.ID, sessionId)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if html == "" {
+			b.Fatal("未生成SAMLResponse")
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/wonderivan/logger"
+	"ops-api/dao"
+	"ops-api/model"
+	"time"
+)
+
+// 单点登录协议
+const (
+	SSOProtocolOAuth = "oauth2"
+	SSOProtocolCAS   = "cas3"
+	SSOProtocolSAML  = "saml2"
+	SSOProtocolNginx = "nginx"
+	SSOProtocolWsFed = "wsfed"
+)
+
+// 单点登录协议错误类型
+const (
+	SSOErrorUnregistered      = "unregistered_service" // 应用未注册或配置错误
+	SSOErrorInvalidTicket     = "invalid_ticket"       // 票据无效、已过期或已使用
+	SSOErrorExpiredCode       = "expired_code"         // 授权码无效、已过期或已使用
+	SSOErrorSignatureMismatch = "signature_mismatch"   // 请求或票据签名校验失败
+	SSOErrorInvalidClient     = "invalid_client"       // 客户端认证失败
+	SSOErrorInvalidRequest    = "invalid_request"      // 请求参数错误
+	SSOErrorAccessDenied      = "access_denied"        // 用户无权访问应用
+	SSOErrorProtocolRetired   = "protocol_retired"     // 应用使用的单点登录协议已停用
+)
+
+const (
+	ssoErrorReportMaxRecords = 50  // 错误报告中返回的最近错误记录数
+	ssoErrorDetailMaxLength  = 255 // 错误详情最大长度
+)
+
+// ssoProtocolErrors 单点登录协议错误计数，应用未注册时 site 为 unknown，避免使用请求中的应用标识作为标签
+var ssoProtocolErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "sso_protocol_errors_total",
+	Help: "单点登录协议错误次数",
+}, []string{"protocol", "site", "reason"})
+
+// SSOErrorReport 站点单点登录错误报告
+type SSOErrorReport struct {
+	SiteID  uint                   `json:"site_id"`
+	Days    int                    `json:"days"`
+	Summary []*dao.SSOErrorSummary `json:"summary"`
+	Records []*model.LogSSOError   `json:"records"`
+}
+
+// recordSSOError 记录单点登录协议错误，site 为空表示应用未注册，detail 为错误详情
+func recordSSOError(protocol string, site *model.Site, reason, detail string) {
+
+	var (
+		siteId   uint
+		siteName = "unknown"
+	)
+	if site != nil {
+		siteId, siteName = site.ID, site.Name
+	}
+
+	ssoProtocolErrors.WithLabelValues(protocol, siteName, reason).Inc()
+
+	// 错误详情可能包含请求中的任意内容，限制长度
+	if runes := []rune(detail); len(runes) > ssoErrorDetailMaxLength {
+		detail = string(runes[:ssoErrorDetailMaxLength])
+	}
+
+	go func() {
+		if err := dao.Audit.AddSSOErrorRecord(&model.LogSSOError{
+			SiteID:   siteId,
+			Protocol: protocol,
+			Reason:   reason,
+			Detail:   detail,
+		}); err != nil {
+			logger.Error("ERROR：单点登录错误记录失败，", err.Error())
+		}
+	}()
+}
+
+// GetSiteErrorReport 获取站点最近几天的单点登录错误报告，siteId 为0时获取未注册应用的错误
+func (s *site) GetSiteErrorReport(siteId uint, days int) (*SSOErrorReport, error) {
+
+	if days <= 0 {
+		days = 7
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	summary, err := dao.Audit.GetSSOErrorSummary(siteId, since)
+	if err != nil {
+		return nil, err
+	}
+	records, err := dao.Audit.GetSSOErrorRecords(siteId, since, ssoErrorReportMaxRecords)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSOErrorReport{
+		SiteID:  siteId,
+		Days:    days,
+		Summary: summary,
+		Records: records,
+	}, nil
+}
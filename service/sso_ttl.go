@@ -0,0 +1,57 @@
+package service
+
+import (
+	"fmt"
+	"ops-api/config"
+	"ops-api/model"
+	"time"
+)
+
+// 站点可单独配置授权码、Access Token（及ID Token）、CAS票据的有效期，未配置时使用默认有效期；刷新令牌有效期见 refreshTokenTTL
+
+const (
+	oauthCodeTTL        = 10 * time.Second // 授权码的默认有效期
+	oauthCodeMaxTTL     = 600              // 站点可配置的授权码最长有效期（秒）
+	oauthAccessTokenMin = 60               // 站点可配置的Access Token最短有效期（秒）
+	oauthAccessTokenMax = 24 * 3600        // 站点可配置的Access Token最长有效期（秒）
+	casTicketDefaultTTL = 10 * time.Second // CAS票据的默认有效期
+	casTicketMaxTTL     = 300              // 站点可配置的CAS票据最长有效期（秒）
+)
+
+// validateTokenTTL 校验站点的授权码、Access Token及CAS票据有效期（秒），为0时使用默认有效期
+func validateTokenTTL(codeTTL, accessTokenTTL, ticketTTL uint) error {
+	if codeTTL > oauthCodeMaxTTL {
+		return fmt.Errorf("授权码有效期不能超过%d秒", oauthCodeMaxTTL)
+	}
+	if accessTokenTTL != 0 && (accessTokenTTL < oauthAccessTokenMin || accessTokenTTL > oauthAccessTokenMax) {
+		return fmt.Errorf("Access Token有效期必须在%d-%d秒之间", oauthAccessTokenMin, oauthAccessTokenMax)
+	}
+	if ticketTTL > casTicketMaxTTL {
+		return fmt.Errorf("CAS票据有效期不能超过%d秒", casTicketMaxTTL)
+	}
+	return nil
+}
+
+// authorizationCodeTTL 获取站点的授权码有效期
+func authorizationCodeTTL(site *model.Site) time.Duration {
+	if site.CodeTTL == 0 {
+		return oauthCodeTTL
+	}
+	return time.Duration(site.CodeTTL) * time.Second
+}
+
+// accessTokenTTL 获取站点的Access Token及ID Token有效期，未配置时与平台登录Token的有效期（tokenExpiresTime）一致
+func accessTokenTTL(site *model.Site) time.Duration {
+	if site.AccessTTL == 0 {
+		return time.Duration(config.SSO().TokenExpiresTime) * time.Hour
+	}
+	return time.Duration(site.AccessTTL) * time.Second
+}
+
+// casTicketTTL 获取站点的CAS票据有效期
+func casTicketTTL(site *model.Site) time.Duration {
+	if site.TicketTTL == 0 {
+		return casTicketDefaultTTL
+	}
+	return time.Duration(site.TicketTTL) * time.Second
+}
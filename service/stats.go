@@ -0,0 +1,352 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"time"
+)
+
+var Stats stats
+
+type stats struct{}
+
+// StatsQuery 统计查询参数
+type StatsQuery struct {
+	Start  string `form:"start"`  // 开始日期，格式：YYYY-MM-DD，默认为30天前
+	End    string `form:"end"`    // 结束日期，格式：YYYY-MM-DD，默认为当天
+	Period string `form:"period"` // 统计周期：day、week，默认为day
+	Limit  int    `form:"limit"`  // 排行数量，默认为10
+}
+
+// StatsRollup 统计数据回填参数，指定开始日期时回填开始日期至结束日期的数据，否则回填最近几天的数据
+type StatsRollup struct {
+	Days  int    `json:"days" binding:"omitempty,min=1,max=366"` // 回填最近多少天的数据
+	Start string `json:"start"`                                  // 开始日期，格式：YYYY-MM-DD
+	End   string `json:"end"`                                    // 结束日期，格式：YYYY-MM-DD，默认为当天
+}
+
+// LoginTrendItem 登录趋势
+type LoginTrendItem struct {
+	Period     string `json:"period"`
+	AuthMethod string `json:"auth_method"`
+	Success    int64  `json:"success"`
+	Failed     int64  `json:"failed"`
+}
+
+// FailureStats 登录失败统计
+type FailureStats struct {
+	Total       int64                 `json:"total"`
+	Failed      int64                 `json:"failed"`
+	FailureRate float64               `json:"failure_rate"`
+	Reasons     []*dao.LoginStatCount `json:"reasons"`
+}
+
+// UserLoginStats 用户登录统计
+type UserLoginStats struct {
+	Active []*dao.UserLoginStatCount `json:"active"` // 登录成功次数排行
+	Failed []*dao.UserLoginStatCount `json:"failed"` // 登录失败次数排行
+}
+
+// OplogTrendItem 操作次数趋势
+type OplogTrendItem struct {
+	Period string `json:"period"`
+	Method string `json:"method"`
+	Count  int64  `json:"count"`
+}
+
+// OplogStats 操作日志统计
+type OplogStats struct {
+	Trend     []*OplogTrendItem     `json:"trend"`     // 按请求方法统计的操作次数趋势
+	Operators []*dao.OplogStatCount `json:"operators"` // 操作人操作次数排行
+}
+
+// MFAAdoptionItem MFA使用率
+type MFAAdoptionItem struct {
+	Date        string  `json:"date"`
+	ActiveUsers int64   `json:"active_users"`
+	MFAUsers    int64   `json:"mfa_users"`
+	Rate        float64 `json:"rate"`
+}
+
+// Rollup 汇总指定日期的登录及操作统计数据
+func (s *stats) Rollup(date time.Time) error {
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.Stats.RollupLogin(tx, date); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := dao.Stats.RollupUserLogin(tx, date); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := dao.Stats.RollupOplog(tx, date); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 用户数量仅能获取当前的快照
+	if date.Format("2006-01-02") == time.Now().In(date.Location()).Format("2006-01-02") {
+		if err := dao.Stats.SnapshotUsers(tx, date); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// RollupRecent 汇总最近几天的登录统计数据（包含当天），由定时任务调用，按系统时区划分日期
+func (s *stats) RollupRecent(days int) error {
+	now := time.Now().In(config.Location())
+	for i := days - 1; i >= 0; i-- {
+		if err := s.Rollup(now.AddDate(0, 0, -i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Backfill 回填统计数据，指定开始日期时回填开始日期至结束日期的数据，否则回填最近几天的数据
+func (s *stats) Backfill(data *StatsRollup) error {
+
+	if data.Start == "" {
+		if data.Days <= 0 {
+			return errors.New("请指定回填天数或开始日期")
+		}
+		return s.RollupRecent(data.Days)
+	}
+
+	start, end, err := s.parseRange(&StatsQuery{Start: data.Start, End: data.End})
+	if err != nil {
+		return err
+	}
+	loc := config.Location()
+	startDate, _ := time.ParseInLocation("2006-01-02", start, loc)
+	endDate, _ := time.ParseInLocation("2006-01-02", end, loc)
+	if endDate.After(time.Now()) {
+		endDate = time.Now().In(loc)
+	}
+	for date := startDate; !date.After(endDate); date = date.AddDate(0, 0, 1) {
+		if err := s.Rollup(date); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetLoginTrend 获取按认证方式统计的登录趋势
+func (s *stats) GetLoginTrend(query *StatsQuery) ([]*LoginTrendItem, error) {
+	start, end, err := s.parseRange(query)
+	if err != nil {
+		return nil, err
+	}
+	if query.Period != "" && query.Period != "day" && query.Period != "week" {
+		return nil, errors.New("统计周期仅支持day、week")
+	}
+
+	counts, err := dao.Stats.GetLoginCountByMethod(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		items []*LoginTrendItem
+		index = make(map[string]*LoginTrendItem)
+	)
+	for _, count := range counts {
+		period := s.period(count.Date, query.Period)
+		key := period + "|" + count.AuthMethod
+		item, ok := index[key]
+		if !ok {
+			item = &LoginTrendItem{Period: period, AuthMethod: count.AuthMethod}
+			index[key] = item
+			items = append(items, item)
+		}
+		if count.Status == 1 {
+			item.Success += count.Count
+		} else {
+			item.Failed += count.Count
+		}
+	}
+
+	return items, nil
+}
+
+// GetAppLaunches 获取各应用登录次数排行
+func (s *stats) GetAppLaunches(query *StatsQuery) ([]*dao.LoginStatCount, error) {
+	start, end, err := s.parseRange(query)
+	if err != nil {
+		return nil, err
+	}
+	return dao.Stats.GetAppLaunchCount(start, end, s.limit(query))
+}
+
+// GetFailureStats 获取登录失败率及失败原因排行
+func (s *stats) GetFailureStats(query *StatsQuery) (*FailureStats, error) {
+	start, end, err := s.parseRange(query)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := dao.Stats.GetLoginCountByStatus(start, end)
+	if err != nil {
+		return nil, err
+	}
+	data := &FailureStats{}
+	for _, count := range counts {
+		data.Total += count.Count
+		if count.Status == 2 {
+			data.Failed += count.Count
+		}
+	}
+	if data.Total > 0 {
+		data.FailureRate = float64(data.Failed) / float64(data.Total)
+	}
+
+	if data.Reasons, err = dao.Stats.GetFailureReasonCount(start, end, s.limit(query)); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// GetUserLoginStats 获取用户登录成功及失败次数排行
+func (s *stats) GetUserLoginStats(query *StatsQuery) (*UserLoginStats, error) {
+	start, end, err := s.parseRange(query)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &UserLoginStats{}
+	if data.Active, err = dao.Stats.GetUserLoginCount(start, end, "success", s.limit(query)); err != nil {
+		return nil, err
+	}
+	if data.Failed, err = dao.Stats.GetUserLoginCount(start, end, "failed", s.limit(query)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetOplogStats 获取操作次数趋势及操作人排行
+func (s *stats) GetOplogStats(query *StatsQuery) (*OplogStats, error) {
+	start, end, err := s.parseRange(query)
+	if err != nil {
+		return nil, err
+	}
+	if query.Period != "" && query.Period != "day" && query.Period != "week" {
+		return nil, errors.New("统计周期仅支持day、week")
+	}
+
+	counts, err := dao.Stats.GetOplogCountByDate(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &OplogStats{Trend: []*OplogTrendItem{}}
+	index := make(map[string]*OplogTrendItem)
+	for _, count := range counts {
+		period := s.period(count.Date, query.Period)
+		key := period + "|" + count.Method
+		item, ok := index[key]
+		if !ok {
+			item = &OplogTrendItem{Period: period, Method: count.Method}
+			index[key] = item
+			data.Trend = append(data.Trend, item)
+		}
+		item.Count += count.Count
+	}
+
+	if data.Operators, err = dao.Stats.GetOplogCountByUser(start, end, s.limit(query)); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// GetMFAAdoption 获取MFA使用率趋势
+func (s *stats) GetMFAAdoption(query *StatsQuery) ([]*MFAAdoptionItem, error) {
+	start, end, err := s.parseRange(query)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := dao.Stats.GetUserSnapshots(start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*MFAAdoptionItem, len(snapshots))
+	for i, snapshot := range snapshots {
+		items[i] = &MFAAdoptionItem{
+			Date:        snapshot.Date,
+			ActiveUsers: snapshot.ActiveUsers,
+			MFAUsers:    snapshot.MFAUsers,
+		}
+		if snapshot.ActiveUsers > 0 {
+			items[i].Rate = float64(snapshot.MFAUsers) / float64(snapshot.ActiveUsers)
+		}
+	}
+
+	return items, nil
+}
+
+// parseRange 解析统计日期范围
+func (s *stats) parseRange(query *StatsQuery) (start, end string, err error) {
+	now := time.Now().In(config.Location())
+	start, end = query.Start, query.End
+	if start == "" {
+		start = now.AddDate(0, 0, -29).Format("2006-01-02")
+	}
+	if end == "" {
+		end = now.Format("2006-01-02")
+	}
+
+	startDate, err := time.Parse("2006-01-02", start)
+	if err != nil {
+		return "", "", errors.New("开始日期格式错误，请使用YYYY-MM-DD格式")
+	}
+	endDate, err := time.Parse("2006-01-02", end)
+	if err != nil {
+		return "", "", errors.New("结束日期格式错误，请使用YYYY-MM-DD格式")
+	}
+	if endDate.Before(startDate) {
+		return "", "", errors.New("结束日期不能早于开始日期")
+	}
+	if endDate.Sub(startDate) > 366*24*time.Hour {
+		return "", "", errors.New("统计范围不能超过一年")
+	}
+
+	return start, end, nil
+}
+
+// period 获取日期所属的统计周期，按周统计时为ISO周（如：2024-W05）
+func (s *stats) period(date, period string) string {
+	if period != "week" {
+		return date
+	}
+	day, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	year, week := day.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// limit 排行数量
+func (s *stats) limit(query *StatsQuery) int {
+	if query.Limit <= 0 || query.Limit > 100 {
+		return 10
+	}
+	return query.Limit
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/robfig/cron/v3"
 	"github.com/wonderivan/logger"
+	"ops-api/config"
 	"ops-api/dao"
 	"ops-api/global"
 	"ops-api/model"
@@ -143,7 +144,8 @@ func (t *task) GetTaskLogList(id uint, page, limit int) (data *dao.TaskLogList,
 
 // TaskInit 初始化任务调度器并加载数据库中的任务
 func TaskInit() error {
-	global.CornSchedule = cron.New(cron.WithChain())
+	// 按系统时区调度任务，修改系统时区后需重启服务生效
+	global.CornSchedule = cron.New(cron.WithChain(), cron.WithLocation(config.Location()))
 
 	// 清空所有任务的EntryID，这里WHERE 1 = 1 是为了避免条件为空的情况
 	if err := global.MySQLClient.Model(&model.ScheduledTask{}).Where("1 = 1").Update("entry_id", nil).Error; err != nil {
@@ -202,6 +204,17 @@ func AddOrUpdateTask(task model.ScheduledTask) error {
 			executeBuiltInMethod(task, &execLog)
 		}
 
+		// 推送到管理控制台
+		if err := global.MySQLClient.First(&execLog, execLog.ID).Error; err == nil {
+			ConsoleEvent.Publish(ConsoleEventTask, map[string]interface{}{
+				"task_id":   task.ID,
+				"name":      task.Name,
+				"run_at":    execLog.RunAt,
+				"finish_at": execLog.FinishAt,
+				"result":    execLog.Result,
+			})
+		}
+
 		// 更新任务信息
 		if err := global.MySQLClient.Model(&task).Updates(map[string]interface{}{
 			"last_run_at":     startTime,
@@ -312,6 +325,54 @@ func executeBuiltInMethod(task model.ScheduledTask, execLog *model.ScheduledTask
 		}
 	}
 
+	// 登录统计汇总（汇总前一天及当天的数据，前一天的数据用于补全跨天的登录记录）
+	if task.BuiltInMethod == "login_stats_rollup" {
+		if err := Stats.RollupRecent(2); err != nil {
+			global.MySQLClient.Model(execLog).Update("result", err.Error())
+			global.MySQLClient.Model(&task).Update("LastRunResult", "失败")
+			logger.Warn("任务执行失败:", err.Error())
+		} else {
+			global.MySQLClient.Model(execLog).Update("result", "成功")
+			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
+		}
+	}
+
+	// 合规报告（生成上月的访问审查及登录审计报告并发送给接收人）
+	if task.BuiltInMethod == "compliance_report" {
+		if err := ComplianceReport.ComplianceReportNotice(&task); err != nil {
+			global.MySQLClient.Model(execLog).Update("result", err.Error())
+			global.MySQLClient.Model(&task).Update("LastRunResult", "失败")
+			logger.Warn("任务执行失败:", err.Error())
+		} else {
+			global.MySQLClient.Model(execLog).Update("result", "成功")
+			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
+		}
+	}
+
+	// 安全事件通知（汇总发送）
+	if task.BuiltInMethod == "security_event_notify" {
+		if err := SecurityEvent.SecurityEventNotice(&task); err != nil {
+			global.MySQLClient.Model(execLog).Update("result", err.Error())
+			global.MySQLClient.Model(&task).Update("LastRunResult", "失败")
+			logger.Warn("任务执行失败:", err.Error())
+		} else {
+			global.MySQLClient.Model(execLog).Update("result", "成功")
+			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
+		}
+	}
+
+	// SAML应用SP证书过期提醒
+	if task.BuiltInMethod == "sp_certificate_expire_notify" {
+		if err := Site.SPCertificateExpiredNotice(&task); err != nil {
+			global.MySQLClient.Model(execLog).Update("result", err.Error())
+			global.MySQLClient.Model(&task).Update("LastRunResult", "失败")
+			logger.Warn("任务执行失败:", err.Error())
+		} else {
+			global.MySQLClient.Model(execLog).Update("result", "成功")
+			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
+		}
+	}
+
 	// URL地址证书监控
 	if task.BuiltInMethod == "url_certificate_expire_notify" {
 		if err := UrlAddress.AutoCertificateCheck(&task); err != nil {
@@ -323,4 +384,49 @@ func executeBuiltInMethod(task model.ScheduledTask, execLog *model.ScheduledTask
 			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
 		}
 	}
+
+	// 告警通知（重复提醒未恢复的内置告警）
+	if task.BuiltInMethod == "alert_notify" {
+		if err := Alert.AlertNotice(&task); err != nil {
+			global.MySQLClient.Model(execLog).Update("result", err.Error())
+			global.MySQLClient.Model(&task).Update("LastRunResult", "失败")
+			logger.Warn("任务执行失败:", err.Error())
+		} else {
+			global.MySQLClient.Model(execLog).Update("result", "成功")
+			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
+		}
+	}
+	// 站点授权到期撤销（撤销已到期的授权并通知应用负责人）
+	if task.BuiltInMethod == "site_grant_expire" {
+		if err := SiteGrant.SiteGrantExpireNotice(); err != nil {
+			global.MySQLClient.Model(execLog).Update("result", err.Error())
+			global.MySQLClient.Model(&task).Update("LastRunResult", "失败")
+			logger.Warn("任务执行失败:", err.Error())
+		} else {
+			global.MySQLClient.Model(execLog).Update("result", "成功")
+			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
+		}
+	}
+	// SP Metadata自动刷新（更新SAML2站点的EntityID及SP证书）
+	if task.BuiltInMethod == "sp_metadata_refresh" {
+		if err := Site.SPMetadataRefresh(&task); err != nil {
+			global.MySQLClient.Model(execLog).Update("result", err.Error())
+			global.MySQLClient.Model(&task).Update("LastRunResult", "失败")
+			logger.Warn("任务执行失败:", err.Error())
+		} else {
+			global.MySQLClient.Model(execLog).Update("result", "成功")
+			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
+		}
+	}
+	// 动态分组同步（根据用户属性重新计算动态分组成员）
+	if task.BuiltInMethod == "dynamic_group_sync" {
+		if err := DynamicGroup.SyncAll(); err != nil {
+			global.MySQLClient.Model(execLog).Update("result", err.Error())
+			global.MySQLClient.Model(&task).Update("LastRunResult", "失败")
+			logger.Warn("任务执行失败:", err.Error())
+		} else {
+			global.MySQLClient.Model(execLog).Update("result", "成功")
+			global.MySQLClient.Model(&task).Update("LastRunResult", "成功")
+		}
+	}
 }
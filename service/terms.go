@@ -0,0 +1,265 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"strings"
+)
+
+var Terms terms
+
+type terms struct{}
+
+// TermsAcceptPage 单点登录需要先接受使用条款时返回给前端的页面，前端使用返回的Token重新发起授权请求获取待接受的条款
+const TermsAcceptPage = "TERMS_ACCEPT"
+
+// TermsCreate 创建使用条款结构体
+type TermsCreate struct {
+	Name     string `json:"name" binding:"required"`
+	Content  string `json:"content" binding:"required"`
+	Enabled  *bool  `json:"enabled" binding:"required"`
+	AllSites *bool  `json:"all_sites" binding:"required"`
+	Sites    []uint `json:"sites"`
+}
+
+// TermsUpdate 更新使用条款结构体，条款内容变更时版本号递增，所有用户需要重新接受
+type TermsUpdate struct {
+	ID uint `json:"id" binding:"required"`
+	TermsCreate
+}
+
+// TermsPending 待接受的使用条款
+type TermsPending struct {
+	ID      uint   `json:"id"`
+	Name    string `json:"name"`
+	Version uint   `json:"version"`
+	Content string `json:"content"`
+}
+
+// TermsAccept 接受使用条款结构体
+type TermsAccept struct {
+	Application string `json:"application"` // 触发接受的应用名称
+	Terms       []struct {
+		ID      uint `json:"id" binding:"required"`
+		Version uint `json:"version" binding:"required"`
+	} `json:"terms" binding:"required,min=1,dive"`
+}
+
+// TermsRequiredError 访问应用前需要先接受使用条款
+type TermsRequiredError struct {
+	Application string          `json:"application"`
+	Terms       []*TermsPending `json:"terms"`
+}
+
+func (e *TermsRequiredError) Error() string {
+	return "访问该应用需要先阅读并接受使用条款"
+}
+
+// termsPending 判断单点登录是否因未接受使用条款而中断
+func termsPending(err error) bool {
+	var termsErr *TermsRequiredError
+	return errors.As(err, &termsErr)
+}
+
+// GetTermsList 获取使用条款列表（表格）
+func (t *terms) GetTermsList(name string, page, limit int) (*dao.TermsList, error) {
+	return dao.Terms.GetTermsList(name, page, limit)
+}
+
+// GetTermsVersions 获取使用条款的历史版本
+func (t *terms) GetTermsVersions(id uint) ([]*model.TermsVersion, error) {
+	return dao.Terms.GetTermsVersions(id)
+}
+
+// AddTerms 创建使用条款
+func (t *terms) AddTerms(data *TermsCreate) (*model.Terms, error) {
+
+	item := &model.Terms{
+		Name:     data.Name,
+		Version:  1,
+		Content:  data.Content,
+		Enabled:  *data.Enabled,
+		AllSites: *data.AllSites,
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	result, err := dao.Terms.AddTerms(tx, item)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := dao.Terms.CreateTermsVersion(tx, result); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := t.updateSites(tx, result, data.Sites); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateTerms 更新使用条款
+func (t *terms) UpdateTerms(data *TermsUpdate) (*model.Terms, error) {
+
+	item, err := dao.Terms.GetTerms(data.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 条款内容变更时生成新版本
+	changed := strings.TrimSpace(item.Content) != strings.TrimSpace(data.Content)
+	if changed {
+		item.Version++
+	}
+	item.Name = data.Name
+	item.Content = data.Content
+	item.Enabled = *data.Enabled
+	item.AllSites = *data.AllSites
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.Terms.UpdateTerms(tx, item); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if changed {
+		if err := dao.Terms.CreateTermsVersion(tx, item); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := t.updateSites(tx, item, data.Sites); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return item, nil
+}
+
+// DeleteTerms 删除使用条款
+func (t *terms) DeleteTerms(id uint) error {
+
+	item, err := dao.Terms.GetTerms(id)
+	if err != nil {
+		return err
+	}
+
+	// 开启事务
+	tx := global.MySQLClient.Begin()
+
+	if err := dao.Terms.DeleteTerms(tx, item); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 提交事务
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+// GetAcceptanceList 获取使用条款接受记录（表格）
+func (t *terms) GetAcceptanceList(termsId uint, username string, page, limit int) (*dao.TermsAcceptanceList, error) {
+	return dao.Terms.GetAcceptanceList(termsId, username, page, limit)
+}
+
+// Check 判断用户是否已接受访问应用需要的所有使用条款（当前版本），未接受时返回 *TermsRequiredError
+func (t *terms) Check(site *model.Site, userId uint) error {
+
+	items, err := dao.Terms.GetSiteTerms(site.ID)
+	if err != nil {
+		return err
+	}
+
+	var pending []*TermsPending
+	for _, item := range items {
+		accepted, err := dao.Terms.IsAccepted(item.ID, item.Version, userId)
+		if err != nil {
+			return err
+		}
+		if !accepted {
+			pending = append(pending, &TermsPending{
+				ID:      item.ID,
+				Name:    item.Name,
+				Version: item.Version,
+				Content: item.Content,
+			})
+		}
+	}
+
+	if len(pending) > 0 {
+		return &TermsRequiredError{Application: site.Name, Terms: pending}
+	}
+
+	return nil
+}
+
+// Accept 用户接受使用条款，仅允许接受条款的当前版本
+func (t *terms) Accept(userId uint, username string, data *TermsAccept, clientIP, userAgent string) error {
+
+	var acceptances []*model.TermsAcceptance
+	for _, accepted := range data.Terms {
+		item, err := dao.Terms.GetTerms(accepted.ID)
+		if err != nil {
+			return errors.New("使用条款不存在")
+		}
+		if !item.Enabled {
+			return fmt.Errorf("使用条款%s未启用", item.Name)
+		}
+		if item.Version != accepted.Version {
+			return fmt.Errorf("使用条款%s已更新，请阅读最新版本后重新接受", item.Name)
+		}
+		acceptances = append(acceptances, &model.TermsAcceptance{
+			TermsID:   item.ID,
+			Version:   item.Version,
+			UserID:    userId,
+			Name:      item.Name,
+			Username:  username,
+			Site:      data.Application,
+			ClientIP:  clientIP,
+			UserAgent: userAgent,
+		})
+	}
+
+	return dao.Terms.CreateAcceptances(acceptances)
+}
+
+// updateSites 更新使用条款关联的应用
+func (t *terms) updateSites(tx *gorm.DB, item *model.Terms, siteIds []uint) error {
+
+	var sites []model.Site
+	if len(siteIds) > 0 {
+		if err := tx.Where("id IN ?", siteIds).Find(&sites).Error; err != nil {
+			return err
+		}
+	}
+	if !item.AllSites && len(sites) == 0 {
+		return errors.New("未对所有应用生效时需要指定应用")
+	}
+
+	return dao.Terms.UpdateTermsSites(tx, item, sites)
+}
@@ -0,0 +1,19 @@
+package service
+
+import "time"
+
+// ThrottleError 操作过于频繁（如重复发送验证码），接口返回429及建议的重试等待时间
+type ThrottleError struct {
+	msg        string
+	retryAfter time.Duration
+}
+
+func (e *ThrottleError) Error() string { return e.msg }
+
+// RetryAfter 返回建议的重试等待时间
+func (e *ThrottleError) RetryAfter() time.Duration { return e.retryAfter }
+
+// NewThrottleError 创建操作过于频繁错误
+func NewThrottleError(retryAfter time.Duration, msg string) *ThrottleError {
+	return &ThrottleError{msg: msg, retryAfter: retryAfter}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"ops-api/config"
+	"ops-api/utils"
+	"ops-api/utils/scan"
+)
+
+var Upload upload
+
+type upload struct{}
+
+const (
+	uploadDefaultMaxSize      = 2048 // 默认的上传文件大小限制（KB）
+	uploadDefaultMaxDimension = 4096 // 默认的图片宽度及高度限制（像素）
+)
+
+// errUploadType 文件类型不在允许的范围内
+var errUploadType = errors.New("不支持的文件类型")
+
+// uploadImageExtensions 允许上传的图片类型及保存时使用的文件后缀
+var uploadImageExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// UploadImage 处理后的图片
+type UploadImage struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+}
+
+// File 校验上传的文件：大小不超过 uploadMaxSize，根据文件内容识别的类型在 contentTypes 中（为空时不限制），
+// 并使用 uploadScanner 配置的扫描器扫描，返回文件内容及识别的类型
+func (u *upload) File(filename string, r io.Reader, contentTypes ...string) ([]byte, string, error) {
+
+	maxSize := u.maxSize()
+	data, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, "", fmt.Errorf("文件大小不能超过%dKB", maxSize>>10)
+	}
+
+	// 根据文件内容识别类型，不信任客户端提供的文件后缀及Content-Type
+	contentType := http.DetectContentType(data)
+	if len(contentTypes) > 0 && !utils.Contains(contentTypes, contentType) {
+		return nil, "", errUploadType
+	}
+
+	if err := u.scan(filename, data); err != nil {
+		return nil, "", err
+	}
+
+	return data, contentType, nil
+}
+
+// Image 校验上传的图片并重新编码以去除EXIF等元数据，图片宽度及高度不能超过 uploadMaxDimension
+func (u *upload) Image(filename string, r io.Reader) (*UploadImage, error) {
+
+	types := make([]string, 0, len(uploadImageExtensions))
+	for contentType := range uploadImageExtensions {
+		types = append(types, contentType)
+	}
+
+	data, contentType, err := u.File(filename, r, types...)
+	if err != nil {
+		if errors.Is(err, errUploadType) {
+			return nil, errors.New("图片仅支持png、jpg、jpeg、gif、webp格式")
+		}
+		return nil, err
+	}
+
+	data, err = u.sanitizeImage(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadImage{
+		Data:        data,
+		ContentType: contentType,
+		Ext:         uploadImageExtensions[contentType],
+	}, nil
+}
+
+// scan 使用配置的扫描器扫描文件，扫描服务不可用时拒绝上传
+func (u *upload) scan(filename string, data []byte) error {
+
+	name := config.GetString("uploadScanner")
+	if name == "" {
+		return nil
+	}
+
+	scanner, ok := scan.Get(name)
+	if !ok {
+		logger.Error("ERROR：未注册的文件扫描器：" + name)
+		return errors.New("文件扫描服务异常，请稍后再试")
+	}
+
+	address := config.GetString("uploadScanAddress")
+	threat, err := scanner(address, filename, data)
+	if err != nil {
+		logger.Error(fmt.Sprintf("ERROR：文件%s扫描失败，%s", filename, err.Error()))
+		return errors.New("文件扫描服务异常，请稍后再试")
+	}
+	if threat != "" {
+		logger.Warn(fmt.Sprintf("文件%s扫描发现威胁：%s，已拒绝上传", filename, threat))
+		return errors.New("文件存在安全风险，已拒绝上传")
+	}
+
+	return nil
+}
+
+// sanitizeImage 校验图片尺寸并重新编码，webp格式无法重新编码，仅移除EXIF、XMP数据块
+func (u *upload) sanitizeImage(data []byte, contentType string) ([]byte, error) {
+
+	if contentType == "image/webp" {
+		return u.sanitizeWebP(data)
+	}
+
+	// 解码前先校验尺寸，避免解码超大图片占用过多内存
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.New("图片格式错误")
+	}
+	if err := u.checkDimension(cfg.Width, cfg.Height); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch contentType {
+	case "image/gif":
+		img, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.New("图片格式错误")
+		}
+		err = gif.EncodeAll(&buf, img)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, errors.New("图片格式错误")
+		}
+		if contentType == "image/png" {
+			err = png.Encode(&buf, img)
+		} else {
+			err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// sanitizeWebP 校验webp图片尺寸并移除EXIF、XMP数据块
+func (u *upload) sanitizeWebP(data []byte) ([]byte, error) {
+
+	invalid := errors.New("图片格式错误")
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, invalid
+	}
+
+	var (
+		out           = bytes.NewBuffer(append([]byte{}, data[0:12]...))
+		width, height int
+		vp8x          = -1 // VP8X数据块在输出中的位置，用于清除EXIF、XMP标记
+	)
+	for offset := 12; offset < len(data); {
+		if offset+8 > len(data) {
+			return nil, invalid
+		}
+		fourCC := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		if offset+8+size > len(data) {
+			return nil, invalid
+		}
+		// 数据块大小为奇数时末尾有一个填充字节
+		end := min(offset+8+size+size%2, len(data))
+		payload := data[offset+8 : offset+8+size]
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+			offset = end
+			continue
+		case "VP8X":
+			if size < 10 {
+				return nil, invalid
+			}
+			vp8x = out.Len()
+			width = int(uint32(payload[4])|uint32(payload[5])<<8|uint32(payload[6])<<16) + 1
+			height = int(uint32(payload[7])|uint32(payload[8])<<8|uint32(payload[9])<<16) + 1
+		case "VP8 ":
+			if width == 0 && size >= 10 {
+				width = int(binary.LittleEndian.Uint16(payload[6:8]) & 0x3fff)
+				height = int(binary.LittleEndian.Uint16(payload[8:10]) & 0x3fff)
+			}
+		case "VP8L":
+			if width == 0 && size >= 5 {
+				bits := binary.LittleEndian.Uint32(payload[1:5])
+				width = int(bits&0x3fff) + 1
+				height = int((bits>>14)&0x3fff) + 1
+			}
+		}
+
+		out.Write(data[offset:end])
+		offset = end
+	}
+
+	if width == 0 || height == 0 {
+		return nil, invalid
+	}
+	if err := u.checkDimension(width, height); err != nil {
+		return nil, err
+	}
+
+	result := out.Bytes()
+	// 清除VP8X中的EXIF、XMP标记并更新RIFF大小
+	if vp8x >= 0 {
+		result[vp8x+8] &^= 0x08 | 0x04
+	}
+	binary.LittleEndian.PutUint32(result[4:8], uint32(len(result)-8))
+
+	return result, nil
+}
+
+// checkDimension 校验图片宽度及高度
+func (u *upload) checkDimension(width, height int) error {
+	maxDimension := config.GetInt("uploadMaxDimension")
+	if maxDimension <= 0 {
+		maxDimension = uploadDefaultMaxDimension
+	}
+	if width > maxDimension || height > maxDimension {
+		return fmt.Errorf("图片宽度及高度不能超过%d像素", maxDimension)
+	}
+	return nil
+}
+
+// maxSize 上传文件大小限制（字节）
+func (u *upload) maxSize() int64 {
+	maxSize := config.GetInt("uploadMaxSize")
+	if maxSize <= 0 {
+		maxSize = uploadDefaultMaxSize
+	}
+	return int64(maxSize) << 10
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"github.com/wonderivan/logger"
+	"net"
+	"ops-api/dao"
+	"ops-api/model"
+	"ops-api/utils"
+	"ops-api/utils/breaker"
+	"time"
+)
+
+// 头像上传队列：MinIO不可用时头像暂存到数据库，MinIO恢复后由后台任务上传并更新用户头像
+
+const (
+	uploadQueueInterval  = 30 * time.Second // 检查待上传文件的间隔
+	uploadQueueBatchSize = 20               // 每次最多上传的文件数量
+)
+
+var UploadQueue uploadQueue
+
+type uploadQueue struct{}
+
+// UploadQueueInit 定时上传MinIO不可用期间暂存的文件
+func UploadQueueInit() {
+	go func() {
+		ticker := time.NewTicker(uploadQueueInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			UploadQueue.flush()
+		}
+	}()
+}
+
+// isStorageUnavailable 判断上传失败是否由MinIO不可用（熔断、网络错误）导致，其它错误（如权限不足）不进入队列
+func isStorageUnavailable(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, breaker.ErrOpen) || errors.As(err, &netErr)
+}
+
+// enqueue 暂存待上传文件
+func (u *uploadQueue) enqueue(object, contentType, username string, data []byte) error {
+	return dao.PendingUpload.SavePendingUpload(&model.PendingUpload{
+		Object:      object,
+		ContentType: contentType,
+		Data:        data,
+		Username:    username,
+		CreatedAt:   time.Now(),
+	})
+}
+
+// flush 上传暂存的文件，MinIO熔断期间上传请求直接失败，熔断时间结束后的上传请求即为探测请求
+func (u *uploadQueue) flush() {
+
+	items, err := dao.PendingUpload.GetPendingUploads(uploadQueueBatchSize)
+	if err != nil {
+		logger.Error("获取待上传文件失败：" + err.Error())
+		return
+	}
+
+	for _, item := range items {
+		if err := utils.FileUpload(item.Object, item.ContentType, bytes.NewReader(item.Data), int64(len(item.Data))); err != nil {
+			logger.Warn("暂存文件上传失败：" + item.Object + "，" + err.Error())
+			if err := dao.PendingUpload.RecordPendingUploadFailure(item.ID, err.Error()); err != nil {
+				logger.Error("记录文件上传失败原因失败：" + err.Error())
+			}
+			// MinIO再次不可用时结束本次上传
+			if isStorageUnavailable(err) {
+				return
+			}
+			continue
+		}
+
+		if item.Username != "" {
+			if err := dao.User.UpdateUserAvatar(item.Username, item.Object); err != nil {
+				logger.Error("更新用户头像失败：" + err.Error())
+				continue
+			}
+		}
+		if err := dao.PendingUpload.DeletePendingUpload(item); err != nil {
+			logger.Error("删除暂存文件失败：" + err.Error())
+		}
+	}
+}
+
+// PendingCount 获取待上传文件数量
+func (u *uploadQueue) PendingCount() (int64, error) {
+	return dao.PendingUpload.CountPendingUploads()
+}
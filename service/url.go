@@ -12,6 +12,7 @@ import (
 	"ops-api/dao"
 	"ops-api/global"
 	"ops-api/model"
+	"ops-api/utils"
 	"ops-api/utils/notify"
 	"strconv"
 	"strings"
@@ -119,7 +120,7 @@ func (u *urlAddress) AutoCertificateCheck(task *model.ScheduledTask) error {
 func urlCertificateExpiredNoticeFeishuPost(urls []*model.DomainCertificateMonitor) map[string]interface{} {
 	var (
 		now     = time.Now()
-		issuer  = config.Conf.Settings["issuer"].(string)
+		issuer  = config.GetString("issuer")
 		content = make([][]map[string]interface{}, 0)
 	)
 
@@ -132,10 +133,10 @@ func urlCertificateExpiredNoticeFeishuPost(urls []*model.DomainCertificateMonito
 		)
 
 		if url.ExpirationAt != nil {
-			expiredAt = url.ExpirationAt.Format("2006-01-02 15:04:05")
+			expiredAt = utils.FormatTime(*url.ExpirationAt)
 		}
 		if url.LastCheckAt != nil {
-			checkedAt = url.LastCheckAt.Format("2006-01-02 15:04:05")
+			checkedAt = utils.FormatTime(*url.LastCheckAt)
 		}
 
 		if url.Status != nil {
@@ -205,7 +206,7 @@ func urlCertificateExpiredNoticeMarkdown(urls []*model.DomainCertificateMonitor)
 	var (
 		builder = &strings.Builder{}
 		now     = time.Now()
-		issuer  = config.Conf.Settings["issuer"].(string)
+		issuer  = config.GetString("issuer")
 	)
 
 	builder.WriteString("**URL 站点 HTTPS 证书异常提醒：**\n\n")
@@ -218,10 +219,10 @@ func urlCertificateExpiredNoticeMarkdown(urls []*model.DomainCertificateMonitor)
 		)
 
 		if url.ExpirationAt != nil {
-			expiredAt = url.ExpirationAt.Format("2006-01-02 15:04:05")
+			expiredAt = utils.FormatTime(*url.ExpirationAt)
 		}
 		if url.LastCheckAt != nil {
-			checkedAt = url.LastCheckAt.Format("2006-01-02 15:04:05")
+			checkedAt = utils.FormatTime(*url.LastCheckAt)
 		}
 
 		if url.Status != nil {
@@ -256,7 +257,7 @@ func urlCertificateExpiredNoticeMarkdown(urls []*model.DomainCertificateMonitor)
 func urlCertificateExpiredNoticeHTML(urls []*model.DomainCertificateMonitor) string {
 
 	var (
-		issuer = config.Conf.Settings["issuer"].(string)
+		issuer = config.GetString("issuer")
 		now    = time.Now()
 	)
 
@@ -418,10 +419,10 @@ func generateUrlCertificateRows(urls []*model.DomainCertificateMonitor, now time
 
 		// 格式化时间
 		if url.ExpirationAt != nil {
-			expiredAt = url.ExpirationAt.Format("2006-01-02 15:04:05")
+			expiredAt = utils.FormatTime(*url.ExpirationAt)
 		}
 		if url.LastCheckAt != nil {
-			checkedAt = url.LastCheckAt.Format("2006-01-02 15:04:05")
+			checkedAt = utils.FormatTime(*url.LastCheckAt)
 		}
 
 		rows.WriteString(fmt.Sprintf(`
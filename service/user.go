@@ -1,11 +1,16 @@
 package service
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"github.com/alibabacloud-go/tea/tea"
 	"github.com/go-playground/validator/v10"
 	"github.com/pquerna/otp/totp"
+	"github.com/wonderivan/logger"
 	"gorm.io/gorm"
+	"html"
+	"io"
 	"ops-api/config"
 	"ops-api/dao"
 	"ops-api/global"
@@ -13,13 +18,24 @@ import (
 	"ops-api/model"
 	"ops-api/utils"
 	"ops-api/utils/check"
+	"ops-api/utils/hook"
+	"ops-api/utils/i18n"
 	"ops-api/utils/mail"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 )
 
 var User user
 
+const (
+	avatarUploadURLExpires = 10 * time.Minute // 头像临时上传链接有效期
+)
+
+// avatarExtensions 允许上传的头像格式
+var avatarExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+
 type user struct{}
 
 // AuthorizeParam 通用的单点登录授权参数接口
@@ -35,131 +51,225 @@ type AuthorizeParam interface {
 	GetScope() string
 	GetState() string
 	GetNonce() string
+	GetAcrValues() string
+	GetResource() string
+	GetAudience() string
+	GetCodeChallenge() string
+	GetCodeChallengeMethod() string
 	GetNginxRedirectURI() string
+	GetWtrealm() string
+	GetWreply() string
+	GetWctx() string
 }
 
 // UserLogin 用户登录结构体（支持CAS3.0、OAuth2.0、OIDC和SAML2）
 type UserLogin struct {
-	Username         string `json:"username" binding:"required"`
-	Password         string `json:"password" binding:"required"`
-	ResponseType     string `json:"response_type"`      // OAuth2.0客户端：授权类型，固定值：code
-	ClientId         string `json:"client_id"`          // OAuth2.0客户端：客户端ID
-	RedirectURI      string `json:"redirect_uri"`       // OAuth2.0客户端：重定向URL
-	State            string `json:"state"`              // OAuth2.0客户端：客户端状态码
-	Scope            string `json:"scope"`              // OAuth2.0客户端：申请权限范围
-	Nonce            string `json:"nonce"`              // OIDC客户端：随机码
-	Service          string `json:"service"`            // CAS3.0客户端：回调地址
-	SAMLRequest      string `json:"SAMLRequest"`        // SAML2客户端：SAMLRequest
-	RelayState       string `json:"RelayState"`         // SAML2客户端：客户端状态码
-	SigAlg           string `json:"SigAlg"`             // SAML2客户端：签名算法
-	Signature        string `json:"Signature"`          // SAML2客户端：签名
-	NginxRedirectURI string `json:"nginx_redirect_uri"` // Nginx代理客户端：回调地址
+	Username            string `json:"username" binding:"required"`
+	Password            string `json:"password" binding:"required"`
+	ResponseType        string `json:"response_type"`         // OAuth2.0客户端：响应类型，如：code、id_token、code id_token
+	ClientId            string `json:"client_id"`             // OAuth2.0客户端：客户端ID
+	RedirectURI         string `json:"redirect_uri"`          // OAuth2.0客户端：重定向URL
+	State               string `json:"state"`                 // OAuth2.0客户端：客户端状态码
+	Scope               string `json:"scope"`                 // OAuth2.0客户端：申请权限范围
+	Nonce               string `json:"nonce"`                 // OIDC客户端：随机码
+	AcrValues           string `json:"acr_values"`            // OIDC客户端：要求的认证等级
+	Resource            string `json:"resource"`              // OAuth2.0客户端：申请访问的资源（RFC 8707）
+	Audience            string `json:"audience"`              // OAuth2.0客户端：申请访问的资源，与resource作用相同
+	CodeChallenge       string `json:"code_challenge"`        // OAuth2.0客户端：PKCE code_challenge
+	CodeChallengeMethod string `json:"code_challenge_method"` // OAuth2.0客户端：PKCE code_challenge计算方式
+	Service             string `json:"service"`               // CAS3.0客户端：回调地址
+	SAMLRequest         string `json:"SAMLRequest"`           // SAML2客户端：SAMLRequest
+	RelayState          string `json:"RelayState"`            // SAML2客户端：客户端状态码
+	SigAlg              string `json:"SigAlg"`                // SAML2客户端：签名算法
+	Signature           string `json:"Signature"`             // SAML2客户端：签名
+	NginxRedirectURI    string `json:"nginx_redirect_uri"`    // Nginx代理客户端：回调地址
+	Wtrealm             string `json:"wtrealm"`               // WS-Fed客户端：RP标识
+	Wreply              string `json:"wreply"`                // WS-Fed客户端：RP回调地址
+	Wctx                string `json:"wctx"`                  // WS-Fed客户端：RP状态信息
 }
 
 // DingTalkLogin 钉钉扫码登录结构体（支持CAS3.0、OAuth2.0、OIDC和SAML2）
 type DingTalkLogin struct {
-	AuthCode         string `json:"authCode" binding:"required"`
-	ResponseType     string `json:"response_type"`      // OAuth2.0客户端：授权类型，固定值：code
-	ClientId         string `json:"client_id"`          // OAuth2.0客户端：客户端ID
-	RedirectURI      string `json:"redirect_uri"`       // OAuth2.0客户端：重定向URL
-	State            string `json:"state"`              // OAuth2.0客户端：客户端状态码
-	Scope            string `json:"scope"`              // OAuth2.0客户端：申请权限范围
-	Nonce            string `json:"nonce"`              // OIDC客户端：随机码
-	Service          string `json:"service"`            // CAS3.0客户端：回调地址
-	SAMLRequest      string `json:"SAMLRequest"`        // SAML2客户端：SAMLRequest
-	RelayState       string `json:"RelayState"`         // SAML2客户端：客户端状态码
-	SigAlg           string `json:"SigAlg"`             // SAML2客户端：签名算法
-	Signature        string `json:"Signature"`          // SAML2客户端：签名
-	NginxRedirectURI string `json:"nginx_redirect_uri"` // Nginx代理客户端：回调地址
+	AuthCode            string `json:"authCode" binding:"required"`
+	ResponseType        string `json:"response_type"`         // OAuth2.0客户端：响应类型，如：code、id_token、code id_token
+	ClientId            string `json:"client_id"`             // OAuth2.0客户端：客户端ID
+	RedirectURI         string `json:"redirect_uri"`          // OAuth2.0客户端：重定向URL
+	State               string `json:"state"`                 // OAuth2.0客户端：客户端状态码
+	Scope               string `json:"scope"`                 // OAuth2.0客户端：申请权限范围
+	Nonce               string `json:"nonce"`                 // OIDC客户端：随机码
+	AcrValues           string `json:"acr_values"`            // OIDC客户端：要求的认证等级
+	Resource            string `json:"resource"`              // OAuth2.0客户端：申请访问的资源（RFC 8707）
+	Audience            string `json:"audience"`              // OAuth2.0客户端：申请访问的资源，与resource作用相同
+	CodeChallenge       string `json:"code_challenge"`        // OAuth2.0客户端：PKCE code_challenge
+	CodeChallengeMethod string `json:"code_challenge_method"` // OAuth2.0客户端：PKCE code_challenge计算方式
+	Service             string `json:"service"`               // CAS3.0客户端：回调地址
+	SAMLRequest         string `json:"SAMLRequest"`           // SAML2客户端：SAMLRequest
+	RelayState          string `json:"RelayState"`            // SAML2客户端：客户端状态码
+	SigAlg              string `json:"SigAlg"`                // SAML2客户端：签名算法
+	Signature           string `json:"Signature"`             // SAML2客户端：签名
+	NginxRedirectURI    string `json:"nginx_redirect_uri"`    // Nginx代理客户端：回调地址
+	Wtrealm             string `json:"wtrealm"`               // WS-Fed客户端：RP标识
+	Wreply              string `json:"wreply"`                // WS-Fed客户端：RP回调地址
+	Wctx                string `json:"wctx"`                  // WS-Fed客户端：RP状态信息
 }
 
 // WeChatLogin 企业微信扫码登录结构体（支持CAS3.0、OAuth2.0、OIDC和SAML2）
 type WeChatLogin struct {
-	Code             string `json:"code" binding:"required"`
-	Appid            string `json:"appid" binding:"required"`
-	ResponseType     string `json:"response_type"`      // OAuth2.0客户端：授权类型，固定值：code
-	ClientId         string `json:"client_id"`          // OAuth2.0客户端：客户端ID
-	RedirectURI      string `json:"redirect_uri"`       // OAuth2.0客户端：重定向URL
-	State            string `json:"state"`              // OAuth2.0客户端：客户端状态码
-	Scope            string `json:"scope"`              // OAuth2.0客户端：申请权限范围
-	Nonce            string `json:"nonce"`              // OIDC客户端：随机码
-	Service          string `json:"service"`            // CAS3.0客户端：回调地址
-	SAMLRequest      string `json:"SAMLRequest"`        // SAML2客户端：SAMLRequest
-	RelayState       string `json:"RelayState"`         // SAML2客户端：客户端状态码
-	SigAlg           string `json:"SigAlg"`             // SAML2客户端：签名算法
-	Signature        string `json:"Signature"`          // SAML2客户端：签名
-	NginxRedirectURI string `json:"nginx_redirect_uri"` // Nginx代理客户端：回调地址
+	Code                string `json:"code" binding:"required"`
+	Appid               string `json:"appid" binding:"required"`
+	ResponseType        string `json:"response_type"`         // OAuth2.0客户端：响应类型，如：code、id_token、code id_token
+	ClientId            string `json:"client_id"`             // OAuth2.0客户端：客户端ID
+	RedirectURI         string `json:"redirect_uri"`          // OAuth2.0客户端：重定向URL
+	State               string `json:"state"`                 // OAuth2.0客户端：客户端状态码
+	Scope               string `json:"scope"`                 // OAuth2.0客户端：申请权限范围
+	Nonce               string `json:"nonce"`                 // OIDC客户端：随机码
+	AcrValues           string `json:"acr_values"`            // OIDC客户端：要求的认证等级
+	Resource            string `json:"resource"`              // OAuth2.0客户端：申请访问的资源（RFC 8707）
+	Audience            string `json:"audience"`              // OAuth2.0客户端：申请访问的资源，与resource作用相同
+	CodeChallenge       string `json:"code_challenge"`        // OAuth2.0客户端：PKCE code_challenge
+	CodeChallengeMethod string `json:"code_challenge_method"` // OAuth2.0客户端：PKCE code_challenge计算方式
+	Service             string `json:"service"`               // CAS3.0客户端：回调地址
+	SAMLRequest         string `json:"SAMLRequest"`           // SAML2客户端：SAMLRequest
+	RelayState          string `json:"RelayState"`            // SAML2客户端：客户端状态码
+	SigAlg              string `json:"SigAlg"`                // SAML2客户端：签名算法
+	Signature           string `json:"Signature"`             // SAML2客户端：签名
+	NginxRedirectURI    string `json:"nginx_redirect_uri"`    // Nginx代理客户端：回调地址
+	Wtrealm             string `json:"wtrealm"`               // WS-Fed客户端：RP标识
+	Wreply              string `json:"wreply"`                // WS-Fed客户端：RP回调地址
+	Wctx                string `json:"wctx"`                  // WS-Fed客户端：RP状态信息
 }
 
 // FeishuLogin 飞书扫码登录结构体（支持CAS3.0、OAuth2.0、OIDC和SAML2）
 type FeishuLogin struct {
-	Code             string `json:"code" binding:"required"`
-	Byte             string `json:"byte" binding:"required"` // 自定义参数
-	ResponseType     string `json:"response_type"`           // OAuth2.0客户端：授权类型，固定值：code
-	ClientId         string `json:"client_id"`               // OAuth2.0客户端：客户端ID
-	RedirectURI      string `json:"redirect_uri"`            // OAuth2.0客户端：重定向URL
-	State            string `json:"state"`                   // OAuth2.0客户端：客户端状态码
-	Scope            string `json:"scope"`                   // OAuth2.0客户端：申请权限范围
-	Nonce            string `json:"nonce"`                   // OIDC客户端：随机码
-	Service          string `json:"service"`                 // CAS3.0客户端：回调地址
-	SAMLRequest      string `json:"SAMLRequest"`             // SAML2客户端：SAMLRequest
-	RelayState       string `json:"RelayState"`              // SAML2客户端：客户端状态码
-	SigAlg           string `json:"SigAlg"`                  // SAML2客户端：签名算法
-	Signature        string `json:"Signature"`               // SAML2客户端：签名
-	NginxRedirectURI string `json:"nginx_redirect_uri"`      // Nginx代理客户端：回调地址
-}
-
-func (f FeishuLogin) GetResponseType() string     { return f.ResponseType }
-func (f FeishuLogin) GetClientId() string         { return f.ClientId }
-func (f FeishuLogin) GetRedirectURI() string      { return f.RedirectURI }
-func (f FeishuLogin) GetService() string          { return f.Service }
-func (f FeishuLogin) GetSAMLRequest() string      { return f.SAMLRequest }
-func (f FeishuLogin) GetRelayState() string       { return f.RelayState }
-func (f FeishuLogin) GetSigAlg() string           { return f.SigAlg }
-func (f FeishuLogin) GetSignature() string        { return f.Signature }
-func (f FeishuLogin) GetScope() string            { return f.Scope }
-func (f FeishuLogin) GetState() string            { return f.State }
-func (f FeishuLogin) GetNonce() string            { return f.Nonce }
-func (f FeishuLogin) GetNginxRedirectURI() string { return f.NginxRedirectURI }
-
-func (d DingTalkLogin) GetResponseType() string     { return d.ResponseType }
-func (d DingTalkLogin) GetClientId() string         { return d.ClientId }
-func (d DingTalkLogin) GetRedirectURI() string      { return d.RedirectURI }
-func (d DingTalkLogin) GetService() string          { return d.Service }
-func (d DingTalkLogin) GetSAMLRequest() string      { return d.SAMLRequest }
-func (d DingTalkLogin) GetRelayState() string       { return d.RelayState }
-func (d DingTalkLogin) GetSigAlg() string           { return d.SigAlg }
-func (d DingTalkLogin) GetSignature() string        { return d.Signature }
-func (d DingTalkLogin) GetScope() string            { return d.Scope }
-func (d DingTalkLogin) GetState() string            { return d.State }
-func (d DingTalkLogin) GetNonce() string            { return d.Nonce }
-func (d DingTalkLogin) GetNginxRedirectURI() string { return d.NginxRedirectURI }
-
-func (w WeChatLogin) GetResponseType() string     { return w.ResponseType }
-func (w WeChatLogin) GetClientId() string         { return w.ClientId }
-func (w WeChatLogin) GetRedirectURI() string      { return w.RedirectURI }
-func (w WeChatLogin) GetService() string          { return w.Service }
-func (w WeChatLogin) GetSAMLRequest() string      { return w.SAMLRequest }
-func (w WeChatLogin) GetRelayState() string       { return w.RelayState }
-func (w WeChatLogin) GetSigAlg() string           { return w.SigAlg }
-func (w WeChatLogin) GetSignature() string        { return w.Signature }
-func (w WeChatLogin) GetScope() string            { return w.Scope }
-func (w WeChatLogin) GetState() string            { return w.State }
-func (w WeChatLogin) GetNonce() string            { return w.Nonce }
-func (w WeChatLogin) GetNginxRedirectURI() string { return w.NginxRedirectURI }
-
-func (u UserLogin) GetResponseType() string     { return u.ResponseType }
-func (u UserLogin) GetClientId() string         { return u.ClientId }
-func (u UserLogin) GetRedirectURI() string      { return u.RedirectURI }
-func (u UserLogin) GetService() string          { return u.Service }
-func (u UserLogin) GetSAMLRequest() string      { return u.SAMLRequest }
-func (u UserLogin) GetRelayState() string       { return u.RelayState }
-func (u UserLogin) GetSigAlg() string           { return u.SigAlg }
-func (u UserLogin) GetSignature() string        { return u.Signature }
-func (u UserLogin) GetScope() string            { return u.Scope }
-func (u UserLogin) GetState() string            { return u.State }
-func (u UserLogin) GetNonce() string            { return u.Nonce }
-func (u UserLogin) GetNginxRedirectURI() string { return u.NginxRedirectURI }
+	Code                string `json:"code" binding:"required"`
+	Byte                string `json:"byte" binding:"required"` // 自定义参数
+	ResponseType        string `json:"response_type"`           // OAuth2.0客户端：响应类型，如：code、id_token、code id_token
+	ClientId            string `json:"client_id"`               // OAuth2.0客户端：客户端ID
+	RedirectURI         string `json:"redirect_uri"`            // OAuth2.0客户端：重定向URL
+	State               string `json:"state"`                   // OAuth2.0客户端：客户端状态码
+	Scope               string `json:"scope"`                   // OAuth2.0客户端：申请权限范围
+	Nonce               string `json:"nonce"`                   // OIDC客户端：随机码
+	AcrValues           string `json:"acr_values"`              // OIDC客户端：要求的认证等级
+	Resource            string `json:"resource"`                // OAuth2.0客户端：申请访问的资源（RFC 8707）
+	Audience            string `json:"audience"`                // OAuth2.0客户端：申请访问的资源，与resource作用相同
+	CodeChallenge       string `json:"code_challenge"`          // OAuth2.0客户端：PKCE code_challenge
+	CodeChallengeMethod string `json:"code_challenge_method"`   // OAuth2.0客户端：PKCE code_challenge计算方式
+	Service             string `json:"service"`                 // CAS3.0客户端：回调地址
+	SAMLRequest         string `json:"SAMLRequest"`             // SAML2客户端：SAMLRequest
+	RelayState          string `json:"RelayState"`              // SAML2客户端：客户端状态码
+	SigAlg              string `json:"SigAlg"`                  // SAML2客户端：签名算法
+	Signature           string `json:"Signature"`               // SAML2客户端：签名
+	NginxRedirectURI    string `json:"nginx_redirect_uri"`      // Nginx代理客户端：回调地址
+	Wtrealm             string `json:"wtrealm"`                 // WS-Fed客户端：RP标识
+	Wreply              string `json:"wreply"`                  // WS-Fed客户端：RP回调地址
+	Wctx                string `json:"wctx"`                    // WS-Fed客户端：RP状态信息
+}
+
+func (f FeishuLogin) GetResponseType() string        { return f.ResponseType }
+func (f FeishuLogin) GetClientId() string            { return f.ClientId }
+func (f FeishuLogin) GetRedirectURI() string         { return f.RedirectURI }
+func (f FeishuLogin) GetService() string             { return f.Service }
+func (f FeishuLogin) GetSAMLRequest() string         { return f.SAMLRequest }
+func (f FeishuLogin) GetRelayState() string          { return f.RelayState }
+func (f FeishuLogin) GetSigAlg() string              { return f.SigAlg }
+func (f FeishuLogin) GetSignature() string           { return f.Signature }
+func (f FeishuLogin) GetScope() string               { return f.Scope }
+func (f FeishuLogin) GetState() string               { return f.State }
+func (f FeishuLogin) GetNonce() string               { return f.Nonce }
+func (f FeishuLogin) GetAcrValues() string           { return f.AcrValues }
+func (f FeishuLogin) GetResource() string            { return f.Resource }
+func (f FeishuLogin) GetAudience() string            { return f.Audience }
+func (f FeishuLogin) GetCodeChallenge() string       { return f.CodeChallenge }
+func (f FeishuLogin) GetCodeChallengeMethod() string { return f.CodeChallengeMethod }
+func (f FeishuLogin) GetNginxRedirectURI() string    { return f.NginxRedirectURI }
+func (f FeishuLogin) GetWtrealm() string             { return f.Wtrealm }
+func (f FeishuLogin) GetWreply() string              { return f.Wreply }
+func (f FeishuLogin) GetWctx() string                { return f.Wctx }
+
+func (d DingTalkLogin) GetResponseType() string        { return d.ResponseType }
+func (d DingTalkLogin) GetClientId() string            { return d.ClientId }
+func (d DingTalkLogin) GetRedirectURI() string         { return d.RedirectURI }
+func (d DingTalkLogin) GetService() string             { return d.Service }
+func (d DingTalkLogin) GetSAMLRequest() string         { return d.SAMLRequest }
+func (d DingTalkLogin) GetRelayState() string          { return d.RelayState }
+func (d DingTalkLogin) GetSigAlg() string              { return d.SigAlg }
+func (d DingTalkLogin) GetSignature() string           { return d.Signature }
+func (d DingTalkLogin) GetScope() string               { return d.Scope }
+func (d DingTalkLogin) GetState() string               { return d.State }
+func (d DingTalkLogin) GetNonce() string               { return d.Nonce }
+func (d DingTalkLogin) GetAcrValues() string           { return d.AcrValues }
+func (d DingTalkLogin) GetResource() string            { return d.Resource }
+func (d DingTalkLogin) GetAudience() string            { return d.Audience }
+func (d DingTalkLogin) GetCodeChallenge() string       { return d.CodeChallenge }
+func (d DingTalkLogin) GetCodeChallengeMethod() string { return d.CodeChallengeMethod }
+func (d DingTalkLogin) GetNginxRedirectURI() string    { return d.NginxRedirectURI }
+func (d DingTalkLogin) GetWtrealm() string             { return d.Wtrealm }
+func (d DingTalkLogin) GetWreply() string              { return d.Wreply }
+func (d DingTalkLogin) GetWctx() string                { return d.Wctx }
+
+func (w WeChatLogin) GetResponseType() string        { return w.ResponseType }
+func (w WeChatLogin) GetClientId() string            { return w.ClientId }
+func (w WeChatLogin) GetRedirectURI() string         { return w.RedirectURI }
+func (w WeChatLogin) GetService() string             { return w.Service }
+func (w WeChatLogin) GetSAMLRequest() string         { return w.SAMLRequest }
+func (w WeChatLogin) GetRelayState() string          { return w.RelayState }
+func (w WeChatLogin) GetSigAlg() string              { return w.SigAlg }
+func (w WeChatLogin) GetSignature() string           { return w.Signature }
+func (w WeChatLogin) GetScope() string               { return w.Scope }
+func (w WeChatLogin) GetState() string               { return w.State }
+func (w WeChatLogin) GetNonce() string               { return w.Nonce }
+func (w WeChatLogin) GetAcrValues() string           { return w.AcrValues }
+func (w WeChatLogin) GetResource() string            { return w.Resource }
+func (w WeChatLogin) GetAudience() string            { return w.Audience }
+func (w WeChatLogin) GetCodeChallenge() string       { return w.CodeChallenge }
+func (w WeChatLogin) GetCodeChallengeMethod() string { return w.CodeChallengeMethod }
+func (w WeChatLogin) GetNginxRedirectURI() string    { return w.NginxRedirectURI }
+func (w WeChatLogin) GetWtrealm() string             { return w.Wtrealm }
+func (w WeChatLogin) GetWreply() string              { return w.Wreply }
+func (w WeChatLogin) GetWctx() string                { return w.Wctx }
+
+func (u UserLogin) GetResponseType() string        { return u.ResponseType }
+func (u UserLogin) GetClientId() string            { return u.ClientId }
+func (u UserLogin) GetRedirectURI() string         { return u.RedirectURI }
+func (u UserLogin) GetService() string             { return u.Service }
+func (u UserLogin) GetSAMLRequest() string         { return u.SAMLRequest }
+func (u UserLogin) GetRelayState() string          { return u.RelayState }
+func (u UserLogin) GetSigAlg() string              { return u.SigAlg }
+func (u UserLogin) GetSignature() string           { return u.Signature }
+func (u UserLogin) GetScope() string               { return u.Scope }
+func (u UserLogin) GetState() string               { return u.State }
+func (u UserLogin) GetNonce() string               { return u.Nonce }
+func (u UserLogin) GetAcrValues() string           { return u.AcrValues }
+func (u UserLogin) GetResource() string            { return u.Resource }
+func (u UserLogin) GetAudience() string            { return u.Audience }
+func (u UserLogin) GetCodeChallenge() string       { return u.CodeChallenge }
+func (u UserLogin) GetCodeChallengeMethod() string { return u.CodeChallengeMethod }
+func (u UserLogin) GetNginxRedirectURI() string    { return u.NginxRedirectURI }
+func (u UserLogin) GetWtrealm() string             { return u.Wtrealm }
+func (u UserLogin) GetWreply() string              { return u.Wreply }
+func (u UserLogin) GetWctx() string                { return u.Wctx }
+
+// AvatarUploadURL 头像临时上传链接
+type AvatarUploadURL struct {
+	URL       string `json:"url"`        // 临时上传链接，客户端使用PUT方法直接上传到OSS
+	Object    string `json:"object"`     // 对象路径，上传完成后提交到头像更新接口
+	ExpiresIn int    `json:"expires_in"` // 链接有效期（秒）
+}
+
+// AvatarUpdate 头像更新
+type AvatarUpdate struct {
+	Object string `json:"object" binding:"required"`
+}
+
+// LanguageUpdate 首选语言更新
+type LanguageUpdate struct {
+	Language string `json:"language" binding:"required"`
+}
+
+// TimezoneUpdate 时区更新，为空时使用系统时区
+type TimezoneUpdate struct {
+	Timezone string `json:"timezone"`
+}
 
 // ValidateCode 获取校验码
 type ValidateCode struct {
@@ -172,6 +282,7 @@ type RestPassword struct {
 	Username     string `json:"username" binding:"required"`
 	ValidateType uint   `json:"validate_type" binding:"required"` // 验证类型：1：短信验证码，2：MFA验证码
 	Code         string `json:"code" binding:"required"`
+	ResetToken   string `json:"reset_token"` // 获取验证码时返回的重置令牌，短信及邮箱验证码必填
 	Password     string `json:"password" binding:"required"`
 	RePassword   string `json:"re_password" binding:"required"`
 }
@@ -206,6 +317,12 @@ func (u *user) GetUser(userid uint) (user *dao.UserInfoWithMenu, err error) {
 // AddUser 创建用户
 func (u *user) AddUser(data *dao.UserCreate) (authUser *model.AuthUser, err error) {
 
+	// 标准化手机号
+	var phoneNational, phoneDisplay string
+	if err := normalizeUserPhone(&data.PhoneNumber, &phoneNational, &phoneDisplay); err != nil {
+		return nil, err
+	}
+
 	// 字段校验
 	validate := validator.New()
 	// 注册自定义检验方法
@@ -223,7 +340,7 @@ func (u *user) AddUser(data *dao.UserCreate) (authUser *model.AuthUser, err erro
 
 	// 获取密码有效期
 	currentTime := time.Now()
-	passwordExpiredAtDays := config.Conf.Settings["passwordExpireDays"].(int)
+	passwordExpiredAtDays := config.GetInt("passwordExpireDays")
 	passwordExpiredAt := currentTime.AddDate(0, 0, passwordExpiredAtDays)
 
 	user := &model.AuthUser{
@@ -231,13 +348,32 @@ func (u *user) AddUser(data *dao.UserCreate) (authUser *model.AuthUser, err erro
 		Username:          data.Username,
 		Password:          data.Password,
 		PhoneNumber:       data.PhoneNumber,
+		PhoneNational:     phoneNational,
+		PhoneDisplay:      phoneDisplay,
 		IsActive:          true,
 		Email:             data.Email,
 		UserFrom:          data.UserFrom,
+		Department:        data.Department,
+		Title:             data.Title,
+		RequiredActions:   RequiredAction.Initial(),
 		PasswordExpiredAt: &passwordExpiredAt,
 	}
 
-	return dao.User.AddUser(user)
+	result, err := dao.User.AddUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// 根据自动分配规则分配分组及站点
+	ProvisionRule.ApplyToUser(result)
+
+	// 根据用户属性计算动态分组
+	DynamicGroup.SyncUser(result)
+
+	// 推送至下游系统
+	ProvisionWebhook.PublishUser(ProvisionEventUserCreated, result)
+
+	return result, nil
 }
 
 // DeleteUser 删除
@@ -279,12 +415,23 @@ func (u *user) DeleteUser(id int) (err error) {
 		return err
 	}
 
+	// 清除用户信息缓存
+	dao.User.ClearUserInfoCache(user.ID)
+
+	// 推送至下游系统
+	ProvisionWebhook.PublishUser(ProvisionEventUserDeleted, user)
+
 	return nil
 }
 
 // UpdateUser 更新
 func (u *user) UpdateUser(data *dao.UserUpdate) (*model.AuthUser, error) {
 
+	// 标准化手机号
+	if err := normalizeUserPhone(&data.PhoneNumber, &data.PhoneNational, &data.PhoneDisplay); err != nil {
+		return nil, err
+	}
+
 	// 字段校验
 	validate := validator.New()
 	// 注册自定义检验方法
@@ -301,7 +448,18 @@ func (u *user) UpdateUser(data *dao.UserUpdate) (*model.AuthUser, error) {
 		return nil, err
 	}
 
-	return dao.User.UpdateUser(user, data)
+	result, err := dao.User.UpdateUser(user, data)
+	if err != nil {
+		return nil, err
+	}
+
+	// 根据用户属性重新计算动态分组
+	DynamicGroup.SyncUser(result)
+
+	// 推送至下游系统
+	ProvisionWebhook.PublishUser(ProvisionEventUserUpdated, result)
+
+	return result, nil
 }
 
 // UpdateUserPassword 更改密码
@@ -331,7 +489,7 @@ func (u *user) UpdateUserPassword(data *dao.UserPasswordUpdate) (err error) {
 }
 
 // ResetUserMFA 重置MFA
-func (u *user) ResetUserMFA(id int) error {
+func (u *user) ResetUserMFA(id int, operator string) error {
 
 	// 查询要重置的用户
 	user := &model.AuthUser{}
@@ -339,32 +497,133 @@ func (u *user) ResetUserMFA(id int) error {
 		return err
 	}
 
-	return dao.User.ResetUserMFA(user)
+	if err := dao.User.ResetUserMFA(user); err != nil {
+		return err
+	}
+
+	// 通知安全管理员
+	SecurityEvent.Publish(SecurityEventMFAReset, operator, user.Username, "用户MFA已被重置，下次登录需重新绑定")
+	return nil
+}
+
+// GetAvatarUploadURL 获取头像临时上传链接，头像不再经过服务端中转
+func (u *user) GetAvatarUploadURL(username, filename string) (*AvatarUploadURL, error) {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !avatarExtensions[ext] {
+		return nil, errors.New("头像仅支持png、jpg、jpeg、gif、webp格式")
+	}
+
+	// 头像存储的路径和文件名：avatar/<用户名><文件后缀>
+	object := fmt.Sprintf("avatar/%s%s", username, ext)
+	uploadURL, err := utils.GetPresignedPutURL(object, avatarUploadURLExpires)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AvatarUploadURL{
+		URL:       uploadURL.String(),
+		Object:    object,
+		ExpiresIn: int(avatarUploadURLExpires.Seconds()),
+	}, nil
+}
+
+// UpdateAvatar 客户端通过临时链接上传完成后更新头像
+func (u *user) UpdateAvatar(username string, data *AvatarUpdate) error {
+	// 只允许使用当前用户的头像路径
+	ext := strings.ToLower(filepath.Ext(data.Object))
+	if data.Object != fmt.Sprintf("avatar/%s%s", username, ext) || !avatarExtensions[ext] {
+		return errors.New("头像路径不合法")
+	}
+
+	// 校验对象是否已上传
+	if _, err := utils.StatObject(data.Object); err != nil {
+		return errors.New("头像未上传或已过期")
+	}
+
+	// 客户端直接上传至OSS，需下载后校验并使用处理后的图片覆盖
+	object, err := utils.GetObject(data.Object)
+	if err != nil {
+		return err
+	}
+	avatar, err := Upload.Image(data.Object, object)
+	_ = object.Close()
+	if err == nil && avatar.Ext != ext && !(avatar.Ext == ".jpg" && ext == ".jpeg") {
+		err = errors.New("头像格式与文件后缀不一致")
+	}
+	if err != nil {
+		_ = utils.RemoveObject(data.Object)
+		return err
+	}
+	if err := utils.FileUpload(data.Object, avatar.ContentType, bytes.NewReader(avatar.Data), int64(len(avatar.Data))); err != nil {
+		return err
+	}
+
+	return dao.User.UpdateUserAvatar(username, data.Object)
+}
+
+// UploadAvatar 头像上传，图片经过校验及重新编码后保存；MinIO不可用时暂存头像（queued 为 true），恢复后自动上传并更新
+func (u *user) UploadAvatar(username, filename string, file io.Reader) (queued bool, err error) {
+
+	avatar, err := Upload.Image(filename, file)
+	if err != nil {
+		return false, err
+	}
+
+	// 头像存储的路径和文件名：avatar/<用户名><文件后缀>，文件后缀根据图片内容确定
+	object := fmt.Sprintf("avatar/%s%s", username, avatar.Ext)
+	if err := utils.FileUpload(object, avatar.ContentType, bytes.NewReader(avatar.Data), int64(len(avatar.Data))); err != nil {
+		if !isStorageUnavailable(err) {
+			return false, err
+		}
+		if err := UploadQueue.enqueue(object, avatar.ContentType, username, avatar.Data); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	return false, dao.User.UpdateUserAvatar(username, object)
+}
+
+// UpdateLanguage 设置首选语言，影响通知邮件、短信及单点登录页面的语言
+func (u *user) UpdateLanguage(username string, data *LanguageUpdate) error {
+	locale := i18n.Normalize(data.Language)
+	if locale == "" {
+		return fmt.Errorf("不支持的语言，可选值：%s", strings.Join(i18n.Locales, "、"))
+	}
+	return dao.User.UpdateUserLanguage(username, locale)
+}
+
+// UpdateTimezone 设置时区，影响通知邮件及个人生成的报告中的时间
+func (u *user) UpdateTimezone(username string, data *TimezoneUpdate) error {
+	if _, err := config.LoadLocation(data.Timezone); err != nil {
+		return fmt.Errorf("无效的时区：%s", data.Timezone)
+	}
+	return dao.User.UpdateUserTimezone(username, data.Timezone)
 }
 
-// GetVerificationCode 获取重置密码短信验证码
-func (u *user) GetVerificationCode(data *ValidateCode) (err error) {
+// GetVerificationCode 获取重置密码验证码，返回与验证码绑定的一次性重置令牌，fingerprint 为请求设备指纹
+func (u *user) GetVerificationCode(data *ValidateCode, fingerprint string) (resetToken string, err error) {
 
 	var (
 		keyName = fmt.Sprintf("%s_rest_password_verification_code", data.Username)
 	)
 
 	// 判断Redis缓存中指定的Key是否存在
-	val, err := global.RedisClient.Exists(keyName).Result()
+	exists, err := global.Cache.Exists(keyName)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	// 已存在
-	if val >= 1 {
+	if exists {
 		// 判断Key的有效期
-		ttl, err := global.RedisClient.TTL(keyName).Result()
+		ttl, err := global.Cache.TTL(keyName)
 		if err != nil {
-			return err
+			return "", err
 		}
 
 		if ttl.Seconds() > 240 {
-			return errors.New(fmt.Sprintf("验证码已发送，请%d秒后重试", int(ttl.Seconds())))
+			return "", NewThrottleError(ttl-240*time.Second, fmt.Sprintf("验证码已发送，请%d秒后重试", int(ttl.Seconds())))
 		}
 	}
 
@@ -374,80 +633,65 @@ func (u *user) GetVerificationCode(data *ValidateCode) (err error) {
 	}
 	userinfo, err := dao.User.GetUser(conditions)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	if userinfo.IsActive == false {
-		return errors.New("用户未激活，请联系管理员")
+		return "", errors.New("用户未激活，请联系管理员")
 	}
 
 	// 发送验证码
 	var code string
 	if data.ValidateType == 1 {
 		if userinfo.PhoneNumber == "" {
-			return errors.New("用户未绑定手机号，请联系管理员")
+			return "", errors.New("用户未绑定手机号，请联系管理员")
 		}
-		number, err := SMS.SMSSend(userinfo.PhoneNumber, "重置密码")
+		number, err := SMS.SMSSend(userinfo.PhoneNumber, "重置密码", userLocale(userinfo))
 		if err != nil {
-			return err
+			return "", err
 		}
 		code = number
 	} else if data.ValidateType == 3 {
 
 		// 判断是否开启此功能
-		if config.Conf.Settings["passwordMailResetOff"].(bool) == false {
-			return errors.New("功能未启用，请联系管理员")
+		if config.GetBool("passwordMailResetOff") == false {
+			return "", errors.New("功能未启用，请联系管理员")
 		}
 
 		if userinfo.Email == "" {
-			return errors.New("用户未绑定邮箱，请联系管理员")
+			return "", errors.New("用户未绑定邮箱，请联系管理员")
 		}
 
 		// 生成HTML内容
 		code = strconv.Itoa(utils.GenerateRandomNumber())
-		htmlBody := RestPasswordHTML(code)
+		locale := userLocale(userinfo)
+		htmlBody := RestPasswordHTML(locale, code)
 
 		// 发送邮件
-		if err := mail.Email.SendMsg([]string{userinfo.Email}, nil, nil, "密码重置", htmlBody, "html"); err != nil {
-			return err
+		if err := mail.Email.SendMsg([]string{userinfo.Email}, nil, nil, i18n.T(locale, "password_reset.subject"), htmlBody, "html"); err != nil {
+			return "", err
 		}
 	} else {
-		return errors.New("验证码类型错误")
+		return "", errors.New("验证码类型错误")
 	}
 
-	// 将验证码写入Redis缓存，如果已存在则会更新Key的值并刷新TTL
-	return global.RedisClient.Set(keyName, code, time.Duration(5)*time.Minute).Err()
+	// 生成一次性重置令牌，重新获取验证码后之前的令牌失效
+	return newPasswordResetToken(keyName, &passwordResetToken{
+		Username:    userinfo.Username,
+		Code:        code,
+		Fingerprint: fingerprint,
+	})
 }
 
 // RestPasswordHTML 密码重置邮件HTML
-func RestPasswordHTML(number string) string {
-
-	issuer := config.Conf.Settings["issuer"].(string)
-
-	return fmt.Sprintf(`
-		<!DOCTYPE html>
-		<html>
-		<head>
-			<meta charset="UTF-8">
-			<title>密码重置</title>
-		</head>
-		<body>
-			<p>您的验证码为：%s，请妥善保管，切勿泄露。</p>
-			<br>
-			<p>此致，<br>%s</p>
-			<p style="color: red">此邮件为系统自动发送，请勿回复此邮件。</p>
-		</body>
-		</html>
-	`, number, issuer)
+func RestPasswordHTML(locale, number string) string {
+	return verificationCodeHTML(locale, i18n.T(locale, "password_reset.subject"), number)
 }
 
-// UpdateSelfPassword 用户重置密码
-func (u *user) UpdateSelfPassword(data *RestPassword) (err error) {
+// UpdateSelfPassword 用户重置密码，重置成功后注销用户的所有会话并通知用户
+func (u *user) UpdateSelfPassword(data *RestPassword, fingerprint, clientIP string) (err error) {
 
-	var (
-		user    model.AuthUser
-		keyName = fmt.Sprintf("%s_rest_password_verification_code", data.Username)
-	)
+	var user model.AuthUser
 
 	// 获取用户信息
 	if err := global.MySQLClient.First(&user, "username", data.Username).Error; err != nil {
@@ -457,27 +701,31 @@ func (u *user) UpdateSelfPassword(data *RestPassword) (err error) {
 	// 验证码校验
 	if data.ValidateType == 1 || data.ValidateType == 3 {
 
-		// 从缓存中获取验证码
-		result, err := global.RedisClient.Get(keyName).Result()
-		if err != nil {
+		// 校验并使用重置令牌，令牌仅能在获取验证码的设备上使用一次
+		if err := usePasswordResetToken(data.ResetToken, data.Username, data.Code, fingerprint); err != nil {
 			return err
 		}
-		// 判断是否正确
-		if result != data.Code {
-			return errors.New("校验码错误")
-		}
 	} else if data.ValidateType == 2 {
 		// MFA验证码校验
 
 		// 获取Secret
 		if user.MFACode == nil {
-			return errors.New("您还未绑定MFA")
+			return errors.New(i18n.T(userLocale(&user), "mfa.not_bound"))
 		}
 
 		// 校验MFA
 		valid := totp.Validate(data.Code, *user.MFACode)
 		if !valid {
-			return errors.New("校验码错误")
+			return errors.New(i18n.T(userLocale(&user), "mfa.invalid_code"))
+		}
+
+		// 同一动态口令仅能使用一次
+		ok, err := global.Cache.SetNX(fmt.Sprintf("reset_password_otp:%s:%s", user.Username, data.Code), "1", 90*time.Second)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New(i18n.T(userLocale(&user), "mfa.invalid_code"))
 		}
 	} else {
 		return errors.New("验证码类型错误")
@@ -489,11 +737,23 @@ func (u *user) UpdateSelfPassword(data *RestPassword) (err error) {
 		Password:   data.Password,
 		RePassword: data.RePassword,
 	}
-	return u.UpdateUserPassword(userInfo)
+	if err := u.UpdateUserPassword(userInfo); err != nil {
+		return err
+	}
+
+	// 注销用户已登录的所有会话
+	if err := Session.RevokeUser(user.ID); err != nil {
+		logger.Error("ERROR：密码重置后注销会话失败，", err.Error())
+	}
+
+	// 通知用户密码已重置
+	passwordResetNotice(&user, clientIP)
+
+	return nil
 }
 
 // DingTalkLogin 钉钉扫码认证
-func (u *user) DingTalkLogin(params *DingTalkLogin) (token, redirectUri, username, application string, err error) {
+func (u *user) DingTalkLogin(params *DingTalkLogin, clientIP string) (token, redirectUri, username, application string, err error) {
 
 	// 初始化钉钉客户端
 	dingClient, err := NewDingTalkClient()
@@ -513,10 +773,20 @@ func (u *user) DingTalkLogin(params *DingTalkLogin) (token, redirectUri, usernam
 		return "", "", "", "", err
 	}
 
+	// 钉钉返回的手机号不含国际区号，按国际区号标准化后匹配
+	mobile := tea.StringValue(userInfo.Body.Mobile)
+	if stateCode := tea.StringValue(userInfo.Body.StateCode); stateCode != "" {
+		mobile = "+" + stateCode + mobile
+	}
+	phone, err := normalizePhone(mobile)
+	if err != nil {
+		return "", "", "", "", err
+	}
+
 	// 定义用户匹配条件
 	conditions := map[string]interface{}{
 		"name":         userInfo.Body.Nick,
-		"phone_number": userInfo.Body.Mobile,
+		"phone_number": phone.E164,
 	}
 
 	// 在本地数据库中查找匹配的用户
@@ -530,6 +800,11 @@ func (u *user) DingTalkLogin(params *DingTalkLogin) (token, redirectUri, usernam
 		return "", "", "", "", errors.New("拒绝登录，请联系管理员")
 	}
 
+	// 应急账号仅允许使用账号密码及MFA登录
+	if user.BreakGlass {
+		return "", "", user.Username, "", errors.New("拒绝登录，请联系管理员")
+	}
+
 	// 判断密码是否过期
 	if user.PasswordExpiredAt != nil {
 		now := time.Now()
@@ -538,15 +813,31 @@ func (u *user) DingTalkLogin(params *DingTalkLogin) (token, redirectUri, usernam
 		}
 	}
 
+	// 登录策略检查（扫码登录已在移动端完成身份确认，命中MFA策略时不再要求MFA认证）
+	if _, err := LoginPolicy.CheckLoginPolicy(user, clientIP); err != nil {
+		return "", "", user.Username, "", err
+	}
+
+	// 执行身份认证通过后及签发Token前的登录扩展
+	for _, stage := range []hook.Stage{hook.PostAuth, hook.PreToken} {
+		if err := LoginHook.Run(newHookContext(stage, "钉钉扫码", user, clientIP, params)); err != nil {
+			return "", "", user.Username, "", err
+		}
+	}
+
 	// 生成用户Token
-	token, err = middleware.GenerateJWT(user.ID, user.Name, user.Username)
+	token, sessionId, err := middleware.GenerateJWT(user.ID, user.Name, user.Username, []string{middleware.AMRMultiChannel})
 	if err != nil {
 		return "", "", "", "", err
 	}
 
 	// 处理单点登录请求
-	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" {
-		callbackData, siteName, err := SSO.Login(params, *user)
+	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" || params.Wtrealm != "" {
+		callbackData, siteName, err := SSO.Login(params, *user, sessionId)
+		if termsPending(err) || consentPending(err) {
+			// 需要先接受使用条款或确认授权，登录成功后由用户重新访问应用时处理
+			return token, "", user.Username, siteName, nil
+		}
 		if err != nil {
 			return "", "", "", siteName, err
 		}
@@ -558,7 +849,7 @@ func (u *user) DingTalkLogin(params *DingTalkLogin) (token, redirectUri, usernam
 }
 
 // FeishuLogin 飞书扫码认证
-func (u *user) FeishuLogin(params *FeishuLogin) (token, redirectUri, username, application string, err error) {
+func (u *user) FeishuLogin(params *FeishuLogin, clientIP string) (token, redirectUri, username, application string, err error) {
 
 	client, err := NewFeishuClient()
 	if err != nil {
@@ -574,11 +865,16 @@ func (u *user) FeishuLogin(params *FeishuLogin) (token, redirectUri, username, a
 	// 获取用户信息
 	userinfo, err := client.GetUserInfo(*resp.Data.AccessToken)
 
+	// 飞书返回的手机号包含国际区号，如：+8613800000000，标准化后匹配
+	phone, err := normalizePhone(*userinfo.Data.Mobile)
+	if err != nil {
+		return "", "", "", "", err
+	}
+
 	// 定义用户匹配条件
-	mobile := *userinfo.Data.Mobile
 	conditions := map[string]interface{}{
 		"name":         *userinfo.Data.Name,
-		"phone_number": mobile[3:],
+		"phone_number": phone.E164,
 		//"email":        *userinfo.Data.Email,
 	}
 
@@ -593,6 +889,11 @@ func (u *user) FeishuLogin(params *FeishuLogin) (token, redirectUri, username, a
 		return "", "", "", "", errors.New("拒绝登录，请联系管理员")
 	}
 
+	// 应急账号仅允许使用账号密码及MFA登录
+	if user.BreakGlass {
+		return "", "", user.Username, "", errors.New("拒绝登录，请联系管理员")
+	}
+
 	// 判断密码是否过期
 	if user.PasswordExpiredAt != nil {
 		now := time.Now()
@@ -601,15 +902,31 @@ func (u *user) FeishuLogin(params *FeishuLogin) (token, redirectUri, username, a
 		}
 	}
 
+	// 登录策略检查（扫码登录已在移动端完成身份确认，命中MFA策略时不再要求MFA认证）
+	if _, err := LoginPolicy.CheckLoginPolicy(user, clientIP); err != nil {
+		return "", "", user.Username, "", err
+	}
+
+	// 执行身份认证通过后及签发Token前的登录扩展
+	for _, stage := range []hook.Stage{hook.PostAuth, hook.PreToken} {
+		if err := LoginHook.Run(newHookContext(stage, "飞书扫码", user, clientIP, params)); err != nil {
+			return "", "", user.Username, "", err
+		}
+	}
+
 	// 生成用户Token
-	token, err = middleware.GenerateJWT(user.ID, user.Name, user.Username)
+	token, sessionId, err := middleware.GenerateJWT(user.ID, user.Name, user.Username, []string{middleware.AMRMultiChannel})
 	if err != nil {
 		return "", "", "", "", err
 	}
 
 	// 处理单点登录请求
-	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" {
-		callbackData, siteName, err := SSO.Login(params, *user)
+	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" || params.Wtrealm != "" {
+		callbackData, siteName, err := SSO.Login(params, *user, sessionId)
+		if termsPending(err) || consentPending(err) {
+			// 需要先接受使用条款或确认授权，登录成功后由用户重新访问应用时处理
+			return token, "", user.Username, siteName, nil
+		}
 		if err != nil {
 			return "", "", "", siteName, err
 		}
@@ -621,7 +938,7 @@ func (u *user) FeishuLogin(params *FeishuLogin) (token, redirectUri, username, a
 }
 
 // WeChatLogin 企业微信扫码认证
-func (u *user) WeChatLogin(params *WeChatLogin) (token, redirectUri, username, application string, err error) {
+func (u *user) WeChatLogin(params *WeChatLogin, clientIP string) (token, redirectUri, username, application string, err error) {
 
 	wechatClient, err := NewWeChatClient()
 	if err != nil {
@@ -650,6 +967,11 @@ func (u *user) WeChatLogin(params *WeChatLogin) (token, redirectUri, username, a
 		return "", "", "", "", errors.New("拒绝登录，请联系管理员")
 	}
 
+	// 应急账号仅允许使用账号密码及MFA登录
+	if user.BreakGlass {
+		return "", "", user.Username, "", errors.New("拒绝登录，请联系管理员")
+	}
+
 	// 判断密码是否过期
 	if user.PasswordExpiredAt != nil {
 		now := time.Now()
@@ -658,15 +980,31 @@ func (u *user) WeChatLogin(params *WeChatLogin) (token, redirectUri, username, a
 		}
 	}
 
+	// 登录策略检查（扫码登录已在移动端完成身份确认，命中MFA策略时不再要求MFA认证）
+	if _, err := LoginPolicy.CheckLoginPolicy(user, clientIP); err != nil {
+		return "", "", user.Username, "", err
+	}
+
+	// 执行身份认证通过后及签发Token前的登录扩展
+	for _, stage := range []hook.Stage{hook.PostAuth, hook.PreToken} {
+		if err := LoginHook.Run(newHookContext(stage, "企业微信扫码", user, clientIP, params)); err != nil {
+			return "", "", user.Username, "", err
+		}
+	}
+
 	// 生成用户Token
-	token, err = middleware.GenerateJWT(user.ID, user.Name, user.Username)
+	token, sessionId, err := middleware.GenerateJWT(user.ID, user.Name, user.Username, []string{middleware.AMRMultiChannel})
 	if err != nil {
 		return "", "", "", "", err
 	}
 
 	// 处理单点登录请求
-	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" {
-		callbackData, siteName, err := SSO.Login(params, *user)
+	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" || params.Wtrealm != "" {
+		callbackData, siteName, err := SSO.Login(params, *user, sessionId)
+		if termsPending(err) || consentPending(err) {
+			// 需要先接受使用条款或确认授权，登录成功后由用户重新访问应用时处理
+			return token, "", user.Username, siteName, nil
+		}
 		if err != nil {
 			return "", "", "", siteName, err
 		}
@@ -678,10 +1016,15 @@ func (u *user) WeChatLogin(params *WeChatLogin) (token, redirectUri, username, a
 }
 
 // Login 用户登录（支持CAS3.0、OAuth2.0、OIDC、SAML2和Nginx拦截）
-func (u *user) Login(params *UserLogin) (token, redirectUri, application string, mfaPage *string, err error) {
+func (u *user) Login(params *UserLogin, clientIP string) (token, redirectUri, application string, mfaPage *string, err error) {
 
 	var user model.AuthUser
 
+	// 执行凭据校验前的登录扩展
+	if err := LoginHook.Run(newHookContext(hook.PreAuth, "账号密码", &model.AuthUser{Username: params.Username}, clientIP, params)); err != nil {
+		return "", "", "", nil, err
+	}
+
 	// 用户认证
 	if err := u.AuthenticateUser(params, &user); err != nil {
 		return "", "", "", nil, err
@@ -692,6 +1035,18 @@ func (u *user) Login(params *UserLogin) (token, redirectUri, application string,
 		return "", "", "", nil, errors.New("拒绝登录，请联系管理员")
 	}
 
+	// 应急账号仅允许从指定网络登录且必须进行MFA认证，不执行密码过期、登录策略及登录扩展检查，避免上游服务故障时无法登录
+	if user.BreakGlass {
+		if err := BreakGlass.Check(&user, clientIP); err != nil {
+			return "", "", "", nil, err
+		}
+		token, nextPage, err := handleMFA(user)
+		if err != nil {
+			return "", "", "", nil, err
+		}
+		return token, "", "", nextPage, nil
+	}
+
 	// 判断密码是否过期
 	if user.PasswordExpiredAt != nil {
 		now := time.Now()
@@ -700,9 +1055,39 @@ func (u *user) Login(params *UserLogin) (token, redirectUri, application string,
 		}
 	}
 
-	// 判断系统是否启用MFA认证
-	mfaEnable := config.Conf.Settings["mfa"].(bool)
-	if mfaEnable {
+	// 登录策略检查，命中MFA策略时即使系统未启用MFA认证也需要进行MFA认证
+	mfaRequired, err := LoginPolicy.CheckLoginPolicy(&user, clientIP)
+	if err != nil {
+		return "", "", "", nil, err
+	}
+	if mfaRequired && user.MFACode == nil {
+		return "", "", "", nil, errors.New("当前网络环境需要MFA认证，请先在可信网络中绑定MFA")
+	}
+
+	// 执行身份认证通过后的登录扩展
+	if err := LoginHook.Run(newHookContext(hook.PostAuth, "账号密码", &user, clientIP, params)); err != nil {
+		return "", "", "", nil, err
+	}
+
+	// 首次登录需要完成的操作（修改初始密码、验证邮箱等），已绑定MFA且需要MFA认证时在MFA认证通过后执行
+	if RequiredAction.Required(&user) && (user.MFACode == nil || !(config.GetBool("mfa") || mfaRequired)) {
+		token, nextPage, err := RequiredAction.Begin(&user, false)
+		if err != nil {
+			return "", "", "", nil, err
+		}
+		return token, "", "", nextPage, nil
+	}
+
+	return u.completeLogin(params, user, clientIP, mfaRequired, false)
+}
+
+// completeLogin 完成账号密码登录，需要MFA认证时返回MFA页面，否则签发Token并处理单点登录请求，mfa表示用户已通过MFA认证
+func (u *user) completeLogin(params *UserLogin, user model.AuthUser, clientIP string, mfaRequired, mfa bool) (token, redirectUri, application string, mfaPage *string, err error) {
+
+	// 判断系统是否启用MFA认证，首次登录需要绑定MFA时即使系统未启用MFA认证也需要绑定
+	_, mfaEnroll := RequiredAction.pending(&user)
+	mfaEnable := config.GetBool("mfa")
+	if !mfa && (mfaEnable || mfaRequired || mfaEnroll) {
 		token, nextPage, err := handleMFA(user)
 		if err != nil {
 			return "", "", "", nil, err
@@ -712,15 +1097,34 @@ func (u *user) Login(params *UserLogin) (token, redirectUri, application string,
 		}
 	}
 
+	// 执行签发Token前的登录扩展
+	if err := LoginHook.Run(newHookContext(hook.PreToken, "账号密码", &user, clientIP, params)); err != nil {
+		return "", "", "", nil, err
+	}
+
 	// 生成用户Token
-	token, err = middleware.GenerateJWT(user.ID, user.Name, user.Username)
+	amr := []string{middleware.AMRPassword}
+	if mfa {
+		amr = []string{middleware.AMRPassword, middleware.AMROTP, middleware.AMRMultiFactor}
+	}
+	token, sessionId, err := middleware.GenerateJWT(user.ID, user.Name, user.Username, amr)
 	if err != nil {
 		return "", "", "", nil, err
 	}
 
 	// 处理单点登录请求
-	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" || params.NginxRedirectURI != "" {
-		callbackData, siteName, err := SSO.Login(params, user)
+	if params.SAMLRequest != "" || params.Service != "" || params.ClientId != "" || params.NginxRedirectURI != "" || params.Wtrealm != "" {
+		callbackData, siteName, err := SSO.Login(params, user, sessionId)
+		if termsPending(err) {
+			// 需要先接受使用条款，返回用户Token，由前端跳转至条款页面
+			page := TermsAcceptPage
+			return token, "", siteName, &page, nil
+		}
+		if consentPending(err) {
+			// 需要先确认授权，返回用户Token，由前端跳转至授权确认页面
+			page := ConsentPage
+			return token, "", siteName, &page, nil
+		}
 		if err != nil {
 			return "", "", siteName, nil, err
 		}
@@ -766,7 +1170,7 @@ func (u *user) AuthenticateUser(params *UserLogin, user *model.AuthUser) error {
 	return nil
 }
 
-// RecordLoginInfo 记录用户登录信息
+// RecordLoginInfo 记录用户登录信息，登录成功时记录登录的国家/地区
 func (u *user) RecordLoginInfo(loginMethod, username, userAgent, clientIP, application string, failedReason error) error {
 
 	// 开启事务
@@ -806,6 +1210,13 @@ func (u *user) RecordLoginInfo(loginMethod, username, userAgent, clientIP, appli
 		return err
 	}
 
+	// 记录登录的国家/地区，新国家/地区登录时通知用户，记录失败不影响用户登录
+	if failedReason == nil && user != nil {
+		if err := Device.RecordCountry(user, userAgent, clientIP); err != nil {
+			logger.Error("登录地区记录失败：" + err.Error())
+		}
+	}
+
 	return nil
 }
 
@@ -816,7 +1227,7 @@ func handleMFA(user model.AuthUser) (temToken string, nextPage *string, err erro
 	token := utils.GenerateRandomString(32)
 
 	// 将token写入Redis缓存，并设置有效期为2分钟（这里的时间和前端配置的定时器保持一至）
-	if err := global.RedisClient.Set(token, user.Username, 2*time.Minute).Err(); err != nil {
+	if err := global.Cache.Set(token, user.Username, 2*time.Minute); err != nil {
 		return "", nil, err
 	}
 
@@ -848,13 +1259,14 @@ func (u *user) PasswordExpiredNotice() (map[string]interface{}, error) {
 	for _, user := range users {
 
 		// 格式化密码过期时间
-		expiredAt := user.PasswordExpiredAt.Format("2006-01-02 15:04:05")
+		expiredAt := utils.FormatTimeIn(user.PasswordExpiredAt, timezoneLocation(user.Timezone))
 
 		// 生成HTML内容
-		htmlBody := passwordExpiredNoticeHTML(user.Name, user.Username, expiredAt)
+		locale := languageLocale(user.Language)
+		htmlBody := passwordExpiredNoticeHTML(locale, user.Username, expiredAt)
 
 		// 发送邮件函数
-		if err := mail.Email.SendMsg([]string{user.Email}, nil, nil, "密码过期提醒", htmlBody, "html"); err != nil {
+		if err := mail.Email.SendMsg([]string{user.Email}, nil, nil, i18n.T(locale, "password_expire.subject"), htmlBody, "html"); err != nil {
 			// 添加到失败列表
 			result["failed"] = append(result["failed"].([]map[string]string), map[string]string{
 				"email": user.Email,
@@ -870,11 +1282,11 @@ func (u *user) PasswordExpiredNotice() (map[string]interface{}, error) {
 }
 
 // passwordExpiredNoticeHTML 密码过期通知正文
-func passwordExpiredNoticeHTML(name, username, expiredAt string) string {
+func passwordExpiredNoticeHTML(locale, username, expiredAt string) string {
 
 	var (
-		externalUrl = config.Conf.Settings["externalUrl"].(string)
-		issuer      = config.Conf.Settings["issuer"].(string)
+		externalUrl = config.GetString("externalUrl")
+		issuer      = config.GetString("issuer")
 	)
 
 	url := externalUrl
@@ -886,19 +1298,25 @@ func passwordExpiredNoticeHTML(name, username, expiredAt string) string {
 
 	return fmt.Sprintf(`
 		<!DOCTYPE html>
-		<html>
+		<html lang="%s">
 		<head>
 			<meta charset="UTF-8">
-			<title>密码到期提醒</title>
+			<title>%s</title>
 		</head>
 		<body>
-			<p>亲爱的同事：</p>
-			<p>您好，目前检测到您的账户（<strong>%s</strong>）密码在 <strong>%s</strong> 过期。</p>
-			<p>为了保护您的账号安全，请及时登录【<a href="%s" target="_blank">密码修改自助平台</a>】修改密码，逾期未修改则会导致您的账户无法登录到相关的平台。</p>
+			<p>%s</p>
+			<p>%s</p>
+			<p>%s</p>
 			<br>
-			<p>此致，<br>%s</p>
-			<p style="color: red">此邮件为系统自动发送，请不要回复此邮件。</p>
+			<p>%s</p>
+			<p style="color: red">%s</p>
 		</body>
 		</html>
-	`, username, expiredAt, resetPasswordURL, issuer)
+	`, locale,
+		i18n.T(locale, "password_expire.subject"),
+		i18n.T(locale, "password_expire.greeting"),
+		i18n.T(locale, "password_expire.body", html.EscapeString(username), expiredAt),
+		i18n.T(locale, "password_expire.action", resetPasswordURL),
+		i18n.T(locale, "mail.signature", html.EscapeString(issuer)),
+		i18n.T(locale, "mail.no_reply"))
 }
@@ -16,10 +16,10 @@ type WechatClient struct {
 func NewWeChatClient() (*WechatClient, error) {
 
 	var (
-		wechatCorpId  = config.Conf.Settings["wechatCorpId"].(string)
-		wechatAgentId = config.Conf.Settings["wechatAgentId"].(int)
-		wechatSecret  = config.Conf.Settings["wechatSecret"].(string)
-		externalUrl   = config.Conf.Settings["externalUrl"].(string)
+		wechatCorpId  = config.GetString("wechatCorpId")
+		wechatAgentId = config.GetInt("wechatAgentId")
+		wechatSecret  = config.GetString("wechatSecret")
+		externalUrl   = config.GetString("externalUrl")
 	)
 
 	// 解密
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/ma314smith/signedxml"
+	"net/url"
+	"ops-api/config"
+	"ops-api/dao"
+	"ops-api/global"
+	"ops-api/model"
+	"ops-api/utils"
+	"sort"
+	"strings"
+	"time"
+)
+
+var wsFedPostFormTemplate = utils.GenerateWsFedResponsePostForm()
+
+const (
+	wsFedSAMLNamespace  = "urn:oasis:names:tc:SAML:1.0:assertion"
+	wsFedDSigNamespace  = "http://www.w3.org/2000/09/xmldsig#"
+	wsFedTokenType      = "urn:oasis:names:tc:SAML:1.0:assertion"
+	wsFedClaimNamespace = "http://schemas.xmlsoap.org/ws/2005/05/identity/claims"
+	wsFedRoleClaim      = "http://schemas.microsoft.com/ws/2008/06/identity/claims/role"
+	wsFedTimeFormat     = "2006-01-02T15:04:05.000Z"
+)
+
+// wsFedDefaultClaims 站点未配置声明映射时默认下发的声明
+var wsFedDefaultClaims = map[string]string{
+	wsFedClaimNamespace + "/name":         "username",
+	wsFedClaimNamespace + "/upn":          "email",
+	wsFedClaimNamespace + "/emailaddress": "email",
+	wsFedClaimNamespace + "/givenname":    "name",
+	wsFedRoleClaim:                        "groups",
+}
+
+// WsFedSignIn WS-Fed客户端登录请求参数
+type WsFedSignIn struct {
+	Wa      string `json:"wa" form:"wa"`                              // 操作类型，固定值：wsignin1.0
+	Wtrealm string `json:"wtrealm" form:"wtrealm" binding:"required"` // RP标识
+	Wreply  string `json:"wreply" form:"wreply"`                      // RP回调地址，为空时使用站点回调地址
+	Wctx    string `json:"wctx" form:"wctx"`                          // RP的状态信息，原样返回
+}
+
+// WsFedResponse IDP返回给浏览器的WS-Fed登录响应数据
+type WsFedResponse struct {
+	PostFormText
+	URL     string
+	Wresult string
+	Wctx    string
+}
+
+// SAML 1.1 Assertion数据结构体
+type wsFedAssertion struct {
+	XMLName                 xml.Name                     `xml:"saml:Assertion"`
+	Xmlns                   string                       `xml:"xmlns:saml,attr"`
+	MajorVersion            string                       `xml:"MajorVersion,attr"`
+	MinorVersion            string                       `xml:"MinorVersion,attr"`
+	AssertionID             string                       `xml:"AssertionID,attr"`
+	Issuer                  string                       `xml:"Issuer,attr"`
+	IssueInstant            string                       `xml:"IssueInstant,attr"`
+	Conditions              wsFedConditions              `xml:"saml:Conditions"`
+	AttributeStatement      wsFedAttributeStatement      `xml:"saml:AttributeStatement"`
+	AuthenticationStatement wsFedAuthenticationStatement `xml:"saml:AuthenticationStatement"`
+	Signature               wsFedSignature               `xml:"ds:Signature"`
+}
+type wsFedConditions struct {
+	NotBefore                    string `xml:"NotBefore,attr"`
+	NotOnOrAfter                 string `xml:"NotOnOrAfter,attr"`
+	AudienceRestrictionCondition struct {
+		Audience string `xml:"saml:Audience"`
+	} `xml:"saml:AudienceRestrictionCondition"`
+}
+type wsFedSubject struct {
+	NameIdentifier      string `xml:"saml:NameIdentifier"`
+	SubjectConfirmation struct {
+		ConfirmationMethod string `xml:"saml:ConfirmationMethod"`
+	} `xml:"saml:SubjectConfirmation"`
+}
+type wsFedAttributeStatement struct {
+	Subject    wsFedSubject      `xml:"saml:Subject"`
+	Attributes []wsFedAttributes `xml:"saml:Attribute"`
+}
+type wsFedAttributes struct {
+	AttributeName      string   `xml:"AttributeName,attr"`
+	AttributeNamespace string   `xml:"AttributeNamespace,attr"`
+	AttributeValues    []string `xml:"saml:AttributeValue"`
+}
+type wsFedAuthenticationStatement struct {
+	AuthenticationMethod  string       `xml:"AuthenticationMethod,attr"`
+	AuthenticationInstant string       `xml:"AuthenticationInstant,attr"`
+	Subject               wsFedSubject `xml:"saml:Subject"`
+}
+
+// XML签名模板，DigestValue和SignatureValue由signedxml计算后填充
+type wsFedSignature struct {
+	Xmlns      string `xml:"xmlns:ds,attr"`
+	SignedInfo struct {
+		CanonicalizationMethod wsFedAlgorithm `xml:"ds:CanonicalizationMethod"`
+		SignatureMethod        wsFedAlgorithm `xml:"ds:SignatureMethod"`
+		Reference              struct {
+			URI        string           `xml:"URI,attr"`
+			Transforms []wsFedAlgorithm `xml:"ds:Transforms>ds:Transform"`
+			Digest     wsFedAlgorithm   `xml:"ds:DigestMethod"`
+			Value      string           `xml:"ds:DigestValue"`
+		} `xml:"ds:Reference"`
+	} `xml:"ds:SignedInfo"`
+	SignatureValue  string `xml:"ds:SignatureValue"`
+	X509Certificate string `xml:"ds:KeyInfo>ds:X509Data>ds:X509Certificate"`
+}
+type wsFedAlgorithm struct {
+	Algorithm string `xml:"Algorithm,attr"`
+}
+
+// GetWsFedAuthorize WS-Fed客户端授权，返回自动提交到RP的HTML表单
+func (s *sso) GetWsFedAuthorize(data *WsFedSignIn, userId uint) (html, siteName string, err error) {
+
+	var b bytes.Buffer
+
+	// 获取RP应用
+	site, err := dao.Site.GetWsFedSite(data.Wtrealm)
+	if err != nil {
+		recordSSOError(SSOProtocolWsFed, nil, SSOErrorUnregistered, data.Wtrealm)
+		return "", "", errors.New("应用未注册或配置错误")
+	}
+
+	// 判断应用使用的单点登录协议是否已停用
+	if err := checkProtocolSunset(SSOProtocolWsFed, site); err != nil {
+		return "", site.Name, err
+	}
+
+	// 判断用户是否有权限访问
+	if !site.AllOpen {
+		if !dao.Site.IsUserInSite(userId, site) {
+			recordSSOError(SSOProtocolWsFed, site, SSOErrorAccessDenied, fmt.Sprintf("用户ID：%d", userId))
+			return "", site.Name, errors.New("您无权访问该应用")
+		}
+	}
+
+	// 判断用户是否已接受使用条款
+	if err := Terms.Check(site, userId); err != nil {
+		return "", site.Name, err
+	}
+
+	// 获取回调地址，未指定wreply时使用站点配置的回调地址，未配置回调地址时拒绝请求，避免Token被提交到任意地址
+	if site.CallbackUrl == "" {
+		recordSSOError(SSOProtocolWsFed, site, SSOErrorInvalidRequest, "应用未配置回调地址")
+		return "", site.Name, errors.New("应用未配置回调地址")
+	}
+	replyUrl := site.CallbackUrl
+	if data.Wreply != "" {
+		if !matchWsFedReply(site.CallbackUrl, data.Wreply) {
+			recordSSOError(SSOProtocolWsFed, site, SSOErrorInvalidRequest, "wreply与应用回调地址不匹配："+data.Wreply)
+			return "", site.Name, errors.New("wreply与应用回调地址不匹配")
+		}
+		replyUrl = data.Wreply
+	}
+
+	// 获取用户信息
+	var user model.AuthUser
+	if err := global.MySQLClient.Preload("Groups").First(&user, userId).Error; err != nil {
+		return "", site.Name, err
+	}
+
+	// 生成Token
+	token, err := s.generateWsFedToken(site, &user)
+	if err != nil {
+		return "", site.Name, err
+	}
+
+	// 生成HTML响应
+	var htmlData = WsFedResponse{
+		PostFormText: newPostFormText(userLocale(&user)),
+		URL:          replyUrl,
+		Wresult:      token,
+		Wctx:         data.Wctx,
+	}
+	if err := wsFedPostFormTemplate.Execute(&b, htmlData); err != nil {
+		return "", site.Name, err
+	}
+
+	// 通知应用用户已登录
+	SiteLaunchHook.Publish(site, SSOProtocolWsFed, userId)
+
+	return b.String(), site.Name, nil
+}
+
+// generateWsFedToken 生成包含已签名SAML 1.1 Assertion的RequestSecurityTokenResponse
+func (s *sso) generateWsFedToken(site *model.Site, user *model.AuthUser) (string, error) {
+
+	externalUrl := config.GetString("externalUrl")
+
+	// 获取声明映射
+	claims, err := s.getWsFedClaimMapping(site)
+	if err != nil {
+		return "", err
+	}
+
+	// 获取IDP证书和私钥
+	cert, err := utils.LoadIdpCertificate()
+	if err != nil {
+		return "", err
+	}
+	privateKey, err := utils.LoadIdpPrivateKey()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	expires := now.Add(time.Hour)
+	assertionId := "_" + uuid.New().String()
+
+	subject := wsFedSubject{NameIdentifier: user.Username}
+	subject.SubjectConfirmation.ConfirmationMethod = "urn:oasis:names:tc:SAML:1.0:cm:bearer"
+
+	assertion := wsFedAssertion{
+		Xmlns:        wsFedSAMLNamespace,
+		MajorVersion: "1",
+		MinorVersion: "1",
+		AssertionID:  assertionId,
+		Issuer:       externalUrl,
+		IssueInstant: now.Format(wsFedTimeFormat),
+		Conditions: wsFedConditions{
+			NotBefore:    now.Add(-5 * time.Minute).Format(wsFedTimeFormat),
+			NotOnOrAfter: expires.Format(wsFedTimeFormat),
+		},
+		AttributeStatement: wsFedAttributeStatement{Subject: subject},
+		AuthenticationStatement: wsFedAuthenticationStatement{
+			AuthenticationMethod:  "urn:oasis:names:tc:SAML:1.0:am:password",
+			AuthenticationInstant: now.Format(wsFedTimeFormat),
+			Subject:               subject,
+		},
+	}
+	assertion.Conditions.AudienceRestrictionCondition.Audience = site.EntityId
+
+	// 添加用户声明（SAML 1.1中声明URI需要拆分为命名空间和名称）
+	claimNames := make([]string, 0, len(claims))
+	for claim := range claims {
+		claimNames = append(claimNames, claim)
+	}
+	sort.Strings(claimNames)
+	for _, claim := range claimNames {
+		values := getUserClaimValues(user, claims[claim])
+		if len(values) == 0 {
+			continue
+		}
+		index := strings.LastIndex(claim, "/")
+		if index <= 0 || index == len(claim)-1 {
+			continue
+		}
+		assertion.AttributeStatement.Attributes = append(assertion.AttributeStatement.Attributes, wsFedAttributes{
+			AttributeNamespace: claim[:index],
+			AttributeName:      claim[index+1:],
+			AttributeValues:    values,
+		})
+	}
+
+	// 签名模板
+	signature := &assertion.Signature
+	signature.Xmlns = wsFedDSigNamespace
+	signature.SignedInfo.CanonicalizationMethod.Algorithm = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	signature.SignedInfo.SignatureMethod.Algorithm = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	signature.SignedInfo.Reference.URI = "#" + assertionId
+	signature.SignedInfo.Reference.Transforms = []wsFedAlgorithm{
+		{Algorithm: "http://www.w3.org/2000/09/xmldsig#enveloped-signature"},
+		{Algorithm: "http://www.w3.org/2001/10/xml-exc-c14n#"},
+	}
+	signature.SignedInfo.Reference.Digest.Algorithm = "http://www.w3.org/2001/04/xmlenc#sha256"
+	signature.X509Certificate = base64.StdEncoding.EncodeToString(cert.Raw)
+
+	assertionXML, err := xml.Marshal(assertion)
+	if err != nil {
+		return "", err
+	}
+
+	// 对Assertion进行签名，SAML 1.1使用AssertionID作为引用标识
+	signer, err := signedxml.NewSigner(string(assertionXML))
+	if err != nil {
+		return "", err
+	}
+	signer.SetReferenceIDAttribute("AssertionID")
+	signedAssertion, err := signer.Sign(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	var realm bytes.Buffer
+	if err := xml.EscapeText(&realm, []byte(site.EntityId)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`<t:RequestSecurityTokenResponse xmlns:t="http://schemas.xmlsoap.org/ws/2005/02/trust">`+
+		`<t:Lifetime>`+
+		`<wsu:Created xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">%s</wsu:Created>`+
+		`<wsu:Expires xmlns:wsu="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">%s</wsu:Expires>`+
+		`</t:Lifetime>`+
+		`<wsp:AppliesTo xmlns:wsp="http://schemas.xmlsoap.org/ws/2004/09/policy">`+
+		`<wsa:EndpointReference xmlns:wsa="http://www.w3.org/2005/08/addressing"><wsa:Address>%s</wsa:Address></wsa:EndpointReference>`+
+		`</wsp:AppliesTo>`+
+		`<t:RequestedSecurityToken>%s</t:RequestedSecurityToken>`+
+		`<t:TokenType>%s</t:TokenType>`+
+		`<t:RequestType>http://schemas.xmlsoap.org/ws/2005/02/trust/Issue</t:RequestType>`+
+		`<t:KeyType>http://schemas.xmlsoap.org/ws/2005/05/identity/NoProofKey</t:KeyType>`+
+		`</t:RequestSecurityTokenResponse>`,
+		now.Format(wsFedTimeFormat), expires.Format(wsFedTimeFormat), realm.String(), signedAssertion, wsFedTokenType), nil
+}
+
+// getWsFedClaimMapping 获取站点的声明映射，未配置时使用默认映射
+func (s *sso) getWsFedClaimMapping(site *model.Site) (map[string]string, error) {
+	if strings.TrimSpace(site.ClaimMapping) == "" {
+		return wsFedDefaultClaims, nil
+	}
+
+	var claims map[string]string
+	if err := json.Unmarshal([]byte(site.ClaimMapping), &claims); err != nil {
+		return nil, errors.New("应用声明映射配置错误")
+	}
+
+	return claims, nil
+}
+
+// GetWsFedMetadata 获取WS-Fed联合元数据
+func (s *sso) GetWsFedMetadata() (metadata string, err error) {
+
+	externalUrl := config.GetString("externalUrl")
+
+	// 获取证书
+	cert, err := utils.LoadIdpCertificate()
+	if err != nil {
+		return "", err
+	}
+
+	var entityId bytes.Buffer
+	if err := xml.EscapeText(&entityId, []byte(externalUrl)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>`+
+		`<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" ID="_%s" entityID="%s">`+
+		`<RoleDescriptor xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:fed="http://docs.oasis-open.org/wsfed/federation/200706" xsi:type="fed:SecurityTokenServiceType" protocolSupportEnumeration="http://docs.oasis-open.org/wsfed/federation/200706">`+
+		`<KeyDescriptor use="signing"><KeyInfo xmlns="http://www.w3.org/2000/09/xmldsig#"><X509Data><X509Certificate>%s</X509Certificate></X509Data></KeyInfo></KeyDescriptor>`+
+		`<fed:TokenTypesOffered><fed:TokenType Uri="%s"/></fed:TokenTypesOffered>`+
+		`<fed:PassiveRequestorEndpoint><wsa:EndpointReference xmlns:wsa="http://www.w3.org/2005/08/addressing"><wsa:Address>%s/login</wsa:Address></wsa:EndpointReference></fed:PassiveRequestorEndpoint>`+
+		`</RoleDescriptor>`+
+		`</EntityDescriptor>`,
+		uuid.New().String(), entityId.String(), base64.StdEncoding.EncodeToString(cert.Raw), wsFedTokenType, entityId.String()), nil
+}
+
+// matchWsFedReply 判断wreply是否与应用回调地址匹配：协议及主机（含端口）完全一致，路径与回调地址相同或以回调地址路径加“/”开头
+func matchWsFedReply(callbackUrl, wreply string) bool {
+
+	registered, err := url.Parse(callbackUrl)
+	if err != nil || registered.Host == "" {
+		return false
+	}
+	reply, err := url.Parse(wreply)
+	if err != nil || reply.Host == "" || reply.User != nil || reply.Fragment != "" || strings.Contains(reply.Path, "..") {
+		return false
+	}
+
+	if !strings.EqualFold(reply.Scheme, registered.Scheme) || !strings.EqualFold(reply.Host, registered.Host) {
+		return false
+	}
+
+	prefix := registered.EscapedPath()
+	path := reply.EscapedPath()
+	if path == prefix || prefix == "" || prefix == "/" {
+		return true
+	}
+	return strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/")
+}
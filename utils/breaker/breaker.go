@@ -0,0 +1,118 @@
+package breaker
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"sync"
+	"time"
+)
+
+// 熔断器：依赖服务（Redis、MinIO）连续失败达到阈值后熔断，熔断期间直接返回 ErrOpen，不再等待网络超时；
+// 熔断时间结束后放行一次探测请求，成功后恢复，失败后重新熔断
+
+// ErrOpen 依赖服务已熔断
+var ErrOpen = errors.New("依赖服务暂不可用")
+
+// dependencyAvailable 依赖服务是否可用，1为可用，0为已熔断
+var dependencyAvailable = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "dependency_available",
+	Help: "依赖服务是否可用（1为可用，0为已熔断）",
+}, []string{"dependency"})
+
+// Breaker 熔断器
+type Breaker struct {
+	name      string
+	threshold int           // 连续失败多少次后熔断
+	cooldown  time.Duration // 熔断时间
+
+	mutex     sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+	lastError string
+	since     time.Time // 不可用的开始时间
+}
+
+// Status 熔断器状态
+type Status struct {
+	Name      string     `json:"name"`
+	Available bool       `json:"available"`            // 是否可用
+	Failures  int        `json:"failures"`             // 连续失败次数
+	LastError string     `json:"last_error,omitempty"` // 最近一次失败的原因
+	Since     *time.Time `json:"since,omitempty"`      // 不可用的开始时间
+}
+
+// New 创建熔断器
+func New(name string, threshold int, cooldown time.Duration) *Breaker {
+	dependencyAvailable.WithLabelValues(name).Set(1)
+	return &Breaker{name: name, threshold: threshold, cooldown: cooldown}
+}
+
+// Allow 判断是否允许请求依赖服务，熔断时间结束后只放行一次探测请求
+func (b *Breaker) Allow() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	if time.Now().Before(b.openUntil) || b.probing {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// Success 记录一次成功的请求，熔断器恢复
+func (b *Breaker) Success() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures, b.probing, b.lastError, b.since = 0, false, "", time.Time{}
+	dependencyAvailable.WithLabelValues(b.name).Set(1)
+}
+
+// Failure 记录一次失败的请求，连续失败达到阈值后熔断；熔断期间的失败（如 ErrOpen）不延长熔断时间
+func (b *Breaker) Failure(err error) {
+	if err == nil || errors.Is(err, ErrOpen) {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := time.Now()
+	if b.failures >= b.threshold && now.Before(b.openUntil) {
+		return
+	}
+	if b.failures == 0 {
+		b.since = now
+	}
+	b.failures++
+	b.lastError = err.Error()
+	if b.failures >= b.threshold {
+		b.openUntil, b.probing = now.Add(b.cooldown), false
+		dependencyAvailable.WithLabelValues(b.name).Set(0)
+	}
+}
+
+// Available 判断依赖服务是否可用（未熔断）
+func (b *Breaker) Available() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.failures < b.threshold
+}
+
+// Status 获取熔断器状态
+func (b *Breaker) Status() Status {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	status := Status{Name: b.name, Available: b.failures < b.threshold, Failures: b.failures, LastError: b.lastError}
+	if !b.since.IsZero() {
+		since := b.since
+		status.Since = &since
+	}
+	return status
+}
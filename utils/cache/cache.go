@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// 缓存接口：业务代码通过 Cache 访问缓存，不直接依赖Redis，支持Redis（默认）、内存及Memcached；
+// 内存缓存仅适用于单实例部署（轻量部署模式），Memcached不支持发布订阅，多实例部署时Token注销通知依赖本地缓存过期
+
+// ErrNotFound Key不存在或已过期
+var ErrNotFound = errors.New("缓存不存在")
+
+// Cache 缓存
+type Cache interface {
+	// Get 获取值，Key不存在时返回 ErrNotFound
+	Get(key string) (string, error)
+	// Set 设置值，ttl 为0时不过期
+	Set(key, value string, ttl time.Duration) error
+	// SetNX Key不存在时设置值，并发设置时只有一个能设置成功
+	SetNX(key, value string, ttl time.Duration) (bool, error)
+	// Del 删除Key，返回删除的数量，并发删除同一Key时只有一个能删除成功
+	Del(keys ...string) (int64, error)
+	// Exists 判断Key是否存在
+	Exists(key string) (bool, error)
+	// ExistsEach 分别判断多个Key是否存在，返回结果与 keys 顺序一致，Redis通过Pipeline一次请求完成查询
+	ExistsEach(keys ...string) ([]bool, error)
+	// TTL 获取剩余有效期，Key不存在或未设置有效期时返回0
+	TTL(key string) (time.Duration, error)
+	// Expire 设置有效期，Key不存在时返回false
+	Expire(key string, ttl time.Duration) (bool, error)
+	// Incr 原子自增并返回自增后的值，Key不存在时从0开始并设置有效期 ttl（为0时不过期）
+	Incr(key string, ttl time.Duration) (int64, error)
+
+	// SAdd 向集合中添加成员并重新设置集合的有效期
+	SAdd(key, member string, ttl time.Duration) error
+	// SMembers 获取集合的所有成员
+	SMembers(key string) ([]string, error)
+	// SRem 删除集合中的成员
+	SRem(key, member string) error
+
+	// RPush 向列表末尾添加元素
+	RPush(key, value string) error
+	// PopAll 取出列表中的所有元素并清空列表
+	PopAll(key string) ([]string, error)
+
+	// Publish 发布消息
+	Publish(channel, message string) error
+	// Subscribe 订阅消息，收到消息时调用 handler；订阅失败时返回错误，连接恢复后自动重新订阅
+	Subscribe(channel string, handler func(message string)) error
+}
@@ -0,0 +1,420 @@
+package cache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Memcached 使用meta命令（Memcached 1.6及以上版本）访问，多个服务器时按Key的哈希值分布；
+// 集合及列表以JSON格式保存，使用CAS保证并发修改的一致性；Memcached不支持发布订阅
+
+const (
+	memcachedMaxIdle     = 8                   // 每个服务器最多保留的空闲连接数
+	memcachedMaxKeyLen   = 250                 // Key的最大长度
+	memcachedMaxRelative = 30 * 24 * time.Hour // 超过30天的有效期需要使用过期时间戳
+	memcachedCASRetries  = 10                  // CAS冲突时的最大重试次数
+)
+
+// errCASConflict CAS冲突，值已被其它请求修改
+var errCASConflict = errors.New("缓存已被修改")
+
+// Memcached Memcached缓存
+type Memcached struct {
+	servers []*memcachedServer
+	timeout time.Duration
+}
+
+type memcachedServer struct {
+	addr  string
+	mutex sync.Mutex
+	idle  []*memcachedConn
+}
+
+type memcachedConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+}
+
+// memcachedReply meta命令的响应，如：VA 2 c123 t60
+type memcachedReply struct {
+	code  string
+	flags map[byte]string
+	value []byte
+}
+
+// NewMemcached 创建Memcached缓存，servers 为服务器地址（host:port），timeout 为连接及读写超时时间
+func NewMemcached(servers []string, timeout time.Duration) (*Memcached, error) {
+	if len(servers) == 0 {
+		return nil, errors.New("未配置Memcached服务器地址")
+	}
+	m := &Memcached{timeout: timeout}
+	for _, addr := range servers {
+		m.servers = append(m.servers, &memcachedServer{addr: addr})
+	}
+	return m, nil
+}
+
+// Ping 检查所有服务器是否可用
+func (m *Memcached) Ping() error {
+	for _, server := range m.servers {
+		if err := server.do(m.timeout, func(c *memcachedConn) error {
+			_, err := c.request("mn", nil)
+			return err
+		}); err != nil {
+			return fmt.Errorf("%s：%w", server.addr, err)
+		}
+	}
+	return nil
+}
+
+// memcachedKey Key不能包含空白及控制字符且长度不能超过250，不符合要求时使用SHA256摘要
+func memcachedKey(key string) string {
+	valid := key != "" && len(key) <= memcachedMaxKeyLen
+	for i := 0; valid && i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			valid = false
+		}
+	}
+	if valid {
+		return key
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// memcachedTTL 转换有效期（秒），0为不过期，不足1秒按1秒计算，超过30天时使用过期时间戳
+func memcachedTTL(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl > memcachedMaxRelative {
+		return time.Now().Add(ttl).Unix()
+	}
+	seconds := int64(ttl / time.Second)
+	if ttl%time.Second != 0 {
+		seconds++
+	}
+	return seconds
+}
+
+// exec 在Key所在的服务器上执行命令
+func (m *Memcached) exec(key, command string, data []byte) (*memcachedReply, error) {
+	server := m.servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(m.servers))]
+	var reply *memcachedReply
+	err := server.do(m.timeout, func(c *memcachedConn) (err error) {
+		reply, err = c.request(command, data)
+		return err
+	})
+	return reply, err
+}
+
+// do 获取连接执行命令，执行失败时关闭连接
+func (s *memcachedServer) do(timeout time.Duration, fn func(c *memcachedConn) error) error {
+	s.mutex.Lock()
+	var c *memcachedConn
+	if n := len(s.idle); n > 0 {
+		c, s.idle = s.idle[n-1], s.idle[:n-1]
+	}
+	s.mutex.Unlock()
+
+	if c == nil {
+		conn, err := net.DialTimeout("tcp", s.addr, timeout)
+		if err != nil {
+			return err
+		}
+		c = &memcachedConn{conn: conn, rw: bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))}
+	}
+
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		c.conn.Close()
+		return err
+	}
+	if err := fn(c); err != nil {
+		c.conn.Close()
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.idle) < memcachedMaxIdle {
+		s.idle = append(s.idle, c)
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// request 发送命令并读取响应，data 为需要写入的值
+func (c *memcachedConn) request(command string, data []byte) (*memcachedReply, error) {
+	if _, err := c.rw.WriteString(command + "\r\n"); err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if _, err := c.rw.Write(append(data, '\r', '\n')); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.rw.Flush(); err != nil {
+		return nil, err
+	}
+
+	line, err := c.rw.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, errors.New("Memcached响应格式错误")
+	}
+
+	reply := &memcachedReply{code: fields[0], flags: make(map[byte]string)}
+	switch reply.code {
+	case "VA":
+		if len(fields) < 2 {
+			return nil, errors.New("Memcached响应格式错误")
+		}
+		size, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, errors.New("Memcached响应格式错误")
+		}
+		reply.value = make([]byte, size+2)
+		if _, err := io.ReadFull(c.rw, reply.value); err != nil {
+			return nil, err
+		}
+		reply.value = reply.value[:size]
+		fields = fields[2:]
+	case "HD", "EN", "NF", "NS", "EX", "MN":
+		fields = fields[1:]
+	default:
+		return nil, fmt.Errorf("Memcached返回错误：%s", strings.TrimSpace(line))
+	}
+	for _, field := range fields {
+		reply.flags[field[0]] = field[1:]
+	}
+	return reply, nil
+}
+
+func (m *Memcached) Get(key string) (string, error) {
+	reply, err := m.exec(key, "mg "+memcachedKey(key)+" v", nil)
+	if err != nil {
+		return "", err
+	}
+	if reply.code != "VA" {
+		return "", ErrNotFound
+	}
+	return string(reply.value), nil
+}
+
+func (m *Memcached) Set(key, value string, ttl time.Duration) error {
+	_, err := m.exec(key, fmt.Sprintf("ms %s %d T%d", memcachedKey(key), len(value), memcachedTTL(ttl)), []byte(value))
+	return err
+}
+
+func (m *Memcached) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	reply, err := m.exec(key, fmt.Sprintf("ms %s %d T%d ME", memcachedKey(key), len(value), memcachedTTL(ttl)), []byte(value))
+	if err != nil {
+		return false, err
+	}
+	return reply.code == "HD", nil
+}
+
+func (m *Memcached) Del(keys ...string) (int64, error) {
+	var count int64
+	for _, key := range keys {
+		reply, err := m.exec(key, "md "+memcachedKey(key), nil)
+		if err != nil {
+			return count, err
+		}
+		if reply.code == "HD" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *Memcached) Exists(key string) (bool, error) {
+	reply, err := m.exec(key, "mg "+memcachedKey(key), nil)
+	if err != nil {
+		return false, err
+	}
+	return reply.code == "HD", nil
+}
+
+// ExistsEach 逐个查询Key是否存在
+func (m *Memcached) ExistsEach(keys ...string) ([]bool, error) {
+	exists := make([]bool, len(keys))
+	for i, key := range keys {
+		found, err := m.Exists(key)
+		if err != nil {
+			return nil, err
+		}
+		exists[i] = found
+	}
+	return exists, nil
+}
+
+func (m *Memcached) TTL(key string) (time.Duration, error) {
+	reply, err := m.exec(key, "mg "+memcachedKey(key)+" t", nil)
+	if err != nil || reply.code != "HD" {
+		return 0, err
+	}
+	seconds, _ := strconv.ParseInt(reply.flags['t'], 10, 64)
+	if seconds < 0 {
+		return 0, nil
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+func (m *Memcached) Expire(key string, ttl time.Duration) (bool, error) {
+	reply, err := m.exec(key, fmt.Sprintf("mg %s T%d", memcachedKey(key), memcachedTTL(ttl)), nil)
+	if err != nil {
+		return false, err
+	}
+	return reply.code == "HD", nil
+}
+
+// Incr Key不存在时自动创建，初始值为1
+func (m *Memcached) Incr(key string, ttl time.Duration) (int64, error) {
+	reply, err := m.exec(key, fmt.Sprintf("ma %s N%d J1 v", memcachedKey(key), memcachedTTL(ttl)), nil)
+	if err != nil {
+		return 0, err
+	}
+	if reply.code != "VA" {
+		return 0, fmt.Errorf("Memcached自增失败：%s", reply.code)
+	}
+	return strconv.ParseInt(string(reply.value), 10, 64)
+}
+
+// update 使用CAS修改JSON格式的集合或列表，fn 返回修改后的值，ttl 小于0时保留原有效期
+func (m *Memcached) update(key string, ttl time.Duration, fn func(values []string) []string) error {
+	mk := memcachedKey(key)
+	for i := 0; i < memcachedCASRetries; i++ {
+		reply, err := m.exec(key, "mg "+mk+" v c t", nil)
+		if err != nil {
+			return err
+		}
+		var values []string
+		exists := reply.code == "VA"
+		if exists {
+			if err := json.Unmarshal(reply.value, &values); err != nil {
+				return err
+			}
+		}
+
+		data, err := json.Marshal(fn(values))
+		if err != nil {
+			return err
+		}
+
+		expires := memcachedTTL(ttl)
+		if ttl < 0 {
+			expires = 0
+			if seconds, _ := strconv.ParseInt(reply.flags['t'], 10, 64); seconds > 0 {
+				expires = seconds
+			}
+		}
+		command := fmt.Sprintf("ms %s %d T%d ME", mk, len(data), expires)
+		if exists {
+			command = fmt.Sprintf("ms %s %d T%d C%s", mk, len(data), expires, reply.flags['c'])
+		}
+		result, err := m.exec(key, command, data)
+		if err != nil {
+			return err
+		}
+		if result.code == "HD" {
+			return nil
+		}
+	}
+	return errCASConflict
+}
+
+func (m *Memcached) SAdd(key, member string, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = -1
+	}
+	return m.update(key, ttl, func(members []string) []string {
+		for _, item := range members {
+			if item == member {
+				return members
+			}
+		}
+		return append(members, member)
+	})
+}
+
+func (m *Memcached) SMembers(key string) ([]string, error) {
+	value, err := m.Get(key)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var members []string
+	err = json.Unmarshal([]byte(value), &members)
+	return members, err
+}
+
+func (m *Memcached) SRem(key, member string) error {
+	return m.update(key, -1, func(members []string) []string {
+		result := make([]string, 0, len(members))
+		for _, item := range members {
+			if item != member {
+				result = append(result, item)
+			}
+		}
+		return result
+	})
+}
+
+func (m *Memcached) RPush(key, value string) error {
+	return m.update(key, -1, func(values []string) []string {
+		return append(values, value)
+	})
+}
+
+// PopAll 获取列表后使用CAS删除，删除前列表被修改时重试
+func (m *Memcached) PopAll(key string) ([]string, error) {
+	mk := memcachedKey(key)
+	for i := 0; i < memcachedCASRetries; i++ {
+		reply, err := m.exec(key, "mg "+mk+" v c", nil)
+		if err != nil {
+			return nil, err
+		}
+		if reply.code != "VA" {
+			return nil, nil
+		}
+		var values []string
+		if err := json.Unmarshal(reply.value, &values); err != nil {
+			return nil, err
+		}
+		result, err := m.exec(key, fmt.Sprintf("md %s C%s", mk, reply.flags['c']), nil)
+		if err != nil {
+			return nil, err
+		}
+		if result.code == "HD" {
+			return values, nil
+		}
+	}
+	return nil, errCASConflict
+}
+
+// Publish Memcached不支持发布订阅，不发送消息
+func (m *Memcached) Publish(channel, message string) error {
+	return nil
+}
+
+// Subscribe Memcached不支持发布订阅，不会收到其它实例的消息
+func (m *Memcached) Subscribe(channel string, handler func(message string)) error {
+	return nil
+}
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// memoryCleanupInterval 清理过期Key的间隔
+const memoryCleanupInterval = time.Minute
+
+// memoryItem 内存缓存中的值，字符串、集合及列表分别保存
+type memoryItem struct {
+	value     string
+	set       map[string]struct{}
+	list      []string
+	expiresAt time.Time // 为零值时不过期
+}
+
+func (i *memoryItem) expired(now time.Time) bool {
+	return !i.expiresAt.IsZero() && !now.Before(i.expiresAt)
+}
+
+// Memory 内存缓存，仅适用于单实例部署，服务重启后缓存丢失
+type Memory struct {
+	mutex    sync.Mutex
+	items    map[string]*memoryItem
+	handlers map[string][]func(message string)
+}
+
+// NewMemory 创建内存缓存，并定时清理过期Key
+func NewMemory() *Memory {
+	m := &Memory{
+		items:    make(map[string]*memoryItem),
+		handlers: make(map[string][]func(message string)),
+	}
+	go m.cleanup()
+	return m
+}
+
+// cleanup 定时清理过期Key
+func (m *Memory) cleanup() {
+	ticker := time.NewTicker(memoryCleanupInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		m.mutex.Lock()
+		for key, item := range m.items {
+			if item.expired(now) {
+				delete(m.items, key)
+			}
+		}
+		m.mutex.Unlock()
+	}
+}
+
+// item 获取未过期的值，调用前需要加锁
+func (m *Memory) item(key string) *memoryItem {
+	item, ok := m.items[key]
+	if !ok {
+		return nil
+	}
+	if item.expired(time.Now()) {
+		delete(m.items, key)
+		return nil
+	}
+	return item
+}
+
+// expiresAt 计算过期时间，ttl 为0时不过期
+func expiresAt(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return time.Now().Add(ttl)
+}
+
+func (m *Memory) Get(key string) (string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item := m.item(key)
+	if item == nil {
+		return "", ErrNotFound
+	}
+	return item.value, nil
+}
+
+func (m *Memory) Set(key, value string, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.items[key] = &memoryItem{value: value, expiresAt: expiresAt(ttl)}
+	return nil
+}
+
+func (m *Memory) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.item(key) != nil {
+		return false, nil
+	}
+	m.items[key] = &memoryItem{value: value, expiresAt: expiresAt(ttl)}
+	return true, nil
+}
+
+func (m *Memory) Del(keys ...string) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var count int64
+	for _, key := range keys {
+		if m.item(key) != nil {
+			delete(m.items, key)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *Memory) Exists(key string) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return m.item(key) != nil, nil
+}
+
+func (m *Memory) ExistsEach(keys ...string) ([]bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	exists := make([]bool, len(keys))
+	for i, key := range keys {
+		exists[i] = m.item(key) != nil
+	}
+	return exists, nil
+}
+
+func (m *Memory) TTL(key string) (time.Duration, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item := m.item(key)
+	if item == nil || item.expiresAt.IsZero() {
+		return 0, nil
+	}
+	return time.Until(item.expiresAt), nil
+}
+
+func (m *Memory) Expire(key string, ttl time.Duration) (bool, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item := m.item(key)
+	if item == nil {
+		return false, nil
+	}
+	item.expiresAt = expiresAt(ttl)
+	return true, nil
+}
+
+func (m *Memory) Incr(key string, ttl time.Duration) (int64, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item := m.item(key)
+	if item == nil {
+		m.items[key] = &memoryItem{value: "1", expiresAt: expiresAt(ttl)}
+		return 1, nil
+	}
+	count, err := strconv.ParseInt(item.value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	count++
+	item.value = strconv.FormatInt(count, 10)
+	return count, nil
+}
+
+func (m *Memory) SAdd(key, member string, ttl time.Duration) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item := m.item(key)
+	if item == nil {
+		item = &memoryItem{set: make(map[string]struct{})}
+		m.items[key] = item
+	}
+	if item.set == nil {
+		item.set = make(map[string]struct{})
+	}
+	item.set[member] = struct{}{}
+	if ttl > 0 {
+		item.expiresAt = expiresAt(ttl)
+	}
+	return nil
+}
+
+func (m *Memory) SMembers(key string) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item := m.item(key)
+	if item == nil {
+		return nil, nil
+	}
+	members := make([]string, 0, len(item.set))
+	for member := range item.set {
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+func (m *Memory) SRem(key, member string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if item := m.item(key); item != nil {
+		delete(item.set, member)
+	}
+	return nil
+}
+
+func (m *Memory) RPush(key, value string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item := m.item(key)
+	if item == nil {
+		item = &memoryItem{}
+		m.items[key] = item
+	}
+	item.list = append(item.list, value)
+	return nil
+}
+
+func (m *Memory) PopAll(key string) ([]string, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	item := m.item(key)
+	if item == nil {
+		return nil, nil
+	}
+	delete(m.items, key)
+	return item.list, nil
+}
+
+// Publish 发布消息，仅通知本实例的订阅者
+func (m *Memory) Publish(channel, message string) error {
+	m.mutex.Lock()
+	handlers := append([]func(message string){}, m.handlers[channel]...)
+	m.mutex.Unlock()
+
+	for _, handler := range handlers {
+		handler(message)
+	}
+	return nil
+}
+
+func (m *Memory) Subscribe(channel string, handler func(message string)) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.handlers[channel] = append(m.handlers[channel], handler)
+	return nil
+}
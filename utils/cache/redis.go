@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"github.com/go-redis/redis"
+	"time"
+)
+
+// incrScript 自增并在首次创建时设置有效期
+var incrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count
+`)
+
+// Redis Redis缓存
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis 创建Redis缓存
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Get(key string) (string, error) {
+	value, err := r.client.Get(key).Result()
+	if err == redis.Nil {
+		return "", ErrNotFound
+	}
+	return value, err
+}
+
+func (r *Redis) Set(key, value string, ttl time.Duration) error {
+	return r.client.Set(key, value, ttl).Err()
+}
+
+func (r *Redis) SetNX(key, value string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(key, value, ttl).Result()
+}
+
+func (r *Redis) Del(keys ...string) (int64, error) {
+	return r.client.Del(keys...).Result()
+}
+
+func (r *Redis) Exists(key string) (bool, error) {
+	count, err := r.client.Exists(key).Result()
+	return count > 0, err
+}
+
+func (r *Redis) ExistsEach(keys ...string) ([]bool, error) {
+	pipe := r.client.Pipeline()
+	commands := make([]*redis.IntCmd, len(keys))
+	for i, key := range keys {
+		commands[i] = pipe.Exists(key)
+	}
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+
+	exists := make([]bool, len(keys))
+	for i, command := range commands {
+		exists[i] = command.Val() > 0
+	}
+	return exists, nil
+}
+
+func (r *Redis) TTL(key string) (time.Duration, error) {
+	ttl, err := r.client.TTL(key).Result()
+	if err != nil || ttl < 0 {
+		return 0, err
+	}
+	return ttl, nil
+}
+
+func (r *Redis) Expire(key string, ttl time.Duration) (bool, error) {
+	return r.client.Expire(key, ttl).Result()
+}
+
+func (r *Redis) Incr(key string, ttl time.Duration) (int64, error) {
+	return incrScript.Run(r.client, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+func (r *Redis) SAdd(key, member string, ttl time.Duration) error {
+	pipe := r.client.Pipeline()
+	pipe.SAdd(key, member)
+	if ttl > 0 {
+		pipe.Expire(key, ttl)
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+func (r *Redis) SMembers(key string) ([]string, error) {
+	return r.client.SMembers(key).Result()
+}
+
+func (r *Redis) SRem(key, member string) error {
+	return r.client.SRem(key, member).Err()
+}
+
+func (r *Redis) RPush(key, value string) error {
+	return r.client.RPush(key, value).Err()
+}
+
+func (r *Redis) PopAll(key string) ([]string, error) {
+	pipe := r.client.TxPipeline()
+	values := pipe.LRange(key, 0, -1)
+	pipe.Del(key)
+	if _, err := pipe.Exec(); err != nil {
+		return nil, err
+	}
+	return values.Val(), nil
+}
+
+func (r *Redis) Publish(channel, message string) error {
+	return r.client.Publish(channel, message).Err()
+}
+
+// Subscribe 订阅消息，连接断开或订阅失败后接收消息时自动重新连接并重新订阅
+func (r *Redis) Subscribe(channel string, handler func(message string)) error {
+	pubSub := r.client.Subscribe()
+	err := pubSub.Subscribe(channel)
+	go func() {
+		for message := range pubSub.Channel() {
+			handler(message.Payload)
+		}
+	}()
+	return err
+}
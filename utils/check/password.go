@@ -11,8 +11,8 @@ import (
 func PasswordCheck(password string) error {
 
 	var (
-		passwordLength                            = config.Conf.Settings["passwordLength"].(int)
-		passwordComplexity                        = config.Conf.Settings["passwordComplexity"].([]string)
+		passwordLength                            = config.GetInt("passwordLength")
+		passwordComplexity                        = config.GetList("passwordComplexity")
 		hasUpper, hasLower, hasNumber, hasSpecial bool
 	)
 
@@ -55,5 +55,6 @@ func PasswordCheck(password string) error {
 		}
 	}
 
-	return nil
+	// 泄露密码检查
+	return PasswordBreachCheck(password)
 }
@@ -0,0 +1,220 @@
+package check
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/wonderivan/logger"
+	"io"
+	"math"
+	"net/http"
+	"ops-api/config"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hibpRangeAPI HaveIBeenPwned k-匿名查询接口，仅需提交密码SHA1的前5位
+const hibpRangeAPI = "https://api.pwnedpasswords.com/range/"
+
+var (
+	breachFilter     *bloomFilter
+	breachFilterPath string
+	breachFilterLock sync.Mutex
+	hibpClient       = &http.Client{Timeout: 5 * time.Second}
+)
+
+// bloomFilter 布隆过滤器，用于离线环境下的泄露密码快速判断
+type bloomFilter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// newBloomFilter 根据预期元素数量创建布隆过滤器（误判率约为0.1%）
+func newBloomFilter(n uint64) *bloomFilter {
+	if n == 0 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(0.001) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Ceil(math.Ln2 * float64(m) / float64(n)))
+	return &bloomFilter{
+		bits: make([]uint64, (m+63)/64),
+		m:    m,
+		k:    k,
+	}
+}
+
+// locations 使用SHA1摘要做双重哈希计算位置
+func (b *bloomFilter) locations(digest []byte) []uint64 {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16])
+	locations := make([]uint64, b.k)
+	for i := uint64(0); i < b.k; i++ {
+		locations[i] = (h1 + i*h2) % b.m
+	}
+	return locations
+}
+
+func (b *bloomFilter) add(digest []byte) {
+	for _, l := range b.locations(digest) {
+		b.bits[l/64] |= 1 << (l % 64)
+	}
+}
+
+func (b *bloomFilter) contains(digest []byte) bool {
+	for _, l := range b.locations(digest) {
+		if b.bits[l/64]&(1<<(l%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PasswordBreachCheck 检查密码是否存在于已泄露密码库中
+func PasswordBreachCheck(password string) error {
+
+	// 判断是否开启此功能
+	enabled := config.GetBool("passwordBreachCheck")
+	if !enabled {
+		return nil
+	}
+
+	mode := config.GetString("passwordBreachMode")
+	sum := sha1.Sum([]byte(password))
+	digest := sum[:]
+
+	// 离线模式：本地泄露密码库（布隆过滤器）
+	if mode == "offline" || mode == "both" {
+		breached, err := offlineBreachCheck(digest)
+		if err != nil {
+			logger.Warn("本地泄露密码库检查失败：" + err.Error())
+		} else if breached {
+			return errors.New("该密码已出现在公开泄露的密码库中，请更换密码")
+		}
+	}
+
+	// 在线模式：HaveIBeenPwned k-匿名接口
+	if mode == "" || mode == "online" || mode == "both" {
+		breached, err := onlineBreachCheck(digest)
+		if err != nil {
+			// 接口不可用时不阻断密码设置
+			logger.Warn("在线泄露密码检查失败：" + err.Error())
+		} else if breached {
+			return errors.New("该密码已出现在公开泄露的密码库中，请更换密码")
+		}
+	}
+
+	return nil
+}
+
+// onlineBreachCheck 通过HaveIBeenPwned接口检查，仅提交SHA1前5位，不会泄露完整密码哈希
+func onlineBreachCheck(digest []byte) (bool, error) {
+
+	hash := strings.ToUpper(hex.EncodeToString(digest))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequest(http.MethodGet, hibpRangeAPI+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// 填充响应，避免根据响应长度推断查询内容
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := hibpClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return false, errors.New(fmt.Sprintf("unexpected status code: %d", resp.StatusCode))
+	}
+
+	// 响应格式：<SHA1后35位>:<出现次数>
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(parts) != 2 || parts[0] != suffix {
+			continue
+		}
+		// 填充数据的出现次数为0
+		return parts[1] != "0", nil
+	}
+
+	return false, scanner.Err()
+}
+
+// offlineBreachCheck 通过本地泄露密码库检查
+func offlineBreachCheck(digest []byte) (bool, error) {
+
+	corpus := config.GetString("passwordBreachCorpus")
+	if corpus == "" {
+		return false, errors.New("未配置本地泄露密码库")
+	}
+
+	filter, err := loadBreachFilter(corpus)
+	if err != nil {
+		return false, err
+	}
+
+	return filter.contains(digest), nil
+}
+
+// loadBreachFilter 加载本地泄露密码库并构建布隆过滤器，仅在首次使用或路径变更时加载
+func loadBreachFilter(path string) (*bloomFilter, error) {
+
+	breachFilterLock.Lock()
+	defer breachFilterLock.Unlock()
+
+	if breachFilter != nil && breachFilterPath == path {
+		return breachFilter, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 统计行数，用于计算布隆过滤器大小
+	var lines uint64
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	// 密码库格式与HaveIBeenPwned离线下载格式一致：每行一个SHA1，可带 :<出现次数> 后缀
+	filter := newBloomFilter(lines)
+	scanner = bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if idx := strings.Index(line, ":"); idx >= 0 {
+			line = line[:idx]
+		}
+		digest, err := hex.DecodeString(line)
+		if err != nil || len(digest) != sha1.Size {
+			continue
+		}
+		filter.add(digest)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	breachFilter = filter
+	breachFilterPath = path
+	logger.Info(fmt.Sprintf("本地泄露密码库加载成功，共 %d 条记录.", lines))
+
+	return filter, nil
+}
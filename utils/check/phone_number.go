@@ -1,19 +1,56 @@
 package check
 
 import (
+	"errors"
 	"github.com/go-playground/validator/v10"
-	"regexp"
+	"github.com/nyaruka/phonenumbers"
+	"strings"
 )
 
-// PhoneNumberCheck 手机号验证，用于结构体中手机号检验
-func PhoneNumberCheck(n validator.FieldLevel) bool {
+// PhoneNumber 标准化后的手机号
+type PhoneNumber struct {
+	E164          string // E.164格式，如：+8613800000000，用于存储、发送短信及扫码登录时匹配用户
+	National      string // 国内格式，如：138 0000 0000
+	International string // 国际格式，如：+86 138 0000 0000
+}
+
+// ParsePhoneNumber 解析并校验手机号，未携带国际区号时按region（如：CN）解析，固定电话等无法接收短信的号码视为无效
+func ParsePhoneNumber(number, region string) (*PhoneNumber, error) {
 
-	// 获取手机号
-	phoneNumber := n.Field().String()
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return nil, errors.New("手机号不能为空")
+	}
 
-	// 手机号码验证规则
-	ruler := "^1[345789]{1}\\d{9}$"
-	reg := regexp.MustCompile(ruler)
+	num, err := phonenumbers.Parse(number, strings.ToUpper(region))
+	if err != nil {
+		return nil, errors.New("手机号格式错误：" + number)
+	}
+	if !phonenumbers.IsValidNumber(num) {
+		return nil, errors.New("无效的手机号：" + number)
+	}
 
-	return reg.MatchString(phoneNumber)
+	// 部分地区（如：美国）无法区分固定电话及手机号，该类号码允许使用
+	switch phonenumbers.GetNumberType(num) {
+	case phonenumbers.MOBILE, phonenumbers.FIXED_LINE_OR_MOBILE:
+	default:
+		return nil, errors.New("该号码无法接收短信，请使用手机号：" + number)
+	}
+
+	return &PhoneNumber{
+		E164:          phonenumbers.Format(num, phonenumbers.E164),
+		National:      phonenumbers.Format(num, phonenumbers.NATIONAL),
+		International: phonenumbers.Format(num, phonenumbers.INTERNATIONAL),
+	}, nil
+}
+
+// IsPhoneRegion 判断是否为支持的手机号地区代码，如：CN、US
+func IsPhoneRegion(region string) bool {
+	return phonenumbers.GetSupportedRegions()[strings.ToUpper(region)]
+}
+
+// PhoneNumberCheck 手机号验证，用于结构体中手机号检验，手机号需先按默认地区标准化为E.164格式
+func PhoneNumberCheck(n validator.FieldLevel) bool {
+	_, err := ParsePhoneNumber(n.Field().String(), "")
+	return err == nil
 }
@@ -0,0 +1,75 @@
+package conformance
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// casServiceResponse CAS票据校验响应
+type casServiceResponse struct {
+	XMLName xml.Name `xml:"http://www.yale.edu/tp/cas serviceResponse"`
+	Success *struct {
+		User string `xml:"user"`
+	} `xml:"authenticationSuccess"`
+	Failure *struct {
+		Code string `xml:"code,attr"`
+	} `xml:"authenticationFailure"`
+}
+
+// cas1Validate CAS1.0票据校验（CAS Protocol 3.0 2.4）
+func cas1Validate(r *runner) {
+
+	query := url.Values{"service": {testService}, "ticket": {testTicket}}
+	resp := r.get("校验无效的票据", "CAS Protocol 3.0 2.4.2", pathCASValidate, query, nil)
+	if resp == nil {
+		return
+	}
+	r.expect("返回200", "CAS Protocol 3.0 2.4.2", resp.status == http.StatusOK, "状态码为%d", resp.status)
+	r.expect("响应为no", "CAS Protocol 3.0 2.4.2", string(resp.body) == "no\n\n", "响应为%q", string(resp.body))
+}
+
+// cas2ServiceValidate CAS2.0票据校验（CAS Protocol 3.0 2.5）
+func cas2ServiceValidate(r *runner) {
+	casServiceValidate(r, pathCAS2Validate, "CAS Protocol 3.0 2.5")
+}
+
+// cas3ServiceValidate CAS3.0票据校验（CAS Protocol 3.0 2.8）
+func cas3ServiceValidate(r *runner) {
+	casServiceValidate(r, pathCAS3Validate, "CAS Protocol 3.0 2.8")
+}
+
+// casServiceValidate 票据校验失败时返回 authenticationFailure 及对应的错误码（CAS Protocol 3.0 2.5.3）
+func casServiceValidate(r *runner, path, spec string) {
+
+	resp := r.get("缺少参数", spec, path, nil, nil)
+	if resp != nil {
+		r.casFailure("缺少参数：", resp, "INVALID_REQUEST")
+	}
+
+	query := url.Values{"service": {testService}, "ticket": {testTicket}}
+	resp = r.get("校验无效的票据", spec, path, query, nil)
+	if resp != nil {
+		r.casFailure("无效的票据：", resp, "INVALID_TICKET", "INVALID_SERVICE")
+	}
+}
+
+// casFailure 检查票据校验失败响应，scenario为检查项名称前缀
+func (r *runner) casFailure(scenario string, resp *response, codes ...string) {
+
+	const spec = "CAS Protocol 3.0 2.5.3"
+
+	r.expect(scenario+"返回200", spec, resp.status == http.StatusOK, "状态码为%d", resp.status)
+	contentType := resp.contentType()
+	r.expect(scenario+"Content-Type为XML", spec, contentType == "application/xml" || contentType == "text/xml", "Content-Type为%s", contentType)
+
+	var body casServiceResponse
+	if err := xml.Unmarshal(resp.body, &body); !r.expect(scenario+"响应为cas:serviceResponse", spec, err == nil, "解析失败：%v", err) {
+		return
+	}
+	if !r.expect(scenario+"返回authenticationFailure", spec, body.Failure != nil && body.Success == nil, "响应中没有authenticationFailure") {
+		return
+	}
+	r.expect(scenario+"错误码为"+strings.Join(codes, "或"), spec, contains(codes, body.Failure.Code), "错误码为%s", body.Failure.Code)
+}
@@ -0,0 +1,225 @@
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 协议一致性测试：按能力（capability）对IdP的OIDC发现、JWKS、Token、UserInfo，CAS票据校验及SAML2、WS-Fed元数据接口发起请求，
+// 按规范条款检查响应状态码、响应头及响应内容，并使用客户端常用的解析库（如 jwx 解析JWKS）验证响应可被客户端正常使用；
+// 所有检查均不需要用户凭据，只校验公开接口及错误响应，可在生产环境执行
+
+// 接口地址，与路由保持一致
+const (
+	pathOIDCDiscovery = "/.well-known/openid-configuration"
+	pathOIDCJwks      = "/api/v1/sso/oidc/jwks"
+	pathOAuthToken    = "/api/v1/sso/oauth/token"
+	pathOAuthUserinfo = "/api/v1/sso/oauth/userinfo"
+	pathCASValidate   = "/validate"
+	pathCAS2Validate  = "/serviceValidate"
+	pathCAS3Validate  = "/p3/serviceValidate"
+	pathSAMLMetadata  = "/api/v1/sso/saml/metadata"
+	pathWsFedMetadata = "/FederationMetadata/2007-06/FederationMetadata.xml"
+)
+
+// 测试使用的客户端标识、票据，均为不存在的数据
+const (
+	testClientId     = "conformance-test"
+	testClientSecret = "conformance-test"
+	testService      = "https://conformance.invalid/cas"
+	testTicket       = "ST-conformance-test"
+	testAccessToken  = "conformance-test"
+)
+
+// Check 单项检查结果
+type Check struct {
+	Name    string `json:"name"`
+	Spec    string `json:"spec"` // 依据的规范条款
+	Passed  bool   `json:"passed"`
+	Message string `json:"message,omitempty"` // 未通过的原因
+}
+
+// Capability 单个能力的检查结果，所有检查项通过时为通过
+type Capability struct {
+	Name     string   `json:"name"`
+	Protocol string   `json:"protocol"`
+	Passed   bool     `json:"passed"`
+	Duration int64    `json:"duration"` // 耗时，单位：毫秒
+	Checks   []*Check `json:"checks"`
+}
+
+// Report 一致性测试报告
+type Report struct {
+	Target       string        `json:"target"`
+	StartedAt    time.Time     `json:"started_at"`
+	Duration     int64         `json:"duration"` // 耗时，单位：毫秒
+	Passed       int           `json:"passed"`   // 通过的能力数量
+	Failed       int           `json:"failed"`   // 未通过的能力数量
+	Capabilities []*Capability `json:"capabilities"`
+}
+
+// capability 能力定义
+type capability struct {
+	name     string
+	protocol string
+	run      func(r *runner)
+}
+
+// capabilities 支持的能力，按执行顺序排列
+var capabilities = []capability{
+	{"oidc_discovery", "OIDC", oidcDiscovery},
+	{"oidc_jwks", "OIDC", oidcJwks},
+	{"oauth_token", "OAuth2.0", oauthToken},
+	{"oidc_userinfo", "OIDC", oidcUserinfo},
+	{"cas1_validate", "CAS", cas1Validate},
+	{"cas2_service_validate", "CAS", cas2ServiceValidate},
+	{"cas3_service_validate", "CAS", cas3ServiceValidate},
+	{"saml_metadata", "SAML2", samlMetadata},
+	{"wsfed_metadata", "WS-Fed", wsfedMetadata},
+}
+
+// Capabilities 获取支持的能力名称
+func Capabilities() []string {
+	names := make([]string, 0, len(capabilities))
+	for _, item := range capabilities {
+		names = append(names, item.name)
+	}
+	return names
+}
+
+// Run 对目标地址执行一致性测试，names为空时执行所有能力
+func Run(target string, client *http.Client, names []string) (*Report, error) {
+
+	target = strings.TrimRight(target, "/")
+	if _, err := url.ParseRequestURI(target); err != nil {
+		return nil, fmt.Errorf("测试地址格式错误：%s", target)
+	}
+
+	selected := capabilities
+	if len(names) > 0 {
+		selected = make([]capability, 0, len(names))
+		for _, name := range names {
+			found := false
+			for _, item := range capabilities {
+				if item.name == name {
+					selected = append(selected, item)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("不支持的能力：%s，可选值为：%s", name, strings.Join(Capabilities(), "、"))
+			}
+		}
+	}
+
+	report := &Report{Target: target, StartedAt: time.Now()}
+	for _, item := range selected {
+		r := &runner{
+			target:     target,
+			client:     client,
+			capability: &Capability{Name: item.name, Protocol: item.protocol, Passed: true},
+		}
+		start := time.Now()
+		item.run(r)
+		r.capability.Duration = time.Since(start).Milliseconds()
+
+		if r.capability.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Capabilities = append(report.Capabilities, r.capability)
+	}
+	report.Duration = time.Since(report.StartedAt).Milliseconds()
+
+	return report, nil
+}
+
+// runner 单个能力的执行器
+type runner struct {
+	target     string
+	client     *http.Client
+	capability *Capability
+}
+
+// response 接口响应
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// contentType 响应的媒体类型，不包含charset等参数
+func (r *response) contentType() string {
+	contentType, _, _ := strings.Cut(r.header.Get("Content-Type"), ";")
+	return strings.TrimSpace(strings.ToLower(contentType))
+}
+
+// expect 记录检查结果，ok为false时记录未通过的原因
+func (r *runner) expect(name, spec string, ok bool, format string, args ...interface{}) bool {
+	check := &Check{Name: name, Spec: spec, Passed: ok}
+	if !ok {
+		check.Message = fmt.Sprintf(format, args...)
+		r.capability.Passed = false
+	}
+	r.capability.Checks = append(r.capability.Checks, check)
+	return ok
+}
+
+// do 发送请求，请求失败时记录为未通过的检查项并返回nil
+func (r *runner) do(name, spec string, req *http.Request) *response {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		r.expect(name, spec, false, "请求失败：%s", err.Error())
+		return nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		r.expect(name, spec, false, "读取响应失败：%s", err.Error())
+		return nil
+	}
+	return &response{status: resp.StatusCode, header: resp.Header, body: body}
+}
+
+// get 发送GET请求
+func (r *runner) get(name, spec, path string, query url.Values, header map[string]string) *response {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, r.target+path, nil)
+	if err != nil {
+		r.expect(name, spec, false, "创建请求失败：%s", err.Error())
+		return nil
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return r.do(name, spec, req)
+}
+
+// postForm 发送表单请求
+func (r *runner) postForm(name, spec, path string, form url.Values, header map[string]string) *response {
+	req, err := http.NewRequest(http.MethodPost, r.target+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		r.expect(name, spec, false, "创建请求失败：%s", err.Error())
+		return nil
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	return r.do(name, spec, req)
+}
+
+// absoluteURL 判断是否为不包含fragment的绝对地址
+func absoluteURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && u.IsAbs() && u.Host != "" && u.Fragment == ""
+}
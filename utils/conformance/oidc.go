@@ -0,0 +1,200 @@
+package conformance
+
+import (
+	"encoding/json"
+	"github.com/lestrrat-go/jwx/jwk"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// oidcConfiguration OIDC发现文档中需要检查的字段
+type oidcConfiguration struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	UserinfoEndpoint                 string   `json:"userinfo_endpoint"`
+	JwksURI                          string   `json:"jwks_uri"`
+	ResponseTypesSupported           []string `json:"response_types_supported"`
+	SubjectTypesSupported            []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+}
+
+// oauthErrorBody OAuth2.0错误响应
+type oauthErrorBody struct {
+	Error string `json:"error"`
+}
+
+// oidcDiscovery OIDC发现文档（OpenID Connect Discovery 1.0）
+func oidcDiscovery(r *runner) {
+
+	const spec = "OpenID Connect Discovery 1.0"
+
+	resp := r.get("获取发现文档", spec+" 4.1", pathOIDCDiscovery, nil, nil)
+	if resp == nil {
+		return
+	}
+	if !r.expect("返回200", spec+" 4.2", resp.status == http.StatusOK, "状态码为%d", resp.status) {
+		return
+	}
+	r.expect("Content-Type为application/json", spec+" 4.2", resp.contentType() == "application/json", "Content-Type为%s", resp.contentType())
+
+	var cfg oidcConfiguration
+	if err := json.Unmarshal(resp.body, &cfg); !r.expect("响应为JSON对象", spec+" 4.2", err == nil, "解析失败：%v", err) {
+		return
+	}
+
+	// 必填字段
+	missing := make([]string, 0)
+	for name, empty := range map[string]bool{
+		"issuer":                                cfg.Issuer == "",
+		"authorization_endpoint":                cfg.AuthorizationEndpoint == "",
+		"token_endpoint":                        cfg.TokenEndpoint == "",
+		"jwks_uri":                              cfg.JwksURI == "",
+		"response_types_supported":              len(cfg.ResponseTypesSupported) == 0,
+		"subject_types_supported":               len(cfg.SubjectTypesSupported) == 0,
+		"id_token_signing_alg_values_supported": len(cfg.IDTokenSigningAlgValuesSupported) == 0,
+	} {
+		if empty {
+			missing = append(missing, name)
+		}
+	}
+	r.expect("包含必填字段", spec+" 3", len(missing) == 0, "缺少字段：%s", strings.Join(missing, "、"))
+
+	issuer, err := url.Parse(cfg.Issuer)
+	r.expect("issuer为不包含query及fragment的绝对地址", spec+" 3", err == nil && issuer.IsAbs() && issuer.RawQuery == "" && issuer.Fragment == "",
+		"issuer为%s", cfg.Issuer)
+
+	invalid := make([]string, 0)
+	for name, value := range map[string]string{
+		"authorization_endpoint": cfg.AuthorizationEndpoint,
+		"token_endpoint":         cfg.TokenEndpoint,
+		"userinfo_endpoint":      cfg.UserinfoEndpoint,
+		"jwks_uri":               cfg.JwksURI,
+	} {
+		if value != "" && !absoluteURL(value) {
+			invalid = append(invalid, name)
+		}
+	}
+	r.expect("端点为绝对地址", spec+" 3", len(invalid) == 0, "端点不是绝对地址：%s", strings.Join(invalid, "、"))
+
+	r.expect("response_types_supported包含code", spec+" 3", contains(cfg.ResponseTypesSupported, "code"),
+		"response_types_supported为%v", cfg.ResponseTypesSupported)
+	r.expect("id_token_signing_alg_values_supported包含RS256", spec+" 3", contains(cfg.IDTokenSigningAlgValuesSupported, "RS256"),
+		"id_token_signing_alg_values_supported为%v", cfg.IDTokenSigningAlgValuesSupported)
+	if len(cfg.CodeChallengeMethodsSupported) > 0 {
+		r.expect("code_challenge_methods_supported包含S256", "RFC 8414 2", contains(cfg.CodeChallengeMethodsSupported, "S256"),
+			"code_challenge_methods_supported为%v", cfg.CodeChallengeMethodsSupported)
+	}
+}
+
+// oidcJwks JWKS，使用 jwx 解析以验证客户端可以获取签名公钥
+func oidcJwks(r *runner) {
+
+	resp := r.get("获取JWKS", "OpenID Connect Core 1.0 10.1", pathOIDCJwks, nil, nil)
+	if resp == nil {
+		return
+	}
+	if !r.expect("返回200", "RFC 7517 5", resp.status == http.StatusOK, "状态码为%d", resp.status) {
+		return
+	}
+	r.expect("Content-Type为application/json", "RFC 7517 8.5", resp.contentType() == "application/json" || resp.contentType() == "application/jwk-set+json",
+		"Content-Type为%s", resp.contentType())
+
+	set, err := jwk.Parse(resp.body)
+	if !r.expect("jwx可以解析JWKS", "RFC 7517 5", err == nil, "解析失败：%v", err) {
+		return
+	}
+	if !r.expect("包含至少一个密钥", "RFC 7517 5.1", set.Len() > 0, "JWKS中没有密钥") {
+		return
+	}
+
+	var (
+		withoutKid = 0
+		private    = make([]string, 0)
+		weak       = make([]string, 0)
+	)
+	for i := 0; i < set.Len(); i++ {
+		key, _ := set.Get(i)
+		if key.KeyID() == "" {
+			withoutKid++
+		}
+		switch item := key.(type) {
+		case jwk.RSAPrivateKey, jwk.ECDSAPrivateKey, jwk.OKPPrivateKey, jwk.SymmetricKey:
+			private = append(private, key.KeyID())
+		case jwk.RSAPublicKey:
+			if len(item.N())*8 < 2048 {
+				weak = append(weak, key.KeyID())
+			}
+		}
+	}
+	r.expect("密钥包含kid", "OpenID Connect Core 1.0 10.1", withoutKid == 0, "%d个密钥缺少kid", withoutKid)
+	r.expect("不包含私钥或对称密钥", "RFC 7517 9.2", len(private) == 0, "包含私钥：%s", strings.Join(private, "、"))
+	r.expect("RSA密钥长度不少于2048位", "RFC 7518 3.3", len(weak) == 0, "密钥长度不足：%s", strings.Join(weak, "、"))
+}
+
+// oauthToken Token接口的错误响应（RFC 6749）
+func oauthToken(r *runner) {
+
+	// 缺少grant_type
+	resp := r.postForm("缺少grant_type", "RFC 6749 5.2", pathOAuthToken, url.Values{}, nil)
+	if resp != nil {
+		r.expect("缺少参数返回400", "RFC 6749 5.2", resp.status == http.StatusBadRequest, "状态码为%d", resp.status)
+		r.expect("错误码为invalid_request", "RFC 6749 5.2", oauthErrorCode(resp) == "invalid_request", "错误码为%s", oauthErrorCode(resp))
+		r.expect("Content-Type为application/json", "RFC 6749 5.2", resp.contentType() == "application/json", "Content-Type为%s", resp.contentType())
+		r.expect("响应不允许缓存", "RFC 6749 5.1", resp.header.Get("Cache-Control") == "no-store", "Cache-Control为%s", resp.header.Get("Cache-Control"))
+	}
+
+	// 未注册的客户端（client_secret_basic）
+	form := url.Values{"grant_type": {"authorization_code"}, "code": {"conformance-test"}, "redirect_uri": {testService}}
+	req := r.postForm("未注册的客户端", "RFC 6749 5.2", pathOAuthToken, form, map[string]string{
+		"Authorization": basicAuth(testClientId, testClientSecret),
+	})
+	if req != nil {
+		r.expect("客户端认证失败返回401", "RFC 6749 5.2", req.status == http.StatusUnauthorized, "状态码为%d", req.status)
+		r.expect("错误码为invalid_client", "RFC 6749 5.2", oauthErrorCode(req) == "invalid_client", "错误码为%s", oauthErrorCode(req))
+	}
+}
+
+// oidcUserinfo UserInfo接口的Bearer Token校验（RFC 6750）
+func oidcUserinfo(r *runner) {
+
+	resp := r.get("未携带Token", "RFC 6750 3.1", pathOAuthUserinfo, nil, nil)
+	if resp != nil {
+		r.expect("未携带Token返回401", "RFC 6750 3.1", resp.status == http.StatusUnauthorized, "状态码为%d", resp.status)
+		r.expect("返回WWW-Authenticate: Bearer", "RFC 6750 3", strings.HasPrefix(resp.header.Get("WWW-Authenticate"), "Bearer"),
+			"WWW-Authenticate为%s", resp.header.Get("WWW-Authenticate"))
+	}
+
+	resp = r.get("无效的Token", "RFC 6750 3.1", pathOAuthUserinfo, nil, map[string]string{"Authorization": "Bearer " + testAccessToken})
+	if resp != nil {
+		r.expect("无效的Token返回401", "RFC 6750 3.1", resp.status == http.StatusUnauthorized, "状态码为%d", resp.status)
+		r.expect("WWW-Authenticate包含error=\"invalid_token\"", "RFC 6750 3.1", strings.Contains(resp.header.Get("WWW-Authenticate"), `error="invalid_token"`),
+			"WWW-Authenticate为%s", resp.header.Get("WWW-Authenticate"))
+	}
+}
+
+// oauthErrorCode 获取OAuth2.0错误响应中的错误码
+func oauthErrorCode(resp *response) string {
+	var body oauthErrorBody
+	_ = json.Unmarshal(resp.body, &body)
+	return body.Error
+}
+
+// basicAuth 生成client_secret_basic认证请求头，客户端标识及密钥需要先进行表单编码（RFC 6749 2.3.1）
+func basicAuth(clientId, clientSecret string) string {
+	req := &http.Request{Header: http.Header{}}
+	req.SetBasicAuth(url.QueryEscape(clientId), url.QueryEscape(clientSecret))
+	return req.Header.Get("Authorization")
+}
+
+// contains 判断字符串切片中是否包含指定的值
+func contains(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
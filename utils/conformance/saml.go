@@ -0,0 +1,147 @@
+package conformance
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	samlProtocol      = "urn:oasis:names:tc:SAML:2.0:protocol"
+	samlBindingPost   = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	samlBindingRedir  = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	wsfedProtocol     = "http://docs.oasis-open.org/wsfed/federation/200706"
+	samlMetadataSpec  = "SAML V2.0 Metadata"
+	wsfedMetadataSpec = "WS-Federation 1.2"
+)
+
+// samlKeyDescriptor 元数据中的证书
+type samlKeyDescriptor struct {
+	Use         string `xml:"use,attr"`
+	Certificate string `xml:"KeyInfo>X509Data>X509Certificate"`
+}
+
+// samlEntityDescriptor SAML2及WS-Fed元数据
+type samlEntityDescriptor struct {
+	XMLName          xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID         string   `xml:"entityID,attr"`
+	IDPSSODescriptor *struct {
+		ProtocolSupportEnumeration string              `xml:"protocolSupportEnumeration,attr"`
+		KeyDescriptors             []samlKeyDescriptor `xml:"KeyDescriptor"`
+		SingleSignOnServices       []struct {
+			Binding  string `xml:"Binding,attr"`
+			Location string `xml:"Location,attr"`
+		} `xml:"SingleSignOnService"`
+	} `xml:"IDPSSODescriptor"`
+	RoleDescriptor *struct {
+		ProtocolSupportEnumeration string              `xml:"protocolSupportEnumeration,attr"`
+		KeyDescriptors             []samlKeyDescriptor `xml:"KeyDescriptor"`
+		PassiveRequestorEndpoint   string              `xml:"PassiveRequestorEndpoint>EndpointReference>Address"`
+	} `xml:"RoleDescriptor"`
+}
+
+// samlMetadata IdP元数据（SAML V2.0 Metadata）
+func samlMetadata(r *runner) {
+
+	metadata := r.metadata(pathSAMLMetadata, samlMetadataSpec)
+	if metadata == nil {
+		return
+	}
+
+	descriptor := metadata.IDPSSODescriptor
+	if !r.expect("包含IDPSSODescriptor", samlMetadataSpec+" 2.4.3", descriptor != nil, "元数据中没有IDPSSODescriptor") {
+		return
+	}
+	r.expect("protocolSupportEnumeration包含SAML2.0协议", samlMetadataSpec+" 2.4.1",
+		contains(strings.Fields(descriptor.ProtocolSupportEnumeration), samlProtocol),
+		"protocolSupportEnumeration为%s", descriptor.ProtocolSupportEnumeration)
+	r.signingCertificate(descriptor.KeyDescriptors, samlMetadataSpec+" 2.4.1.1")
+
+	var bindings, invalid []string
+	for _, item := range descriptor.SingleSignOnServices {
+		if item.Binding == samlBindingPost || item.Binding == samlBindingRedir {
+			bindings = append(bindings, item.Binding)
+		}
+		if !absoluteURL(item.Location) {
+			invalid = append(invalid, item.Location)
+		}
+	}
+	r.expect("SingleSignOnService支持HTTP-Redirect或HTTP-POST绑定", "SAML V2.0 Profiles 4.1.2", len(bindings) > 0, "没有支持的SingleSignOnService绑定")
+	r.expect("SingleSignOnService地址为绝对地址", samlMetadataSpec+" 2.2.2", len(invalid) == 0, "地址不是绝对地址：%s", strings.Join(invalid, "、"))
+}
+
+// wsfedMetadata WS-Fed联合元数据
+func wsfedMetadata(r *runner) {
+
+	metadata := r.metadata(pathWsFedMetadata, wsfedMetadataSpec+" 3.1")
+	if metadata == nil {
+		return
+	}
+
+	descriptor := metadata.RoleDescriptor
+	if !r.expect("包含SecurityTokenServiceType角色", wsfedMetadataSpec+" 3.1.2", descriptor != nil, "元数据中没有RoleDescriptor") {
+		return
+	}
+	r.expect("protocolSupportEnumeration包含WS-Federation协议", wsfedMetadataSpec+" 3.1.2",
+		contains(strings.Fields(descriptor.ProtocolSupportEnumeration), wsfedProtocol),
+		"protocolSupportEnumeration为%s", descriptor.ProtocolSupportEnumeration)
+	r.signingCertificate(descriptor.KeyDescriptors, wsfedMetadataSpec+" 3.1.2")
+	r.expect("PassiveRequestorEndpoint为绝对地址", wsfedMetadataSpec+" 3.1.2.8", absoluteURL(strings.TrimSpace(descriptor.PassiveRequestorEndpoint)),
+		"PassiveRequestorEndpoint为%s", descriptor.PassiveRequestorEndpoint)
+}
+
+// metadata 获取并解析元数据
+func (r *runner) metadata(path, spec string) *samlEntityDescriptor {
+
+	resp := r.get("获取元数据", spec, path, nil, nil)
+	if resp == nil {
+		return nil
+	}
+	if !r.expect("返回200", spec, resp.status == http.StatusOK, "状态码为%d", resp.status) {
+		return nil
+	}
+	contentType := resp.contentType()
+	r.expect("Content-Type为XML", samlMetadataSpec+" 4.1.1",
+		contentType == "application/xml" || contentType == "text/xml" || contentType == "application/samlmetadata+xml",
+		"Content-Type为%s", contentType)
+
+	var metadata samlEntityDescriptor
+	if err := xml.Unmarshal(resp.body, &metadata); !r.expect("响应为EntityDescriptor", samlMetadataSpec+" 2.3.2", err == nil, "解析失败：%v", err) {
+		return nil
+	}
+	r.expect("entityID不为空", samlMetadataSpec+" 2.3.2", metadata.EntityID != "", "entityID为空")
+
+	return &metadata
+}
+
+// signingCertificate 检查签名证书，未指定用途的证书同时用于签名及加密
+func (r *runner) signingCertificate(descriptors []samlKeyDescriptor, spec string) {
+
+	var certificates []string
+	for _, item := range descriptors {
+		if item.Use == "" || item.Use == "signing" {
+			certificates = append(certificates, item.Certificate)
+		}
+	}
+	if !r.expect("包含签名证书", spec, len(certificates) > 0, "元数据中没有签名证书") {
+		return
+	}
+
+	for _, item := range certificates {
+		raw, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(item), ""))
+		if err != nil {
+			r.expect("签名证书可以解析", spec, false, "证书Base64解码失败：%s", err.Error())
+			return
+		}
+		crt, err := x509.ParseCertificate(raw)
+		if !r.expect("签名证书可以解析", spec, err == nil, "证书解析失败：%v", err) {
+			return
+		}
+		now := time.Now()
+		r.expect("签名证书在有效期内", spec, now.After(crt.NotBefore) && now.Before(crt.NotAfter),
+			"证书有效期为%s至%s", crt.NotBefore.Format(time.DateTime), crt.NotAfter.Format(time.DateTime))
+	}
+}
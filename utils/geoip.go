@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"github.com/oschwald/geoip2-golang"
+	"net"
+	"sync"
+)
+
+// geoIPDatabase GeoLite2 City数据库路径
+const geoIPDatabase = "config/GeoLite2-City.mmdb"
+
+var (
+	geoIPMutex  sync.Mutex
+	geoIPReader *geoip2.Reader
+)
+
+// LookupCountry 查询IP地址所属的国家/地区代码（ISO 3166-1），内网地址等无法识别时返回空字符串
+func LookupCountry(ip string) (string, error) {
+	reader, err := getGeoIPReader()
+	if err != nil {
+		return "", err
+	}
+
+	address := net.ParseIP(ip)
+	if address == nil {
+		return "", nil
+	}
+
+	record, err := reader.Country(address)
+	if err != nil {
+		return "", err
+	}
+
+	return record.Country.IsoCode, nil
+}
+
+// getGeoIPReader 获取GeoIP数据库，首次使用时打开，之后复用
+func getGeoIPReader() (*geoip2.Reader, error) {
+	geoIPMutex.Lock()
+	defer geoIPMutex.Unlock()
+
+	if geoIPReader != nil {
+		return geoIPReader, nil
+	}
+
+	reader, err := geoip2.Open(geoIPDatabase)
+	if err != nil {
+		return nil, err
+	}
+	geoIPReader = reader
+
+	return geoIPReader, nil
+}
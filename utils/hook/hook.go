@@ -0,0 +1,103 @@
+// Package hook 登录流程扩展点
+//
+// 除在管理后台配置Webhook外，也可以通过内置扩展实现自定义校验，无需修改登录流程代码：
+//
+//	package hrcheck
+//
+//	func init() {
+//		hook.Register(hook.PostAuth, "hr-status", func(ctx *hook.Context) error {
+//			if resigned(ctx.Username) {
+//				return errors.New("账号已离职，拒绝登录")
+//			}
+//			return nil
+//		})
+//	}
+//
+// 然后在 main.go 中匿名导入扩展包：import _ "ops-api/plugins/hrcheck"
+package hook
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Stage 登录流程扩展点
+type Stage string
+
+const (
+	PreAuth  Stage = "pre_auth"  // 凭据校验前（仅账号密码登录）
+	PostAuth Stage = "post_auth" // 身份认证通过后（MFA认证前）
+	PreToken Stage = "pre_token" // 签发用户Token前
+)
+
+// Stages 所有扩展点
+var Stages = []Stage{PreAuth, PostAuth, PreToken}
+
+// Context 传递给扩展的登录上下文
+type Context struct {
+	Stage       Stage  `json:"stage"`
+	AuthMethod  string `json:"auth_method"` // 认证方式，与登录日志一致，如：账号密码、钉钉扫码
+	Username    string `json:"username"`
+	Name        string `json:"name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	PhoneNumber string `json:"phone_number,omitempty"`
+	UserFrom    string `json:"user_from,omitempty"`
+	ClientIP    string `json:"client_ip"`
+	Application string `json:"application,omitempty"` // 单点登录的应用标识（ClientId、Service、Wtrealm等）
+}
+
+// Handler 内置扩展处理函数，返回错误时拒绝登录，错误信息将返回给用户
+type Handler func(ctx *Context) error
+
+// Plugin 内置扩展
+type Plugin struct {
+	Name    string
+	Stage   Stage
+	Handler Handler
+}
+
+var (
+	mutex   sync.RWMutex
+	plugins []*Plugin
+)
+
+// Register 注册内置扩展，通常在扩展包的 init 函数中调用，按注册顺序执行
+func Register(stage Stage, name string, handler Handler) {
+	if !Valid(stage) {
+		panic(fmt.Sprintf("hook: 不支持的扩展点 %s", stage))
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for _, p := range plugins {
+		if p.Stage == stage && p.Name == name {
+			panic(fmt.Sprintf("hook: 扩展 %s 重复注册", name))
+		}
+	}
+	plugins = append(plugins, &Plugin{Name: name, Stage: stage, Handler: handler})
+}
+
+// Plugins 获取指定扩展点已注册的内置扩展
+func Plugins(stage Stage) []*Plugin {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	var data []*Plugin
+	for _, p := range plugins {
+		if p.Stage == stage {
+			data = append(data, p)
+		}
+	}
+	return data
+}
+
+// Valid 判断扩展点是否有效
+func Valid(stage Stage) bool {
+	for _, s := range Stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
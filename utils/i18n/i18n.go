@@ -0,0 +1,58 @@
+package i18n
+
+import (
+	"fmt"
+	"strings"
+)
+
+// 支持的语言
+const (
+	EnUS = "en-US"
+	ZhCN = "zh-CN"
+
+	// DefaultLocale 用户及系统均未指定语言时使用的默认语言
+	DefaultLocale = EnUS
+)
+
+// Locales 支持的语言列表
+var Locales = []string{EnUS, ZhCN}
+
+// Normalize 将语言标识规范化为支持的语言，如：zh、zh_CN、zh-Hans -> zh-CN，en、en-GB -> en-US，不支持时返回空字符串
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(lang, "_", "-")))
+	switch {
+	case lang == "":
+		return ""
+	case lang == "zh" || strings.HasPrefix(lang, "zh-"):
+		return ZhCN
+	case lang == "en" || strings.HasPrefix(lang, "en-"):
+		return EnUS
+	default:
+		return ""
+	}
+}
+
+// FromAcceptLanguage 从 Accept-Language 请求头中获取第一个支持的语言，如：zh-CN,zh;q=0.9,en;q=0.8
+func FromAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if locale := Normalize(tag); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// T 获取指定语言的文本，参数按 fmt.Sprintf 格式化；语言不支持时使用默认语言，文本不存在时返回key
+func T(locale, key string, args ...interface{}) string {
+	text, ok := messages[locale][key]
+	if !ok {
+		if text, ok = messages[DefaultLocale][key]; !ok {
+			return key
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
@@ -0,0 +1,88 @@
+package i18n
+
+// messages 文本目录，新增文本时需要同时添加所有语言
+var messages = map[string]map[string]string{
+	EnUS: {
+		// 邮件通用
+		"mail.signature": "Best regards,<br>%s",
+		"mail.no_reply":  "This email was sent automatically. Please do not reply.",
+		"mail.code":      "Your verification code is %s. Keep it safe and do not share it with anyone.",
+
+		// 密码重置、密码获取
+		"password_reset.subject": "Password reset",
+		"password_query.subject": "Password retrieval",
+		"verify_email.subject":   "Email verification",
+
+		// 密码过期提醒
+		"password_expire.subject":  "Password expiration reminder",
+		"password_expire.greeting": "Hello,",
+		"password_expire.body":     "The password of your account (<strong>%s</strong>) will expire at <strong>%s</strong>.",
+		"password_expire.action":   "To keep your account secure, please change your password on the <a href=\"%s\" target=\"_blank\">self-service password portal</a> in time. Otherwise you will not be able to sign in to the connected applications.",
+
+		// 密码重置通知
+		"password_changed.subject":  "Your password was reset",
+		"password_changed.body":     "The password of your account (<strong>%s</strong>) was reset at %s from IP address %s.",
+		"password_changed.sessions": "For your security, you have been signed out of all devices.",
+		"password_changed.action":   "If you did not reset your password, please contact the administrator immediately.",
+
+		// 新设备登录提醒
+		"new_device.subject":  "New sign-in to your account",
+		"new_device.greeting": "Hello %s,",
+		"new_device.body":     "Your account was just signed in from a new device:",
+		"new_device.time":     "Time: %s",
+		"new_device.device":   "Device: %s",
+		"new_device.ip":       "IP address: %s",
+		"new_device.action":   "If this was you, no action is needed. If you don't recognize this activity, please <a href=\"%s\" target=\"_blank\">secure your account</a> immediately: you will be signed out of all devices and asked to change your password at next sign-in. The link is valid for 24 hours.",
+
+		"new_device.body_country": "Your account was just signed in from a new location:",
+		"new_device.location":     "Location: %s",
+
+		// MFA
+		"mfa.invalid_code": "Invalid verification code",
+		"mfa.not_bound":    "You have not set up MFA yet",
+
+		// 单点登录页面
+		"sso.noscript": "<strong>Note:</strong> Since your browser does not support JavaScript, you must press the Continue button once to proceed.",
+		"sso.continue": "Continue",
+		"sso.redirect": "Redirecting to login page...",
+		"sso.title":    "Redirecting",
+	},
+	ZhCN: {
+		"mail.signature": "此致，<br>%s",
+		"mail.no_reply":  "此邮件为系统自动发送，请勿回复此邮件。",
+		"mail.code":      "您的验证码为：%s，请妥善保管，切勿泄露。",
+
+		"password_reset.subject": "密码重置",
+		"password_query.subject": "密码获取",
+		"verify_email.subject":   "邮箱验证",
+
+		"password_expire.subject":  "密码过期提醒",
+		"password_expire.greeting": "亲爱的同事：",
+		"password_expire.body":     "您好，目前检测到您的账户（<strong>%s</strong>）密码在 <strong>%s</strong> 过期。",
+		"password_expire.action":   "为了保护您的账号安全，请及时登录【<a href=\"%s\" target=\"_blank\">密码修改自助平台</a>】修改密码，逾期未修改则会导致您的账户无法登录到相关的平台。",
+
+		"password_changed.subject":  "密码重置通知",
+		"password_changed.body":     "您的账户（<strong>%s</strong>）密码已于 %s 被重置，操作IP：%s。",
+		"password_changed.sessions": "为了保护您的账号安全，您已在所有设备上退出登录。",
+		"password_changed.action":   "如果不是您本人操作，请立即联系管理员。",
+
+		"new_device.subject":  "新设备登录提醒",
+		"new_device.greeting": "%s，您好：",
+		"new_device.body":     "您的账号刚刚在一台新设备上登录：",
+		"new_device.time":     "登录时间：%s",
+		"new_device.device":   "登录设备：%s",
+		"new_device.ip":       "登录IP：%s",
+		"new_device.action":   "如果是您本人操作，请忽略此邮件；如果不是您本人操作，请立即<a href=\"%s\" target=\"_blank\">保护我的账号</a>：您将在所有设备上退出登录，并在下次登录时修改密码。链接24小时内有效。",
+
+		"new_device.body_country": "您的账号刚刚在一个新的国家/地区登录：",
+		"new_device.location":     "登录地区：%s",
+
+		"mfa.invalid_code": "验证码错误",
+		"mfa.not_bound":    "您还未绑定MFA",
+
+		"sso.noscript": "<strong>提示：</strong>您的浏览器不支持JavaScript，请点击“继续”按钮完成登录。",
+		"sso.continue": "继续",
+		"sso.redirect": "正在跳转到登录页面...",
+		"sso.title":    "正在跳转",
+	},
+}
@@ -0,0 +1,213 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"github.com/lestrrat-go/jwx/jwk"
+	"ops-api/config"
+	"sort"
+	"sync"
+)
+
+// keyStore 已解析的IDP密钥及证书，避免每次签发或校验时重复解析
+type keyStore struct {
+	privateKeyPEM  string // 原始私钥，用于判断配置是否已轮换
+	publicKeyPEM   string // 原始公钥
+	certificatePEM string // 原始证书
+	privateKey     *rsa.PrivateKey
+	publicKey      *rsa.PublicKey
+	certificate    *x509.Certificate
+	kid            string // 基于公钥内容生成的Key ID
+}
+
+var (
+	keyStoreMutex   sync.RWMutex
+	currentKeyStore *keyStore
+)
+
+// LoadKeyStore 加载并解析IDP密钥及证书，服务启动及证书更新后调用
+func LoadKeyStore() error {
+	_, err := reloadKeyStore()
+	return err
+}
+
+// getKeyStore 获取已解析的密钥，配置中的密钥发生变化时自动重新加载
+func getKeyStore() (*keyStore, error) {
+	keyStoreMutex.RLock()
+	store := currentKeyStore
+	keyStoreMutex.RUnlock()
+
+	if store != nil &&
+		store.privateKeyPEM == settingString("privateKey") &&
+		store.publicKeyPEM == settingString("publicKey") &&
+		store.certificatePEM == settingString("certificate") {
+		return store, nil
+	}
+
+	return reloadKeyStore()
+}
+
+// reloadKeyStore 重新解析配置中的密钥及证书
+func reloadKeyStore() (*keyStore, error) {
+	store := &keyStore{
+		privateKeyPEM:  settingString("privateKey"),
+		publicKeyPEM:   settingString("publicKey"),
+		certificatePEM: settingString("certificate"),
+	}
+
+	// 解析私钥及公钥
+	privateKey, err := ParseRSAPrivateKey(store.privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	store.privateKey = privateKey
+	publicKey, err := ParseRSAPublicKey(store.publicKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	store.publicKey = publicKey
+
+	// 解析证书
+	block, _ := pem.Decode([]byte(store.certificatePEM))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("invalid certificate")
+	}
+	if store.certificate, err = x509.ParseCertificate(block.Bytes); err != nil {
+		return nil, err
+	}
+
+	// 基于公钥内容生成kid
+	if store.kid, err = RSAKeyId(publicKey); err != nil {
+		return nil, err
+	}
+
+	keyStoreMutex.Lock()
+	currentKeyStore = store
+	keyStoreMutex.Unlock()
+
+	return store, nil
+}
+
+// settingString 获取字符串类型的配置项
+func settingString(key string) string {
+	value := config.GetString(key)
+	return value
+}
+
+// LoadIdpPrivateKey 获取IDP私钥
+func LoadIdpPrivateKey() (*rsa.PrivateKey, error) {
+	store, err := getKeyStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.privateKey, nil
+}
+
+// LoadIdpPublicKey 获取IDP公钥
+func LoadIdpPublicKey() (*rsa.PublicKey, error) {
+	store, err := getKeyStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.publicKey, nil
+}
+
+// LoadIdpKeyId 获取IDP公钥的Key ID
+func LoadIdpKeyId() (string, error) {
+	store, err := getKeyStore()
+	if err != nil {
+		return "", err
+	}
+	return store.kid, nil
+}
+
+// ParseRSAPrivateKey 解析PKCS#8 PEM格式的RSA私钥
+func ParseRSAPrivateKey(privateKeyPEM string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKeyPEM))
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("invalid private key")
+	}
+	privateKeyInterface, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := privateKeyInterface.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid private key")
+	}
+	return privateKey, nil
+}
+
+// ParseRSAPublicKey 解析PKIX PEM格式的RSA公钥
+func ParseRSAPublicKey(publicKeyPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("invalid public key")
+	}
+	publicKeyInterface, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, ok := publicKeyInterface.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid public key")
+	}
+	return publicKey, nil
+}
+
+// GenerateRSAKeyPair 生成RSA密钥对，返回PKCS#8 PEM格式私钥及PKIX PEM格式公钥
+func GenerateRSAKeyPair(bits int) (privateKeyPEM, publicKeyPEM string, err error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		return "", "", err
+	}
+	privateKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", "", err
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return "", "", err
+	}
+	privateKeyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateKeyBytes}))
+	publicKeyPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes}))
+	return privateKeyPEM, publicKeyPEM, nil
+}
+
+// RSAKeyId 基于公钥内容生成Key ID
+func RSAKeyId(publicKey *rsa.PublicKey) (string, error) {
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(publicKeyBytes)
+	return base64.URLEncoding.EncodeToString(hash[:]), nil
+}
+
+// MarshalJwks 生成并序列化JWK Set，keys 为 kid -> 公钥
+func MarshalJwks(keys map[string]*rsa.PublicKey) ([]byte, error) {
+	kids := make([]string, 0, len(keys))
+	for kid := range keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwkSet := jwk.NewSet()
+	for _, kid := range kids {
+		jwkKey, err := jwk.New(keys[kid])
+		if err != nil {
+			return nil, err
+		}
+		_ = jwkKey.Set(jwk.KeyIDKey, kid)
+		_ = jwkKey.Set(jwk.AlgorithmKey, "RS256")
+		_ = jwkKey.Set("use", "sig")
+		jwkSet.Add(jwkKey)
+	}
+	return json.Marshal(jwkSet)
+}
@@ -7,6 +7,7 @@ import (
 	"net/url"
 	"ops-api/config"
 	"ops-api/global"
+	"strings"
 	"time"
 )
 
@@ -33,6 +34,36 @@ func GetPresignedURL(fileName string, expiryTime time.Duration) (url *url.URL, e
 	return presignedURL, nil
 }
 
+// GetPresignedPutURL 获取临时上传链接，客户端可直接通过PUT方法上传文件到OSS
+func GetPresignedPutURL(fileName string, expiryTime time.Duration) (url *url.URL, err error) {
+
+	presignedURL, err := global.MinioClient.PresignedPutObject(context.Background(), config.Conf.OSS.BucketName, fileName, expiryTime)
+	if err != nil {
+		return nil, err
+	}
+
+	return presignedURL, nil
+}
+
+// GetObjectURL 获取对象访问地址，配置了OSS公共访问地址（如CDN）时直接拼接返回，否则返回临时访问链接
+func GetObjectURL(fileName string, expiryTime time.Duration) (string, error) {
+	if publicUrl := settingString("ossPublicUrl"); publicUrl != "" {
+		return strings.TrimRight(publicUrl, "/") + "/" + strings.TrimLeft(fileName, "/"), nil
+	}
+
+	presignedURL, err := GetPresignedURL(fileName, expiryTime)
+	if err != nil {
+		return "", err
+	}
+
+	return presignedURL.String(), nil
+}
+
+// GetObject 获取对象内容，使用完成后需关闭
+func GetObject(objectName string) (io.ReadCloser, error) {
+	return global.MinioClient.GetObject(context.Background(), config.Conf.OSS.BucketName, objectName, minio.GetObjectOptions{})
+}
+
 // StatObject 获取对象信息
 func StatObject(objectName string) (objectInfo *minio.ObjectInfo, err error) {
 
@@ -43,3 +74,8 @@ func StatObject(objectName string) (objectInfo *minio.ObjectInfo, err error) {
 
 	return &info, nil
 }
+
+// RemoveObject 删除对象
+func RemoveObject(objectName string) error {
+	return global.MinioClient.RemoveObject(context.Background(), config.Conf.OSS.BucketName, objectName, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,355 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Version 转换后的OpenAPI版本
+const Version = "3.0.3"
+
+// 参数中需要移入schema的字段
+var schemaFields = []string{
+	"type", "format", "items", "collectionFormat", "default", "maximum", "exclusiveMaximum", "minimum",
+	"exclusiveMinimum", "maxLength", "minLength", "pattern", "maxItems", "minItems", "uniqueItems", "enum", "multipleOf",
+}
+
+// Convert 将Swagger 2.0文档转换为OpenAPI 3.0文档
+func Convert(swagger []byte) ([]byte, error) {
+
+	var doc map[string]interface{}
+	if err := json.Unmarshal(swagger, &doc); err != nil {
+		return nil, err
+	}
+
+	consumes := stringList(doc["consumes"])
+	produces := stringList(doc["produces"])
+
+	spec := map[string]interface{}{
+		"openapi": Version,
+		"info":    doc["info"],
+		"servers": servers(doc),
+		"paths":   map[string]interface{}{},
+	}
+	for _, key := range []string{"tags", "externalDocs", "security"} {
+		if value, ok := doc[key]; ok {
+			spec[key] = value
+		}
+	}
+
+	components := map[string]interface{}{}
+	if definitions, ok := doc["definitions"].(map[string]interface{}); ok {
+		components["schemas"] = definitions
+	}
+	if schemes, ok := doc["securityDefinitions"].(map[string]interface{}); ok {
+		securitySchemes := map[string]interface{}{}
+		for name, scheme := range schemes {
+			if scheme, ok := scheme.(map[string]interface{}); ok {
+				securitySchemes[name] = securityScheme(scheme)
+			}
+		}
+		components["securitySchemes"] = securitySchemes
+	}
+	if len(components) > 0 {
+		spec["components"] = components
+	}
+
+	if paths, ok := doc["paths"].(map[string]interface{}); ok {
+		result := spec["paths"].(map[string]interface{})
+		for path, item := range paths {
+			item, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			result[path] = pathItem(item, consumes, produces)
+		}
+	}
+
+	return json.Marshal(rewriteRefs(spec))
+}
+
+// servers 根据host、basePath及schemes生成服务地址
+func servers(doc map[string]interface{}) []map[string]interface{} {
+	host, _ := doc["host"].(string)
+	basePath, _ := doc["basePath"].(string)
+	if basePath == "" {
+		basePath = "/"
+	}
+	if host == "" {
+		return []map[string]interface{}{{"url": basePath}}
+	}
+
+	schemes := stringList(doc["schemes"])
+	if len(schemes) == 0 {
+		schemes = []string{"https"}
+	}
+	list := make([]map[string]interface{}, 0, len(schemes))
+	for _, scheme := range schemes {
+		list = append(list, map[string]interface{}{"url": scheme + "://" + host + strings.TrimSuffix(basePath, "/")})
+	}
+	return list
+}
+
+// pathItem 转换路径下的所有操作
+func pathItem(item map[string]interface{}, consumes, produces []string) map[string]interface{} {
+
+	result := map[string]interface{}{}
+	var shared []interface{}
+	if parameters, ok := item["parameters"].([]interface{}); ok {
+		shared = parameters
+	}
+
+	for method, operation := range item {
+		switch method {
+		case "get", "put", "post", "delete", "options", "head", "patch":
+			if operation, ok := operation.(map[string]interface{}); ok {
+				result[method] = convertOperation(operation, shared, consumes, produces)
+			}
+		case "parameters":
+		default:
+			result[method] = operation
+		}
+	}
+	return result
+}
+
+// convertOperation 转换单个操作：body、formData参数转换为requestBody，响应的schema转换为content
+func convertOperation(operation map[string]interface{}, shared []interface{}, consumes, produces []string) map[string]interface{} {
+
+	if list := stringList(operation["consumes"]); len(list) > 0 {
+		consumes = list
+	}
+	if list := stringList(operation["produces"]); len(list) > 0 {
+		produces = list
+	}
+	if len(consumes) == 0 {
+		consumes = []string{"application/json"}
+	}
+	if len(produces) == 0 {
+		produces = []string{"application/json"}
+	}
+
+	result := map[string]interface{}{}
+	for key, value := range operation {
+		switch key {
+		case "consumes", "produces", "parameters", "responses", "schemes":
+		default:
+			result[key] = value
+		}
+	}
+
+	var (
+		parameters []interface{}
+		form       = map[string]interface{}{}
+		required   []string
+		file       bool
+	)
+	all := append([]interface{}{}, shared...)
+	if list, ok := operation["parameters"].([]interface{}); ok {
+		all = append(all, list...)
+	}
+	for _, parameter := range all {
+		parameter, ok := parameter.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch parameter["in"] {
+		case "body":
+			body := map[string]interface{}{
+				"content":  content(consumes, parameter["schema"]),
+				"required": parameter["required"] == true,
+			}
+			if description, ok := parameter["description"]; ok {
+				body["description"] = description
+			}
+			result["requestBody"] = body
+		case "formData":
+			name, _ := parameter["name"].(string)
+			schema := parameterSchema(parameter)
+			if schema["type"] == "file" {
+				schema["type"], schema["format"], file = "string", "binary", true
+			}
+			if description, ok := parameter["description"]; ok {
+				schema["description"] = description
+			}
+			form[name] = schema
+			if parameter["required"] == true {
+				required = append(required, name)
+			}
+		default:
+			parameters = append(parameters, convertParameter(parameter))
+		}
+	}
+
+	if len(form) > 0 {
+		mediaType := "application/x-www-form-urlencoded"
+		if file {
+			mediaType = "multipart/form-data"
+		}
+		schema := map[string]interface{}{"type": "object", "properties": form}
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+		result["requestBody"] = map[string]interface{}{
+			"content": map[string]interface{}{mediaType: map[string]interface{}{"schema": schema}},
+		}
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	responses := map[string]interface{}{}
+	if list, ok := operation["responses"].(map[string]interface{}); ok {
+		for status, response := range list {
+			response, ok := response.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			responses[status] = convertResponse(response, produces)
+		}
+	}
+	if len(responses) == 0 {
+		responses["default"] = map[string]interface{}{"description": ""}
+	}
+	result["responses"] = responses
+
+	return result
+}
+
+// convertParameter 转换path、query、header参数，类型信息移入schema
+func convertParameter(parameter map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range parameter {
+		if !isSchemaField(key) {
+			result[key] = value
+		}
+	}
+	result["schema"] = parameterSchema(parameter)
+	return result
+}
+
+// convertResponse 转换响应，schema移入content，响应头的类型信息移入schema
+func convertResponse(response map[string]interface{}, produces []string) map[string]interface{} {
+
+	result := map[string]interface{}{"description": ""}
+	for key, value := range response {
+		switch key {
+		case "schema", "examples", "headers":
+		default:
+			result[key] = value
+		}
+	}
+	if schema, ok := response["schema"]; ok {
+		result["content"] = content(produces, schema)
+	}
+	if headers, ok := response["headers"].(map[string]interface{}); ok {
+		converted := map[string]interface{}{}
+		for name, header := range headers {
+			header, ok := header.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			item := map[string]interface{}{"schema": parameterSchema(header)}
+			if description, ok := header["description"]; ok {
+				item["description"] = description
+			}
+			converted[name] = item
+		}
+		result["headers"] = converted
+	}
+	return result
+}
+
+// parameterSchema 获取参数的类型信息
+func parameterSchema(parameter map[string]interface{}) map[string]interface{} {
+	schema := map[string]interface{}{}
+	for _, key := range schemaFields {
+		if value, ok := parameter[key]; ok && key != "collectionFormat" {
+			schema[key] = value
+		}
+	}
+	if _, ok := schema["type"]; !ok {
+		schema["type"] = "string"
+	}
+	return schema
+}
+
+// securityScheme 转换认证方式
+func securityScheme(scheme map[string]interface{}) map[string]interface{} {
+	switch scheme["type"] {
+	case "basic":
+		return map[string]interface{}{"type": "http", "scheme": "basic", "description": scheme["description"]}
+	case "oauth2":
+		flow := map[string]interface{}{"scopes": scheme["scopes"]}
+		if scheme["scopes"] == nil {
+			flow["scopes"] = map[string]interface{}{}
+		}
+		for _, key := range []string{"authorizationUrl", "tokenUrl"} {
+			if value, ok := scheme[key]; ok {
+				flow[key] = value
+			}
+		}
+		name, _ := scheme["flow"].(string)
+		switch name {
+		case "accessCode":
+			name = "authorizationCode"
+		case "application":
+			name = "clientCredentials"
+		}
+		return map[string]interface{}{"type": "oauth2", "flows": map[string]interface{}{name: flow}}
+	default:
+		return scheme
+	}
+}
+
+// content 生成请求体或响应的content
+func content(mediaTypes []string, schema interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for _, mediaType := range mediaTypes {
+		result[mediaType] = map[string]interface{}{"schema": schema}
+	}
+	return result
+}
+
+// rewriteRefs 将#/definitions/下的引用改为#/components/schemas/
+func rewriteRefs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if ref, ok := item.(string); ok && key == "$ref" {
+				v[key] = strings.Replace(ref, "#/definitions/", "#/components/schemas/", 1)
+				continue
+			}
+			v[key] = rewriteRefs(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = rewriteRefs(item)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			rewriteRefs(item)
+		}
+	}
+	return value
+}
+
+func isSchemaField(key string) bool {
+	for _, field := range schemaFields {
+		if field == key {
+			return true
+		}
+	}
+	return false
+}
+
+func stringList(value interface{}) []string {
+	list, _ := value.([]interface{})
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if s, ok := item.(string); ok {
+			result = append(result, s)
+		}
+	}
+	return result
+}
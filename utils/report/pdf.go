@@ -0,0 +1,212 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDF页面布局（A4横向，单位：pt）
+const (
+	pdfPageWidth  = 842.0
+	pdfPageHeight = 595.0
+	pdfMargin     = 36.0
+	pdfFontSize   = 9.0
+	pdfLineHeight = 14.0
+	pdfTitleSize  = 14.0
+)
+
+// WritePDF 生成PDF文件，不依赖第三方库
+// 中文使用PDF阅读器内置的 STSong-Light 字体（UniGB-UCS2-H编码），文件中不嵌入字体
+func WritePDF(w io.Writer, title string, sheets []*Sheet) error {
+	p := &pdfDocument{}
+
+	for _, sheet := range sheets {
+		p.newPage()
+		p.text(pdfMargin, pdfTitleSize, fmt.Sprintf("%s - %s", title, sheet.Name))
+		p.y -= pdfLineHeight
+
+		widths := columnWidths(sheet)
+		p.row(sheet.Header, widths)
+		p.line()
+		for _, row := range sheet.Rows {
+			if p.y < pdfMargin+pdfLineHeight {
+				p.newPage()
+				p.row(sheet.Header, widths)
+				p.line()
+			}
+			p.row(row, widths)
+		}
+		if len(sheet.Rows) == 0 {
+			p.row([]string{"无数据"}, []float64{pdfPageWidth - 2*pdfMargin})
+		}
+	}
+
+	return p.write(w)
+}
+
+// pdfDocument 简单的PDF文档，每页仅包含文本及分隔线
+type pdfDocument struct {
+	pages   []*bytes.Buffer
+	current *bytes.Buffer
+	y       float64
+}
+
+// newPage 新建页面
+func (p *pdfDocument) newPage() {
+	p.current = &bytes.Buffer{}
+	p.pages = append(p.pages, p.current)
+	p.y = pdfPageHeight - pdfMargin
+}
+
+// text 在当前行输出文本
+func (p *pdfDocument) text(x, size float64, value string) {
+	fmt.Fprintf(p.current, "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, p.y-size, encodeUCS2(value))
+}
+
+// row 输出一行表格数据，超出列宽的内容截断
+func (p *pdfDocument) row(values []string, widths []float64) {
+	x := pdfMargin
+	for i, value := range values {
+		if i >= len(widths) {
+			break
+		}
+		p.text(x, pdfFontSize, truncate(value, widths[i]-4))
+		x += widths[i]
+	}
+	p.y -= pdfLineHeight
+}
+
+// line 输出分隔线
+func (p *pdfDocument) line() {
+	y := p.y + pdfLineHeight/2 - 2
+	fmt.Fprintf(p.current, "0.5 w %.2f %.2f m %.2f %.2f l S\n", pdfMargin, y, pdfPageWidth-pdfMargin, y)
+}
+
+// write 输出PDF文件
+func (p *pdfDocument) write(w io.Writer) error {
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+
+	addObject := func(content string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), content)
+	}
+
+	// 对象编号：1：Catalog，2：Pages，3：Font，4：CIDFont，5：FontDescriptor，之后每页两个对象（Page、Content）
+	pageCount := len(p.pages)
+	kids := make([]string, pageCount)
+	for i := range p.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+i*2)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	addObject("<< /Type /Catalog /Pages 2 0 R >>")
+	addObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pageCount))
+	addObject("<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>")
+	addObject("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 4 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>")
+	addObject("<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	for i, page := range p.pages {
+		addObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, 7+i*2))
+		addObject(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	// 交叉引用表
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// columnWidths 按各列内容长度分配列宽
+func columnWidths(sheet *Sheet) []float64 {
+	available := pdfPageWidth - 2*pdfMargin
+	weights := make([]float64, len(sheet.Header))
+	for i, header := range sheet.Header {
+		weights[i] = textWidth(header)
+	}
+	for _, row := range sheet.Rows {
+		for i, value := range row {
+			if i < len(weights) && textWidth(value) > weights[i] {
+				weights[i] = textWidth(value)
+			}
+		}
+	}
+
+	// 单列最多占用页面宽度的40%
+	var total float64
+	for i := range weights {
+		if weights[i] > available*0.4 {
+			weights[i] = available * 0.4
+		}
+		total += weights[i]
+	}
+
+	widths := make([]float64, len(weights))
+	for i, weight := range weights {
+		if total > 0 {
+			widths[i] = available * weight / total
+		}
+	}
+	return widths
+}
+
+// textWidth 估算文本宽度，中文等全角字符按一个字宽、半角字符按半个字宽计算
+func textWidth(value string) float64 {
+	var width float64
+	for _, r := range value {
+		if r < 0x80 {
+			width += pdfFontSize / 2
+		} else {
+			width += pdfFontSize
+		}
+	}
+	return width
+}
+
+// truncate 截断超出宽度的文本
+func truncate(value string, width float64) string {
+	if textWidth(value) <= width {
+		return value
+	}
+
+	var (
+		b        strings.Builder
+		current  float64
+		ellipsis = textWidth("...")
+	)
+	for _, r := range value {
+		w := textWidth(string(r))
+		if current+w+ellipsis > width {
+			break
+		}
+		b.WriteRune(r)
+		current += w
+	}
+	b.WriteString("...")
+	return b.String()
+}
+
+// encodeUCS2 将文本编码为UCS-2大端序的十六进制字符串
+func encodeUCS2(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		// 基本多文种平面以外的字符及控制字符使用空格代替
+		if r > 0xFFFF || r < 0x20 {
+			r = ' '
+		}
+		for _, unit := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&b, "%04X", unit)
+		}
+	}
+	return b.String()
+}
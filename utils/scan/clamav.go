@@ -0,0 +1,68 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamavChunkSize INSTREAM 每次发送的数据大小
+const clamavChunkSize = 64 << 10
+
+// ClamAV 通过 clamd 的 INSTREAM 命令扫描文件，address 格式：unix:///var/run/clamav/clamd.ctl 或 tcp://127.0.0.1:3310
+func ClamAV(address, filename string, data []byte) (string, error) {
+
+	network, addr := "tcp", address
+	if strings.HasPrefix(address, "unix://") {
+		network, addr = "unix", strings.TrimPrefix(address, "unix://")
+	} else {
+		addr = strings.TrimPrefix(address, "tcp://")
+	}
+
+	conn, err := net.DialTimeout(network, addr, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", err
+	}
+	size := make([]byte, 4)
+	for offset := 0; offset < len(data); offset += clamavChunkSize {
+		chunk := data[offset:min(offset+clamavChunkSize, len(data))]
+		binary.BigEndian.PutUint32(size, uint32(len(chunk)))
+		if _, err := conn.Write(size); err != nil {
+			return "", err
+		}
+		if _, err := conn.Write(chunk); err != nil {
+			return "", err
+		}
+	}
+	// 长度为0的数据块表示发送结束
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return "", err
+	}
+
+	// 响应格式：stream: OK、stream: <威胁名称> FOUND、<错误信息> ERROR
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil {
+		return "", err
+	}
+	result := strings.TrimSpace(string(bytes.TrimRight(reply, "\x00")))
+	result = strings.TrimPrefix(result, "stream: ")
+
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	default:
+		return "", fmt.Errorf("clamd: %s", result)
+	}
+}
@@ -0,0 +1,51 @@
+package scan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// httpResult 外部扫描接口响应结构体
+type httpResult struct {
+	Clean  bool   `json:"clean"`  // 是否未发现威胁
+	Threat string `json:"threat"` // 发现的威胁名称
+}
+
+// HTTP 调用外部扫描接口，请求体为文件内容，文件名通过请求头 X-Filename 传递；
+// 接口返回 200 及 {"clean": false, "threat": "..."} 时视为发现威胁
+func HTTP(address, filename string, data []byte) (string, error) {
+
+	req, err := http.NewRequest(http.MethodPost, address, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Filename", url.PathEscape(filename))
+
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("返回状态码 %d", resp.StatusCode)
+	}
+
+	result := &httpResult{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(result); err != nil {
+		return "", fmt.Errorf("响应解析失败：%s", err.Error())
+	}
+	if result.Clean {
+		return "", nil
+	}
+	if result.Threat == "" {
+		result.Threat = "unknown"
+	}
+	return result.Threat, nil
+}
@@ -0,0 +1,57 @@
+// Package scan 上传文件扫描（病毒、恶意内容）
+//
+// 内置 ClamAV（clamav）及外部扫描接口（http）两种扫描器，在系统设置中通过 uploadScanner、uploadScanAddress 启用；
+// 也可以注册自定义扫描器，无需修改上传流程代码：
+//
+//	package sandbox
+//
+//	func init() {
+//		scan.Register("sandbox", func(address, filename string, data []byte) (string, error) {
+//			return submit(address, filename, data)
+//		})
+//	}
+//
+// 然后在 main.go 中匿名导入扩展包：import _ "ops-api/plugins/sandbox"，并将 uploadScanner 设置为 sandbox
+package scan
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// timeout 扫描超时时间
+const timeout = 30 * time.Second
+
+// Scanner 扫描器，address 为系统设置中配置的扫描服务地址；发现威胁时返回威胁名称，扫描失败时返回错误
+type Scanner func(address, filename string, data []byte) (threat string, err error)
+
+var (
+	mutex    sync.RWMutex
+	scanners = make(map[string]Scanner)
+)
+
+func init() {
+	Register("clamav", ClamAV)
+	Register("http", HTTP)
+}
+
+// Register 注册扫描器，通常在扩展包的 init 函数中调用
+func Register(name string, scanner Scanner) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, ok := scanners[name]; ok {
+		panic(fmt.Sprintf("scan: 扫描器 %s 重复注册", name))
+	}
+	scanners[name] = scanner
+}
+
+// Get 获取已注册的扫描器
+func Get(name string) (Scanner, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	scanner, ok := scanners[name]
+	return scanner, ok
+}
@@ -1,7 +1,7 @@
 package sms
 
 import (
-	"fmt"
+	"encoding/json"
 	openapi "github.com/alibabacloud-go/darabonba-openapi/v2/client"
 	openapiutil "github.com/alibabacloud-go/openapi-util/service"
 	util "github.com/alibabacloud-go/tea-utils/v2/service"
@@ -43,20 +43,16 @@ func CreateApiInfo(action string) (_result *openapi.Params) {
 // CreateClient 创建客户端
 func CreateClient() (_result *openapi.Client, _err error) {
 
-	var (
-		accessKeyId     = config.Conf.Settings["smsAppKey"].(string)
-		accessKeySecret = config.Conf.Settings["smsAppSecret"].(string)
-		endpoint        = config.Conf.Settings["smsEndpoint"].(string)
-	)
+	smsConf := config.SMS()
 
 	// secret解密
-	str, _ := utils.Decrypt(accessKeySecret)
+	str, _ := utils.Decrypt(smsConf.AppSecret)
 
 	// 指定客户端配置
 	conf := &openapi.Config{
-		AccessKeyId:     tea.String(accessKeyId),
+		AccessKeyId:     tea.String(smsConf.AppKey),
 		AccessKeySecret: tea.String(str),
-		Endpoint:        tea.String(endpoint),
+		Endpoint:        tea.String(smsConf.Endpoint),
 	}
 
 	// 客户端实例化
@@ -66,12 +62,9 @@ func CreateClient() (_result *openapi.Client, _err error) {
 	return _result, _err
 }
 
-func AliyunSend(receiver, templateParas string) (resp *string, err error) {
+func AliyunSend(receiver, templateId string, templateParas map[string]string) (resp *string, err error) {
 
-	var (
-		smsSignature  = config.Conf.Settings["smsSignature"].(string)
-		smsTemplateId = config.Conf.Settings["smsTemplateId"].(string)
-	)
+	smsSignature := config.SMS().Signature
 
 	// 创建客户端
 	client, _err := CreateClient()
@@ -81,12 +74,18 @@ func AliyunSend(receiver, templateParas string) (resp *string, err error) {
 
 	params := CreateApiInfo("SendSms")
 
+	// 模板参数
+	paras, err := json.Marshal(templateParas)
+	if err != nil {
+		return nil, err
+	}
+
 	// 指定请求参数
 	queries := map[string]interface{}{}
 	queries["PhoneNumbers"] = tea.String(receiver)
 	queries["SignName"] = tea.String(smsSignature)
-	queries["TemplateCode"] = tea.String(smsTemplateId)
-	queries["TemplateParam"] = tea.String(fmt.Sprintf("{\"code\":\"%s\"}", templateParas))
+	queries["TemplateCode"] = tea.String(templateId)
+	queries["TemplateParam"] = tea.String(string(paras))
 
 	// 指定运行时选项
 	runtime := &util.RuntimeOptions{}
@@ -0,0 +1,58 @@
+package sms
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"ops-api/config"
+	"strconv"
+	"time"
+)
+
+// CallbackMaxAge 回调地址签名有效期，华为云状态报告最长在72小时内推送
+const CallbackMaxAge = 72 * time.Hour
+
+// callbackSign 使用系统密钥对时间戳签名
+func callbackSign(ts string) string {
+	secret := config.GetString("secret")
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("sms_callback:" + ts))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignCallbackURL 为短信状态回调地址添加时间戳及签名，短信服务商回调时原样携带
+func SignCallbackURL(callbackUrl string) string {
+	if callbackUrl == "" {
+		return ""
+	}
+	u, err := url.Parse(callbackUrl)
+	if err != nil {
+		return callbackUrl
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	query := u.Query()
+	query.Set("ts", ts)
+	query.Set("sign", callbackSign(ts))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// VerifyCallback 校验短信状态回调地址中的签名及时间戳
+func VerifyCallback(ts, sign string) error {
+	if ts == "" || sign == "" {
+		return errors.New("回调签名缺失")
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return errors.New("回调时间戳无效")
+	}
+	if time.Since(time.Unix(unix, 0)) > CallbackMaxAge {
+		return errors.New("回调签名已过期")
+	}
+	if !hmac.Equal([]byte(sign), []byte(callbackSign(ts))) {
+		return errors.New("回调签名无效")
+	}
+	return nil
+}
@@ -3,7 +3,7 @@ package sms
 import (
 	"bytes"
 	"crypto/tls"
-	"fmt"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/url"
@@ -21,13 +21,14 @@ import (
 //	statusCallBack: 短信发送状态回调地址，为空则表示不接收
 //	signature: 短信签名名称
 //	receiver: 接口短信的电话号码
-//	templateParas: 模板参数
-func HuaweiSend(sender, templateId, statusCallBack, signature, receiver string, templateParas string) (resp string, err error) {
+//	templateParas: 模板参数，按模板中变量的顺序排列
+func HuaweiSend(sender, templateId, statusCallBack, signature, receiver string, templateParas []string) (resp string, err error) {
 
 	var (
-		appKey    = config.Conf.Settings["smsAppKey"].(string)
-		appSecret = config.Conf.Settings["smsAppSecret"].(string)
-		endpoint  = config.Conf.Settings["smsEndpoint"].(string)
+		conf      = config.SMS()
+		appKey    = conf.AppKey
+		appSecret = conf.AppSecret
+		endpoint  = conf.Endpoint
 	)
 
 	// secret解密
@@ -43,10 +44,13 @@ func HuaweiSend(sender, templateId, statusCallBack, signature, receiver string,
 	apiAddress := endpoint
 
 	// 构造参数
-	paras := fmt.Sprintf("[\"%s\"]", templateParas)
+	paras, err := json.Marshal(templateParas)
+	if err != nil {
+		return "", err
+	}
 
 	// 构造请求体
-	body := buildRequestBody(sender, receiver, templateId, paras, statusCallBack, signature)
+	body := buildRequestBody(sender, receiver, templateId, string(paras), statusCallBack, signature)
 
 	// 发送短信请求
 	resp, err = post(apiAddress, []byte(body), appInfo)
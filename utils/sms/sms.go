@@ -8,7 +8,7 @@ import (
 
 // Sender 发送短信接口
 type Sender interface {
-	SendSMS(phoneNumber, code string) (string, error)
+	SendSMS(phoneNumber, templateId string, params []Param) (string, error)
 	ProcessResponse(resp string) (smsMsgId string, err error)
 }
 
@@ -50,28 +50,24 @@ type HuaweiSMSSender struct{}
 type AliyunSMSSender struct{}
 
 // SendSMS 华为云短信发送
-func (s *HuaweiSMSSender) SendSMS(phoneNumber, code string) (string, error) {
+func (s *HuaweiSMSSender) SendSMS(phoneNumber, templateId string, params []Param) (string, error) {
 
-	var (
-		smsSender      = config.Conf.Settings["smsSender"].(string)
-		smsTemplateId  = config.Conf.Settings["smsTemplateId"].(string)
-		smsCallbackUrl = config.Conf.Settings["smsCallbackUrl"].(string)
-		smsSignature   = config.Conf.Settings["smsSignature"].(string)
-	)
+	conf := config.SMS()
 
+	// 回调地址携带签名，防止伪造短信回执
 	return HuaweiSend(
-		smsSender,
-		smsTemplateId,
-		smsCallbackUrl,
-		smsSignature,
+		conf.Sender,
+		templateId,
+		SignCallbackURL(conf.CallbackUrl),
+		conf.Signature,
 		phoneNumber,
-		code,
+		paramValues(params),
 	)
 }
 
 // SendSMS 阿里云短信发送
-func (s *AliyunSMSSender) SendSMS(phoneNumber, code string) (string, error) {
-	resp, err := AliyunSend(phoneNumber, code)
+func (s *AliyunSMSSender) SendSMS(phoneNumber, templateId string, params []Param) (string, error) {
+	resp, err := AliyunSend(phoneNumber, templateId, paramMap(params))
 	if err != nil {
 		return "", err
 	}
@@ -109,9 +105,7 @@ func (s *AliyunSMSSender) ProcessResponse(resp string) (string, error) {
 // GetSMSSender 获取短信发送器
 func GetSMSSender() Sender {
 
-	smsProvider := config.Conf.Settings["smsProvider"].(string)
-
-	switch smsProvider {
+	switch config.SMS().Provider {
 	case "huawei":
 		return &HuaweiSMSSender{}
 	case "aliyun":
@@ -0,0 +1,25 @@
+package sms
+
+// Param 短信模板参数，华为云按模板中变量的顺序传参，阿里云按变量名传参
+type Param struct {
+	Name  string
+	Value string
+}
+
+// paramValues 按顺序获取模板参数的值
+func paramValues(params []Param) []string {
+	values := make([]string, 0, len(params))
+	for _, param := range params {
+		values = append(values, param.Value)
+	}
+	return values
+}
+
+// paramMap 获取变量名与值的对应关系
+func paramMap(params []Param) map[string]string {
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		values[param.Name] = param.Value
+	}
+	return values
+}
@@ -5,16 +5,18 @@ import (
 	"compress/flate"
 	"crypto/x509"
 	"encoding/base64"
-	"encoding/pem"
 	"encoding/xml"
-	"errors"
+	"fmt"
 	"github.com/wonderivan/logger"
 	"html/template"
 	"io"
 	"net/http"
-	"ops-api/config"
+	"time"
 )
 
+// spMetadataClient 获取SP Metadata使用的HTTP客户端
+var spMetadataClient = &http.Client{Timeout: 10 * time.Second}
+
 // EntityDescriptor SP Metadata中的数据绑定结构体
 type EntityDescriptor struct {
 	XMLName         xml.Name        `xml:"EntityDescriptor"`
@@ -22,7 +24,19 @@ type EntityDescriptor struct {
 	SPSSODescriptor SPSSODescriptor `xml:"SPSSODescriptor"`
 }
 type SPSSODescriptor struct {
-	KeyDescriptors []KeyDescriptor `xml:"KeyDescriptor"`
+	KeyDescriptors            []KeyDescriptor            `xml:"KeyDescriptor"`
+	AssertionConsumerServices []AssertionConsumerService `xml:"AssertionConsumerService"`
+	SingleLogoutServices      []SingleLogoutService      `xml:"SingleLogoutService"`
+}
+type SingleLogoutService struct {
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+}
+type AssertionConsumerService struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	Index     string `xml:"index,attr"`
+	IsDefault string `xml:"isDefault,attr"`
 }
 type KeyDescriptor struct {
 	Use     string  `xml:"use,attr"`
@@ -60,13 +74,15 @@ type NameIDPolicy struct {
 // ParseSPMetadata SP Metadata数据解析
 func ParseSPMetadata(metadataUrl string) (*EntityDescriptor, error) {
 
-	var entityDescriptor = &EntityDescriptor{}
-
 	// 请求SP Metadata地址
-	resp, err := http.Get(metadataUrl)
+	resp, err := spMetadataClient.Get(metadataUrl)
 	if err != nil {
 		return nil, err
 	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取SP Metadata失败，HTTP状态码：%d", resp.StatusCode)
+	}
 
 	// 获取请求到的数据
 	data, err := io.ReadAll(resp.Body)
@@ -74,9 +90,16 @@ func ParseSPMetadata(metadataUrl string) (*EntityDescriptor, error) {
 		return nil, err
 	}
 
+	return ParseSPMetadataXML(data)
+}
+
+// ParseSPMetadataXML SP Metadata XML解析（上传的文件或粘贴的内容）
+func ParseSPMetadataXML(data []byte) (*EntityDescriptor, error) {
+
+	var entityDescriptor = &EntityDescriptor{}
+
 	// 将Metadata数据绑定到结构体
-	err = xml.Unmarshal(data, &entityDescriptor)
-	if err != nil {
+	if err := xml.Unmarshal(data, &entityDescriptor); err != nil {
 		return nil, err
 	}
 
@@ -87,11 +110,11 @@ func ParseSPMetadata(metadataUrl string) (*EntityDescriptor, error) {
 func GenerateSAMLResponsePostForm() *template.Template {
 	return template.Must(template.New("saml-response-form").Parse(
 		`<!DOCTYPE html>` +
-			`<html lang="en">` +
+			`<html lang="{{.Lang}}">` +
 			`<body onload="document.getElementById('saml').submit()">` +
 			`<noscript>` +
 			`<p>` +
-			`<strong>Note:</strong> Since your browser does not support JavaScript, you must press the Continue button once to proceed.` +
+			`{{.Note}}` +
 			`</p>` +
 			`</noscript>` +
 			`<form method="post" action="{{.URL}}" id="saml">` +
@@ -99,7 +122,32 @@ func GenerateSAMLResponsePostForm() *template.Template {
 			`<input type="hidden" name="RelayState" value="{{.RelayState}}" />` +
 			`<noscript>` +
 			`<div>` +
-			`<input type="submit" value="Continue" />` +
+			`<input type="submit" value="{{.Continue}}" />` +
+			`</div>` +
+			`</noscript>` +
+			`</form>` +
+			`</body>` +
+			`</html>`))
+}
+
+// GenerateWsFedResponsePostForm 生成WS-Fed登录响应PostForm表单
+func GenerateWsFedResponsePostForm() *template.Template {
+	return template.Must(template.New("wsfed-response-form").Parse(
+		`<!DOCTYPE html>` +
+			`<html lang="{{.Lang}}">` +
+			`<body onload="document.getElementById('wsfed').submit()">` +
+			`<noscript>` +
+			`<p>` +
+			`{{.Note}}` +
+			`</p>` +
+			`</noscript>` +
+			`<form method="post" action="{{.URL}}" id="wsfed">` +
+			`<input type="hidden" name="wa" value="wsignin1.0" />` +
+			`<input type="hidden" name="wresult" value="{{.Wresult}}" />` +
+			`{{if .Wctx}}<input type="hidden" name="wctx" value="{{.Wctx}}" />{{end}}` +
+			`<noscript>` +
+			`<div>` +
+			`<input type="submit" value="{{.Continue}}" />` +
 			`</div>` +
 			`</noscript>` +
 			`</form>` +
@@ -109,25 +157,11 @@ func GenerateSAMLResponsePostForm() *template.Template {
 
 // LoadIdpCertificate 获取IDP证书
 func LoadIdpCertificate() (*x509.Certificate, error) {
-
-	// 读取证书
-	certificate := config.Conf.Settings["certificate"].(string)
-
-	certificateData := []byte(certificate)
-
-	// 解码PEM格式证书
-	block, _ := pem.Decode(certificateData)
-	if block == nil || block.Type != "CERTIFICATE" {
-		return nil, errors.New("invalid certificate")
-	}
-
-	// 解析证书
-	cert, err := x509.ParseCertificate(block.Bytes)
+	store, err := getKeyStore()
 	if err != nil {
 		return nil, err
 	}
-
-	return cert, nil
+	return store.certificate, nil
 }
 
 // Decompress 解压缩
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"math/rand"
+	"strings"
 	"time"
 )
 
@@ -53,3 +54,20 @@ func ParseTime(dateStr string) *time.Time {
 	}
 	return &parsedTime
 }
+
+// MaskEmail 邮箱脱敏，仅保留用户名首字符及域名
+func MaskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return email
+	}
+	return email[:1] + "***" + email[at:]
+}
+
+// MaskPhone 手机号脱敏，仅保留前3位及后4位
+func MaskPhone(phone string) string {
+	if len(phone) < 7 {
+		return phone
+	}
+	return phone[:3] + "****" + phone[len(phone)-4:]
+}
@@ -2,22 +2,29 @@ package utils
 
 // excludedFields 不记录日志的字段
 var excludedFields = map[string]struct{}{
-	"password":          {},
-	"re_password":       {},
-	"client_id":         {},
-	"client_secret":     {},
-	"certificate":       {},
-	"mfa_code":          {},
-	"DeletedAt":         {},
-	"ldapBindPassword":  {},
-	"mailPassword":      {},
-	"smsAppSecret":      {},
-	"dingdingAppSecret": {},
-	"feishuAppSecret":   {},
-	"wechatSecret":      {},
-	"access_key":        {},
-	"secret_key":        {},
-	"iam_password":      {},
+	"password":               {},
+	"re_password":            {},
+	"client_id":              {},
+	"client_secret":          {},
+	"certificate":            {},
+	"mfa_code":               {},
+	"DeletedAt":              {},
+	"ldapBindPassword":       {},
+	"mailPassword":           {},
+	"smsAppSecret":           {},
+	"dingdingAppSecret":      {},
+	"feishuAppSecret":        {},
+	"wechatSecret":           {},
+	"scimToken":              {},
+	"oidcRegistrationToken":  {},
+	"ldapServerBindPassword": {},
+	"ldapServerPrivateKey":   {},
+	"itsmJiraToken":          {},
+	"itsmWebhookToken":       {},
+	"access_key":             {},
+	"secret_key":             {},
+	"iam_password":           {},
+	"launch_secret":          {},
 }
 
 // FilterFields 递归过滤敏感字段
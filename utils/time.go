@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"ops-api/config"
+	"time"
+)
+
+// FormatTime 按系统时区格式化时间，用于通知、报告等展示给用户的时间
+func FormatTime(t time.Time) string {
+	return FormatTimeIn(t, config.Location())
+}
+
+// FormatTimeIn 按指定时区格式化时间
+func FormatTimeIn(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02 15:04:05")
+}